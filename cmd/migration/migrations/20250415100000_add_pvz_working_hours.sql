@@ -0,0 +1,37 @@
+-- +goose Up
+
+ALTER TABLE pvzs
+    ADD COLUMN time_zone TEXT NOT NULL DEFAULT 'Europe/Moscow',
+    ADD COLUMN working_hours_policy TEXT CHECK (working_hours_policy IN ('none', 'reject', 'flag')) NOT NULL DEFAULT 'none';
+
+CREATE TABLE pvz_working_hours (
+    pvz_id UUID NOT NULL,
+    weekday SMALLINT CHECK (weekday BETWEEN 0 AND 6) NOT NULL,
+    open_time TIME NOT NULL,
+    close_time TIME NOT NULL,
+    PRIMARY KEY (pvz_id, weekday),
+    FOREIGN KEY (pvz_id) REFERENCES pvzs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE pvz_schedule_exceptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pvz_id UUID NOT NULL,
+    date DATE NOT NULL,
+    is_closed BOOLEAN NOT NULL,
+    open_time TIME,
+    close_time TIME,
+    reason TEXT NOT NULL DEFAULT '',
+    UNIQUE (pvz_id, date),
+    FOREIGN KEY (pvz_id) REFERENCES pvzs(id) ON DELETE CASCADE
+);
+
+ALTER TABLE receptions
+    ADD COLUMN outside_working_hours BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE receptions DROP COLUMN IF EXISTS outside_working_hours;
+DROP TABLE IF EXISTS pvz_schedule_exceptions;
+DROP TABLE IF EXISTS pvz_working_hours;
+ALTER TABLE pvzs
+    DROP COLUMN IF EXISTS working_hours_policy,
+    DROP COLUMN IF EXISTS time_zone;
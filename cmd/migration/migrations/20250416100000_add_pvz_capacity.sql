@@ -0,0 +1,19 @@
+-- +goose Up
+
+ALTER TABLE pvzs
+    ADD COLUMN capacity INTEGER CHECK (capacity >= 0),
+    ADD COLUMN capacity_policy TEXT CHECK (capacity_policy IN ('reject', 'warn')) NOT NULL DEFAULT 'reject',
+    ADD COLUMN occupancy INTEGER CHECK (occupancy >= 0) NOT NULL DEFAULT 0;
+
+UPDATE pvzs SET occupancy = (
+    SELECT COUNT(*)
+    FROM products p
+    JOIN receptions r ON r.id = p.reception_id
+    WHERE r.pvz_id = pvzs.id
+);
+
+-- +goose Down
+ALTER TABLE pvzs
+    DROP COLUMN IF EXISTS occupancy,
+    DROP COLUMN IF EXISTS capacity_policy,
+    DROP COLUMN IF EXISTS capacity;
@@ -0,0 +1,7 @@
+-- +goose Up
+
+ALTER TABLE pvzs
+    ADD COLUMN address TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE pvzs DROP COLUMN IF EXISTS address;
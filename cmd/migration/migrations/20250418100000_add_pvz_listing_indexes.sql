@@ -0,0 +1,10 @@
+-- +goose Up
+
+CREATE INDEX IF NOT EXISTS idx_pvzs_city ON pvzs (city);
+CREATE INDEX IF NOT EXISTS idx_receptions_pvz_id_date_time ON receptions (pvz_id, date_time);
+CREATE INDEX IF NOT EXISTS idx_products_reception_id_type ON products (reception_id, type);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_reception_id_type;
+DROP INDEX IF EXISTS idx_receptions_pvz_id_date_time;
+DROP INDEX IF EXISTS idx_pvzs_city;
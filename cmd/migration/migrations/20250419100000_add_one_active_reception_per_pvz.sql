@@ -0,0 +1,18 @@
+-- +goose Up
+
+-- До появления ограничения параллельные запросы могли открыть несколько приёмок на один ПВЗ:
+-- оставляем активной только самую свежую.
+UPDATE receptions r
+SET status = 'close'
+WHERE r.status = 'in_progress'
+  AND EXISTS (
+      SELECT 1 FROM receptions newer
+      WHERE newer.pvz_id = r.pvz_id
+        AND newer.status = 'in_progress'
+        AND (newer.date_time, newer.id) > (r.date_time, r.id)
+  );
+
+CREATE UNIQUE INDEX receptions_one_active_per_pvz ON receptions (pvz_id) WHERE status = 'in_progress';
+
+-- +goose Down
+DROP INDEX IF EXISTS receptions_one_active_per_pvz;
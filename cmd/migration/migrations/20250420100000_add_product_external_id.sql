@@ -0,0 +1,7 @@
+-- +goose Up
+
+ALTER TABLE products
+    ADD COLUMN external_id TEXT;
+
+-- +goose Down
+ALTER TABLE products DROP COLUMN IF EXISTS external_id;
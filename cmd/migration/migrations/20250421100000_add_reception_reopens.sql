@@ -0,0 +1,14 @@
+-- +goose Up
+
+CREATE TABLE reception_reopens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reception_id UUID NOT NULL REFERENCES receptions(id) ON DELETE CASCADE,
+    reopened_by TEXT NOT NULL DEFAULT '',
+    reopened_at TIMESTAMP NOT NULL,
+    reason TEXT NOT NULL
+);
+
+CREATE INDEX idx_reception_reopens_reception_id ON reception_reopens (reception_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS reception_reopens;
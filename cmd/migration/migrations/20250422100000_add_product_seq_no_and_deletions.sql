@@ -0,0 +1,52 @@
+-- +goose Up
+
+-- Счётчик последнего выданного порядкового номера товара в приёмке.
+-- Номера не переиспользуются после удаления, поэтому храним его отдельно от MAX(seq_no).
+ALTER TABLE receptions
+    ADD COLUMN last_seq_no BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE products
+    ADD COLUMN seq_no BIGINT;
+
+UPDATE products p
+SET seq_no = numbered.seq_no
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY reception_id ORDER BY date_time, id) AS seq_no
+    FROM products
+) numbered
+WHERE numbered.id = p.id;
+
+UPDATE receptions r
+SET last_seq_no = counters.last_seq_no
+FROM (
+    SELECT reception_id, MAX(seq_no) AS last_seq_no
+    FROM products
+    GROUP BY reception_id
+) counters
+WHERE counters.reception_id = r.id;
+
+ALTER TABLE products
+    ALTER COLUMN seq_no SET NOT NULL;
+
+CREATE UNIQUE INDEX products_reception_seq_no ON products (reception_id, seq_no);
+
+CREATE TABLE product_deletions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL,
+    reception_id UUID NOT NULL REFERENCES receptions(id) ON DELETE CASCADE,
+    seq_no BIGINT NOT NULL,
+    type TEXT NOT NULL,
+    external_id TEXT,
+    kind TEXT NOT NULL CHECK (kind IN ('undo', 'remove')),
+    reason TEXT NOT NULL DEFAULT '',
+    deleted_by TEXT NOT NULL DEFAULT '',
+    deleted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_product_deletions_reception_id ON product_deletions (reception_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS product_deletions;
+DROP INDEX IF EXISTS products_reception_seq_no;
+ALTER TABLE products DROP COLUMN IF EXISTS seq_no;
+ALTER TABLE receptions DROP COLUMN IF EXISTS last_seq_no;
@@ -0,0 +1,21 @@
+-- +goose Up
+
+-- Повторные сканирования одной посылки в рамках приёмки раньше не отсекались:
+-- у более поздних дублей сбрасываем внешний идентификатор, чтобы можно было построить индекс.
+UPDATE products p
+SET external_id = NULL
+WHERE p.external_id IS NOT NULL
+  AND EXISTS (
+      SELECT 1 FROM products earlier
+      WHERE earlier.reception_id = p.reception_id
+        AND earlier.external_id = p.external_id
+        AND earlier.seq_no < p.seq_no
+  );
+
+CREATE UNIQUE INDEX products_reception_external_id ON products (reception_id, external_id) WHERE external_id IS NOT NULL;
+
+CREATE INDEX idx_products_external_id ON products (external_id) WHERE external_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_external_id;
+DROP INDEX IF EXISTS products_reception_external_id;
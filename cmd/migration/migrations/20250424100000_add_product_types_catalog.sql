@@ -0,0 +1,26 @@
+-- +goose Up
+
+CREATE TABLE product_types (
+    code TEXT PRIMARY KEY,
+    name_ru TEXT NOT NULL,
+    name_en TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- Коды существующих типов совпадают со значениями, которые уже лежат в products.type
+-- и которые присылают клиенты, поэтому API и данные остаются совместимыми.
+INSERT INTO product_types (code, name_ru, name_en) VALUES
+    ('электроника', 'Электроника', 'Electronics'),
+    ('одежда', 'Одежда', 'Clothes'),
+    ('обувь', 'Обувь', 'Shoes');
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_type_check;
+
+ALTER TABLE products
+    ADD CONSTRAINT products_type_fkey FOREIGN KEY (type) REFERENCES product_types(code) ON UPDATE CASCADE;
+
+-- +goose Down
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_type_fkey;
+ALTER TABLE products
+    ADD CONSTRAINT products_type_check CHECK (type IN ('электроника', 'одежда', 'обувь'));
+DROP TABLE IF EXISTS product_types;
@@ -0,0 +1,14 @@
+-- +goose Up
+
+ALTER TABLE receptions
+    ADD COLUMN auto_closed BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN close_reason TEXT;
+
+CREATE INDEX idx_products_reception_date_time ON products (reception_id, date_time);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_reception_date_time;
+
+ALTER TABLE receptions
+    DROP COLUMN IF EXISTS close_reason,
+    DROP COLUMN IF EXISTS auto_closed;
@@ -0,0 +1,34 @@
+-- +goose Up
+
+CREATE TABLE reception_manifest_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reception_id UUID NOT NULL REFERENCES receptions(id) ON DELETE CASCADE,
+    external_id TEXT NOT NULL,
+    type TEXT NOT NULL REFERENCES product_types(code) ON UPDATE CASCADE,
+    CONSTRAINT reception_manifest_items_external_id UNIQUE (reception_id, external_id)
+);
+
+CREATE TABLE reception_discrepancy_reports (
+    reception_id UUID PRIMARY KEY REFERENCES receptions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    expected_count INT NOT NULL,
+    received_count INT NOT NULL,
+    matched_count INT NOT NULL
+);
+
+CREATE TABLE reception_discrepancy_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reception_id UUID NOT NULL REFERENCES reception_discrepancy_reports(reception_id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('missing', 'unexpected', 'duplicated', 'type_mismatch')),
+    external_id TEXT NOT NULL DEFAULT '',
+    expected_type TEXT NOT NULL DEFAULT '',
+    actual_type TEXT NOT NULL DEFAULT '',
+    product_id UUID
+);
+
+CREATE INDEX idx_reception_discrepancy_items_reception_id ON reception_discrepancy_items (reception_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS reception_discrepancy_items;
+DROP TABLE IF EXISTS reception_discrepancy_reports;
+DROP TABLE IF EXISTS reception_manifest_items;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+CREATE TABLE reception_summaries (
+    reception_id UUID PRIMARY KEY REFERENCES receptions(id) ON DELETE CASCADE,
+    closed_at TIMESTAMP NOT NULL,
+    total_products INT NOT NULL,
+    products_by_type JSONB NOT NULL DEFAULT '{}'::jsonb,
+    open_duration_seconds BIGINT NOT NULL,
+    deletions_count INT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS reception_summaries;
@@ -0,0 +1,25 @@
+-- +goose Up
+
+ALTER TABLE products
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'received'
+        CHECK (status IN ('received', 'issued', 'returned', 'refused')),
+    ADD COLUMN status_changed_at TIMESTAMP;
+
+CREATE TABLE product_status_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL,
+    pvz_id UUID NOT NULL REFERENCES pvzs(id),
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_by TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL,
+    CONSTRAINT product_status_events_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE INDEX idx_product_status_events_product ON product_status_events (product_id, changed_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS product_status_events;
+ALTER TABLE products DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE products DROP COLUMN IF EXISTS status;
@@ -0,0 +1,29 @@
+-- +goose Up
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('employee', 'moderator', 'client'));
+
+CREATE TABLE order_owners (
+    external_id TEXT PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_order_owners_customer ON order_owners (customer_id);
+
+CREATE TABLE pickup_codes (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- Пока код не использован, он однозначно указывает на товар: по нему сотрудник ищет посылку.
+CREATE UNIQUE INDEX pickup_codes_active_code ON pickup_codes (code) WHERE used_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS pickup_codes;
+DROP TABLE IF EXISTS order_owners;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('employee', 'moderator'));
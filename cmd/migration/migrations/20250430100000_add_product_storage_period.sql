@@ -0,0 +1,27 @@
+-- +goose Up
+
+ALTER TABLE product_types
+    ADD COLUMN storage_days INT NOT NULL DEFAULT 7 CHECK (storage_days > 0);
+
+ALTER TABLE products ADD COLUMN storage_deadline TIMESTAMP;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+ALTER TABLE products ADD CONSTRAINT products_status_check
+    CHECK (status IN ('received', 'issued', 'returned', 'refused', 'return_to_sender'));
+
+-- Срок хранения начинает идти с закрытия приёмки; для уже закрытых приёмок считаем его от их даты.
+UPDATE products p
+SET storage_deadline = r.date_time + pt.storage_days * INTERVAL '1 day'
+FROM receptions r, product_types pt
+WHERE r.id = p.reception_id AND pt.code = p.type AND r.status = 'close';
+
+CREATE INDEX idx_products_storage_deadline ON products (storage_deadline) WHERE status = 'received';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_storage_deadline;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+UPDATE products SET status = 'received' WHERE status = 'return_to_sender';
+ALTER TABLE products ADD CONSTRAINT products_status_check
+    CHECK (status IN ('received', 'issued', 'returned', 'refused'));
+ALTER TABLE products DROP COLUMN IF EXISTS storage_deadline;
+ALTER TABLE product_types DROP COLUMN IF EXISTS storage_days;
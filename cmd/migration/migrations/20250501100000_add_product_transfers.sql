@@ -0,0 +1,36 @@
+-- +goose Up
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+ALTER TABLE products ADD CONSTRAINT products_status_check
+    CHECK (status IN ('received', 'issued', 'returned', 'refused', 'return_to_sender', 'in_transit'));
+
+CREATE TABLE transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source_pvz_id UUID NOT NULL REFERENCES pvzs(id),
+    destination_pvz_id UUID NOT NULL REFERENCES pvzs(id),
+    status TEXT NOT NULL CHECK (status IN ('in_transit', 'accepted')),
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    accepted_by TEXT,
+    accepted_at TIMESTAMP,
+    reception_id UUID REFERENCES receptions(id),
+    CHECK (source_pvz_id <> destination_pvz_id)
+);
+
+CREATE TABLE transfer_items (
+    transfer_id UUID NOT NULL REFERENCES transfers(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL,
+    PRIMARY KEY (transfer_id, product_id),
+    CONSTRAINT transfer_items_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE INDEX idx_transfer_items_product ON transfer_items (product_id);
+CREATE INDEX idx_transfers_destination ON transfers (destination_pvz_id, created_at) WHERE status = 'in_transit';
+
+-- +goose Down
+DROP TABLE IF EXISTS transfer_items;
+DROP TABLE IF EXISTS transfers;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+UPDATE products SET status = 'received' WHERE status = 'in_transit';
+ALTER TABLE products ADD CONSTRAINT products_status_check
+    CHECK (status IN ('received', 'issued', 'returned', 'refused', 'return_to_sender'));
@@ -0,0 +1,26 @@
+-- +goose Up
+
+ALTER TABLE product_types
+    ADD COLUMN size_category TEXT NOT NULL DEFAULT 'm' CHECK (size_category IN ('s', 'm', 'l'));
+
+CREATE TABLE storage_cells (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pvz_id UUID NOT NULL REFERENCES pvzs(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    size TEXT NOT NULL CHECK (size IN ('s', 'm', 'l')),
+    capacity INT NOT NULL DEFAULT 1 CHECK (capacity > 0),
+    CONSTRAINT storage_cells_pvz_code UNIQUE (pvz_id, code)
+);
+
+-- Ячейка освобождается, когда товар покидает ПВЗ, поэтому занятость ячейки — число ссылающихся на неё товаров.
+ALTER TABLE products
+    ADD COLUMN cell_id UUID,
+    ADD CONSTRAINT products_cell_id_fkey FOREIGN KEY (cell_id) REFERENCES storage_cells(id);
+
+CREATE INDEX idx_products_cell_id ON products (cell_id) WHERE cell_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_cell_id;
+ALTER TABLE products DROP COLUMN IF EXISTS cell_id;
+DROP TABLE IF EXISTS storage_cells;
+ALTER TABLE product_types DROP COLUMN IF EXISTS size_category;
@@ -0,0 +1,49 @@
+-- +goose Up
+
+CREATE TABLE inventory_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pvz_id UUID NOT NULL REFERENCES pvzs(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('in_progress', 'completed', 'approved')),
+    started_by TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    expected_count INT,
+    scanned_count INT,
+    matched_count INT,
+    approved_by TEXT,
+    approved_at TIMESTAMP,
+    approval_comment TEXT NOT NULL DEFAULT ''
+);
+
+-- Пока инвентаризацию не подписал модератор, новую на этом ПВЗ начать нельзя.
+CREATE UNIQUE INDEX inventory_sessions_one_open_per_pvz ON inventory_sessions (pvz_id) WHERE status <> 'approved';
+
+CREATE TABLE inventory_scans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES inventory_sessions(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    product_id UUID REFERENCES products(id) ON DELETE SET NULL,
+    scanned_by TEXT NOT NULL DEFAULT '',
+    scanned_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX inventory_scans_session_product ON inventory_scans (session_id, product_id) WHERE product_id IS NOT NULL;
+CREATE INDEX idx_inventory_scans_session_id ON inventory_scans (session_id, scanned_at);
+
+CREATE TABLE inventory_discrepancies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES inventory_sessions(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('missing', 'surplus')),
+    product_id UUID,
+    code TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL DEFAULT '',
+    external_id TEXT NOT NULL DEFAULT '',
+    cell_code TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_inventory_discrepancies_session_id ON inventory_discrepancies (session_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS inventory_discrepancies;
+DROP TABLE IF EXISTS inventory_scans;
+DROP TABLE IF EXISTS inventory_sessions;
@@ -0,0 +1,34 @@
+-- +goose Up
+
+CREATE TABLE damage_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID REFERENCES products(id) ON DELETE CASCADE,
+    reception_id UUID REFERENCES receptions(id) ON DELETE CASCADE,
+    pvz_id UUID NOT NULL REFERENCES pvzs(id) ON DELETE CASCADE,
+    description TEXT NOT NULL,
+    severity TEXT NOT NULL CHECK (severity IN ('minor', 'moderate', 'severe')),
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    -- Акт составляется либо на товар, либо на приёмку целиком.
+    CONSTRAINT damage_reports_single_target CHECK ((product_id IS NULL) <> (reception_id IS NULL))
+);
+
+CREATE INDEX idx_damage_reports_product_id ON damage_reports (product_id) WHERE product_id IS NOT NULL;
+CREATE INDEX idx_damage_reports_reception_id ON damage_reports (reception_id) WHERE reception_id IS NOT NULL;
+
+-- Сами файлы лежат в хранилище (BlobStore), в базе только ключ и метаданные.
+CREATE TABLE damage_photos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    report_id UUID NOT NULL REFERENCES damage_reports(id) ON DELETE CASCADE,
+    blob_key TEXT NOT NULL UNIQUE,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    uploaded_by TEXT NOT NULL DEFAULT '',
+    uploaded_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_damage_photos_report_id ON damage_photos (report_id, uploaded_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS damage_photos;
+DROP TABLE IF EXISTS damage_reports;
@@ -0,0 +1,18 @@
+-- +goose Up
+
+-- Доменные события, которые репозитории пишут в одной транзакции с изменением данных.
+-- seq задаёт порядок публикации; published_at заполняется, когда событие отдано Publisher.
+CREATE TABLE outbox_events (
+    id UUID PRIMARY KEY,
+    seq BIGSERIAL NOT NULL UNIQUE,
+    event_type TEXT NOT NULL,
+    pvz_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events (seq) WHERE published_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS outbox_events;
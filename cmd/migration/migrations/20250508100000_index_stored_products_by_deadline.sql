@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Не выкупленные и возвращённые клиентом товары тоже уходят отправителю по сроку хранения,
+-- поэтому индекс для фоновой проверки должен покрывать и их.
+DROP INDEX IF EXISTS idx_products_storage_deadline;
+CREATE INDEX idx_products_storage_deadline ON products (storage_deadline)
+    WHERE status IN ('received', 'refused', 'returned');
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_products_storage_deadline;
+CREATE INDEX idx_products_storage_deadline ON products (storage_deadline) WHERE status = 'received';
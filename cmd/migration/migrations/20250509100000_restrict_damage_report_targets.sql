@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- Акт о повреждении — документ: удаление товара или приёмки не должно молча стирать его вместе с фото.
+ALTER TABLE damage_reports
+    DROP CONSTRAINT damage_reports_product_id_fkey,
+    DROP CONSTRAINT damage_reports_reception_id_fkey,
+    ADD CONSTRAINT damage_reports_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT damage_reports_reception_id_fkey FOREIGN KEY (reception_id) REFERENCES receptions(id) ON DELETE RESTRICT;
+
+-- +goose Down
+ALTER TABLE damage_reports
+    DROP CONSTRAINT damage_reports_product_id_fkey,
+    DROP CONSTRAINT damage_reports_reception_id_fkey,
+    ADD CONSTRAINT damage_reports_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    ADD CONSTRAINT damage_reports_reception_id_fkey FOREIGN KEY (reception_id) REFERENCES receptions(id) ON DELETE CASCADE;
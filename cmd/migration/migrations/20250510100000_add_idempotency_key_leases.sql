@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- Аренда незавершённого ключа: выполняющийся запрос её продлевает, а после падения экземпляра
+-- ключ с истёкшей арендой может занять повтор, не дожидаясь истечения expires_at.
+ALTER TABLE idempotency_keys ADD COLUMN locked_until TIMESTAMP NOT NULL DEFAULT now();
+
+-- +goose Down
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1,7 @@
+-- +goose Up
+
+-- Принятое перемещение считается активностью приёмки, в которую оно принято (автозакрытие по простою).
+CREATE INDEX idx_transfers_reception_id ON transfers (reception_id) WHERE reception_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transfers_reception_id;
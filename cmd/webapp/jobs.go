@@ -0,0 +1,166 @@
+package main
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	productUsecase "avito_spring_staj_2025/internal/product/usecase"
+	receptionUsecase "avito_spring_staj_2025/internal/reception/usecase"
+	"avito_spring_staj_2025/internal/service/idempotency"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/metrics"
+	"avito_spring_staj_2025/internal/service/outbox"
+	"avito_spring_staj_2025/internal/service/scheduler"
+	webhookUsecase "avito_spring_staj_2025/internal/webhook/usecase"
+	"context"
+	"go.uber.org/zap"
+	"log"
+	"os"
+	"time"
+)
+
+// Ключи advisory lock фоновых задач; должны быть уникальны в пределах базы.
+const (
+	autoCloseReceptionsLockKey    = 1
+	returnOverdueProductsLockKey  = 2
+	cleanupIdempotencyKeysLockKey = 3
+	relayOutboxEventsLockKey      = 4
+	deliverWebhooksLockKey        = 5
+)
+
+const (
+	defaultAutoCloseInterval          = 10 * time.Minute
+	defaultAutoCloseInactiveFor       = 12 * time.Hour
+	defaultStorageCheckInterval       = 24 * time.Hour
+	defaultIdempotencyCleanupInterval = time.Hour
+	defaultOutboxRelayInterval        = time.Second
+	defaultWebhookDeliveryInterval    = 5 * time.Second
+	defaultEventStreamPollInterval    = 500 * time.Millisecond
+)
+
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("Invalid duration in %s: %q", name, value)
+	}
+	return duration
+}
+
+func autoCloseReceptionsJob(receptionUseCase receptionUsecase.ReceptionUsecase) scheduler.Job {
+	inactiveFor := durationFromEnv("AUTO_CLOSE_INACTIVE_AFTER", defaultAutoCloseInactiveFor)
+
+	return scheduler.Job{
+		Name:     "auto_close_receptions",
+		Interval: durationFromEnv("AUTO_CLOSE_INTERVAL", defaultAutoCloseInterval),
+		LockKey:  autoCloseReceptionsLockKey,
+		Run: func(ctx context.Context) error {
+			closed, err := receptionUseCase.CloseStaleReceptions(ctx, inactiveFor)
+			for _, reception := range closed {
+				metrics.AmountOfAutoClosedReceptions.Inc()
+				logger.JobLogger.Info("reception auto-closed",
+					zap.String("reception_id", reception.Id),
+					zap.String("pvz_id", reception.PvzId),
+					zap.String("reason", reception.CloseReason),
+				)
+			}
+			return err
+		},
+	}
+}
+
+func returnOverdueProductsJob(productUseCase productUsecase.ProductUsecase) scheduler.Job {
+	return scheduler.Job{
+		Name:     "return_overdue_products",
+		Interval: durationFromEnv("STORAGE_CHECK_INTERVAL", defaultStorageCheckInterval),
+		LockKey:  returnOverdueProductsLockKey,
+		Run: func(ctx context.Context) error {
+			returned, err := productUseCase.ReturnOverdueProducts(ctx)
+			for _, event := range returned {
+				metrics.AmountOfReturnedToSenderProducts.Inc()
+				logger.JobLogger.Info("product returned to sender",
+					zap.String("product_id", event.ProductId),
+					zap.String("pvz_id", event.PvzId),
+				)
+			}
+			return err
+		},
+	}
+}
+
+func cleanupIdempotencyKeysJob(store *idempotency.Store) scheduler.Job {
+	return scheduler.Job{
+		Name:     "cleanup_idempotency_keys",
+		Interval: durationFromEnv("IDEMPOTENCY_CLEANUP_INTERVAL", defaultIdempotencyCleanupInterval),
+		LockKey:  cleanupIdempotencyKeysLockKey,
+		Run: func(ctx context.Context) error {
+			deleted, err := store.DeleteExpired(ctx, time.Now())
+			if deleted > 0 {
+				logger.JobLogger.Info("expired idempotency keys deleted", zap.Int64("count", deleted))
+			}
+			return err
+		},
+	}
+}
+
+// relayOutboxEventsJob публикует события outbox; advisory lock задачи гарантирует единственный relay,
+// без которого нельзя сохранить порядок событий ПВЗ.
+func relayOutboxEventsJob(relay *outbox.Relay) scheduler.Job {
+	return scheduler.Job{
+		Name:     "relay_outbox_events",
+		Interval: durationFromEnv("OUTBOX_RELAY_INTERVAL", defaultOutboxRelayInterval),
+		LockKey:  relayOutboxEventsLockKey,
+		Run: func(ctx context.Context) error {
+			published, err := relay.Relay(ctx)
+			if published > 0 {
+				logger.JobLogger.Info("outbox events published", zap.Int("count", published))
+			}
+			return err
+		},
+	}
+}
+
+// tailOutboxEventsJob отдаёт новые события outbox в SSE-хаб своего экземпляра. Задача локальная:
+// у каждого экземпляра свои подписчики, поэтому advisory lock ей не нужен.
+func tailOutboxEventsJob(tail *outbox.Tail) scheduler.Job {
+	return scheduler.Job{
+		Name:     "tail_outbox_events",
+		Interval: durationFromEnv("EVENT_STREAM_POLL_INTERVAL", defaultEventStreamPollInterval),
+		Local:    true,
+		Run: func(ctx context.Context) error {
+			_, err := tail.Poll(ctx)
+			return err
+		},
+	}
+}
+
+func deliverWebhooksJob(webhookUseCase webhookUsecase.WebhookUsecase) scheduler.Job {
+	return scheduler.Job{
+		Name:     "deliver_webhooks",
+		Interval: durationFromEnv("WEBHOOK_DELIVERY_INTERVAL", defaultWebhookDeliveryInterval),
+		LockKey:  deliverWebhooksLockKey,
+		Run: func(ctx context.Context) error {
+			processed, err := webhookUseCase.DeliverPending(ctx, webhookUsecase.DefaultDeliveryBatchSize)
+			for _, delivery := range processed {
+				fields := []zap.Field{
+					zap.String("delivery_id", delivery.Id),
+					zap.String("webhook_id", delivery.SubscriptionId),
+					zap.String("event_type", delivery.EventType),
+					zap.Int("attempts", delivery.Attempts),
+					zap.Int("status_code", delivery.LastStatusCode),
+				}
+				switch delivery.Status {
+				case models.WEBHOOK_DELIVERY_DELIVERED:
+					logger.JobLogger.Info("webhook delivered", fields...)
+				case models.WEBHOOK_DELIVERY_DEAD:
+					logger.JobLogger.Error("webhook delivery is dead", append(fields, zap.String("error", delivery.LastError))...)
+				default:
+					logger.JobLogger.Warn("webhook delivery failed, will retry",
+						append(fields, zap.String("error", delivery.LastError), zap.Time("next_attempt_at", delivery.NextAttemptAt))...)
+				}
+			}
+			return err
+		},
+	}
+}
@@ -4,30 +4,89 @@ import (
 	authController "avito_spring_staj_2025/internal/auth/handler"
 	authRepository "avito_spring_staj_2025/internal/auth/repository"
 	authUsecase "avito_spring_staj_2025/internal/auth/usecase"
+	damageController "avito_spring_staj_2025/internal/damage/handler"
+	damageRepository "avito_spring_staj_2025/internal/damage/repository"
+	damageUsecase "avito_spring_staj_2025/internal/damage/usecase"
 	"avito_spring_staj_2025/internal/db"
+	eventStreamController "avito_spring_staj_2025/internal/eventstream/handler"
+	eventStreamRepository "avito_spring_staj_2025/internal/eventstream/repository"
+	eventStreamUsecase "avito_spring_staj_2025/internal/eventstream/usecase"
 	"avito_spring_staj_2025/internal/pvz/handler/gen"
 	"avito_spring_staj_2025/internal/service/metrics"
 	"google.golang.org/grpc"
 	"net"
 
+	productController "avito_spring_staj_2025/internal/product/handler"
+	productRepository "avito_spring_staj_2025/internal/product/repository"
+	productUsecase "avito_spring_staj_2025/internal/product/usecase"
+	productTypeController "avito_spring_staj_2025/internal/producttype/handler"
+	productTypeRepository "avito_spring_staj_2025/internal/producttype/repository"
+	productTypeUsecase "avito_spring_staj_2025/internal/producttype/usecase"
 	pvzController "avito_spring_staj_2025/internal/pvz/handler"
 	pvzRepository "avito_spring_staj_2025/internal/pvz/repository"
 	pvzUsecase "avito_spring_staj_2025/internal/pvz/usecase"
 
+	exportController "avito_spring_staj_2025/internal/export/handler"
+	exportRepository "avito_spring_staj_2025/internal/export/repository"
+	exportUsecase "avito_spring_staj_2025/internal/export/usecase"
+	inventoryController "avito_spring_staj_2025/internal/inventory/handler"
+	inventoryRepository "avito_spring_staj_2025/internal/inventory/repository"
+	inventoryUsecase "avito_spring_staj_2025/internal/inventory/usecase"
 	receptionController "avito_spring_staj_2025/internal/reception/handler"
 	receptionRepository "avito_spring_staj_2025/internal/reception/repository"
 	receptionUsecase "avito_spring_staj_2025/internal/reception/usecase"
+	scheduleController "avito_spring_staj_2025/internal/schedule/handler"
+	scheduleRepository "avito_spring_staj_2025/internal/schedule/repository"
+	scheduleUsecase "avito_spring_staj_2025/internal/schedule/usecase"
+	"avito_spring_staj_2025/internal/service/blobstore"
+	"avito_spring_staj_2025/internal/service/eventhub"
+	"avito_spring_staj_2025/internal/service/idempotency"
 	"avito_spring_staj_2025/internal/service/jwt"
 	"avito_spring_staj_2025/internal/service/logger"
 	"avito_spring_staj_2025/internal/service/middleware"
+	"avito_spring_staj_2025/internal/service/outbox"
 	"avito_spring_staj_2025/internal/service/router"
+	"avito_spring_staj_2025/internal/service/scheduler"
+	"avito_spring_staj_2025/internal/service/webhookclient"
+	storageCellController "avito_spring_staj_2025/internal/storagecell/handler"
+	storageCellRepository "avito_spring_staj_2025/internal/storagecell/repository"
+	storageCellUsecase "avito_spring_staj_2025/internal/storagecell/usecase"
+	webhookController "avito_spring_staj_2025/internal/webhook/handler"
+	webhookRepository "avito_spring_staj_2025/internal/webhook/repository"
+	webhookUsecase "avito_spring_staj_2025/internal/webhook/usecase"
+	"context"
 	"fmt"
 	"github.com/joho/godotenv"
 	"log"
 	"net/http"
 	"os"
+	_ "time/tzdata"
 )
 
+const (
+	defaultDamagePhotosDir = "data/damage_photos"
+	defaultOutboxFile      = "data/outbox_events.jsonl"
+)
+
+func stringFromEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// newEventPublisher выбирает, куда relay отдаёт события outbox: в лог задач (по умолчанию) или в файл.
+func newEventPublisher() (outbox.Publisher, error) {
+	switch publisher := stringFromEnv("OUTBOX_PUBLISHER", "log"); publisher {
+	case "log":
+		return outbox.NewLogPublisher(logger.JobLogger), nil
+	case "file":
+		return outbox.NewFilePublisher(stringFromEnv("OUTBOX_FILE", defaultOutboxFile))
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", publisher)
+	}
+}
+
 func main() {
 	_ = godotenv.Load()
 	db := db.DbConnect()
@@ -52,14 +111,75 @@ func main() {
 	authUseCase := authUsecase.NewAuthUsecase(authRepository, jwtToken)
 	authHandler := authController.NewAuthHandler(authUseCase)
 
+	productTypeRepository := productTypeRepository.NewProductTypeRepository(db)
+	productTypeUseCase := productTypeUsecase.NewProductTypeUsecase(productTypeRepository)
+	productTypeHandler := productTypeController.NewProductTypeHandler(productTypeUseCase)
+
 	pvzRepository := pvzRepository.NewPvzRepository(db)
-	pvzUseCase := pvzUsecase.NewPvzUsecase(pvzRepository)
+	pvzUseCase := pvzUsecase.NewPvzUsecase(pvzRepository, productTypeUseCase)
 	pvzHandler := pvzController.NewPvzHandler(pvzUseCase)
 
+	scheduleRepository := scheduleRepository.NewScheduleRepository(db)
+	scheduleUseCase := scheduleUsecase.NewScheduleUsecase(scheduleRepository)
+	scheduleHandler := scheduleController.NewScheduleHandler(scheduleUseCase)
+
 	receptionRepository := receptionRepository.NewReceptionRepository(db)
-	receptionUseCase := receptionUsecase.NewReceptionUsecase(receptionRepository)
+	receptionUseCase := receptionUsecase.NewReceptionUsecase(receptionRepository, productTypeUseCase, scheduleUseCase)
 	receptionHandler := receptionController.NewReceptionHandler(receptionUseCase)
 
+	productRepository := productRepository.NewProductRepository(db)
+	productUseCase := productUsecase.NewProductUsecase(productRepository)
+	productHandler := productController.NewProductHandler(productUseCase)
+
+	exportRepository := exportRepository.NewExportRepository(db)
+	exportUseCase := exportUsecase.NewExportUsecase(exportRepository)
+	exportHandler := exportController.NewExportHandler(exportUseCase)
+
+	storageCellRepository := storageCellRepository.NewStorageCellRepository(db)
+	storageCellUseCase := storageCellUsecase.NewStorageCellUsecase(storageCellRepository)
+	storageCellHandler := storageCellController.NewStorageCellHandler(storageCellUseCase)
+
+	inventoryRepository := inventoryRepository.NewInventoryRepository(db)
+	inventoryUseCase := inventoryUsecase.NewInventoryUsecase(inventoryRepository)
+	inventoryHandler := inventoryController.NewInventoryHandler(inventoryUseCase)
+
+	damagePhotoStore, err := blobstore.NewLocalBlobStore(stringFromEnv("DAMAGE_PHOTOS_DIR", defaultDamagePhotosDir))
+	if err != nil {
+		log.Fatalf("Failed to create damage photo storage: %v", err)
+	}
+	damageRepository := damageRepository.NewDamageRepository(db)
+	damageUseCase := damageUsecase.NewDamageUsecase(damageRepository, damagePhotoStore)
+	damageHandler := damageController.NewDamageHandler(damageUseCase)
+
+	webhookRepository := webhookRepository.NewWebhookRepository(db)
+	webhookUseCase := webhookUsecase.NewWebhookUsecase(webhookRepository,
+		webhookclient.NewClient(durationFromEnv("WEBHOOK_TIMEOUT", webhookclient.DefaultTimeout)))
+	webhookHandler := webhookController.NewWebhookHandler(webhookUseCase)
+
+	eventHub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+	eventStreamRepository := eventStreamRepository.NewEventStreamRepository(db)
+	eventStreamUseCase := eventStreamUsecase.NewEventStreamUsecase(eventStreamRepository, eventHub)
+	eventStreamHandler := eventStreamController.NewEventStreamHandler(eventStreamUseCase)
+
+	idempotencyStore := idempotency.NewStore(db)
+
+	eventPublisher, err := newEventPublisher()
+	if err != nil {
+		log.Fatalf("Failed to create event publisher: %v", err)
+	}
+
+	jobScheduler := scheduler.NewScheduler(db)
+	jobScheduler.Add(autoCloseReceptionsJob(receptionUseCase))
+	jobScheduler.Add(returnOverdueProductsJob(productUseCase))
+	jobScheduler.Add(cleanupIdempotencyKeysJob(idempotencyStore))
+	// Вебхуки подключены к relay вторым publisher-ом: доставка заводится только для закоммиченного события и один раз.
+	relay := outbox.NewRelay(db, outbox.NewMultiPublisher(eventPublisher, webhookUseCase), outbox.DefaultRelayBatchSize)
+	jobScheduler.Add(relayOutboxEventsJob(relay))
+	// SSE-хаб живёт в памяти процесса, поэтому каждый экземпляр сам читает outbox, не дожидаясь relay.
+	jobScheduler.Add(tailOutboxEventsJob(outbox.NewTail(db, eventHub, outbox.DefaultRelayBatchSize)))
+	jobScheduler.Add(deliverWebhooksJob(webhookUseCase))
+	jobScheduler.Start(context.Background())
+
 	go func() {
 		lis, err := net.Listen("tcp", os.Getenv("GRPC_URL"))
 		if err != nil {
@@ -75,7 +195,7 @@ func main() {
 		}
 	}()
 
-	mainRouter := router.SetUpRoutes(authHandler, pvzHandler, receptionHandler, jwtToken)
+	mainRouter := router.SetUpRoutes(authHandler, pvzHandler, receptionHandler, scheduleHandler, exportHandler, productTypeHandler, productHandler, storageCellHandler, inventoryHandler, damageHandler, webhookHandler, eventStreamHandler, idempotencyStore, durationFromEnv("IDEMPOTENCY_KEY_TTL", middleware.DefaultIdempotencyKeyTTL), jwtToken)
 	mainRouter.Use(middleware.RequestIDMiddleware)
 	mainRouter.Use(middleware.RateLimitMiddleware)
 	http.Handle("/", middleware.EnableCORS(mainRouter))
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+const (
+	DAMAGE_SEVERITY_MINOR    = "minor"
+	DAMAGE_SEVERITY_MODERATE = "moderate"
+	DAMAGE_SEVERITY_SEVERE   = "severe"
+)
+
+const (
+	MAX_DAMAGE_DESCRIPTION_LENGTH = 2000
+	MAX_DAMAGE_PHOTOS             = 10
+	MAX_DAMAGE_PHOTO_SIZE         = 5 << 20
+)
+
+// DamagePhotoContentTypes — форматы фото, которые принимаются. Тип определяется по содержимому файла,
+// заголовку Content-Type клиента не доверяем.
+var DamagePhotoContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+func IsValidDamageSeverity(severity string) bool {
+	switch severity {
+	case DAMAGE_SEVERITY_MINOR, DAMAGE_SEVERITY_MODERATE, DAMAGE_SEVERITY_SEVERE:
+		return true
+	}
+	return false
+}
+
+// DamageReport — акт о повреждении товара или приёмки целиком. Заполнено ровно одно из ProductId и ReceptionId.
+type DamageReport struct {
+	Id          string
+	ProductId   string
+	ReceptionId string
+	PvzId       string
+	Description string
+	Severity    string
+	CreatedBy   string
+	CreatedAt   time.Time
+	Photos      []DamagePhoto
+}
+
+type DamagePhoto struct {
+	Id          string
+	ReportId    string
+	BlobKey     string
+	ContentType string
+	Size        int64
+	UploadedBy  string
+	UploadedAt  time.Time
+}
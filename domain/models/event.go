@@ -0,0 +1,106 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EVENT_PVZ_CREATED        = "PvzCreated"
+	EVENT_RECEPTION_OPENED   = "ReceptionOpened"
+	EVENT_PRODUCT_ADDED      = "ProductAdded"
+	EVENT_PRODUCT_DELETED    = "ProductDeleted"
+	EVENT_RECEPTION_CLOSED   = "ReceptionClosed"
+	EVENT_RECEPTION_REOPENED = "ReceptionReopened"
+)
+
+// ReceptionActivityEventTypes — события приёмок, которые отдаются в SSE-поток активности.
+var ReceptionActivityEventTypes = []string{
+	EVENT_RECEPTION_OPENED,
+	EVENT_PRODUCT_ADDED,
+	EVENT_PRODUCT_DELETED,
+	EVENT_RECEPTION_CLOSED,
+	EVENT_RECEPTION_REOPENED,
+}
+
+func IsReceptionActivityEvent(eventType string) bool {
+	for _, activityType := range ReceptionActivityEventTypes {
+		if eventType == activityType {
+			return true
+		}
+	}
+	return false
+}
+
+// MAX_EVENT_STREAM_REPLAY — сколько пропущенных событий поток догоняет по Last-Event-ID; если их больше,
+// клиенту отправляется reset и он перечитывает состояние целиком.
+const MAX_EVENT_STREAM_REPLAY = 1000
+
+// EventStreamFilter — область SSE-потока: один ПВЗ или все ПВЗ города.
+type EventStreamFilter struct {
+	PvzId string
+	City  string
+}
+
+// DomainEvent — событие из outbox. Seq растёт в порядке фиксации транзакций в пределах одного ПВЗ,
+// по нему подписчики восстанавливают порядок и отбрасывают дубли.
+type DomainEvent struct {
+	Id         string          `json:"id"`
+	Seq        int64           `json:"seq"`
+	Type       string          `json:"type"`
+	PvzId      string          `json:"pvzId"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurredAt"`
+}
+
+type PvzCreatedPayload struct {
+	PvzId            string    `json:"pvzId"`
+	City             string    `json:"city"`
+	Address          string    `json:"address,omitempty"`
+	RegistrationDate time.Time `json:"registrationDate"`
+}
+
+type ReceptionOpenedPayload struct {
+	ReceptionId string    `json:"receptionId"`
+	PvzId       string    `json:"pvzId"`
+	DateTime    time.Time `json:"dateTime"`
+}
+
+type ProductAddedPayload struct {
+	ProductId   string    `json:"productId"`
+	ReceptionId string    `json:"receptionId"`
+	PvzId       string    `json:"pvzId"`
+	Type        string    `json:"type"`
+	SeqNo       int64     `json:"seqNo"`
+	ExternalId  string    `json:"externalId,omitempty"`
+	DateTime    time.Time `json:"dateTime"`
+	// TransferId заполнен, если товар не отсканирован, а принят по перемещению с другого ПВЗ
+	TransferId string `json:"transferId,omitempty"`
+}
+
+type ProductDeletedPayload struct {
+	ProductId   string    `json:"productId"`
+	ReceptionId string    `json:"receptionId"`
+	PvzId       string    `json:"pvzId"`
+	Type        string    `json:"type"`
+	SeqNo       int64     `json:"seqNo"`
+	Kind        string    `json:"kind"`
+	Reason      string    `json:"reason,omitempty"`
+	DeletedAt   time.Time `json:"deletedAt"`
+}
+
+type ReceptionClosedPayload struct {
+	ReceptionId string    `json:"receptionId"`
+	PvzId       string    `json:"pvzId"`
+	AutoClosed  bool      `json:"autoClosed"`
+	CloseReason string    `json:"closeReason,omitempty"`
+	ClosedAt    time.Time `json:"closedAt"`
+}
+
+type ReceptionReopenedPayload struct {
+	ReceptionId string    `json:"receptionId"`
+	PvzId       string    `json:"pvzId"`
+	ReopenedBy  string    `json:"reopenedBy,omitempty"`
+	Reason      string    `json:"reason"`
+	ReopenedAt  time.Time `json:"reopenedAt"`
+}
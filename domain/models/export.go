@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+const (
+	EXPORT_ENTITY_PVZS       = "pvzs"
+	EXPORT_ENTITY_RECEPTIONS = "receptions"
+	EXPORT_ENTITY_PRODUCTS   = "products"
+)
+
+type ExportFilter struct {
+	From time.Time
+	To   time.Time
+	City string
+}
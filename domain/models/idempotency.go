@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	IDEMPOTENCY_STATUS_IN_PROGRESS = "in_progress"
+	IDEMPOTENCY_STATUS_COMPLETED   = "completed"
+)
+
+const MAX_IDEMPOTENCY_KEY_LENGTH = 255
+
+// IdempotencyRecord — запрос с заголовком Idempotency-Key и, когда он выполнен, сохранённый ответ на него.
+// RequestHash нужен, чтобы тот же ключ нельзя было переиспользовать для другого запроса.
+// LockedUntil — аренда ключа выполняющимся запросом: пока запрос жив, он её продлевает, а ключ с истёкшей арендой
+// (экземпляр упал посреди запроса) может занять повтор.
+type IdempotencyRecord struct {
+	Scope               string
+	Key                 string
+	RequestHash         string
+	Status              string
+	ResponseStatus      int
+	ResponseContentType string
+	ResponseBody        []byte
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+	LockedUntil         time.Time
+}
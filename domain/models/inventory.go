@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+const (
+	INVENTORY_STATUS_IN_PROGRESS = "in_progress"
+	INVENTORY_STATUS_COMPLETED   = "completed"
+	INVENTORY_STATUS_APPROVED    = "approved"
+)
+
+const (
+	INVENTORY_DISCREPANCY_MISSING = "missing"
+	INVENTORY_DISCREPANCY_SURPLUS = "surplus"
+)
+
+const (
+	MAX_INVENTORY_CODE_LENGTH    = 128
+	MAX_INVENTORY_COMMENT_LENGTH = 1000
+)
+
+// InventorySession — инвентаризация ПВЗ: сотрудник сканирует всё, что лежит на полках,
+// завершает пересчёт, и система сверяет отсканированное с товарами, которые числятся на ПВЗ.
+// Итог подписывает модератор.
+type InventorySession struct {
+	Id              string
+	PvzId           string
+	Status          string
+	StartedBy       string
+	StartedAt       time.Time
+	CompletedAt     *time.Time
+	ApprovedBy      string
+	ApprovedAt      *time.Time
+	ApprovalComment string
+	// Report есть только у завершённых инвентаризаций
+	Report *InventoryReport
+}
+
+// InventoryScan — один отсканированный код. ProductId пуст, если код не совпал ни с одним товаром ПВЗ.
+type InventoryScan struct {
+	Id        string
+	SessionId string
+	Code      string
+	ProductId string
+	ScannedBy string
+	ScannedAt time.Time
+}
+
+// InventoryProduct — товар, который по данным системы лежит на ПВЗ.
+type InventoryProduct struct {
+	ProductId  string
+	Type       string
+	ExternalId string
+	CellCode   string
+}
+
+// InventoryProductMatch — товар ПВЗ, на который указывает отсканированный код.
+type InventoryProductMatch struct {
+	ProductId string
+	Scanned   bool
+}
+
+// InventoryReport — сверка инвентаризации: Missing числятся на ПВЗ, но не найдены,
+// Surplus отсканированы, но не числятся на ПВЗ.
+type InventoryReport struct {
+	ExpectedCount int
+	ScannedCount  int
+	MatchedCount  int
+	Missing       []InventoryProduct
+	Surplus       []InventoryScan
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const MAX_MANIFEST_SIZE = 5000
+
+// Виды расхождений между манифестом поставки и фактически принятыми товарами.
+const (
+	DISCREPANCY_MISSING       = "missing"
+	DISCREPANCY_UNEXPECTED    = "unexpected"
+	DISCREPANCY_DUPLICATED    = "duplicated"
+	DISCREPANCY_TYPE_MISMATCH = "type_mismatch"
+)
+
+// ManifestItem — позиция ожидаемой поставки: что должна привезти машина в рамках приёмки.
+type ManifestItem struct {
+	Id          string
+	ReceptionId string
+	ExternalId  string
+	Type        string
+}
+
+// DiscrepancyItem — одно расхождение. Для missing заполнен только ожидаемый тип,
+// для unexpected — только фактический, ProductId указывает на принятый товар, если он есть.
+type DiscrepancyItem struct {
+	Kind         string
+	ExternalId   string
+	ExpectedType string
+	ActualType   string
+	ProductId    string
+}
+
+// DiscrepancyReport — отчёт о расхождениях, который строится при закрытии приёмки с манифестом.
+type DiscrepancyReport struct {
+	ReceptionId   string
+	CreatedAt     time.Time
+	ExpectedCount int
+	ReceivedCount int
+	MatchedCount  int
+	Items         []DiscrepancyItem
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PICKUP_CODE_LENGTH = 6
+	// MAX_PICKUP_CODE_ATTEMPTS — после стольких неверных вводов код блокируется, и клиенту нужно выпустить новый.
+	MAX_PICKUP_CODE_ATTEMPTS = 5
+)
+
+// OrderOwner связывает заказ во внешней системе (external_id товара) с клиентом, который может его забрать.
+type OrderOwner struct {
+	ExternalId string
+	CustomerId string
+	CreatedAt  time.Time
+}
+
+// PickupCode — код выдачи товара клиенту. Code пуст, если код для товара ещё не выпускался.
+type PickupCode struct {
+	ProductId      string
+	ExternalId     string
+	PvzId          string
+	Code           string
+	FailedAttempts int
+	CreatedAt      time.Time
+	UsedAt         *time.Time
+}
+
+func (c PickupCode) Locked() bool {
+	return c.FailedAttempts >= MAX_PICKUP_CODE_ATTEMPTS
+}
+
+func (c PickupCode) AttemptsLeft() int {
+	return max(MAX_PICKUP_CODE_ATTEMPTS-c.FailedAttempts, 0)
+}
+
+// QrPayload — содержимое QR-кода: сканер сотрудника получает из него и товар, и код.
+func (c PickupCode) QrPayload() string {
+	return fmt.Sprintf("pvz-pickup:%s:%s", c.ProductId, c.Code)
+}
@@ -2,15 +2,123 @@ package models
 
 import "time"
 
+// Коды типов товаров, заведённые в справочнике product_types миграцией.
 const (
 	ELECTRONIC_TYPE = "электроника"
 	CLOTHES_TYPE    = "одежда"
 	BOOTS_TYPE      = "обувь"
 )
 
+const MAX_PRODUCT_BATCH_SIZE = 1000
+
+const (
+	PRODUCT_DELETION_UNDO   = "undo"
+	PRODUCT_DELETION_REMOVE = "remove"
+)
+
+// Статусы жизненного цикла товара: принят в приёмке, выдан клиенту, возвращён клиентом, не выкуплен при выдаче,
+// не забран за срок хранения и ждёт отправки обратно отправителю или едет на другой ПВЗ.
+const (
+	PRODUCT_STATUS_RECEIVED         = "received"
+	PRODUCT_STATUS_ISSUED           = "issued"
+	PRODUCT_STATUS_RETURNED         = "returned"
+	PRODUCT_STATUS_REFUSED          = "refused"
+	PRODUCT_STATUS_RETURN_TO_SENDER = "return_to_sender"
+	PRODUCT_STATUS_IN_TRANSIT       = "in_transit"
+)
+
+// SYSTEM_ACTOR записывается в журнал вместо пользователя, если статус сменил фоновый обработчик.
+const SYSTEM_ACTOR = "system"
+
+const STORAGE_EXPIRED_REASON = "storage period expired"
+
+// productStatusTransitions — допустимые переходы между статусами товара.
+// Не выкупленный и возвращённый клиентом товар остаётся на ПВЗ, пока его не отправят обратно отправителю.
+var productStatusTransitions = map[string][]string{
+	PRODUCT_STATUS_RECEIVED:   {PRODUCT_STATUS_ISSUED, PRODUCT_STATUS_REFUSED, PRODUCT_STATUS_RETURN_TO_SENDER, PRODUCT_STATUS_IN_TRANSIT},
+	PRODUCT_STATUS_ISSUED:     {PRODUCT_STATUS_RETURNED},
+	PRODUCT_STATUS_REFUSED:    {PRODUCT_STATUS_RETURN_TO_SENDER},
+	PRODUCT_STATUS_RETURNED:   {PRODUCT_STATUS_RETURN_TO_SENDER},
+	PRODUCT_STATUS_IN_TRANSIT: {PRODUCT_STATUS_RECEIVED},
+}
+
+// ProductStoredStatuses — статусы, в которых товар хранится на ПВЗ со сроком хранения;
+// по его истечении товар уходит обратно отправителю.
+var ProductStoredStatuses = []string{
+	PRODUCT_STATUS_RECEIVED,
+	PRODUCT_STATUS_REFUSED,
+	PRODUCT_STATUS_RETURNED,
+}
+
+// CanChangeProductStatus проверяет, разрешён ли переход товара из статуса from в статус to.
+func CanChangeProductStatus(from, to string) bool {
+	for _, allowed := range productStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
 type Product struct {
 	Id          string
 	DateTime    time.Time
 	Type        string
 	ReceptionId string
+	// SeqNo — порядковый номер товара внутри приёмки, монотонно растёт и не переиспользуется
+	SeqNo int64 `json:",omitempty"`
+	// ExternalId — идентификатор посылки во внешней системе (штрихкод или номер заказа), может отсутствовать
+	ExternalId string `json:",omitempty"`
+	// OverCapacity выставляется, если товар принят сверх вместимости ПВЗ с политикой warn
+	OverCapacity bool `json:"-"`
+	// CellId и CellCode — ячейка хранения, если её назначили при сканировании
+	CellId   string `json:"-"`
+	CellCode string `json:"-"`
+}
+
+// ProductLookup — товар, найденный по внешнему идентификатору, вместе с приёмкой, в которой он лежит.
+type ProductLookup struct {
+	Product         Product
+	PvzId           string
+	ReceptionStatus string
+}
+
+// ProductDeletion — запись журнала удалений товаров из активной приёмки.
+// Kind различает отмену последних сканирований (undo) и точечное удаление с причиной (remove).
+type ProductDeletion struct {
+	Id          string
+	ProductId   string
+	ReceptionId string
+	SeqNo       int64
+	Type        string
+	ExternalId  string
+	Kind        string
+	Reason      string
+	DeletedBy   string
+	DeletedAt   time.Time
+}
+
+// ProductState — товар вместе с текущим статусом и приёмкой, в которой он был принят.
+type ProductState struct {
+	Product         Product
+	Status          string
+	StatusChangedAt *time.Time
+	PvzId           string
+	ReceptionStatus string
+	// CustomerId — владелец заказа; если он известен, выдать товар можно только по коду выдачи
+	CustomerId string
+	// StorageDeadline — до какого момента товар ждёт клиента; назначается при закрытии приёмки
+	StorageDeadline *time.Time
+}
+
+// ProductStatusEvent — запись журнала смены статуса товара: кто, где, когда и почему.
+type ProductStatusEvent struct {
+	Id         string
+	ProductId  string
+	PvzId      string
+	FromStatus string
+	ToStatus   string
+	Reason     string
+	ChangedBy  string
+	ChangedAt  time.Time
 }
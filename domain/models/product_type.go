@@ -0,0 +1,21 @@
+package models
+
+// ProductType — запись справочника типов товаров. Code хранится в products.type и не меняется,
+// названия можно править, а неактивный тип нельзя использовать для новых товаров.
+type ProductType struct {
+	Code   string
+	NameRu string
+	NameEn string
+	Active bool
+	// StorageDays — сколько дней товар этого типа ждёт клиента после закрытия приёмки
+	StorageDays int
+	// SizeCategory — размер товара для подбора ячейки хранения (s, m, l)
+	SizeCategory string
+}
+
+const MAX_PRODUCT_TYPE_CODE_LENGTH = 64
+
+const (
+	DEFAULT_STORAGE_DAYS = 7
+	MAX_STORAGE_DAYS     = 90
+)
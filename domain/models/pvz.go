@@ -2,9 +2,58 @@ package models
 
 import "time"
 
+const (
+	CAPACITY_POLICY_REJECT = "reject"
+	CAPACITY_POLICY_WARN   = "warn"
+)
+
+const (
+	IMPORT_MODE_PARTIAL        = "partial"
+	IMPORT_MODE_ALL_OR_NOTHING = "all_or_nothing"
+)
+
+const (
+	PVZ_SORT_REGISTRATION_DATE = "registrationDate"
+	PVZ_SORT_CITY              = "city"
+	PVZ_SORT_LAST_RECEPTION_AT = "lastReceptionAt"
+	SORT_ORDER_ASC             = "asc"
+	SORT_ORDER_DESC            = "desc"
+)
+
+// PvzFilter описывает фильтры и сортировку списка ПВЗ.
+// Период From-To ограничивает дату регистрации ПВЗ и, если задан ProductType, дату приёмки товара.
+type PvzFilter struct {
+	From        time.Time
+	To          time.Time
+	Cities      []string
+	InProgress  *bool
+	ProductType string
+	SortBy      string
+	SortOrder   string
+}
+
 type Pvz struct {
 	Id               string
 	RegistrationDate time.Time
 	City             string
+	Address          string      `json:",omitempty"`
+	Capacity         *int        `json:"-"`
+	CapacityPolicy   string      `json:"-"`
+	Occupancy        int         `json:"-"`
 	Receptions       []Reception `json:"-"`
 }
+
+type PvzImportRowError struct {
+	Line   int
+	Id     string
+	Errors []string
+}
+
+type PvzImportReport struct {
+	DryRun    bool
+	Mode      string
+	Total     int
+	Valid     int
+	Inserted  int
+	RowErrors []PvzImportRowError
+}
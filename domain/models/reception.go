@@ -8,9 +8,36 @@ const (
 )
 
 type Reception struct {
-	Id       string
-	DateTime time.Time
-	PvzId    string
-	Status   string
-	Products []Product `json:"-"`
+	Id                  string
+	DateTime            time.Time
+	PvzId               string
+	Status              string
+	OutsideWorkingHours bool      `json:",omitempty"`
+	AutoClosed          bool      `json:",omitempty"`
+	CloseReason         string    `json:",omitempty"`
+	Products            []Product `json:"-"`
+	// DiscrepancyReport заполняется при закрытии приёмки, к которой приложен манифест
+	DiscrepancyReport *DiscrepancyReport `json:"-"`
+	// Summary — итоги приёмки, есть только у закрытых
+	Summary *ReceptionSummary `json:"-"`
+}
+
+// ReceptionSummary — итоги приёмки, которые считаются и сохраняются при её закрытии.
+// При повторном закрытии после переоткрытия итоги пересчитываются.
+type ReceptionSummary struct {
+	ReceptionId    string
+	ClosedAt       time.Time
+	TotalProducts  int
+	ProductsByType map[string]int
+	OpenDuration   time.Duration
+	DeletionsCount int
+}
+
+// ReceptionReopen — запись журнала повторных открытий закрытой приёмки модератором.
+type ReceptionReopen struct {
+	Id          string
+	ReceptionId string
+	ReopenedBy  string
+	ReopenedAt  time.Time
+	Reason      string
 }
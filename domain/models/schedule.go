@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	WORKING_HOURS_POLICY_NONE   = "none"
+	WORKING_HOURS_POLICY_REJECT = "reject"
+	WORKING_HOURS_POLICY_FLAG   = "flag"
+)
+
+const (
+	DEFAULT_TIME_ZONE = "Europe/Moscow"
+	DATE_LAYOUT       = "2006-01-02"
+)
+
+type WorkingHours struct {
+	Weekday   int
+	OpenTime  string
+	CloseTime string
+}
+
+type ScheduleException struct {
+	Id        string
+	PvzId     string
+	Date      time.Time
+	IsClosed  bool
+	OpenTime  string
+	CloseTime string
+	Reason    string
+}
+
+type PvzSchedule struct {
+	PvzId        string
+	TimeZone     string
+	Policy       string
+	WorkingHours []WorkingHours
+	Exceptions   []ScheduleException
+}
+
+// ParseClock принимает время в формате "15:04" или "15:04:05" (так его отдаёт Postgres для TIME)
+// и возвращает количество минут от начала суток. "24:00" означает конец суток и годится только для закрытия.
+func ParseClock(value string) (int, error) {
+	if value == "24:00" || value == "24:00:00" {
+		return 24 * 60, nil
+	}
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t.Hour()*60 + t.Minute(), nil
+		}
+	}
+	return 0, errors.New("invalid working hours")
+}
+
+// IsOpenAt проверяет, работает ли ПВЗ в момент at по его локальному времени.
+// Исключения (праздники, временное закрытие) имеют приоритет над недельным расписанием.
+// Если недельное расписание не задано, ПВЗ считается работающим круглосуточно.
+func (s PvzSchedule) IsOpenAt(at time.Time) (bool, error) {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return false, errors.New("invalid time zone")
+	}
+	local := at.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+
+	for _, exception := range s.Exceptions {
+		if exception.Date.Format(DATE_LAYOUT) != local.Format(DATE_LAYOUT) {
+			continue
+		}
+		if exception.IsClosed {
+			return false, nil
+		}
+		return withinHours(minutes, exception.OpenTime, exception.CloseTime)
+	}
+
+	if len(s.WorkingHours) == 0 {
+		return true, nil
+	}
+	for _, hours := range s.WorkingHours {
+		if hours.Weekday == int(local.Weekday()) {
+			return withinHours(minutes, hours.OpenTime, hours.CloseTime)
+		}
+	}
+	return false, nil
+}
+
+func withinHours(minutes int, openTime, closeTime string) (bool, error) {
+	open, err := ParseClock(openTime)
+	if err != nil {
+		return false, err
+	}
+	closing, err := ParseClock(closeTime)
+	if err != nil {
+		return false, err
+	}
+	return minutes >= open && minutes < closing, nil
+}
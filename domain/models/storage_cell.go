@@ -0,0 +1,86 @@
+package models
+
+import "errors"
+
+// Размеры ячеек хранения и товаров: в ячейку помещаются товары её размера и меньше.
+const (
+	CELL_SIZE_S = "s"
+	CELL_SIZE_M = "m"
+	CELL_SIZE_L = "l"
+)
+
+const DEFAULT_SIZE_CATEGORY = CELL_SIZE_M
+
+const (
+	MAX_STORAGE_CELLS    = 1000
+	MAX_CELL_CODE_LENGTH = 32
+)
+
+var cellSizeRank = map[string]int{
+	CELL_SIZE_S: 1,
+	CELL_SIZE_M: 2,
+	CELL_SIZE_L: 3,
+}
+
+func IsValidCellSize(size string) bool {
+	_, ok := cellSizeRank[size]
+	return ok
+}
+
+// StorageCell — ячейка хранения на полке ПВЗ. Occupied — сколько товаров в ней лежит сейчас.
+type StorageCell struct {
+	Id       string
+	PvzId    string
+	Code     string
+	Size     string
+	Capacity int
+	Occupied int
+}
+
+// Fits сообщает, помещается ли в ячейку товар размера size.
+func (c StorageCell) Fits(size string) bool {
+	return cellSizeRank[c.Size] >= cellSizeRank[size]
+}
+
+func (c StorageCell) Full() bool {
+	return c.Occupied >= c.Capacity
+}
+
+// CanHold проверяет, что в ячейку можно положить ещё один товар размера size.
+func (c StorageCell) CanHold(size string) error {
+	if !c.Fits(size) {
+		return errors.New("cell is too small")
+	}
+	if c.Full() {
+		return errors.New("cell is full")
+	}
+	return nil
+}
+
+// ProductLocation — где лежит товар: ПВЗ его приёмки и ячейка, если она назначена.
+type ProductLocation struct {
+	ProductId    string
+	Type         string
+	Status       string
+	PvzId        string
+	SizeCategory string
+	Cell         *StorageCell
+}
+
+// ProductAtPvzStatuses — статусы, в которых товар физически лежит на ПВЗ.
+var ProductAtPvzStatuses = []string{
+	PRODUCT_STATUS_RECEIVED,
+	PRODUCT_STATUS_RETURNED,
+	PRODUCT_STATUS_REFUSED,
+	PRODUCT_STATUS_RETURN_TO_SENDER,
+}
+
+// IsProductAtPvz сообщает, лежит ли товар в этом статусе физически на ПВЗ.
+func IsProductAtPvz(status string) bool {
+	for _, atPvz := range ProductAtPvzStatuses {
+		if status == atPvz {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	TRANSFER_STATUS_IN_TRANSIT = "in_transit"
+	TRANSFER_STATUS_ACCEPTED   = "accepted"
+)
+
+// Transfer — перемещение товаров с одного ПВЗ на другой. Пока перемещение не принято,
+// товары числятся в пути и не лежат ни на одном ПВЗ; при приёме они попадают в активную
+// приёмку ПВЗ назначения (ReceptionId).
+type Transfer struct {
+	Id               string
+	SourcePvzId      string
+	DestinationPvzId string
+	Status           string
+	CreatedBy        string
+	CreatedAt        time.Time
+	AcceptedBy       string
+	AcceptedAt       *time.Time
+	ReceptionId      string
+	ProductIds       []string
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+const (
+	WEBHOOK_DELIVERY_PENDING   = "pending"
+	WEBHOOK_DELIVERY_DELIVERED = "delivered"
+	WEBHOOK_DELIVERY_DEAD      = "dead"
+)
+
+const (
+	MAX_WEBHOOK_URL_LENGTH    = 2048
+	MIN_WEBHOOK_SECRET_LENGTH = 16
+	MAX_WEBHOOK_SECRET_LENGTH = 256
+	// MAX_WEBHOOK_ATTEMPTS — после стольких неудачных попыток доставка переходит в dead
+	MAX_WEBHOOK_ATTEMPTS             = 8
+	WEBHOOK_RETRY_BASE_DELAY         = 30 * time.Second
+	WEBHOOK_RETRY_MAX_DELAY          = time.Hour
+	DEFAULT_WEBHOOK_DELIVERIES_LIMIT = 50
+	MAX_WEBHOOK_DELIVERIES_LIMIT     = 500
+)
+
+// WebhookEventTypes — события outbox, на которые можно подписаться.
+var WebhookEventTypes = map[string]bool{
+	EVENT_PVZ_CREATED:        true,
+	EVENT_RECEPTION_OPENED:   true,
+	EVENT_PRODUCT_ADDED:      true,
+	EVENT_PRODUCT_DELETED:    true,
+	EVENT_RECEPTION_CLOSED:   true,
+	EVENT_RECEPTION_REOPENED: true,
+}
+
+func IsValidWebhookDeliveryStatus(status string) bool {
+	return status == WEBHOOK_DELIVERY_PENDING || status == WEBHOOK_DELIVERY_DELIVERED || status == WEBHOOK_DELIVERY_DEAD
+}
+
+// WebhookSubscription — подписка партнёра. Пустой PvzIds означает все ПВЗ.
+type WebhookSubscription struct {
+	Id         string
+	Url        string
+	Secret     string
+	EventTypes []string
+	PvzIds     []string
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+// WebhookDelivery — доставка одного события одной подписке. Payload — тело запроса, оно же подписывается.
+type WebhookDelivery struct {
+	Id             string
+	SubscriptionId string
+	EventId        string
+	EventType      string
+	PvzId          string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// DueWebhookDelivery — доставка, которую пора отправить, вместе с адресом и секретом подписки.
+type DueWebhookDelivery struct {
+	Delivery WebhookDelivery
+	Url      string
+	Secret   string
+}
@@ -21,6 +21,7 @@ type CreatePvzRequest struct {
 	Id               string    `json:"id"`
 	RegistrationDate time.Time `json:"registrationDate"`
 	City             string    `json:"city"`
+	Address          string    `json:"address,omitempty"`
 }
 
 type CreateReceptionRequest struct {
@@ -35,8 +36,74 @@ type GetPvzInfoQueries struct {
 }
 
 type AddProductRequest struct {
-	Type  string `json:"type"`
-	PvzId string `json:"pvzId"`
+	Type       string `json:"type"`
+	PvzId      string `json:"pvzId"`
+	ExternalId string `json:"externalId,omitempty"`
+	// CellCode — ячейка хранения, в которую сразу кладут товар
+	CellCode string `json:"cellCode,omitempty"`
+}
+
+type BatchProductItem struct {
+	Type       string `json:"type"`
+	ExternalId string `json:"externalId,omitempty"`
+}
+
+type AddProductsBatchRequest struct {
+	PvzId    string             `json:"pvzId"`
+	Products []BatchProductItem `json:"products"`
+}
+
+type ManifestItemRequest struct {
+	Type       string `json:"type"`
+	ExternalId string `json:"externalId"`
+}
+
+type SetManifestRequest struct {
+	Items []ManifestItemRequest `json:"items"`
+}
+
+type RemoveProductRequest struct {
+	ProductId string `json:"productId"`
+	Reason    string `json:"reason"`
+}
+
+// ChangeProductStatusRequest — выдача товара клиенту, отказ от него или возврат. Причина обязательна для отказа и возврата.
+type ChangeProductStatusRequest struct {
+	ProductId string `json:"productId"`
+	Reason    string `json:"reason"`
+}
+
+type SetOrderCustomerRequest struct {
+	CustomerId string `json:"customerId"`
+}
+
+// PickupProductRequest — выдача товара по коду клиента; productId и code сканируются из QR или вводятся вручную.
+type PickupProductRequest struct {
+	ProductId string `json:"productId"`
+	Code      string `json:"code"`
+}
+
+type CreateProductTypeRequest struct {
+	Code   string `json:"code"`
+	NameRu string `json:"nameRu"`
+	NameEn string `json:"nameEn"`
+	Active *bool  `json:"active,omitempty"`
+	// StorageDays по умолчанию равен models.DEFAULT_STORAGE_DAYS
+	StorageDays *int `json:"storageDays,omitempty"`
+	// SizeCategory по умолчанию равен models.DEFAULT_SIZE_CATEGORY
+	SizeCategory *string `json:"sizeCategory,omitempty"`
+}
+
+type UpdateProductTypeRequest struct {
+	NameRu       *string `json:"nameRu,omitempty"`
+	NameEn       *string `json:"nameEn,omitempty"`
+	Active       *bool   `json:"active,omitempty"`
+	StorageDays  *int    `json:"storageDays,omitempty"`
+	SizeCategory *string `json:"sizeCategory,omitempty"`
+}
+
+type ReopenReceptionRequest struct {
+	Reason string `json:"reason"`
 }
 
 type GetPvzListRequest struct {
@@ -45,3 +112,95 @@ type GetPvzListRequest struct {
 	Limit     int    `json:"limit"`
 	Offset    int    `json:"offset"`
 }
+
+// WorkingHoursItem — часы работы в один день недели (0 — воскресенье) по местному времени ПВЗ, "HH:MM".
+// Смены через полночь не поддерживаются: работу до конца суток задают закрытием в "24:00",
+// а продолжение ночью — часами следующего дня с "00:00".
+type WorkingHoursItem struct {
+	Weekday   int    `json:"weekday"`
+	OpenTime  string `json:"openTime"`
+	CloseTime string `json:"closeTime"`
+}
+
+type SetWorkingHoursRequest struct {
+	TimeZone     string             `json:"timeZone"`
+	Policy       string             `json:"policy"`
+	WorkingHours []WorkingHoursItem `json:"workingHours"`
+}
+
+type AddScheduleExceptionRequest struct {
+	Date      string `json:"date"`
+	IsClosed  bool   `json:"isClosed"`
+	OpenTime  string `json:"openTime"`
+	CloseTime string `json:"closeTime"`
+	Reason    string `json:"reason"`
+}
+
+type SetPvzCapacityRequest struct {
+	Capacity *int   `json:"capacity"`
+	Policy   string `json:"policy"`
+}
+
+type ImportPvzRow struct {
+	Line             int
+	Id               string
+	RegistrationDate string
+	City             string
+	Address          string
+	// ParseError заполняется, если строку CSV не удалось разобрать на колонки
+	ParseError string
+}
+
+type ImportPvzsRequest struct {
+	Rows   []ImportPvzRow
+	DryRun bool
+	Mode   string
+}
+
+type CreateTransferRequest struct {
+	DestinationPvzId string   `json:"destinationPvzId"`
+	ProductIds       []string `json:"productIds"`
+}
+
+type StorageCellRequest struct {
+	Code string `json:"code"`
+	Size string `json:"size"`
+	// Capacity по умолчанию равна 1
+	Capacity *int `json:"capacity,omitempty"`
+}
+
+type SetStorageCellsRequest struct {
+	Cells []StorageCellRequest `json:"cells"`
+}
+
+type AssignCellRequest struct {
+	ProductId string `json:"productId"`
+	// CellCode можно не указывать: тогда товар кладётся в подобранную свободную ячейку
+	CellCode string `json:"cellCode,omitempty"`
+}
+
+type InventoryScanRequest struct {
+	// Code — id товара или его штрихкод
+	Code string `json:"code"`
+}
+
+type ApproveInventoryRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+type CreateDamageReportRequest struct {
+	// Заполняется ровно одно из ProductId и ReceptionId
+	ProductId   string `json:"productId,omitempty"`
+	ReceptionId string `json:"receptionId,omitempty"`
+	Description string `json:"description"`
+	Severity    string `json:"severity"`
+}
+
+type CreateWebhookRequest struct {
+	Url        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	// PvzIds можно не указывать — тогда приходят события всех ПВЗ
+	PvzIds []string `json:"pvzIds,omitempty"`
+	// Secret можно не указывать — тогда он будет сгенерирован и возвращён в ответе
+	Secret string `json:"secret,omitempty"`
+}
@@ -15,35 +15,387 @@ type CreatePvzResponse struct {
 	Id               string    `json:"id"`
 	RegistrationDate time.Time `json:"registrationDate"`
 	City             string    `json:"city"`
+	Address          string    `json:"address,omitempty"`
 }
 
 type CreateReceptionResponse struct {
-	Id       string    `json:"id"`
-	DateTime time.Time `json:"dateTime"`
-	PvzId    string    `json:"pvzId"`
-	Status   string    `json:"status"`
+	Id                  string    `json:"id"`
+	DateTime            time.Time `json:"dateTime"`
+	PvzId               string    `json:"pvzId"`
+	Status              string    `json:"status"`
+	OutsideWorkingHours bool      `json:"outsideWorkingHours,omitempty"`
 }
 
 type AddProductResponse struct {
-	Id          string    `json:"id"`
-	DateTime    time.Time `json:"dateTime"`
-	Type        string    `json:"type"`
-	ReceptionId string    `json:"receptionId"`
+	Id           string    `json:"id"`
+	DateTime     time.Time `json:"dateTime"`
+	Type         string    `json:"type"`
+	ReceptionId  string    `json:"receptionId"`
+	SeqNo        int64     `json:"seqNo,omitempty"`
+	ExternalId   string    `json:"externalId,omitempty"`
+	OverCapacity bool      `json:"overCapacity,omitempty"`
+	CellCode     string    `json:"cellCode,omitempty"`
+}
+
+type AddProductsBatchResponse struct {
+	Products []AddProductResponse `json:"products"`
+}
+
+type ProductTypeResponse struct {
+	Code         string `json:"code"`
+	NameRu       string `json:"nameRu"`
+	NameEn       string `json:"nameEn"`
+	Active       bool   `json:"active"`
+	StorageDays  int    `json:"storageDays"`
+	SizeCategory string `json:"sizeCategory"`
+}
+
+type GetProductTypesResponse struct {
+	ProductTypes []ProductTypeResponse `json:"productTypes"`
+}
+
+type GetProductsByBarcodeResponse struct {
+	Products []ProductLookupResponse `json:"products"`
+}
+
+type ProductLookupResponse struct {
+	Id              string    `json:"id"`
+	DateTime        time.Time `json:"dateTime"`
+	Type            string    `json:"type"`
+	ExternalId      string    `json:"externalId"`
+	SeqNo           int64     `json:"seqNo"`
+	ReceptionId     string    `json:"receptionId"`
+	ReceptionStatus string    `json:"receptionStatus"`
+	PvzId           string    `json:"pvzId"`
+}
+
+type ProductStatusEventResponse struct {
+	ProductId  string    `json:"productId"`
+	PvzId      string    `json:"pvzId"`
+	FromStatus string    `json:"fromStatus"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason,omitempty"`
+	ChangedBy  string    `json:"changedBy"`
+	ChangedAt  time.Time `json:"changedAt"`
+}
+
+type ProductHistoryResponse struct {
+	ProductId       string                       `json:"productId"`
+	Type            string                       `json:"type"`
+	ExternalId      string                       `json:"externalId,omitempty"`
+	Status          string                       `json:"status"`
+	ReceivedAt      time.Time                    `json:"receivedAt"`
+	StorageDeadline *time.Time                   `json:"storageDeadline,omitempty"`
+	Events          []ProductStatusEventResponse `json:"events"`
+}
+
+type OverdueProductResponse struct {
+	ProductId       string     `json:"productId"`
+	Type            string     `json:"type"`
+	ExternalId      string     `json:"externalId,omitempty"`
+	ReceptionId     string     `json:"receptionId"`
+	Status          string     `json:"status"`
+	ReceivedAt      time.Time  `json:"receivedAt"`
+	StorageDeadline *time.Time `json:"storageDeadline"`
+}
+
+type GetOverdueProductsResponse struct {
+	Products []OverdueProductResponse `json:"products"`
+}
+
+type OrderCustomerResponse struct {
+	ExternalId string `json:"externalId"`
+	CustomerId string `json:"customerId"`
+}
+
+type PickupCodeResponse struct {
+	ProductId    string `json:"productId"`
+	ExternalId   string `json:"externalId"`
+	PvzId        string `json:"pvzId"`
+	Code         string `json:"code"`
+	QrPayload    string `json:"qrPayload"`
+	AttemptsLeft int    `json:"attemptsLeft"`
+}
+
+type GetPickupCodesResponse struct {
+	PickupCodes []PickupCodeResponse `json:"pickupCodes"`
+}
+
+type DeleteProductsResponse struct {
+	Deleted []DeletedProductResponse `json:"deleted"`
+}
+
+type DeletedProductResponse struct {
+	Id    string `json:"id"`
+	SeqNo int64  `json:"seqNo"`
+	Type  string `json:"type"`
 }
 
 type CloseReceptionResponse struct {
-	Id       string    `json:"id"`
-	DateTime time.Time `json:"dateTime"`
-	PvzId    string    `json:"pvzId"`
-	Status   string    `json:"status"`
+	Id                string                     `json:"id"`
+	DateTime          time.Time                  `json:"dateTime"`
+	PvzId             string                     `json:"pvzId"`
+	Status            string                     `json:"status"`
+	Summary           *ReceptionSummaryResponse  `json:"summary,omitempty"`
+	DiscrepancyReport *DiscrepancyReportResponse `json:"discrepancyReport,omitempty"`
+}
+
+type ReceptionSummaryResponse struct {
+	ClosedAt            time.Time      `json:"closedAt"`
+	TotalProducts       int            `json:"totalProducts"`
+	ProductsByType      map[string]int `json:"productsByType"`
+	OpenDurationSeconds int64          `json:"openDurationSeconds"`
+	Deletions           int            `json:"deletions"`
+}
+
+// NewReceptionSummaryResponse возвращает nil, если у приёмки ещё нет итогов.
+func NewReceptionSummaryResponse(summary *models.ReceptionSummary) *ReceptionSummaryResponse {
+	if summary == nil {
+		return nil
+	}
+	return &ReceptionSummaryResponse{
+		ClosedAt:            summary.ClosedAt,
+		TotalProducts:       summary.TotalProducts,
+		ProductsByType:      summary.ProductsByType,
+		OpenDurationSeconds: int64(summary.OpenDuration / time.Second),
+		Deletions:           summary.DeletionsCount,
+	}
+}
+
+type ManifestItemResponse struct {
+	ExternalId string `json:"externalId"`
+	Type       string `json:"type"`
+}
+
+type ManifestResponse struct {
+	ReceptionId string                 `json:"receptionId"`
+	Items       []ManifestItemResponse `json:"items"`
+}
+
+type DiscrepancyItemResponse struct {
+	Kind         string `json:"kind"`
+	ExternalId   string `json:"externalId,omitempty"`
+	ExpectedType string `json:"expectedType,omitempty"`
+	ActualType   string `json:"actualType,omitempty"`
+	ProductId    string `json:"productId,omitempty"`
+}
+
+type DiscrepancyReportResponse struct {
+	ReceptionId   string                    `json:"receptionId"`
+	CreatedAt     time.Time                 `json:"createdAt"`
+	ExpectedCount int                       `json:"expectedCount"`
+	ReceivedCount int                       `json:"receivedCount"`
+	MatchedCount  int                       `json:"matchedCount"`
+	Items         []DiscrepancyItemResponse `json:"items"`
+}
+
+type ReopenReceptionResponse struct {
+	Id         string    `json:"id"`
+	DateTime   time.Time `json:"dateTime"`
+	PvzId      string    `json:"pvzId"`
+	Status     string    `json:"status"`
+	ReopenedBy string    `json:"reopenedBy"`
+	ReopenedAt time.Time `json:"reopenedAt"`
+	Reason     string    `json:"reason"`
 }
 
 type GetPvzsInformationResponse struct {
 	Pvz        models.Pvz                 `json:"pvz"`
+	Capacity   PvzCapacityResponse        `json:"capacity"`
 	Receptions []GetReceptionWithProducts `json:"receptions"`
 }
 
+type PvzCapacityResponse struct {
+	Capacity  *int   `json:"capacity"`
+	Policy    string `json:"policy"`
+	Occupancy int    `json:"occupancy"`
+}
+
+type PvzDetailResponse struct {
+	Id               string              `json:"id"`
+	RegistrationDate time.Time           `json:"registrationDate"`
+	City             string              `json:"city"`
+	Address          string              `json:"address,omitempty"`
+	Capacity         PvzCapacityResponse `json:"capacity"`
+}
+
 type GetReceptionWithProducts struct {
-	Reception models.Reception `json:"reception"`
-	Products  []models.Product `json:"products"`
+	Reception models.Reception          `json:"reception"`
+	Summary   *ReceptionSummaryResponse `json:"summary,omitempty"`
+	Products  []models.Product          `json:"products"`
+}
+
+type WorkingHoursResponse struct {
+	Weekday   int    `json:"weekday"`
+	OpenTime  string `json:"openTime"`
+	CloseTime string `json:"closeTime"`
+}
+
+type ScheduleExceptionResponse struct {
+	Id        string `json:"id"`
+	Date      string `json:"date"`
+	IsClosed  bool   `json:"isClosed"`
+	OpenTime  string `json:"openTime,omitempty"`
+	CloseTime string `json:"closeTime,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+type PvzScheduleResponse struct {
+	PvzId        string                      `json:"pvzId"`
+	TimeZone     string                      `json:"timeZone"`
+	Policy       string                      `json:"policy"`
+	WorkingHours []WorkingHoursResponse      `json:"workingHours"`
+	Exceptions   []ScheduleExceptionResponse `json:"exceptions"`
+}
+
+type ImportPvzRowError struct {
+	Line   int      `json:"line"`
+	Id     string   `json:"id"`
+	Errors []string `json:"errors"`
+}
+
+type ImportPvzsResponse struct {
+	DryRun   bool                `json:"dryRun"`
+	Mode     string              `json:"mode"`
+	Total    int                 `json:"total"`
+	Valid    int                 `json:"valid"`
+	Inserted int                 `json:"inserted"`
+	Errors   []ImportPvzRowError `json:"errors"`
+}
+
+type TransferResponse struct {
+	Id               string     `json:"id"`
+	SourcePvzId      string     `json:"sourcePvzId"`
+	DestinationPvzId string     `json:"destinationPvzId"`
+	Status           string     `json:"status"`
+	CreatedBy        string     `json:"createdBy"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	AcceptedBy       string     `json:"acceptedBy,omitempty"`
+	AcceptedAt       *time.Time `json:"acceptedAt,omitempty"`
+	ReceptionId      string     `json:"receptionId,omitempty"`
+	ProductIds       []string   `json:"productIds"`
+}
+
+type GetTransfersResponse struct {
+	Transfers []TransferResponse `json:"transfers"`
+}
+
+type StorageCellResponse struct {
+	Code     string `json:"code"`
+	Size     string `json:"size"`
+	Capacity int    `json:"capacity"`
+	Occupied int    `json:"occupied"`
+}
+
+type GetStorageCellsResponse struct {
+	PvzId string                `json:"pvzId"`
+	Cells []StorageCellResponse `json:"cells"`
+}
+
+type ProductLocationResponse struct {
+	ProductId    string               `json:"productId"`
+	Type         string               `json:"type"`
+	Status       string               `json:"status"`
+	PvzId        string               `json:"pvzId"`
+	SizeCategory string               `json:"sizeCategory"`
+	Cell         *StorageCellResponse `json:"cell,omitempty"`
+}
+
+type InventoryMissingItemResponse struct {
+	ProductId  string `json:"productId"`
+	Type       string `json:"type"`
+	ExternalId string `json:"externalId,omitempty"`
+	CellCode   string `json:"cellCode,omitempty"`
+}
+
+type InventorySurplusItemResponse struct {
+	Code string `json:"code"`
+}
+
+type InventoryReportResponse struct {
+	ExpectedCount int                            `json:"expectedCount"`
+	ScannedCount  int                            `json:"scannedCount"`
+	MatchedCount  int                            `json:"matchedCount"`
+	Missing       []InventoryMissingItemResponse `json:"missing"`
+	Surplus       []InventorySurplusItemResponse `json:"surplus"`
+}
+
+type InventorySessionResponse struct {
+	Id              string                   `json:"id"`
+	PvzId           string                   `json:"pvzId"`
+	Status          string                   `json:"status"`
+	StartedBy       string                   `json:"startedBy"`
+	StartedAt       time.Time                `json:"startedAt"`
+	CompletedAt     *time.Time               `json:"completedAt,omitempty"`
+	ApprovedBy      string                   `json:"approvedBy,omitempty"`
+	ApprovedAt      *time.Time               `json:"approvedAt,omitempty"`
+	ApprovalComment string                   `json:"approvalComment,omitempty"`
+	Report          *InventoryReportResponse `json:"report,omitempty"`
+}
+
+type InventoryScanResponse struct {
+	Id        string    `json:"id"`
+	Code      string    `json:"code"`
+	ProductId string    `json:"productId,omitempty"`
+	Matched   bool      `json:"matched"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
+
+type DamagePhotoResponse struct {
+	Id          string    `json:"id"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	UploadedBy  string    `json:"uploadedBy"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+	Url         string    `json:"url"`
+}
+
+type DamageReportResponse struct {
+	Id          string                `json:"id"`
+	ProductId   string                `json:"productId,omitempty"`
+	ReceptionId string                `json:"receptionId,omitempty"`
+	PvzId       string                `json:"pvzId"`
+	Description string                `json:"description"`
+	Severity    string                `json:"severity"`
+	CreatedBy   string                `json:"createdBy"`
+	CreatedAt   time.Time             `json:"createdAt"`
+	Photos      []DamagePhotoResponse `json:"photos"`
+}
+
+type GetDamageReportsResponse struct {
+	Reports []DamageReportResponse `json:"reports"`
+}
+
+type WebhookResponse struct {
+	Id         string   `json:"id"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	PvzIds     []string `json:"pvzIds"`
+	// Secret возвращается только при создании подписки
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type GetWebhooksResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+type WebhookDeliveryResponse struct {
+	Id             string     `json:"id"`
+	EventId        string     `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	PvzId          string     `json:"pvzId"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`
+	LastStatusCode int        `json:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
 }
@@ -23,7 +23,7 @@ func NewAuthUsecase(authRepository AuthRepository, jwtService JwtTokenService) A
 }
 
 func (au AuthUsecase) DummyLogin(_ context.Context, role string) (string, error) {
-	if role != "employee" && role != "moderator" {
+	if role != "employee" && role != "moderator" && role != "client" {
 		return "", errors.New("invalid role")
 	}
 	tokenExpTime := time.Now().Add(24 * time.Hour).Unix()
@@ -40,7 +40,7 @@ func (au AuthUsecase) Register(ctx context.Context, credentials requests.Registe
 		return models.User{}, err
 	}
 
-	if credentials.Role != "employee" && credentials.Role != "moderator" {
+	if credentials.Role != "employee" && credentials.Role != "moderator" && credentials.Role != "client" {
 		return models.User{}, errors.New("invalid role")
 	}
 
@@ -66,7 +66,7 @@ func (au AuthUsecase) Login(ctx context.Context, credentials requests.LoginReque
 		return "", errors.New("invalid password")
 	}
 	tokenExpTime := time.Now().Add(24 * time.Hour).Unix()
-	jwtToken, err := au.jwtService.Create(user.Role, tokenExpTime)
+	jwtToken, err := au.jwtService.CreateWithSubject(user.Id, user.Role, tokenExpTime)
 	if err != nil {
 		return "", err
 	}
@@ -36,6 +36,15 @@ func TestAuthUsecase_DummyLogin(t *testing.T) {
 			expectedToken: "employee_token",
 			expectedErr:   "",
 		},
+		{
+			name: "success client",
+			role: "client",
+			mockSetup: func(_ *repositoryMocks.MockAuthRepository, mj *jwtMocks.MockJwtService) {
+				mj.On("Create", "client", expTime).Return("client_token", nil)
+			},
+			expectedToken: "client_token",
+			expectedErr:   "",
+		},
 		{
 			name: "invalid role",
 			role: "admin",
@@ -203,6 +212,7 @@ func TestAuthUsecase_Login(t *testing.T) {
 	testPassword := "password123"
 	hashedPassword, _ := auth.HashPassword(testPassword)
 	testUser := &models.User{
+		Id:       "user-1",
 		Email:    testEmail,
 		Password: hashedPassword,
 		Role:     "employee",
@@ -225,7 +235,7 @@ func TestAuthUsecase_Login(t *testing.T) {
 				mr.On("GetUserByEmail", mock.Anything, testEmail).
 					Return(testUser, nil)
 
-				mj.On("Create", "employee", mock.MatchedBy(func(t int64) bool {
+				mj.On("CreateWithSubject", "user-1", "employee", mock.MatchedBy(func(t int64) bool {
 					expected := time.Now().Add(24 * time.Hour).Unix()
 					return math.Abs(float64(t-expected)) <= 1
 				})).Return("valid_token", nil)
@@ -268,7 +278,7 @@ func TestAuthUsecase_Login(t *testing.T) {
 			mockSetup: func(mr *repositoryMocks.MockAuthRepository, mj *jwtMocks.MockJwtService) {
 				mr.On("GetUserByEmail", mock.Anything, testEmail).
 					Return(testUser, nil)
-				mj.On("Create", "employee", mock.MatchedBy(func(t int64) bool {
+				mj.On("CreateWithSubject", "user-1", "employee", mock.MatchedBy(func(t int64) bool {
 					return math.Abs(float64(t-expTime)) <= 1
 				})).Return("", errors.New("jwt error"))
 			},
@@ -14,6 +14,7 @@ type AuthRepository interface {
 
 type JwtTokenService interface {
 	Create(role string, tokenExpTime int64) (string, error)
+	CreateWithSubject(subject, role string, tokenExpTime int64) (string, error)
 	Validate(tokenString string) (*jwt_package.JwtCsrfClaims, error)
 	ParseSecretGetter(token *jwt.Token) (interface{}, error)
 }
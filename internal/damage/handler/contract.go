@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"context"
+	"io"
+)
+
+type DamageUsecase interface {
+	CreateDamageReport(ctx context.Context, data requests.CreateDamageReportRequest) (models.DamageReport, error)
+	GetDamageReport(ctx context.Context, reportId string) (models.DamageReport, error)
+	GetDamageReports(ctx context.Context, productId, receptionId string) ([]models.DamageReport, error)
+	UploadDamagePhoto(ctx context.Context, reportId string, content []byte) (models.DamagePhoto, error)
+	GetDamagePhoto(ctx context.Context, reportId, photoId string) (models.DamagePhoto, io.ReadCloser, error)
+}
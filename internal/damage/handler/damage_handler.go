@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxPhotoRequestSize оставляет запас под заголовки multipart поверх лимита на сам файл.
+const maxPhotoRequestSize = models.MAX_DAMAGE_PHOTO_SIZE + 64<<10
+
+type DamageHandler struct {
+	usecase DamageUsecase
+}
+
+func NewDamageHandler(usecase DamageUsecase) *DamageHandler {
+	return &DamageHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *DamageHandler) CreateDamageReport(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	var data requests.CreateDamageReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data = requests.CreateDamageReportRequest{
+		ProductId:   sanitizer.Sanitize(data.ProductId),
+		ReceptionId: sanitizer.Sanitize(data.ReceptionId),
+		Description: sanitizer.Sanitize(data.Description),
+		Severity:    sanitizer.Sanitize(data.Severity),
+	}
+
+	report, err := h.usecase.CreateDamageReport(ctx, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, toDamageReportResponse(report), requestID)
+}
+
+func (h *DamageHandler) GetDamageReport(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	reportId := sanitizer.Sanitize(mux.Vars(r)["reportId"])
+
+	report, err := h.usecase.GetDamageReport(ctx, reportId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toDamageReportResponse(report), requestID)
+}
+
+func (h *DamageHandler) GetDamageReports(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	productId := sanitizer.Sanitize(r.URL.Query().Get("productId"))
+	receptionId := sanitizer.Sanitize(r.URL.Query().Get("receptionId"))
+
+	reports, err := h.usecase.GetDamageReports(ctx, productId, receptionId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetDamageReportsResponse{
+		Reports: make([]responses.DamageReportResponse, 0, len(reports)),
+	}
+	for _, report := range reports {
+		response.Reports = append(response.Reports, toDamageReportResponse(report))
+	}
+	h.writeJSON(w, http.StatusOK, response, requestID)
+}
+
+// UploadDamagePhoto принимает фото либо телом запроса целиком, либо полем photo формы multipart/form-data.
+func (h *DamageHandler) UploadDamagePhoto(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	reportId := sanitizer.Sanitize(mux.Vars(r)["reportId"])
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoRequestSize)
+
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		formFile, _, err := r.FormFile("photo")
+		if err != nil {
+			h.handleError(w, photoReadError(err), requestID)
+			return
+		}
+		defer formFile.Close()
+		file = formFile
+	}
+
+	// Читаем на байт больше лимита, чтобы отличить файл ровно на лимит от превышающего его.
+	content, err := io.ReadAll(io.LimitReader(file, models.MAX_DAMAGE_PHOTO_SIZE+1))
+	if err != nil {
+		h.handleError(w, photoReadError(err), requestID)
+		return
+	}
+
+	photo, err := h.usecase.UploadDamagePhoto(ctx, reportId, content)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, toDamagePhotoResponse(photo), requestID)
+}
+
+func photoReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return errors.New("photo is too large")
+	}
+	return errors.New("photo is required")
+}
+
+func (h *DamageHandler) GetDamagePhoto(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	reportId := sanitizer.Sanitize(mux.Vars(r)["reportId"])
+	photoId := sanitizer.Sanitize(mux.Vars(r)["photoId"])
+
+	photo, content, err := h.usecase.GetDamagePhoto(ctx, reportId, photoId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	defer func() {
+		if err := content.Close(); err != nil {
+			logger.AccessLogger.Error("Failed to close photo",
+				zap.String("request_id", requestID),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	w.Header().Set("Content-Type", photo.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(photo.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		logger.AccessLogger.Error("Failed to write photo",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (h *DamageHandler) writeJSON(w http.ResponseWriter, status int, response interface{}, requestID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.AccessLogger.Error("Failed to encode response",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+}
+
+func toDamagePhotoResponse(photo models.DamagePhoto) responses.DamagePhotoResponse {
+	return responses.DamagePhotoResponse{
+		Id:          photo.Id,
+		ContentType: photo.ContentType,
+		Size:        photo.Size,
+		UploadedBy:  photo.UploadedBy,
+		UploadedAt:  photo.UploadedAt,
+		Url:         "/api/damage-reports/" + photo.ReportId + "/photos/" + photo.Id,
+	}
+}
+
+func toDamageReportResponse(report models.DamageReport) responses.DamageReportResponse {
+	response := responses.DamageReportResponse{
+		Id:          report.Id,
+		ProductId:   report.ProductId,
+		ReceptionId: report.ReceptionId,
+		PvzId:       report.PvzId,
+		Description: report.Description,
+		Severity:    report.Severity,
+		CreatedBy:   report.CreatedBy,
+		CreatedAt:   report.CreatedAt,
+		Photos:      make([]responses.DamagePhotoResponse, 0, len(report.Photos)),
+	}
+	for _, photo := range report.Photos {
+		response.Photos = append(response.Photos, toDamagePhotoResponse(photo))
+	}
+	return response
+}
+
+func (h *DamageHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "product id or reception id is required", "description is required", "description is too long",
+		"invalid severity", "photo is required", "photo is empty":
+		w.WriteHeader(http.StatusBadRequest)
+	case "product not found", "reception not found", "damage report not found", "damage photo not found", "blob not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "too many photos":
+		w.WriteHeader(http.StatusConflict)
+	case "photo is too large":
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case "unsupported photo type":
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"bytes"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDamageHandler(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	createdAt := time.Date(2025, 5, 4, 10, 0, 0, 0, time.UTC)
+	vars := map[string]string{"reportId": "rep1", "photoId": "photo1"}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		call           func(h *DamageHandler) http.HandlerFunc
+		mockBehavior   func(usecase *usecaseMocks.DamageUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/api/damage-reports",
+			body:   `{"productId":"prod1","description":"мятая коробка","severity":"minor"}`,
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.CreateDamageReport },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				usecase.On("CreateDamageReport", mock.Anything, requests.CreateDamageReportRequest{
+					ProductId: "prod1", Description: "мятая коробка", Severity: "minor",
+				}).Return(models.DamageReport{
+					Id: "rep1", ProductId: "prod1", PvzId: "pvz1", Description: "мятая коробка", Severity: "minor",
+					CreatedBy: "user1", CreatedAt: createdAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":"rep1","productId":"prod1","pvzId":"pvz1","description":"мятая коробка","severity":"minor",
+				"createdBy":"user1","createdAt":"2025-05-04T10:00:00Z","photos":[]}`,
+		},
+		{
+			name:   "create with invalid severity",
+			method: http.MethodPost,
+			path:   "/api/damage-reports",
+			body:   `{"productId":"prod1","description":"скол","severity":"fatal"}`,
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.CreateDamageReport },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				usecase.On("CreateDamageReport", mock.Anything, mock.Anything).
+					Return(models.DamageReport{}, errors.New("invalid severity"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid severity"}`,
+		},
+		{
+			name:   "get with photos",
+			method: http.MethodGet,
+			path:   "/api/damage-reports/rep1",
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.GetDamageReport },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				usecase.On("GetDamageReport", mock.Anything, "rep1").Return(models.DamageReport{
+					Id: "rep1", ReceptionId: "rec1", PvzId: "pvz1", Description: "намокла паллета", Severity: "severe",
+					CreatedAt: createdAt,
+					Photos: []models.DamagePhoto{{
+						Id: "photo1", ReportId: "rep1", ContentType: "image/jpeg", Size: 1024, UploadedBy: "user1", UploadedAt: createdAt,
+					}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"rep1","receptionId":"rec1","pvzId":"pvz1","description":"намокла паллета","severity":"severe",
+				"createdBy":"","createdAt":"2025-05-04T10:00:00Z","photos":[{"id":"photo1","contentType":"image/jpeg","size":1024,
+				"uploadedBy":"user1","uploadedAt":"2025-05-04T10:00:00Z","url":"/api/damage-reports/rep1/photos/photo1"}]}`,
+		},
+		{
+			name:   "get foreign report",
+			method: http.MethodGet,
+			path:   "/api/damage-reports/rep1",
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.GetDamageReport },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				usecase.On("GetDamageReport", mock.Anything, "rep1").
+					Return(models.DamageReport{}, errors.New("damage report not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"damage report not found"}`,
+		},
+		{
+			name:   "list by product",
+			method: http.MethodGet,
+			path:   "/api/damage-reports?productId=prod1",
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.GetDamageReports },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				usecase.On("GetDamageReports", mock.Anything, "prod1", "").Return([]models.DamageReport{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"reports":[]}`,
+		},
+		{
+			name:   "upload raw body",
+			method: http.MethodPost,
+			path:   "/api/damage-reports/rep1/photos",
+			body:   "\x89PNG\r\n\x1a\n",
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.UploadDamagePhoto },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				usecase.On("UploadDamagePhoto", mock.Anything, "rep1", []byte("\x89PNG\r\n\x1a\n")).Return(models.DamagePhoto{
+					Id: "photo1", ReportId: "rep1", ContentType: "image/png", Size: 8, UploadedAt: createdAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":"photo1","contentType":"image/png","size":8,"uploadedBy":"","uploadedAt":"2025-05-04T10:00:00Z",
+				"url":"/api/damage-reports/rep1/photos/photo1"}`,
+		},
+		{
+			name:   "upload unsupported type",
+			method: http.MethodPost,
+			path:   "/api/damage-reports/rep1/photos",
+			body:   "plain text",
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.UploadDamagePhoto },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				usecase.On("UploadDamagePhoto", mock.Anything, "rep1", mock.Anything).
+					Return(models.DamagePhoto{}, errors.New("unsupported photo type"))
+			},
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedBody:   `{"errors":"unsupported photo type"}`,
+		},
+		{
+			name:   "upload too large",
+			method: http.MethodPost,
+			path:   "/api/damage-reports/rep1/photos",
+			body:   strings.Repeat("x", models.MAX_DAMAGE_PHOTO_SIZE+100),
+			call:   func(h *DamageHandler) http.HandlerFunc { return h.UploadDamagePhoto },
+			mockBehavior: func(usecase *usecaseMocks.DamageUsecaseMock) {
+				// в usecase уходит не больше лимита плюс один байт — этого достаточно, чтобы отклонить файл
+				usecase.On("UploadDamagePhoto", mock.Anything, "rep1", mock.MatchedBy(func(content []byte) bool {
+					return len(content) == models.MAX_DAMAGE_PHOTO_SIZE+1
+				})).Return(models.DamagePhoto{}, errors.New("photo is too large"))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   `{"errors":"photo is too large"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.DamageUsecaseMock)
+			handler := NewDamageHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, vars)
+			w := httptest.NewRecorder()
+			tt.call(handler)(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDamageHandler_UploadMultipart(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	photo := []byte("\xff\xd8\xff\xe0jpeg")
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photo", "damage.jpg")
+	require.NoError(t, err)
+	_, err = part.Write(photo)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	mockUsecase := new(usecaseMocks.DamageUsecaseMock)
+	mockUsecase.On("UploadDamagePhoto", mock.Anything, "rep1", photo).
+		Return(models.DamagePhoto{Id: "photo1", ReportId: "rep1", ContentType: "image/jpeg"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/damage-reports/rep1/photos", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req = mux.SetURLVars(req, map[string]string{"reportId": "rep1"})
+	w := httptest.NewRecorder()
+	NewDamageHandler(mockUsecase).UploadDamagePhoto(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockUsecase.AssertExpectations(t)
+}
+
+func TestDamageHandler_UploadMultipartOverRequestLimit(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photo", "damage.jpg")
+	require.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("x"), maxPhotoRequestSize))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	mockUsecase := new(usecaseMocks.DamageUsecaseMock)
+	req := httptest.NewRequest(http.MethodPost, "/api/damage-reports/rep1/photos", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req = mux.SetURLVars(req, map[string]string{"reportId": "rep1"})
+	w := httptest.NewRecorder()
+	NewDamageHandler(mockUsecase).UploadDamagePhoto(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"errors":"photo is too large"}`, w.Body.String())
+	mockUsecase.AssertExpectations(t)
+}
+
+func TestDamageHandler_GetDamagePhoto(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	photo := []byte("\x89PNG\r\n\x1a\n")
+
+	mockUsecase := new(usecaseMocks.DamageUsecaseMock)
+	mockUsecase.On("GetDamagePhoto", mock.Anything, "rep1", "photo1").
+		Return(models.DamagePhoto{Id: "photo1", ContentType: "image/png", Size: int64(len(photo))},
+			io.NopCloser(bytes.NewReader(photo)), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/damage-reports/rep1/photos/photo1", nil)
+	req = mux.SetURLVars(req, map[string]string{"reportId": "rep1", "photoId": "photo1"})
+	w := httptest.NewRecorder()
+	NewDamageHandler(mockUsecase).GetDamagePhoto(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "8", w.Header().Get("Content-Length"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, photo, w.Body.Bytes())
+	mockUsecase.AssertExpectations(t)
+}
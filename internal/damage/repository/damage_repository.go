@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+const damageReportColumns = "id, product_id, reception_id, pvz_id, description, severity, created_by, created_at"
+
+type DamageRepository struct {
+	db *sql.DB
+}
+
+func NewDamageRepository(db *sql.DB) DamageRepository {
+	return DamageRepository{
+		db: db,
+	}
+}
+
+// WithinTransaction выполняет fn в одной транзакции; транзакция, уже открытая выше по стеку
+// любым репозиторием, переиспользуется.
+func (r DamageRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return dbtx.WithinTransaction(ctx, r.db, fn)
+}
+
+// GetProductPvz возвращает ПВЗ, в приёмку которого попал товар.
+func (r DamageRepository) GetProductPvz(ctx context.Context, productId string) (string, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductPvz called", zap.String("request_id", requestID), zap.String("product_id", productId))
+
+	query, args, err := sq.Select("r.pvz_id").
+		From("products p").
+		Join("receptions r ON r.id = p.reception_id").
+		Where(sq.Eq{"p.id": productId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return "", err
+	}
+
+	var pvzId string
+	if err := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&pvzId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("product not found")
+		}
+		logger.DBLogger.Error("failed to scan product pvz", zap.Error(err))
+		return "", err
+	}
+	return pvzId, nil
+}
+
+func (r DamageRepository) GetReceptionPvz(ctx context.Context, receptionId string) (string, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetReceptionPvz called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("pvz_id").
+		From("receptions").
+		Where(sq.Eq{"id": receptionId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return "", err
+	}
+
+	var pvzId string
+	if err := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&pvzId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("reception not found")
+		}
+		logger.DBLogger.Error("failed to scan reception pvz", zap.Error(err))
+		return "", err
+	}
+	return pvzId, nil
+}
+
+// IsProductOwnedBy проверяет, что заказ, к которому относится товар, привязан к клиенту.
+func (r DamageRepository) IsProductOwnedBy(ctx context.Context, productId, customerId string) (bool, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("IsProductOwnedBy called", zap.String("request_id", requestID), zap.String("product_id", productId))
+
+	query, args, err := sq.Select("1").
+		From("products p").
+		Join("order_owners o ON o.external_id = p.external_id").
+		Where(sq.Eq{"p.id": productId, "o.customer_id": customerId}).
+		Prefix("SELECT EXISTS (").
+		Suffix(")").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return false, err
+	}
+
+	var owned bool
+	if err := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&owned); err != nil {
+		logger.DBLogger.Error("failed to check product owner", zap.Error(err))
+		return false, err
+	}
+	return owned, nil
+}
+
+func (r DamageRepository) CreateDamageReport(ctx context.Context, report models.DamageReport) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CreateDamageReport called", zap.String("request_id", requestID), zap.String("report_id", report.Id))
+
+	query, args, err := sq.Insert("damage_reports").
+		Columns("id", "product_id", "reception_id", "pvz_id", "description", "severity", "created_by", "created_at").
+		Values(report.Id, nullableId(report.ProductId), nullableId(report.ReceptionId), report.PvzId,
+			report.Description, report.Severity, report.CreatedBy, report.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to insert damage report", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func nullableId(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
+// GetDamageReport возвращает акт вместе с фотографиями.
+func (r DamageRepository) GetDamageReport(ctx context.Context, reportId string) (*models.DamageReport, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetDamageReport called", zap.String("request_id", requestID), zap.String("report_id", reportId))
+
+	report, err := r.getDamageReport(ctx, reportId, "")
+	if err != nil {
+		return nil, err
+	}
+	reports := []models.DamageReport{*report}
+	if err := r.attachPhotos(ctx, reports); err != nil {
+		return nil, err
+	}
+	return &reports[0], nil
+}
+
+// LockDamageReport читает акт без фотографий и блокирует его до конца транзакции,
+// чтобы параллельные загрузки не превысили лимит фото.
+func (r DamageRepository) LockDamageReport(ctx context.Context, reportId string) (*models.DamageReport, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockDamageReport called", zap.String("request_id", requestID), zap.String("report_id", reportId))
+
+	return r.getDamageReport(ctx, reportId, "FOR UPDATE")
+}
+
+func (r DamageRepository) getDamageReport(ctx context.Context, reportId, suffix string) (*models.DamageReport, error) {
+	query, args, err := sq.Select(damageReportColumns).
+		From("damage_reports").
+		Where(sq.Eq{"id": reportId}).
+		Suffix(suffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	report, err := scanDamageReport(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("damage report not found")
+		}
+		logger.DBLogger.Error("failed to scan damage report", zap.Error(err))
+		return nil, err
+	}
+	return report, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDamageReport(row rowScanner) (*models.DamageReport, error) {
+	var report models.DamageReport
+	var productId, receptionId sql.NullString
+	err := row.Scan(&report.Id, &productId, &receptionId, &report.PvzId, &report.Description,
+		&report.Severity, &report.CreatedBy, &report.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	report.ProductId = productId.String
+	report.ReceptionId = receptionId.String
+	return &report, nil
+}
+
+// GetDamageReports возвращает акты по товару или по приёмке, от новых к старым.
+func (r DamageRepository) GetDamageReports(ctx context.Context, productId, receptionId string) ([]models.DamageReport, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetDamageReports called",
+		zap.String("request_id", requestID),
+		zap.String("product_id", productId),
+		zap.String("reception_id", receptionId),
+	)
+
+	builder := sq.Select(damageReportColumns).
+		From("damage_reports").
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar)
+	if productId != "" {
+		builder = builder.Where(sq.Eq{"product_id": productId})
+	}
+	if receptionId != "" {
+		builder = builder.Where(sq.Eq{"reception_id": receptionId})
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query damage reports", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	reports := []models.DamageReport{}
+	for rows.Next() {
+		report, err := scanDamageReport(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan damage report", zap.Error(err))
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachPhotos(ctx, reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// attachPhotos подгружает фотографии сразу для всех актов одним запросом.
+func (r DamageRepository) attachPhotos(ctx context.Context, reports []models.DamageReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(reports))
+	index := make(map[string]int, len(reports))
+	for i, report := range reports {
+		ids = append(ids, report.Id)
+		index[report.Id] = i
+		reports[i].Photos = []models.DamagePhoto{}
+	}
+
+	query, args, err := sq.Select("id", "report_id", "blob_key", "content_type", "size", "uploaded_by", "uploaded_at").
+		From("damage_photos").
+		Where(sq.Eq{"report_id": ids}).
+		OrderBy("uploaded_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query damage photos", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	for rows.Next() {
+		var photo models.DamagePhoto
+		if err := rows.Scan(&photo.Id, &photo.ReportId, &photo.BlobKey, &photo.ContentType,
+			&photo.Size, &photo.UploadedBy, &photo.UploadedAt); err != nil {
+			logger.DBLogger.Error("failed to scan damage photo", zap.Error(err))
+			return err
+		}
+		i := index[photo.ReportId]
+		reports[i].Photos = append(reports[i].Photos, photo)
+	}
+	return rows.Err()
+}
+
+func (r DamageRepository) CountDamagePhotos(ctx context.Context, reportId string) (int, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CountDamagePhotos called", zap.String("request_id", requestID), zap.String("report_id", reportId))
+
+	query, args, err := sq.Select("COUNT(*)").
+		From("damage_photos").
+		Where(sq.Eq{"report_id": reportId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return 0, err
+	}
+
+	var count int
+	if err := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		logger.DBLogger.Error("failed to count damage photos", zap.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r DamageRepository) AddDamagePhoto(ctx context.Context, photo models.DamagePhoto) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("AddDamagePhoto called", zap.String("request_id", requestID), zap.String("report_id", photo.ReportId))
+
+	query, args, err := sq.Insert("damage_photos").
+		Columns("id", "report_id", "blob_key", "content_type", "size", "uploaded_by", "uploaded_at").
+		Values(photo.Id, photo.ReportId, photo.BlobKey, photo.ContentType, photo.Size, photo.UploadedBy, photo.UploadedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to insert damage photo", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetDamagePhoto ищет фото только внутри указанного акта, чтобы доступ к акту нельзя было обойти чужим id фото.
+func (r DamageRepository) GetDamagePhoto(ctx context.Context, reportId, photoId string) (*models.DamagePhoto, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetDamagePhoto called", zap.String("request_id", requestID), zap.String("photo_id", photoId))
+
+	query, args, err := sq.Select("id", "report_id", "blob_key", "content_type", "size", "uploaded_by", "uploaded_at").
+		From("damage_photos").
+		Where(sq.Eq{"id": photoId, "report_id": reportId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var photo models.DamagePhoto
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&photo.Id, &photo.ReportId, &photo.BlobKey,
+		&photo.ContentType, &photo.Size, &photo.UploadedBy, &photo.UploadedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("damage photo not found")
+		}
+		logger.DBLogger.Error("failed to scan damage photo", zap.Error(err))
+		return nil, err
+	}
+	return &photo, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func newMockRepository(t *testing.T) (DamageRepository, sqlmock.Sqlmock) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return NewDamageRepository(db), mock
+}
+
+var (
+	reportColumns = []string{"id", "product_id", "reception_id", "pvz_id", "description", "severity", "created_by", "created_at"}
+	photoColumns  = []string{"id", "report_id", "blob_key", "content_type", "size", "uploaded_by", "uploaded_at"}
+)
+
+func TestDamageRepository_GetProductPvz(t *testing.T) {
+	query := `^SELECT r.pvz_id FROM products p JOIN receptions r ON r.id = p.reception_id WHERE p.id = \$1$`
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).WithArgs("prod1").WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}).AddRow("pvz1"))
+
+		pvzId, err := repo.GetProductPvz(context.Background(), "prod1")
+		require.NoError(t, err)
+		assert.Equal(t, "pvz1", pvzId)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).WithArgs("prod1").WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}))
+
+		_, err := repo.GetProductPvz(context.Background(), "prod1")
+		assert.EqualError(t, err, "product not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDamageRepository_IsProductOwnedBy(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT EXISTS \( SELECT 1 FROM products p JOIN order_owners o ON o.external_id = p.external_id `+
+		`WHERE o.customer_id = \$1 AND p.id = \$2 \)$`).
+		WithArgs("customer1", "prod1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	owned, err := repo.IsProductOwnedBy(context.Background(), "prod1", "customer1")
+	require.NoError(t, err)
+	assert.True(t, owned)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDamageRepository_CreateDamageReport(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	createdAt := time.Now()
+	mock.ExpectExec(`^INSERT INTO damage_reports \(id,product_id,reception_id,pvz_id,description,severity,created_by,created_at\) `+
+		`VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\)$`).
+		WithArgs("rep1", nil, "rec1", "pvz1", "намокла паллета", models.DAMAGE_SEVERITY_SEVERE, "user1", createdAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.CreateDamageReport(context.Background(), models.DamageReport{
+		Id: "rep1", ReceptionId: "rec1", PvzId: "pvz1", Description: "намокла паллета",
+		Severity: models.DAMAGE_SEVERITY_SEVERE, CreatedBy: "user1", CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDamageRepository_GetDamageReport(t *testing.T) {
+	createdAt := time.Date(2025, 5, 4, 10, 0, 0, 0, time.UTC)
+	query := `^SELECT id, product_id, reception_id, pvz_id, description, severity, created_by, created_at ` +
+		`FROM damage_reports WHERE id = \$1$`
+
+	t.Run("With Photos", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).
+			WithArgs("rep1").
+			WillReturnRows(sqlmock.NewRows(reportColumns).
+				AddRow("rep1", "prod1", nil, "pvz1", "скол", "minor", "user1", createdAt))
+		mock.ExpectQuery(`^SELECT id, report_id, blob_key, content_type, size, uploaded_by, uploaded_at FROM damage_photos ` +
+			`WHERE report_id IN \(\$1\) ORDER BY uploaded_at$`).
+			WithArgs("rep1").
+			WillReturnRows(sqlmock.NewRows(photoColumns).
+				AddRow("photo1", "rep1", "damage/rep1/photo1.jpg", "image/jpeg", 1024, "user1", createdAt))
+
+		report, err := repo.GetDamageReport(context.Background(), "rep1")
+		require.NoError(t, err)
+		assert.Equal(t, &models.DamageReport{
+			Id: "rep1", ProductId: "prod1", PvzId: "pvz1", Description: "скол", Severity: "minor",
+			CreatedBy: "user1", CreatedAt: createdAt,
+			Photos: []models.DamagePhoto{{
+				Id: "photo1", ReportId: "rep1", BlobKey: "damage/rep1/photo1.jpg", ContentType: "image/jpeg",
+				Size: 1024, UploadedBy: "user1", UploadedAt: createdAt,
+			}},
+		}, report)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).WithArgs("rep1").WillReturnRows(sqlmock.NewRows(reportColumns))
+
+		_, err := repo.GetDamageReport(context.Background(), "rep1")
+		assert.EqualError(t, err, "damage report not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDamageRepository_LockDamageReport(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT id, .* FROM damage_reports WHERE id = \$1 FOR UPDATE$`).
+		WithArgs("rep1").
+		WillReturnRows(sqlmock.NewRows(reportColumns).
+			AddRow("rep1", nil, "rec1", "pvz1", "скол", "minor", "", time.Now()))
+
+	report, err := repo.LockDamageReport(context.Background(), "rep1")
+	require.NoError(t, err)
+	assert.Equal(t, "rec1", report.ReceptionId)
+	assert.Nil(t, report.Photos)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDamageRepository_GetDamageReports(t *testing.T) {
+	createdAt := time.Now()
+
+	t.Run("By Reception", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(`^SELECT id, .* FROM damage_reports WHERE reception_id = \$1 ORDER BY created_at DESC$`).
+			WithArgs("rec1").
+			WillReturnRows(sqlmock.NewRows(reportColumns).
+				AddRow("rep2", nil, "rec1", "pvz1", "b", "minor", "", createdAt).
+				AddRow("rep1", nil, "rec1", "pvz1", "a", "minor", "", createdAt))
+		mock.ExpectQuery(`^SELECT .* FROM damage_photos WHERE report_id IN \(\$1,\$2\) ORDER BY uploaded_at$`).
+			WithArgs("rep2", "rep1").
+			WillReturnRows(sqlmock.NewRows(photoColumns).
+				AddRow("photo1", "rep1", "damage/rep1/photo1.png", "image/png", 10, "", createdAt))
+
+		reports, err := repo.GetDamageReports(context.Background(), "", "rec1")
+		require.NoError(t, err)
+		require.Len(t, reports, 2)
+		assert.Empty(t, reports[0].Photos)
+		assert.Len(t, reports[1].Photos, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(`^SELECT id, .* FROM damage_reports WHERE product_id = \$1 ORDER BY created_at DESC$`).
+			WithArgs("prod1").
+			WillReturnRows(sqlmock.NewRows(reportColumns))
+
+		reports, err := repo.GetDamageReports(context.Background(), "prod1", "")
+		require.NoError(t, err)
+		assert.Equal(t, []models.DamageReport{}, reports)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDamageRepository_AddDamagePhoto(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	uploadedAt := time.Now()
+	mock.ExpectExec(`^INSERT INTO damage_photos \(id,report_id,blob_key,content_type,size,uploaded_by,uploaded_at\) `+
+		`VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\)$`).
+		WithArgs("photo1", "rep1", "damage/rep1/photo1.png", "image/png", int64(10), "user1", uploadedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.AddDamagePhoto(context.Background(), models.DamagePhoto{
+		Id: "photo1", ReportId: "rep1", BlobKey: "damage/rep1/photo1.png", ContentType: "image/png",
+		Size: 10, UploadedBy: "user1", UploadedAt: uploadedAt,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDamageRepository_GetDamagePhoto(t *testing.T) {
+	query := `^SELECT id, report_id, blob_key, content_type, size, uploaded_by, uploaded_at FROM damage_photos ` +
+		`WHERE id = \$1 AND report_id = \$2$`
+
+	t.Run("Photo Of Another Report", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).WithArgs("photo1", "rep2").WillReturnRows(sqlmock.NewRows(photoColumns))
+
+		_, err := repo.GetDamagePhoto(context.Background(), "rep2", "photo1")
+		assert.EqualError(t, err, "damage photo not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"io"
+)
+
+type DamageRepository interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	GetProductPvz(ctx context.Context, productId string) (string, error)
+	GetReceptionPvz(ctx context.Context, receptionId string) (string, error)
+	IsProductOwnedBy(ctx context.Context, productId, customerId string) (bool, error)
+	CreateDamageReport(ctx context.Context, report models.DamageReport) error
+	GetDamageReport(ctx context.Context, reportId string) (*models.DamageReport, error)
+	LockDamageReport(ctx context.Context, reportId string) (*models.DamageReport, error)
+	GetDamageReports(ctx context.Context, productId, receptionId string) ([]models.DamageReport, error)
+	CountDamagePhotos(ctx context.Context, reportId string) (int, error)
+	AddDamagePhoto(ctx context.Context, photo models.DamagePhoto) error
+	GetDamagePhoto(ctx context.Context, reportId, photoId string) (*models.DamagePhoto, error)
+}
+
+// BlobStore — хранилище файлов фотографий. Ключ выбирает usecase, хранилище его не интерпретирует.
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
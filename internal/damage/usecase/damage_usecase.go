@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type DamageUsecase struct {
+	damageRepository DamageRepository
+	blobStore        BlobStore
+}
+
+func NewDamageUsecase(damageRepository DamageRepository, blobStore BlobStore) DamageUsecase {
+	return DamageUsecase{
+		damageRepository: damageRepository,
+		blobStore:        blobStore,
+	}
+}
+
+func (du DamageUsecase) CreateDamageReport(ctx context.Context, data requests.CreateDamageReportRequest) (models.DamageReport, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.DamageReport{}, errors.New("this role is not allowed")
+	}
+	if (data.ProductId == "") == (data.ReceptionId == "") {
+		return models.DamageReport{}, errors.New("product id or reception id is required")
+	}
+	description := strings.TrimSpace(data.Description)
+	if description == "" {
+		return models.DamageReport{}, errors.New("description is required")
+	}
+	if len([]rune(description)) > models.MAX_DAMAGE_DESCRIPTION_LENGTH {
+		return models.DamageReport{}, errors.New("description is too long")
+	}
+	if !models.IsValidDamageSeverity(data.Severity) {
+		return models.DamageReport{}, errors.New("invalid severity")
+	}
+
+	var pvzId string
+	var err error
+	if data.ProductId != "" {
+		pvzId, err = du.damageRepository.GetProductPvz(ctx, data.ProductId)
+	} else {
+		pvzId, err = du.damageRepository.GetReceptionPvz(ctx, data.ReceptionId)
+	}
+	if err != nil {
+		return models.DamageReport{}, err
+	}
+
+	report := models.DamageReport{
+		Id:          uuid.New().String(),
+		ProductId:   data.ProductId,
+		ReceptionId: data.ReceptionId,
+		PvzId:       pvzId,
+		Description: description,
+		Severity:    data.Severity,
+		CreatedBy:   middleware.GetUserId(ctx),
+		CreatedAt:   time.Now(),
+		Photos:      []models.DamagePhoto{},
+	}
+	if err := du.damageRepository.CreateDamageReport(ctx, report); err != nil {
+		return models.DamageReport{}, err
+	}
+	return report, nil
+}
+
+func (du DamageUsecase) GetDamageReport(ctx context.Context, reportId string) (models.DamageReport, error) {
+	report, err := du.damageRepository.GetDamageReport(ctx, reportId)
+	if err != nil {
+		return models.DamageReport{}, err
+	}
+	if err := du.checkReportAccess(ctx, report); err != nil {
+		return models.DamageReport{}, err
+	}
+	return *report, nil
+}
+
+// GetDamageReports возвращает акты по товару или по приёмке. Клиент видит только акты по своим товарам.
+func (du DamageUsecase) GetDamageReports(ctx context.Context, productId, receptionId string) ([]models.DamageReport, error) {
+	if (productId == "") == (receptionId == "") {
+		return nil, errors.New("product id or reception id is required")
+	}
+
+	switch ctx.Value(middleware.ContextKeyRole).(string) {
+	case "employee", "moderator":
+	case "client":
+		if productId == "" {
+			return nil, errors.New("this role is not allowed")
+		}
+		owned, err := du.damageRepository.IsProductOwnedBy(ctx, productId, middleware.GetUserId(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if !owned {
+			return nil, errors.New("product not found")
+		}
+	default:
+		return nil, errors.New("this role is not allowed")
+	}
+
+	return du.damageRepository.GetDamageReports(ctx, productId, receptionId)
+}
+
+// UploadDamagePhoto сохраняет фото к акту. Формат определяется по первым байтам файла.
+func (du DamageUsecase) UploadDamagePhoto(ctx context.Context, reportId string, content []byte) (models.DamagePhoto, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.DamagePhoto{}, errors.New("this role is not allowed")
+	}
+	if len(content) == 0 {
+		return models.DamagePhoto{}, errors.New("photo is empty")
+	}
+	if len(content) > models.MAX_DAMAGE_PHOTO_SIZE {
+		return models.DamagePhoto{}, errors.New("photo is too large")
+	}
+	contentType := http.DetectContentType(content)
+	extension, ok := models.DamagePhotoContentTypes[contentType]
+	if !ok {
+		return models.DamagePhoto{}, errors.New("unsupported photo type")
+	}
+
+	photoId := uuid.New().String()
+	photo := models.DamagePhoto{
+		Id:          photoId,
+		ReportId:    reportId,
+		BlobKey:     "damage/" + reportId + "/" + photoId + extension,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		UploadedBy:  middleware.GetUserId(ctx),
+		UploadedAt:  time.Now(),
+	}
+
+	stored := false
+	err := du.damageRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := du.damageRepository.LockDamageReport(ctx, reportId); err != nil {
+			return err
+		}
+		count, err := du.damageRepository.CountDamagePhotos(ctx, reportId)
+		if err != nil {
+			return err
+		}
+		if count >= models.MAX_DAMAGE_PHOTOS {
+			return errors.New("too many photos")
+		}
+
+		if err := du.blobStore.Put(ctx, photo.BlobKey, bytes.NewReader(content)); err != nil {
+			return err
+		}
+		stored = true
+		return du.damageRepository.AddDamagePhoto(ctx, photo)
+	})
+	if err != nil {
+		// Файл без записи в базе никто не увидит, поэтому удаляем его по возможности.
+		if stored {
+			_ = du.blobStore.Delete(context.WithoutCancel(ctx), photo.BlobKey)
+		}
+		return models.DamagePhoto{}, err
+	}
+	return photo, nil
+}
+
+// GetDamagePhoto отдаёт фото тем же, кому доступен сам акт. Вызывающий закрывает возвращённый поток.
+func (du DamageUsecase) GetDamagePhoto(ctx context.Context, reportId, photoId string) (models.DamagePhoto, io.ReadCloser, error) {
+	report, err := du.damageRepository.GetDamageReport(ctx, reportId)
+	if err != nil {
+		return models.DamagePhoto{}, nil, err
+	}
+	if err := du.checkReportAccess(ctx, report); err != nil {
+		return models.DamagePhoto{}, nil, err
+	}
+
+	photo, err := du.damageRepository.GetDamagePhoto(ctx, reportId, photoId)
+	if err != nil {
+		return models.DamagePhoto{}, nil, err
+	}
+	content, err := du.blobStore.Get(ctx, photo.BlobKey)
+	if err != nil {
+		return models.DamagePhoto{}, nil, err
+	}
+	return *photo, content, nil
+}
+
+// checkReportAccess пускает сотрудников и модераторов к любым актам, а клиента — только к актам по его товарам.
+// Чужой акт для клиента выглядит как несуществующий.
+func (du DamageUsecase) checkReportAccess(ctx context.Context, report *models.DamageReport) error {
+	switch ctx.Value(middleware.ContextKeyRole).(string) {
+	case "employee", "moderator":
+		return nil
+	case "client":
+		if report.ProductId == "" {
+			return errors.New("damage report not found")
+		}
+		owned, err := du.damageRepository.IsProductOwnedBy(ctx, report.ProductId, middleware.GetUserId(ctx))
+		if err != nil {
+			return err
+		}
+		if !owned {
+			return errors.New("damage report not found")
+		}
+		return nil
+	default:
+		return errors.New("this role is not allowed")
+	}
+}
@@ -0,0 +1,369 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	blobstoreMocks "avito_spring_staj_2025/internal/tests/mocks/blobstore_mocks"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"strings"
+	"testing"
+)
+
+func roleCtx(role string) func() context.Context {
+	return func() context.Context {
+		return context.WithValue(context.Background(), middleware.ContextKeyRole, role)
+	}
+}
+
+func clientCtx() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "client")
+	return context.WithValue(ctx, middleware.ContextKeyUserId, "customer1")
+}
+
+var pngPhoto = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+
+func TestDamageUsecase_CreateDamageReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.CreateDamageReportRequest
+		mockSetup   func(*repositoryMocks.MockDamageRepository)
+		expectedErr error
+	}{
+		{
+			name: "product report",
+			ctx:  roleCtx("employee"),
+			data: requests.CreateDamageReportRequest{ProductId: "prod1", Description: " мятая коробка ", Severity: models.DAMAGE_SEVERITY_MINOR},
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetProductPvz", mock.Anything, "prod1").Return("pvz1", nil)
+				m.On("CreateDamageReport", mock.Anything, mock.MatchedBy(func(r models.DamageReport) bool {
+					return r.Id != "" && r.ProductId == "prod1" && r.PvzId == "pvz1" && r.Description == "мятая коробка"
+				})).Return(nil)
+			},
+		},
+		{
+			name: "reception report",
+			ctx:  roleCtx("employee"),
+			data: requests.CreateDamageReportRequest{ReceptionId: "rec1", Description: "намокла паллета", Severity: models.DAMAGE_SEVERITY_SEVERE},
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetReceptionPvz", mock.Anything, "rec1").Return("pvz1", nil)
+				m.On("CreateDamageReport", mock.Anything, mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "unknown product",
+			ctx:  roleCtx("employee"),
+			data: requests.CreateDamageReportRequest{ProductId: "prod1", Description: "скол", Severity: models.DAMAGE_SEVERITY_MODERATE},
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetProductPvz", mock.Anything, "prod1").Return("", errors.New("product not found"))
+			},
+			expectedErr: errors.New("product not found"),
+		},
+		{
+			name:        "both targets",
+			ctx:         roleCtx("employee"),
+			data:        requests.CreateDamageReportRequest{ProductId: "prod1", ReceptionId: "rec1", Description: "скол", Severity: "minor"},
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository) {},
+			expectedErr: errors.New("product id or reception id is required"),
+		},
+		{
+			name:        "empty description",
+			ctx:         roleCtx("employee"),
+			data:        requests.CreateDamageReportRequest{ProductId: "prod1", Description: "  ", Severity: "minor"},
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository) {},
+			expectedErr: errors.New("description is required"),
+		},
+		{
+			name:        "invalid severity",
+			ctx:         roleCtx("employee"),
+			data:        requests.CreateDamageReportRequest{ProductId: "prod1", Description: "скол", Severity: "fatal"},
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository) {},
+			expectedErr: errors.New("invalid severity"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         roleCtx("moderator"),
+			data:        requests.CreateDamageReportRequest{ProductId: "prod1", Description: "скол", Severity: "minor"},
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockDamageRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewDamageUsecase(mockRepo, new(blobstoreMocks.MockBlobStore))
+
+			_, err := uc.CreateDamageReport(tt.ctx(), tt.data)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDamageUsecase_GetDamageReport(t *testing.T) {
+	productReport := &models.DamageReport{Id: "rep1", ProductId: "prod1"}
+	receptionReport := &models.DamageReport{Id: "rep2", ReceptionId: "rec1"}
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		reportId    string
+		mockSetup   func(*repositoryMocks.MockDamageRepository)
+		expectedErr error
+	}{
+		{
+			name:     "moderator",
+			ctx:      roleCtx("moderator"),
+			reportId: "rep1",
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetDamageReport", mock.Anything, "rep1").Return(productReport, nil)
+			},
+		},
+		{
+			name:     "client owns product",
+			ctx:      clientCtx,
+			reportId: "rep1",
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetDamageReport", mock.Anything, "rep1").Return(productReport, nil)
+				m.On("IsProductOwnedBy", mock.Anything, "prod1", "customer1").Return(true, nil)
+			},
+		},
+		{
+			name:     "client does not own product",
+			ctx:      clientCtx,
+			reportId: "rep1",
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetDamageReport", mock.Anything, "rep1").Return(productReport, nil)
+				m.On("IsProductOwnedBy", mock.Anything, "prod1", "customer1").Return(false, nil)
+			},
+			expectedErr: errors.New("damage report not found"),
+		},
+		{
+			name:     "client and reception report",
+			ctx:      clientCtx,
+			reportId: "rep2",
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetDamageReport", mock.Anything, "rep2").Return(receptionReport, nil)
+			},
+			expectedErr: errors.New("damage report not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockDamageRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewDamageUsecase(mockRepo, new(blobstoreMocks.MockBlobStore))
+
+			_, err := uc.GetDamageReport(tt.ctx(), tt.reportId)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDamageUsecase_GetDamageReports(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		productId   string
+		receptionId string
+		mockSetup   func(*repositoryMocks.MockDamageRepository)
+		expectedErr error
+	}{
+		{
+			name:        "employee by reception",
+			ctx:         roleCtx("employee"),
+			receptionId: "rec1",
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("GetDamageReports", mock.Anything, "", "rec1").Return([]models.DamageReport{}, nil)
+			},
+		},
+		{
+			name:      "client by own product",
+			ctx:       clientCtx,
+			productId: "prod1",
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("IsProductOwnedBy", mock.Anything, "prod1", "customer1").Return(true, nil)
+				m.On("GetDamageReports", mock.Anything, "prod1", "").Return([]models.DamageReport{}, nil)
+			},
+		},
+		{
+			name:      "client by foreign product",
+			ctx:       clientCtx,
+			productId: "prod1",
+			mockSetup: func(m *repositoryMocks.MockDamageRepository) {
+				m.On("IsProductOwnedBy", mock.Anything, "prod1", "customer1").Return(false, nil)
+			},
+			expectedErr: errors.New("product not found"),
+		},
+		{
+			name:        "client by reception",
+			ctx:         clientCtx,
+			receptionId: "rec1",
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "no filter",
+			ctx:         roleCtx("employee"),
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository) {},
+			expectedErr: errors.New("product id or reception id is required"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockDamageRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewDamageUsecase(mockRepo, new(blobstoreMocks.MockBlobStore))
+
+			_, err := uc.GetDamageReports(tt.ctx(), tt.productId, tt.receptionId)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDamageUsecase_UploadDamagePhoto(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		content     []byte
+		mockSetup   func(*repositoryMocks.MockDamageRepository, *blobstoreMocks.MockBlobStore)
+		expectedErr error
+	}{
+		{
+			name:    "success",
+			ctx:     roleCtx("employee"),
+			content: pngPhoto,
+			mockSetup: func(m *repositoryMocks.MockDamageRepository, store *blobstoreMocks.MockBlobStore) {
+				m.On("LockDamageReport", mock.Anything, "rep1").Return(&models.DamageReport{Id: "rep1"}, nil)
+				m.On("CountDamagePhotos", mock.Anything, "rep1").Return(2, nil)
+				store.On("Put", mock.Anything, mock.MatchedBy(func(key string) bool {
+					return strings.HasPrefix(key, "damage/rep1/") && strings.HasSuffix(key, ".png")
+				}), mock.Anything).Return(nil)
+				m.On("AddDamagePhoto", mock.Anything, mock.MatchedBy(func(p models.DamagePhoto) bool {
+					return p.ReportId == "rep1" && p.ContentType == "image/png" && p.Size == int64(len(pngPhoto))
+				})).Return(nil)
+			},
+		},
+		{
+			name:    "insert fails removes blob",
+			ctx:     roleCtx("employee"),
+			content: pngPhoto,
+			mockSetup: func(m *repositoryMocks.MockDamageRepository, store *blobstoreMocks.MockBlobStore) {
+				m.On("LockDamageReport", mock.Anything, "rep1").Return(&models.DamageReport{Id: "rep1"}, nil)
+				m.On("CountDamagePhotos", mock.Anything, "rep1").Return(0, nil)
+				store.On("Put", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				m.On("AddDamagePhoto", mock.Anything, mock.Anything).Return(errors.New("db error"))
+				store.On("Delete", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedErr: errors.New("db error"),
+		},
+		{
+			name:    "too many photos",
+			ctx:     roleCtx("employee"),
+			content: pngPhoto,
+			mockSetup: func(m *repositoryMocks.MockDamageRepository, _ *blobstoreMocks.MockBlobStore) {
+				m.On("LockDamageReport", mock.Anything, "rep1").Return(&models.DamageReport{Id: "rep1"}, nil)
+				m.On("CountDamagePhotos", mock.Anything, "rep1").Return(models.MAX_DAMAGE_PHOTOS, nil)
+			},
+			expectedErr: errors.New("too many photos"),
+		},
+		{
+			name:    "report not found",
+			ctx:     roleCtx("employee"),
+			content: pngPhoto,
+			mockSetup: func(m *repositoryMocks.MockDamageRepository, _ *blobstoreMocks.MockBlobStore) {
+				m.On("LockDamageReport", mock.Anything, "rep1").Return(nil, errors.New("damage report not found"))
+			},
+			expectedErr: errors.New("damage report not found"),
+		},
+		{
+			name:        "not an image",
+			ctx:         roleCtx("employee"),
+			content:     []byte("<html><script>alert(1)</script></html>"),
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository, _ *blobstoreMocks.MockBlobStore) {},
+			expectedErr: errors.New("unsupported photo type"),
+		},
+		{
+			name:        "too large",
+			ctx:         roleCtx("employee"),
+			content:     append(append([]byte{}, pngPhoto...), make([]byte, models.MAX_DAMAGE_PHOTO_SIZE)...),
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository, _ *blobstoreMocks.MockBlobStore) {},
+			expectedErr: errors.New("photo is too large"),
+		},
+		{
+			name:        "empty",
+			ctx:         roleCtx("employee"),
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository, _ *blobstoreMocks.MockBlobStore) {},
+			expectedErr: errors.New("photo is empty"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         roleCtx("client"),
+			content:     pngPhoto,
+			mockSetup:   func(_ *repositoryMocks.MockDamageRepository, _ *blobstoreMocks.MockBlobStore) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockDamageRepository)
+			mockStore := new(blobstoreMocks.MockBlobStore)
+			tt.mockSetup(mockRepo, mockStore)
+			uc := NewDamageUsecase(mockRepo, mockStore)
+
+			_, err := uc.UploadDamagePhoto(tt.ctx(), "rep1", tt.content)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDamageUsecase_GetDamagePhoto(t *testing.T) {
+	t.Run("client owns product", func(t *testing.T) {
+		mockRepo := new(repositoryMocks.MockDamageRepository)
+		mockStore := new(blobstoreMocks.MockBlobStore)
+		mockRepo.On("GetDamageReport", mock.Anything, "rep1").Return(&models.DamageReport{Id: "rep1", ProductId: "prod1"}, nil)
+		mockRepo.On("IsProductOwnedBy", mock.Anything, "prod1", "customer1").Return(true, nil)
+		mockRepo.On("GetDamagePhoto", mock.Anything, "rep1", "photo1").
+			Return(&models.DamagePhoto{Id: "photo1", ReportId: "rep1", BlobKey: "damage/rep1/photo1.png"}, nil)
+		mockStore.On("Get", mock.Anything, "damage/rep1/photo1.png").Return(io.NopCloser(bytes.NewReader(pngPhoto)), nil)
+		uc := NewDamageUsecase(mockRepo, mockStore)
+
+		photo, content, err := uc.GetDamagePhoto(clientCtx(), "rep1", "photo1")
+		assert.NoError(t, err)
+		assert.Equal(t, "photo1", photo.Id)
+		data, _ := io.ReadAll(content)
+		assert.Equal(t, pngPhoto, data)
+		mockRepo.AssertExpectations(t)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("client does not own product", func(t *testing.T) {
+		mockRepo := new(repositoryMocks.MockDamageRepository)
+		mockStore := new(blobstoreMocks.MockBlobStore)
+		mockRepo.On("GetDamageReport", mock.Anything, "rep1").Return(&models.DamageReport{Id: "rep1", ProductId: "prod1"}, nil)
+		mockRepo.On("IsProductOwnedBy", mock.Anything, "prod1", "customer1").Return(false, nil)
+		uc := NewDamageUsecase(mockRepo, mockStore)
+
+		_, content, err := uc.GetDamagePhoto(clientCtx(), "rep1", "photo1")
+		assert.Equal(t, errors.New("damage report not found"), err)
+		assert.Nil(t, content)
+		mockRepo.AssertExpectations(t)
+		mockStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,11 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/internal/eventstream/usecase"
+	"context"
+)
+
+type EventStreamUsecase interface {
+	OpenPvzStream(ctx context.Context, pvzId string, lastEventId int64) (*usecase.EventStream, error)
+	OpenCityStream(ctx context.Context, city string, lastEventId int64) (*usecase.EventStream, error)
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/eventstream/usecase"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHeartbeatInterval — как часто в простаивающий поток пишется комментарий, чтобы прокси не закрывали соединение.
+	DefaultHeartbeatInterval = 15 * time.Second
+	// reconnectDelay подсказывает клиенту паузу перед переподключением.
+	reconnectDelay = 3 * time.Second
+)
+
+type EventStreamHandler struct {
+	usecase   EventStreamUsecase
+	heartbeat time.Duration
+}
+
+func NewEventStreamHandler(usecase EventStreamUsecase) *EventStreamHandler {
+	return &EventStreamHandler{
+		usecase:   usecase,
+		heartbeat: DefaultHeartbeatInterval,
+	}
+}
+
+// StreamPvzEvents отдаёт активность приёмок ПВЗ как Server-Sent Events. id каждого сообщения — seq события,
+// поэтому после обрыва клиент продолжает с места остановки, присылая его в Last-Event-ID.
+func (h *EventStreamHandler) StreamPvzEvents(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+	lastEventId, err := parseLastEventId(r)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	stream, err := h.usecase.OpenPvzStream(r.Context(), pvzId, lastEventId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	h.serveStream(w, r, stream, requestID)
+}
+
+// StreamCityEvents — то же, что StreamPvzEvents, но по всем ПВЗ города из параметра city.
+func (h *EventStreamHandler) StreamCityEvents(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+
+	city := sanitizer.Sanitize(r.URL.Query().Get("city"))
+	lastEventId, err := parseLastEventId(r)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	stream, err := h.usecase.OpenCityStream(r.Context(), city, lastEventId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	h.serveStream(w, r, stream, requestID)
+}
+
+func parseLastEventId(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if value == "" {
+		return 0, nil
+	}
+	lastEventId, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || lastEventId < 0 {
+		return 0, errors.New("invalid last event id")
+	}
+	return lastEventId, nil
+}
+
+// serveStream держит соединение до отключения клиента. Если хаб отключил медленного клиента, поток завершается,
+// и клиент переподключается с Last-Event-ID.
+func (h *EventStreamHandler) serveStream(w http.ResponseWriter, r *http.Request, stream *usecase.EventStream, requestID string) {
+	defer stream.Close()
+	controller := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	logger.AccessLogger.Info("Event stream opened",
+		zap.String("request_id", requestID),
+		zap.String("url", r.URL.Path),
+		zap.Int("replayed", len(stream.Replay)),
+		zap.Bool("reset", stream.Reset),
+	)
+	defer logger.AccessLogger.Info("Event stream closed", zap.String("request_id", requestID))
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds()); err != nil {
+		return
+	}
+	if stream.Reset {
+		if _, err := io.WriteString(w, "event: reset\ndata: {}\n\n"); err != nil {
+			return
+		}
+	}
+	for _, event := range stream.Replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		logger.AccessLogger.Error("Streaming is not supported", zap.String("request_id", requestID), zap.Error(err))
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-stream.Events():
+			if !ok {
+				if stream.Lagged() {
+					logger.AccessLogger.Warn("Event stream dropped a slow client", zap.String("request_id", requestID))
+				}
+				return
+			}
+			if !stream.Accept(event) {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, event models.DomainEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+	return err
+}
+
+func (h *EventStreamHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "invalid last event id", "invalid city":
+		w.WriteHeader(http.StatusBadRequest)
+	case "pvz not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
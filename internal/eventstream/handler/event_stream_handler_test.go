@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/eventstream/usecase"
+	"avito_spring_staj_2025/internal/service/eventhub"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEvent(seq int64, eventType string) models.DomainEvent {
+	return models.DomainEvent{
+		Id: "event1", Seq: seq, Type: eventType, PvzId: "pvz1", Payload: json.RawMessage(`{"receptionId":"rec1"}`),
+		OccurredAt: time.Date(2025, 5, 8, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestEventStreamHandler_Errors(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	tests := []struct {
+		name           string
+		lastEventId    string
+		call           func(h *EventStreamHandler) http.HandlerFunc
+		mockBehavior   func(usecase *usecaseMocks.EventStreamUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid last event id",
+			lastEventId:    "abc",
+			call:           func(h *EventStreamHandler) http.HandlerFunc { return h.StreamPvzEvents },
+			mockBehavior:   func(usecase *usecaseMocks.EventStreamUsecaseMock) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid last event id"}`,
+		},
+		{
+			name: "unknown pvz",
+			call: func(h *EventStreamHandler) http.HandlerFunc { return h.StreamPvzEvents },
+			mockBehavior: func(usecase *usecaseMocks.EventStreamUsecaseMock) {
+				usecase.On("OpenPvzStream", mock.Anything, "pvz1", int64(0)).Return(nil, errors.New("pvz not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"pvz not found"}`,
+		},
+		{
+			name: "client",
+			call: func(h *EventStreamHandler) http.HandlerFunc { return h.StreamCityEvents },
+			mockBehavior: func(usecase *usecaseMocks.EventStreamUsecaseMock) {
+				usecase.On("OpenCityStream", mock.Anything, "Москва", int64(0)).Return(nil, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"errors":"this role is not allowed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.EventStreamUsecaseMock)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/pvz/events?city=Москва", nil)
+			req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz1"})
+			if tt.lastEventId != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventId)
+			}
+			w := httptest.NewRecorder()
+			tt.call(NewEventStreamHandler(mockUsecase))(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEventStreamHandler_Replay(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	hub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+	stream := usecase.NewEventStream(hub.Subscribe(nil), []models.DomainEvent{testEvent(11, models.EVENT_PRODUCT_ADDED)}, false)
+
+	mockUsecase := new(usecaseMocks.EventStreamUsecaseMock)
+	mockUsecase.On("OpenPvzStream", mock.Anything, "pvz1", int64(10)).Return(stream, nil)
+
+	// Клиент уже отключился: обработчик отдаёт догон и сразу завершается.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/pvz/pvz1/events", nil).WithContext(ctx)
+	req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz1"})
+	req.Header.Set("Last-Event-ID", "10")
+	w := httptest.NewRecorder()
+	NewEventStreamHandler(mockUsecase).StreamPvzEvents(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "retry: 3000\n\n"+
+		"id: 11\nevent: ProductAdded\n"+
+		`data: {"id":"event1","seq":11,"type":"ProductAdded","pvzId":"pvz1","payload":{"receptionId":"rec1"},"occurredAt":"2025-05-08T10:00:00Z"}`+
+		"\n\n", w.Body.String())
+	assert.Equal(t, 0, hub.Subscribers())
+}
+
+func TestEventStreamHandler_Reset(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	hub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+	mockUsecase := new(usecaseMocks.EventStreamUsecaseMock)
+	mockUsecase.On("OpenCityStream", mock.Anything, "Казань", int64(1)).
+		Return(usecase.NewEventStream(hub.Subscribe(nil), nil, true), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/pvz/events?city=Казань", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "1")
+	w := httptest.NewRecorder()
+	NewEventStreamHandler(mockUsecase).StreamCityEvents(w, req)
+
+	assert.Equal(t, "retry: 3000\n\nevent: reset\ndata: {}\n\n", w.Body.String())
+}
+
+func TestEventStreamHandler_Live(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	hub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+	stream := usecase.NewEventStream(hub.Subscribe(nil), []models.DomainEvent{testEvent(11, models.EVENT_PRODUCT_ADDED)}, false)
+
+	mockUsecase := new(usecaseMocks.EventStreamUsecaseMock)
+	mockUsecase.On("OpenPvzStream", mock.Anything, "pvz1", int64(10)).Return(stream, nil)
+	handler := NewEventStreamHandler(mockUsecase)
+	handler.heartbeat = 20 * time.Millisecond
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/pvz/{pvzId}/events", handler.StreamPvzEvents)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/pvz/pvz1/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "10")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	reader := bufio.NewReader(res.Body)
+	readMessage := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	assert.Equal(t, "retry: 3000\n", readMessage())
+	assert.Contains(t, readMessage(), "id: 11\n")
+
+	// Повтор из хаба уже отданного при догоне события пропускается.
+	require.NoError(t, hub.Publish(context.Background(), testEvent(11, models.EVENT_PRODUCT_ADDED)))
+	require.NoError(t, hub.Publish(context.Background(), testEvent(12, models.EVENT_RECEPTION_CLOSED)))
+	message := readMessage()
+	for message == ": ping\n" {
+		message = readMessage()
+	}
+	assert.True(t, strings.HasPrefix(message, "id: 12\nevent: ReceptionClosed\ndata: "), message)
+
+	message = readMessage()
+	assert.Equal(t, ": ping\n", message)
+
+	require.NoError(t, res.Body.Close())
+	require.Eventually(t, func() bool { return hub.Subscribers() == 0 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type EventStreamRepository struct {
+	db *sql.DB
+}
+
+func NewEventStreamRepository(db *sql.DB) EventStreamRepository {
+	return EventStreamRepository{
+		db: db,
+	}
+}
+
+func (r EventStreamRepository) PvzExists(ctx context.Context, pvzId string) (bool, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("PvzExists called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := sq.Select("1").
+		From("pvzs").
+		Where(sq.Eq{"id": pvzId}).
+		Prefix("SELECT EXISTS (").
+		Suffix(")").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return false, err
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&exists); err != nil {
+		logger.DBLogger.Error("failed to check pvz", zap.Error(err))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r EventStreamRepository) GetCityPvzIds(ctx context.Context, city string) ([]string, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetCityPvzIds called", zap.String("request_id", requestID), zap.String("city", city))
+
+	query, args, err := sq.Select("id").
+		From("pvzs").
+		Where(sq.Eq{"city": city}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query city pvzs", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	pvzIds := []string{}
+	for rows.Next() {
+		var pvzId string
+		if err := rows.Scan(&pvzId); err != nil {
+			logger.DBLogger.Error("failed to scan pvz id", zap.Error(err))
+			return nil, err
+		}
+		pvzIds = append(pvzIds, pvzId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pvzIds, nil
+}
+
+// GetEventsAfter читает события активности приёмок с seq больше afterSeq по возрастанию seq.
+// Берутся и ещё не опубликованные relay события: они уже зафиксированы, а дубли с живым потоком отсекаются по seq.
+func (r EventStreamRepository) GetEventsAfter(ctx context.Context, filter models.EventStreamFilter, afterSeq int64, limit int) ([]models.DomainEvent, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetEventsAfter called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", filter.PvzId),
+		zap.String("city", filter.City),
+		zap.Int64("after_seq", afterSeq),
+	)
+
+	builder := sq.Select("e.id", "e.seq", "e.event_type", "e.pvz_id", "e.payload", "e.occurred_at").
+		From("outbox_events e").
+		Where(sq.Gt{"e.seq": afterSeq}).
+		Where(sq.Eq{"e.event_type": models.ReceptionActivityEventTypes}).
+		OrderBy("e.seq").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+	if filter.PvzId != "" {
+		builder = builder.Where(sq.Eq{"e.pvz_id": filter.PvzId})
+	}
+	if filter.City != "" {
+		builder = builder.Join("pvzs p ON p.id = e.pvz_id").Where(sq.Eq{"p.city": filter.City})
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query outbox events", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	events := []models.DomainEvent{}
+	for rows.Next() {
+		var event models.DomainEvent
+		if err := rows.Scan(&event.Id, &event.Seq, &event.Type, &event.PvzId, &event.Payload, &event.OccurredAt); err != nil {
+			logger.DBLogger.Error("failed to scan outbox event", zap.Error(err))
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func newMockRepository(t *testing.T) (EventStreamRepository, sqlmock.Sqlmock) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return NewEventStreamRepository(db), mock
+}
+
+var eventColumns = []string{"id", "seq", "event_type", "pvz_id", "payload", "occurred_at"}
+
+func TestEventStreamRepository_PvzExists(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT EXISTS \( SELECT 1 FROM pvzs WHERE id = \$1 \)$`).
+		WithArgs("pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repo.PvzExists(context.Background(), "pvz1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventStreamRepository_GetCityPvzIds(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT id FROM pvzs WHERE city = \$1$`).
+		WithArgs("Москва").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("pvz1").AddRow("pvz2"))
+
+	pvzIds, err := repo.GetCityPvzIds(context.Background(), "Москва")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pvz1", "pvz2"}, pvzIds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventStreamRepository_GetEventsAfter(t *testing.T) {
+	occurredAt := time.Date(2025, 5, 8, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Pvz", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(`^SELECT e.id, e.seq, e.event_type, e.pvz_id, e.payload, e.occurred_at FROM outbox_events e `+
+			`WHERE e.seq > \$1 AND e.event_type IN \(\$2,\$3,\$4,\$5,\$6\) AND e.pvz_id = \$7 ORDER BY e.seq LIMIT 1001$`).
+			WithArgs(int64(10), models.EVENT_RECEPTION_OPENED, models.EVENT_PRODUCT_ADDED, models.EVENT_PRODUCT_DELETED,
+				models.EVENT_RECEPTION_CLOSED, models.EVENT_RECEPTION_REOPENED, "pvz1").
+			WillReturnRows(sqlmock.NewRows(eventColumns).
+				AddRow("event1", 11, models.EVENT_PRODUCT_ADDED, "pvz1", []byte(`{"productId":"prod1"}`), occurredAt))
+
+		events, err := repo.GetEventsAfter(context.Background(), models.EventStreamFilter{PvzId: "pvz1"}, 10, 1001)
+		require.NoError(t, err)
+		assert.Equal(t, []models.DomainEvent{{
+			Id: "event1", Seq: 11, Type: models.EVENT_PRODUCT_ADDED, PvzId: "pvz1",
+			Payload: json.RawMessage(`{"productId":"prod1"}`), OccurredAt: occurredAt,
+		}}, events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("City", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(`^SELECT e.id, e.seq, e.event_type, e.pvz_id, e.payload, e.occurred_at FROM outbox_events e `+
+			`JOIN pvzs p ON p.id = e.pvz_id WHERE e.seq > \$1 AND e.event_type IN \(\$2,\$3,\$4,\$5,\$6\) AND p.city = \$7 `+
+			`ORDER BY e.seq LIMIT 1001$`).
+			WithArgs(int64(0), models.EVENT_RECEPTION_OPENED, models.EVENT_PRODUCT_ADDED, models.EVENT_PRODUCT_DELETED,
+				models.EVENT_RECEPTION_CLOSED, models.EVENT_RECEPTION_REOPENED, "Казань").
+			WillReturnRows(sqlmock.NewRows(eventColumns))
+
+		events, err := repo.GetEventsAfter(context.Background(), models.EventStreamFilter{City: "Казань"}, 0, 1001)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,18 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/eventhub"
+	"context"
+)
+
+type EventStreamRepository interface {
+	PvzExists(ctx context.Context, pvzId string) (bool, error)
+	GetCityPvzIds(ctx context.Context, city string) ([]string, error)
+	GetEventsAfter(ctx context.Context, filter models.EventStreamFilter, afterSeq int64, limit int) ([]models.DomainEvent, error)
+}
+
+// EventHub — источник живых событий, на который подписываются потоки.
+type EventHub interface {
+	Subscribe(filter func(models.DomainEvent) bool) *eventhub.Subscription
+}
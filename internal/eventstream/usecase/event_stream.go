@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/eventhub"
+	"encoding/json"
+)
+
+// EventStream — открытый поток одного клиента: сначала Replay (события после Last-Event-ID), затем живые
+// события хаба. Живые события нужно пропускать через Accept, он отсеивает уже отправленные при догоне.
+type EventStream struct {
+	// Replay отдаётся клиенту до живых событий.
+	Replay []models.DomainEvent
+	// Reset означает, что пропущено больше, чем поток может догнать, и клиенту нужно перечитать состояние.
+	Reset bool
+
+	subscription *eventhub.Subscription
+	// city и cityPvzIds заданы только у потока по городу.
+	city       string
+	cityPvzIds map[string]bool
+	lastSeq    map[string]int64
+}
+
+func NewEventStream(subscription *eventhub.Subscription, replay []models.DomainEvent, reset bool) *EventStream {
+	stream := &EventStream{
+		Replay:       replay,
+		Reset:        reset,
+		subscription: subscription,
+		lastSeq:      make(map[string]int64),
+	}
+	for _, event := range replay {
+		stream.lastSeq[event.PvzId] = event.Seq
+	}
+	return stream
+}
+
+func (s *EventStream) Events() <-chan models.DomainEvent {
+	return s.subscription.Events()
+}
+
+// Lagged сообщает, что хаб отключил поток из-за медленного клиента.
+func (s *EventStream) Lagged() bool {
+	return s.subscription.Lagged()
+}
+
+func (s *EventStream) Close() {
+	s.subscription.Close()
+}
+
+// Accept решает, отдавать ли живое событие клиенту. Seq растёт в пределах ПВЗ, поэтому событие,
+// не новее последнего отправленного по этому ПВЗ, уже было в Replay. Поток по городу дополнительно
+// следит за PvzCreated, чтобы подхватывать ПВЗ, открытые после подключения.
+func (s *EventStream) Accept(event models.DomainEvent) bool {
+	if event.Type == models.EVENT_PVZ_CREATED {
+		if s.cityPvzIds != nil {
+			var payload models.PvzCreatedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err == nil && payload.City == s.city {
+				s.cityPvzIds[event.PvzId] = true
+			}
+		}
+		return false
+	}
+	if s.cityPvzIds != nil && !s.cityPvzIds[event.PvzId] {
+		return false
+	}
+	if event.Seq <= s.lastSeq[event.PvzId] {
+		return false
+	}
+	s.lastSeq[event.PvzId] = event.Seq
+	return true
+}
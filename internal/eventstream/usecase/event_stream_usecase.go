@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+)
+
+type EventStreamUsecase struct {
+	eventStreamRepository EventStreamRepository
+	hub                   EventHub
+}
+
+func NewEventStreamUsecase(eventStreamRepository EventStreamRepository, hub EventHub) EventStreamUsecase {
+	return EventStreamUsecase{
+		eventStreamRepository: eventStreamRepository,
+		hub:                   hub,
+	}
+}
+
+// OpenPvzStream открывает поток активности приёмок одного ПВЗ. lastEventId — seq последнего полученного
+// клиентом события, 0 — без догона.
+func (eu EventStreamUsecase) OpenPvzStream(ctx context.Context, pvzId string, lastEventId int64) (*EventStream, error) {
+	if err := checkStreamAccess(ctx, lastEventId); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(pvzId); err != nil {
+		return nil, errors.New("pvz not found")
+	}
+	exists, err := eu.eventStreamRepository.PvzExists(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("pvz not found")
+	}
+
+	// Подписка оформляется до чтения истории, чтобы между ними не потерялось ни одного события.
+	subscription := eu.hub.Subscribe(func(event models.DomainEvent) bool {
+		return event.PvzId == pvzId && models.IsReceptionActivityEvent(event.Type)
+	})
+	replay, reset, err := eu.replay(ctx, models.EventStreamFilter{PvzId: pvzId}, lastEventId)
+	if err != nil {
+		subscription.Close()
+		return nil, err
+	}
+	return NewEventStream(subscription, replay, reset), nil
+}
+
+// OpenCityStream открывает поток активности приёмок всех ПВЗ города, включая открытые после подключения.
+func (eu EventStreamUsecase) OpenCityStream(ctx context.Context, city string, lastEventId int64) (*EventStream, error) {
+	if err := checkStreamAccess(ctx, lastEventId); err != nil {
+		return nil, err
+	}
+	if city != "Москва" && city != "Санкт-Петербург" && city != "Казань" {
+		return nil, errors.New("invalid city")
+	}
+
+	// Хаб не знает городов ПВЗ, поэтому по городу фильтрует сам поток; PvzCreated нужен, чтобы узнавать о новых ПВЗ.
+	subscription := eu.hub.Subscribe(func(event models.DomainEvent) bool {
+		return event.Type == models.EVENT_PVZ_CREATED || models.IsReceptionActivityEvent(event.Type)
+	})
+	pvzIds, err := eu.eventStreamRepository.GetCityPvzIds(ctx, city)
+	if err != nil {
+		subscription.Close()
+		return nil, err
+	}
+	replay, reset, err := eu.replay(ctx, models.EventStreamFilter{City: city}, lastEventId)
+	if err != nil {
+		subscription.Close()
+		return nil, err
+	}
+
+	stream := NewEventStream(subscription, replay, reset)
+	stream.city = city
+	stream.cityPvzIds = make(map[string]bool, len(pvzIds))
+	for _, pvzId := range pvzIds {
+		stream.cityPvzIds[pvzId] = true
+	}
+	for _, event := range replay {
+		stream.cityPvzIds[event.PvzId] = true
+	}
+	return stream, nil
+}
+
+func checkStreamAccess(ctx context.Context, lastEventId int64) error {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return errors.New("this role is not allowed")
+	}
+	if lastEventId < 0 {
+		return errors.New("invalid last event id")
+	}
+	return nil
+}
+
+// replay читает на одно событие больше лимита, чтобы отличить «догнали ровно лимит» от «пропущено больше».
+func (eu EventStreamUsecase) replay(ctx context.Context, filter models.EventStreamFilter, lastEventId int64) ([]models.DomainEvent, bool, error) {
+	if lastEventId == 0 {
+		return nil, false, nil
+	}
+	events, err := eu.eventStreamRepository.GetEventsAfter(ctx, filter, lastEventId, models.MAX_EVENT_STREAM_REPLAY+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(events) > models.MAX_EVENT_STREAM_REPLAY {
+		return nil, true, nil
+	}
+	return events, false, nil
+}
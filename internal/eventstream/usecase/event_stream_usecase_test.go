@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/eventhub"
+	"avito_spring_staj_2025/internal/service/middleware"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	pvz1 = "11111111-1111-1111-1111-111111111111"
+	pvz2 = "22222222-2222-2222-2222-222222222222"
+)
+
+func roleCtx(role string) context.Context {
+	return context.WithValue(context.Background(), middleware.ContextKeyRole, role)
+}
+
+func activity(seq int64, pvzId, eventType string) models.DomainEvent {
+	return models.DomainEvent{Id: "event", Seq: seq, Type: eventType, PvzId: pvzId, Payload: json.RawMessage(`{}`)}
+}
+
+func pvzCreated(seq int64, pvzId, city string) models.DomainEvent {
+	payload, _ := json.Marshal(models.PvzCreatedPayload{PvzId: pvzId, City: city})
+	return models.DomainEvent{Id: "event", Seq: seq, Type: models.EVENT_PVZ_CREATED, PvzId: pvzId, Payload: payload}
+}
+
+// drain возвращает живые события, которые поток пропустил бы клиенту.
+func drain(t *testing.T, stream *EventStream) []int64 {
+	t.Helper()
+	var accepted []int64
+	for {
+		select {
+		case event := <-stream.Events():
+			if stream.Accept(event) {
+				accepted = append(accepted, event.Seq)
+			}
+		default:
+			return accepted
+		}
+	}
+}
+
+func TestEventStreamUsecase_OpenPvzStream(t *testing.T) {
+	t.Run("Live Events Of Pvz", func(t *testing.T) {
+		repo := new(repositoryMocks.MockEventStreamRepository)
+		repo.On("PvzExists", mock.Anything, pvz1).Return(true, nil)
+		hub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+
+		stream, err := NewEventStreamUsecase(repo, hub).OpenPvzStream(roleCtx("employee"), pvz1, 0)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Empty(t, stream.Replay)
+		repo.AssertNotCalled(t, "GetEventsAfter", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		for _, event := range []models.DomainEvent{
+			activity(1, pvz1, models.EVENT_RECEPTION_OPENED),
+			activity(2, pvz2, models.EVENT_RECEPTION_OPENED),
+			pvzCreated(3, pvz1, "Москва"),
+			activity(4, pvz1, models.EVENT_PRODUCT_ADDED),
+		} {
+			require.NoError(t, hub.Publish(context.Background(), event))
+		}
+		assert.Equal(t, []int64{1, 4}, drain(t, stream))
+	})
+
+	t.Run("Replay Then Live Without Duplicates", func(t *testing.T) {
+		repo := new(repositoryMocks.MockEventStreamRepository)
+		repo.On("PvzExists", mock.Anything, pvz1).Return(true, nil)
+		hub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+		repo.On("GetEventsAfter", mock.Anything, models.EventStreamFilter{PvzId: pvz1}, int64(10), models.MAX_EVENT_STREAM_REPLAY+1).
+			Run(func(mock.Arguments) {
+				// Событие 12 уже в базе и успело прийти в хаб между подпиской и чтением истории.
+				_ = hub.Publish(context.Background(), activity(12, pvz1, models.EVENT_PRODUCT_ADDED))
+			}).
+			Return([]models.DomainEvent{activity(11, pvz1, models.EVENT_PRODUCT_ADDED), activity(12, pvz1, models.EVENT_PRODUCT_ADDED)}, nil)
+
+		stream, err := NewEventStreamUsecase(repo, hub).OpenPvzStream(roleCtx("moderator"), pvz1, 10)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Len(t, stream.Replay, 2)
+		assert.False(t, stream.Reset)
+
+		require.NoError(t, hub.Publish(context.Background(), activity(13, pvz1, models.EVENT_RECEPTION_CLOSED)))
+		assert.Equal(t, []int64{13}, drain(t, stream))
+	})
+
+	t.Run("Too Far Behind", func(t *testing.T) {
+		repo := new(repositoryMocks.MockEventStreamRepository)
+		repo.On("PvzExists", mock.Anything, pvz1).Return(true, nil)
+		repo.On("GetEventsAfter", mock.Anything, mock.Anything, int64(1), models.MAX_EVENT_STREAM_REPLAY+1).
+			Return(make([]models.DomainEvent, models.MAX_EVENT_STREAM_REPLAY+1), nil)
+
+		stream, err := NewEventStreamUsecase(repo, eventhub.NewHub(1)).OpenPvzStream(roleCtx("employee"), pvz1, 1)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.True(t, stream.Reset)
+		assert.Empty(t, stream.Replay)
+	})
+
+	t.Run("Replay Error Closes Subscription", func(t *testing.T) {
+		repo := new(repositoryMocks.MockEventStreamRepository)
+		repo.On("PvzExists", mock.Anything, pvz1).Return(true, nil)
+		repo.On("GetEventsAfter", mock.Anything, mock.Anything, int64(5), mock.Anything).Return(nil, errors.New("db error"))
+		hub := eventhub.NewHub(1)
+
+		_, err := NewEventStreamUsecase(repo, hub).OpenPvzStream(roleCtx("employee"), pvz1, 5)
+		assert.EqualError(t, err, "db error")
+		assert.Equal(t, 0, hub.Subscribers())
+	})
+
+	t.Run("Unknown Pvz", func(t *testing.T) {
+		repo := new(repositoryMocks.MockEventStreamRepository)
+		repo.On("PvzExists", mock.Anything, pvz1).Return(false, nil)
+
+		_, err := NewEventStreamUsecase(repo, eventhub.NewHub(1)).OpenPvzStream(roleCtx("employee"), pvz1, 0)
+		assert.EqualError(t, err, "pvz not found")
+	})
+
+	t.Run("Malformed Pvz Id", func(t *testing.T) {
+		_, err := NewEventStreamUsecase(new(repositoryMocks.MockEventStreamRepository), eventhub.NewHub(1)).
+			OpenPvzStream(roleCtx("employee"), "pvz1", 0)
+		assert.EqualError(t, err, "pvz not found")
+	})
+
+	t.Run("Client", func(t *testing.T) {
+		_, err := NewEventStreamUsecase(new(repositoryMocks.MockEventStreamRepository), eventhub.NewHub(1)).
+			OpenPvzStream(roleCtx("client"), pvz1, 0)
+		assert.EqualError(t, err, "this role is not allowed")
+	})
+}
+
+func TestEventStreamUsecase_OpenCityStream(t *testing.T) {
+	t.Run("Tracks Pvzs Of City", func(t *testing.T) {
+		const pvz3 = "33333333-3333-3333-3333-333333333333"
+		repo := new(repositoryMocks.MockEventStreamRepository)
+		repo.On("GetCityPvzIds", mock.Anything, "Казань").Return([]string{pvz1}, nil)
+		repo.On("GetEventsAfter", mock.Anything, models.EventStreamFilter{City: "Казань"}, int64(3), models.MAX_EVENT_STREAM_REPLAY+1).
+			Return([]models.DomainEvent{activity(4, pvz1, models.EVENT_RECEPTION_OPENED)}, nil)
+		hub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+
+		stream, err := NewEventStreamUsecase(repo, hub).OpenCityStream(roleCtx("moderator"), "Казань", 3)
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Len(t, stream.Replay, 1)
+
+		for _, event := range []models.DomainEvent{
+			activity(4, pvz1, models.EVENT_RECEPTION_OPENED),
+			activity(5, pvz2, models.EVENT_RECEPTION_OPENED),
+			pvzCreated(6, pvz3, "Казань"),
+			pvzCreated(7, pvz2, "Москва"),
+			activity(8, pvz3, models.EVENT_RECEPTION_OPENED),
+			activity(9, pvz1, models.EVENT_PRODUCT_DELETED),
+			activity(10, pvz2, models.EVENT_PRODUCT_ADDED),
+		} {
+			require.NoError(t, hub.Publish(context.Background(), event))
+		}
+		assert.Equal(t, []int64{8, 9}, drain(t, stream))
+	})
+
+	t.Run("Invalid City", func(t *testing.T) {
+		_, err := NewEventStreamUsecase(new(repositoryMocks.MockEventStreamRepository), eventhub.NewHub(1)).
+			OpenCityStream(roleCtx("employee"), "Тверь", 0)
+		assert.EqualError(t, err, "invalid city")
+	})
+
+	t.Run("Negative Last Event Id", func(t *testing.T) {
+		_, err := NewEventStreamUsecase(new(repositoryMocks.MockEventStreamRepository), eventhub.NewHub(1)).
+			OpenCityStream(roleCtx("employee"), "Москва", -1)
+		assert.EqualError(t, err, "invalid last event id")
+	})
+}
@@ -0,0 +1,11 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/export/usecase"
+	"context"
+)
+
+type ExportUsecase interface {
+	Export(ctx context.Context, entity string, filter models.ExportFilter, writer usecase.RowWriter) error
+}
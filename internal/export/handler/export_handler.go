@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/export/usecase"
+	"avito_spring_staj_2025/internal/service/export"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ExportHandler struct {
+	usecase ExportUsecase
+}
+
+func NewExportHandler(usecase ExportUsecase) *ExportHandler {
+	return &ExportHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetRequestID(ctx)
+	sanitizer := bluemonday.UGCPolicy()
+
+	entity := mux.Vars(r)["entity"]
+	queryParams := r.URL.Query()
+
+	format, err := exportFormat(sanitizer.Sanitize(queryParams.Get("format")), r.Header.Get("Accept"))
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	var filter models.ExportFilter
+	if startDateStr := sanitizer.Sanitize(queryParams.Get("startDate")); startDateStr != "" {
+		filter.From, err = time.Parse(time.RFC3339, startDateStr)
+		if err != nil {
+			h.handleError(w, errors.New("invalid startDate"), requestID)
+			return
+		}
+	}
+	if endDateStr := sanitizer.Sanitize(queryParams.Get("endDate")); endDateStr != "" {
+		filter.To, err = time.Parse(time.RFC3339, endDateStr)
+		if err != nil {
+			h.handleError(w, errors.New("invalid endDate"), requestID)
+			return
+		}
+	}
+	filter.City = sanitizer.Sanitize(queryParams.Get("city"))
+
+	// Заголовки ответа отправляются только с первой строкой выгрузки,
+	// поэтому ошибки валидации и запроса к БД ещё можно вернуть обычным JSON.
+	out := &lazyResponseWriter{
+		w:           w,
+		contentType: export.CsvContentType,
+		filename:    fmt.Sprintf("%s_%s.%s", entity, time.Now().Format("20060102_150405"), format),
+	}
+	var writer usecase.RowWriter = export.NewCsvWriter(out)
+	if format == export.FORMAT_XLSX {
+		out.contentType = export.XlsxContentType
+		writer = export.NewXlsxWriter(out)
+	}
+
+	err = h.usecase.Export(ctx, entity, filter, writer)
+	if err != nil {
+		if !out.started {
+			h.handleError(w, err, requestID)
+			return
+		}
+		logger.AccessLogger.Error("Export interrupted",
+			zap.String("request_id", requestID),
+			zap.String("entity", entity),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.AccessLogger.Info("Export finished",
+		zap.String("request_id", requestID),
+		zap.String("entity", entity),
+		zap.String("format", format),
+	)
+}
+
+func exportFormat(format, accept string) (string, error) {
+	if format == "" {
+		switch {
+		case strings.Contains(accept, export.XlsxContentType):
+			return export.FORMAT_XLSX, nil
+		default:
+			return export.FORMAT_CSV, nil
+		}
+	}
+	switch format {
+	case export.FORMAT_CSV, export.FORMAT_XLSX:
+		return format, nil
+	default:
+		return "", errors.New("invalid export format")
+	}
+}
+
+type lazyResponseWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (l *lazyResponseWriter) Write(p []byte) (int, error) {
+	if !l.started {
+		l.started = true
+		l.w.Header().Set("Content-Type", l.contentType)
+		l.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, l.filename))
+		l.w.WriteHeader(http.StatusOK)
+	}
+	return l.w.Write(p)
+}
+
+func (h *ExportHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "invalid startDate", "invalid endDate", "invalid date range", "invalid export format":
+		w.WriteHeader(http.StatusBadRequest)
+	case "unknown export entity":
+		w.WriteHeader(http.StatusNotFound)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
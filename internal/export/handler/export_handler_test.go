@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"archive/zip"
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/export"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"bytes"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportHandler_Export(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                string
+		entity              string
+		query               string
+		accept              string
+		mockBehavior        func(*usecaseMocks.ExportUsecaseMock)
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:   "csv by default",
+			entity: "pvzs",
+			query:  "?startDate=2025-04-01T00:00:00Z&city=Москва",
+			mockBehavior: func(m *usecaseMocks.ExportUsecaseMock) {
+				m.On("Export", mock.Anything, "pvzs", models.ExportFilter{From: from, City: "Москва"}).
+					Return([][]string{{"id", "city"}, {"pvz1", "Москва"}}, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: export.CsvContentType,
+			expectedBody:        "id,city\npvz1,Москва\n",
+		},
+		{
+			name:   "xlsx by accept header",
+			entity: "receptions",
+			accept: export.XlsxContentType,
+			mockBehavior: func(m *usecaseMocks.ExportUsecaseMock) {
+				m.On("Export", mock.Anything, "receptions", models.ExportFilter{}).
+					Return([][]string{{"id"}, {"rec1"}}, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: export.XlsxContentType,
+		},
+		{
+			name:                "invalid format",
+			entity:              "pvzs",
+			query:               "?format=pdf",
+			mockBehavior:        func(m *usecaseMocks.ExportUsecaseMock) {},
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/json",
+			expectedBody:        `{"errors":"invalid export format"}` + "\n",
+		},
+		{
+			name:                "invalid start date",
+			entity:              "pvzs",
+			query:               "?startDate=yesterday",
+			mockBehavior:        func(m *usecaseMocks.ExportUsecaseMock) {},
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/json",
+			expectedBody:        `{"errors":"invalid startDate"}` + "\n",
+		},
+		{
+			name:   "unknown entity",
+			entity: "users",
+			mockBehavior: func(m *usecaseMocks.ExportUsecaseMock) {
+				m.On("Export", mock.Anything, "users", models.ExportFilter{}).
+					Return(nil, errors.New("unknown export entity"))
+			},
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/json",
+			expectedBody:        `{"errors":"unknown export entity"}` + "\n",
+		},
+		{
+			name:   "client is forbidden",
+			entity: "products",
+			mockBehavior: func(m *usecaseMocks.ExportUsecaseMock) {
+				m.On("Export", mock.Anything, "products", models.ExportFilter{}).
+					Return(nil, errors.New("this role is not allowed"))
+			},
+			expectedStatus:      http.StatusForbidden,
+			expectedContentType: "application/json",
+			expectedBody:        `{"errors":"this role is not allowed"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ExportUsecaseMock)
+			tt.mockBehavior(mockUsecase)
+			handler := NewExportHandler(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/export/"+tt.entity+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			req = mux.SetURLVars(req, map[string]string{"entity": tt.entity})
+			w := httptest.NewRecorder()
+
+			handler.Export(w, req)
+
+			res := w.Result()
+			defer func() {
+				_ = res.Body.Close()
+			}()
+			body, _ := io.ReadAll(res.Body)
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.Equal(t, tt.expectedContentType, res.Header.Get("Content-Type"))
+			switch {
+			case tt.expectedContentType == export.XlsxContentType:
+				archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+				require.NoError(t, err)
+				var sheet string
+				for _, file := range archive.File {
+					if file.Name == "xl/worksheets/sheet1.xml" {
+						rc, err := file.Open()
+						require.NoError(t, err)
+						content, _ := io.ReadAll(rc)
+						_ = rc.Close()
+						sheet = string(content)
+					}
+				}
+				assert.True(t, strings.Contains(sheet, "rec1"))
+				assert.Contains(t, res.Header.Get("Content-Disposition"), ".xlsx")
+			default:
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
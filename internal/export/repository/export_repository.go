@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type ExportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) ExportRepository {
+	return ExportRepository{
+		db: db,
+	}
+}
+
+func (r ExportRepository) StreamPvzs(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error {
+	queryBuilder := sq.Select("id", "registration_date", "city", "address", "capacity", "occupancy").
+		From("pvzs").
+		OrderBy("registration_date", "id").
+		PlaceholderFormat(sq.Dollar)
+	queryBuilder = applyFilter(queryBuilder, "registration_date", "city", filter)
+	return r.stream(ctx, "StreamPvzs", queryBuilder, fn)
+}
+
+func (r ExportRepository) StreamReceptions(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error {
+	queryBuilder := sq.Select("r.id", "r.date_time", "r.pvz_id", "p.city", "r.status").
+		From("receptions r").
+		Join("pvzs p ON p.id = r.pvz_id").
+		OrderBy("r.date_time", "r.id").
+		PlaceholderFormat(sq.Dollar)
+	queryBuilder = applyFilter(queryBuilder, "r.date_time", "p.city", filter)
+	return r.stream(ctx, "StreamReceptions", queryBuilder, fn)
+}
+
+func (r ExportRepository) StreamProducts(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error {
+	queryBuilder := sq.Select("pr.id", "pr.date_time", "pr.type", "pr.reception_id", "r.pvz_id", "p.city").
+		From("products pr").
+		Join("receptions r ON r.id = pr.reception_id").
+		Join("pvzs p ON p.id = r.pvz_id").
+		OrderBy("pr.date_time", "pr.id").
+		PlaceholderFormat(sq.Dollar)
+	queryBuilder = applyFilter(queryBuilder, "pr.date_time", "p.city", filter)
+	return r.stream(ctx, "StreamProducts", queryBuilder, fn)
+}
+
+func applyFilter(queryBuilder sq.SelectBuilder, dateColumn, cityColumn string, filter models.ExportFilter) sq.SelectBuilder {
+	if !filter.From.IsZero() {
+		queryBuilder = queryBuilder.Where(sq.GtOrEq{dateColumn: filter.From})
+	}
+	if !filter.To.IsZero() {
+		queryBuilder = queryBuilder.Where(sq.LtOrEq{dateColumn: filter.To})
+	}
+	if filter.City != "" {
+		queryBuilder = queryBuilder.Where(sq.Eq{cityColumn: filter.City})
+	}
+	return queryBuilder
+}
+
+// stream построчно читает результат запроса и передаёт значения в fn, не накапливая их в памяти.
+func (r ExportRepository) stream(ctx context.Context, method string, queryBuilder sq.SelectBuilder, fn func(values []string) error) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info(method+" called", zap.String("request_id", requestID))
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query export rows", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	raw := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			logger.DBLogger.Error("failed to scan export row", zap.Error(err))
+			return err
+		}
+		values := make([]string, len(raw))
+		for i, value := range raw {
+			values[i] = value.String
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("failed to iterate export rows", zap.Error(err))
+		return err
+	}
+
+	logger.DBLogger.Info(method+" finished",
+		zap.String("request_id", requestID),
+		zap.Int("rows", count),
+	)
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestExportRepository_StreamPvzs(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	registrationDate := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		filter       models.ExportFilter
+		mock         func(sqlmock.Sqlmock)
+		expectedRows [][]string
+		expectedErr  string
+	}{
+		{
+			name:   "Success",
+			filter: models.ExportFilter{From: from, City: "Москва"},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, occupancy FROM pvzs WHERE registration_date >= \$1 AND city = \$2 ORDER BY registration_date, id`).
+					WithArgs(from, "Москва").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city", "address", "capacity", "occupancy"}).
+						AddRow("pvz1", registrationDate, "Москва", "ул. Ленина, 1", nil, 3))
+			},
+			expectedRows: [][]string{{"pvz1", "2025-04-10T12:00:00Z", "Москва", "ул. Ленина, 1", "", "3"}},
+		},
+		{
+			name: "Query Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, occupancy FROM pvzs ORDER BY registration_date, id`).
+					WillReturnError(errors.New("db error"))
+			},
+			expectedErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				_ = db.Close()
+			}()
+
+			repo := NewExportRepository(db)
+			tt.mock(mock)
+
+			var rows [][]string
+			err = repo.StreamPvzs(ctx, tt.filter, func(values []string) error {
+				rows = append(rows, values)
+				return nil
+			})
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRows, rows)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExportRepository_StreamProducts(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+	to := time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery(`SELECT pr.id, pr.date_time, pr.type, pr.reception_id, r.pvz_id, p.city FROM products pr JOIN receptions r ON r.id = pr.reception_id JOIN pvzs p ON p.id = r.pvz_id WHERE pr.date_time <= \$1 ORDER BY pr.date_time, pr.id`).
+		WithArgs(to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "pvz_id", "city"}).
+			AddRow("prod1", "2025-04-11T10:00:00Z", "обувь", "rec1", "pvz1", "Казань").
+			AddRow("prod2", "2025-04-11T11:00:00Z", "одежда", "rec1", "pvz1", "Казань"))
+
+	repo := NewExportRepository(db)
+	stopErr := errors.New("client gone")
+	calls := 0
+	err = repo.StreamProducts(ctx, models.ExportFilter{To: to}, func(values []string) error {
+		calls++
+		return stopErr
+	})
+
+	assert.ErrorIs(t, err, stopErr)
+	assert.Equal(t, 1, calls)
+}
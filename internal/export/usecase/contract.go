@@ -0,0 +1,17 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+)
+
+type ExportRepository interface {
+	StreamPvzs(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error
+	StreamReceptions(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error
+	StreamProducts(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error
+}
+
+type RowWriter interface {
+	WriteRow(values []string) error
+	Close() error
+}
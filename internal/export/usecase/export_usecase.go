@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+)
+
+type ExportUsecase struct {
+	exportRepository ExportRepository
+}
+
+func NewExportUsecase(exportRepository ExportRepository) ExportUsecase {
+	return ExportUsecase{
+		exportRepository: exportRepository,
+	}
+}
+
+var exportColumns = map[string][]string{
+	models.EXPORT_ENTITY_PVZS:       {"id", "registration_date", "city", "address", "capacity", "occupancy"},
+	models.EXPORT_ENTITY_RECEPTIONS: {"id", "date_time", "pvz_id", "city", "status"},
+	models.EXPORT_ENTITY_PRODUCTS:   {"id", "date_time", "type", "reception_id", "pvz_id", "city"},
+}
+
+// Export выгружает сущности целиком по всем ПВЗ, поэтому доступен только сотрудникам и модераторам.
+func (eu ExportUsecase) Export(ctx context.Context, entity string, filter models.ExportFilter, writer RowWriter) error {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return errors.New("this role is not allowed")
+	}
+
+	columns, ok := exportColumns[entity]
+	if !ok {
+		return errors.New("unknown export entity")
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return errors.New("invalid date range")
+	}
+
+	stream := eu.exportRepository.StreamPvzs
+	switch entity {
+	case models.EXPORT_ENTITY_RECEPTIONS:
+		stream = eu.exportRepository.StreamReceptions
+	case models.EXPORT_ENTITY_PRODUCTS:
+		stream = eu.exportRepository.StreamProducts
+	}
+
+	if err := writer.WriteRow(columns); err != nil {
+		return err
+	}
+	if err := stream(ctx, filter, writer.WriteRow); err != nil {
+		return err
+	}
+	return writer.Close()
+}
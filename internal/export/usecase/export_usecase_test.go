@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/export"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestExportUsecase_Export(t *testing.T) {
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		role         string
+		entity       string
+		filter       models.ExportFilter
+		mockSetup    func(*repositoryMocks.MockExportRepository)
+		expectedErr  error
+		expectedBody string
+	}{
+		{
+			name:   "pvzs",
+			entity: models.EXPORT_ENTITY_PVZS,
+			filter: models.ExportFilter{From: from, To: to, City: "Москва"},
+			mockSetup: func(m *repositoryMocks.MockExportRepository) {
+				m.On("StreamPvzs", mock.Anything, models.ExportFilter{From: from, To: to, City: "Москва"}).
+					Return([][]string{{"pvz1", "2025-04-10T00:00:00Z", "Москва", "", "", "0"}}, nil)
+			},
+			expectedBody: "id,registration_date,city,address,capacity,occupancy\npvz1,2025-04-10T00:00:00Z,Москва,,,0\n",
+		},
+		{
+			name:   "receptions",
+			entity: models.EXPORT_ENTITY_RECEPTIONS,
+			mockSetup: func(m *repositoryMocks.MockExportRepository) {
+				m.On("StreamReceptions", mock.Anything, models.ExportFilter{}).
+					Return([][]string{{"rec1", "2025-04-10T10:00:00Z", "pvz1", "Казань", "close"}}, nil)
+			},
+			expectedBody: "id,date_time,pvz_id,city,status\nrec1,2025-04-10T10:00:00Z,pvz1,Казань,close\n",
+		},
+		{
+			name:   "products",
+			entity: models.EXPORT_ENTITY_PRODUCTS,
+			mockSetup: func(m *repositoryMocks.MockExportRepository) {
+				m.On("StreamProducts", mock.Anything, models.ExportFilter{}).Return(nil, nil)
+			},
+			expectedBody: "id,date_time,type,reception_id,pvz_id,city\n",
+		},
+		{
+			name:        "client is not allowed",
+			role:        "client",
+			entity:      models.EXPORT_ENTITY_PRODUCTS,
+			mockSetup:   func(m *repositoryMocks.MockExportRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "unknown entity",
+			entity:      "users",
+			mockSetup:   func(m *repositoryMocks.MockExportRepository) {},
+			expectedErr: errors.New("unknown export entity"),
+		},
+		{
+			name:        "invalid date range",
+			entity:      models.EXPORT_ENTITY_PVZS,
+			filter:      models.ExportFilter{From: to, To: from},
+			mockSetup:   func(m *repositoryMocks.MockExportRepository) {},
+			expectedErr: errors.New("invalid date range"),
+		},
+		{
+			name:   "repository error",
+			entity: models.EXPORT_ENTITY_PVZS,
+			mockSetup: func(m *repositoryMocks.MockExportRepository) {
+				m.On("StreamPvzs", mock.Anything, models.ExportFilter{}).Return(nil, errors.New("db error"))
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockExportRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewExportUsecase(mockRepo)
+
+			role := tt.role
+			if role == "" {
+				role = "employee"
+			}
+			ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, role)
+
+			var buf bytes.Buffer
+			err := uc.Export(ctx, tt.entity, tt.filter, export.NewCsvWriter(&buf))
+
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, buf.String())
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"context"
+)
+
+type InventoryUsecase interface {
+	StartInventory(ctx context.Context, pvzId string) (models.InventorySession, error)
+	ScanInventoryItem(ctx context.Context, sessionId string, data requests.InventoryScanRequest) (models.InventoryScan, error)
+	CompleteInventory(ctx context.Context, sessionId string) (models.InventorySession, error)
+	GetInventorySession(ctx context.Context, sessionId string) (models.InventorySession, error)
+	ApproveInventory(ctx context.Context, sessionId string, data requests.ApproveInventoryRequest) (models.InventorySession, error)
+}
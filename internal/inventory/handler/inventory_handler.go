@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type InventoryHandler struct {
+	usecase InventoryUsecase
+}
+
+func NewInventoryHandler(usecase InventoryUsecase) *InventoryHandler {
+	return &InventoryHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *InventoryHandler) StartInventory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	session, err := h.usecase.StartInventory(ctx, pvzId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, toInventorySessionResponse(session), requestID)
+}
+
+func (h *InventoryHandler) GetInventorySession(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	sessionId := sanitizer.Sanitize(mux.Vars(r)["sessionId"])
+
+	session, err := h.usecase.GetInventorySession(ctx, sessionId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toInventorySessionResponse(session), requestID)
+}
+
+func (h *InventoryHandler) ScanInventoryItem(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	sessionId := sanitizer.Sanitize(mux.Vars(r)["sessionId"])
+
+	var data requests.InventoryScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.Code = sanitizer.Sanitize(data.Code)
+
+	scan, err := h.usecase.ScanInventoryItem(ctx, sessionId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, responses.InventoryScanResponse{
+		Id:        scan.Id,
+		Code:      scan.Code,
+		ProductId: scan.ProductId,
+		Matched:   scan.ProductId != "",
+		ScannedAt: scan.ScannedAt,
+	}, requestID)
+}
+
+func (h *InventoryHandler) CompleteInventory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	sessionId := sanitizer.Sanitize(mux.Vars(r)["sessionId"])
+
+	session, err := h.usecase.CompleteInventory(ctx, sessionId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toInventorySessionResponse(session), requestID)
+}
+
+func (h *InventoryHandler) ApproveInventory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	sessionId := sanitizer.Sanitize(mux.Vars(r)["sessionId"])
+
+	var data requests.ApproveInventoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.Comment = sanitizer.Sanitize(data.Comment)
+
+	session, err := h.usecase.ApproveInventory(ctx, sessionId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toInventorySessionResponse(session), requestID)
+}
+
+func (h *InventoryHandler) writeJSON(w http.ResponseWriter, status int, response interface{}, requestID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.AccessLogger.Error("Failed to encode response",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+}
+
+func toInventorySessionResponse(session models.InventorySession) responses.InventorySessionResponse {
+	response := responses.InventorySessionResponse{
+		Id:              session.Id,
+		PvzId:           session.PvzId,
+		Status:          session.Status,
+		StartedBy:       session.StartedBy,
+		StartedAt:       session.StartedAt,
+		CompletedAt:     session.CompletedAt,
+		ApprovedBy:      session.ApprovedBy,
+		ApprovedAt:      session.ApprovedAt,
+		ApprovalComment: session.ApprovalComment,
+	}
+	if session.Report == nil {
+		return response
+	}
+
+	report := &responses.InventoryReportResponse{
+		ExpectedCount: session.Report.ExpectedCount,
+		ScannedCount:  session.Report.ScannedCount,
+		MatchedCount:  session.Report.MatchedCount,
+		Missing:       make([]responses.InventoryMissingItemResponse, 0, len(session.Report.Missing)),
+		Surplus:       make([]responses.InventorySurplusItemResponse, 0, len(session.Report.Surplus)),
+	}
+	for _, product := range session.Report.Missing {
+		report.Missing = append(report.Missing, responses.InventoryMissingItemResponse{
+			ProductId:  product.ProductId,
+			Type:       product.Type,
+			ExternalId: product.ExternalId,
+			CellCode:   product.CellCode,
+		})
+	}
+	for _, scan := range session.Report.Surplus {
+		report.Surplus = append(report.Surplus, responses.InventorySurplusItemResponse{Code: scan.Code})
+	}
+	response.Report = report
+	return response
+}
+
+func (h *InventoryHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "code is required", "invalid code", "comment is too long":
+		w.WriteHeader(http.StatusBadRequest)
+	case "pvz not found", "inventory session not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "inventory session already in progress", "inventory session is not in progress",
+		"inventory session is not completed", "product already scanned":
+		w.WriteHeader(http.StatusConflict)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
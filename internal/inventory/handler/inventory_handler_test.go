@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInventoryHandler(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	startedAt := time.Date(2025, 5, 3, 10, 0, 0, 0, time.UTC)
+	completedAt := startedAt.Add(time.Hour)
+	vars := map[string]string{"pvzId": "pvz1", "sessionId": "inv1"}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		call           func(h *InventoryHandler) http.HandlerFunc
+		mockBehavior   func(usecase *usecaseMocks.InventoryUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "start",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz1/inventory",
+			call:   func(h *InventoryHandler) http.HandlerFunc { return h.StartInventory },
+			mockBehavior: func(usecase *usecaseMocks.InventoryUsecaseMock) {
+				usecase.On("StartInventory", mock.Anything, "pvz1").Return(models.InventorySession{
+					Id: "inv1", PvzId: "pvz1", Status: models.INVENTORY_STATUS_IN_PROGRESS, StartedBy: "user1", StartedAt: startedAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":"inv1","pvzId":"pvz1","status":"in_progress","startedBy":"user1","startedAt":"2025-05-03T10:00:00Z"}`,
+		},
+		{
+			name:   "start twice",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz1/inventory",
+			call:   func(h *InventoryHandler) http.HandlerFunc { return h.StartInventory },
+			mockBehavior: func(usecase *usecaseMocks.InventoryUsecaseMock) {
+				usecase.On("StartInventory", mock.Anything, "pvz1").
+					Return(models.InventorySession{}, errors.New("inventory session already in progress"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"inventory session already in progress"}`,
+		},
+		{
+			name:   "scan surplus",
+			method: http.MethodPost,
+			path:   "/api/inventory/inv1/scans",
+			body:   `{"code":"unknown"}`,
+			call:   func(h *InventoryHandler) http.HandlerFunc { return h.ScanInventoryItem },
+			mockBehavior: func(usecase *usecaseMocks.InventoryUsecaseMock) {
+				usecase.On("ScanInventoryItem", mock.Anything, "inv1", requests.InventoryScanRequest{Code: "unknown"}).
+					Return(models.InventoryScan{Id: "scan1", Code: "unknown", ScannedAt: startedAt}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":"scan1","code":"unknown","matched":false,"scannedAt":"2025-05-03T10:00:00Z"}`,
+		},
+		{
+			name:   "scan without code",
+			method: http.MethodPost,
+			path:   "/api/inventory/inv1/scans",
+			body:   `{}`,
+			call:   func(h *InventoryHandler) http.HandlerFunc { return h.ScanInventoryItem },
+			mockBehavior: func(usecase *usecaseMocks.InventoryUsecaseMock) {
+				usecase.On("ScanInventoryItem", mock.Anything, "inv1", requests.InventoryScanRequest{}).
+					Return(models.InventoryScan{}, errors.New("code is required"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"code is required"}`,
+		},
+		{
+			name:   "complete",
+			method: http.MethodPost,
+			path:   "/api/inventory/inv1/complete",
+			call:   func(h *InventoryHandler) http.HandlerFunc { return h.CompleteInventory },
+			mockBehavior: func(usecase *usecaseMocks.InventoryUsecaseMock) {
+				usecase.On("CompleteInventory", mock.Anything, "inv1").Return(models.InventorySession{
+					Id: "inv1", PvzId: "pvz1", Status: models.INVENTORY_STATUS_COMPLETED, StartedAt: startedAt, CompletedAt: &completedAt,
+					Report: &models.InventoryReport{
+						ExpectedCount: 2, ScannedCount: 2, MatchedCount: 1,
+						Missing: []models.InventoryProduct{{ProductId: "prod2", Type: "обувь", CellCode: "A-1"}},
+						Surplus: []models.InventoryScan{{Code: "unknown"}},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"inv1","pvzId":"pvz1","status":"completed","startedBy":"","startedAt":"2025-05-03T10:00:00Z",
+				"completedAt":"2025-05-03T11:00:00Z","report":{"expectedCount":2,"scannedCount":2,"matchedCount":1,
+				"missing":[{"productId":"prod2","type":"обувь","cellCode":"A-1"}],"surplus":[{"code":"unknown"}]}}`,
+		},
+		{
+			name:   "get not found",
+			method: http.MethodGet,
+			path:   "/api/inventory/inv1",
+			call:   func(h *InventoryHandler) http.HandlerFunc { return h.GetInventorySession },
+			mockBehavior: func(usecase *usecaseMocks.InventoryUsecaseMock) {
+				usecase.On("GetInventorySession", mock.Anything, "inv1").
+					Return(models.InventorySession{}, errors.New("inventory session not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"inventory session not found"}`,
+		},
+		{
+			name:   "approve by employee",
+			method: http.MethodPost,
+			path:   "/api/inventory/inv1/approve",
+			body:   `{"comment":"ok"}`,
+			call:   func(h *InventoryHandler) http.HandlerFunc { return h.ApproveInventory },
+			mockBehavior: func(usecase *usecaseMocks.InventoryUsecaseMock) {
+				usecase.On("ApproveInventory", mock.Anything, "inv1", requests.ApproveInventoryRequest{Comment: "ok"}).
+					Return(models.InventorySession{}, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"errors":"this role is not allowed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.InventoryUsecaseMock)
+			handler := NewInventoryHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, vars)
+			w := httptest.NewRecorder()
+			tt.call(handler)(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+	openSessionPerPvzIndex  = "inventory_sessions_one_open_per_pvz"
+	scannedProductIndex     = "inventory_scans_session_product"
+	inventorySessionPvzFk   = "inventory_sessions_pvz_id_fkey"
+	inventorySessionColumns = "id, pvz_id, status, started_by, started_at, completed_at, expected_count, scanned_count, matched_count, approved_by, approved_at, approval_comment"
+)
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode && pqErr.Constraint == constraint
+}
+
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolationCode && pqErr.Constraint == constraint
+}
+
+type InventoryRepository struct {
+	db *sql.DB
+}
+
+func NewInventoryRepository(db *sql.DB) InventoryRepository {
+	return InventoryRepository{
+		db: db,
+	}
+}
+
+// WithinTransaction выполняет fn в одной транзакции; транзакция, уже открытая выше по стеку
+// любым репозиторием, переиспользуется.
+func (r InventoryRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return dbtx.WithinTransaction(ctx, r.db, fn)
+}
+
+func (r InventoryRepository) CreateInventorySession(ctx context.Context, session models.InventorySession) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CreateInventorySession called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", session.PvzId),
+	)
+
+	query, args, err := sq.Insert("inventory_sessions").
+		Columns("id", "pvz_id", "status", "started_by", "started_at").
+		Values(session.Id, session.PvzId, session.Status, session.StartedBy, session.StartedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err = dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		if isUniqueViolation(err, openSessionPerPvzIndex) {
+			return errors.New("inventory session already in progress")
+		}
+		if isForeignKeyViolation(err, inventorySessionPvzFk) {
+			return errors.New("pvz not found")
+		}
+		logger.DBLogger.Error("failed to insert inventory session", zap.Error(err))
+		return err
+	}
+
+	logger.DBLogger.Info("Inventory session successfully created",
+		zap.String("request_id", requestID),
+		zap.String("session_id", session.Id),
+	)
+	return nil
+}
+
+// GetInventorySession возвращает инвентаризацию вместе с отчётом, если она уже завершена.
+func (r InventoryRepository) GetInventorySession(ctx context.Context, sessionId string) (*models.InventorySession, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetInventorySession called", zap.String("request_id", requestID), zap.String("session_id", sessionId))
+
+	session, err := r.getInventorySession(ctx, sessionId, "")
+	if err != nil {
+		return nil, err
+	}
+	if session.Report != nil {
+		if err := r.attachDiscrepancies(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// LockInventorySession читает инвентаризацию без отчёта и блокирует её до конца транзакции.
+func (r InventoryRepository) LockInventorySession(ctx context.Context, sessionId string) (*models.InventorySession, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockInventorySession called", zap.String("request_id", requestID), zap.String("session_id", sessionId))
+
+	return r.getInventorySession(ctx, sessionId, "FOR UPDATE")
+}
+
+func (r InventoryRepository) getInventorySession(ctx context.Context, sessionId, suffix string) (*models.InventorySession, error) {
+	query, args, err := sq.Select(inventorySessionColumns).
+		From("inventory_sessions").
+		Where(sq.Eq{"id": sessionId}).
+		Suffix(suffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var session models.InventorySession
+	var completedAt, approvedAt sql.NullTime
+	var expectedCount, scannedCount, matchedCount sql.NullInt64
+	var approvedBy sql.NullString
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(
+		&session.Id, &session.PvzId, &session.Status, &session.StartedBy, &session.StartedAt, &completedAt,
+		&expectedCount, &scannedCount, &matchedCount, &approvedBy, &approvedAt, &session.ApprovalComment,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("inventory session not found")
+		}
+		logger.DBLogger.Error("failed to scan inventory session", zap.Error(err))
+		return nil, err
+	}
+
+	if completedAt.Valid {
+		session.CompletedAt = &completedAt.Time
+		session.Report = &models.InventoryReport{
+			ExpectedCount: int(expectedCount.Int64),
+			ScannedCount:  int(scannedCount.Int64),
+			MatchedCount:  int(matchedCount.Int64),
+		}
+	}
+	if approvedAt.Valid {
+		session.ApprovedAt = &approvedAt.Time
+	}
+	session.ApprovedBy = approvedBy.String
+	return &session, nil
+}
+
+func (r InventoryRepository) attachDiscrepancies(ctx context.Context, session *models.InventorySession) error {
+	query, args, err := sq.Select("kind", "product_id", "code", "type", "external_id", "cell_code").
+		From("inventory_discrepancies").
+		Where(sq.Eq{"session_id": session.Id}).
+		OrderBy("kind", "cell_code", "code").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query inventory discrepancies", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	for rows.Next() {
+		var kind, code, productType, externalId, cellCode string
+		var productId sql.NullString
+		if err := rows.Scan(&kind, &productId, &code, &productType, &externalId, &cellCode); err != nil {
+			logger.DBLogger.Error("failed to scan inventory discrepancy", zap.Error(err))
+			return err
+		}
+		switch kind {
+		case models.INVENTORY_DISCREPANCY_MISSING:
+			session.Report.Missing = append(session.Report.Missing, models.InventoryProduct{
+				ProductId:  productId.String,
+				Type:       productType,
+				ExternalId: externalId,
+				CellCode:   cellCode,
+			})
+		case models.INVENTORY_DISCREPANCY_SURPLUS:
+			session.Report.Surplus = append(session.Report.Surplus, models.InventoryScan{
+				SessionId: session.Id,
+				Code:      code,
+			})
+		}
+	}
+	return rows.Err()
+}
+
+// FindPvzProductsByCode ищет среди товаров ПВЗ те, на которые указывает код: id товара или его штрихкод.
+// Scanned показывает, что товар уже отсканирован в этой инвентаризации.
+func (r InventoryRepository) FindPvzProductsByCode(ctx context.Context, sessionId, pvzId, code string) ([]models.InventoryProductMatch, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("FindPvzProductsByCode called",
+		zap.String("request_id", requestID),
+		zap.String("session_id", sessionId),
+	)
+
+	query, args, err := sq.Select("p.id").
+		Column(sq.Expr("EXISTS (SELECT 1 FROM inventory_scans s WHERE s.session_id = ? AND s.product_id = p.id)", sessionId)).
+		From("products p").
+		Join("receptions r ON r.id = p.reception_id").
+		Where(sq.Eq{"r.pvz_id": pvzId, "p.status": models.ProductAtPvzStatuses}).
+		Where(sq.Or{sq.Expr("p.id::text = ?", code), sq.Eq{"p.external_id": code}}).
+		OrderBy("p.date_time").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query products by code", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var matches []models.InventoryProductMatch
+	for rows.Next() {
+		var match models.InventoryProductMatch
+		if err := rows.Scan(&match.ProductId, &match.Scanned); err != nil {
+			logger.DBLogger.Error("failed to scan product match", zap.Error(err))
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+func (r InventoryRepository) AddInventoryScan(ctx context.Context, scan models.InventoryScan) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("AddInventoryScan called",
+		zap.String("request_id", requestID),
+		zap.String("session_id", scan.SessionId),
+	)
+
+	var productId interface{}
+	if scan.ProductId != "" {
+		productId = scan.ProductId
+	}
+	query, args, err := sq.Insert("inventory_scans").
+		Columns("id", "session_id", "code", "product_id", "scanned_by", "scanned_at").
+		Values(scan.Id, scan.SessionId, scan.Code, productId, scan.ScannedBy, scan.ScannedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err = dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		if isUniqueViolation(err, scannedProductIndex) {
+			return errors.New("product already scanned")
+		}
+		logger.DBLogger.Error("failed to insert inventory scan", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r InventoryRepository) GetInventoryScans(ctx context.Context, sessionId string) ([]models.InventoryScan, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetInventoryScans called", zap.String("request_id", requestID), zap.String("session_id", sessionId))
+
+	query, args, err := sq.Select("id", "session_id", "code", "product_id", "scanned_by", "scanned_at").
+		From("inventory_scans").
+		Where(sq.Eq{"session_id": sessionId}).
+		OrderBy("scanned_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query inventory scans", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var scans []models.InventoryScan
+	for rows.Next() {
+		var scan models.InventoryScan
+		var productId sql.NullString
+		if err := rows.Scan(&scan.Id, &scan.SessionId, &scan.Code, &productId, &scan.ScannedBy, &scan.ScannedAt); err != nil {
+			logger.DBLogger.Error("failed to scan inventory scan", zap.Error(err))
+			return nil, err
+		}
+		scan.ProductId = productId.String
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
+
+// GetPvzStoredProducts возвращает товары, которые по данным системы сейчас лежат на ПВЗ.
+func (r InventoryRepository) GetPvzStoredProducts(ctx context.Context, pvzId string) ([]models.InventoryProduct, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetPvzStoredProducts called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := sq.Select("p.id", "p.type", "COALESCE(p.external_id, '')", "COALESCE(c.code, '')").
+		From("products p").
+		Join("receptions r ON r.id = p.reception_id").
+		LeftJoin("storage_cells c ON c.id = p.cell_id").
+		Where(sq.Eq{"r.pvz_id": pvzId, "p.status": models.ProductAtPvzStatuses}).
+		OrderBy("c.code", "p.date_time").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query pvz products", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var products []models.InventoryProduct
+	for rows.Next() {
+		var product models.InventoryProduct
+		if err := rows.Scan(&product.ProductId, &product.Type, &product.ExternalId, &product.CellCode); err != nil {
+			logger.DBLogger.Error("failed to scan pvz product", zap.Error(err))
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+// CompleteInventorySession сохраняет итоги пересчёта и расхождения. Завершить можно только идущую инвентаризацию.
+func (r InventoryRepository) CompleteInventorySession(ctx context.Context, session *models.InventorySession) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CompleteInventorySession called",
+		zap.String("request_id", requestID),
+		zap.String("session_id", session.Id),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		report := session.Report
+
+		query, args, err := sq.Update("inventory_sessions").
+			Set("status", models.INVENTORY_STATUS_COMPLETED).
+			Set("completed_at", session.CompletedAt).
+			Set("expected_count", report.ExpectedCount).
+			Set("scanned_count", report.ScannedCount).
+			Set("matched_count", report.MatchedCount).
+			Where(sq.Eq{"id": session.Id, "status": models.INVENTORY_STATUS_IN_PROGRESS}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			logger.DBLogger.Error("failed to complete inventory session", zap.Error(err))
+			return err
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return errors.New("inventory session is not in progress")
+		}
+
+		if len(report.Missing) == 0 && len(report.Surplus) == 0 {
+			return nil
+		}
+
+		insertBuilder := sq.Insert("inventory_discrepancies").
+			Columns("session_id", "kind", "product_id", "code", "type", "external_id", "cell_code").
+			PlaceholderFormat(sq.Dollar)
+		for _, product := range report.Missing {
+			insertBuilder = insertBuilder.Values(session.Id, models.INVENTORY_DISCREPANCY_MISSING, product.ProductId,
+				"", product.Type, product.ExternalId, product.CellCode)
+		}
+		for _, scan := range report.Surplus {
+			insertBuilder = insertBuilder.Values(session.Id, models.INVENTORY_DISCREPANCY_SURPLUS, nil,
+				scan.Code, "", "", "")
+		}
+		query, args, err = insertBuilder.ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert inventory discrepancies", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+}
+
+// ApproveInventorySession фиксирует подпись модератора под завершённой инвентаризацией.
+func (r InventoryRepository) ApproveInventorySession(ctx context.Context, session *models.InventorySession) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("ApproveInventorySession called",
+		zap.String("request_id", requestID),
+		zap.String("session_id", session.Id),
+	)
+
+	query, args, err := sq.Update("inventory_sessions").
+		Set("status", models.INVENTORY_STATUS_APPROVED).
+		Set("approved_by", session.ApprovedBy).
+		Set("approved_at", session.ApprovedAt).
+		Set("approval_comment", session.ApprovalComment).
+		Where(sq.Eq{"id": session.Id, "status": models.INVENTORY_STATUS_COMPLETED}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to approve inventory session", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("inventory session is not completed")
+	}
+	return nil
+}
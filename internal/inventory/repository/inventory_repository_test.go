@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func newMockRepository(t *testing.T) (InventoryRepository, sqlmock.Sqlmock) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return NewInventoryRepository(db), mock
+}
+
+var sessionColumns = []string{"id", "pvz_id", "status", "started_by", "started_at", "completed_at",
+	"expected_count", "scanned_count", "matched_count", "approved_by", "approved_at", "approval_comment"}
+
+func TestInventoryRepository_CreateInventorySession(t *testing.T) {
+	startedAt := time.Now()
+	session := models.InventorySession{Id: "inv1", PvzId: "pvz1", Status: models.INVENTORY_STATUS_IN_PROGRESS, StartedBy: "user1", StartedAt: startedAt}
+	query := `^INSERT INTO inventory_sessions \(id,pvz_id,status,started_by,started_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\)$`
+
+	tests := []struct {
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("inv1", "pvz1", models.INVENTORY_STATUS_IN_PROGRESS, "user1", startedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Already In Progress",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: openSessionPerPvzIndex})
+			},
+			errMsg: "inventory session already in progress",
+		},
+		{
+			name: "Pvz Not Found",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WillReturnError(&pq.Error{Code: foreignKeyViolationCode, Constraint: inventorySessionPvzFk})
+			},
+			errMsg: "pvz not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			tt.mock(mock)
+
+			err := repo.CreateInventorySession(context.Background(), session)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestInventoryRepository_GetInventorySession(t *testing.T) {
+	startedAt := time.Date(2025, 5, 3, 10, 0, 0, 0, time.UTC)
+	completedAt := startedAt.Add(time.Hour)
+
+	t.Run("Completed With Report", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(`^SELECT id, pvz_id, status, .* FROM inventory_sessions WHERE id = \$1$`).
+			WithArgs("inv1").
+			WillReturnRows(sqlmock.NewRows(sessionColumns).
+				AddRow("inv1", "pvz1", models.INVENTORY_STATUS_COMPLETED, "user1", startedAt, completedAt, 2, 2, 1, nil, nil, ""))
+		mock.ExpectQuery(`^SELECT kind, product_id, code, type, external_id, cell_code FROM inventory_discrepancies ` +
+			`WHERE session_id = \$1 ORDER BY kind, cell_code, code$`).
+			WithArgs("inv1").
+			WillReturnRows(sqlmock.NewRows([]string{"kind", "product_id", "code", "type", "external_id", "cell_code"}).
+				AddRow(models.INVENTORY_DISCREPANCY_MISSING, "prod2", "", "обувь", "", "A-1").
+				AddRow(models.INVENTORY_DISCREPANCY_SURPLUS, nil, "unknown", "", "", ""))
+
+		session, err := repo.GetInventorySession(context.Background(), "inv1")
+		require.NoError(t, err)
+		assert.Equal(t, &models.InventorySession{
+			Id: "inv1", PvzId: "pvz1", Status: models.INVENTORY_STATUS_COMPLETED, StartedBy: "user1",
+			StartedAt: startedAt, CompletedAt: &completedAt,
+			Report: &models.InventoryReport{
+				ExpectedCount: 2, ScannedCount: 2, MatchedCount: 1,
+				Missing: []models.InventoryProduct{{ProductId: "prod2", Type: "обувь", CellCode: "A-1"}},
+				Surplus: []models.InventoryScan{{SessionId: "inv1", Code: "unknown"}},
+			},
+		}, session)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(`^SELECT id, pvz_id, status, .* FROM inventory_sessions WHERE id = \$1$`).
+			WithArgs("inv1").
+			WillReturnRows(sqlmock.NewRows(sessionColumns))
+
+		_, err := repo.GetInventorySession(context.Background(), "inv1")
+		assert.EqualError(t, err, "inventory session not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInventoryRepository_LockInventorySession(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT id, pvz_id, status, .* FROM inventory_sessions WHERE id = \$1 FOR UPDATE$`).
+		WithArgs("inv1").
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow("inv1", "pvz1", models.INVENTORY_STATUS_IN_PROGRESS, "", time.Now(), nil, nil, nil, nil, nil, nil, ""))
+
+	session, err := repo.LockInventorySession(context.Background(), "inv1")
+	require.NoError(t, err)
+	assert.Equal(t, models.INVENTORY_STATUS_IN_PROGRESS, session.Status)
+	assert.Nil(t, session.Report)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInventoryRepository_FindPvzProductsByCode(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT p.id, EXISTS \(SELECT 1 FROM inventory_scans s WHERE s.session_id = \$1 AND s.product_id = p.id\) `+
+		`FROM products p JOIN receptions r ON r.id = p.reception_id `+
+		`WHERE p.status IN \(\$2,\$3,\$4,\$5\) AND r.pvz_id = \$6 AND \(p.id::text = \$7 OR p.external_id = \$8\) `+
+		`ORDER BY p.date_time$`).
+		WithArgs("inv1", models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_RETURNED, models.PRODUCT_STATUS_REFUSED,
+			models.PRODUCT_STATUS_RETURN_TO_SENDER, "pvz1", "4600001", "4600001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "exists"}).
+			AddRow("prod1", true).
+			AddRow("prod2", false))
+
+	matches, err := repo.FindPvzProductsByCode(context.Background(), "inv1", "pvz1", "4600001")
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryProductMatch{{ProductId: "prod1", Scanned: true}, {ProductId: "prod2"}}, matches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInventoryRepository_AddInventoryScan(t *testing.T) {
+	scannedAt := time.Now()
+	query := `^INSERT INTO inventory_scans \(id,session_id,code,product_id,scanned_by,scanned_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\)$`
+
+	t.Run("Surplus Without Product", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).
+			WithArgs("scan1", "inv1", "unknown", nil, "user1", scannedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.AddInventoryScan(context.Background(), models.InventoryScan{
+			Id: "scan1", SessionId: "inv1", Code: "unknown", ScannedBy: "user1", ScannedAt: scannedAt,
+		})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Scanned", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).
+			WithArgs("scan1", "inv1", "prod1", "prod1", "", scannedAt).
+			WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: scannedProductIndex})
+
+		err := repo.AddInventoryScan(context.Background(), models.InventoryScan{
+			Id: "scan1", SessionId: "inv1", Code: "prod1", ProductId: "prod1", ScannedAt: scannedAt,
+		})
+		assert.EqualError(t, err, "product already scanned")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInventoryRepository_GetPvzStoredProducts(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT p.id, p.type, COALESCE\(p.external_id, ''\), COALESCE\(c.code, ''\) FROM products p `+
+		`JOIN receptions r ON r.id = p.reception_id LEFT JOIN storage_cells c ON c.id = p.cell_id `+
+		`WHERE p.status IN \(\$1,\$2,\$3,\$4\) AND r.pvz_id = \$5 ORDER BY c.code, p.date_time$`).
+		WithArgs(models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_RETURNED, models.PRODUCT_STATUS_REFUSED,
+			models.PRODUCT_STATUS_RETURN_TO_SENDER, "pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "external_id", "code"}).
+			AddRow("prod1", "обувь", "4600001", "A-1").
+			AddRow("prod2", "одежда", "", ""))
+
+	products, err := repo.GetPvzStoredProducts(context.Background(), "pvz1")
+	require.NoError(t, err)
+	assert.Equal(t, []models.InventoryProduct{
+		{ProductId: "prod1", Type: "обувь", ExternalId: "4600001", CellCode: "A-1"},
+		{ProductId: "prod2", Type: "одежда"},
+	}, products)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInventoryRepository_CompleteInventorySession(t *testing.T) {
+	completedAt := time.Now()
+	updateQuery := `^UPDATE inventory_sessions SET status = \$1, completed_at = \$2, expected_count = \$3, ` +
+		`scanned_count = \$4, matched_count = \$5 WHERE id = \$6 AND status = \$7$`
+	session := &models.InventorySession{
+		Id: "inv1", CompletedAt: &completedAt,
+		Report: &models.InventoryReport{
+			ExpectedCount: 2, ScannedCount: 2, MatchedCount: 1,
+			Missing: []models.InventoryProduct{{ProductId: "prod2", Type: "обувь", CellCode: "A-1"}},
+			Surplus: []models.InventoryScan{{Code: "unknown"}},
+		},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(updateQuery).
+			WithArgs(models.INVENTORY_STATUS_COMPLETED, &completedAt, 2, 2, 1, "inv1", models.INVENTORY_STATUS_IN_PROGRESS).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`^INSERT INTO inventory_discrepancies \(session_id,kind,product_id,code,type,external_id,cell_code\) `+
+			`VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\),\(\$8,\$9,\$10,\$11,\$12,\$13,\$14\)$`).
+			WithArgs("inv1", models.INVENTORY_DISCREPANCY_MISSING, "prod2", "", "обувь", "", "A-1",
+				"inv1", models.INVENTORY_DISCREPANCY_SURPLUS, nil, "unknown", "", "", "").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.CompleteInventorySession(context.Background(), session))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not In Progress", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(updateQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.CompleteInventorySession(context.Background(), session)
+		assert.EqualError(t, err, "inventory session is not in progress")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInventoryRepository_ApproveInventorySession(t *testing.T) {
+	approvedAt := time.Now()
+	session := &models.InventorySession{Id: "inv1", ApprovedBy: "mod1", ApprovedAt: &approvedAt, ApprovalComment: "ok"}
+	query := `^UPDATE inventory_sessions SET status = \$1, approved_by = \$2, approved_at = \$3, approval_comment = \$4 ` +
+		`WHERE id = \$5 AND status = \$6$`
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).
+			WithArgs(models.INVENTORY_STATUS_APPROVED, "mod1", &approvedAt, "ok", "inv1", models.INVENTORY_STATUS_COMPLETED).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.ApproveInventorySession(context.Background(), session))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Completed", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.ApproveInventorySession(context.Background(), session)
+		assert.EqualError(t, err, "inventory session is not completed")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+)
+
+type InventoryRepository interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	CreateInventorySession(ctx context.Context, session models.InventorySession) error
+	GetInventorySession(ctx context.Context, sessionId string) (*models.InventorySession, error)
+	LockInventorySession(ctx context.Context, sessionId string) (*models.InventorySession, error)
+	FindPvzProductsByCode(ctx context.Context, sessionId, pvzId, code string) ([]models.InventoryProductMatch, error)
+	AddInventoryScan(ctx context.Context, scan models.InventoryScan) error
+	GetInventoryScans(ctx context.Context, sessionId string) ([]models.InventoryScan, error)
+	GetPvzStoredProducts(ctx context.Context, pvzId string) ([]models.InventoryProduct, error)
+	CompleteInventorySession(ctx context.Context, session *models.InventorySession) error
+	ApproveInventorySession(ctx context.Context, session *models.InventorySession) error
+}
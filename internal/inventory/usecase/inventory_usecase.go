@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+type InventoryUsecase struct {
+	inventoryRepository InventoryRepository
+}
+
+func NewInventoryUsecase(inventoryRepository InventoryRepository) InventoryUsecase {
+	return InventoryUsecase{
+		inventoryRepository: inventoryRepository,
+	}
+}
+
+// StartInventory открывает инвентаризацию ПВЗ. Одновременно на ПВЗ может быть только одна неподписанная инвентаризация.
+func (iu InventoryUsecase) StartInventory(ctx context.Context, pvzId string) (models.InventorySession, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.InventorySession{}, errors.New("this role is not allowed")
+	}
+
+	session := models.InventorySession{
+		Id:        uuid.New().String(),
+		PvzId:     pvzId,
+		Status:    models.INVENTORY_STATUS_IN_PROGRESS,
+		StartedBy: middleware.GetUserId(ctx),
+		StartedAt: time.Now(),
+	}
+	if err := iu.inventoryRepository.CreateInventorySession(ctx, session); err != nil {
+		return models.InventorySession{}, err
+	}
+	return session, nil
+}
+
+// ScanInventoryItem записывает отсканированный код. Код, не совпавший ни с одним товаром ПВЗ, сохраняется как излишек.
+// Если по штрихкоду находится несколько товаров, скан засчитывается первому ещё не отсканированному.
+func (iu InventoryUsecase) ScanInventoryItem(ctx context.Context, sessionId string, data requests.InventoryScanRequest) (models.InventoryScan, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.InventoryScan{}, errors.New("this role is not allowed")
+	}
+	code := strings.TrimSpace(data.Code)
+	if code == "" {
+		return models.InventoryScan{}, errors.New("code is required")
+	}
+	if len([]rune(code)) > models.MAX_INVENTORY_CODE_LENGTH {
+		return models.InventoryScan{}, errors.New("invalid code")
+	}
+
+	scan := models.InventoryScan{
+		Id:        uuid.New().String(),
+		SessionId: sessionId,
+		Code:      code,
+		ScannedBy: middleware.GetUserId(ctx),
+		ScannedAt: time.Now(),
+	}
+	err := iu.inventoryRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		session, err := iu.inventoryRepository.LockInventorySession(ctx, sessionId)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.INVENTORY_STATUS_IN_PROGRESS {
+			return errors.New("inventory session is not in progress")
+		}
+
+		matches, err := iu.inventoryRepository.FindPvzProductsByCode(ctx, sessionId, session.PvzId, code)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			if !match.Scanned {
+				scan.ProductId = match.ProductId
+				break
+			}
+		}
+		if len(matches) > 0 && scan.ProductId == "" {
+			return errors.New("product already scanned")
+		}
+
+		return iu.inventoryRepository.AddInventoryScan(ctx, scan)
+	})
+	if err != nil {
+		return models.InventoryScan{}, err
+	}
+	return scan, nil
+}
+
+// CompleteInventory завершает пересчёт и сохраняет сверку с товарами, которые числятся на ПВЗ.
+func (iu InventoryUsecase) CompleteInventory(ctx context.Context, sessionId string) (models.InventorySession, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.InventorySession{}, errors.New("this role is not allowed")
+	}
+
+	var session *models.InventorySession
+	err := iu.inventoryRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		session, err = iu.inventoryRepository.LockInventorySession(ctx, sessionId)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.INVENTORY_STATUS_IN_PROGRESS {
+			return errors.New("inventory session is not in progress")
+		}
+
+		expected, err := iu.inventoryRepository.GetPvzStoredProducts(ctx, session.PvzId)
+		if err != nil {
+			return err
+		}
+		scans, err := iu.inventoryRepository.GetInventoryScans(ctx, sessionId)
+		if err != nil {
+			return err
+		}
+
+		report := reconcileInventory(expected, scans)
+		completedAt := time.Now()
+		session.Status = models.INVENTORY_STATUS_COMPLETED
+		session.CompletedAt = &completedAt
+		session.Report = &report
+		return iu.inventoryRepository.CompleteInventorySession(ctx, session)
+	})
+	if err != nil {
+		return models.InventorySession{}, err
+	}
+	return *session, nil
+}
+
+func (iu InventoryUsecase) GetInventorySession(ctx context.Context, sessionId string) (models.InventorySession, error) {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return models.InventorySession{}, errors.New("this role is not allowed")
+	}
+
+	session, err := iu.inventoryRepository.GetInventorySession(ctx, sessionId)
+	if err != nil {
+		return models.InventorySession{}, err
+	}
+	return *session, nil
+}
+
+// ApproveInventory — подпись модератора под итогами завершённой инвентаризации.
+func (iu InventoryUsecase) ApproveInventory(ctx context.Context, sessionId string, data requests.ApproveInventoryRequest) (models.InventorySession, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.InventorySession{}, errors.New("this role is not allowed")
+	}
+	comment := strings.TrimSpace(data.Comment)
+	if len([]rune(comment)) > models.MAX_INVENTORY_COMMENT_LENGTH {
+		return models.InventorySession{}, errors.New("comment is too long")
+	}
+
+	session, err := iu.inventoryRepository.GetInventorySession(ctx, sessionId)
+	if err != nil {
+		return models.InventorySession{}, err
+	}
+	if session.Status != models.INVENTORY_STATUS_COMPLETED {
+		return models.InventorySession{}, errors.New("inventory session is not completed")
+	}
+
+	approvedAt := time.Now()
+	session.Status = models.INVENTORY_STATUS_APPROVED
+	session.ApprovedBy = middleware.GetUserId(ctx)
+	session.ApprovedAt = &approvedAt
+	session.ApprovalComment = comment
+	if err := iu.inventoryRepository.ApproveInventorySession(ctx, session); err != nil {
+		return models.InventorySession{}, err
+	}
+	return *session, nil
+}
@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+)
+
+func roleCtx(role string) func() context.Context {
+	return func() context.Context {
+		return context.WithValue(context.Background(), middleware.ContextKeyRole, role)
+	}
+}
+
+func TestInventoryUsecase_StartInventory(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		mockSetup   func(*repositoryMocks.MockInventoryRepository)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  roleCtx("employee"),
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("CreateInventorySession", mock.Anything, mock.MatchedBy(func(s models.InventorySession) bool {
+					return s.Id != "" && s.PvzId == "pvz1" && s.Status == models.INVENTORY_STATUS_IN_PROGRESS
+				})).Return(nil)
+			},
+		},
+		{
+			name: "already in progress",
+			ctx:  roleCtx("employee"),
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("CreateInventorySession", mock.Anything, mock.Anything).
+					Return(errors.New("inventory session already in progress"))
+			},
+			expectedErr: errors.New("inventory session already in progress"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         roleCtx("moderator"),
+			mockSetup:   func(_ *repositoryMocks.MockInventoryRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockInventoryRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewInventoryUsecase(mockRepo)
+
+			_, err := uc.StartInventory(tt.ctx(), "pvz1")
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInventoryUsecase_ScanInventoryItem(t *testing.T) {
+	inProgress := &models.InventorySession{Id: "inv1", PvzId: "pvz1", Status: models.INVENTORY_STATUS_IN_PROGRESS}
+
+	tests := []struct {
+		name              string
+		ctx               func() context.Context
+		code              string
+		mockSetup         func(*repositoryMocks.MockInventoryRepository)
+		expectedProductId string
+		expectedErr       error
+	}{
+		{
+			name: "matched",
+			ctx:  roleCtx("employee"),
+			code: " 4600001 ",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("LockInventorySession", mock.Anything, "inv1").Return(inProgress, nil)
+				m.On("FindPvzProductsByCode", mock.Anything, "inv1", "pvz1", "4600001").
+					Return([]models.InventoryProductMatch{{ProductId: "prod1", Scanned: true}, {ProductId: "prod2"}}, nil)
+				m.On("AddInventoryScan", mock.Anything, mock.MatchedBy(func(s models.InventoryScan) bool {
+					return s.Code == "4600001" && s.ProductId == "prod2" && s.SessionId == "inv1"
+				})).Return(nil)
+			},
+			expectedProductId: "prod2",
+		},
+		{
+			name: "surplus",
+			ctx:  roleCtx("employee"),
+			code: "unknown",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("LockInventorySession", mock.Anything, "inv1").Return(inProgress, nil)
+				m.On("FindPvzProductsByCode", mock.Anything, "inv1", "pvz1", "unknown").Return(nil, nil)
+				m.On("AddInventoryScan", mock.Anything, mock.MatchedBy(func(s models.InventoryScan) bool {
+					return s.Code == "unknown" && s.ProductId == ""
+				})).Return(nil)
+			},
+		},
+		{
+			name: "already scanned",
+			ctx:  roleCtx("employee"),
+			code: "prod1",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("LockInventorySession", mock.Anything, "inv1").Return(inProgress, nil)
+				m.On("FindPvzProductsByCode", mock.Anything, "inv1", "pvz1", "prod1").
+					Return([]models.InventoryProductMatch{{ProductId: "prod1", Scanned: true}}, nil)
+			},
+			expectedErr: errors.New("product already scanned"),
+		},
+		{
+			name: "session completed",
+			ctx:  roleCtx("employee"),
+			code: "prod1",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("LockInventorySession", mock.Anything, "inv1").
+					Return(&models.InventorySession{Id: "inv1", Status: models.INVENTORY_STATUS_COMPLETED}, nil)
+			},
+			expectedErr: errors.New("inventory session is not in progress"),
+		},
+		{
+			name:        "empty code",
+			ctx:         roleCtx("employee"),
+			code:        "  ",
+			mockSetup:   func(_ *repositoryMocks.MockInventoryRepository) {},
+			expectedErr: errors.New("code is required"),
+		},
+		{
+			name:        "too long code",
+			ctx:         roleCtx("employee"),
+			code:        strings.Repeat("1", models.MAX_INVENTORY_CODE_LENGTH+1),
+			mockSetup:   func(_ *repositoryMocks.MockInventoryRepository) {},
+			expectedErr: errors.New("invalid code"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         roleCtx("client"),
+			code:        "prod1",
+			mockSetup:   func(_ *repositoryMocks.MockInventoryRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockInventoryRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewInventoryUsecase(mockRepo)
+
+			scan, err := uc.ScanInventoryItem(tt.ctx(), "inv1", requests.InventoryScanRequest{Code: tt.code})
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				assert.Equal(t, tt.expectedProductId, scan.ProductId)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInventoryUsecase_CompleteInventory(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockSetup   func(*repositoryMocks.MockInventoryRepository)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("LockInventorySession", mock.Anything, "inv1").
+					Return(&models.InventorySession{Id: "inv1", PvzId: "pvz1", Status: models.INVENTORY_STATUS_IN_PROGRESS}, nil)
+				m.On("GetPvzStoredProducts", mock.Anything, "pvz1").
+					Return([]models.InventoryProduct{{ProductId: "prod1"}, {ProductId: "prod2"}}, nil)
+				m.On("GetInventoryScans", mock.Anything, "inv1").
+					Return([]models.InventoryScan{{Code: "prod1", ProductId: "prod1"}, {Code: "xyz"}}, nil)
+				m.On("CompleteInventorySession", mock.Anything, mock.MatchedBy(func(s *models.InventorySession) bool {
+					return s.Status == models.INVENTORY_STATUS_COMPLETED && s.CompletedAt != nil &&
+						s.Report.MatchedCount == 1 && len(s.Report.Missing) == 1 && len(s.Report.Surplus) == 1
+				})).Return(nil)
+			},
+		},
+		{
+			name: "not in progress",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("LockInventorySession", mock.Anything, "inv1").
+					Return(&models.InventorySession{Id: "inv1", Status: models.INVENTORY_STATUS_APPROVED}, nil)
+			},
+			expectedErr: errors.New("inventory session is not in progress"),
+		},
+		{
+			name: "not found",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("LockInventorySession", mock.Anything, "inv1").Return(nil, errors.New("inventory session not found"))
+			},
+			expectedErr: errors.New("inventory session not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockInventoryRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewInventoryUsecase(mockRepo)
+
+			_, err := uc.CompleteInventory(roleCtx("employee")(), "inv1")
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInventoryUsecase_ApproveInventory(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		comment     string
+		mockSetup   func(*repositoryMocks.MockInventoryRepository)
+		expectedErr error
+	}{
+		{
+			name:    "success",
+			ctx:     roleCtx("moderator"),
+			comment: " расхождения объяснены ",
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("GetInventorySession", mock.Anything, "inv1").
+					Return(&models.InventorySession{Id: "inv1", Status: models.INVENTORY_STATUS_COMPLETED}, nil)
+				m.On("ApproveInventorySession", mock.Anything, mock.MatchedBy(func(s *models.InventorySession) bool {
+					return s.Status == models.INVENTORY_STATUS_APPROVED && s.ApprovedAt != nil &&
+						s.ApprovalComment == "расхождения объяснены"
+				})).Return(nil)
+			},
+		},
+		{
+			name: "not completed",
+			ctx:  roleCtx("moderator"),
+			mockSetup: func(m *repositoryMocks.MockInventoryRepository) {
+				m.On("GetInventorySession", mock.Anything, "inv1").
+					Return(&models.InventorySession{Id: "inv1", Status: models.INVENTORY_STATUS_IN_PROGRESS}, nil)
+			},
+			expectedErr: errors.New("inventory session is not completed"),
+		},
+		{
+			name:        "comment too long",
+			ctx:         roleCtx("moderator"),
+			comment:     strings.Repeat("я", models.MAX_INVENTORY_COMMENT_LENGTH+1),
+			mockSetup:   func(_ *repositoryMocks.MockInventoryRepository) {},
+			expectedErr: errors.New("comment is too long"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         roleCtx("employee"),
+			mockSetup:   func(_ *repositoryMocks.MockInventoryRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockInventoryRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewInventoryUsecase(mockRepo)
+
+			_, err := uc.ApproveInventory(tt.ctx(), "inv1", requests.ApproveInventoryRequest{Comment: tt.comment})
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
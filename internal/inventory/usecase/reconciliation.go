@@ -0,0 +1,32 @@
+package usecase
+
+import "avito_spring_staj_2025/domain/models"
+
+// reconcileInventory сверяет товары, которые числятся на ПВЗ, с отсканированными кодами.
+// Скан товара, который успел покинуть ПВЗ после сканирования, не считается ни совпадением, ни излишком.
+func reconcileInventory(expected []models.InventoryProduct, scans []models.InventoryScan) models.InventoryReport {
+	scanned := make(map[string]bool, len(scans))
+	report := models.InventoryReport{
+		ExpectedCount: len(expected),
+		ScannedCount:  len(scans),
+		Missing:       []models.InventoryProduct{},
+		Surplus:       []models.InventoryScan{},
+	}
+
+	for _, scan := range scans {
+		if scan.ProductId == "" {
+			report.Surplus = append(report.Surplus, scan)
+			continue
+		}
+		scanned[scan.ProductId] = true
+	}
+
+	for _, product := range expected {
+		if scanned[product.ProductId] {
+			report.MatchedCount++
+			continue
+		}
+		report.Missing = append(report.Missing, product)
+	}
+	return report
+}
@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReconcileInventory(t *testing.T) {
+	expected := []models.InventoryProduct{
+		{ProductId: "prod1", Type: "обувь", CellCode: "A-1"},
+		{ProductId: "prod2", Type: "одежда", ExternalId: "4600001"},
+		{ProductId: "prod3", Type: "электроника"},
+	}
+	scans := []models.InventoryScan{
+		{Code: "prod1", ProductId: "prod1"},
+		{Code: "4600001", ProductId: "prod2"},
+		{Code: "unknown"},
+		// товар выдали уже после сканирования — в сверке он не участвует
+		{Code: "prod9", ProductId: "prod9"},
+	}
+
+	report := reconcileInventory(expected, scans)
+
+	assert.Equal(t, 3, report.ExpectedCount)
+	assert.Equal(t, 4, report.ScannedCount)
+	assert.Equal(t, 2, report.MatchedCount)
+	assert.Equal(t, []models.InventoryProduct{{ProductId: "prod3", Type: "электроника"}}, report.Missing)
+	assert.Equal(t, []models.InventoryScan{{Code: "unknown"}}, report.Surplus)
+}
+
+func TestReconcileInventory_Empty(t *testing.T) {
+	report := reconcileInventory(nil, nil)
+
+	assert.Equal(t, models.InventoryReport{
+		Missing: []models.InventoryProduct{},
+		Surplus: []models.InventoryScan{},
+	}, report)
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"context"
+)
+
+type ProductUsecase interface {
+	IssueProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error)
+	RefuseProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error)
+	ReturnProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error)
+	GetProductHistory(ctx context.Context, productId string) (*models.ProductState, []models.ProductStatusEvent, error)
+	SetOrderCustomer(ctx context.Context, externalId string, data requests.SetOrderCustomerRequest) (models.OrderOwner, error)
+	GetPickupCodes(ctx context.Context) ([]models.PickupCode, error)
+	RegeneratePickupCode(ctx context.Context, productId string) (models.PickupCode, error)
+	PickupProduct(ctx context.Context, pvzId string, data requests.PickupProductRequest) (models.ProductStatusEvent, error)
+	GetOverdueProducts(ctx context.Context, pvzId string) ([]models.ProductState, error)
+	CreateTransfer(ctx context.Context, sourcePvzId string, data requests.CreateTransferRequest) (models.Transfer, error)
+	AcceptTransfer(ctx context.Context, pvzId, transferId string) (models.Transfer, error)
+	GetIncomingTransfers(ctx context.Context, pvzId string) ([]models.Transfer, error)
+}
@@ -0,0 +1,411 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ProductHandler struct {
+	usecase ProductUsecase
+}
+
+func NewProductHandler(usecase ProductUsecase) *ProductHandler {
+	return &ProductHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *ProductHandler) IssueProduct(w http.ResponseWriter, r *http.Request) {
+	h.changeProductStatus(w, r, h.usecase.IssueProduct)
+}
+
+func (h *ProductHandler) RefuseProduct(w http.ResponseWriter, r *http.Request) {
+	h.changeProductStatus(w, r, h.usecase.RefuseProduct)
+}
+
+func (h *ProductHandler) ReturnProduct(w http.ResponseWriter, r *http.Request) {
+	h.changeProductStatus(w, r, h.usecase.ReturnProduct)
+}
+
+type changeProductStatusFunc func(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error)
+
+func (h *ProductHandler) changeProductStatus(w http.ResponseWriter, r *http.Request, change changeProductStatusFunc) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.ChangeProductStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.ProductId = sanitizer.Sanitize(data.ProductId)
+	data.Reason = sanitizer.Sanitize(data.Reason)
+
+	event, err := change(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toProductStatusEventResponse(event)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) GetProductHistory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	productId := sanitizer.Sanitize(mux.Vars(r)["productId"])
+
+	state, events, err := h.usecase.GetProductHistory(ctx, productId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.ProductHistoryResponse{
+		ProductId:       state.Product.Id,
+		Type:            state.Product.Type,
+		ExternalId:      state.Product.ExternalId,
+		Status:          state.Status,
+		ReceivedAt:      state.Product.DateTime,
+		StorageDeadline: state.StorageDeadline,
+		Events:          make([]responses.ProductStatusEventResponse, 0, len(events)),
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, toProductStatusEventResponse(event))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) SetOrderCustomer(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	externalId := sanitizer.Sanitize(mux.Vars(r)["externalId"])
+
+	var data requests.SetOrderCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.CustomerId = sanitizer.Sanitize(data.CustomerId)
+
+	owner, err := h.usecase.SetOrderCustomer(ctx, externalId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(responses.OrderCustomerResponse{
+		ExternalId: owner.ExternalId,
+		CustomerId: owner.CustomerId,
+	}); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) GetPickupCodes(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	defer cancel()
+
+	codes, err := h.usecase.GetPickupCodes(ctx)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetPickupCodesResponse{
+		PickupCodes: make([]responses.PickupCodeResponse, 0, len(codes)),
+	}
+	for _, code := range codes {
+		response.PickupCodes = append(response.PickupCodes, toPickupCodeResponse(code))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) RegeneratePickupCode(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	productId := sanitizer.Sanitize(mux.Vars(r)["productId"])
+
+	code, err := h.usecase.RegeneratePickupCode(ctx, productId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toPickupCodeResponse(code)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) PickupProduct(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.PickupProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.ProductId = sanitizer.Sanitize(data.ProductId)
+	data.Code = sanitizer.Sanitize(data.Code)
+
+	event, err := h.usecase.PickupProduct(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toProductStatusEventResponse(event)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) GetOverdueProducts(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	products, err := h.usecase.GetOverdueProducts(ctx, pvzId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetOverdueProductsResponse{
+		Products: make([]responses.OverdueProductResponse, 0, len(products)),
+	}
+	for _, state := range products {
+		response.Products = append(response.Products, responses.OverdueProductResponse{
+			ProductId:       state.Product.Id,
+			Type:            state.Product.Type,
+			ExternalId:      state.Product.ExternalId,
+			ReceptionId:     state.Product.ReceptionId,
+			Status:          state.Status,
+			ReceivedAt:      state.Product.DateTime,
+			StorageDeadline: state.StorageDeadline,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.CreateTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.DestinationPvzId = sanitizer.Sanitize(data.DestinationPvzId)
+	for i := range data.ProductIds {
+		data.ProductIds[i] = sanitizer.Sanitize(data.ProductIds[i])
+	}
+
+	transfer, err := h.usecase.CreateTransfer(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(toTransferResponse(transfer)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+	transferId := sanitizer.Sanitize(mux.Vars(r)["transferId"])
+
+	transfer, err := h.usecase.AcceptTransfer(ctx, pvzId, transferId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toTransferResponse(transfer)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductHandler) GetIncomingTransfers(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	transfers, err := h.usecase.GetIncomingTransfers(ctx, pvzId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetTransfersResponse{
+		Transfers: make([]responses.TransferResponse, 0, len(transfers)),
+	}
+	for _, transfer := range transfers {
+		response.Transfers = append(response.Transfers, toTransferResponse(transfer))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func toTransferResponse(transfer models.Transfer) responses.TransferResponse {
+	return responses.TransferResponse{
+		Id:               transfer.Id,
+		SourcePvzId:      transfer.SourcePvzId,
+		DestinationPvzId: transfer.DestinationPvzId,
+		Status:           transfer.Status,
+		CreatedBy:        transfer.CreatedBy,
+		CreatedAt:        transfer.CreatedAt,
+		AcceptedBy:       transfer.AcceptedBy,
+		AcceptedAt:       transfer.AcceptedAt,
+		ReceptionId:      transfer.ReceptionId,
+		ProductIds:       transfer.ProductIds,
+	}
+}
+
+func toPickupCodeResponse(code models.PickupCode) responses.PickupCodeResponse {
+	return responses.PickupCodeResponse{
+		ProductId:    code.ProductId,
+		ExternalId:   code.ExternalId,
+		PvzId:        code.PvzId,
+		Code:         code.Code,
+		QrPayload:    code.QrPayload(),
+		AttemptsLeft: code.AttemptsLeft(),
+	}
+}
+
+func toProductStatusEventResponse(event models.ProductStatusEvent) responses.ProductStatusEventResponse {
+	return responses.ProductStatusEventResponse{
+		ProductId:  event.ProductId,
+		PvzId:      event.PvzId,
+		FromStatus: event.FromStatus,
+		Status:     event.ToStatus,
+		Reason:     event.Reason,
+		ChangedBy:  event.ChangedBy,
+		ChangedAt:  event.ChangedAt,
+	}
+}
+
+func (h *ProductHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "pvz not found", "product id is required", "reason is required", "external id is required",
+		"customer id is required", "customer not found", "pickup code is required", "invalid pickup code",
+		"destination pvz id is required", "destination pvz must differ from source", "product ids are required",
+		"too many products in transfer", "duplicate product id in transfer", "no active reception":
+		w.WriteHeader(http.StatusBadRequest)
+	case "product not found", "pickup code not found", "transfer not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "invalid product status transition", "product is not at this pvz", "reception is not closed",
+		"product must be issued by pickup code", "pickup code already used", "transfer is not for this pvz",
+		"transfer already accepted", "product already scanned", "pvz capacity exceeded":
+		w.WriteHeader(http.StatusConflict)
+	case "pickup code is locked":
+		w.WriteHeader(http.StatusTooManyRequests)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,369 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProductHandler(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	changedAt := time.Date(2025, 4, 28, 12, 0, 0, 0, time.UTC)
+	receivedAt := changedAt.Add(-24 * time.Hour)
+	issued := models.ProductStatusEvent{
+		Id: "ev-1", ProductId: "prod-1", PvzId: "pvz-1",
+		FromStatus: models.PRODUCT_STATUS_RECEIVED, ToStatus: models.PRODUCT_STATUS_ISSUED,
+		ChangedBy: "user-1", ChangedAt: changedAt,
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		vars           map[string]string
+		call           func(h *ProductHandler) http.HandlerFunc
+		mockBehavior   func(usecase *usecaseMocks.MockProductUsecase)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "issue",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/issue_product",
+			body:   `{"productId":"prod-1"}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.IssueProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("IssueProduct", mock.Anything, "pvz-1", requests.ChangeProductStatusRequest{ProductId: "prod-1"}).
+					Return(issued, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"productId":"prod-1","pvzId":"pvz-1","fromStatus":"received","status":"issued",` +
+				`"changedBy":"user-1","changedAt":"2025-04-28T12:00:00Z"}`,
+		},
+		{
+			name:   "issue twice",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/issue_product",
+			body:   `{"productId":"prod-1"}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.IssueProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("IssueProduct", mock.Anything, "pvz-1", mock.Anything).
+					Return(models.ProductStatusEvent{}, errors.New("invalid product status transition"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"invalid product status transition"}`,
+		},
+		{
+			name:   "refuse without reason",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/refuse_product",
+			body:   `{"productId":"prod-1"}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.RefuseProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("RefuseProduct", mock.Anything, "pvz-1", mock.Anything).
+					Return(models.ProductStatusEvent{}, errors.New("reason is required"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "return",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-2/return_product",
+			body:   `{"productId":"prod-1","reason":"брак"}`,
+			vars:   map[string]string{"pvzId": "pvz-2"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.ReturnProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("ReturnProduct", mock.Anything, "pvz-2", requests.ChangeProductStatusRequest{ProductId: "prod-1", Reason: "брак"}).
+					Return(models.ProductStatusEvent{
+						ProductId: "prod-1", PvzId: "pvz-2",
+						FromStatus: models.PRODUCT_STATUS_ISSUED, ToStatus: models.PRODUCT_STATUS_RETURNED,
+						Reason: "брак", ChangedBy: "user-2", ChangedAt: changedAt,
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"productId":"prod-1","pvzId":"pvz-2","fromStatus":"issued","status":"returned",` +
+				`"reason":"брак","changedBy":"user-2","changedAt":"2025-04-28T12:00:00Z"}`,
+		},
+		{
+			name:   "return forbidden",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-2/return_product",
+			body:   `{"productId":"prod-1","reason":"брак"}`,
+			vars:   map[string]string{"pvzId": "pvz-2"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.ReturnProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("ReturnProduct", mock.Anything, "pvz-2", mock.Anything).
+					Return(models.ProductStatusEvent{}, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid body",
+			method:         http.MethodPost,
+			path:           "/api/pvz/pvz-1/issue_product",
+			body:           `{`,
+			vars:           map[string]string{"pvzId": "pvz-1"},
+			call:           func(h *ProductHandler) http.HandlerFunc { return h.IssueProduct },
+			mockBehavior:   func(_ *usecaseMocks.MockProductUsecase) {},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "history",
+			method: http.MethodGet,
+			path:   "/api/products/prod-1/history",
+			vars:   map[string]string{"productId": "prod-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.GetProductHistory },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("GetProductHistory", mock.Anything, "prod-1").Return(&models.ProductState{
+					Product: models.Product{Id: "prod-1", Type: "обувь", DateTime: receivedAt, ExternalId: "BC-1"},
+					Status:  models.PRODUCT_STATUS_ISSUED,
+				}, []models.ProductStatusEvent{issued}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"productId":"prod-1","type":"обувь","externalId":"BC-1","status":"issued","receivedAt":"2025-04-27T12:00:00Z",` +
+				`"events":[{"productId":"prod-1","pvzId":"pvz-1","fromStatus":"received","status":"issued","changedBy":"user-1","changedAt":"2025-04-28T12:00:00Z"}]}`,
+		},
+		{
+			name:   "history not found",
+			method: http.MethodGet,
+			path:   "/api/products/missing/history",
+			vars:   map[string]string{"productId": "missing"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.GetProductHistory },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("GetProductHistory", mock.Anything, "missing").Return(nil, nil, errors.New("product not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "overdue products",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz-1/overdue_products",
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.GetOverdueProducts },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("GetOverdueProducts", mock.Anything, "pvz-1").Return([]models.ProductState{{
+					Product:         models.Product{Id: "prod-1", Type: "обувь", DateTime: receivedAt, ReceptionId: "rec-1"},
+					Status:          models.PRODUCT_STATUS_RETURN_TO_SENDER,
+					StorageDeadline: &changedAt,
+				}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"products":[{"productId":"prod-1","type":"обувь","receptionId":"rec-1","status":"return_to_sender",` +
+				`"receivedAt":"2025-04-27T12:00:00Z","storageDeadline":"2025-04-28T12:00:00Z"}]}`,
+		},
+		{
+			name:   "overdue products forbidden",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz-1/overdue_products",
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.GetOverdueProducts },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("GetOverdueProducts", mock.Anything, "pvz-1").Return(nil, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "create transfer",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/transfers",
+			body:   `{"destinationPvzId":"pvz-2","productIds":["prod-1"]}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.CreateTransfer },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("CreateTransfer", mock.Anything, "pvz-1", requests.CreateTransferRequest{DestinationPvzId: "pvz-2", ProductIds: []string{"prod-1"}}).
+					Return(models.Transfer{
+						Id: "tr-1", SourcePvzId: "pvz-1", DestinationPvzId: "pvz-2", Status: models.TRANSFER_STATUS_IN_TRANSIT,
+						CreatedBy: "user-1", CreatedAt: changedAt, ProductIds: []string{"prod-1"},
+					}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":"tr-1","sourcePvzId":"pvz-1","destinationPvzId":"pvz-2","status":"in_transit","createdBy":"user-1",` +
+				`"createdAt":"2025-04-28T12:00:00Z","productIds":["prod-1"]}`,
+		},
+		{
+			name:   "create transfer of product in transit",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/transfers",
+			body:   `{"destinationPvzId":"pvz-2","productIds":["prod-1"]}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.CreateTransfer },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("CreateTransfer", mock.Anything, "pvz-1", mock.Anything).
+					Return(models.Transfer{}, errors.New("invalid product status transition"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "accept transfer",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-2/transfers/tr-1/accept",
+			vars:   map[string]string{"pvzId": "pvz-2", "transferId": "tr-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.AcceptTransfer },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("AcceptTransfer", mock.Anything, "pvz-2", "tr-1").
+					Return(models.Transfer{
+						Id: "tr-1", SourcePvzId: "pvz-1", DestinationPvzId: "pvz-2", Status: models.TRANSFER_STATUS_ACCEPTED,
+						CreatedBy: "user-1", CreatedAt: receivedAt, AcceptedBy: "user-2", AcceptedAt: &changedAt,
+						ReceptionId: "rec-2", ProductIds: []string{"prod-1"},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"tr-1","sourcePvzId":"pvz-1","destinationPvzId":"pvz-2","status":"accepted","createdBy":"user-1",` +
+				`"createdAt":"2025-04-27T12:00:00Z","acceptedBy":"user-2","acceptedAt":"2025-04-28T12:00:00Z","receptionId":"rec-2","productIds":["prod-1"]}`,
+		},
+		{
+			name:   "accept transfer twice",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-2/transfers/tr-1/accept",
+			vars:   map[string]string{"pvzId": "pvz-2", "transferId": "tr-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.AcceptTransfer },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("AcceptTransfer", mock.Anything, "pvz-2", "tr-1").
+					Return(models.Transfer{}, errors.New("transfer already accepted"))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "incoming transfers",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz-2/transfers/incoming",
+			vars:   map[string]string{"pvzId": "pvz-2"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.GetIncomingTransfers },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("GetIncomingTransfers", mock.Anything, "pvz-2").Return([]models.Transfer{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"transfers":[]}`,
+		},
+		{
+			name:   "set order customer",
+			method: http.MethodPut,
+			path:   "/api/orders/ORDER-1/customer",
+			body:   `{"customerId":"customer-1"}`,
+			vars:   map[string]string{"externalId": "ORDER-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.SetOrderCustomer },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("SetOrderCustomer", mock.Anything, "ORDER-1", requests.SetOrderCustomerRequest{CustomerId: "customer-1"}).
+					Return(models.OrderOwner{ExternalId: "ORDER-1", CustomerId: "customer-1"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"externalId":"ORDER-1","customerId":"customer-1"}`,
+		},
+		{
+			name:   "pickup codes",
+			method: http.MethodGet,
+			path:   "/api/pickup_codes",
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.GetPickupCodes },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("GetPickupCodes", mock.Anything).Return([]models.PickupCode{
+					{ProductId: "prod-1", ExternalId: "ORDER-1", PvzId: "pvz-1", Code: "123456", FailedAttempts: 1},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"pickupCodes":[{"productId":"prod-1","externalId":"ORDER-1","pvzId":"pvz-1","code":"123456",` +
+				`"qrPayload":"pvz-pickup:prod-1:123456","attemptsLeft":4}]}`,
+		},
+		{
+			name:   "pickup",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/pickup_product",
+			body:   `{"productId":"prod-1","code":"123456"}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.PickupProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("PickupProduct", mock.Anything, "pvz-1", requests.PickupProductRequest{ProductId: "prod-1", Code: "123456"}).
+					Return(issued, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"productId":"prod-1","pvzId":"pvz-1","fromStatus":"received","status":"issued",` +
+				`"changedBy":"user-1","changedAt":"2025-04-28T12:00:00Z"}`,
+		},
+		{
+			name:   "pickup with wrong code",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/pickup_product",
+			body:   `{"productId":"prod-1","code":"000000"}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.PickupProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("PickupProduct", mock.Anything, "pvz-1", mock.Anything).
+					Return(models.ProductStatusEvent{}, errors.New("invalid pickup code"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "pickup with locked code",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz-1/pickup_product",
+			body:   `{"productId":"prod-1","code":"123456"}`,
+			vars:   map[string]string{"pvzId": "pvz-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.PickupProduct },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("PickupProduct", mock.Anything, "pvz-1", mock.Anything).
+					Return(models.ProductStatusEvent{}, errors.New("pickup code is locked"))
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:   "regenerate pickup code",
+			method: http.MethodPost,
+			path:   "/api/pickup_codes/prod-1/regenerate",
+			vars:   map[string]string{"productId": "prod-1"},
+			call:   func(h *ProductHandler) http.HandlerFunc { return h.RegeneratePickupCode },
+			mockBehavior: func(usecase *usecaseMocks.MockProductUsecase) {
+				usecase.On("RegeneratePickupCode", mock.Anything, "prod-1").
+					Return(models.PickupCode{ProductId: "prod-1", ExternalId: "ORDER-1", PvzId: "pvz-1", Code: "654321"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"productId":"prod-1","externalId":"ORDER-1","pvzId":"pvz-1","code":"654321",` +
+				`"qrPayload":"pvz-pickup:prod-1:654321","attemptsLeft":5}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.MockProductUsecase)
+			handler := NewProductHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.vars != nil {
+				req = mux.SetURLVars(req, tt.vars)
+			}
+			w := httptest.NewRecorder()
+			tt.call(handler)(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, string(body))
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	uniqueViolationCode   = "23505"
+	activePickupCodeIndex = "pickup_codes_active_code"
+)
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode && pqErr.Constraint == constraint
+}
+
+func (r ProductRepository) GetUserRole(ctx context.Context, userId string) (string, error) {
+	query, args, err := sq.Select("role").
+		From("users").
+		Where(sq.Eq{"id": userId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return "", err
+	}
+
+	var role string
+	if err := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("customer not found")
+		}
+		logger.DBLogger.Error("failed to scan user role", zap.Error(err))
+		return "", err
+	}
+	return role, nil
+}
+
+// SetOrderOwner привязывает заказ к клиенту; повторная привязка заменяет владельца.
+func (r ProductRepository) SetOrderOwner(ctx context.Context, owner models.OrderOwner) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("SetOrderOwner called",
+		zap.String("request_id", requestID),
+		zap.String("external_id", owner.ExternalId),
+	)
+
+	query, args, err := sq.Insert("order_owners").
+		Columns("external_id", "customer_id", "created_at").
+		Values(owner.ExternalId, owner.CustomerId, owner.CreatedAt).
+		Suffix("ON CONFLICT (external_id) DO UPDATE SET customer_id = EXCLUDED.customer_id, created_at = EXCLUDED.created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to upsert order owner", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetCustomerPickupCodes возвращает товары клиента, готовые к выдаче, вместе с их кодами, если они уже выпущены.
+func (r ProductRepository) GetCustomerPickupCodes(ctx context.Context, customerId string) ([]models.PickupCode, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetCustomerPickupCodes called",
+		zap.String("request_id", requestID),
+		zap.String("customer_id", customerId),
+	)
+
+	query, args, err := sq.
+		Select("p.id", "p.external_id", "r.pvz_id", "c.code", "c.failed_attempts", "c.created_at").
+		From("order_owners o").
+		Join("products p ON p.external_id = o.external_id").
+		Join("receptions r ON r.id = p.reception_id").
+		LeftJoin("pickup_codes c ON c.product_id = p.id").
+		Where(sq.Eq{
+			"o.customer_id": customerId,
+			"p.status":      models.PRODUCT_STATUS_RECEIVED,
+			"r.status":      models.STATUS_CLOSED,
+		}).
+		OrderBy("p.date_time").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query pickup codes", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	codes := []models.PickupCode{}
+	for rows.Next() {
+		var code models.PickupCode
+		var value sql.NullString
+		var failedAttempts sql.NullInt64
+		var createdAt sql.NullTime
+		if err := rows.Scan(&code.ProductId, &code.ExternalId, &code.PvzId, &value, &failedAttempts, &createdAt); err != nil {
+			logger.DBLogger.Error("failed to scan pickup code", zap.Error(err))
+			return nil, err
+		}
+		code.Code = value.String
+		code.FailedAttempts = int(failedAttempts.Int64)
+		code.CreatedAt = createdAt.Time
+		codes = append(codes, code)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// SavePickupCode выпускает код для товара или заменяет прежний неиспользованный, сбрасывая счётчик попыток.
+// Если такой код уже выдан другому товару, возвращает "pickup code collision" — код нужно сгенерировать заново.
+func (r ProductRepository) SavePickupCode(ctx context.Context, code models.PickupCode) error {
+	query, args, err := sq.Insert("pickup_codes").
+		Columns("product_id", "code", "failed_attempts", "created_at").
+		Values(code.ProductId, code.Code, 0, code.CreatedAt).
+		Suffix("ON CONFLICT (product_id) DO UPDATE SET code = EXCLUDED.code, failed_attempts = 0, created_at = EXCLUDED.created_at " +
+			"WHERE pickup_codes.used_at IS NULL").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		if isUniqueViolation(err, activePickupCodeIndex) {
+			return errors.New("pickup code collision")
+		}
+		logger.DBLogger.Error("failed to save pickup code", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// LockPickupCode читает код выдачи товара и блокирует его до конца транзакции.
+func (r ProductRepository) LockPickupCode(ctx context.Context, productId string) (*models.PickupCode, error) {
+	query, args, err := sq.Select("product_id", "code", "failed_attempts", "created_at", "used_at").
+		From("pickup_codes").
+		Where(sq.Eq{"product_id": productId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var code models.PickupCode
+	var usedAt sql.NullTime
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&code.ProductId, &code.Code, &code.FailedAttempts, &code.CreatedAt, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("pickup code not found")
+		}
+		logger.DBLogger.Error("failed to scan pickup code", zap.Error(err))
+		return nil, err
+	}
+	if usedAt.Valid {
+		code.UsedAt = &usedAt.Time
+	}
+	return &code, nil
+}
+
+func (r ProductRepository) RegisterFailedPickupAttempt(ctx context.Context, productId string) error {
+	query, args, err := sq.Update("pickup_codes").
+		Set("failed_attempts", sq.Expr("failed_attempts + 1")).
+		Where(sq.Eq{"product_id": productId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to register pickup attempt", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r ProductRepository) MarkPickupCodeUsed(ctx context.Context, productId string, usedAt time.Time) error {
+	query, args, err := sq.Update("pickup_codes").
+		Set("used_at", usedAt).
+		Where(sq.Eq{"product_id": productId, "used_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to mark pickup code used", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("pickup code already used")
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProductRepository_GetUserRole(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT role FROM users WHERE id = \$1$`
+
+	mock.ExpectQuery(query).
+		WithArgs("customer-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("client"))
+	role, err := repo.GetUserRole(context.Background(), "customer-1")
+	require.NoError(t, err)
+	assert.Equal(t, "client", role)
+
+	mock.ExpectQuery(query).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetUserRole(context.Background(), "missing")
+	assert.EqualError(t, err, "customer not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_SetOrderOwner(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	createdAt := time.Now()
+
+	mock.ExpectExec(`^INSERT INTO order_owners \(external_id,customer_id,created_at\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \(external_id\) DO UPDATE`).
+		WithArgs("ORDER-1", "customer-1", createdAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.SetOrderOwner(context.Background(), models.OrderOwner{ExternalId: "ORDER-1", CustomerId: "customer-1", CreatedAt: createdAt})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_GetCustomerPickupCodes(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`^SELECT p.id, p.external_id, r.pvz_id, c.code, c.failed_attempts, c.created_at FROM order_owners o `+
+		`JOIN products p ON p.external_id = o.external_id JOIN receptions r ON r.id = p.reception_id `+
+		`LEFT JOIN pickup_codes c ON c.product_id = p.id WHERE o.customer_id = \$1 AND p.status = \$2 AND r.status = \$3 ORDER BY p.date_time$`).
+		WithArgs("customer-1", models.PRODUCT_STATUS_RECEIVED, models.STATUS_CLOSED).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "external_id", "pvz_id", "code", "failed_attempts", "created_at"}).
+			AddRow("prod-1", "ORDER-1", "pvz-1", "123456", 1, createdAt).
+			AddRow("prod-2", "ORDER-1", "pvz-1", nil, nil, nil))
+
+	codes, err := repo.GetCustomerPickupCodes(context.Background(), "customer-1")
+	require.NoError(t, err)
+	assert.Equal(t, []models.PickupCode{
+		{ProductId: "prod-1", ExternalId: "ORDER-1", PvzId: "pvz-1", Code: "123456", FailedAttempts: 1, CreatedAt: createdAt},
+		{ProductId: "prod-2", ExternalId: "ORDER-1", PvzId: "pvz-1"},
+	}, codes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_SavePickupCode(t *testing.T) {
+	code := models.PickupCode{ProductId: "prod-1", Code: "123456", CreatedAt: time.Now()}
+	query := `^INSERT INTO pickup_codes \(product_id,code,failed_attempts,created_at\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(product_id\) DO UPDATE`
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).
+			WithArgs("prod-1", "123456", 0, code.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.SavePickupCode(context.Background(), code))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).
+			WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: activePickupCodeIndex})
+
+		assert.EqualError(t, repo.SavePickupCode(context.Background(), code), "pickup code collision")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProductRepository_LockPickupCode(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT product_id, code, failed_attempts, created_at, used_at FROM pickup_codes WHERE product_id = \$1 FOR UPDATE$`
+	createdAt := time.Now()
+
+	mock.ExpectQuery(query).
+		WithArgs("prod-1").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "code", "failed_attempts", "created_at", "used_at"}).
+			AddRow("prod-1", "123456", 2, createdAt, nil))
+	code, err := repo.LockPickupCode(context.Background(), "prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.PickupCode{ProductId: "prod-1", Code: "123456", FailedAttempts: 2, CreatedAt: createdAt}, code)
+
+	mock.ExpectQuery(query).
+		WithArgs("prod-2").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.LockPickupCode(context.Background(), "prod-2")
+	assert.EqualError(t, err, "pickup code not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_RegisterFailedPickupAttempt(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(`^UPDATE pickup_codes SET failed_attempts = failed_attempts \+ 1 WHERE product_id = \$1$`).
+		WithArgs("prod-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.RegisterFailedPickupAttempt(context.Background(), "prod-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_MarkPickupCodeUsed(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^UPDATE pickup_codes SET used_at = \$1 WHERE product_id = \$2 AND used_at IS NULL$`
+	usedAt := time.Now()
+
+	mock.ExpectExec(query).
+		WithArgs(usedAt, "prod-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.MarkPickupCodeUsed(context.Background(), "prod-1", usedAt))
+
+	mock.ExpectExec(query).
+		WithArgs(usedAt, "prod-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.EqualError(t, repo.MarkPickupCodeUsed(context.Background(), "prod-1", usedAt), "pickup code already used")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"avito_spring_staj_2025/internal/service/pvzcapacity"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+type ProductRepository struct {
+	db *sql.DB
+}
+
+func NewProductRepository(db *sql.DB) ProductRepository {
+	return ProductRepository{
+		db: db,
+	}
+}
+
+// WithinTransaction выполняет fn в одной транзакции; транзакция, уже открытая выше по стеку
+// любым репозиторием, переиспользуется.
+func (r ProductRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return dbtx.WithinTransaction(ctx, r.db, fn)
+}
+
+func (r ProductRepository) GetPvzById(ctx context.Context, pvzId string) (*models.Pvz, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetPvzById called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := sq.Select("id", "registration_date", "city").
+		From("pvzs").
+		Where(sq.Eq{"id": pvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var pvz models.Pvz
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&pvz.Id, &pvz.RegistrationDate, &pvz.City)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("pvz not found")
+		}
+		logger.DBLogger.Error("failed to scan pvz", zap.Error(err))
+		return nil, err
+	}
+
+	return &pvz, nil
+}
+
+// GetProductState возвращает товар с текущим статусом и ПВЗ, куда он был принят.
+func (r ProductRepository) GetProductState(ctx context.Context, productId string) (*models.ProductState, error) {
+	return r.getProductState(ctx, productId, false)
+}
+
+// LockProductState делает то же, что GetProductState, но блокирует строку товара до конца транзакции.
+func (r ProductRepository) LockProductState(ctx context.Context, productId string) (*models.ProductState, error) {
+	return r.getProductState(ctx, productId, true)
+}
+
+// productStateColumns — колонки, которые читает scanProductState.
+var productStateColumns = []string{"p.id", "p.date_time", "p.type", "p.reception_id", "p.seq_no", "p.external_id",
+	"p.status", "p.status_changed_at", "p.storage_deadline", "r.pvz_id", "r.status", "o.customer_id"}
+
+func productStateQuery() sq.SelectBuilder {
+	return sq.Select(productStateColumns...).
+		From("products p").
+		Join("receptions r ON r.id = p.reception_id").
+		LeftJoin("order_owners o ON o.external_id = p.external_id").
+		PlaceholderFormat(sq.Dollar)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProductState(row rowScanner) (models.ProductState, error) {
+	var state models.ProductState
+	var externalId sql.NullString
+	var statusChangedAt sql.NullTime
+	var storageDeadline sql.NullTime
+	var customerId sql.NullString
+	err := row.Scan(
+		&state.Product.Id,
+		&state.Product.DateTime,
+		&state.Product.Type,
+		&state.Product.ReceptionId,
+		&state.Product.SeqNo,
+		&externalId,
+		&state.Status,
+		&statusChangedAt,
+		&storageDeadline,
+		&state.PvzId,
+		&state.ReceptionStatus,
+		&customerId,
+	)
+	if err != nil {
+		return models.ProductState{}, err
+	}
+	state.Product.ExternalId = externalId.String
+	state.CustomerId = customerId.String
+	if statusChangedAt.Valid {
+		state.StatusChangedAt = &statusChangedAt.Time
+	}
+	if storageDeadline.Valid {
+		state.StorageDeadline = &storageDeadline.Time
+	}
+	return state, nil
+}
+
+func (r ProductRepository) getProductState(ctx context.Context, productId string, forUpdate bool) (*models.ProductState, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("getProductState called",
+		zap.String("request_id", requestID),
+		zap.String("product_id", productId),
+		zap.Bool("for_update", forUpdate),
+	)
+
+	queryBuilder := productStateQuery().Where(sq.Eq{"p.id": productId})
+	if forUpdate {
+		queryBuilder = queryBuilder.Suffix("FOR UPDATE OF p")
+	}
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	state, err := scanProductState(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		logger.DBLogger.Error("failed to scan product", zap.Error(err))
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// ChangeProductStatus переводит товар в новый статус и пишет событие в журнал.
+// Обновление условное по прежнему статусу, поэтому параллельный переход не пройдёт дважды.
+func (r ProductRepository) ChangeProductStatus(ctx context.Context, event models.ProductStatusEvent) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("ChangeProductStatus called",
+		zap.String("request_id", requestID),
+		zap.String("product_id", event.ProductId),
+		zap.String("from_status", event.FromStatus),
+		zap.String("to_status", event.ToStatus),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+
+		update := sq.Update("products").
+			Set("status", event.ToStatus).
+			Set("status_changed_at", event.ChangedAt)
+		// Выданный или отправленный в другой ПВЗ товар освобождает ячейку хранения
+		if !models.IsProductAtPvz(event.ToStatus) {
+			update = update.Set("cell_id", nil)
+		}
+		query, args, err := update.
+			Where(sq.Eq{"id": event.ProductId, "status": event.FromStatus}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			logger.DBLogger.Error("failed to update product status", zap.Error(err))
+			return err
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return errors.New("invalid product status transition")
+		}
+
+		query, args, err = sq.Insert("product_status_events").
+			Columns("id", "product_id", "pvz_id", "from_status", "to_status", "reason", "changed_by", "changed_at").
+			Values(event.Id, event.ProductId, event.PvzId, event.FromStatus, event.ToStatus, event.Reason, event.ChangedBy, event.ChangedAt).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert product status event", zap.Error(err))
+			return err
+		}
+
+		return nil
+	})
+}
+
+// ChangePvzOccupancy сдвигает заполненность ПВЗ на delta, не опуская её ниже нуля.
+// Вместимость здесь не проверяется: вернувшийся товар нельзя не принять.
+func (r ProductRepository) ChangePvzOccupancy(ctx context.Context, pvzId string, delta int) error {
+	query, args, err := sq.Update("pvzs").
+		Set("occupancy", sq.Expr("GREATEST(occupancy + ?, 0)", delta)).
+		Where(sq.Eq{"id": pvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to update pvz occupancy", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ReservePvzCapacity занимает на ПВЗ count мест с той же проверкой вместимости, что и при сканировании товаров.
+func (r ProductRepository) ReservePvzCapacity(ctx context.Context, pvzId string, count int) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("ReservePvzCapacity called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+		zap.Int("count", count),
+	)
+
+	_, _, err := pvzcapacity.Reserve(ctx, dbtx.Conn(ctx, r.db), pvzId, count)
+	return err
+}
+
+// GetProductStatusEvents возвращает журнал смены статусов товара в хронологическом порядке.
+func (r ProductRepository) GetProductStatusEvents(ctx context.Context, productId string) ([]models.ProductStatusEvent, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductStatusEvents called",
+		zap.String("request_id", requestID),
+		zap.String("product_id", productId),
+	)
+
+	query, args, err := sq.
+		Select("id", "product_id", "pvz_id", "from_status", "to_status", "reason", "changed_by", "changed_at").
+		From("product_status_events").
+		Where(sq.Eq{"product_id": productId}).
+		OrderBy("changed_at", "id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query product status events", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	events := []models.ProductStatusEvent{}
+	for rows.Next() {
+		var event models.ProductStatusEvent
+		err := rows.Scan(&event.Id, &event.ProductId, &event.PvzId, &event.FromStatus, &event.ToStatus,
+			&event.Reason, &event.ChangedBy, &event.ChangedAt)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan product status event", zap.Error(err))
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func newMockRepository(t *testing.T) (ProductRepository, sqlmock.Sqlmock) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return NewProductRepository(db), mock
+}
+
+var productStateColumnNames = []string{"id", "date_time", "type", "reception_id", "seq_no", "external_id",
+	"status", "status_changed_at", "storage_deadline", "pvz_id", "status", "customer_id"}
+
+func TestProductRepository_LockProductState(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT p.id, p.date_time, p.type, p.reception_id, p.seq_no, p.external_id, p.status, p.status_changed_at, p.storage_deadline, r.pvz_id, r.status, o.customer_id ` +
+		`FROM products p JOIN receptions r ON r.id = p.reception_id LEFT JOIN order_owners o ON o.external_id = p.external_id WHERE p.id = \$1 FOR UPDATE OF p$`
+	receivedAt := time.Date(2025, 4, 28, 10, 0, 0, 0, time.UTC)
+	changedAt := receivedAt.Add(time.Hour)
+	deadline := receivedAt.Add(7 * 24 * time.Hour)
+
+	mock.ExpectQuery(query).
+		WithArgs("prod-1").
+		WillReturnRows(sqlmock.NewRows(productStateColumnNames).
+			AddRow("prod-1", receivedAt, "обувь", "rec-1", int64(3), "BC-1", models.PRODUCT_STATUS_ISSUED, changedAt, deadline, "pvz-1", models.STATUS_CLOSED, "user-9"))
+	state, err := repo.LockProductState(context.Background(), "prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.ProductState{
+		Product:         models.Product{Id: "prod-1", DateTime: receivedAt, Type: "обувь", ReceptionId: "rec-1", SeqNo: 3, ExternalId: "BC-1"},
+		Status:          models.PRODUCT_STATUS_ISSUED,
+		StatusChangedAt: &changedAt,
+		PvzId:           "pvz-1",
+		ReceptionStatus: models.STATUS_CLOSED,
+		CustomerId:      "user-9",
+		StorageDeadline: &deadline,
+	}, state)
+
+	mock.ExpectQuery(query).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.LockProductState(context.Background(), "missing")
+	assert.EqualError(t, err, "product not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_GetProductState(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`LEFT JOIN order_owners o ON o.external_id = p.external_id WHERE p.id = \$1$`).
+		WithArgs("prod-1").
+		WillReturnRows(sqlmock.NewRows(productStateColumnNames).
+			AddRow("prod-1", time.Time{}, "обувь", "rec-1", int64(1), nil, models.PRODUCT_STATUS_RECEIVED, nil, nil, "pvz-1", models.STATUS_ACTIVE, nil))
+	state, err := repo.GetProductState(context.Background(), "prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.PRODUCT_STATUS_RECEIVED, state.Status)
+	assert.Nil(t, state.StatusChangedAt)
+	assert.Nil(t, state.StorageDeadline)
+	assert.Empty(t, state.Product.ExternalId)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_ChangeProductStatus(t *testing.T) {
+	changedAt := time.Now()
+	event := models.ProductStatusEvent{
+		Id:         "ev-1",
+		ProductId:  "prod-1",
+		PvzId:      "pvz-1",
+		FromStatus: models.PRODUCT_STATUS_RECEIVED,
+		ToStatus:   models.PRODUCT_STATUS_ISSUED,
+		ChangedBy:  "user-1",
+		ChangedAt:  changedAt,
+	}
+	updateQuery := `^UPDATE products SET status = \$1, status_changed_at = \$2, cell_id = \$3 WHERE id = \$4 AND status = \$5$`
+	insertQuery := `^INSERT INTO product_status_events \(id,product_id,pvz_id,from_status,to_status,reason,changed_by,changed_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\)$`
+
+	tests := []struct {
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(updateQuery).
+					WithArgs(models.PRODUCT_STATUS_ISSUED, changedAt, nil, "prod-1", models.PRODUCT_STATUS_RECEIVED).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertQuery).
+					WithArgs("ev-1", "prod-1", "pvz-1", models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_ISSUED, "", "user-1", changedAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Status Already Changed",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(updateQuery).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			errMsg: "invalid product status transition",
+		},
+		{
+			name: "Event Insert Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(updateQuery).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertQuery).
+					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
+			},
+			errMsg: "insert failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			tt.mock(mock)
+
+			err := repo.ChangeProductStatus(context.Background(), event)
+
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProductRepository_ChangeProductStatus_KeepsCell(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	changedAt := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^UPDATE products SET status = \$1, status_changed_at = \$2 WHERE id = \$3 AND status = \$4$`).
+		WithArgs(models.PRODUCT_STATUS_REFUSED, changedAt, "prod-1", models.PRODUCT_STATUS_RECEIVED).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^INSERT INTO product_status_events`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.ChangeProductStatus(context.Background(), models.ProductStatusEvent{
+		Id:         "ev-2",
+		ProductId:  "prod-1",
+		PvzId:      "pvz-1",
+		FromStatus: models.PRODUCT_STATUS_RECEIVED,
+		ToStatus:   models.PRODUCT_STATUS_REFUSED,
+		ChangedAt:  changedAt,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_ChangePvzOccupancy(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(`^UPDATE pvzs SET occupancy = GREATEST\(occupancy \+ \$1, 0\) WHERE id = \$2$`).
+		WithArgs(-1, "pvz-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.ChangePvzOccupancy(context.Background(), "pvz-1", -1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_ReservePvzCapacity(t *testing.T) {
+	reserveQuery := `^UPDATE pvzs SET occupancy = occupancy \+ \$1 WHERE id = \$2 AND \(capacity IS NULL OR occupancy \+ \$3 <= capacity OR capacity_policy = \$4\) RETURNING occupancy, capacity$`
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(reserveQuery).
+			WithArgs(2, "pvz-2", 2, models.CAPACITY_POLICY_WARN).
+			WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(5, 10))
+
+		require.NoError(t, repo.ReservePvzCapacity(context.Background(), "pvz-2", 2))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Capacity Exceeded", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(reserveQuery).
+			WithArgs(2, "pvz-2", 2, models.CAPACITY_POLICY_WARN).
+			WillReturnError(sql.ErrNoRows)
+
+		assert.EqualError(t, repo.ReservePvzCapacity(context.Background(), "pvz-2", 2), "pvz capacity exceeded")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProductRepository_GetProductStatusEvents(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	changedAt := time.Now()
+
+	mock.ExpectQuery(`^SELECT id, product_id, pvz_id, from_status, to_status, reason, changed_by, changed_at FROM product_status_events WHERE product_id = \$1 ORDER BY changed_at, id$`).
+		WithArgs("prod-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "pvz_id", "from_status", "to_status", "reason", "changed_by", "changed_at"}).
+			AddRow("ev-1", "prod-1", "pvz-1", models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_ISSUED, "", "user-1", changedAt).
+			AddRow("ev-2", "prod-1", "pvz-2", models.PRODUCT_STATUS_ISSUED, models.PRODUCT_STATUS_RETURNED, "брак", "user-2", changedAt))
+
+	events, err := repo.GetProductStatusEvents(context.Background(), "prod-1")
+	require.NoError(t, err)
+	assert.Equal(t, []models.ProductStatusEvent{
+		{Id: "ev-1", ProductId: "prod-1", PvzId: "pvz-1", FromStatus: models.PRODUCT_STATUS_RECEIVED, ToStatus: models.PRODUCT_STATUS_ISSUED, ChangedBy: "user-1", ChangedAt: changedAt},
+		{Id: "ev-2", ProductId: "prod-1", PvzId: "pvz-2", FromStatus: models.PRODUCT_STATUS_ISSUED, ToStatus: models.PRODUCT_STATUS_RETURNED, Reason: "брак", ChangedBy: "user-2", ChangedAt: changedAt},
+	}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
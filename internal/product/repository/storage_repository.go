@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+	"time"
+)
+
+// GetOverdueProductIds возвращает товары, которые лежат в закрытых приёмках дольше срока хранения
+// и всё ещё хранятся на ПВЗ (не забраны, не выкуплены или возвращены клиентом), начиная с самых просроченных.
+func (r ProductRepository) GetOverdueProductIds(ctx context.Context, now time.Time) ([]string, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetOverdueProductIds called", zap.String("request_id", requestID), zap.Time("now", now))
+
+	query, args, err := sq.Select("p.id").
+		From("products p").
+		Join("receptions r ON r.id = p.reception_id").
+		Where(sq.Eq{"p.status": models.ProductStoredStatuses, "r.status": models.STATUS_CLOSED}).
+		Where(sq.Lt{"p.storage_deadline": now}).
+		OrderBy("p.storage_deadline", "p.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query overdue products", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			logger.DBLogger.Error("failed to scan product id", zap.Error(err))
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetPvzOverdueProducts возвращает товары ПВЗ, которые уже ждут отправки отправителю,
+// и те, чей срок хранения истёк, но фоновый обработчик до них ещё не дошёл.
+func (r ProductRepository) GetPvzOverdueProducts(ctx context.Context, pvzId string, now time.Time) ([]models.ProductState, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetPvzOverdueProducts called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := productStateQuery().
+		Where(sq.Eq{"r.pvz_id": pvzId}).
+		Where(sq.Or{
+			sq.Eq{"p.status": models.PRODUCT_STATUS_RETURN_TO_SENDER},
+			sq.And{
+				sq.Eq{"p.status": models.ProductStoredStatuses, "r.status": models.STATUS_CLOSED},
+				sq.Lt{"p.storage_deadline": now},
+			},
+		}).
+		OrderBy("p.storage_deadline", "p.id").
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query overdue products", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	products := []models.ProductState{}
+	for rows.Next() {
+		state, err := scanProductState(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan product", zap.Error(err))
+			return nil, err
+		}
+		products = append(products, state)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return products, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProductRepository_GetOverdueProductIds(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`^SELECT p.id FROM products p JOIN receptions r ON r.id = p.reception_id `+
+		`WHERE p.status IN \(\$1,\$2,\$3\) AND r.status = \$4 AND p.storage_deadline < \$5 ORDER BY p.storage_deadline, p.id$`).
+		WithArgs(models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_REFUSED, models.PRODUCT_STATUS_RETURNED, models.STATUS_CLOSED, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("prod-1").AddRow("prod-2"))
+
+	ids, err := repo.GetOverdueProductIds(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod-1", "prod-2"}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_GetPvzOverdueProducts(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+	deadline := now.Add(-time.Hour)
+
+	mock.ExpectQuery(`LEFT JOIN order_owners o ON o.external_id = p.external_id WHERE r.pvz_id = \$1 `+
+		`AND \(p.status = \$2 OR \(p.status IN \(\$3,\$4,\$5\) AND r.status = \$6 AND p.storage_deadline < \$7\)\) ORDER BY p.storage_deadline, p.id$`).
+		WithArgs("pvz-1", models.PRODUCT_STATUS_RETURN_TO_SENDER, models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_REFUSED,
+			models.PRODUCT_STATUS_RETURNED, models.STATUS_CLOSED, now).
+		WillReturnRows(sqlmock.NewRows(productStateColumnNames).
+			AddRow("prod-1", now, "обувь", "rec-1", int64(1), "BC-1", models.PRODUCT_STATUS_RETURN_TO_SENDER, now, deadline, "pvz-1", models.STATUS_CLOSED, nil).
+			AddRow("prod-2", now, "обувь", "rec-1", int64(2), nil, models.PRODUCT_STATUS_RECEIVED, nil, deadline, "pvz-1", models.STATUS_CLOSED, nil))
+
+	products, err := repo.GetPvzOverdueProducts(context.Background(), "pvz-1", now)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, models.PRODUCT_STATUS_RETURN_TO_SENDER, products[0].Status)
+	assert.Equal(t, &deadline, products[1].StorageDeadline)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
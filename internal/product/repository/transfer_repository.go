@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"avito_spring_staj_2025/internal/service/outbox"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+	"time"
+)
+
+const productExternalIdPerReceptionIndex = "products_reception_external_id"
+
+// CreateTransfer сохраняет перемещение вместе со списком товаров.
+func (r ProductRepository) CreateTransfer(ctx context.Context, transfer models.Transfer) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CreateTransfer called",
+		zap.String("request_id", requestID),
+		zap.String("transfer_id", transfer.Id),
+		zap.Int("count", len(transfer.ProductIds)),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+
+		query, args, err := sq.Insert("transfers").
+			Columns("id", "source_pvz_id", "destination_pvz_id", "status", "created_by", "created_at").
+			Values(transfer.Id, transfer.SourcePvzId, transfer.DestinationPvzId, transfer.Status, transfer.CreatedBy, transfer.CreatedAt).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert transfer", zap.Error(err))
+			return err
+		}
+
+		itemsBuilder := sq.Insert("transfer_items").Columns("transfer_id", "product_id")
+		for _, productId := range transfer.ProductIds {
+			itemsBuilder = itemsBuilder.Values(transfer.Id, productId)
+		}
+		query, args, err = itemsBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert transfer items", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+}
+
+var transferColumns = []string{"t.id", "t.source_pvz_id", "t.destination_pvz_id", "t.status", "t.created_by", "t.created_at",
+	"t.accepted_by", "t.accepted_at", "t.reception_id"}
+
+func scanTransfer(row rowScanner) (models.Transfer, error) {
+	var transfer models.Transfer
+	var acceptedBy sql.NullString
+	var acceptedAt sql.NullTime
+	var receptionId sql.NullString
+	err := row.Scan(&transfer.Id, &transfer.SourcePvzId, &transfer.DestinationPvzId, &transfer.Status,
+		&transfer.CreatedBy, &transfer.CreatedAt, &acceptedBy, &acceptedAt, &receptionId)
+	if err != nil {
+		return models.Transfer{}, err
+	}
+	transfer.AcceptedBy = acceptedBy.String
+	transfer.ReceptionId = receptionId.String
+	if acceptedAt.Valid {
+		transfer.AcceptedAt = &acceptedAt.Time
+	}
+	return transfer, nil
+}
+
+// LockTransfer читает перемещение со списком товаров и блокирует его до конца транзакции.
+func (r ProductRepository) LockTransfer(ctx context.Context, transferId string) (*models.Transfer, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockTransfer called", zap.String("request_id", requestID), zap.String("transfer_id", transferId))
+
+	query, args, err := sq.Select(transferColumns...).
+		From("transfers t").
+		Where(sq.Eq{"t.id": transferId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	transfer, err := scanTransfer(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("transfer not found")
+		}
+		logger.DBLogger.Error("failed to scan transfer", zap.Error(err))
+		return nil, err
+	}
+
+	transfers := []models.Transfer{transfer}
+	if err = r.attachTransferItems(ctx, transfers); err != nil {
+		return nil, err
+	}
+	return &transfers[0], nil
+}
+
+// GetIncomingTransfers возвращает перемещения, которые едут на ПВЗ и ещё не приняты.
+func (r ProductRepository) GetIncomingTransfers(ctx context.Context, pvzId string) ([]models.Transfer, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetIncomingTransfers called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := sq.Select(transferColumns...).
+		From("transfers t").
+		Where(sq.Eq{"t.destination_pvz_id": pvzId, "t.status": models.TRANSFER_STATUS_IN_TRANSIT}).
+		OrderBy("t.created_at", "t.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query transfers", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	transfers := []models.Transfer{}
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan transfer", zap.Error(err))
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	if err = r.attachTransferItems(ctx, transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// attachTransferItems одним запросом подтягивает товары для всех переданных перемещений.
+func (r ProductRepository) attachTransferItems(ctx context.Context, transfers []models.Transfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(transfers))
+	ids := make([]string, 0, len(transfers))
+	for i, transfer := range transfers {
+		index[transfer.Id] = i
+		ids = append(ids, transfer.Id)
+		transfers[i].ProductIds = []string{}
+	}
+
+	query, args, err := sq.Select("transfer_id", "product_id").
+		From("transfer_items").
+		Where(sq.Eq{"transfer_id": ids}).
+		OrderBy("transfer_id", "product_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query transfer items", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	for rows.Next() {
+		var transferId, productId string
+		if err := rows.Scan(&transferId, &productId); err != nil {
+			logger.DBLogger.Error("failed to scan transfer item", zap.Error(err))
+			return err
+		}
+		if i, ok := index[transferId]; ok {
+			transfers[i].ProductIds = append(transfers[i].ProductIds, productId)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// AcceptTransfer отмечает перемещение принятым. Обновление условное по статусу,
+// поэтому одно перемещение нельзя принять дважды.
+func (r ProductRepository) AcceptTransfer(ctx context.Context, transfer models.Transfer) error {
+	query, args, err := sq.Update("transfers").
+		Set("status", models.TRANSFER_STATUS_ACCEPTED).
+		Set("accepted_by", transfer.AcceptedBy).
+		Set("accepted_at", transfer.AcceptedAt).
+		Set("reception_id", transfer.ReceptionId).
+		Where(sq.Eq{"id": transfer.Id, "status": models.TRANSFER_STATUS_IN_TRANSIT}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to accept transfer", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("transfer already accepted")
+	}
+	return nil
+}
+
+// LockActiveReception возвращает активную приёмку ПВЗ и блокирует её до конца транзакции.
+func (r ProductRepository) LockActiveReception(ctx context.Context, pvzId string) (*models.Reception, error) {
+	query, args, err := sq.Select("id", "date_time", "pvz_id", "status").
+		From("receptions").
+		Where(sq.Eq{"pvz_id": pvzId, "status": models.STATUS_ACTIVE}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var reception models.Reception
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&reception.Id, &reception.DateTime, &reception.PvzId, &reception.Status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("no active reception")
+		}
+		logger.DBLogger.Error("failed to scan reception", zap.Error(err))
+		return nil, err
+	}
+	return &reception, nil
+}
+
+// MoveProductToReception перекладывает товар в другую приёмку ПВЗ pvzId со следующим порядковым номером
+// и в той же транзакции пишет событие ProductAdded с номером перемещения: для подписчиков товар появился в приёмке.
+// Срок хранения сбрасывается: его заново назначит закрытие новой приёмки.
+// Приёмка должна быть заблокирована вызывающим, чтобы номера не пересеклись.
+func (r ProductRepository) MoveProductToReception(ctx context.Context, pvzId, productId, receptionId, transferId string) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("MoveProductToReception called",
+		zap.String("request_id", requestID),
+		zap.String("product_id", productId),
+		zap.String("reception_id", receptionId),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+
+		query, args, err := sq.Update("receptions").
+			Set("last_seq_no", sq.Expr("last_seq_no + 1")).
+			Where(sq.Eq{"id": receptionId}).
+			Suffix("RETURNING last_seq_no").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+
+		var seqNo int64
+		if err = tx.QueryRowContext(ctx, query, args...).Scan(&seqNo); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("reception not found")
+			}
+			logger.DBLogger.Error("failed to reserve product sequence number", zap.Error(err))
+			return err
+		}
+
+		query, args, err = sq.Update("products").
+			Set("reception_id", receptionId).
+			Set("seq_no", seqNo).
+			Set("storage_deadline", nil).
+			Where(sq.Eq{"id": productId}).
+			Suffix("RETURNING type, external_id, date_time").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+
+		var productType string
+		var externalId sql.NullString
+		var dateTime time.Time
+		if err = tx.QueryRowContext(ctx, query, args...).Scan(&productType, &externalId, &dateTime); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("product not found")
+			}
+			if isUniqueViolation(err, productExternalIdPerReceptionIndex) {
+				return errors.New("product already scanned")
+			}
+			logger.DBLogger.Error("failed to move product", zap.Error(err))
+			return err
+		}
+
+		event, err := outbox.NewEvent(models.EVENT_PRODUCT_ADDED, pvzId, models.ProductAddedPayload{
+			ProductId:   productId,
+			ReceptionId: receptionId,
+			PvzId:       pvzId,
+			Type:        productType,
+			SeqNo:       seqNo,
+			ExternalId:  externalId.String,
+			DateTime:    dateTime,
+			TransferId:  transferId,
+		}, time.Now())
+		if err != nil {
+			return err
+		}
+		return outbox.Append(ctx, tx, event)
+	})
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var transferColumnNames = []string{"id", "source_pvz_id", "destination_pvz_id", "status", "created_by", "created_at",
+	"accepted_by", "accepted_at", "reception_id"}
+
+func TestProductRepository_CreateTransfer(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	transfer := models.Transfer{
+		Id: "tr-1", SourcePvzId: "pvz-1", DestinationPvzId: "pvz-2", Status: models.TRANSFER_STATUS_IN_TRANSIT,
+		CreatedBy: "user-1", CreatedAt: time.Now(), ProductIds: []string{"prod-1", "prod-2"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^INSERT INTO transfers \(id,source_pvz_id,destination_pvz_id,status,created_by,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\)$`).
+		WithArgs("tr-1", "pvz-1", "pvz-2", models.TRANSFER_STATUS_IN_TRANSIT, "user-1", transfer.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^INSERT INTO transfer_items \(transfer_id,product_id\) VALUES \(\$1,\$2\),\(\$3,\$4\)$`).
+		WithArgs("tr-1", "prod-1", "tr-1", "prod-2").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.CreateTransfer(context.Background(), transfer))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_LockTransfer(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT t.id, t.source_pvz_id, t.destination_pvz_id, t.status, t.created_by, t.created_at, t.accepted_by, t.accepted_at, t.reception_id ` +
+		`FROM transfers t WHERE t.id = \$1 FOR UPDATE$`
+	createdAt := time.Now()
+
+	mock.ExpectQuery(query).
+		WithArgs("tr-1").
+		WillReturnRows(sqlmock.NewRows(transferColumnNames).
+			AddRow("tr-1", "pvz-1", "pvz-2", models.TRANSFER_STATUS_IN_TRANSIT, "user-1", createdAt, nil, nil, nil))
+	mock.ExpectQuery(`^SELECT transfer_id, product_id FROM transfer_items WHERE transfer_id IN \(\$1\) ORDER BY transfer_id, product_id$`).
+		WithArgs("tr-1").
+		WillReturnRows(sqlmock.NewRows([]string{"transfer_id", "product_id"}).AddRow("tr-1", "prod-1").AddRow("tr-1", "prod-2"))
+	transfer, err := repo.LockTransfer(context.Background(), "tr-1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Transfer{
+		Id: "tr-1", SourcePvzId: "pvz-1", DestinationPvzId: "pvz-2", Status: models.TRANSFER_STATUS_IN_TRANSIT,
+		CreatedBy: "user-1", CreatedAt: createdAt, ProductIds: []string{"prod-1", "prod-2"},
+	}, transfer)
+
+	mock.ExpectQuery(query).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.LockTransfer(context.Background(), "missing")
+	assert.EqualError(t, err, "transfer not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_GetIncomingTransfers(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`FROM transfers t WHERE t.destination_pvz_id = \$1 AND t.status = \$2 ORDER BY t.created_at, t.id$`).
+		WithArgs("pvz-2", models.TRANSFER_STATUS_IN_TRANSIT).
+		WillReturnRows(sqlmock.NewRows(transferColumnNames).
+			AddRow("tr-1", "pvz-1", "pvz-2", models.TRANSFER_STATUS_IN_TRANSIT, "user-1", createdAt, nil, nil, nil).
+			AddRow("tr-2", "pvz-3", "pvz-2", models.TRANSFER_STATUS_IN_TRANSIT, "user-3", createdAt, nil, nil, nil))
+	mock.ExpectQuery(`FROM transfer_items WHERE transfer_id IN \(\$1,\$2\)`).
+		WithArgs("tr-1", "tr-2").
+		WillReturnRows(sqlmock.NewRows([]string{"transfer_id", "product_id"}).AddRow("tr-1", "prod-1").AddRow("tr-2", "prod-7"))
+
+	transfers, err := repo.GetIncomingTransfers(context.Background(), "pvz-2")
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	assert.Equal(t, []string{"prod-1"}, transfers[0].ProductIds)
+	assert.Equal(t, []string{"prod-7"}, transfers[1].ProductIds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_AcceptTransfer(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^UPDATE transfers SET status = \$1, accepted_by = \$2, accepted_at = \$3, reception_id = \$4 WHERE id = \$5 AND status = \$6$`
+	acceptedAt := time.Now()
+	transfer := models.Transfer{Id: "tr-1", AcceptedBy: "user-2", AcceptedAt: &acceptedAt, ReceptionId: "rec-2"}
+
+	mock.ExpectExec(query).
+		WithArgs(models.TRANSFER_STATUS_ACCEPTED, "user-2", &acceptedAt, "rec-2", "tr-1", models.TRANSFER_STATUS_IN_TRANSIT).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.AcceptTransfer(context.Background(), transfer))
+
+	mock.ExpectExec(query).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.EqualError(t, repo.AcceptTransfer(context.Background(), transfer), "transfer already accepted")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_LockActiveReception(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = \$1 AND status = \$2 FOR UPDATE$`
+
+	mock.ExpectQuery(query).
+		WithArgs("pvz-2", models.STATUS_ACTIVE).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
+			AddRow("rec-2", time.Now(), "pvz-2", models.STATUS_ACTIVE))
+	reception, err := repo.LockActiveReception(context.Background(), "pvz-2")
+	require.NoError(t, err)
+	assert.Equal(t, "rec-2", reception.Id)
+
+	mock.ExpectQuery(query).
+		WithArgs("pvz-3", models.STATUS_ACTIVE).
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.LockActiveReception(context.Background(), "pvz-3")
+	assert.EqualError(t, err, "no active reception")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_MoveProductToReception(t *testing.T) {
+	seqQuery := `^UPDATE receptions SET last_seq_no = last_seq_no \+ 1 WHERE id = \$1 RETURNING last_seq_no$`
+	moveQuery := `^UPDATE products SET reception_id = \$1, seq_no = \$2, storage_deadline = \$3 WHERE id = \$4 RETURNING type, external_id, date_time$`
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(seqQuery).
+			WithArgs("rec-2").
+			WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(8)))
+		mock.ExpectQuery(moveQuery).
+			WithArgs("rec-2", int64(8), nil, "prod-1").
+			WillReturnRows(sqlmock.NewRows([]string{"type", "external_id", "date_time"}).AddRow("обувь", "BC-1", time.Now()))
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1, hashtext\(id\)\) FROM unnest\(\$2::text\[\]\) AS id$`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`^INSERT INTO outbox_events \(id,event_type,pvz_id,payload,occurred_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\)$`).
+			WithArgs(sqlmock.AnyArg(), models.EVENT_PRODUCT_ADDED, "pvz-2", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.MoveProductToReception(context.Background(), "pvz-2", "prod-1", "rec-2", "tr-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already In Reception", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(seqQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(9)))
+		mock.ExpectQuery(moveQuery).
+			WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: productExternalIdPerReceptionIndex})
+		mock.ExpectRollback()
+
+		assert.EqualError(t, repo.MoveProductToReception(context.Background(), "pvz-2", "prod-1", "rec-2", "tr-1"), "product already scanned")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"time"
+)
+
+type ProductRepository interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	GetPvzById(ctx context.Context, pvzId string) (*models.Pvz, error)
+	GetProductState(ctx context.Context, productId string) (*models.ProductState, error)
+	LockProductState(ctx context.Context, productId string) (*models.ProductState, error)
+	ChangeProductStatus(ctx context.Context, event models.ProductStatusEvent) error
+	ChangePvzOccupancy(ctx context.Context, pvzId string, delta int) error
+	ReservePvzCapacity(ctx context.Context, pvzId string, count int) error
+	GetProductStatusEvents(ctx context.Context, productId string) ([]models.ProductStatusEvent, error)
+	GetUserRole(ctx context.Context, userId string) (string, error)
+	SetOrderOwner(ctx context.Context, owner models.OrderOwner) error
+	GetCustomerPickupCodes(ctx context.Context, customerId string) ([]models.PickupCode, error)
+	SavePickupCode(ctx context.Context, code models.PickupCode) error
+	LockPickupCode(ctx context.Context, productId string) (*models.PickupCode, error)
+	RegisterFailedPickupAttempt(ctx context.Context, productId string) error
+	MarkPickupCodeUsed(ctx context.Context, productId string, usedAt time.Time) error
+	GetOverdueProductIds(ctx context.Context, now time.Time) ([]string, error)
+	GetPvzOverdueProducts(ctx context.Context, pvzId string, now time.Time) ([]models.ProductState, error)
+	CreateTransfer(ctx context.Context, transfer models.Transfer) error
+	LockTransfer(ctx context.Context, transferId string) (*models.Transfer, error)
+	GetIncomingTransfers(ctx context.Context, pvzId string) ([]models.Transfer, error)
+	AcceptTransfer(ctx context.Context, transfer models.Transfer) error
+	LockActiveReception(ctx context.Context, pvzId string) (*models.Reception, error)
+	MoveProductToReception(ctx context.Context, pvzId, productId, receptionId, transferId string) error
+}
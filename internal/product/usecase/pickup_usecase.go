@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// pickupCodeGenerateAttempts ограничивает повторы генерации при совпадении с уже выданным кодом.
+const pickupCodeGenerateAttempts = 5
+
+// SetOrderCustomer привязывает заказ к клиенту, которому будут показаны коды выдачи его товаров.
+func (pu ProductUsecase) SetOrderCustomer(ctx context.Context, externalId string, data requests.SetOrderCustomerRequest) (models.OrderOwner, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.OrderOwner{}, errors.New("this role is not allowed")
+	}
+	externalId = strings.TrimSpace(externalId)
+	if externalId == "" {
+		return models.OrderOwner{}, errors.New("external id is required")
+	}
+	if data.CustomerId == "" {
+		return models.OrderOwner{}, errors.New("customer id is required")
+	}
+	role, err := pu.productRepository.GetUserRole(ctx, data.CustomerId)
+	if err != nil {
+		return models.OrderOwner{}, err
+	}
+	if role != "client" {
+		return models.OrderOwner{}, errors.New("customer not found")
+	}
+
+	owner := models.OrderOwner{
+		ExternalId: externalId,
+		CustomerId: data.CustomerId,
+		CreatedAt:  time.Now(),
+	}
+	if err := pu.productRepository.SetOrderOwner(ctx, owner); err != nil {
+		return models.OrderOwner{}, err
+	}
+	return owner, nil
+}
+
+// GetPickupCodes возвращает коды выдачи для товаров клиента, которые уже ждут его на ПВЗ.
+// Код выпускается при первом запросе, чтобы не генерировать коды для заказов, которые никто не смотрит.
+func (pu ProductUsecase) GetPickupCodes(ctx context.Context) ([]models.PickupCode, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "client" {
+		return nil, errors.New("this role is not allowed")
+	}
+	customerId := middleware.GetUserId(ctx)
+	if customerId == "" {
+		return []models.PickupCode{}, nil
+	}
+
+	codes, err := pu.productRepository.GetCustomerPickupCodes(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range codes {
+		if codes[i].Code != "" {
+			continue
+		}
+		if codes[i], err = pu.issuePickupCode(ctx, codes[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// RegeneratePickupCode выпускает клиенту новый код взамен прежнего, например заблокированного после неверных вводов.
+func (pu ProductUsecase) RegeneratePickupCode(ctx context.Context, productId string) (models.PickupCode, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "client" {
+		return models.PickupCode{}, errors.New("this role is not allowed")
+	}
+	if productId == "" {
+		return models.PickupCode{}, errors.New("product id is required")
+	}
+
+	codes, err := pu.productRepository.GetCustomerPickupCodes(ctx, middleware.GetUserId(ctx))
+	if err != nil {
+		return models.PickupCode{}, err
+	}
+	for _, code := range codes {
+		if code.ProductId == productId {
+			return pu.issuePickupCode(ctx, code)
+		}
+	}
+	return models.PickupCode{}, errors.New("product not found")
+}
+
+func (pu ProductUsecase) issuePickupCode(ctx context.Context, code models.PickupCode) (models.PickupCode, error) {
+	code.FailedAttempts = 0
+	code.CreatedAt = time.Now()
+	for attempt := 0; attempt < pickupCodeGenerateAttempts; attempt++ {
+		value, err := generatePickupCode()
+		if err != nil {
+			return models.PickupCode{}, err
+		}
+		code.Code = value
+
+		err = pu.productRepository.SavePickupCode(ctx, code)
+		if err == nil {
+			return code, nil
+		}
+		if err.Error() != "pickup code collision" {
+			return models.PickupCode{}, err
+		}
+	}
+	return models.PickupCode{}, errors.New("failed to generate pickup code")
+}
+
+func generatePickupCode() (string, error) {
+	var code strings.Builder
+	for i := 0; i < models.PICKUP_CODE_LENGTH; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(byte('0' + digit.Int64()))
+	}
+	return code.String(), nil
+}
+
+// PickupProduct выдаёт товар клиенту после проверки кода. Неверный код расходует попытку,
+// и попытка учитывается даже при ошибке, поэтому транзакция в этом случае фиксируется.
+func (pu ProductUsecase) PickupProduct(ctx context.Context, pvzId string, data requests.PickupProductRequest) (models.ProductStatusEvent, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.ProductStatusEvent{}, errors.New("this role is not allowed")
+	}
+	if data.ProductId == "" {
+		return models.ProductStatusEvent{}, errors.New("product id is required")
+	}
+	value := strings.TrimSpace(data.Code)
+	if value == "" {
+		return models.ProductStatusEvent{}, errors.New("pickup code is required")
+	}
+	if _, err := pu.productRepository.GetPvzById(ctx, pvzId); err != nil {
+		return models.ProductStatusEvent{}, err
+	}
+
+	var event models.ProductStatusEvent
+	var wrongCode bool
+	err := pu.productRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		code, err := pu.productRepository.LockPickupCode(ctx, data.ProductId)
+		if err != nil {
+			return err
+		}
+		if code.UsedAt != nil {
+			return errors.New("pickup code already used")
+		}
+		if code.Locked() {
+			return errors.New("pickup code is locked")
+		}
+		if subtle.ConstantTimeCompare([]byte(code.Code), []byte(value)) != 1 {
+			wrongCode = true
+			return pu.productRepository.RegisterFailedPickupAttempt(ctx, data.ProductId)
+		}
+
+		state, err := pu.productRepository.LockProductState(ctx, data.ProductId)
+		if err != nil {
+			return err
+		}
+		event, err = pu.applyProductStatus(ctx, state, pvzId, models.PRODUCT_STATUS_ISSUED, "")
+		if err != nil {
+			return err
+		}
+		return pu.productRepository.MarkPickupCodeUsed(ctx, data.ProductId, event.ChangedAt)
+	})
+	if err != nil {
+		return models.ProductStatusEvent{}, err
+	}
+	if wrongCode {
+		return models.ProductStatusEvent{}, errors.New("invalid pickup code")
+	}
+
+	return event, nil
+}
@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func clientCtx() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "client")
+	return context.WithValue(ctx, middleware.ContextKeyUserId, "customer-1")
+}
+
+func TestProductUsecase_SetOrderCustomer(t *testing.T) {
+	ctx := context.WithValue(moderatorCtx(), middleware.ContextKeyUserId, "moderator-1")
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetUserRole", mock.Anything, "customer-1").Return("client", nil)
+		repo.On("SetOrderOwner", mock.Anything, mock.MatchedBy(func(owner models.OrderOwner) bool {
+			return owner.ExternalId == "ORDER-1" && owner.CustomerId == "customer-1" && !owner.CreatedAt.IsZero()
+		})).Return(nil)
+		uc := NewProductUsecase(repo)
+
+		owner, err := uc.SetOrderCustomer(ctx, " ORDER-1 ", requests.SetOrderCustomerRequest{CustomerId: "customer-1"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "ORDER-1", owner.ExternalId)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("user is not a client", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetUserRole", mock.Anything, "employee-1").Return("employee", nil)
+		uc := NewProductUsecase(repo)
+
+		_, err := uc.SetOrderCustomer(ctx, "ORDER-1", requests.SetOrderCustomerRequest{CustomerId: "employee-1"})
+
+		assert.EqualError(t, err, "customer not found")
+		repo.AssertNotCalled(t, "SetOrderOwner", mock.Anything, mock.Anything)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		uc := NewProductUsecase(new(repositoryMocks.MockProductRepository))
+
+		_, err := uc.SetOrderCustomer(ctx, " ", requests.SetOrderCustomerRequest{CustomerId: "customer-1"})
+		assert.EqualError(t, err, "external id is required")
+
+		_, err = uc.SetOrderCustomer(ctx, "ORDER-1", requests.SetOrderCustomerRequest{})
+		assert.EqualError(t, err, "customer id is required")
+
+		_, err = uc.SetOrderCustomer(employeeCtx(), "ORDER-1", requests.SetOrderCustomerRequest{CustomerId: "customer-1"})
+		assert.EqualError(t, err, "this role is not allowed")
+	})
+}
+
+func TestProductUsecase_GetPickupCodes(t *testing.T) {
+	t.Run("issues missing codes", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetCustomerPickupCodes", mock.Anything, "customer-1").Return([]models.PickupCode{
+			{ProductId: "prod-1", ExternalId: "ORDER-1", PvzId: "pvz-1", Code: "123456", FailedAttempts: 2},
+			{ProductId: "prod-2", ExternalId: "ORDER-1", PvzId: "pvz-1"},
+		}, nil)
+		repo.On("SavePickupCode", mock.Anything, mock.MatchedBy(func(code models.PickupCode) bool {
+			return code.ProductId == "prod-2"
+		})).Return(errors.New("pickup code collision")).Once()
+		repo.On("SavePickupCode", mock.Anything, mock.MatchedBy(func(code models.PickupCode) bool {
+			return code.ProductId == "prod-2"
+		})).Return(nil).Once()
+		uc := NewProductUsecase(repo)
+
+		codes, err := uc.GetPickupCodes(clientCtx())
+
+		require.NoError(t, err)
+		require.Len(t, codes, 2)
+		assert.Equal(t, "123456", codes[0].Code)
+		assert.Equal(t, 3, codes[0].AttemptsLeft())
+		assert.Regexp(t, regexp.MustCompile(`^\d{6}$`), codes[1].Code)
+		assert.Equal(t, "pvz-pickup:prod-2:"+codes[1].Code, codes[1].QrPayload())
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("only for clients", func(t *testing.T) {
+		uc := NewProductUsecase(new(repositoryMocks.MockProductRepository))
+
+		_, err := uc.GetPickupCodes(employeeCtx())
+
+		assert.EqualError(t, err, "this role is not allowed")
+	})
+}
+
+func TestProductUsecase_RegeneratePickupCode(t *testing.T) {
+	t.Run("resets attempts", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetCustomerPickupCodes", mock.Anything, "customer-1").Return([]models.PickupCode{
+			{ProductId: "prod-1", Code: "123456", FailedAttempts: models.MAX_PICKUP_CODE_ATTEMPTS},
+		}, nil)
+		repo.On("SavePickupCode", mock.Anything, mock.Anything).Return(nil)
+		uc := NewProductUsecase(repo)
+
+		code, err := uc.RegeneratePickupCode(clientCtx(), "prod-1")
+
+		require.NoError(t, err)
+		assert.False(t, code.Locked())
+		assert.Equal(t, models.MAX_PICKUP_CODE_ATTEMPTS, code.AttemptsLeft())
+	})
+
+	t.Run("foreign product", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetCustomerPickupCodes", mock.Anything, "customer-1").Return([]models.PickupCode{}, nil)
+		uc := NewProductUsecase(repo)
+
+		_, err := uc.RegeneratePickupCode(clientCtx(), "prod-9")
+
+		assert.EqualError(t, err, "product not found")
+	})
+}
+
+func TestProductUsecase_PickupProduct(t *testing.T) {
+	data := requests.PickupProductRequest{ProductId: "prod-1", Code: "123456"}
+	usedAt := time.Now()
+
+	tests := []struct {
+		name        string
+		data        requests.PickupProductRequest
+		mockSetup   func(*repositoryMocks.MockProductRepository)
+		expectedErr string
+	}{
+		{
+			name: "success",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockPickupCode", mock.Anything, "prod-1").Return(&models.PickupCode{ProductId: "prod-1", Code: "123456"}, nil)
+				state := productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_CLOSED)
+				state.CustomerId = "customer-1"
+				m.On("LockProductState", mock.Anything, "prod-1").Return(state, nil)
+				m.On("ChangeProductStatus", mock.Anything,
+					matchEvent("prod-1", "pvz-1", models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_ISSUED, "")).
+					Return(nil)
+				m.On("ChangePvzOccupancy", mock.Anything, "pvz-1", -1).Return(nil)
+				m.On("MarkPickupCodeUsed", mock.Anything, "prod-1", mock.AnythingOfType("time.Time")).Return(nil)
+			},
+		},
+		{
+			name: "wrong code spends attempt",
+			data: requests.PickupProductRequest{ProductId: "prod-1", Code: "000000"},
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockPickupCode", mock.Anything, "prod-1").Return(&models.PickupCode{ProductId: "prod-1", Code: "123456"}, nil)
+				m.On("RegisterFailedPickupAttempt", mock.Anything, "prod-1").Return(nil)
+			},
+			expectedErr: "invalid pickup code",
+		},
+		{
+			name: "locked after too many attempts",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockPickupCode", mock.Anything, "prod-1").
+					Return(&models.PickupCode{ProductId: "prod-1", Code: "123456", FailedAttempts: models.MAX_PICKUP_CODE_ATTEMPTS}, nil)
+			},
+			expectedErr: "pickup code is locked",
+		},
+		{
+			name: "already used",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockPickupCode", mock.Anything, "prod-1").
+					Return(&models.PickupCode{ProductId: "prod-1", Code: "123456", UsedAt: &usedAt}, nil)
+			},
+			expectedErr: "pickup code already used",
+		},
+		{
+			name: "wrong pvz",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockPickupCode", mock.Anything, "prod-1").Return(&models.PickupCode{ProductId: "prod-1", Code: "123456"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-2", models.STATUS_CLOSED), nil)
+			},
+			expectedErr: "product is not at this pvz",
+		},
+		{
+			name:        "code is required",
+			data:        requests.PickupProductRequest{ProductId: "prod-1", Code: " "},
+			mockSetup:   func(_ *repositoryMocks.MockProductRepository) {},
+			expectedErr: "pickup code is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(repositoryMocks.MockProductRepository)
+			tt.mockSetup(repo)
+			uc := NewProductUsecase(repo)
+
+			event, err := uc.PickupProduct(employeeCtx(), "pvz-1", tt.data)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.PRODUCT_STATUS_ISSUED, event.ToStatus)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProductUsecase_IssueProduct_RequiresPickupCodeForOwnedOrder(t *testing.T) {
+	repo := new(repositoryMocks.MockProductRepository)
+	state := productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_CLOSED)
+	state.CustomerId = "customer-1"
+	repo.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+	repo.On("LockProductState", mock.Anything, "prod-1").Return(state, nil)
+	uc := NewProductUsecase(repo)
+
+	_, err := uc.IssueProduct(employeeCtx(), "pvz-1", requests.ChangeProductStatusRequest{ProductId: "prod-1"})
+
+	assert.EqualError(t, err, "product must be issued by pickup code")
+	repo.AssertNotCalled(t, "ChangeProductStatus", mock.Anything, mock.Anything)
+}
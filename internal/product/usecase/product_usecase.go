@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+type ProductUsecase struct {
+	productRepository ProductRepository
+}
+
+func NewProductUsecase(productRepository ProductRepository) ProductUsecase {
+	return ProductUsecase{
+		productRepository: productRepository,
+	}
+}
+
+// IssueProduct выдаёт клиенту товар, лежащий на этом ПВЗ, и освобождает под ним место.
+func (pu ProductUsecase) IssueProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error) {
+	return pu.changeProductStatus(ctx, pvzId, data, models.PRODUCT_STATUS_ISSUED)
+}
+
+// RefuseProduct фиксирует отказ клиента забрать товар при выдаче; товар остаётся на ПВЗ.
+func (pu ProductUsecase) RefuseProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error) {
+	return pu.changeProductStatus(ctx, pvzId, data, models.PRODUCT_STATUS_REFUSED)
+}
+
+// ReturnProduct принимает от клиента ранее выданный товар. Вернуть его можно только на ПВЗ, где его выдали:
+// товар числится в приёмке этого ПВЗ, и на другом он не учитывался бы ни в приёмке, ни в ячейках.
+func (pu ProductUsecase) ReturnProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error) {
+	return pu.changeProductStatus(ctx, pvzId, data, models.PRODUCT_STATUS_RETURNED)
+}
+
+func (pu ProductUsecase) changeProductStatus(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest, status string) (models.ProductStatusEvent, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.ProductStatusEvent{}, errors.New("this role is not allowed")
+	}
+	if data.ProductId == "" {
+		return models.ProductStatusEvent{}, errors.New("product id is required")
+	}
+	reason := strings.TrimSpace(data.Reason)
+	if reason == "" && status != models.PRODUCT_STATUS_ISSUED {
+		return models.ProductStatusEvent{}, errors.New("reason is required")
+	}
+	if _, err := pu.productRepository.GetPvzById(ctx, pvzId); err != nil {
+		return models.ProductStatusEvent{}, err
+	}
+
+	var event models.ProductStatusEvent
+	err := pu.productRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		state, err := pu.productRepository.LockProductState(ctx, data.ProductId)
+		if err != nil {
+			return err
+		}
+		// Заказ с известным владельцем выдаётся только через проверку кода клиента.
+		if status == models.PRODUCT_STATUS_ISSUED && state.CustomerId != "" {
+			return errors.New("product must be issued by pickup code")
+		}
+
+		event, err = pu.applyProductStatus(ctx, state, pvzId, status, reason)
+		return err
+	})
+	if err != nil {
+		return models.ProductStatusEvent{}, err
+	}
+
+	return event, nil
+}
+
+// applyProductStatus проверяет переход по state и записывает его; вызывается внутри транзакции
+// после блокировки строки товара.
+func (pu ProductUsecase) applyProductStatus(ctx context.Context, state *models.ProductState, pvzId, status, reason string) (models.ProductStatusEvent, error) {
+	if !models.CanChangeProductStatus(state.Status, status) {
+		return models.ProductStatusEvent{}, errors.New("invalid product status transition")
+	}
+	// Любой переход выполняется на ПВЗ, куда товар был принят. Выдать товар или отказаться от него
+	// можно только после закрытия приёмки: в открытой его ещё могут удалить.
+	if state.PvzId != pvzId {
+		return models.ProductStatusEvent{}, errors.New("product is not at this pvz")
+	}
+	if status != models.PRODUCT_STATUS_RETURNED && state.ReceptionStatus != models.STATUS_CLOSED {
+		return models.ProductStatusEvent{}, errors.New("reception is not closed")
+	}
+
+	event := newProductStatusEvent(ctx, state, pvzId, status, reason)
+	if err := pu.productRepository.ChangeProductStatus(ctx, event); err != nil {
+		return models.ProductStatusEvent{}, err
+	}
+
+	var err error
+	switch status {
+	case models.PRODUCT_STATUS_ISSUED, models.PRODUCT_STATUS_IN_TRANSIT, models.PRODUCT_STATUS_RETURN_TO_SENDER:
+		err = pu.productRepository.ChangePvzOccupancy(ctx, pvzId, -1)
+	case models.PRODUCT_STATUS_RETURNED:
+		err = pu.productRepository.ChangePvzOccupancy(ctx, pvzId, 1)
+	}
+	if err != nil {
+		return models.ProductStatusEvent{}, err
+	}
+
+	return event, nil
+}
+
+func newProductStatusEvent(ctx context.Context, state *models.ProductState, pvzId, status, reason string) models.ProductStatusEvent {
+	return models.ProductStatusEvent{
+		Id:         uuid.New().String(),
+		ProductId:  state.Product.Id,
+		PvzId:      pvzId,
+		FromStatus: state.Status,
+		ToStatus:   status,
+		Reason:     reason,
+		ChangedBy:  middleware.GetUserId(ctx),
+		ChangedAt:  time.Now(),
+	}
+}
+
+// GetProductHistory возвращает текущее состояние товара и журнал смены его статусов.
+func (pu ProductUsecase) GetProductHistory(ctx context.Context, productId string) (*models.ProductState, []models.ProductStatusEvent, error) {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return nil, nil, errors.New("this role is not allowed")
+	}
+	if productId == "" {
+		return nil, nil, errors.New("product id is required")
+	}
+
+	state, err := pu.productRepository.GetProductState(ctx, productId)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := pu.productRepository.GetProductStatusEvents(ctx, productId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return state, events, nil
+}
@@ -0,0 +1,313 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func employeeCtx() context.Context {
+	ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+	return context.WithValue(ctx, middleware.ContextKeyUserId, "user-1")
+}
+
+func moderatorCtx() context.Context {
+	return context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+}
+
+func productState(status, pvzId, receptionStatus string) *models.ProductState {
+	return &models.ProductState{
+		Product:         models.Product{Id: "prod-1", Type: models.CLOTHES_TYPE, ReceptionId: "rec-1"},
+		Status:          status,
+		PvzId:           pvzId,
+		ReceptionStatus: receptionStatus,
+	}
+}
+
+func matchEvent(productId, pvzId, from, to, reason string) interface{} {
+	return mock.MatchedBy(func(event models.ProductStatusEvent) bool {
+		return event.Id != "" && event.ProductId == productId && event.PvzId == pvzId &&
+			event.FromStatus == from && event.ToStatus == to && event.Reason == reason &&
+			event.ChangedBy == "user-1" && !event.ChangedAt.IsZero()
+	})
+}
+
+func TestProductUsecase_IssueProduct(t *testing.T) {
+	data := requests.ChangeProductStatusRequest{ProductId: "prod-1"}
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.ChangeProductStatusRequest
+		mockSetup   func(*repositoryMocks.MockProductRepository)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  employeeCtx,
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_CLOSED), nil)
+				m.On("ChangeProductStatus", mock.Anything,
+					matchEvent("prod-1", "pvz-1", models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_ISSUED, "")).
+					Return(nil)
+				m.On("ChangePvzOccupancy", mock.Anything, "pvz-1", -1).Return(nil)
+			},
+		},
+		{
+			name:        "invalid role",
+			ctx:         moderatorCtx,
+			data:        data,
+			mockSetup:   func(_ *repositoryMocks.MockProductRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "missing product id",
+			ctx:         employeeCtx,
+			data:        requests.ChangeProductStatusRequest{},
+			mockSetup:   func(_ *repositoryMocks.MockProductRepository) {},
+			expectedErr: errors.New("product id is required"),
+		},
+		{
+			name: "pvz not found",
+			ctx:  employeeCtx,
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(nil, errors.New("pvz not found"))
+			},
+			expectedErr: errors.New("pvz not found"),
+		},
+		{
+			name: "product not found",
+			ctx:  employeeCtx,
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").Return(nil, errors.New("product not found"))
+			},
+			expectedErr: errors.New("product not found"),
+		},
+		{
+			name: "already issued",
+			ctx:  employeeCtx,
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_ISSUED, "pvz-1", models.STATUS_CLOSED), nil)
+			},
+			expectedErr: errors.New("invalid product status transition"),
+		},
+		{
+			name: "product at another pvz",
+			ctx:  employeeCtx,
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-2", models.STATUS_CLOSED), nil)
+			},
+			expectedErr: errors.New("product is not at this pvz"),
+		},
+		{
+			name: "reception still in progress",
+			ctx:  employeeCtx,
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_ACTIVE), nil)
+			},
+			expectedErr: errors.New("reception is not closed"),
+		},
+		{
+			name: "concurrent transition",
+			ctx:  employeeCtx,
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_CLOSED), nil)
+				m.On("ChangeProductStatus", mock.Anything, mock.Anything).
+					Return(errors.New("invalid product status transition"))
+			},
+			expectedErr: errors.New("invalid product status transition"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(repositoryMocks.MockProductRepository)
+			tt.mockSetup(repo)
+			uc := NewProductUsecase(repo)
+
+			event, err := uc.IssueProduct(tt.ctx(), "pvz-1", tt.data)
+
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.PRODUCT_STATUS_ISSUED, event.ToStatus)
+				assert.Equal(t, "user-1", event.ChangedBy)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProductUsecase_RefuseProduct(t *testing.T) {
+	t.Run("success keeps occupancy", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+		repo.On("LockProductState", mock.Anything, "prod-1").
+			Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_CLOSED), nil)
+		repo.On("ChangeProductStatus", mock.Anything,
+			matchEvent("prod-1", "pvz-1", models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_REFUSED, "не подошёл размер")).
+			Return(nil)
+		uc := NewProductUsecase(repo)
+
+		event, err := uc.RefuseProduct(employeeCtx(), "pvz-1",
+			requests.ChangeProductStatusRequest{ProductId: "prod-1", Reason: " не подошёл размер "})
+
+		require.NoError(t, err)
+		assert.Equal(t, models.PRODUCT_STATUS_REFUSED, event.ToStatus)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "ChangePvzOccupancy", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reason is required", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		uc := NewProductUsecase(repo)
+
+		_, err := uc.RefuseProduct(employeeCtx(), "pvz-1", requests.ChangeProductStatusRequest{ProductId: "prod-1", Reason: "  "})
+
+		assert.EqualError(t, err, "reason is required")
+	})
+
+	t.Run("issued product cannot be refused", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+		repo.On("LockProductState", mock.Anything, "prod-1").
+			Return(productState(models.PRODUCT_STATUS_ISSUED, "pvz-1", models.STATUS_CLOSED), nil)
+		uc := NewProductUsecase(repo)
+
+		_, err := uc.RefuseProduct(employeeCtx(), "pvz-1", requests.ChangeProductStatusRequest{ProductId: "prod-1", Reason: "брак"})
+
+		assert.EqualError(t, err, "invalid product status transition")
+	})
+}
+
+func TestProductUsecase_ReturnProduct(t *testing.T) {
+	data := requests.ChangeProductStatusRequest{ProductId: "prod-1", Reason: "брак"}
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+		repo.On("LockProductState", mock.Anything, "prod-1").
+			Return(productState(models.PRODUCT_STATUS_ISSUED, "pvz-1", models.STATUS_CLOSED), nil)
+		repo.On("ChangeProductStatus", mock.Anything,
+			matchEvent("prod-1", "pvz-1", models.PRODUCT_STATUS_ISSUED, models.PRODUCT_STATUS_RETURNED, "брак")).
+			Return(nil)
+		repo.On("ChangePvzOccupancy", mock.Anything, "pvz-1", 1).Return(nil)
+		uc := NewProductUsecase(repo)
+
+		event, err := uc.ReturnProduct(employeeCtx(), "pvz-1", data)
+
+		require.NoError(t, err)
+		assert.Equal(t, "pvz-1", event.PvzId)
+		assert.Equal(t, models.PRODUCT_STATUS_RETURNED, event.ToStatus)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("cannot return at another pvz", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+		repo.On("LockProductState", mock.Anything, "prod-1").
+			Return(productState(models.PRODUCT_STATUS_ISSUED, "pvz-1", models.STATUS_CLOSED), nil)
+		uc := NewProductUsecase(repo)
+
+		_, err := uc.ReturnProduct(employeeCtx(), "pvz-2", data)
+
+		assert.EqualError(t, err, "product is not at this pvz")
+		repo.AssertNotCalled(t, "ChangeProductStatus", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "ChangePvzOccupancy", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	for _, status := range []string{models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_REFUSED, models.PRODUCT_STATUS_RETURNED} {
+		t.Run("cannot return "+status+" product", func(t *testing.T) {
+			repo := new(repositoryMocks.MockProductRepository)
+			repo.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+			repo.On("LockProductState", mock.Anything, "prod-1").
+				Return(productState(status, "pvz-1", models.STATUS_CLOSED), nil)
+			uc := NewProductUsecase(repo)
+
+			_, err := uc.ReturnProduct(employeeCtx(), "pvz-1", data)
+
+			assert.EqualError(t, err, "invalid product status transition")
+			repo.AssertNotCalled(t, "ChangeProductStatus", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestProductUsecase_GetProductHistory(t *testing.T) {
+	changedAt := time.Now()
+	events := []models.ProductStatusEvent{
+		{Id: "ev-1", ProductId: "prod-1", PvzId: "pvz-1", FromStatus: models.PRODUCT_STATUS_RECEIVED, ToStatus: models.PRODUCT_STATUS_ISSUED, ChangedBy: "user-1", ChangedAt: changedAt},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		state := productState(models.PRODUCT_STATUS_ISSUED, "pvz-1", models.STATUS_CLOSED)
+		repo.On("GetProductState", mock.Anything, "prod-1").Return(state, nil)
+		repo.On("GetProductStatusEvents", mock.Anything, "prod-1").Return(events, nil)
+		uc := NewProductUsecase(repo)
+
+		gotState, gotEvents, err := uc.GetProductHistory(moderatorCtx(), "prod-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, state, gotState)
+		assert.Equal(t, events, gotEvents)
+	})
+
+	t.Run("product not found", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetProductState", mock.Anything, "missing").Return(nil, errors.New("product not found"))
+		uc := NewProductUsecase(repo)
+
+		_, _, err := uc.GetProductHistory(employeeCtx(), "missing")
+
+		assert.EqualError(t, err, "product not found")
+		repo.AssertNotCalled(t, "GetProductStatusEvents", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		uc := NewProductUsecase(new(repositoryMocks.MockProductRepository))
+		ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "client")
+
+		_, _, err := uc.GetProductHistory(ctx, "prod-1")
+
+		assert.EqualError(t, err, "this role is not allowed")
+	})
+}
+
+func TestCanChangeProductStatus(t *testing.T) {
+	assert.True(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_ISSUED))
+	assert.True(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_REFUSED))
+	assert.True(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_ISSUED, models.PRODUCT_STATUS_RETURNED))
+	assert.False(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_RECEIVED, models.PRODUCT_STATUS_RETURNED))
+	assert.False(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_REFUSED, models.PRODUCT_STATUS_ISSUED))
+	assert.False(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_RETURNED, models.PRODUCT_STATUS_ISSUED))
+	assert.True(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_REFUSED, models.PRODUCT_STATUS_RETURN_TO_SENDER))
+	assert.True(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_RETURNED, models.PRODUCT_STATUS_RETURN_TO_SENDER))
+	assert.False(t, models.CanChangeProductStatus(models.PRODUCT_STATUS_RETURN_TO_SENDER, models.PRODUCT_STATUS_RECEIVED))
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ReturnOverdueProducts переводит в статус возврата отправителю товары, которые не забрали за срок хранения,
+// и освобождает занятое ими место на ПВЗ.
+// Вызывается фоновым обработчиком, поэтому роль не проверяется, а в журнал автором пишется SYSTEM_ACTOR.
+// Каждый товар обрабатывается в своей транзакции: ошибка по одному не мешает остальным.
+func (pu ProductUsecase) ReturnOverdueProducts(ctx context.Context) ([]models.ProductStatusEvent, error) {
+	now := time.Now()
+	ids, err := pu.productRepository.GetOverdueProductIds(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, middleware.ContextKeyUserId, models.SYSTEM_ACTOR)
+	returned := make([]models.ProductStatusEvent, 0, len(ids))
+	var errs []error
+	for _, productId := range ids {
+		var event *models.ProductStatusEvent
+		err = pu.productRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+			state, err := pu.productRepository.LockProductState(ctx, productId)
+			if err != nil {
+				return err
+			}
+			// Пока ждали блокировку, товар могли выдать или продлить ему срок.
+			if !slices.Contains(models.ProductStoredStatuses, state.Status) || state.StorageDeadline == nil || !state.StorageDeadline.Before(now) {
+				return nil
+			}
+			changed, err := pu.applyProductStatus(ctx, state, state.PvzId, models.PRODUCT_STATUS_RETURN_TO_SENDER, models.STORAGE_EXPIRED_REASON)
+			if err != nil {
+				return err
+			}
+			event = &changed
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("product %s: %w", productId, err))
+			continue
+		}
+		if event != nil {
+			returned = append(returned, *event)
+		}
+	}
+
+	return returned, errors.Join(errs...)
+}
+
+// GetOverdueProducts возвращает товары ПВЗ с истёкшим сроком хранения, в том числе уже отмеченные к возврату.
+func (pu ProductUsecase) GetOverdueProducts(ctx context.Context, pvzId string) ([]models.ProductState, error) {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return nil, errors.New("this role is not allowed")
+	}
+	if _, err := pu.productRepository.GetPvzById(ctx, pvzId); err != nil {
+		return nil, err
+	}
+
+	return pu.productRepository.GetPvzOverdueProducts(ctx, pvzId, time.Now())
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func overdueState(productId string, deadline time.Time) *models.ProductState {
+	state := productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_CLOSED)
+	state.Product.Id = productId
+	state.StorageDeadline = &deadline
+	return state
+}
+
+func TestProductUsecase_ReturnOverdueProducts(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+
+	repo := new(repositoryMocks.MockProductRepository)
+	repo.On("GetOverdueProductIds", mock.Anything, mock.AnythingOfType("time.Time")).
+		Return([]string{"prod-1", "prod-2", "prod-3", "prod-4", "prod-5"}, nil)
+	repo.On("LockProductState", mock.Anything, "prod-1").Return(overdueState("prod-1", expired), nil)
+	repo.On("ChangeProductStatus", mock.Anything, mock.MatchedBy(func(event models.ProductStatusEvent) bool {
+		return event.ProductId == "prod-1" && event.PvzId == "pvz-1" && event.ToStatus == models.PRODUCT_STATUS_RETURN_TO_SENDER &&
+			event.Reason == models.STORAGE_EXPIRED_REASON && event.ChangedBy == models.SYSTEM_ACTOR
+	})).Return(nil)
+	repo.On("ChangePvzOccupancy", mock.Anything, "pvz-1", -1).Return(nil).Twice()
+	// prod-2 выдали, пока задача ждала блокировку
+	issued := overdueState("prod-2", expired)
+	issued.Status = models.PRODUCT_STATUS_ISSUED
+	repo.On("LockProductState", mock.Anything, "prod-2").Return(issued, nil)
+	// prod-3 тем временем продлили срок
+	repo.On("LockProductState", mock.Anything, "prod-3").Return(overdueState("prod-3", time.Now().Add(time.Hour)), nil)
+	repo.On("LockProductState", mock.Anything, "prod-4").Return(nil, errors.New("db error"))
+	// prod-5 клиент не выкупил, и он так и остался лежать на ПВЗ
+	refused := overdueState("prod-5", expired)
+	refused.Status = models.PRODUCT_STATUS_REFUSED
+	repo.On("LockProductState", mock.Anything, "prod-5").Return(refused, nil)
+	repo.On("ChangeProductStatus", mock.Anything, mock.MatchedBy(func(event models.ProductStatusEvent) bool {
+		return event.ProductId == "prod-5" && event.FromStatus == models.PRODUCT_STATUS_REFUSED &&
+			event.ToStatus == models.PRODUCT_STATUS_RETURN_TO_SENDER
+	})).Return(nil)
+	uc := NewProductUsecase(repo)
+
+	returned, err := uc.ReturnOverdueProducts(context.Background())
+
+	assert.EqualError(t, err, "product prod-4: db error")
+	require.Len(t, returned, 2)
+	assert.Equal(t, "prod-1", returned[0].ProductId)
+	assert.Equal(t, "prod-5", returned[1].ProductId)
+	repo.AssertNumberOfCalls(t, "ChangeProductStatus", 2)
+	repo.AssertExpectations(t)
+}
+
+func TestProductUsecase_GetOverdueProducts(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+		repo.On("GetPvzOverdueProducts", mock.Anything, "pvz-1", mock.AnythingOfType("time.Time")).
+			Return([]models.ProductState{*overdueState("prod-1", time.Now())}, nil)
+		uc := NewProductUsecase(repo)
+
+		products, err := uc.GetOverdueProducts(moderatorCtx(), "pvz-1")
+
+		require.NoError(t, err)
+		assert.Len(t, products, 1)
+	})
+
+	t.Run("pvz not found", func(t *testing.T) {
+		repo := new(repositoryMocks.MockProductRepository)
+		repo.On("GetPvzById", mock.Anything, "pvz-9").Return(nil, errors.New("pvz not found"))
+		uc := NewProductUsecase(repo)
+
+		_, err := uc.GetOverdueProducts(employeeCtx(), "pvz-9")
+
+		assert.EqualError(t, err, "pvz not found")
+	})
+
+	t.Run("clients are not allowed", func(t *testing.T) {
+		uc := NewProductUsecase(new(repositoryMocks.MockProductRepository))
+
+		_, err := uc.GetOverdueProducts(clientCtx(), "pvz-1")
+
+		assert.EqualError(t, err, "this role is not allowed")
+	})
+}
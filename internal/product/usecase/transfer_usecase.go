@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// CreateTransfer отправляет товары с ПВЗ sourcePvzId на другой ПВЗ. Каждый товар должен лежать
+// на ПВЗ-источнике в закрытой приёмке; до приёма на месте назначения он числится в пути.
+// Перемещение либо создаётся целиком, либо не создаётся вовсе.
+func (pu ProductUsecase) CreateTransfer(ctx context.Context, sourcePvzId string, data requests.CreateTransferRequest) (models.Transfer, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.Transfer{}, errors.New("this role is not allowed")
+	}
+	destinationPvzId := strings.TrimSpace(data.DestinationPvzId)
+	if destinationPvzId == "" {
+		return models.Transfer{}, errors.New("destination pvz id is required")
+	}
+	if destinationPvzId == sourcePvzId {
+		return models.Transfer{}, errors.New("destination pvz must differ from source")
+	}
+	if len(data.ProductIds) == 0 {
+		return models.Transfer{}, errors.New("product ids are required")
+	}
+	if len(data.ProductIds) > models.MAX_PRODUCT_BATCH_SIZE {
+		return models.Transfer{}, errors.New("too many products in transfer")
+	}
+	seen := make(map[string]struct{}, len(data.ProductIds))
+	for _, productId := range data.ProductIds {
+		if productId == "" {
+			return models.Transfer{}, errors.New("product id is required")
+		}
+		if _, ok := seen[productId]; ok {
+			return models.Transfer{}, errors.New("duplicate product id in transfer")
+		}
+		seen[productId] = struct{}{}
+	}
+
+	if _, err := pu.productRepository.GetPvzById(ctx, sourcePvzId); err != nil {
+		return models.Transfer{}, err
+	}
+	if _, err := pu.productRepository.GetPvzById(ctx, destinationPvzId); err != nil {
+		return models.Transfer{}, err
+	}
+
+	transfer := models.Transfer{
+		Id:               uuid.New().String(),
+		SourcePvzId:      sourcePvzId,
+		DestinationPvzId: destinationPvzId,
+		Status:           models.TRANSFER_STATUS_IN_TRANSIT,
+		CreatedBy:        middleware.GetUserId(ctx),
+		CreatedAt:        time.Now(),
+		ProductIds:       data.ProductIds,
+	}
+	reason := "transfer " + transfer.Id
+	err := pu.productRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, productId := range transfer.ProductIds {
+			state, err := pu.productRepository.LockProductState(ctx, productId)
+			if err != nil {
+				return err
+			}
+			if _, err = pu.applyProductStatus(ctx, state, sourcePvzId, models.PRODUCT_STATUS_IN_TRANSIT, reason); err != nil {
+				return err
+			}
+		}
+		return pu.productRepository.CreateTransfer(ctx, transfer)
+	})
+	if err != nil {
+		return models.Transfer{}, err
+	}
+
+	return transfer, nil
+}
+
+// AcceptTransfer принимает перемещение на ПВЗ назначения: товары попадают в его активную приёмку
+// и снова становятся принятыми. Срок хранения им назначит закрытие этой приёмки.
+func (pu ProductUsecase) AcceptTransfer(ctx context.Context, pvzId, transferId string) (models.Transfer, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.Transfer{}, errors.New("this role is not allowed")
+	}
+	if _, err := pu.productRepository.GetPvzById(ctx, pvzId); err != nil {
+		return models.Transfer{}, err
+	}
+
+	var transfer *models.Transfer
+	err := pu.productRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		transfer, err = pu.productRepository.LockTransfer(ctx, transferId)
+		if err != nil {
+			return err
+		}
+		if transfer.DestinationPvzId != pvzId {
+			return errors.New("transfer is not for this pvz")
+		}
+		if transfer.Status != models.TRANSFER_STATUS_IN_TRANSIT {
+			return errors.New("transfer already accepted")
+		}
+		reception, err := pu.productRepository.LockActiveReception(ctx, pvzId)
+		if err != nil {
+			return err
+		}
+
+		// Места на ПВЗ занимаются сразу под всё перемещение: если не хватает хотя бы одного, приёмка откатывается целиком
+		if err = pu.productRepository.ReservePvzCapacity(ctx, pvzId, len(transfer.ProductIds)); err != nil {
+			return err
+		}
+
+		reason := "transfer " + transfer.Id
+		for _, productId := range transfer.ProductIds {
+			state, err := pu.productRepository.LockProductState(ctx, productId)
+			if err != nil {
+				return err
+			}
+			if !models.CanChangeProductStatus(state.Status, models.PRODUCT_STATUS_RECEIVED) {
+				return errors.New("invalid product status transition")
+			}
+			if err = pu.productRepository.MoveProductToReception(ctx, pvzId, productId, reception.Id, transfer.Id); err != nil {
+				return err
+			}
+			event := newProductStatusEvent(ctx, state, pvzId, models.PRODUCT_STATUS_RECEIVED, reason)
+			if err = pu.productRepository.ChangeProductStatus(ctx, event); err != nil {
+				return err
+			}
+		}
+
+		acceptedAt := time.Now()
+		transfer.Status = models.TRANSFER_STATUS_ACCEPTED
+		transfer.AcceptedBy = middleware.GetUserId(ctx)
+		transfer.AcceptedAt = &acceptedAt
+		transfer.ReceptionId = reception.Id
+		return pu.productRepository.AcceptTransfer(ctx, *transfer)
+	})
+	if err != nil {
+		return models.Transfer{}, err
+	}
+
+	return *transfer, nil
+}
+
+// GetIncomingTransfers возвращает непринятые перемещения, которые едут на ПВЗ.
+func (pu ProductUsecase) GetIncomingTransfers(ctx context.Context, pvzId string) ([]models.Transfer, error) {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return nil, errors.New("this role is not allowed")
+	}
+	if _, err := pu.productRepository.GetPvzById(ctx, pvzId); err != nil {
+		return nil, err
+	}
+
+	return pu.productRepository.GetIncomingTransfers(ctx, pvzId)
+}
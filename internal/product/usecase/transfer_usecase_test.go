@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestProductUsecase_CreateTransfer(t *testing.T) {
+	data := requests.CreateTransferRequest{DestinationPvzId: "pvz-2", ProductIds: []string{"prod-1"}}
+
+	tests := []struct {
+		name        string
+		data        requests.CreateTransferRequest
+		mockSetup   func(*repositoryMocks.MockProductRepository)
+		expectedErr string
+	}{
+		{
+			name: "success",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-1", models.STATUS_CLOSED), nil)
+				m.On("ChangeProductStatus", mock.Anything, mock.MatchedBy(func(event models.ProductStatusEvent) bool {
+					return event.ProductId == "prod-1" && event.PvzId == "pvz-1" && event.ToStatus == models.PRODUCT_STATUS_IN_TRANSIT
+				})).Return(nil)
+				m.On("ChangePvzOccupancy", mock.Anything, "pvz-1", -1).Return(nil)
+				m.On("CreateTransfer", mock.Anything, mock.MatchedBy(func(transfer models.Transfer) bool {
+					return transfer.Id != "" && transfer.SourcePvzId == "pvz-1" && transfer.DestinationPvzId == "pvz-2" &&
+						transfer.Status == models.TRANSFER_STATUS_IN_TRANSIT && transfer.CreatedBy == "user-1"
+				})).Return(nil)
+			},
+		},
+		{
+			name: "product already in transit",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_IN_TRANSIT, "pvz-1", models.STATUS_CLOSED), nil)
+			},
+			expectedErr: "invalid product status transition",
+		},
+		{
+			name: "product at another pvz",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_RECEIVED, "pvz-3", models.STATUS_CLOSED), nil)
+			},
+			expectedErr: "product is not at this pvz",
+		},
+		{
+			name: "destination not found",
+			data: data,
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-1").Return(&models.Pvz{Id: "pvz-1"}, nil)
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(nil, errors.New("pvz not found"))
+			},
+			expectedErr: "pvz not found",
+		},
+		{
+			name:        "same pvz",
+			data:        requests.CreateTransferRequest{DestinationPvzId: "pvz-1", ProductIds: []string{"prod-1"}},
+			mockSetup:   func(_ *repositoryMocks.MockProductRepository) {},
+			expectedErr: "destination pvz must differ from source",
+		},
+		{
+			name:        "no products",
+			data:        requests.CreateTransferRequest{DestinationPvzId: "pvz-2"},
+			mockSetup:   func(_ *repositoryMocks.MockProductRepository) {},
+			expectedErr: "product ids are required",
+		},
+		{
+			name:        "duplicate product",
+			data:        requests.CreateTransferRequest{DestinationPvzId: "pvz-2", ProductIds: []string{"prod-1", "prod-1"}},
+			mockSetup:   func(_ *repositoryMocks.MockProductRepository) {},
+			expectedErr: "duplicate product id in transfer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(repositoryMocks.MockProductRepository)
+			tt.mockSetup(repo)
+			uc := NewProductUsecase(repo)
+
+			transfer, err := uc.CreateTransfer(employeeCtx(), "pvz-1", tt.data)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				repo.AssertNotCalled(t, "CreateTransfer", mock.Anything, mock.Anything)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, []string{"prod-1"}, transfer.ProductIds)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProductUsecase_AcceptTransfer(t *testing.T) {
+	transfer := func() *models.Transfer {
+		return &models.Transfer{
+			Id: "tr-1", SourcePvzId: "pvz-1", DestinationPvzId: "pvz-2",
+			Status: models.TRANSFER_STATUS_IN_TRANSIT, ProductIds: []string{"prod-1"},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		mockSetup   func(*repositoryMocks.MockProductRepository)
+		expectedErr string
+	}{
+		{
+			name: "success",
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				m.On("LockTransfer", mock.Anything, "tr-1").Return(transfer(), nil)
+				m.On("LockActiveReception", mock.Anything, "pvz-2").Return(&models.Reception{Id: "rec-2", PvzId: "pvz-2"}, nil)
+				m.On("LockProductState", mock.Anything, "prod-1").
+					Return(productState(models.PRODUCT_STATUS_IN_TRANSIT, "pvz-1", models.STATUS_CLOSED), nil)
+				m.On("ReservePvzCapacity", mock.Anything, "pvz-2", 1).Return(nil)
+				m.On("MoveProductToReception", mock.Anything, "pvz-2", "prod-1", "rec-2", "tr-1").Return(nil)
+				m.On("ChangeProductStatus", mock.Anything,
+					matchEvent("prod-1", "pvz-2", models.PRODUCT_STATUS_IN_TRANSIT, models.PRODUCT_STATUS_RECEIVED, "transfer tr-1")).
+					Return(nil)
+				m.On("AcceptTransfer", mock.Anything, mock.MatchedBy(func(transfer models.Transfer) bool {
+					return transfer.ReceptionId == "rec-2" && transfer.AcceptedBy == "user-1" && transfer.AcceptedAt != nil
+				})).Return(nil)
+			},
+		},
+		{
+			name: "wrong destination",
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				wrong := transfer()
+				wrong.DestinationPvzId = "pvz-3"
+				m.On("LockTransfer", mock.Anything, "tr-1").Return(wrong, nil)
+			},
+			expectedErr: "transfer is not for this pvz",
+		},
+		{
+			name: "already accepted",
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				accepted := transfer()
+				accepted.Status = models.TRANSFER_STATUS_ACCEPTED
+				m.On("LockTransfer", mock.Anything, "tr-1").Return(accepted, nil)
+			},
+			expectedErr: "transfer already accepted",
+		},
+		{
+			name: "no active reception",
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				m.On("LockTransfer", mock.Anything, "tr-1").Return(transfer(), nil)
+				m.On("LockActiveReception", mock.Anything, "pvz-2").Return(nil, errors.New("no active reception"))
+			},
+			expectedErr: "no active reception",
+		},
+		{
+			name: "pvz capacity exceeded",
+			mockSetup: func(m *repositoryMocks.MockProductRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+				m.On("LockTransfer", mock.Anything, "tr-1").Return(transfer(), nil)
+				m.On("LockActiveReception", mock.Anything, "pvz-2").Return(&models.Reception{Id: "rec-2", PvzId: "pvz-2"}, nil)
+				m.On("ReservePvzCapacity", mock.Anything, "pvz-2", 1).Return(errors.New("pvz capacity exceeded"))
+			},
+			expectedErr: "pvz capacity exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(repositoryMocks.MockProductRepository)
+			tt.mockSetup(repo)
+			uc := NewProductUsecase(repo)
+
+			accepted, err := uc.AcceptTransfer(employeeCtx(), "pvz-2", "tr-1")
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				repo.AssertNotCalled(t, "MoveProductToReception", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				repo.AssertNotCalled(t, "AcceptTransfer", mock.Anything, mock.Anything)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.TRANSFER_STATUS_ACCEPTED, accepted.Status)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProductUsecase_GetIncomingTransfers(t *testing.T) {
+	repo := new(repositoryMocks.MockProductRepository)
+	repo.On("GetPvzById", mock.Anything, "pvz-2").Return(&models.Pvz{Id: "pvz-2"}, nil)
+	repo.On("GetIncomingTransfers", mock.Anything, "pvz-2").Return([]models.Transfer{{Id: "tr-1"}}, nil)
+	uc := NewProductUsecase(repo)
+
+	transfers, err := uc.GetIncomingTransfers(moderatorCtx(), "pvz-2")
+	require.NoError(t, err)
+	assert.Len(t, transfers, 1)
+
+	_, err = uc.GetIncomingTransfers(clientCtx(), "pvz-2")
+	assert.EqualError(t, err, "this role is not allowed")
+}
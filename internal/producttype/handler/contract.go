@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"context"
+)
+
+type ProductTypeUsecase interface {
+	GetProductTypes(ctx context.Context) ([]models.ProductType, error)
+	CreateProductType(ctx context.Context, data requests.CreateProductTypeRequest) (models.ProductType, error)
+	UpdateProductType(ctx context.Context, code string, data requests.UpdateProductTypeRequest) (models.ProductType, error)
+	DeleteProductType(ctx context.Context, code string) error
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ProductTypeHandler struct {
+	usecase ProductTypeUsecase
+}
+
+func NewProductTypeHandler(usecase ProductTypeUsecase) *ProductTypeHandler {
+	return &ProductTypeHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *ProductTypeHandler) GetProductTypes(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	defer cancel()
+
+	productTypes, err := h.usecase.GetProductTypes(ctx)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetProductTypesResponse{
+		ProductTypes: make([]responses.ProductTypeResponse, 0, len(productTypes)),
+	}
+	for _, productType := range productTypes {
+		response.ProductTypes = append(response.ProductTypes, toProductTypeResponse(productType))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductTypeHandler) CreateProductType(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	var data requests.CreateProductTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.Code = sanitizer.Sanitize(data.Code)
+	data.NameRu = sanitizer.Sanitize(data.NameRu)
+	data.NameEn = sanitizer.Sanitize(data.NameEn)
+
+	productType, err := h.usecase.CreateProductType(ctx, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(toProductTypeResponse(productType)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductTypeHandler) UpdateProductType(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	code := sanitizer.Sanitize(mux.Vars(r)["code"])
+
+	var data requests.UpdateProductTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	if data.NameRu != nil {
+		nameRu := sanitizer.Sanitize(*data.NameRu)
+		data.NameRu = &nameRu
+	}
+	if data.NameEn != nil {
+		nameEn := sanitizer.Sanitize(*data.NameEn)
+		data.NameEn = &nameEn
+	}
+
+	productType, err := h.usecase.UpdateProductType(ctx, code, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toProductTypeResponse(productType)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ProductTypeHandler) DeleteProductType(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	code := sanitizer.Sanitize(mux.Vars(r)["code"])
+
+	if err := h.usecase.DeleteProductType(ctx, code); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func toProductTypeResponse(productType models.ProductType) responses.ProductTypeResponse {
+	return responses.ProductTypeResponse{
+		Code:   productType.Code,
+		NameRu: productType.NameRu,
+		NameEn: productType.NameEn,
+		Active: productType.Active,
+
+		StorageDays:  productType.StorageDays,
+		SizeCategory: productType.SizeCategory,
+	}
+}
+
+func (h *ProductTypeHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "product type code is required", "invalid product type code", "product type name is required",
+		"invalid storage days", "invalid size category":
+		w.WriteHeader(http.StatusBadRequest)
+	case "product type not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "product type already exists", "product type is in use":
+		w.WriteHeader(http.StatusConflict)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
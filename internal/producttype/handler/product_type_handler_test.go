@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProductTypeHandler(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	inactive := false
+	storageDays := 14
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		vars           map[string]string
+		call           func(h *ProductTypeHandler) http.HandlerFunc
+		mockBehavior   func(usecase *usecaseMocks.ProductTypeUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "list",
+			method: http.MethodGet,
+			path:   "/api/product_types",
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.GetProductTypes },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("GetProductTypes", mock.Anything).Return([]models.ProductType{
+					{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: "m"},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"productTypes":[{"code":"обувь","nameRu":"Обувь","nameEn":"Shoes","active":true,"storageDays":7,"sizeCategory":"m"}]}`,
+		},
+		{
+			name:   "create",
+			method: http.MethodPost,
+			path:   "/api/product_types",
+			body:   `{"code":"шины","nameRu":"Шины","nameEn":"Tyres","storageDays":14}`,
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.CreateProductType },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("CreateProductType", mock.Anything, requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины", NameEn: "Tyres", StorageDays: &storageDays}).
+					Return(models.ProductType{Code: "шины", NameRu: "Шины", NameEn: "Tyres", Active: true, StorageDays: 14, SizeCategory: "l"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"code":"шины","nameRu":"Шины","nameEn":"Tyres","active":true,"storageDays":14,"sizeCategory":"l"}`,
+		},
+		{
+			name:   "create duplicate",
+			method: http.MethodPost,
+			path:   "/api/product_types",
+			body:   `{"code":"обувь","nameRu":"Обувь"}`,
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.CreateProductType },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("CreateProductType", mock.Anything, mock.Anything).
+					Return(models.ProductType{}, errors.New("product type already exists"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"product type already exists"}`,
+		},
+		{
+			name:   "create forbidden",
+			method: http.MethodPost,
+			path:   "/api/product_types",
+			body:   `{"code":"шины","nameRu":"Шины"}`,
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.CreateProductType },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("CreateProductType", mock.Anything, mock.Anything).
+					Return(models.ProductType{}, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"errors":"this role is not allowed"}`,
+		},
+		{
+			name:   "deactivate",
+			method: http.MethodPatch,
+			path:   "/api/product_types/обувь",
+			body:   `{"active":false}`,
+			vars:   map[string]string{"code": "обувь"},
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.UpdateProductType },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("UpdateProductType", mock.Anything, "обувь", requests.UpdateProductTypeRequest{Active: &inactive}).
+					Return(models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", StorageDays: 7, SizeCategory: "s"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"code":"обувь","nameRu":"Обувь","nameEn":"Shoes","active":false,"storageDays":7,"sizeCategory":"s"}`,
+		},
+		{
+			name:   "update not found",
+			method: http.MethodPatch,
+			path:   "/api/product_types/шины",
+			body:   `{"active":false}`,
+			vars:   map[string]string{"code": "шины"},
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.UpdateProductType },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("UpdateProductType", mock.Anything, "шины", mock.Anything).
+					Return(models.ProductType{}, errors.New("product type not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"product type not found"}`,
+		},
+		{
+			name:   "delete in use",
+			method: http.MethodDelete,
+			path:   "/api/product_types/обувь",
+			vars:   map[string]string{"code": "обувь"},
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.DeleteProductType },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("DeleteProductType", mock.Anything, "обувь").Return(errors.New("product type is in use"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"product type is in use"}`,
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			path:   "/api/product_types/шины",
+			vars:   map[string]string{"code": "шины"},
+			call:   func(h *ProductTypeHandler) http.HandlerFunc { return h.DeleteProductType },
+			mockBehavior: func(usecase *usecaseMocks.ProductTypeUsecaseMock) {
+				usecase.On("DeleteProductType", mock.Anything, "шины").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ProductTypeUsecaseMock)
+			handler := NewProductTypeHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.vars != nil {
+				req = mux.SetURLVars(req, tt.vars)
+			}
+			w := httptest.NewRecorder()
+			tt.call(handler)(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, string(body))
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
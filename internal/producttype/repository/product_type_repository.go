@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
+type ProductTypeRepository struct {
+	db *sql.DB
+}
+
+func NewProductTypeRepository(db *sql.DB) ProductTypeRepository {
+	return ProductTypeRepository{
+		db: db,
+	}
+}
+
+func (r ProductTypeRepository) GetProductTypes(ctx context.Context) ([]models.ProductType, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductTypes called", zap.String("request_id", requestID))
+
+	query, args, err := sq.Select("code", "name_ru", "name_en", "active", "storage_days", "size_category").
+		From("product_types").
+		OrderBy("code").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query product types", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	productTypes := []models.ProductType{}
+	for rows.Next() {
+		var productType models.ProductType
+		if err := rows.Scan(&productType.Code, &productType.NameRu, &productType.NameEn, &productType.Active, &productType.StorageDays, &productType.SizeCategory); err != nil {
+			logger.DBLogger.Error("failed to scan product type", zap.Error(err))
+			return nil, err
+		}
+		productTypes = append(productTypes, productType)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return productTypes, nil
+}
+
+func (r ProductTypeRepository) GetProductType(ctx context.Context, code string) (*models.ProductType, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductType called", zap.String("request_id", requestID), zap.String("code", code))
+
+	query, args, err := sq.Select("code", "name_ru", "name_en", "active", "storage_days", "size_category").
+		From("product_types").
+		Where(sq.Eq{"code": code}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var productType models.ProductType
+	err = r.db.QueryRowContext(ctx, query, args...).
+		Scan(&productType.Code, &productType.NameRu, &productType.NameEn, &productType.Active, &productType.StorageDays, &productType.SizeCategory)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product type not found")
+		}
+		logger.DBLogger.Error("failed to scan product type", zap.Error(err))
+		return nil, err
+	}
+
+	return &productType, nil
+}
+
+func (r ProductTypeRepository) CreateProductType(ctx context.Context, productType models.ProductType) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CreateProductType called",
+		zap.String("request_id", requestID),
+		zap.String("code", productType.Code),
+	)
+
+	query, args, err := sq.Insert("product_types").
+		Columns("code", "name_ru", "name_en", "active", "storage_days", "size_category").
+		Values(productType.Code, productType.NameRu, productType.NameEn, productType.Active, productType.StorageDays, productType.SizeCategory).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode {
+			return errors.New("product type already exists")
+		}
+		logger.DBLogger.Error("failed to insert product type", zap.Error(err))
+		return err
+	}
+
+	logger.DBLogger.Info("Product type successfully created",
+		zap.String("request_id", requestID),
+		zap.String("code", productType.Code),
+	)
+
+	return nil
+}
+
+func (r ProductTypeRepository) UpdateProductType(ctx context.Context, productType models.ProductType) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("UpdateProductType called",
+		zap.String("request_id", requestID),
+		zap.String("code", productType.Code),
+	)
+
+	query, args, err := sq.Update("product_types").
+		Set("name_ru", productType.NameRu).
+		Set("name_en", productType.NameEn).
+		Set("active", productType.Active).
+		Set("storage_days", productType.StorageDays).
+		Set("size_category", productType.SizeCategory).
+		Where(sq.Eq{"code": productType.Code}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to update product type", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("product type not found")
+	}
+
+	return nil
+}
+
+// DeleteProductType удаляет тип, только если на него не ссылается ни один товар:
+// для типов с историей нужно снимать флаг active.
+func (r ProductTypeRepository) DeleteProductType(ctx context.Context, code string) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("DeleteProductType called",
+		zap.String("request_id", requestID),
+		zap.String("code", code),
+	)
+
+	query, args, err := sq.Delete("product_types").
+		Where(sq.Eq{"code": code}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build delete SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolationCode {
+			return errors.New("product type is in use")
+		}
+		logger.DBLogger.Error("failed to execute delete", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("product type not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func newMockRepository(t *testing.T) (ProductTypeRepository, sqlmock.Sqlmock) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return NewProductTypeRepository(db), mock
+}
+
+func TestProductTypeRepository_GetProductTypes(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`^SELECT code, name_ru, name_en, active, storage_days, size_category FROM product_types ORDER BY code$`).
+		WillReturnRows(sqlmock.NewRows([]string{"code", "name_ru", "name_en", "active", "storage_days", "size_category"}).
+			AddRow("обувь", "Обувь", "Shoes", true, 7, "m").
+			AddRow("шины", "Шины", "", false, 14, "l"))
+
+	productTypes, err := repo.GetProductTypes(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []models.ProductType{
+		{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: "m"},
+		{Code: "шины", NameRu: "Шины", Active: false, StorageDays: 14, SizeCategory: "l"},
+	}, productTypes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductTypeRepository_GetProductType(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT code, name_ru, name_en, active, storage_days, size_category FROM product_types WHERE code = \$1$`
+
+	mock.ExpectQuery(query).
+		WithArgs("обувь").
+		WillReturnRows(sqlmock.NewRows([]string{"code", "name_ru", "name_en", "active", "storage_days", "size_category"}).
+			AddRow("обувь", "Обувь", "Shoes", true, 7, "m"))
+	productType, err := repo.GetProductType(context.Background(), "обувь")
+	require.NoError(t, err)
+	assert.Equal(t, &models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: "m"}, productType)
+
+	mock.ExpectQuery(query).
+		WithArgs("шины").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetProductType(context.Background(), "шины")
+	assert.EqualError(t, err, "product type not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductTypeRepository_CreateProductType(t *testing.T) {
+	productType := models.ProductType{Code: "шины", NameRu: "Шины", NameEn: "Tyres", Active: true, StorageDays: 14, SizeCategory: "l"}
+	query := `^INSERT INTO product_types \(code,name_ru,name_en,active,storage_days,size_category\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\)$`
+
+	tests := []struct {
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("шины", "Шины", "Tyres", true, 14, "l").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Already Exists",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WillReturnError(&pq.Error{Code: uniqueViolationCode})
+			},
+			errMsg: "product type already exists",
+		},
+		{
+			name: "Database Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WillReturnError(errors.New("db error"))
+			},
+			errMsg: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			tt.mock(mock)
+
+			err := repo.CreateProductType(context.Background(), productType)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProductTypeRepository_UpdateProductType(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^UPDATE product_types SET name_ru = \$1, name_en = \$2, active = \$3, storage_days = \$4, size_category = \$5 WHERE code = \$6$`
+
+	mock.ExpectExec(query).
+		WithArgs("Обувь", "Footwear", false, 10, "s", "обувь").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err := repo.UpdateProductType(context.Background(), models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Footwear", StorageDays: 10, SizeCategory: "s"})
+	require.NoError(t, err)
+
+	mock.ExpectExec(query).
+		WithArgs("Шины", "", true, 7, "m", "шины").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = repo.UpdateProductType(context.Background(), models.ProductType{Code: "шины", NameRu: "Шины", Active: true, StorageDays: 7, SizeCategory: "m"})
+	assert.EqualError(t, err, "product type not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductTypeRepository_DeleteProductType(t *testing.T) {
+	query := `^DELETE FROM product_types WHERE code = \$1$`
+
+	tests := []struct {
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("шины").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Not Found",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("шины").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			errMsg: "product type not found",
+		},
+		{
+			name: "Referenced By Products",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).
+					WithArgs("шины").
+					WillReturnError(&pq.Error{Code: foreignKeyViolationCode})
+			},
+			errMsg: "product type is in use",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			tt.mock(mock)
+
+			err := repo.DeleteProductType(context.Background(), "шины")
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+)
+
+type ProductTypeRepository interface {
+	GetProductTypes(ctx context.Context) ([]models.ProductType, error)
+	GetProductType(ctx context.Context, code string) (*models.ProductType, error)
+	CreateProductType(ctx context.Context, productType models.ProductType) error
+	UpdateProductType(ctx context.Context, productType models.ProductType) error
+	DeleteProductType(ctx context.Context, code string) error
+}
@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// productTypeCacheTTL ограничивает, как долго другие экземпляры сервиса могут не видеть изменения справочника.
+const productTypeCacheTTL = time.Minute
+
+type ProductTypeUsecase struct {
+	productTypeRepository ProductTypeRepository
+	cache                 *productTypeCache
+}
+
+func NewProductTypeUsecase(productTypeRepository ProductTypeRepository) ProductTypeUsecase {
+	return ProductTypeUsecase{
+		productTypeRepository: productTypeRepository,
+		cache:                 &productTypeCache{ttl: productTypeCacheTTL},
+	}
+}
+
+func (pu ProductTypeUsecase) GetProductTypes(ctx context.Context) ([]models.ProductType, error) {
+	return pu.productTypeRepository.GetProductTypes(ctx)
+}
+
+func (pu ProductTypeUsecase) CreateProductType(ctx context.Context, data requests.CreateProductTypeRequest) (models.ProductType, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.ProductType{}, errors.New("this role is not allowed")
+	}
+
+	productType := models.ProductType{
+		Code:   strings.TrimSpace(data.Code),
+		NameRu: strings.TrimSpace(data.NameRu),
+		NameEn: strings.TrimSpace(data.NameEn),
+		Active: true,
+
+		StorageDays:  models.DEFAULT_STORAGE_DAYS,
+		SizeCategory: models.DEFAULT_SIZE_CATEGORY,
+	}
+	if data.Active != nil {
+		productType.Active = *data.Active
+	}
+	if data.StorageDays != nil {
+		productType.StorageDays = *data.StorageDays
+	}
+	if productType.StorageDays < 1 || productType.StorageDays > models.MAX_STORAGE_DAYS {
+		return models.ProductType{}, errors.New("invalid storage days")
+	}
+	if data.SizeCategory != nil {
+		productType.SizeCategory = strings.TrimSpace(*data.SizeCategory)
+	}
+	if !models.IsValidCellSize(productType.SizeCategory) {
+		return models.ProductType{}, errors.New("invalid size category")
+	}
+	if productType.Code == "" {
+		return models.ProductType{}, errors.New("product type code is required")
+	}
+	if len([]rune(productType.Code)) > models.MAX_PRODUCT_TYPE_CODE_LENGTH {
+		return models.ProductType{}, errors.New("invalid product type code")
+	}
+	if productType.NameRu == "" {
+		return models.ProductType{}, errors.New("product type name is required")
+	}
+
+	if err := pu.productTypeRepository.CreateProductType(ctx, productType); err != nil {
+		return models.ProductType{}, err
+	}
+	pu.cache.invalidate()
+
+	return productType, nil
+}
+
+func (pu ProductTypeUsecase) UpdateProductType(ctx context.Context, code string, data requests.UpdateProductTypeRequest) (models.ProductType, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.ProductType{}, errors.New("this role is not allowed")
+	}
+
+	productType, err := pu.productTypeRepository.GetProductType(ctx, code)
+	if err != nil {
+		return models.ProductType{}, err
+	}
+	if data.NameRu != nil {
+		productType.NameRu = strings.TrimSpace(*data.NameRu)
+		if productType.NameRu == "" {
+			return models.ProductType{}, errors.New("product type name is required")
+		}
+	}
+	if data.NameEn != nil {
+		productType.NameEn = strings.TrimSpace(*data.NameEn)
+	}
+	if data.Active != nil {
+		productType.Active = *data.Active
+	}
+	if data.StorageDays != nil {
+		if *data.StorageDays < 1 || *data.StorageDays > models.MAX_STORAGE_DAYS {
+			return models.ProductType{}, errors.New("invalid storage days")
+		}
+		productType.StorageDays = *data.StorageDays
+	}
+	if data.SizeCategory != nil {
+		productType.SizeCategory = strings.TrimSpace(*data.SizeCategory)
+		if !models.IsValidCellSize(productType.SizeCategory) {
+			return models.ProductType{}, errors.New("invalid size category")
+		}
+	}
+
+	if err := pu.productTypeRepository.UpdateProductType(ctx, *productType); err != nil {
+		return models.ProductType{}, err
+	}
+	pu.cache.invalidate()
+
+	return *productType, nil
+}
+
+func (pu ProductTypeUsecase) DeleteProductType(ctx context.Context, code string) error {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return errors.New("this role is not allowed")
+	}
+
+	if err := pu.productTypeRepository.DeleteProductType(ctx, code); err != nil {
+		return err
+	}
+	pu.cache.invalidate()
+
+	return nil
+}
+
+// IsActiveProductType проверяет, можно ли принимать товары этого типа.
+func (pu ProductTypeUsecase) IsActiveProductType(ctx context.Context, code string) (bool, error) {
+	productType, ok, err := pu.lookup(ctx, code)
+	if err != nil || !ok {
+		return false, err
+	}
+	return productType.Active, nil
+}
+
+// ProductTypeExists проверяет, что тип есть в справочнике, в том числе отключённый: по нему можно фильтровать историю.
+func (pu ProductTypeUsecase) ProductTypeExists(ctx context.Context, code string) (bool, error) {
+	_, ok, err := pu.lookup(ctx, code)
+	return ok, err
+}
+
+// ProductTypeSize возвращает размер товаров этого типа для подбора ячейки хранения.
+func (pu ProductTypeUsecase) ProductTypeSize(ctx context.Context, code string) (string, error) {
+	productType, ok, err := pu.lookup(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	if !ok || productType.SizeCategory == "" {
+		return models.DEFAULT_SIZE_CATEGORY, nil
+	}
+	return productType.SizeCategory, nil
+}
+
+func (pu ProductTypeUsecase) lookup(ctx context.Context, code string) (models.ProductType, bool, error) {
+	if productType, ok, fresh := pu.cache.get(code); fresh {
+		return productType, ok, nil
+	}
+
+	productTypes, err := pu.productTypeRepository.GetProductTypes(ctx)
+	if err != nil {
+		return models.ProductType{}, false, err
+	}
+	pu.cache.set(productTypes)
+
+	productType, ok, _ := pu.cache.get(code)
+	return productType, ok, nil
+}
+
+type productTypeCache struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	loadedAt time.Time
+	byCode   map[string]models.ProductType
+}
+
+// get возвращает тип из кэша; fresh=false означает, что кэш пуст или устарел и его надо перечитать.
+func (c *productTypeCache) get(code string) (models.ProductType, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.byCode == nil || time.Since(c.loadedAt) >= c.ttl {
+		return models.ProductType{}, false, false
+	}
+	productType, ok := c.byCode[code]
+	return productType, ok, true
+}
+
+func (c *productTypeCache) set(productTypes []models.ProductType) {
+	byCode := make(map[string]models.ProductType, len(productTypes))
+	for _, productType := range productTypes {
+		byCode[productType.Code] = productType
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byCode = byCode
+	c.loadedAt = time.Now()
+}
+
+func (c *productTypeCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byCode = nil
+}
@@ -0,0 +1,391 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func moderatorCtx() context.Context {
+	return context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+}
+
+func employeeCtx() context.Context {
+	return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+}
+
+func TestProductTypeUsecase_CreateProductType(t *testing.T) {
+	inactive := false
+	storageDays := 14
+	tooLong := models.MAX_STORAGE_DAYS + 1
+	large := models.CELL_SIZE_L
+	paddedLarge := " " + models.CELL_SIZE_L + " "
+	unknownSize := "xl"
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.CreateProductTypeRequest
+		mockSetup   func(*repositoryMocks.MockProductTypeRepository)
+		expectedRes models.ProductType
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  moderatorCtx,
+			data: requests.CreateProductTypeRequest{Code: " шины ", NameRu: "Шины", NameEn: "Tyres"},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("CreateProductType", mock.Anything, models.ProductType{Code: "шины", NameRu: "Шины", NameEn: "Tyres", Active: true, StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.DEFAULT_SIZE_CATEGORY}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "шины", NameRu: "Шины", NameEn: "Tyres", Active: true, StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.DEFAULT_SIZE_CATEGORY},
+		},
+		{
+			name: "custom storage period",
+			ctx:  moderatorCtx,
+			data: requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины", StorageDays: &storageDays},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("CreateProductType", mock.Anything, models.ProductType{Code: "шины", NameRu: "Шины", Active: true, StorageDays: 14, SizeCategory: models.DEFAULT_SIZE_CATEGORY}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "шины", NameRu: "Шины", Active: true, StorageDays: 14, SizeCategory: models.DEFAULT_SIZE_CATEGORY},
+		},
+		{
+			name:        "invalid storage period",
+			ctx:         moderatorCtx,
+			data:        requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины", StorageDays: &tooLong},
+			mockSetup:   func(_ *repositoryMocks.MockProductTypeRepository) {},
+			expectedErr: errors.New("invalid storage days"),
+		},
+		{
+			name: "large size category",
+			ctx:  moderatorCtx,
+			data: requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины", SizeCategory: &large},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("CreateProductType", mock.Anything, models.ProductType{Code: "шины", NameRu: "Шины", Active: true, StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.CELL_SIZE_L}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "шины", NameRu: "Шины", Active: true, StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.CELL_SIZE_L},
+		},
+		{
+			name: "size category with spaces",
+			ctx:  moderatorCtx,
+			data: requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины", SizeCategory: &paddedLarge},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("CreateProductType", mock.Anything, models.ProductType{Code: "шины", NameRu: "Шины", Active: true, StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.CELL_SIZE_L}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "шины", NameRu: "Шины", Active: true, StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.CELL_SIZE_L},
+		},
+		{
+			name:        "invalid size category",
+			ctx:         moderatorCtx,
+			data:        requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины", SizeCategory: &unknownSize},
+			mockSetup:   func(_ *repositoryMocks.MockProductTypeRepository) {},
+			expectedErr: errors.New("invalid size category"),
+		},
+		{
+			name: "created inactive",
+			ctx:  moderatorCtx,
+			data: requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины", Active: &inactive},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("CreateProductType", mock.Anything, models.ProductType{Code: "шины", NameRu: "Шины", StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.DEFAULT_SIZE_CATEGORY}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "шины", NameRu: "Шины", StorageDays: models.DEFAULT_STORAGE_DAYS, SizeCategory: models.DEFAULT_SIZE_CATEGORY},
+		},
+		{
+			name:        "invalid role",
+			ctx:         employeeCtx,
+			data:        requests.CreateProductTypeRequest{Code: "шины", NameRu: "Шины"},
+			mockSetup:   func(_ *repositoryMocks.MockProductTypeRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "empty code",
+			ctx:         moderatorCtx,
+			data:        requests.CreateProductTypeRequest{Code: " ", NameRu: "Шины"},
+			mockSetup:   func(_ *repositoryMocks.MockProductTypeRepository) {},
+			expectedErr: errors.New("product type code is required"),
+		},
+		{
+			name:        "code too long",
+			ctx:         moderatorCtx,
+			data:        requests.CreateProductTypeRequest{Code: strings.Repeat("ш", models.MAX_PRODUCT_TYPE_CODE_LENGTH+1), NameRu: "Шины"},
+			mockSetup:   func(_ *repositoryMocks.MockProductTypeRepository) {},
+			expectedErr: errors.New("invalid product type code"),
+		},
+		{
+			name:        "empty name",
+			ctx:         moderatorCtx,
+			data:        requests.CreateProductTypeRequest{Code: "шины"},
+			mockSetup:   func(_ *repositoryMocks.MockProductTypeRepository) {},
+			expectedErr: errors.New("product type name is required"),
+		},
+		{
+			name: "already exists",
+			ctx:  moderatorCtx,
+			data: requests.CreateProductTypeRequest{Code: "обувь", NameRu: "Обувь"},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("CreateProductType", mock.Anything, mock.Anything).
+					Return(errors.New("product type already exists"))
+			},
+			expectedErr: errors.New("product type already exists"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockProductTypeRepository)
+			uc := NewProductTypeUsecase(mockRepo)
+			tt.mockSetup(mockRepo)
+
+			res, err := uc.CreateProductType(tt.ctx(), tt.data)
+			assert.Equal(t, tt.expectedRes, res)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProductTypeUsecase_UpdateProductType(t *testing.T) {
+	inactive := false
+	newName := "Обувь и аксессуары"
+	emptyName := " "
+	storageDays := 14
+	zeroDays := 0
+	small := models.CELL_SIZE_S
+	paddedSmall := models.CELL_SIZE_S + " "
+	unknownSize := "xl"
+	current := func() *models.ProductType {
+		return &models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: models.CELL_SIZE_M}
+	}
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.UpdateProductTypeRequest
+		mockSetup   func(*repositoryMocks.MockProductTypeRepository)
+		expectedRes models.ProductType
+		expectedErr error
+	}{
+		{
+			name: "deactivate keeps names",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{Active: &inactive},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+				m.On("UpdateProductType", mock.Anything, models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", StorageDays: 7, SizeCategory: models.CELL_SIZE_M}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", StorageDays: 7, SizeCategory: models.CELL_SIZE_M},
+		},
+		{
+			name: "rename",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{NameRu: &newName},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+				m.On("UpdateProductType", mock.Anything, models.ProductType{Code: "обувь", NameRu: newName, NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: models.CELL_SIZE_M}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "обувь", NameRu: newName, NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: models.CELL_SIZE_M},
+		},
+		{
+			name: "change storage period",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{StorageDays: &storageDays},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+				m.On("UpdateProductType", mock.Anything, models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 14, SizeCategory: models.CELL_SIZE_M}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 14, SizeCategory: models.CELL_SIZE_M},
+		},
+		{
+			name: "invalid storage period",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{StorageDays: &zeroDays},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+			},
+			expectedErr: errors.New("invalid storage days"),
+		},
+		{
+			name: "change size category",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{SizeCategory: &small},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+				m.On("UpdateProductType", mock.Anything, models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: models.CELL_SIZE_S}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: models.CELL_SIZE_S},
+		},
+		{
+			name: "size category with spaces",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{SizeCategory: &paddedSmall},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+				m.On("UpdateProductType", mock.Anything, models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: models.CELL_SIZE_S}).
+					Return(nil)
+			},
+			expectedRes: models.ProductType{Code: "обувь", NameRu: "Обувь", NameEn: "Shoes", Active: true, StorageDays: 7, SizeCategory: models.CELL_SIZE_S},
+		},
+		{
+			name: "invalid size category",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{SizeCategory: &unknownSize},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+			},
+			expectedErr: errors.New("invalid size category"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         employeeCtx,
+			data:        requests.UpdateProductTypeRequest{Active: &inactive},
+			mockSetup:   func(_ *repositoryMocks.MockProductTypeRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name: "not found",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{Active: &inactive},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(nil, errors.New("product type not found"))
+			},
+			expectedErr: errors.New("product type not found"),
+		},
+		{
+			name: "empty name",
+			ctx:  moderatorCtx,
+			data: requests.UpdateProductTypeRequest{NameRu: &emptyName},
+			mockSetup: func(m *repositoryMocks.MockProductTypeRepository) {
+				m.On("GetProductType", mock.Anything, "обувь").Return(current(), nil)
+			},
+			expectedErr: errors.New("product type name is required"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockProductTypeRepository)
+			uc := NewProductTypeUsecase(mockRepo)
+			tt.mockSetup(mockRepo)
+
+			res, err := uc.UpdateProductType(tt.ctx(), "обувь", tt.data)
+			assert.Equal(t, tt.expectedRes, res)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProductTypeUsecase_DeleteProductType(t *testing.T) {
+	mockRepo := new(repositoryMocks.MockProductTypeRepository)
+	uc := NewProductTypeUsecase(mockRepo)
+
+	err := uc.DeleteProductType(employeeCtx(), "шины")
+	assert.EqualError(t, err, "this role is not allowed")
+
+	mockRepo.On("DeleteProductType", mock.Anything, "обувь").Return(errors.New("product type is in use")).Once()
+	err = uc.DeleteProductType(moderatorCtx(), "обувь")
+	assert.EqualError(t, err, "product type is in use")
+
+	mockRepo.On("DeleteProductType", mock.Anything, "шины").Return(nil).Once()
+	require.NoError(t, uc.DeleteProductType(moderatorCtx(), "шины"))
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductTypeUsecase_Catalog(t *testing.T) {
+	catalog := []models.ProductType{
+		{Code: "обувь", NameRu: "Обувь", Active: true},
+		{Code: "шины", NameRu: "Шины", Active: false},
+	}
+
+	t.Run("lookups are served from cache", func(t *testing.T) {
+		mockRepo := new(repositoryMocks.MockProductTypeRepository)
+		mockRepo.On("GetProductTypes", mock.Anything).Return(catalog, nil).Once()
+		uc := NewProductTypeUsecase(mockRepo)
+		ctx := context.Background()
+
+		active, err := uc.IsActiveProductType(ctx, "обувь")
+		require.NoError(t, err)
+		assert.True(t, active)
+
+		active, err = uc.IsActiveProductType(ctx, "шины")
+		require.NoError(t, err)
+		assert.False(t, active)
+
+		active, err = uc.IsActiveProductType(ctx, "мебель")
+		require.NoError(t, err)
+		assert.False(t, active)
+
+		exists, err := uc.ProductTypeExists(ctx, "шины")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		exists, err = uc.ProductTypeExists(ctx, "мебель")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("changes invalidate cache", func(t *testing.T) {
+		mockRepo := new(repositoryMocks.MockProductTypeRepository)
+		mockRepo.On("GetProductTypes", mock.Anything).Return(catalog, nil).Once()
+		mockRepo.On("CreateProductType", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("GetProductTypes", mock.Anything).
+			Return(append(catalog, models.ProductType{Code: "мебель", NameRu: "Мебель", Active: true}), nil).Once()
+		uc := NewProductTypeUsecase(mockRepo)
+
+		active, err := uc.IsActiveProductType(context.Background(), "мебель")
+		require.NoError(t, err)
+		assert.False(t, active)
+
+		_, err = uc.CreateProductType(moderatorCtx(), requests.CreateProductTypeRequest{Code: "мебель", NameRu: "Мебель"})
+		require.NoError(t, err)
+
+		active, err = uc.IsActiveProductType(context.Background(), "мебель")
+		require.NoError(t, err)
+		assert.True(t, active)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("expired cache is reloaded", func(t *testing.T) {
+		mockRepo := new(repositoryMocks.MockProductTypeRepository)
+		mockRepo.On("GetProductTypes", mock.Anything).Return(catalog, nil).Twice()
+		uc := NewProductTypeUsecase(mockRepo)
+		uc.cache.ttl = 0
+
+		_, err := uc.IsActiveProductType(context.Background(), "обувь")
+		require.NoError(t, err)
+		_, err = uc.IsActiveProductType(context.Background(), "обувь")
+		require.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := new(repositoryMocks.MockProductTypeRepository)
+		mockRepo.On("GetProductTypes", mock.Anything).Return(nil, errors.New("db error"))
+		uc := NewProductTypeUsecase(mockRepo)
+
+		active, err := uc.IsActiveProductType(context.Background(), "обувь")
+		assert.EqualError(t, err, "db error")
+		assert.False(t, active)
+	})
+}
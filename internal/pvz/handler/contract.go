@@ -4,11 +4,13 @@ import (
 	"avito_spring_staj_2025/domain/models"
 	"avito_spring_staj_2025/domain/requests"
 	"context"
-	"time"
 )
 
 type PvzUsecase interface {
 	CreatePvz(ctx context.Context, data *requests.CreatePvzRequest) error
-	GetPvzsInformation(ctx context.Context, fromDate, toDate time.Time, limit, page int) ([]models.Pvz, error)
+	ImportPvzs(ctx context.Context, data requests.ImportPvzsRequest) (models.PvzImportReport, error)
+	GetPvzsInformation(ctx context.Context, filter models.PvzFilter, limit, page int) ([]models.Pvz, error)
 	GetAllPvzs(ctx context.Context) ([]models.Pvz, error)
+	GetPvz(ctx context.Context, pvzId string) (models.Pvz, error)
+	SetPvzCapacity(ctx context.Context, pvzId string, data requests.SetPvzCapacityRequest) (models.Pvz, error)
 }
@@ -8,16 +8,21 @@ import (
 	"avito_spring_staj_2025/internal/service/logger"
 	"avito_spring_staj_2025/internal/service/middleware"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/microcosm-cc/bluemonday"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -46,6 +51,7 @@ func (h *PvzHandler) CreatePvz(w http.ResponseWriter, r *http.Request) {
 		Id:               sanitizer.Sanitize(data.Id),
 		RegistrationDate: data.RegistrationDate,
 		City:             sanitizer.Sanitize(data.City),
+		Address:          sanitizer.Sanitize(data.Address),
 	}
 
 	err := h.usecase.CreatePvz(ctx, &data)
@@ -63,6 +69,114 @@ func (h *PvzHandler) CreatePvz(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+const maxImportFileSize = 10 << 20
+
+func (h *PvzHandler) ImportPvzs(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		formFile, _, err := r.FormFile("file")
+		if err != nil {
+			h.handleError(w, errors.New("invalid csv"), requestID)
+			return
+		}
+		defer formFile.Close()
+		file = formFile
+	}
+
+	rows, err := parseImportCsv(file, sanitizer)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	data := requests.ImportPvzsRequest{
+		Rows:   rows,
+		DryRun: dryRun,
+		Mode:   sanitizer.Sanitize(r.URL.Query().Get("mode")),
+	}
+
+	report, err := h.usecase.ImportPvzs(ctx, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.ImportPvzsResponse{
+		DryRun:   report.DryRun,
+		Mode:     report.Mode,
+		Total:    report.Total,
+		Valid:    report.Valid,
+		Inserted: report.Inserted,
+		Errors:   make([]responses.ImportPvzRowError, 0, len(report.RowErrors)),
+	}
+	for _, rowError := range report.RowErrors {
+		response.Errors = append(response.Errors, responses.ImportPvzRowError{
+			Line:   rowError.Line,
+			Id:     rowError.Id,
+			Errors: rowError.Errors,
+		})
+	}
+
+	status := http.StatusOK
+	switch {
+	case report.Inserted > 0:
+		status = http.StatusCreated
+	case !report.DryRun && report.Mode == models.IMPORT_MODE_ALL_OR_NOTHING && len(report.RowErrors) > 0:
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+// parseImportCsv читает CSV с колонками id, registration date, city, address.
+// Заголовок необязателен; строки с неверным числом колонок не прерывают разбор, а попадают в отчёт.
+func parseImportCsv(file io.Reader, sanitizer *bluemonday.Policy) ([]requests.ImportPvzRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []requests.ImportPvzRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("invalid csv")
+		}
+		if line == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "id") {
+			continue
+		}
+
+		row := requests.ImportPvzRow{Line: line}
+		if len(record) != 4 {
+			row.ParseError = "invalid number of columns"
+			rows = append(rows, row)
+			continue
+		}
+		row.Id = sanitizer.Sanitize(strings.TrimSpace(record[0]))
+		row.RegistrationDate = sanitizer.Sanitize(strings.TrimSpace(record[1]))
+		row.City = sanitizer.Sanitize(record[2])
+		row.Address = sanitizer.Sanitize(record[3])
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
 func (h *PvzHandler) GetPvzsInformation(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	requestID := middleware.GetRequestID(ctx)
@@ -102,7 +216,30 @@ func (h *PvzHandler) GetPvzsInformation(w http.ResponseWriter, r *http.Request)
 		limit = 10
 	}
 
-	pvzs, err := h.usecase.GetPvzsInformation(ctx, startDate, endDate, limit, page)
+	filter := models.PvzFilter{
+		From:        startDate,
+		To:          endDate,
+		ProductType: sanitizer.Sanitize(queryParams.Get("productType")),
+		SortBy:      sanitizer.Sanitize(queryParams.Get("sort")),
+		SortOrder:   strings.ToLower(sanitizer.Sanitize(queryParams.Get("order"))),
+	}
+	for _, cities := range queryParams["city"] {
+		for _, city := range strings.Split(cities, ",") {
+			if city = strings.TrimSpace(sanitizer.Sanitize(city)); city != "" {
+				filter.Cities = append(filter.Cities, city)
+			}
+		}
+	}
+	if inProgressStr := sanitizer.Sanitize(queryParams.Get("inProgress")); inProgressStr != "" {
+		inProgress, err := strconv.ParseBool(inProgressStr)
+		if err != nil {
+			h.handleError(w, errors.New("invalid inProgress"), requestID)
+			return
+		}
+		filter.InProgress = &inProgress
+	}
+
+	pvzs, err := h.usecase.GetPvzsInformation(ctx, filter, limit, page)
 	if err != nil {
 		h.handleError(w, err, requestID)
 		return
@@ -113,6 +250,7 @@ func (h *PvzHandler) GetPvzsInformation(w http.ResponseWriter, r *http.Request)
 		for _, reception := range pvz.Receptions {
 			receptionsWithProducts = append(receptionsWithProducts, responses.GetReceptionWithProducts{
 				Reception: reception,
+				Summary:   responses.NewReceptionSummaryResponse(reception.Summary),
 				Products:  reception.Products,
 			})
 		}
@@ -122,7 +260,9 @@ func (h *PvzHandler) GetPvzsInformation(w http.ResponseWriter, r *http.Request)
 				Id:               pvz.Id,
 				RegistrationDate: pvz.RegistrationDate,
 				City:             pvz.City,
+				Address:          pvz.Address,
 			},
+			Capacity:   toCapacityResponse(pvz),
 			Receptions: receptionsWithProducts,
 		})
 	}
@@ -135,6 +275,75 @@ func (h *PvzHandler) GetPvzsInformation(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (h *PvzHandler) GetPvz(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	pvz, err := h.usecase.GetPvz(ctx, pvzId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toDetailResponse(pvz)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *PvzHandler) SetPvzCapacity(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.SetPvzCapacityRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.Policy = sanitizer.Sanitize(data.Policy)
+
+	pvz, err := h.usecase.SetPvzCapacity(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toDetailResponse(pvz)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func toCapacityResponse(pvz models.Pvz) responses.PvzCapacityResponse {
+	return responses.PvzCapacityResponse{
+		Capacity:  pvz.Capacity,
+		Policy:    pvz.CapacityPolicy,
+		Occupancy: pvz.Occupancy,
+	}
+}
+
+func toDetailResponse(pvz models.Pvz) responses.PvzDetailResponse {
+	return responses.PvzDetailResponse{
+		Id:               pvz.Id,
+		RegistrationDate: pvz.RegistrationDate,
+		City:             pvz.City,
+		Address:          pvz.Address,
+		Capacity:         toCapacityResponse(pvz),
+	}
+}
+
 func (h *PvzHandler) GetPvzListFromGrpc(w http.ResponseWriter, _ *http.Request) {
 	_ = godotenv.Load()
 	conn, err := grpc.NewClient(os.Getenv("GRPC_URL"), grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -190,7 +399,10 @@ func (h *PvzHandler) handleError(w http.ResponseWriter, err error, requestID str
 	switch err.Error() {
 	case "this city is not allowed", "active reception already exists",
 		"this type is not allowed", "pvz not found",
-		"no active reception", "invalid startDate", "invalid endDate", "no products in reception":
+		"no active reception", "invalid startDate", "invalid endDate", "no products in reception",
+		"invalid capacity", "invalid capacity policy", "invalid csv", "invalid import mode",
+		"empty import file", "too many rows in import file", "invalid inProgress",
+		"invalid date range", "invalid sort field", "invalid sort order":
 		w.WriteHeader(http.StatusBadRequest)
 	case "this role is not allowed":
 		w.WriteHeader(http.StatusForbidden)
@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -23,7 +24,7 @@ func TestPvzHandler_CreatePvz(t *testing.T) {
 	logger.AccessLogger = zap.NewNop()
 	type mockBehavior func(usecase *usecaseMocks.MockPvzUsecase, req requests.CreatePvzRequest)
 
-	testTime := time.Date(2025, 4, 11, 23, 58, 7, 0, time.Local)
+	testTime := time.Date(2025, 4, 11, 23, 58, 7, 0, time.UTC)
 
 	tests := []struct {
 		name           string
@@ -99,11 +100,12 @@ func TestPvzHandler_CreatePvz(t *testing.T) {
 
 func TestPvzHandler_GetPvzsInformation(t *testing.T) {
 	logger.AccessLogger = zap.NewNop()
-	type mockBehavior func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, startDate, endDate time.Time, limit, page int)
+	type mockBehavior func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, filter models.PvzFilter, limit, page int)
 
 	testTime := time.Date(2025, 4, 11, 23, 59, 0, 0, time.Local)
 	startDate := time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC)
 	endDate := time.Date(2025, 4, 12, 0, 0, 0, 0, time.UTC)
+	inProgress := true
 
 	tests := []struct {
 		name           string
@@ -115,8 +117,8 @@ func TestPvzHandler_GetPvzsInformation(t *testing.T) {
 		{
 			name:  "success",
 			query: "?startDate=2025-04-10T00:00:00Z&endDate=2025-04-12T00:00:00Z&page=0&limit=0",
-			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, startDate, endDate time.Time, limit, page int) {
-				usecase.On("GetPvzsInformation", ctx, startDate, endDate, limit, page).
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, filter models.PvzFilter, limit, page int) {
+				usecase.On("GetPvzsInformation", ctx, models.PvzFilter{From: startDate, To: endDate}, limit, page).
 					Return([]models.Pvz{
 						{
 							Id:               "123",
@@ -127,18 +129,86 @@ func TestPvzHandler_GetPvzsInformation(t *testing.T) {
 					}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   fmt.Sprintf(`[{"pvz":{"Id":"123","RegistrationDate":"%s","City":"Moscow"},"receptions":[]}]`, testTime.Format(time.RFC3339)),
+			expectedBody:   fmt.Sprintf(`[{"pvz":{"Id":"123","RegistrationDate":"%s","City":"Moscow"},"capacity":{"capacity":null,"policy":"","occupancy":0},"receptions":[]}]`, testTime.Format(time.RFC3339)),
+		},
+		{
+			name:  "closed reception with summary",
+			query: "?page=1&limit=10",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, filter models.PvzFilter, limit, page int) {
+				usecase.On("GetPvzsInformation", ctx, models.PvzFilter{}, limit, page).
+					Return([]models.Pvz{
+						{
+							Id:               "123",
+							RegistrationDate: testTime,
+							City:             "Moscow",
+							Receptions: []models.Reception{
+								{
+									Id:       "rec1",
+									DateTime: testTime,
+									PvzId:    "123",
+									Status:   models.STATUS_CLOSED,
+									Summary: &models.ReceptionSummary{
+										ReceptionId:    "rec1",
+										ClosedAt:       testTime.Add(time.Hour),
+										TotalProducts:  1,
+										ProductsByType: map[string]int{models.BOOTS_TYPE: 1},
+										OpenDuration:   time.Hour,
+									},
+									Products: []models.Product{},
+								},
+							},
+						},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: fmt.Sprintf(`[{"pvz":{"Id":"123","RegistrationDate":"%[1]s","City":"Moscow"},"capacity":{"capacity":null,"policy":"","occupancy":0},`+
+				`"receptions":[{"reception":{"Id":"rec1","DateTime":"%[1]s","PvzId":"123","Status":"close"},`+
+				`"summary":{"closedAt":"%[2]s","totalProducts":1,"productsByType":{"обувь":1},"openDurationSeconds":3600,"deletions":0},"products":[]}]}]`,
+				testTime.Format(time.RFC3339), testTime.Add(time.Hour).Format(time.RFC3339)),
 		},
 		{
 			name:  "usecase error",
 			query: "?startDate=2025-04-10T00:00:00Z&endDate=2025-04-12T00:00:00Z&page=1&limit=10",
-			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, startDate, endDate time.Time, limit, page int) {
-				usecase.On("GetPvzsInformation", ctx, startDate, endDate, limit, page).
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, filter models.PvzFilter, limit, page int) {
+				usecase.On("GetPvzsInformation", ctx, models.PvzFilter{From: startDate, To: endDate}, limit, page).
 					Return([]models.Pvz{}, errors.New("get error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   `{"errors":"get error"}`,
 		},
+		{
+			name:  "filters and sorting",
+			query: "?city=Москва,Казань&city=Санкт-Петербург&inProgress=true&productType=обувь&sort=lastReceptionAt&order=DESC",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, filter models.PvzFilter, limit, page int) {
+				usecase.On("GetPvzsInformation", ctx, models.PvzFilter{
+					Cities:      []string{"Москва", "Казань", "Санкт-Петербург"},
+					InProgress:  &inProgress,
+					ProductType: "обувь",
+					SortBy:      models.PVZ_SORT_LAST_RECEPTION_AT,
+					SortOrder:   models.SORT_ORDER_DESC,
+				}, limit, page).Return([]models.Pvz{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		{
+			name:  "invalid inProgress",
+			query: "?inProgress=maybe",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, filter models.PvzFilter, limit, page int) {
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid inProgress"}`,
+		},
+		{
+			name:  "invalid sort field",
+			query: "?sort=id",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase, ctx context.Context, filter models.PvzFilter, limit, page int) {
+				usecase.On("GetPvzsInformation", ctx, models.PvzFilter{SortBy: "id"}, limit, page).
+					Return([]models.Pvz{}, errors.New("invalid sort field"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid sort field"}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -149,7 +219,7 @@ func TestPvzHandler_GetPvzsInformation(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/api/pvz"+tt.query, nil)
 			w := httptest.NewRecorder()
 
-			tt.mockBehavior(mockUsecase, req.Context(), startDate, endDate, 10, 1)
+			tt.mockBehavior(mockUsecase, req.Context(), models.PvzFilter{}, 10, 1)
 
 			handler.GetPvzsInformation(w, req)
 
@@ -171,3 +241,209 @@ func TestPvzHandler_GetPvzsInformation(t *testing.T) {
 		})
 	}
 }
+
+func TestPvzHandler_GetPvz(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	testTime := time.Date(2025, 4, 11, 23, 59, 0, 0, time.UTC)
+	capacity := 50
+
+	tests := []struct {
+		name           string
+		mockBehavior   func(*usecaseMocks.MockPvzUsecase)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("GetPvz", mock.Anything, "pvz1").Return(models.Pvz{
+					Id:               "pvz1",
+					RegistrationDate: testTime,
+					City:             "Москва",
+					Capacity:         &capacity,
+					CapacityPolicy:   models.CAPACITY_POLICY_REJECT,
+					Occupancy:        12,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: fmt.Sprintf(`{"id":"pvz1","registrationDate":"%s","city":"Москва",
+				"capacity":{"capacity":50,"policy":"reject","occupancy":12}}`, testTime.Format(time.RFC3339)),
+		},
+		{
+			name: "not found",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("GetPvz", mock.Anything, "pvz1").Return(models.Pvz{}, errors.New("pvz not found"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"pvz not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.MockPvzUsecase)
+			handler := NewPvzHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/pvz/pvz1", nil)
+			req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz1"})
+			w := httptest.NewRecorder()
+
+			handler.GetPvz(w, req)
+
+			body, _ := io.ReadAll(w.Result().Body)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzHandler_SetPvzCapacity(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	capacity := 10
+
+	tests := []struct {
+		name           string
+		inputBody      string
+		mockBehavior   func(*usecaseMocks.MockPvzUsecase)
+		expectedStatus int
+	}{
+		{
+			name:      "success",
+			inputBody: `{"capacity":10,"policy":"warn"}`,
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("SetPvzCapacity", mock.Anything, "pvz1", requests.SetPvzCapacityRequest{Capacity: &capacity, Policy: "warn"}).
+					Return(models.Pvz{Id: "pvz1", Capacity: &capacity}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "invalid capacity",
+			inputBody: `{"capacity":-1}`,
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("SetPvzCapacity", mock.Anything, "pvz1", mock.Anything).
+					Return(models.Pvz{}, errors.New("invalid capacity"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "forbidden",
+			inputBody: `{"capacity":10}`,
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("SetPvzCapacity", mock.Anything, "pvz1", mock.Anything).
+					Return(models.Pvz{}, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.MockPvzUsecase)
+			handler := NewPvzHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/pvz/pvz1/capacity", strings.NewReader(tt.inputBody))
+			req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz1"})
+			w := httptest.NewRecorder()
+
+			handler.SetPvzCapacity(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzHandler_ImportPvzs(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	tests := []struct {
+		name           string
+		query          string
+		inputBody      string
+		mockBehavior   func(*usecaseMocks.MockPvzUsecase)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "success",
+			query: "?mode=partial",
+			inputBody: "id,registrationDate,city,address\n" +
+				"11111111-1111-1111-1111-111111111111,2025-04-01,Москва,\"Тверская, 1\"\n" +
+				"bad,row\n",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("ImportPvzs", mock.Anything, requests.ImportPvzsRequest{
+					Mode: "partial",
+					Rows: []requests.ImportPvzRow{
+						{Line: 2, Id: "11111111-1111-1111-1111-111111111111", RegistrationDate: "2025-04-01", City: "Москва", Address: "Тверская, 1"},
+						{Line: 3, ParseError: "invalid number of columns"},
+					},
+				}).Return(models.PvzImportReport{
+					Mode:      "partial",
+					Total:     2,
+					Valid:     1,
+					Inserted:  1,
+					RowErrors: []models.PvzImportRowError{{Line: 3, Errors: []string{"invalid number of columns"}}},
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"dryRun":false,"mode":"partial","total":2,"valid":1,"inserted":1,
+				"errors":[{"line":3,"id":"","errors":["invalid number of columns"]}]}`,
+		},
+		{
+			name:      "all or nothing rejected",
+			query:     "?mode=all_or_nothing",
+			inputBody: "x,2025-04-01,Омск,\n",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("ImportPvzs", mock.Anything, mock.Anything).Return(models.PvzImportReport{
+					Mode:      models.IMPORT_MODE_ALL_OR_NOTHING,
+					Total:     1,
+					RowErrors: []models.PvzImportRowError{{Line: 1, Id: "x", Errors: []string{"invalid id"}}},
+				}, nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `{"dryRun":false,"mode":"all_or_nothing","total":1,"valid":0,"inserted":0,
+				"errors":[{"line":1,"id":"x","errors":["invalid id"]}]}`,
+		},
+		{
+			name:      "dry run",
+			query:     "?dryRun=true",
+			inputBody: "11111111-1111-1111-1111-111111111111,2025-04-01,Москва,\n",
+			mockBehavior: func(usecase *usecaseMocks.MockPvzUsecase) {
+				usecase.On("ImportPvzs", mock.Anything, mock.MatchedBy(func(data requests.ImportPvzsRequest) bool {
+					return data.DryRun && len(data.Rows) == 1
+				})).Return(models.PvzImportReport{DryRun: true, Mode: "partial", Total: 1, Valid: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"dryRun":true,"mode":"partial","total":1,"valid":1,"inserted":0,"errors":[]}`,
+		},
+		{
+			name:           "malformed csv",
+			inputBody:      "\"unterminated,2025-04-01,Москва,\n",
+			mockBehavior:   func(_ *usecaseMocks.MockPvzUsecase) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid csv"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.MockPvzUsecase)
+			handler := NewPvzHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/pvz/import"+tt.query, strings.NewReader(tt.inputBody))
+			req.Header.Set("Content-Type", "text/csv")
+			w := httptest.NewRecorder()
+
+			handler.ImportPvzs(w, req)
+
+			body, _ := io.ReadAll(w.Result().Body)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"avito_spring_staj_2025/domain/requests"
 	"avito_spring_staj_2025/internal/service/logger"
 	"avito_spring_staj_2025/internal/service/middleware"
+	"avito_spring_staj_2025/internal/service/outbox"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"go.uber.org/zap"
@@ -27,8 +30,8 @@ func (r PvzRepository) CreatePvz(ctx context.Context, data *requests.CreatePvzRe
 	requestID := middleware.GetRequestID(ctx)
 	logger.DBLogger.Info("CreatePvz called", zap.String("request_id", requestID), zap.String("pvz_id", data.Id))
 	queryBuilder := sq.Insert("pvzs").
-		Columns("id", "registration_date", "city").
-		Values(data.Id, data.RegistrationDate, data.City).
+		Columns("id", "registration_date", "city", "address").
+		Values(data.Id, data.RegistrationDate, data.City, data.Address).
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := queryBuilder.ToSql()
@@ -37,11 +40,28 @@ func (r PvzRepository) CreatePvz(ctx context.Context, data *requests.CreatePvzRe
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, query, args...)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.DBLogger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		logger.DBLogger.Error("failed to insert pvz", zap.Error(err))
 		return err
 	}
+	if err = appendPvzCreatedEvents(ctx, tx, []requests.CreatePvzRequest{*data}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.DBLogger.Error("failed to commit transaction", zap.Error(err))
+		return err
+	}
 
 	logger.DBLogger.Info("Pvz successfully created",
 		zap.String("request_id", requestID),
@@ -51,29 +71,236 @@ func (r PvzRepository) CreatePvz(ctx context.Context, data *requests.CreatePvzRe
 	return nil
 }
 
-func (r PvzRepository) GetPvzsFilteredByReceptionDate(ctx context.Context, from, to time.Time, limit, offset int) ([]models.Pvz, error) {
+const importBatchSize = 1000
+
+// CreatePvzs вставляет ПВЗ пачками и возвращает id тех, что уже были в базе: такой ПВЗ мог появиться
+// после проверки импорта, поэтому конфликт по id не ошибка, а пропуск строки. В режиме allOrNothing
+// при любом пропуске транзакция откатывается и не вставляется ничего.
+func (r PvzRepository) CreatePvzs(ctx context.Context, pvzs []requests.CreatePvzRequest, allOrNothing bool) ([]string, error) {
 	requestID := middleware.GetRequestID(ctx)
-	logger.DBLogger.Info("GetPvzsFilteredByReceptionDate called",
+	logger.DBLogger.Info("CreatePvzs called", zap.String("request_id", requestID), zap.Int("count", len(pvzs)))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.DBLogger.Error("failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// События пишутся по пачкам, поэтому все ПВЗ импорта блокируются заранее и в едином порядке.
+	pvzIds := make([]string, 0, len(pvzs))
+	for _, pvz := range pvzs {
+		pvzIds = append(pvzIds, pvz.Id)
+	}
+	if err = outbox.LockPvzs(ctx, tx, pvzIds...); err != nil {
+		return nil, err
+	}
+
+	skippedIds := make([]string, 0)
+	for start := 0; start < len(pvzs); start += importBatchSize {
+		end := min(start+importBatchSize, len(pvzs))
+		queryBuilder := sq.Insert("pvzs").
+			Columns("id", "registration_date", "city", "address").
+			Suffix("ON CONFLICT (id) DO NOTHING RETURNING id").
+			PlaceholderFormat(sq.Dollar)
+		for _, pvz := range pvzs[start:end] {
+			queryBuilder = queryBuilder.Values(pvz.Id, pvz.RegistrationDate, pvz.City, pvz.Address)
+		}
+
+		query, args, err := queryBuilder.ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return nil, err
+		}
+		insertedIds, err := queryIds(ctx, tx, query, args)
+		if err != nil {
+			logger.DBLogger.Error("failed to insert pvzs", zap.Error(err))
+			return nil, err
+		}
+
+		inserted := make([]requests.CreatePvzRequest, 0, len(insertedIds))
+		for _, pvz := range pvzs[start:end] {
+			if insertedIds[pvz.Id] {
+				inserted = append(inserted, pvz)
+			} else {
+				skippedIds = append(skippedIds, pvz.Id)
+			}
+		}
+		if len(inserted) == 0 {
+			continue
+		}
+		if err = appendPvzCreatedEvents(ctx, tx, inserted); err != nil {
+			return nil, err
+		}
+	}
+
+	if allOrNothing && len(skippedIds) > 0 {
+		logger.DBLogger.Info("Pvzs import rolled back because of existing pvzs",
+			zap.String("request_id", requestID),
+			zap.Strings("skipped_ids", skippedIds),
+		)
+		return skippedIds, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.DBLogger.Error("failed to commit transaction", zap.Error(err))
+		return nil, err
+	}
+
+	logger.DBLogger.Info("Pvzs successfully imported",
+		zap.String("request_id", requestID),
+		zap.Int("count", len(pvzs)-len(skippedIds)),
+		zap.Strings("skipped_ids", skippedIds),
+	)
+
+	return skippedIds, nil
+}
+
+func queryIds(ctx context.Context, tx *sql.Tx, query string, args []interface{}) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+func appendPvzCreatedEvents(ctx context.Context, tx *sql.Tx, pvzs []requests.CreatePvzRequest) error {
+	events := make([]models.DomainEvent, 0, len(pvzs))
+	for _, pvz := range pvzs {
+		event, err := outbox.NewEvent(models.EVENT_PVZ_CREATED, pvz.Id, models.PvzCreatedPayload{
+			PvzId:            pvz.Id,
+			City:             pvz.City,
+			Address:          pvz.Address,
+			RegistrationDate: pvz.RegistrationDate,
+		}, pvz.RegistrationDate)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	return outbox.Append(ctx, tx, events...)
+}
+
+func (r PvzRepository) GetExistingPvzIds(ctx context.Context, ids []string) ([]string, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetExistingPvzIds called", zap.String("request_id", requestID), zap.Int("count", len(ids)))
+
+	query, args, err := sq.Select("id").
+		From("pvzs").
+		Where(sq.Eq{"id": ids}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query existing pvzs", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var existing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing = append(existing, id)
+	}
+
+	return existing, rows.Err()
+}
+
+var pvzSortColumns = map[string]string{
+	models.PVZ_SORT_REGISTRATION_DATE: "registration_date",
+	models.PVZ_SORT_CITY:              "city",
+	models.PVZ_SORT_LAST_RECEPTION_AT: "(SELECT MAX(r.date_time) FROM receptions r WHERE r.pvz_id = pvzs.id)",
+}
+
+func (r PvzRepository) GetPvzsFiltered(ctx context.Context, filter models.PvzFilter, limit, offset int) ([]models.Pvz, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetPvzsFiltered called",
 		zap.String("request_id", requestID),
 	)
 	queryBuilder := sq.
-		Select("id", "registration_date", "city").
+		Select("id", "registration_date", "city", "address", "capacity", "capacity_policy", "occupancy").
 		From("pvzs").
 		PlaceholderFormat(sq.Dollar)
 
 	whereClauses := sq.And{}
 
-	if !from.IsZero() {
-		whereClauses = append(whereClauses, sq.GtOrEq{"registration_date": from})
+	if !filter.From.IsZero() {
+		whereClauses = append(whereClauses, sq.GtOrEq{"registration_date": filter.From})
 	}
-	if !to.IsZero() {
-		whereClauses = append(whereClauses, sq.LtOrEq{"registration_date": to})
+	if !filter.To.IsZero() {
+		whereClauses = append(whereClauses, sq.LtOrEq{"registration_date": filter.To})
+	}
+	if len(filter.Cities) > 0 {
+		whereClauses = append(whereClauses, sq.Eq{"city": filter.Cities})
+	}
+	if filter.InProgress != nil {
+		inProgress := "EXISTS (SELECT 1 FROM receptions r WHERE r.pvz_id = pvzs.id AND r.status = ?)"
+		if !*filter.InProgress {
+			inProgress = "NOT " + inProgress
+		}
+		whereClauses = append(whereClauses, sq.Expr(inProgress, models.STATUS_ACTIVE))
+	}
+	if filter.ProductType != "" {
+		productQuery := sq.Select("1").
+			From("products pr").
+			Join("receptions r ON r.id = pr.reception_id").
+			Where("r.pvz_id = pvzs.id").
+			Where(sq.Eq{"pr.type": filter.ProductType})
+		if !filter.From.IsZero() {
+			productQuery = productQuery.Where(sq.GtOrEq{"pr.date_time": filter.From})
+		}
+		if !filter.To.IsZero() {
+			productQuery = productQuery.Where(sq.LtOrEq{"pr.date_time": filter.To})
+		}
+		productSql, productArgs, err := productQuery.ToSql()
+		if err != nil {
+			return nil, err
+		}
+		whereClauses = append(whereClauses, sq.Expr("EXISTS ("+productSql+")", productArgs...))
 	}
 
 	if len(whereClauses) > 0 {
 		queryBuilder = queryBuilder.Where(whereClauses)
 	}
 
+	if filter.SortBy != "" {
+		column, ok := pvzSortColumns[filter.SortBy]
+		if !ok {
+			return nil, errors.New("invalid sort field")
+		}
+		direction := "ASC"
+		if filter.SortOrder == models.SORT_ORDER_DESC {
+			direction = "DESC"
+		}
+		queryBuilder = queryBuilder.OrderBy(column+" "+direction+" NULLS LAST", "id")
+	}
+
 	queryBuilder = queryBuilder.
 		Limit(uint64(limit)).
 		Offset(uint64(offset))
@@ -94,8 +321,8 @@ func (r PvzRepository) GetPvzsFilteredByReceptionDate(ctx context.Context, from,
 
 	var pvzs []models.Pvz
 	for rows.Next() {
-		var p models.Pvz
-		if err := rows.Scan(&p.Id, &p.RegistrationDate, &p.City); err != nil {
+		p, err := scanPvz(rows)
+		if err != nil {
 			return nil, err
 		}
 		pvzs = append(pvzs, p)
@@ -104,6 +331,82 @@ func (r PvzRepository) GetPvzsFilteredByReceptionDate(ctx context.Context, from,
 	return pvzs, nil
 }
 
+func (r PvzRepository) GetPvzById(ctx context.Context, pvzId string) (*models.Pvz, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetPvzById called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := sq.Select("id", "registration_date", "city", "address", "capacity", "capacity_policy", "occupancy").
+		From("pvzs").
+		Where(sq.Eq{"id": pvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	pvz, err := scanPvz(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("pvz not found")
+		}
+		logger.DBLogger.Error("failed to scan pvz", zap.Error(err))
+		return nil, err
+	}
+
+	return &pvz, nil
+}
+
+func (r PvzRepository) SetPvzCapacity(ctx context.Context, pvzId string, capacity *int, policy string) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("SetPvzCapacity called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := sq.Update("pvzs").
+		Set("capacity", capacity).
+		Set("capacity_policy", policy).
+		Where(sq.Eq{"id": pvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to update pvz capacity", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("pvz not found")
+	}
+
+	logger.DBLogger.Info("Pvz capacity successfully updated",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+	)
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPvz(row rowScanner) (models.Pvz, error) {
+	var p models.Pvz
+	var capacity sql.NullInt64
+	if err := row.Scan(&p.Id, &p.RegistrationDate, &p.City, &p.Address, &capacity, &p.CapacityPolicy, &p.Occupancy); err != nil {
+		return models.Pvz{}, err
+	}
+	if capacity.Valid {
+		value := int(capacity.Int64)
+		p.Capacity = &value
+	}
+	return p, nil
+}
+
+// GetPvzReceptions возвращает приёмки ПВЗ вместе с сохранёнными при закрытии итогами.
 func (r PvzRepository) GetPvzReceptions(ctx context.Context, pvzId string) ([]models.Reception, error) {
 	requestID := middleware.GetRequestID(ctx)
 	logger.DBLogger.Info("GetPvzReceptions called",
@@ -111,9 +414,11 @@ func (r PvzRepository) GetPvzReceptions(ctx context.Context, pvzId string) ([]mo
 		zap.String("pvz_id", pvzId),
 	)
 
-	queryBuilder := sq.Select("id", "date_time", "pvz_id", "status").
-		From("receptions").
-		Where(sq.Eq{"pvz_id": pvzId}).
+	queryBuilder := sq.Select("r.id", "r.date_time", "r.pvz_id", "r.status",
+		"s.closed_at", "s.total_products", "s.products_by_type", "s.open_duration_seconds", "s.deletions_count").
+		From("receptions r").
+		LeftJoin("reception_summaries s ON s.reception_id = r.id").
+		Where(sq.Eq{"r.pvz_id": pvzId}).
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := queryBuilder.ToSql()
@@ -134,9 +439,30 @@ func (r PvzRepository) GetPvzReceptions(ctx context.Context, pvzId string) ([]mo
 	var receptions []models.Reception
 	for rows.Next() {
 		var r models.Reception
-		if err := rows.Scan(&r.Id, &r.DateTime, &r.PvzId, &r.Status); err != nil {
+		var closedAt sql.NullTime
+		var totalProducts, openDurationSeconds, deletionsCount sql.NullInt64
+		var productsByType []byte
+		if err := rows.Scan(&r.Id, &r.DateTime, &r.PvzId, &r.Status,
+			&closedAt, &totalProducts, &productsByType, &openDurationSeconds, &deletionsCount); err != nil {
 			return nil, err
 		}
+		if closedAt.Valid {
+			summary := &models.ReceptionSummary{
+				ReceptionId:    r.Id,
+				ClosedAt:       closedAt.Time,
+				TotalProducts:  int(totalProducts.Int64),
+				ProductsByType: map[string]int{},
+				OpenDuration:   time.Duration(openDurationSeconds.Int64) * time.Second,
+				DeletionsCount: int(deletionsCount.Int64),
+			}
+			if len(productsByType) > 0 {
+				if err := json.Unmarshal(productsByType, &summary.ProductsByType); err != nil {
+					logger.DBLogger.Error("failed to decode products by type", zap.Error(err))
+					return nil, err
+				}
+			}
+			r.Summary = summary
+		}
 		receptions = append(receptions, r)
 	}
 
@@ -150,7 +476,7 @@ func (r PvzRepository) GetReceptionProducts(ctx context.Context, receptionId str
 		zap.String("reception_id", receptionId),
 	)
 
-	queryBuilder := sq.Select("id", "date_time", "type", "reception_id").
+	queryBuilder := sq.Select("id", "date_time", "type", "reception_id", "external_id").
 		From("products").
 		Where(sq.Eq{"reception_id": receptionId}).
 		PlaceholderFormat(sq.Dollar)
@@ -174,9 +500,11 @@ func (r PvzRepository) GetReceptionProducts(ctx context.Context, receptionId str
 	var products []models.Product
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.Id, &p.DateTime, &p.Type, &p.ReceptionId); err != nil {
+		var externalId sql.NullString
+		if err := rows.Scan(&p.Id, &p.DateTime, &p.Type, &p.ReceptionId, &externalId); err != nil {
 			return nil, err
 		}
+		p.ExternalId = externalId.String
 		products = append(products, p)
 	}
 
@@ -6,6 +6,7 @@ import (
 	"avito_spring_staj_2025/internal/service/logger"
 	"avito_spring_staj_2025/internal/service/middleware"
 	"context"
+	"database/sql"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -33,9 +34,17 @@ func TestPvzRepository_CreatePvz(t *testing.T) {
 				City:             "Moscow",
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT INTO pvzs \(id,registration_date,city\) VALUES \(\$1,\$2,\$3\)`).
-					WithArgs("pvz123", sqlmock.AnyArg(), "Moscow").
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO pvzs \(id,registration_date,city,address\) VALUES \(\$1,\$2,\$3,\$4\)`).
+					WithArgs("pvz123", sqlmock.AnyArg(), "Moscow", "").
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`^SELECT pg_advisory_xact_lock`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`^INSERT INTO outbox_events \(id,event_type,pvz_id,payload,occurred_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\)$`).
+					WithArgs(sqlmock.AnyArg(), models.EVENT_PVZ_CREATED, "pvz123", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 			},
 			expectedErr: "",
 		},
@@ -47,9 +56,11 @@ func TestPvzRepository_CreatePvz(t *testing.T) {
 				City:             "Spb",
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT INTO pvzs \(id,registration_date,city\) VALUES \(\$1,\$2,\$3\)`).
-					WithArgs("pvz456", sqlmock.AnyArg(), "Spb").
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO pvzs \(id,registration_date,city,address\) VALUES \(\$1,\$2,\$3,\$4\)`).
+					WithArgs("pvz456", sqlmock.AnyArg(), "Spb", "").
 					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
 			},
 			expectedErr: "insert failed",
 		},
@@ -78,34 +89,35 @@ func TestPvzRepository_CreatePvz(t *testing.T) {
 	}
 }
 
-func TestPvzRepository_GetPvzsFilteredByReceptionDate(t *testing.T) {
+func TestPvzRepository_GetPvzsFiltered(t *testing.T) {
 	logger.DBLogger = zap.NewNop()
 	ctx := context.Background()
 
 	now := time.Now()
 	from := now.Add(-24 * time.Hour)
 	to := now
+	inProgress := true
+	notInProgress := false
 
 	tests := []struct {
-		name     string
-		from     time.Time
-		to       time.Time
-		limit    int
-		offset   int
-		mock     func(sqlmock.Sqlmock)
-		expected []models.Pvz
+		name        string
+		filter      models.PvzFilter
+		limit       int
+		offset      int
+		mock        func(sqlmock.Sqlmock)
+		expected    []models.Pvz
+		expectedErr string
 	}{
 		{
 			name:   "With Date Range",
-			from:   from,
-			to:     to,
+			filter: models.PvzFilter{From: from, To: to},
 			limit:  10,
 			offset: 0,
 			mock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "registration_date", "city"}).
-					AddRow("pvz1", now, "Moscow").
-					AddRow("pvz2", now, "SPb")
-				mock.ExpectQuery(`SELECT id, registration_date, city FROM pvzs WHERE \(registration_date >= \$1 AND registration_date <= \$2\) LIMIT 10 OFFSET 0`).
+				rows := sqlmock.NewRows([]string{"id", "registration_date", "city", "address", "capacity", "capacity_policy", "occupancy"}).
+					AddRow("pvz1", now, "Moscow", "", 100, "reject", 10).
+					AddRow("pvz2", now, "SPb", "", nil, "reject", 0)
+				mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, capacity_policy, occupancy FROM pvzs WHERE \(registration_date >= \$1 AND registration_date <= \$2\) LIMIT 10 OFFSET 0`).
 					WithArgs(from, to).
 					WillReturnRows(rows)
 			},
@@ -116,17 +128,65 @@ func TestPvzRepository_GetPvzsFilteredByReceptionDate(t *testing.T) {
 		},
 		{
 			name:   "Without Date Range",
-			from:   time.Time{},
-			to:     time.Time{},
 			limit:  5,
 			offset: 0,
 			mock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "registration_date", "city"})
-				mock.ExpectQuery(`SELECT id, registration_date, city FROM pvzs LIMIT 5 OFFSET 0`).
+				rows := sqlmock.NewRows([]string{"id", "registration_date", "city", "address", "capacity", "capacity_policy", "occupancy"})
+				mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, capacity_policy, occupancy FROM pvzs LIMIT 5 OFFSET 0`).
 					WillReturnRows(rows)
 			},
 			expected: []models.Pvz{},
 		},
+		{
+			name: "Cities, In Progress And Sort",
+			filter: models.PvzFilter{
+				Cities:     []string{"Москва", "Казань"},
+				InProgress: &inProgress,
+				SortBy:     models.PVZ_SORT_LAST_RECEPTION_AT,
+				SortOrder:  models.SORT_ORDER_DESC,
+			},
+			limit:  10,
+			offset: 10,
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "registration_date", "city", "address", "capacity", "capacity_policy", "occupancy"}).
+					AddRow("pvz1", now, "Москва", "", nil, "reject", 0)
+				mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, capacity_policy, occupancy FROM pvzs `+
+					`WHERE \(city IN \(\$1,\$2\) AND EXISTS \(SELECT 1 FROM receptions r WHERE r.pvz_id = pvzs.id AND r.status = \$3\)\) `+
+					`ORDER BY \(SELECT MAX\(r.date_time\) FROM receptions r WHERE r.pvz_id = pvzs.id\) DESC NULLS LAST, id LIMIT 10 OFFSET 10`).
+					WithArgs("Москва", "Казань", models.STATUS_ACTIVE).
+					WillReturnRows(rows)
+			},
+			expected: []models.Pvz{{Id: "pvz1", City: "Москва"}},
+		},
+		{
+			name: "Product Type In Window Without Active Reception",
+			filter: models.PvzFilter{
+				From:        from,
+				InProgress:  &notInProgress,
+				ProductType: models.BOOTS_TYPE,
+				SortBy:      models.PVZ_SORT_CITY,
+				SortOrder:   models.SORT_ORDER_ASC,
+			},
+			limit:  10,
+			offset: 0,
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "registration_date", "city", "address", "capacity", "capacity_policy", "occupancy"})
+				mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, capacity_policy, occupancy FROM pvzs `+
+					`WHERE \(registration_date >= \$1 AND NOT EXISTS \(SELECT 1 FROM receptions r WHERE r.pvz_id = pvzs.id AND r.status = \$2\) `+
+					`AND EXISTS \(SELECT 1 FROM products pr JOIN receptions r ON r.id = pr.reception_id WHERE r.pvz_id = pvzs.id AND pr.type = \$3 AND pr.date_time >= \$4\)\) `+
+					`ORDER BY city ASC NULLS LAST, id LIMIT 10 OFFSET 0`).
+					WithArgs(from, models.STATUS_ACTIVE, models.BOOTS_TYPE, from).
+					WillReturnRows(rows)
+			},
+			expected: []models.Pvz{},
+		},
+		{
+			name:        "Unknown Sort Field",
+			filter:      models.PvzFilter{SortBy: "id; DROP TABLE pvzs"},
+			limit:       10,
+			mock:        func(mock sqlmock.Sqlmock) {},
+			expectedErr: "invalid sort field",
+		},
 	}
 
 	for _, tt := range tests {
@@ -138,7 +198,11 @@ func TestPvzRepository_GetPvzsFilteredByReceptionDate(t *testing.T) {
 			repo := NewPvzRepository(db)
 			tt.mock(mock)
 
-			result, err := repo.GetPvzsFilteredByReceptionDate(ctx, tt.from, tt.to, tt.limit, tt.offset)
+			result, err := repo.GetPvzsFiltered(ctx, tt.filter, tt.limit, tt.offset)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
 			require.NoError(t, err)
 
 			assert.Equal(t, len(tt.expected), len(result))
@@ -155,6 +219,12 @@ func TestPvzRepository_GetPvzsFilteredByReceptionDate(t *testing.T) {
 func TestPvzRepository_GetPvzReceptions(t *testing.T) {
 	logger.DBLogger = zap.NewNop()
 	ctx := context.Background()
+	query := `^SELECT r.id, r.date_time, r.pvz_id, r.status, s.closed_at, s.total_products, s.products_by_type, ` +
+		`s.open_duration_seconds, s.deletions_count FROM receptions r ` +
+		`LEFT JOIN reception_summaries s ON s.reception_id = r.id WHERE r.pvz_id = \$1$`
+	columns := []string{"id", "date_time", "pvz_id", "status",
+		"closed_at", "total_products", "products_by_type", "open_duration_seconds", "deletions_count"}
+	closedAt := time.Now()
 
 	tests := []struct {
 		name        string
@@ -167,16 +237,23 @@ func TestPvzRepository_GetPvzReceptions(t *testing.T) {
 			name:  "Success",
 			pvzId: "pvz1",
 			mock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
-					AddRow("rec1", time.Now(), "pvz1", "ACTIVE").
-					AddRow("rec2", time.Now(), "pvz1", "CLOSED")
-				mock.ExpectQuery(`SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = \$1`).
+				rows := sqlmock.NewRows(columns).
+					AddRow("rec1", time.Now(), "pvz1", "ACTIVE", nil, nil, nil, nil, nil).
+					AddRow("rec2", time.Now(), "pvz1", "CLOSED", closedAt, 3, []byte(`{"обувь":2,"одежда":1}`), 5400, 1)
+				mock.ExpectQuery(query).
 					WithArgs("pvz1").
 					WillReturnRows(rows)
 			},
 			expected: []models.Reception{
 				{Id: "rec1", PvzId: "pvz1", Status: "ACTIVE"},
-				{Id: "rec2", PvzId: "pvz1", Status: "CLOSED"},
+				{Id: "rec2", PvzId: "pvz1", Status: "CLOSED", Summary: &models.ReceptionSummary{
+					ReceptionId:    "rec2",
+					ClosedAt:       closedAt,
+					TotalProducts:  3,
+					ProductsByType: map[string]int{models.BOOTS_TYPE: 2, models.CLOTHES_TYPE: 1},
+					OpenDuration:   90 * time.Minute,
+					DeletionsCount: 1,
+				}},
 			},
 			expectedErr: "",
 		},
@@ -184,9 +261,9 @@ func TestPvzRepository_GetPvzReceptions(t *testing.T) {
 			name:  "No Receptions",
 			pvzId: "pvz2",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = \$1`).
+				mock.ExpectQuery(query).
 					WithArgs("pvz2").
-					WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}))
+					WillReturnRows(sqlmock.NewRows(columns))
 			},
 			expected:    []models.Reception{},
 			expectedErr: "",
@@ -214,6 +291,7 @@ func TestPvzRepository_GetPvzReceptions(t *testing.T) {
 					assert.Equal(t, tt.expected[i].Id, result[i].Id)
 					assert.Equal(t, tt.expected[i].PvzId, result[i].PvzId)
 					assert.Equal(t, tt.expected[i].Status, result[i].Status)
+					assert.Equal(t, tt.expected[i].Summary, result[i].Summary)
 				}
 			}
 
@@ -237,15 +315,15 @@ func TestPvzRepository_GetReceptionProducts(t *testing.T) {
 			name:        "Success",
 			receptionId: "rec1",
 			mock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id"}).
-					AddRow("prod1", time.Now(), "type1", "rec1").
-					AddRow("prod2", time.Now(), "type2", "rec1")
-				mock.ExpectQuery(`SELECT id, date_time, type, reception_id FROM products WHERE reception_id = \$1`).
+				rows := sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "external_id"}).
+					AddRow("prod1", time.Now(), "type1", "rec1", "BC-1").
+					AddRow("prod2", time.Now(), "type2", "rec1", nil)
+				mock.ExpectQuery(`SELECT id, date_time, type, reception_id, external_id FROM products WHERE reception_id = \$1`).
 					WithArgs("rec1").
 					WillReturnRows(rows)
 			},
 			expected: []models.Product{
-				{Id: "prod1", Type: "type1", ReceptionId: "rec1"},
+				{Id: "prod1", Type: "type1", ReceptionId: "rec1", ExternalId: "BC-1"},
 				{Id: "prod2", Type: "type2", ReceptionId: "rec1"},
 			},
 			expectedErr: "",
@@ -254,7 +332,7 @@ func TestPvzRepository_GetReceptionProducts(t *testing.T) {
 			name:        "No Products",
 			receptionId: "rec2",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, date_time, type, reception_id FROM products WHERE reception_id = \$1`).
+				mock.ExpectQuery(`SELECT id, date_time, type, reception_id, external_id FROM products WHERE reception_id = \$1`).
 					WithArgs("rec2").
 					WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id"}))
 			},
@@ -372,3 +450,218 @@ func TestPvzRepository_GetAllPvzs(t *testing.T) {
 		})
 	}
 }
+
+func TestPvzRepository_SetPvzCapacity(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+	capacity := 20
+
+	tests := []struct {
+		name        string
+		mock        func(sqlmock.Sqlmock)
+		expectedErr string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE pvzs SET capacity = \$1, capacity_policy = \$2 WHERE id = \$3`).
+					WithArgs(&capacity, "warn", "pvz1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Not Found",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE pvzs SET capacity = \$1, capacity_policy = \$2 WHERE id = \$3`).
+					WithArgs(&capacity, "warn", "pvz1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedErr: "pvz not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := NewPvzRepository(db)
+			tt.mock(mock)
+
+			err = repo.SetPvzCapacity(ctx, "pvz1", &capacity, "warn")
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzRepository_GetPvzById(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, capacity_policy, occupancy FROM pvzs WHERE id = \$1`).
+		WithArgs("pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city", "address", "capacity", "capacity_policy", "occupancy"}).
+			AddRow("pvz1", time.Now(), "Москва", "Тверская, 1", 30, "reject", 7))
+	mock.ExpectQuery(`SELECT id, registration_date, city, address, capacity, capacity_policy, occupancy FROM pvzs WHERE id = \$1`).
+		WithArgs("pvz2").
+		WillReturnError(sql.ErrNoRows)
+
+	repo := NewPvzRepository(db)
+
+	pvz, err := repo.GetPvzById(ctx, "pvz1")
+	require.NoError(t, err)
+	require.NotNil(t, pvz.Capacity)
+	assert.Equal(t, 30, *pvz.Capacity)
+	assert.Equal(t, 7, pvz.Occupancy)
+
+	_, err = repo.GetPvzById(ctx, "pvz2")
+	require.Error(t, err)
+	assert.Equal(t, "pvz not found", err.Error())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_CreatePvzs(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+	now := time.Now()
+
+	pvzs := []requests.CreatePvzRequest{
+		{Id: "pvz1", RegistrationDate: now, City: "Москва", Address: "Тверская, 1"},
+		{Id: "pvz2", RegistrationDate: now, City: "Казань"},
+	}
+	insertQuery := `^INSERT INTO pvzs \(id,registration_date,city,address\) VALUES \(\$1,\$2,\$3,\$4\),\(\$5,\$6,\$7,\$8\) ` +
+		`ON CONFLICT \(id\) DO NOTHING RETURNING id$`
+	expectImportLock := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock`).
+			WithArgs(sqlmock.AnyArg(), `{"pvz1","pvz2"}`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+	}
+	expectInsert := func(mock sqlmock.Sqlmock, insertedIds ...string) {
+		rows := sqlmock.NewRows([]string{"id"})
+		for _, id := range insertedIds {
+			rows.AddRow(id)
+		}
+		mock.ExpectQuery(insertQuery).
+			WithArgs("pvz1", now, "Москва", "Тверская, 1", "pvz2", now, "Казань", "").
+			WillReturnRows(rows)
+	}
+	expectLock := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`^SELECT pg_advisory_xact_lock`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	tests := []struct {
+		name            string
+		allOrNothing    bool
+		mock            func(sqlmock.Sqlmock)
+		expectedSkipped []string
+		expectedErr     string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectImportLock(mock)
+				expectInsert(mock, "pvz1", "pvz2")
+				expectLock(mock)
+				mock.ExpectExec(`^INSERT INTO outbox_events \(id,event_type,pvz_id,payload,occurred_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\),\(\$6,\$7,\$8,\$9,\$10\)$`).
+					WithArgs(sqlmock.AnyArg(), models.EVENT_PVZ_CREATED, "pvz1", sqlmock.AnyArg(), now,
+						sqlmock.AnyArg(), models.EVENT_PVZ_CREATED, "pvz2", sqlmock.AnyArg(), now).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			expectedSkipped: []string{},
+		},
+		{
+			name: "Pvz Created Concurrently Is Skipped",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectImportLock(mock)
+				expectInsert(mock, "pvz1")
+				expectLock(mock)
+				mock.ExpectExec(`^INSERT INTO outbox_events \(id,event_type,pvz_id,payload,occurred_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\)$`).
+					WithArgs(sqlmock.AnyArg(), models.EVENT_PVZ_CREATED, "pvz1", sqlmock.AnyArg(), now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedSkipped: []string{"pvz2"},
+		},
+		{
+			name:         "All Or Nothing Rolls Back On Existing Pvz",
+			allOrNothing: true,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectImportLock(mock)
+				expectInsert(mock, "pvz1")
+				expectLock(mock)
+				mock.ExpectExec(`^INSERT INTO outbox_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
+			},
+			expectedSkipped: []string{"pvz2"},
+		},
+		{
+			name: "Insert Error Rolls Back",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectImportLock(mock)
+				mock.ExpectQuery(`INSERT INTO pvzs`).
+					WillReturnError(errors.New("db error"))
+				mock.ExpectRollback()
+			},
+			expectedErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := NewPvzRepository(db)
+			tt.mock(mock)
+
+			skipped, err := repo.CreatePvzs(ctx, pvzs, tt.allOrNothing)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedErr, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedSkipped, skipped)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzRepository_GetExistingPvzIds(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id FROM pvzs WHERE id IN \(\$1,\$2\)`).
+		WithArgs("pvz1", "pvz2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("pvz2"))
+
+	existing, err := NewPvzRepository(db).GetExistingPvzIds(context.Background(), []string{"pvz1", "pvz2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pvz2"}, existing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
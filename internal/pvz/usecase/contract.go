@@ -4,13 +4,20 @@ import (
 	"avito_spring_staj_2025/domain/models"
 	"avito_spring_staj_2025/domain/requests"
 	"context"
-	"time"
 )
 
 type PvzRepository interface {
 	CreatePvz(ctx context.Context, data *requests.CreatePvzRequest) error
+	CreatePvzs(ctx context.Context, pvzs []requests.CreatePvzRequest, allOrNothing bool) ([]string, error)
+	GetExistingPvzIds(ctx context.Context, ids []string) ([]string, error)
 	GetPvzReceptions(ctx context.Context, pvzId string) ([]models.Reception, error)
 	GetReceptionProducts(ctx context.Context, receptionId string) ([]models.Product, error)
-	GetPvzsFilteredByReceptionDate(ctx context.Context, from, to time.Time, limit, offset int) ([]models.Pvz, error)
+	GetPvzsFiltered(ctx context.Context, filter models.PvzFilter, limit, offset int) ([]models.Pvz, error)
 	GetAllPvzs(ctx context.Context) ([]models.Pvz, error)
+	GetPvzById(ctx context.Context, pvzId string) (*models.Pvz, error)
+	SetPvzCapacity(ctx context.Context, pvzId string, capacity *int, policy string) error
+}
+
+type ProductTypeCatalog interface {
+	ProductTypeExists(ctx context.Context, code string) (bool, error)
 }
@@ -6,22 +6,26 @@ import (
 	"avito_spring_staj_2025/internal/service/middleware"
 	"context"
 	"errors"
+	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
 type PvzUsecase struct {
 	pvzRepository PvzRepository
+	productTypes  ProductTypeCatalog
 }
 
-func NewPvzUsecase(pvzRepository PvzRepository) PvzUsecase {
+func NewPvzUsecase(pvzRepository PvzRepository, productTypes ProductTypeCatalog) PvzUsecase {
 	return PvzUsecase{
 		pvzRepository: pvzRepository,
+		productTypes:  productTypes,
 	}
 }
 
 func (pu PvzUsecase) CreatePvz(ctx context.Context, data *requests.CreatePvzRequest) error {
-	if data.City != "Москва" && data.City != "Санкт-Петербург" && data.City != "Казань" {
-		return errors.New("this city is not allowed")
+	if err := validatePvz(data); err != nil {
+		return err
 	}
 	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
 		return errors.New("this role is not allowed")
@@ -33,9 +37,160 @@ func (pu PvzUsecase) CreatePvz(ctx context.Context, data *requests.CreatePvzRequ
 	return nil
 }
 
-func (pu PvzUsecase) GetPvzsInformation(ctx context.Context, fromDate, toDate time.Time, limit, page int) ([]models.Pvz, error) {
+func validatePvz(data *requests.CreatePvzRequest) error {
+	if data.City != "Москва" && data.City != "Санкт-Петербург" && data.City != "Казань" {
+		return errors.New("this city is not allowed")
+	}
+	return nil
+}
+
+const maxImportRows = 10000
+
+func (pu PvzUsecase) ImportPvzs(ctx context.Context, data requests.ImportPvzsRequest) (models.PvzImportReport, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.PvzImportReport{}, errors.New("this role is not allowed")
+	}
+	if data.Mode == "" {
+		data.Mode = models.IMPORT_MODE_PARTIAL
+	}
+	if data.Mode != models.IMPORT_MODE_PARTIAL && data.Mode != models.IMPORT_MODE_ALL_OR_NOTHING {
+		return models.PvzImportReport{}, errors.New("invalid import mode")
+	}
+	if len(data.Rows) == 0 {
+		return models.PvzImportReport{}, errors.New("empty import file")
+	}
+	if len(data.Rows) > maxImportRows {
+		return models.PvzImportReport{}, errors.New("too many rows in import file")
+	}
+
+	report := models.PvzImportReport{
+		DryRun: data.DryRun,
+		Mode:   data.Mode,
+		Total:  len(data.Rows),
+	}
+
+	ids := make([]string, 0, len(data.Rows))
+	for _, row := range data.Rows {
+		if _, err := uuid.Parse(row.Id); err == nil {
+			ids = append(ids, row.Id)
+		}
+	}
+	existingIds := make(map[string]bool)
+	if len(ids) > 0 {
+		existing, err := pu.pvzRepository.GetExistingPvzIds(ctx, ids)
+		if err != nil {
+			return models.PvzImportReport{}, err
+		}
+		for _, id := range existing {
+			existingIds[id] = true
+		}
+	}
+
+	seenIds := make(map[string]bool, len(data.Rows))
+	validLines := make(map[string]int, len(data.Rows))
+	valid := make([]requests.CreatePvzRequest, 0, len(data.Rows))
+	for _, row := range data.Rows {
+		pvz, rowErrors := parseImportRow(row)
+		if pvz.Id != "" {
+			if existingIds[pvz.Id] {
+				rowErrors = append(rowErrors, "pvz already exists")
+			} else if seenIds[pvz.Id] {
+				rowErrors = append(rowErrors, "duplicate id in file")
+			}
+			seenIds[pvz.Id] = true
+		}
+
+		if len(rowErrors) > 0 {
+			report.RowErrors = append(report.RowErrors, models.PvzImportRowError{
+				Line:   row.Line,
+				Id:     row.Id,
+				Errors: rowErrors,
+			})
+			continue
+		}
+		validLines[pvz.Id] = row.Line
+		valid = append(valid, pvz)
+	}
+	report.Valid = len(valid)
+
+	if data.DryRun || len(valid) == 0 {
+		return report, nil
+	}
+	if data.Mode == models.IMPORT_MODE_ALL_OR_NOTHING && len(report.RowErrors) > 0 {
+		return report, nil
+	}
+
+	allOrNothing := data.Mode == models.IMPORT_MODE_ALL_OR_NOTHING
+	skippedIds, err := pu.pvzRepository.CreatePvzs(ctx, valid, allOrNothing)
+	if err != nil {
+		return models.PvzImportReport{}, err
+	}
+	// ПВЗ с таким id мог появиться после проверки выше: строка попадает в отчёт так же, как найденная заранее.
+	for _, id := range skippedIds {
+		report.RowErrors = append(report.RowErrors, models.PvzImportRowError{
+			Line:   validLines[id],
+			Id:     id,
+			Errors: []string{"pvz already exists"},
+		})
+	}
+	report.Valid -= len(skippedIds)
+	if allOrNothing && len(skippedIds) > 0 {
+		return report, nil
+	}
+	report.Inserted = report.Valid
+
+	return report, nil
+}
+
+// parseImportRow применяет к строке CSV те же правила, что и CreatePvz, и собирает все ошибки строки сразу.
+func parseImportRow(row requests.ImportPvzRow) (requests.CreatePvzRequest, []string) {
+	if row.ParseError != "" {
+		return requests.CreatePvzRequest{}, []string{row.ParseError}
+	}
+
+	var rowErrors []string
+	pvz := requests.CreatePvzRequest{
+		City:    strings.TrimSpace(row.City),
+		Address: strings.TrimSpace(row.Address),
+	}
+
+	if _, err := uuid.Parse(row.Id); err != nil {
+		rowErrors = append(rowErrors, "invalid id")
+	} else {
+		pvz.Id = row.Id
+	}
+
+	registrationDate, err := time.Parse(time.RFC3339, row.RegistrationDate)
+	if err != nil {
+		registrationDate, err = time.Parse(models.DATE_LAYOUT, row.RegistrationDate)
+	}
+	if err != nil {
+		rowErrors = append(rowErrors, "invalid registration date")
+	}
+	pvz.RegistrationDate = registrationDate
+
+	if err := validatePvz(&pvz); err != nil {
+		rowErrors = append(rowErrors, err.Error())
+	}
+
+	return pvz, rowErrors
+}
+
+func (pu PvzUsecase) GetPvzsInformation(ctx context.Context, filter models.PvzFilter, limit, page int) ([]models.Pvz, error) {
+	if err := validatePvzFilter(&filter); err != nil {
+		return nil, err
+	}
+	if filter.ProductType != "" {
+		exists, err := pu.productTypes.ProductTypeExists(ctx, filter.ProductType)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New("this type is not allowed")
+		}
+	}
 	offset := (page - 1) * limit
-	pvzs, err := pu.pvzRepository.GetPvzsFilteredByReceptionDate(ctx, fromDate, toDate, limit, offset)
+	pvzs, err := pu.pvzRepository.GetPvzsFiltered(ctx, filter, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -59,6 +214,61 @@ func (pu PvzUsecase) GetPvzsInformation(ctx context.Context, fromDate, toDate ti
 	return pvzs, nil
 }
 
+func validatePvzFilter(filter *models.PvzFilter) error {
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return errors.New("invalid date range")
+	}
+
+	switch filter.SortBy {
+	case "":
+		if filter.SortOrder != "" {
+			filter.SortBy = models.PVZ_SORT_REGISTRATION_DATE
+		}
+	case models.PVZ_SORT_REGISTRATION_DATE, models.PVZ_SORT_CITY, models.PVZ_SORT_LAST_RECEPTION_AT:
+	default:
+		return errors.New("invalid sort field")
+	}
+	switch filter.SortOrder {
+	case "":
+		if filter.SortBy != "" {
+			filter.SortOrder = models.SORT_ORDER_ASC
+		}
+	case models.SORT_ORDER_ASC, models.SORT_ORDER_DESC:
+	default:
+		return errors.New("invalid sort order")
+	}
+	return nil
+}
+
 func (pu PvzUsecase) GetAllPvzs(ctx context.Context) ([]models.Pvz, error) {
 	return pu.pvzRepository.GetAllPvzs(ctx)
 }
+
+func (pu PvzUsecase) GetPvz(ctx context.Context, pvzId string) (models.Pvz, error) {
+	pvz, err := pu.pvzRepository.GetPvzById(ctx, pvzId)
+	if err != nil {
+		return models.Pvz{}, err
+	}
+	return *pvz, nil
+}
+
+func (pu PvzUsecase) SetPvzCapacity(ctx context.Context, pvzId string, data requests.SetPvzCapacityRequest) (models.Pvz, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.Pvz{}, errors.New("this role is not allowed")
+	}
+	if data.Capacity != nil && *data.Capacity < 0 {
+		return models.Pvz{}, errors.New("invalid capacity")
+	}
+	if data.Policy == "" {
+		data.Policy = models.CAPACITY_POLICY_REJECT
+	}
+	if data.Policy != models.CAPACITY_POLICY_REJECT && data.Policy != models.CAPACITY_POLICY_WARN {
+		return models.Pvz{}, errors.New("invalid capacity policy")
+	}
+
+	err := pu.pvzRepository.SetPvzCapacity(ctx, pvzId, data.Capacity, data.Policy)
+	if err != nil {
+		return models.Pvz{}, err
+	}
+	return pu.GetPvz(ctx, pvzId)
+}
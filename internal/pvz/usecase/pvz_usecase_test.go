@@ -5,6 +5,7 @@ import (
 	"avito_spring_staj_2025/domain/requests"
 	"avito_spring_staj_2025/internal/service/middleware"
 	"avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
 	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
@@ -66,7 +67,7 @@ func TestPvzUsecase_CreatePvz(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(repositoryMocks.MockPvzRepository)
-			uc := NewPvzUsecase(mockRepo)
+			uc := NewPvzUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog())
 			tt.mockSetup(mockRepo)
 			err := uc.CreatePvz(tt.ctx(), &tt.data)
 			if tt.expectedErr != nil {
@@ -102,7 +103,7 @@ func TestPvzUsecase_GetPvzsInformation(t *testing.T) {
 				reception := models.Reception{Id: "reception123", PvzId: "pvz123"}
 				product := models.Product{Id: "product123", ReceptionId: "reception123"}
 
-				m.On("GetPvzsFilteredByReceptionDate", mock.Anything, now.Add(-24*time.Hour), now, 10, 0).
+				m.On("GetPvzsFiltered", mock.Anything, models.PvzFilter{From: now.Add(-24 * time.Hour), To: now}, 10, 0).
 					Return([]models.Pvz{pvz}, nil)
 				m.On("GetPvzReceptions", mock.Anything, "pvz123").
 					Return([]models.Reception{reception}, nil)
@@ -131,7 +132,7 @@ func TestPvzUsecase_GetPvzsInformation(t *testing.T) {
 			limit:    10,
 			page:     1,
 			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
-				m.On("GetPvzsFilteredByReceptionDate", mock.Anything, now.Add(-24*time.Hour), now, 10, 0).
+				m.On("GetPvzsFiltered", mock.Anything, models.PvzFilter{From: now.Add(-24 * time.Hour), To: now}, 10, 0).
 					Return([]models.Pvz{}, nil)
 			},
 			expectedRes: []models.Pvz{},
@@ -144,7 +145,7 @@ func TestPvzUsecase_GetPvzsInformation(t *testing.T) {
 			limit:    10,
 			page:     1,
 			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
-				m.On("GetPvzsFilteredByReceptionDate", mock.Anything, now.Add(-24*time.Hour), now, 10, 0).
+				m.On("GetPvzsFiltered", mock.Anything, models.PvzFilter{From: now.Add(-24 * time.Hour), To: now}, 10, 0).
 					Return([]models.Pvz{}, errors.New("db error"))
 			},
 			expectedRes: nil,
@@ -158,7 +159,7 @@ func TestPvzUsecase_GetPvzsInformation(t *testing.T) {
 			page:     1,
 			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
 				pvz := models.Pvz{Id: "pvz123", City: "Москва"}
-				m.On("GetPvzsFilteredByReceptionDate", mock.Anything, now.Add(-24*time.Hour), now, 10, 0).
+				m.On("GetPvzsFiltered", mock.Anything, models.PvzFilter{From: now.Add(-24 * time.Hour), To: now}, 10, 0).
 					Return([]models.Pvz{pvz}, nil)
 				m.On("GetPvzReceptions", mock.Anything, "pvz123").
 					Return([]models.Reception{}, errors.New("receptions error"))
@@ -175,7 +176,7 @@ func TestPvzUsecase_GetPvzsInformation(t *testing.T) {
 			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
 				pvz := models.Pvz{Id: "pvz123", City: "Москва"}
 				reception := models.Reception{Id: "reception123", PvzId: "pvz123"}
-				m.On("GetPvzsFilteredByReceptionDate", mock.Anything, now.Add(-24*time.Hour), now, 10, 0).
+				m.On("GetPvzsFiltered", mock.Anything, models.PvzFilter{From: now.Add(-24 * time.Hour), To: now}, 10, 0).
 					Return([]models.Pvz{pvz}, nil)
 				m.On("GetPvzReceptions", mock.Anything, "pvz123").
 					Return([]models.Reception{reception}, nil)
@@ -190,13 +191,316 @@ func TestPvzUsecase_GetPvzsInformation(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(repositoryMocks.MockPvzRepository)
-			uc := NewPvzUsecase(mockRepo)
+			uc := NewPvzUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog())
 			tt.mockSetup(mockRepo)
 
-			res, err := uc.GetPvzsInformation(context.Background(), tt.fromDate, tt.toDate, tt.limit, tt.page)
+			res, err := uc.GetPvzsInformation(context.Background(), models.PvzFilter{From: tt.fromDate, To: tt.toDate}, tt.limit, tt.page)
 			assert.Equal(t, tt.expectedRes, res)
 			assert.Equal(t, tt.expectedErr, err)
 			mockRepo.AssertExpectations(t)
 		})
 	}
 }
+
+func TestPvzUsecase_SetPvzCapacity(t *testing.T) {
+	capacity := 100
+	negative := -1
+
+	tests := []struct {
+		name        string
+		role        string
+		data        requests.SetPvzCapacityRequest
+		mockSetup   func(*repositoryMocks.MockPvzRepository)
+		expectedErr error
+	}{
+		{
+			name: "success with default policy",
+			role: "moderator",
+			data: requests.SetPvzCapacityRequest{Capacity: &capacity},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("SetPvzCapacity", mock.Anything, "pvz1", &capacity, models.CAPACITY_POLICY_REJECT).Return(nil)
+				m.On("GetPvzById", mock.Anything, "pvz1").Return(&models.Pvz{Id: "pvz1", Capacity: &capacity}, nil)
+			},
+		},
+		{
+			name:        "invalid role",
+			role:        "employee",
+			data:        requests.SetPvzCapacityRequest{Capacity: &capacity},
+			mockSetup:   func(_ *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "negative capacity",
+			role:        "moderator",
+			data:        requests.SetPvzCapacityRequest{Capacity: &negative},
+			mockSetup:   func(_ *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("invalid capacity"),
+		},
+		{
+			name:        "invalid policy",
+			role:        "moderator",
+			data:        requests.SetPvzCapacityRequest{Capacity: &capacity, Policy: "ignore"},
+			mockSetup:   func(_ *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("invalid capacity policy"),
+		},
+		{
+			name: "pvz not found",
+			role: "moderator",
+			data: requests.SetPvzCapacityRequest{Policy: models.CAPACITY_POLICY_WARN},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("SetPvzCapacity", mock.Anything, "pvz1", (*int)(nil), models.CAPACITY_POLICY_WARN).Return(errors.New("pvz not found"))
+			},
+			expectedErr: errors.New("pvz not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockPvzRepository)
+			uc := NewPvzUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog())
+			tt.mockSetup(mockRepo)
+
+			ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, tt.role)
+			_, err := uc.SetPvzCapacity(ctx, "pvz1", tt.data)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzUsecase_ImportPvzs(t *testing.T) {
+	const (
+		id1 = "11111111-1111-1111-1111-111111111111"
+		id2 = "22222222-2222-2222-2222-222222222222"
+		id3 = "33333333-3333-3333-3333-333333333333"
+	)
+	rows := []requests.ImportPvzRow{
+		{Line: 2, Id: id1, RegistrationDate: "2025-04-01", City: "Москва", Address: "Тверская, 1"},
+		{Line: 3, Id: id2, RegistrationDate: "2025-04-01T10:00:00Z", City: "Казань"},
+		{Line: 4, Id: id3, RegistrationDate: "yesterday", City: "Новосибирск"},
+		{Line: 5, Id: id1, RegistrationDate: "2025-04-01", City: "Москва"},
+		{Line: 6, ParseError: "invalid number of columns"},
+	}
+
+	tests := []struct {
+		name             string
+		role             string
+		data             requests.ImportPvzsRequest
+		mockSetup        func(*repositoryMocks.MockPvzRepository)
+		expectedInserted int
+		expectedErr      error
+	}{
+		{
+			name: "partial mode inserts valid rows",
+			role: "moderator",
+			data: requests.ImportPvzsRequest{Rows: rows},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetExistingPvzIds", mock.Anything, []string{id1, id2, id3, id1}).Return([]string{}, nil)
+				m.On("CreatePvzs", mock.Anything, mock.MatchedBy(func(pvzs []requests.CreatePvzRequest) bool {
+					return len(pvzs) == 2 && pvzs[0].Id == id1 && pvzs[0].Address == "Тверская, 1" && pvzs[1].Id == id2
+				}), false).Return([]string{}, nil)
+			},
+			expectedInserted: 2,
+		},
+		{
+			name: "pvz created concurrently is skipped",
+			role: "moderator",
+			data: requests.ImportPvzsRequest{Rows: rows},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetExistingPvzIds", mock.Anything, mock.Anything).Return([]string{}, nil)
+				m.On("CreatePvzs", mock.Anything, mock.Anything, false).Return([]string{id2}, nil)
+			},
+			expectedInserted: 1,
+		},
+		{
+			name: "all or nothing mode with pvz created concurrently inserts nothing",
+			role: "moderator",
+			data: requests.ImportPvzsRequest{Rows: rows[:2], Mode: models.IMPORT_MODE_ALL_OR_NOTHING},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetExistingPvzIds", mock.Anything, mock.Anything).Return([]string{}, nil)
+				m.On("CreatePvzs", mock.Anything, mock.Anything, true).Return([]string{id1}, nil)
+			},
+		},
+		{
+			name: "all or nothing mode with errors inserts nothing",
+			role: "moderator",
+			data: requests.ImportPvzsRequest{Rows: rows, Mode: models.IMPORT_MODE_ALL_OR_NOTHING},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetExistingPvzIds", mock.Anything, mock.Anything).Return([]string{}, nil)
+			},
+		},
+		{
+			name: "dry run",
+			role: "moderator",
+			data: requests.ImportPvzsRequest{Rows: rows[:2], DryRun: true},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetExistingPvzIds", mock.Anything, mock.Anything).Return([]string{}, nil)
+			},
+		},
+		{
+			name: "existing pvz is reported",
+			role: "moderator",
+			data: requests.ImportPvzsRequest{Rows: rows[:2], Mode: models.IMPORT_MODE_ALL_OR_NOTHING},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetExistingPvzIds", mock.Anything, mock.Anything).Return([]string{id2}, nil)
+			},
+		},
+		{
+			name:        "invalid role",
+			role:        "employee",
+			data:        requests.ImportPvzsRequest{Rows: rows},
+			mockSetup:   func(_ *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "invalid mode",
+			role:        "moderator",
+			data:        requests.ImportPvzsRequest{Rows: rows, Mode: "upsert"},
+			mockSetup:   func(_ *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("invalid import mode"),
+		},
+		{
+			name:        "empty file",
+			role:        "moderator",
+			mockSetup:   func(_ *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("empty import file"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockPvzRepository)
+			uc := NewPvzUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog())
+			tt.mockSetup(mockRepo)
+
+			ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, tt.role)
+			report, err := uc.ImportPvzs(ctx, tt.data)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedInserted, report.Inserted)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzUsecase_ImportPvzs_Report(t *testing.T) {
+	mockRepo := new(repositoryMocks.MockPvzRepository)
+	mockRepo.On("GetExistingPvzIds", mock.Anything, mock.Anything).Return([]string{}, nil)
+	uc := NewPvzUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog())
+
+	ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+	report, err := uc.ImportPvzs(ctx, requests.ImportPvzsRequest{
+		DryRun: true,
+		Rows: []requests.ImportPvzRow{
+			{Line: 2, Id: "not-a-uuid", RegistrationDate: "01.04.2025", City: "Омск"},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Total)
+	assert.Equal(t, 0, report.Valid)
+	assert.Equal(t, []models.PvzImportRowError{{
+		Line:   2,
+		Id:     "not-a-uuid",
+		Errors: []string{"invalid id", "invalid registration date", "this city is not allowed"},
+	}}, report.RowErrors)
+}
+
+func TestPvzUsecase_ImportPvzs_ReportsSkippedIds(t *testing.T) {
+	const (
+		id1 = "11111111-1111-1111-1111-111111111111"
+		id2 = "22222222-2222-2222-2222-222222222222"
+	)
+	mockRepo := new(repositoryMocks.MockPvzRepository)
+	mockRepo.On("GetExistingPvzIds", mock.Anything, mock.Anything).Return([]string{}, nil)
+	mockRepo.On("CreatePvzs", mock.Anything, mock.Anything, false).Return([]string{id2}, nil)
+	uc := NewPvzUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog())
+
+	ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+	report, err := uc.ImportPvzs(ctx, requests.ImportPvzsRequest{
+		Rows: []requests.ImportPvzRow{
+			{Line: 2, Id: id1, RegistrationDate: "2025-04-01", City: "Москва"},
+			{Line: 3, Id: id2, RegistrationDate: "2025-04-01", City: "Казань"},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Valid)
+	assert.Equal(t, 1, report.Inserted)
+	assert.Equal(t, []models.PvzImportRowError{{
+		Line:   3,
+		Id:     id2,
+		Errors: []string{"pvz already exists"},
+	}}, report.RowErrors)
+}
+
+func TestPvzUsecase_GetPvzsInformation_Filter(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      models.PvzFilter
+		mockSetup   func(*repositoryMocks.MockPvzRepository)
+		expectedErr error
+	}{
+		{
+			name:   "default sort order",
+			filter: models.PvzFilter{SortBy: models.PVZ_SORT_CITY, Cities: []string{"Казань"}},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetPvzsFiltered", mock.Anything, models.PvzFilter{
+					SortBy:    models.PVZ_SORT_CITY,
+					SortOrder: models.SORT_ORDER_ASC,
+					Cities:    []string{"Казань"},
+				}, 10, 0).Return([]models.Pvz{}, nil)
+			},
+		},
+		{
+			name:   "default sort field",
+			filter: models.PvzFilter{SortOrder: models.SORT_ORDER_DESC},
+			mockSetup: func(m *repositoryMocks.MockPvzRepository) {
+				m.On("GetPvzsFiltered", mock.Anything, models.PvzFilter{
+					SortBy:    models.PVZ_SORT_REGISTRATION_DATE,
+					SortOrder: models.SORT_ORDER_DESC,
+				}, 10, 0).Return([]models.Pvz{}, nil)
+			},
+		},
+		{
+			name:        "invalid sort field",
+			filter:      models.PvzFilter{SortBy: "occupancy"},
+			mockSetup:   func(m *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("invalid sort field"),
+		},
+		{
+			name:        "invalid sort order",
+			filter:      models.PvzFilter{SortBy: models.PVZ_SORT_CITY, SortOrder: "up"},
+			mockSetup:   func(m *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("invalid sort order"),
+		},
+		{
+			name:        "invalid product type",
+			filter:      models.PvzFilter{ProductType: "мебель"},
+			mockSetup:   func(m *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("this type is not allowed"),
+		},
+		{
+			name: "invalid date range",
+			filter: models.PvzFilter{
+				From: time.Date(2025, 4, 12, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC),
+			},
+			mockSetup:   func(m *repositoryMocks.MockPvzRepository) {},
+			expectedErr: errors.New("invalid date range"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockPvzRepository)
+			uc := NewPvzUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog())
+			tt.mockSetup(mockRepo)
+
+			_, err := uc.GetPvzsInformation(context.Background(), tt.filter, 10, 1)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -9,6 +9,12 @@ import (
 type ReceptionUsecase interface {
 	CreateReception(ctx context.Context, data requests.CreateReceptionRequest) (models.Reception, error)
 	AddProductToReception(ctx context.Context, data requests.AddProductRequest) (models.Product, error)
-	DeleteLastProduct(ctx context.Context, pvdId string) error
+	AddProductsToReception(ctx context.Context, data requests.AddProductsBatchRequest) ([]models.Product, error)
+	GetProductsByBarcode(ctx context.Context, barcode string) ([]models.ProductLookup, error)
+	DeleteLastProducts(ctx context.Context, pvzId string, count int) ([]models.Product, error)
+	RemoveProduct(ctx context.Context, pvzId string, data requests.RemoveProductRequest) (models.Product, error)
+	ReopenReception(ctx context.Context, receptionId string, data requests.ReopenReceptionRequest) (models.Reception, models.ReceptionReopen, error)
 	CloseReception(ctx context.Context, pvdId string) (models.Reception, error)
+	SetReceptionManifest(ctx context.Context, receptionId string, data requests.SetManifestRequest) ([]models.ManifestItem, error)
+	GetDiscrepancyReport(ctx context.Context, receptionId string) (models.DiscrepancyReport, error)
 }
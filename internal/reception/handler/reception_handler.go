@@ -1,15 +1,19 @@
 package handler
 
 import (
+	"avito_spring_staj_2025/domain/models"
 	"avito_spring_staj_2025/domain/requests"
 	"avito_spring_staj_2025/domain/responses"
 	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/metrics"
 	"avito_spring_staj_2025/internal/service/middleware"
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"github.com/microcosm-cc/bluemonday"
 	"go.uber.org/zap"
 	"net/http"
+	"strconv"
 )
 
 type ReceptionHandler struct {
@@ -43,10 +47,11 @@ func (h *ReceptionHandler) CreateReception(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	response := responses.CreateReceptionResponse{
-		Id:       reception.Id,
-		DateTime: reception.DateTime,
-		PvzId:    reception.PvzId,
-		Status:   reception.Status,
+		Id:                  reception.Id,
+		DateTime:            reception.DateTime,
+		PvzId:               reception.PvzId,
+		Status:              reception.Status,
+		OutsideWorkingHours: reception.OutsideWorkingHours,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -70,8 +75,10 @@ func (h *ReceptionHandler) AddProductToReception(w http.ResponseWriter, r *http.
 	}
 
 	data = requests.AddProductRequest{
-		Type:  sanitizer.Sanitize(data.Type),
-		PvzId: sanitizer.Sanitize(data.PvzId),
+		Type:       sanitizer.Sanitize(data.Type),
+		PvzId:      sanitizer.Sanitize(data.PvzId),
+		ExternalId: sanitizer.Sanitize(data.ExternalId),
+		CellCode:   sanitizer.Sanitize(data.CellCode),
 	}
 
 	product, err := h.usecase.AddProductToReception(ctx, data)
@@ -81,10 +88,105 @@ func (h *ReceptionHandler) AddProductToReception(w http.ResponseWriter, r *http.
 	}
 
 	response := responses.AddProductResponse{
-		Id:          product.Id,
-		DateTime:    product.DateTime,
-		Type:        product.Type,
-		ReceptionId: product.ReceptionId,
+		Id:           product.Id,
+		DateTime:     product.DateTime,
+		Type:         product.Type,
+		ReceptionId:  product.ReceptionId,
+		SeqNo:        product.SeqNo,
+		ExternalId:   product.ExternalId,
+		OverCapacity: product.OverCapacity,
+		CellCode:     product.CellCode,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+const maxBatchBodySize = 1 << 20
+
+func (h *ReceptionHandler) GetProductsByBarcode(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	barcode := sanitizer.Sanitize(r.URL.Query().Get("barcode"))
+
+	lookups, err := h.usecase.GetProductsByBarcode(ctx, barcode)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetProductsByBarcodeResponse{
+		Products: make([]responses.ProductLookupResponse, 0, len(lookups)),
+	}
+	for _, lookup := range lookups {
+		response.Products = append(response.Products, responses.ProductLookupResponse{
+			Id:              lookup.Product.Id,
+			DateTime:        lookup.Product.DateTime,
+			Type:            lookup.Product.Type,
+			ExternalId:      lookup.Product.ExternalId,
+			SeqNo:           lookup.Product.SeqNo,
+			ReceptionId:     lookup.Product.ReceptionId,
+			ReceptionStatus: lookup.ReceptionStatus,
+			PvzId:           lookup.PvzId,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ReceptionHandler) AddProductsToReception(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	var data requests.AddProductsBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	data.PvzId = sanitizer.Sanitize(data.PvzId)
+	for i := range data.Products {
+		data.Products[i] = requests.BatchProductItem{
+			Type:       sanitizer.Sanitize(data.Products[i].Type),
+			ExternalId: sanitizer.Sanitize(data.Products[i].ExternalId),
+		}
+	}
+
+	products, err := h.usecase.AddProductsToReception(ctx, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	metrics.AmountOfAddedProducts.WithLabelValues(r.URL.Path).Add(float64(len(products)))
+
+	response := responses.AddProductsBatchResponse{
+		Products: make([]responses.AddProductResponse, 0, len(products)),
+	}
+	for _, product := range products {
+		response.Products = append(response.Products, responses.AddProductResponse{
+			Id:           product.Id,
+			DateTime:     product.DateTime,
+			Type:         product.Type,
+			ReceptionId:  product.ReceptionId,
+			SeqNo:        product.SeqNo,
+			ExternalId:   product.ExternalId,
+			OverCapacity: product.OverCapacity,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -96,6 +198,45 @@ func (h *ReceptionHandler) AddProductToReception(w http.ResponseWriter, r *http.
 	}
 }
 
+func (h *ReceptionHandler) ReopenReception(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	receptionId := sanitizer.Sanitize(mux.Vars(r)["receptionId"])
+
+	var data requests.ReopenReceptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.Reason = sanitizer.Sanitize(data.Reason)
+
+	reception, reopen, err := h.usecase.ReopenReception(ctx, receptionId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.ReopenReceptionResponse{
+		Id:         reception.Id,
+		DateTime:   reception.DateTime,
+		PvzId:      reception.PvzId,
+		Status:     reception.Status,
+		ReopenedBy: reopen.ReopenedBy,
+		ReopenedAt: reopen.ReopenedAt,
+		Reason:     reopen.Reason,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
 func (h *ReceptionHandler) DeleteLastProduct(w http.ResponseWriter, r *http.Request) {
 	requestID := middleware.GetRequestID(r.Context())
 	ctx, cancel := middleware.WithTimeout(r.Context())
@@ -104,13 +245,68 @@ func (h *ReceptionHandler) DeleteLastProduct(w http.ResponseWriter, r *http.Requ
 
 	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
 
-	err := h.usecase.DeleteLastProduct(ctx, pvzId)
+	count := 1
+	if rawCount := r.URL.Query().Get("count"); rawCount != "" {
+		parsed, err := strconv.Atoi(rawCount)
+		if err != nil {
+			h.handleError(w, errors.New("invalid count"), requestID)
+			return
+		}
+		count = parsed
+	}
+
+	products, err := h.usecase.DeleteLastProducts(ctx, pvzId, count)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeDeletedProducts(w, products, requestID)
+}
+
+func (h *ReceptionHandler) RemoveProduct(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.RemoveProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.ProductId = sanitizer.Sanitize(data.ProductId)
+	data.Reason = sanitizer.Sanitize(data.Reason)
+
+	product, err := h.usecase.RemoveProduct(ctx, pvzId, data)
 	if err != nil {
 		h.handleError(w, err, requestID)
 		return
 	}
 
+	h.writeDeletedProducts(w, []models.Product{product}, requestID)
+}
+
+func (h *ReceptionHandler) writeDeletedProducts(w http.ResponseWriter, products []models.Product, requestID string) {
+	response := responses.DeleteProductsResponse{
+		Deleted: make([]responses.DeletedProductResponse, 0, len(products)),
+	}
+	for _, product := range products {
+		response.Deleted = append(response.Deleted, responses.DeletedProductResponse{
+			Id:    product.Id,
+			SeqNo: product.SeqNo,
+			Type:  product.Type,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
 }
 
 func (h *ReceptionHandler) CloseLastReception(w http.ResponseWriter, r *http.Request) {
@@ -131,6 +327,54 @@ func (h *ReceptionHandler) CloseLastReception(w http.ResponseWriter, r *http.Req
 		DateTime: reception.DateTime,
 		PvzId:    reception.PvzId,
 		Status:   reception.Status,
+		Summary:  responses.NewReceptionSummaryResponse(reception.Summary),
+	}
+	if reception.DiscrepancyReport != nil {
+		report := newDiscrepancyReportResponse(*reception.DiscrepancyReport)
+		response.DiscrepancyReport = &report
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ReceptionHandler) SetReceptionManifest(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	receptionId := sanitizer.Sanitize(mux.Vars(r)["receptionId"])
+
+	var data requests.SetManifestRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	for i := range data.Items {
+		data.Items[i].Type = sanitizer.Sanitize(data.Items[i].Type)
+		data.Items[i].ExternalId = sanitizer.Sanitize(data.Items[i].ExternalId)
+	}
+
+	items, err := h.usecase.SetReceptionManifest(ctx, receptionId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.ManifestResponse{
+		ReceptionId: receptionId,
+		Items:       make([]responses.ManifestItemResponse, 0, len(items)),
+	}
+	for _, item := range items {
+		response.Items = append(response.Items, responses.ManifestItemResponse{
+			ExternalId: item.ExternalId,
+			Type:       item.Type,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -141,6 +385,49 @@ func (h *ReceptionHandler) CloseLastReception(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (h *ReceptionHandler) GetDiscrepancyReport(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	receptionId := sanitizer.Sanitize(mux.Vars(r)["receptionId"])
+
+	report, err := h.usecase.GetDiscrepancyReport(ctx, receptionId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(newDiscrepancyReportResponse(report)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func newDiscrepancyReportResponse(report models.DiscrepancyReport) responses.DiscrepancyReportResponse {
+	response := responses.DiscrepancyReportResponse{
+		ReceptionId:   report.ReceptionId,
+		CreatedAt:     report.CreatedAt,
+		ExpectedCount: report.ExpectedCount,
+		ReceivedCount: report.ReceivedCount,
+		MatchedCount:  report.MatchedCount,
+		Items:         make([]responses.DiscrepancyItemResponse, 0, len(report.Items)),
+	}
+	for _, item := range report.Items {
+		response.Items = append(response.Items, responses.DiscrepancyItemResponse{
+			Kind:         item.Kind,
+			ExternalId:   item.ExternalId,
+			ExpectedType: item.ExpectedType,
+			ActualType:   item.ActualType,
+			ProductId:    item.ProductId,
+		})
+	}
+	return response
+}
+
 func (h *ReceptionHandler) handleError(w http.ResponseWriter, err error, requestID string) {
 	logger.AccessLogger.Error("Handling error",
 		zap.String("request_id", requestID),
@@ -153,8 +440,20 @@ func (h *ReceptionHandler) handleError(w http.ResponseWriter, err error, request
 	switch err.Error() {
 	case "this city is not allowed", "active reception already exists",
 		"this type is not allowed", "pvz not found",
-		"no active reception", "invalid startDate", "invalid endDate", "no products in reception":
+		"no active reception", "invalid startDate", "invalid endDate", "no products in reception",
+		"pvz is closed at this time", "empty product batch", "too many products in batch",
+		"duplicate external id in batch", "reopen reason is required",
+		"invalid count", "not enough products in reception", "product id is required", "remove reason is required",
+		"barcode is required", "empty manifest", "too many items in manifest", "external id is required",
+		"duplicate external id in manifest":
 		w.WriteHeader(http.StatusBadRequest)
+	case "reception not found", "product not found", "discrepancy report not found", "cell not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "reception is not closed", "only the last reception can be reopened", "reception is not in progress":
+		w.WriteHeader(http.StatusConflict)
+	case "pvz capacity exceeded", "product already scanned", "product has already left the reception",
+		"product has damage reports", "cell is too small", "cell is full":
+		w.WriteHeader(http.StatusConflict)
 	case "this role is not allowed":
 		w.WriteHeader(http.StatusForbidden)
 	case "failed to generate error response":
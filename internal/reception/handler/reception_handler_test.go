@@ -140,6 +140,34 @@ func TestPvzHandler_AddProductToReception(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   `{"errors":"product add failed"}`,
 		},
+		{
+			name:         "barcode",
+			inputBody:    `{"type":"обувь","pvzId":"123","externalId":"BC-1"}`,
+			inputRequest: requests.AddProductRequest{Type: "обувь", PvzId: "123", ExternalId: "BC-1"},
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, req requests.AddProductRequest) {
+				usecase.On("AddProductToReception", mock.Anything, req).Return(models.Product{
+					Id:          "prod1",
+					DateTime:    testTime,
+					Type:        "обувь",
+					ReceptionId: "rec1",
+					SeqNo:       4,
+					ExternalId:  "BC-1",
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: fmt.Sprintf(`{"id":"prod1","dateTime":"%s","type":"обувь","receptionId":"rec1","seqNo":4,"externalId":"BC-1"}`,
+				testTime.Format(time.RFC3339)),
+		},
+		{
+			name:         "duplicate scan",
+			inputBody:    `{"type":"обувь","pvzId":"123","externalId":"BC-1"}`,
+			inputRequest: requests.AddProductRequest{Type: "обувь", PvzId: "123", ExternalId: "BC-1"},
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, req requests.AddProductRequest) {
+				usecase.On("AddProductToReception", mock.Anything, req).Return(models.Product{}, errors.New("product already scanned"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"product already scanned"}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -169,6 +197,94 @@ func TestPvzHandler_AddProductToReception(t *testing.T) {
 	}
 }
 
+func TestPvzHandler_AddProductsToReception(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	type mockBehavior func(usecase *usecaseMocks.ReceptionUsecaseMock, req requests.AddProductsBatchRequest)
+
+	testTime := time.Date(2025, 4, 11, 23, 59, 30, 0, time.UTC)
+	batch := requests.AddProductsBatchRequest{
+		PvzId: "123",
+		Products: []requests.BatchProductItem{
+			{Type: "обувь", ExternalId: "barcode-1"},
+			{Type: "одежда"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		inputBody      string
+		inputRequest   requests.AddProductsBatchRequest
+		mockBehavior   mockBehavior
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:         "success",
+			inputBody:    `{"pvzId":"123","products":[{"type":"обувь","externalId":"barcode-1"},{"type":"одежда"}]}`,
+			inputRequest: batch,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, req requests.AddProductsBatchRequest) {
+				usecase.On("AddProductsToReception", mock.Anything, req).Return([]models.Product{
+					{Id: "prod1", DateTime: testTime, Type: "обувь", ReceptionId: "rec1", ExternalId: "barcode-1"},
+					{Id: "prod2", DateTime: testTime, Type: "одежда", ReceptionId: "rec1"},
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: fmt.Sprintf(`{"products":[`+
+				`{"id":"prod1","dateTime":"%[1]s","type":"обувь","receptionId":"rec1","externalId":"barcode-1"},`+
+				`{"id":"prod2","dateTime":"%[1]s","type":"одежда","receptionId":"rec1"}]}`, testTime.Format(time.RFC3339)),
+		},
+		{
+			name:         "invalid item",
+			inputBody:    `{"pvzId":"123","products":[{"type":"обувь","externalId":"barcode-1"},{"type":"одежда"}]}`,
+			inputRequest: batch,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, req requests.AddProductsBatchRequest) {
+				usecase.On("AddProductsToReception", mock.Anything, req).
+					Return([]models.Product{}, errors.New("this type is not allowed"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"this type is not allowed"}`,
+		},
+		{
+			name:         "empty batch",
+			inputBody:    `{"pvzId":"123","products":[]}`,
+			inputRequest: requests.AddProductsBatchRequest{PvzId: "123", Products: []requests.BatchProductItem{}},
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, req requests.AddProductsBatchRequest) {
+				usecase.On("AddProductsToReception", mock.Anything, req).
+					Return([]models.Product{}, errors.New("empty product batch"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"empty product batch"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ReceptionUsecaseMock)
+			handler := NewReceptionHandler(mockUsecase)
+
+			tt.mockBehavior(mockUsecase, tt.inputRequest)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/products/batch", strings.NewReader(tt.inputBody))
+			w := httptest.NewRecorder()
+
+			handler.AddProductsToReception(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
 func TestPvzHandler_CloseLastReception(t *testing.T) {
 	logger.AccessLogger = zap.NewNop()
 	type mockBehavior func(usecase *usecaseMocks.ReceptionUsecaseMock, pvzId string)
@@ -198,6 +314,59 @@ func TestPvzHandler_CloseLastReception(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody:   fmt.Sprintf(`{"id":"rec1","dateTime":"%s","pvzId":"123","status":"close"}`, testTime.Format(time.RFC3339)),
 		},
+		{
+			name:      "success with summary",
+			pathParam: "123",
+			mockPvzId: "123",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, pvzId string) {
+				usecase.On("CloseReception", mock.Anything, pvzId).Return(models.Reception{
+					Id:       "rec1",
+					DateTime: testTime,
+					PvzId:    "123",
+					Status:   "close",
+					Summary: &models.ReceptionSummary{
+						ReceptionId:    "rec1",
+						ClosedAt:       testTime.Add(2 * time.Hour),
+						TotalProducts:  3,
+						ProductsByType: map[string]int{models.CLOTHES_TYPE: 2, models.BOOTS_TYPE: 1},
+						OpenDuration:   2 * time.Hour,
+						DeletionsCount: 1,
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: fmt.Sprintf(`{"id":"rec1","dateTime":"%s","pvzId":"123","status":"close","summary":{`+
+				`"closedAt":"%s","totalProducts":3,"productsByType":{"одежда":2,"обувь":1},"openDurationSeconds":7200,"deletions":1}}`,
+				testTime.Format(time.RFC3339), testTime.Add(2*time.Hour).Format(time.RFC3339)),
+		},
+		{
+			name:      "success with discrepancy report",
+			pathParam: "123",
+			mockPvzId: "123",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, pvzId string) {
+				usecase.On("CloseReception", mock.Anything, pvzId).Return(models.Reception{
+					Id:       "rec1",
+					DateTime: testTime,
+					PvzId:    "123",
+					Status:   "close",
+					DiscrepancyReport: &models.DiscrepancyReport{
+						ReceptionId:   "rec1",
+						CreatedAt:     testTime,
+						ExpectedCount: 1,
+						ReceivedCount: 1,
+						Items: []models.DiscrepancyItem{
+							{Kind: models.DISCREPANCY_UNEXPECTED, ExternalId: "BC-9", ActualType: models.CLOTHES_TYPE, ProductId: "p1"},
+							{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-1", ExpectedType: models.BOOTS_TYPE},
+						},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: fmt.Sprintf(`{"id":"rec1","dateTime":"%[1]s","pvzId":"123","status":"close","discrepancyReport":{`+
+				`"receptionId":"rec1","createdAt":"%[1]s","expectedCount":1,"receivedCount":1,"matchedCount":0,"items":[`+
+				`{"kind":"unexpected","externalId":"BC-9","actualType":"одежда","productId":"p1"},`+
+				`{"kind":"missing","externalId":"BC-1","expectedType":"обувь"}]}}`, testTime.Format(time.RFC3339)),
+		},
 		{
 			name:      "usecase error",
 			pathParam: "123",
@@ -251,6 +420,7 @@ func TestPvzHandler_DeleteLastProduct(t *testing.T) {
 	tests := []struct {
 		name           string
 		pvzId          string
+		query          string
 		mockBehavior   mockBehavior
 		expectedStatus int
 		expectedBody   string
@@ -259,16 +429,47 @@ func TestPvzHandler_DeleteLastProduct(t *testing.T) {
 			name:  "success",
 			pvzId: "123",
 			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, pvzId string) {
-				usecase.On("DeleteLastProduct", mock.Anything, pvzId).Return(nil)
+				usecase.On("DeleteLastProducts", mock.Anything, pvzId, 1).
+					Return([]models.Product{{Id: "p5", SeqNo: 5, Type: "обувь"}}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   ``,
+			expectedBody:   `{"deleted":[{"id":"p5","seqNo":5,"type":"обувь"}]}`,
+		},
+		{
+			name:  "with count",
+			pvzId: "123",
+			query: "?count=2",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, pvzId string) {
+				usecase.On("DeleteLastProducts", mock.Anything, pvzId, 2).
+					Return([]models.Product{{Id: "p5", SeqNo: 5, Type: "обувь"}, {Id: "p4", SeqNo: 4, Type: "одежда"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"deleted":[{"id":"p5","seqNo":5,"type":"обувь"},{"id":"p4","seqNo":4,"type":"одежда"}]}`,
+		},
+		{
+			name:           "invalid count",
+			pvzId:          "123",
+			query:          "?count=abc",
+			mockBehavior:   func(_ *usecaseMocks.ReceptionUsecaseMock, _ string) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid count"}`,
+		},
+		{
+			name:  "not enough products",
+			pvzId: "123",
+			query: "?count=10",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, pvzId string) {
+				usecase.On("DeleteLastProducts", mock.Anything, pvzId, 10).
+					Return(nil, errors.New("not enough products in reception"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"not enough products in reception"}`,
 		},
 		{
 			name:  "usecase error",
 			pvzId: "123",
 			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock, pvzId string) {
-				usecase.On("DeleteLastProduct", mock.Anything, pvzId).Return(errors.New("delete error"))
+				usecase.On("DeleteLastProducts", mock.Anything, pvzId, 1).Return(nil, errors.New("delete error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   `{"errors":"delete error"}`,
@@ -282,7 +483,7 @@ func TestPvzHandler_DeleteLastProduct(t *testing.T) {
 
 			tt.mockBehavior(mockUsecase, tt.pvzId)
 
-			req := httptest.NewRequest(http.MethodDelete, "/api/pvz/"+tt.pvzId+"/delete_last_product", nil)
+			req := httptest.NewRequest(http.MethodPost, "/api/pvz/"+tt.pvzId+"/delete_last_product"+tt.query, nil)
 			w := httptest.NewRecorder()
 			req = mux.SetURLVars(req, map[string]string{"pvzId": tt.pvzId})
 			handler.DeleteLastProduct(w, req)
@@ -297,9 +498,379 @@ func TestPvzHandler_DeleteLastProduct(t *testing.T) {
 
 			body, _ := io.ReadAll(res.Body)
 			assert.Equal(t, tt.expectedStatus, res.StatusCode)
-			if tt.expectedBody != "" {
-				assert.JSONEq(t, tt.expectedBody, string(body))
-			}
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzHandler_RemoveProduct(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(usecase *usecaseMocks.ReceptionUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			body: `{"productId":"p2","reason":"брак"}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("RemoveProduct", mock.Anything, "123", requests.RemoveProductRequest{ProductId: "p2", Reason: "брак"}).
+					Return(models.Product{Id: "p2", SeqNo: 2, Type: "одежда"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"deleted":[{"id":"p2","seqNo":2,"type":"одежда"}]}`,
+		},
+		{
+			name: "missing reason",
+			body: `{"productId":"p2"}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("RemoveProduct", mock.Anything, "123", mock.Anything).
+					Return(models.Product{}, errors.New("remove reason is required"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"remove reason is required"}`,
+		},
+		{
+			name: "product not found",
+			body: `{"productId":"p9","reason":"брак"}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("RemoveProduct", mock.Anything, "123", mock.Anything).
+					Return(models.Product{}, errors.New("product not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"product not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ReceptionUsecaseMock)
+			handler := NewReceptionHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/pvz/123/remove_product", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"pvzId": "123"})
+			w := httptest.NewRecorder()
+			handler.RemoveProduct(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzHandler_ReopenReception(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	testTime := time.Date(2025, 4, 11, 23, 59, 50, 0, time.Local)
+	reopenTime := testTime.Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(usecase *usecaseMocks.ReceptionUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			body: `{"reason":"забыли коробку"}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("ReopenReception", mock.Anything, "rec1", requests.ReopenReceptionRequest{Reason: "забыли коробку"}).
+					Return(models.Reception{Id: "rec1", DateTime: testTime, PvzId: "123", Status: "in_progress"},
+						models.ReceptionReopen{ReceptionId: "rec1", ReopenedBy: "moderator-1", ReopenedAt: reopenTime, Reason: "забыли коробку"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: fmt.Sprintf(`{"id":"rec1","dateTime":"%s","pvzId":"123","status":"in_progress","reopenedBy":"moderator-1","reopenedAt":"%s","reason":"забыли коробку"}`,
+				testTime.Format(time.RFC3339), reopenTime.Format(time.RFC3339)),
+		},
+		{
+			name:           "invalid body",
+			body:           `{`,
+			mockBehavior:   func(_ *usecaseMocks.ReceptionUsecaseMock) {},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"errors":"unexpected EOF"}`,
+		},
+		{
+			name: "empty reason",
+			body: `{"reason":""}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("ReopenReception", mock.Anything, "rec1", mock.Anything).
+					Return(models.Reception{}, models.ReceptionReopen{}, errors.New("reopen reason is required"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"reopen reason is required"}`,
+		},
+		{
+			name: "reception not found",
+			body: `{"reason":"r"}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("ReopenReception", mock.Anything, "rec1", mock.Anything).
+					Return(models.Reception{}, models.ReceptionReopen{}, errors.New("reception not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"reception not found"}`,
+		},
+		{
+			name: "not the last reception",
+			body: `{"reason":"r"}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("ReopenReception", mock.Anything, "rec1", mock.Anything).
+					Return(models.Reception{}, models.ReceptionReopen{}, errors.New("only the last reception can be reopened"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"only the last reception can be reopened"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ReceptionUsecaseMock)
+			handler := NewReceptionHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodPost, "/receptions/rec1/reopen", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"receptionId": "rec1"})
+			w := httptest.NewRecorder()
+			handler.ReopenReception(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzHandler_GetProductsByBarcode(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	testTime := time.Date(2025, 4, 11, 23, 59, 50, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockBehavior   func(usecase *usecaseMocks.ReceptionUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "success",
+			query: "?barcode=BC-1",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("GetProductsByBarcode", mock.Anything, "BC-1").Return([]models.ProductLookup{
+					{
+						Product:         models.Product{Id: "p1", DateTime: testTime, Type: "обувь", ReceptionId: "rec1", SeqNo: 3, ExternalId: "BC-1"},
+						PvzId:           "pvz1",
+						ReceptionStatus: "in_progress",
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"products":[{"id":"p1","dateTime":"2025-04-11T23:59:50Z","type":"обувь","externalId":"BC-1",` +
+				`"seqNo":3,"receptionId":"rec1","receptionStatus":"in_progress","pvzId":"pvz1"}]}`,
+		},
+		{
+			name:  "nothing found",
+			query: "?barcode=BC-2",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("GetProductsByBarcode", mock.Anything, "BC-2").Return([]models.ProductLookup{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"products":[]}`,
+		},
+		{
+			name: "missing barcode",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("GetProductsByBarcode", mock.Anything, "").Return(nil, errors.New("barcode is required"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"barcode is required"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ReceptionUsecaseMock)
+			handler := NewReceptionHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/products"+tt.query, nil)
+			w := httptest.NewRecorder()
+			handler.GetProductsByBarcode(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzHandler_SetReceptionManifest(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	tests := []struct {
+		name           string
+		body           string
+		mockBehavior   func(usecase *usecaseMocks.ReceptionUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			body: `{"items":[{"type":"обувь","externalId":"BC-1"}]}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("SetReceptionManifest", mock.Anything, "rec1", requests.SetManifestRequest{
+					Items: []requests.ManifestItemRequest{{Type: "обувь", ExternalId: "BC-1"}},
+				}).Return([]models.ManifestItem{{Id: "m1", ReceptionId: "rec1", ExternalId: "BC-1", Type: "обувь"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"receptionId":"rec1","items":[{"externalId":"BC-1","type":"обувь"}]}`,
+		},
+		{
+			name: "duplicate external id",
+			body: `{"items":[{"type":"обувь","externalId":"BC-1"},{"type":"обувь","externalId":"BC-1"}]}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("SetReceptionManifest", mock.Anything, "rec1", mock.Anything).
+					Return(nil, errors.New("duplicate external id in manifest"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"duplicate external id in manifest"}`,
+		},
+		{
+			name: "reception closed",
+			body: `{"items":[{"type":"обувь","externalId":"BC-1"}]}`,
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("SetReceptionManifest", mock.Anything, "rec1", mock.Anything).
+					Return(nil, errors.New("reception is not in progress"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"reception is not in progress"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ReceptionUsecaseMock)
+			handler := NewReceptionHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodPut, "/receptions/rec1/manifest", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"receptionId": "rec1"})
+			w := httptest.NewRecorder()
+			handler.SetReceptionManifest(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzHandler_GetDiscrepancyReport(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	createdAt := time.Date(2025, 4, 11, 23, 59, 50, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		mockBehavior   func(usecase *usecaseMocks.ReceptionUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("GetDiscrepancyReport", mock.Anything, "rec1").Return(models.DiscrepancyReport{
+					ReceptionId:   "rec1",
+					CreatedAt:     createdAt,
+					ExpectedCount: 2,
+					ReceivedCount: 2,
+					MatchedCount:  2,
+					Items:         []models.DiscrepancyItem{},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"receptionId":"rec1","createdAt":"2025-04-11T23:59:50Z","expectedCount":2,` +
+				`"receivedCount":2,"matchedCount":2,"items":[]}`,
+		},
+		{
+			name: "not found",
+			mockBehavior: func(usecase *usecaseMocks.ReceptionUsecaseMock) {
+				usecase.On("GetDiscrepancyReport", mock.Anything, "rec1").
+					Return(models.DiscrepancyReport{}, errors.New("discrepancy report not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"discrepancy report not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ReceptionUsecaseMock)
+			handler := NewReceptionHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodGet, "/receptions/rec1/discrepancy_report", nil)
+			req = mux.SetURLVars(req, map[string]string{"receptionId": "rec1"})
+			w := httptest.NewRecorder()
+			handler.GetDiscrepancyReport(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
 		})
 	}
 }
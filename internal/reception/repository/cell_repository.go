@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// LockStorageCell блокирует ячейку ПВЗ по коду до конца транзакции и считает, сколько товаров в ней лежит.
+// Занятость читается отдельным запросом уже после блокировки, чтобы видеть товары, положенные параллельным сканером.
+func (r ReceptionRepository) LockStorageCell(ctx context.Context, pvzId, code string) (*models.StorageCell, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockStorageCell called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+		zap.String("code", code),
+	)
+
+	query, args, err := sq.Select("id", "pvz_id", "code", "size", "capacity").
+		From("storage_cells").
+		Where(sq.Eq{"pvz_id": pvzId, "code": code}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var cell models.StorageCell
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&cell.Id, &cell.PvzId, &cell.Code, &cell.Size, &cell.Capacity)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("cell not found")
+		}
+		logger.DBLogger.Error("failed to lock storage cell", zap.Error(err))
+		return nil, err
+	}
+
+	query, args, err = sq.Select("COUNT(*)").
+		From("products").
+		Where(sq.Eq{"cell_id": cell.Id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	if err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&cell.Occupied); err != nil {
+		logger.DBLogger.Error("failed to count products in cell", zap.Error(err))
+		return nil, err
+	}
+
+	return &cell, nil
+}
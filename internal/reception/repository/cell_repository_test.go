@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestPvzRepository_LockStorageCell(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+	repo := NewReceptionRepository(db)
+	lockQuery := `^SELECT id, pvz_id, code, size, capacity FROM storage_cells WHERE code = \$1 AND pvz_id = \$2 FOR UPDATE$`
+
+	mock.ExpectQuery(lockQuery).
+		WithArgs("A-1", "pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pvz_id", "code", "size", "capacity"}).
+			AddRow("cell1", "pvz1", "A-1", models.CELL_SIZE_M, 2))
+	mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM products WHERE cell_id = \$1$`).
+		WithArgs("cell1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	cell, err := repo.LockStorageCell(context.Background(), "pvz1", "A-1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.StorageCell{Id: "cell1", PvzId: "pvz1", Code: "A-1", Size: models.CELL_SIZE_M, Capacity: 2, Occupied: 1}, cell)
+
+	mock.ExpectQuery(lockQuery).
+		WithArgs("Z-9", "pvz1").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.LockStorageCell(context.Background(), "pvz1", "Z-9")
+	assert.EqualError(t, err, "cell not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
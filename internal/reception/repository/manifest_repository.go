@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// ReplaceManifest заменяет манифест приёмки целиком: старые позиции удаляются, новые вставляются
+// одним многострочным INSERT в той же транзакции.
+func (r ReceptionRepository) ReplaceManifest(ctx context.Context, receptionId string, items []models.ManifestItem) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("ReplaceManifest called",
+		zap.String("request_id", requestID),
+		zap.String("reception_id", receptionId),
+		zap.Int("count", len(items)),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+
+		query, args, err := sq.Delete("reception_manifest_items").
+			Where(sq.Eq{"reception_id": receptionId}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to delete manifest items", zap.Error(err))
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		insertBuilder := sq.Insert("reception_manifest_items").
+			Columns("id", "reception_id", "external_id", "type").
+			PlaceholderFormat(sq.Dollar)
+		for _, item := range items {
+			insertBuilder = insertBuilder.Values(item.Id, receptionId, item.ExternalId, item.Type)
+		}
+		query, args, err = insertBuilder.ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert manifest items", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+}
+
+// GetManifest возвращает позиции манифеста приёмки; если манифест не приложен — пустой список.
+func (r ReceptionRepository) GetManifest(ctx context.Context, receptionId string) ([]models.ManifestItem, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetManifest called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("id", "reception_id", "external_id", "type").
+		From("reception_manifest_items").
+		Where(sq.Eq{"reception_id": receptionId}).
+		OrderBy("external_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query manifest items", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	items := make([]models.ManifestItem, 0)
+	for rows.Next() {
+		var item models.ManifestItem
+		if err = rows.Scan(&item.Id, &item.ReceptionId, &item.ExternalId, &item.Type); err != nil {
+			logger.DBLogger.Error("failed to scan manifest item", zap.Error(err))
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetProductsInReception возвращает все товары приёмки в порядке сканирования.
+func (r ReceptionRepository) GetProductsInReception(ctx context.Context, receptionId string) ([]models.Product, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductsInReception called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("id", "date_time", "type", "reception_id", "seq_no", "external_id").
+		From("products").
+		Where(sq.Eq{"reception_id": receptionId}).
+		OrderBy("seq_no").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query products", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	products := make([]models.Product, 0)
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan product", zap.Error(err))
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetExternalIdsReceivedEarlier возвращает внешние идентификаторы товаров приёмки, которые раньше уже
+// принимались в другой приёмке. Внутри одной приёмки повтор невозможен — его не пропускает уникальный индекс.
+func (r ReceptionRepository) GetExternalIdsReceivedEarlier(ctx context.Context, receptionId string) (map[string]struct{}, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetExternalIdsReceivedEarlier called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("DISTINCT p.external_id").
+		From("products p").
+		Join("products earlier ON earlier.external_id = p.external_id AND earlier.reception_id <> p.reception_id").
+		Where(sq.Eq{"p.reception_id": receptionId}).
+		Where("earlier.date_time < p.date_time").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query earlier received external ids", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	externalIds := make(map[string]struct{})
+	for rows.Next() {
+		var externalId string
+		if err = rows.Scan(&externalId); err != nil {
+			logger.DBLogger.Error("failed to scan external id", zap.Error(err))
+			return nil, err
+		}
+		externalIds[externalId] = struct{}{}
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return externalIds, nil
+}
+
+// SaveDiscrepancyReport сохраняет отчёт о расхождениях. Приёмку можно переоткрыть и закрыть снова,
+// поэтому предыдущий отчёт по ней заменяется.
+func (r ReceptionRepository) SaveDiscrepancyReport(ctx context.Context, report *models.DiscrepancyReport) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("SaveDiscrepancyReport called",
+		zap.String("request_id", requestID),
+		zap.String("reception_id", report.ReceptionId),
+		zap.Int("items", len(report.Items)),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+
+		query, args, err := sq.Delete("reception_discrepancy_reports").
+			Where(sq.Eq{"reception_id": report.ReceptionId}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to delete discrepancy report", zap.Error(err))
+			return err
+		}
+
+		query, args, err = sq.Insert("reception_discrepancy_reports").
+			Columns("reception_id", "created_at", "expected_count", "received_count", "matched_count").
+			Values(report.ReceptionId, report.CreatedAt, report.ExpectedCount, report.ReceivedCount, report.MatchedCount).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert discrepancy report", zap.Error(err))
+			return err
+		}
+		if len(report.Items) == 0 {
+			return nil
+		}
+
+		insertBuilder := sq.Insert("reception_discrepancy_items").
+			Columns("reception_id", "kind", "external_id", "expected_type", "actual_type", "product_id").
+			PlaceholderFormat(sq.Dollar)
+		for _, item := range report.Items {
+			var productId interface{}
+			if item.ProductId != "" {
+				productId = item.ProductId
+			}
+			insertBuilder = insertBuilder.Values(report.ReceptionId, item.Kind, item.ExternalId,
+				item.ExpectedType, item.ActualType, productId)
+		}
+		query, args, err = insertBuilder.ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert discrepancy items", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+}
+
+func (r ReceptionRepository) GetDiscrepancyReport(ctx context.Context, receptionId string) (*models.DiscrepancyReport, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetDiscrepancyReport called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("reception_id", "created_at", "expected_count", "received_count", "matched_count").
+		From("reception_discrepancy_reports").
+		Where(sq.Eq{"reception_id": receptionId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var report models.DiscrepancyReport
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&report.ReceptionId, &report.CreatedAt, &report.ExpectedCount, &report.ReceivedCount, &report.MatchedCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("discrepancy report not found")
+		}
+		logger.DBLogger.Error("failed to scan discrepancy report", zap.Error(err))
+		return nil, err
+	}
+
+	query, args, err = sq.Select("kind", "external_id", "expected_type", "actual_type", "product_id").
+		From("reception_discrepancy_items").
+		Where(sq.Eq{"reception_id": receptionId}).
+		OrderBy("kind", "external_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query discrepancy items", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	report.Items = make([]models.DiscrepancyItem, 0)
+	for rows.Next() {
+		var item models.DiscrepancyItem
+		var productId sql.NullString
+		if err = rows.Scan(&item.Kind, &item.ExternalId, &item.ExpectedType, &item.ActualType, &productId); err != nil {
+			logger.DBLogger.Error("failed to scan discrepancy item", zap.Error(err))
+			return nil, err
+		}
+		item.ProductId = productId.String
+		report.Items = append(report.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestPvzRepository_ReplaceManifest(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	deleteQuery := `^DELETE FROM reception_manifest_items WHERE reception_id = \$1$`
+	insertQuery := `^INSERT INTO reception_manifest_items \(id,reception_id,external_id,type\) VALUES \(\$1,\$2,\$3,\$4\),\(\$5,\$6,\$7,\$8\)$`
+	items := []models.ManifestItem{
+		{Id: "m1", ExternalId: "BC-1", Type: models.CLOTHES_TYPE},
+		{Id: "m2", ExternalId: "BC-2", Type: models.BOOTS_TYPE},
+	}
+
+	tests := []struct {
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(deleteQuery).WithArgs("rec1").
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(insertQuery).
+					WithArgs("m1", "rec1", "BC-1", models.CLOTHES_TYPE, "m2", "rec1", "BC-2", models.BOOTS_TYPE).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Insert Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(deleteQuery).WithArgs("rec1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insertQuery).
+					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
+			},
+			errMsg: "insert failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				err := db.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			repo := NewReceptionRepository(db)
+			tt.mock(mock)
+
+			err = repo.ReplaceManifest(context.Background(), "rec1", items)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzRepository_GetManifest(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	query := `^SELECT id, reception_id, external_id, type FROM reception_manifest_items WHERE reception_id = \$1 ORDER BY external_id$`
+
+	mock.ExpectQuery(query).WithArgs("rec1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reception_id", "external_id", "type"}).
+			AddRow("m1", "rec1", "BC-1", models.CLOTHES_TYPE))
+	items, err := repo.GetManifest(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, []models.ManifestItem{{Id: "m1", ReceptionId: "rec1", ExternalId: "BC-1", Type: models.CLOTHES_TYPE}}, items)
+
+	mock.ExpectQuery(query).WithArgs("rec2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reception_id", "external_id", "type"}))
+	items, err = repo.GetManifest(context.Background(), "rec2")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_GetProductsInReception(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	now := time.Now()
+
+	mock.ExpectQuery(`^SELECT id, date_time, type, reception_id, seq_no, external_id FROM products WHERE reception_id = \$1 ORDER BY seq_no$`).
+		WithArgs("rec1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "seq_no", "external_id"}).
+			AddRow("p1", now, models.CLOTHES_TYPE, "rec1", int64(1), "BC-1").
+			AddRow("p2", now, models.BOOTS_TYPE, "rec1", int64(2), nil))
+
+	products, err := repo.GetProductsInReception(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, []models.Product{
+		{Id: "p1", DateTime: now, Type: models.CLOTHES_TYPE, ReceptionId: "rec1", SeqNo: 1, ExternalId: "BC-1"},
+		{Id: "p2", DateTime: now, Type: models.BOOTS_TYPE, ReceptionId: "rec1", SeqNo: 2},
+	}, products)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_GetExternalIdsReceivedEarlier(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+
+	mock.ExpectQuery(`^SELECT DISTINCT p.external_id FROM products p ` +
+		`JOIN products earlier ON earlier.external_id = p.external_id AND earlier.reception_id <> p.reception_id ` +
+		`WHERE p.reception_id = \$1 AND earlier.date_time < p.date_time$`).
+		WithArgs("rec1").
+		WillReturnRows(sqlmock.NewRows([]string{"external_id"}).AddRow("BC-1").AddRow("BC-7"))
+
+	externalIds, err := repo.GetExternalIdsReceivedEarlier(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"BC-1": {}, "BC-7": {}}, externalIds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_SaveDiscrepancyReport(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+
+	report := &models.DiscrepancyReport{
+		ReceptionId:   "rec1",
+		CreatedAt:     time.Now(),
+		ExpectedCount: 2,
+		ReceivedCount: 2,
+		MatchedCount:  1,
+		Items: []models.DiscrepancyItem{
+			{Kind: models.DISCREPANCY_UNEXPECTED, ExternalId: "BC-9", ActualType: models.CLOTHES_TYPE, ProductId: "p2"},
+			{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-2", ExpectedType: models.BOOTS_TYPE},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^DELETE FROM reception_discrepancy_reports WHERE reception_id = \$1$`).
+		WithArgs("rec1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`^INSERT INTO reception_discrepancy_reports \(reception_id,created_at,expected_count,received_count,matched_count\) VALUES \(\$1,\$2,\$3,\$4,\$5\)$`).
+		WithArgs("rec1", report.CreatedAt, 2, 2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^INSERT INTO reception_discrepancy_items \(reception_id,kind,external_id,expected_type,actual_type,product_id\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\),\(\$7,\$8,\$9,\$10,\$11,\$12\)$`).
+		WithArgs("rec1", models.DISCREPANCY_UNEXPECTED, "BC-9", "", models.CLOTHES_TYPE, "p2",
+			"rec1", models.DISCREPANCY_MISSING, "BC-2", models.BOOTS_TYPE, "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.SaveDiscrepancyReport(context.Background(), report))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_GetDiscrepancyReport(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	headerQuery := `^SELECT reception_id, created_at, expected_count, received_count, matched_count FROM reception_discrepancy_reports WHERE reception_id = \$1$`
+	itemsQuery := `^SELECT kind, external_id, expected_type, actual_type, product_id FROM reception_discrepancy_items WHERE reception_id = \$1 ORDER BY kind, external_id$`
+	createdAt := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery(headerQuery).WithArgs("rec1").
+			WillReturnRows(sqlmock.NewRows([]string{"reception_id", "created_at", "expected_count", "received_count", "matched_count"}).
+				AddRow("rec1", createdAt, 2, 1, 1))
+		mock.ExpectQuery(itemsQuery).WithArgs("rec1").
+			WillReturnRows(sqlmock.NewRows([]string{"kind", "external_id", "expected_type", "actual_type", "product_id"}).
+				AddRow(models.DISCREPANCY_MISSING, "BC-2", models.BOOTS_TYPE, "", nil))
+
+		report, err := repo.GetDiscrepancyReport(context.Background(), "rec1")
+		require.NoError(t, err)
+		assert.Equal(t, &models.DiscrepancyReport{
+			ReceptionId:   "rec1",
+			CreatedAt:     createdAt,
+			ExpectedCount: 2,
+			ReceivedCount: 1,
+			MatchedCount:  1,
+			Items: []models.DiscrepancyItem{
+				{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-2", ExpectedType: models.BOOTS_TYPE},
+			},
+		}, report)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mock.ExpectQuery(headerQuery).WithArgs("rec2").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetDiscrepancyReport(context.Background(), "rec2")
+		assert.EqualError(t, err, "discrepancy report not found")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
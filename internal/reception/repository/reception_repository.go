@@ -2,15 +2,40 @@ package repository
 
 import (
 	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
 	"avito_spring_staj_2025/internal/service/logger"
 	"avito_spring_staj_2025/internal/service/middleware"
+	"avito_spring_staj_2025/internal/service/outbox"
+	"avito_spring_staj_2025/internal/service/pvzcapacity"
 	"context"
 	"database/sql"
 	"errors"
 	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"time"
 )
 
+const (
+	uniqueViolationCode                = "23505"
+	foreignKeyViolationCode            = "23503"
+	activeReceptionPerPvzIndex         = "receptions_one_active_per_pvz"
+	productExternalIdPerReceptionIndex = "products_reception_external_id"
+	productStatusEventsProductFk       = "product_status_events_product_id_fkey"
+	transferItemsProductFk             = "transfer_items_product_id_fkey"
+	damageReportsProductFk             = "damage_reports_product_id_fkey"
+)
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode && pqErr.Constraint == constraint
+}
+
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolationCode && pqErr.Constraint == constraint
+}
+
 type ReceptionRepository struct {
 	db *sql.DB
 }
@@ -21,12 +46,18 @@ func NewReceptionRepository(db *sql.DB) ReceptionRepository {
 	}
 }
 
+// WithinTransaction выполняет fn в одной транзакции; транзакция, уже открытая выше по стеку
+// любым репозиторием, переиспользуется.
+func (r ReceptionRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return dbtx.WithinTransaction(ctx, r.db, fn)
+}
+
 func (r ReceptionRepository) CreateReception(ctx context.Context, data models.Reception) error {
 	requestID := middleware.GetRequestID(ctx)
 	logger.DBLogger.Info("CreateReception called", zap.String("request_id", requestID))
 	queryBuilder := sq.Insert("receptions").
-		Columns("id", "date_time", "pvz_id", "status").
-		Values(data.Id, data.DateTime, data.PvzId, data.Status).
+		Columns("id", "date_time", "pvz_id", "status", "outside_working_hours").
+		Values(data.Id, data.DateTime, data.PvzId, data.Status, data.OutsideWorkingHours).
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := queryBuilder.ToSql()
@@ -35,9 +66,30 @@ func (r ReceptionRepository) CreateReception(ctx context.Context, data models.Re
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, query, args...)
+	err = r.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+		if err != nil {
+			if isUniqueViolation(err, activeReceptionPerPvzIndex) {
+				logger.DBLogger.Info("active reception already exists",
+					zap.String("request_id", requestID),
+					zap.String("pvz_id", data.PvzId))
+				return errors.New("active reception already exists")
+			}
+			logger.DBLogger.Error("failed to insert reception", zap.Error(err))
+			return err
+		}
+
+		event, err := outbox.NewEvent(models.EVENT_RECEPTION_OPENED, data.PvzId, models.ReceptionOpenedPayload{
+			ReceptionId: data.Id,
+			PvzId:       data.PvzId,
+			DateTime:    data.DateTime,
+		}, data.DateTime)
+		if err != nil {
+			return err
+		}
+		return outbox.Append(ctx, dbtx.Conn(ctx, r.db), event)
+	})
 	if err != nil {
-		logger.DBLogger.Error("failed to insert reception", zap.Error(err))
 		return err
 	}
 	logger.DBLogger.Info("Reception successfully created",
@@ -60,7 +112,7 @@ func (r ReceptionRepository) GetPvzById(ctx context.Context, pvzId string) (*mod
 		return nil, err
 	}
 
-	row := r.db.QueryRowContext(ctx, query, args...)
+	row := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...)
 
 	var pvz models.Pvz
 	err = row.Scan(&pvz.Id, &pvz.RegistrationDate, &pvz.City)
@@ -82,11 +134,27 @@ func (r ReceptionRepository) GetPvzById(ctx context.Context, pvzId string) (*mod
 func (r ReceptionRepository) GetCurrentReception(ctx context.Context, pvzId string) (*models.Reception, error) {
 	requestID := middleware.GetRequestID(ctx)
 	logger.DBLogger.Info("GetCurrentReception called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+	return r.getCurrentReception(ctx, pvzId, false)
+}
+
+// LockCurrentReception читает активную приёмку с блокировкой строки до конца транзакции,
+// поэтому добавление, удаление товаров и закрытие одной приёмки выполняются строго по очереди.
+func (r ReceptionRepository) LockCurrentReception(ctx context.Context, pvzId string) (*models.Reception, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockCurrentReception called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+	return r.getCurrentReception(ctx, pvzId, true)
+}
+
+func (r ReceptionRepository) getCurrentReception(ctx context.Context, pvzId string, forUpdate bool) (*models.Reception, error) {
+	requestID := middleware.GetRequestID(ctx)
 	queryBuilder := sq.Select("id", "date_time", "pvz_id", "status").
 		From("receptions").
 		Where(sq.Eq{"pvz_id": pvzId}).
 		Where(sq.Eq{"status": models.STATUS_ACTIVE}).
 		PlaceholderFormat(sq.Dollar)
+	if forUpdate {
+		queryBuilder = queryBuilder.Suffix("FOR UPDATE")
+	}
 
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -94,7 +162,7 @@ func (r ReceptionRepository) GetCurrentReception(ctx context.Context, pvzId stri
 		return nil, err
 	}
 
-	row := r.db.QueryRowContext(ctx, query, args...)
+	row := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...)
 	var reception models.Reception
 
 	err = row.Scan(&reception.Id, &reception.DateTime, &reception.PvzId, &reception.Status)
@@ -109,44 +177,363 @@ func (r ReceptionRepository) GetCurrentReception(ctx context.Context, pvzId stri
 	return &reception, nil
 }
 
-func (r ReceptionRepository) AddProductToReception(ctx context.Context, product models.Product) error {
+// LockReceptionById блокирует строку приёмки по id (в любом статусе) до конца транзакции.
+func (r ReceptionRepository) LockReceptionById(ctx context.Context, receptionId string) (*models.Reception, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockReceptionById called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("id", "date_time", "pvz_id", "status").
+		From("receptions").
+		Where(sq.Eq{"id": receptionId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var reception models.Reception
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&reception.Id, &reception.DateTime, &reception.PvzId, &reception.Status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("reception not found")
+		}
+		logger.DBLogger.Error("failed to scan reception", zap.Error(err))
+		return nil, err
+	}
+	return &reception, nil
+}
+
+func (r ReceptionRepository) GetLastReceptionId(ctx context.Context, pvzId string) (string, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetLastReceptionId called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := sq.Select("id").
+		From("receptions").
+		Where(sq.Eq{"pvz_id": pvzId}).
+		OrderBy("date_time DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return "", err
+	}
+
+	var receptionId string
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&receptionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("reception not found")
+		}
+		logger.DBLogger.Error("failed to scan reception id", zap.Error(err))
+		return "", err
+	}
+	return receptionId, nil
+}
+
+// GetLastReopenedAt возвращает время последнего переоткрытия приёмки; если её не переоткрывали — нулевое время.
+func (r ReceptionRepository) GetLastReopenedAt(ctx context.Context, receptionId string) (time.Time, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetLastReopenedAt called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("MAX(reopened_at)").
+		From("reception_reopens").
+		Where(sq.Eq{"reception_id": receptionId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return time.Time{}, err
+	}
+
+	var reopenedAt sql.NullTime
+	if err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&reopenedAt); err != nil {
+		logger.DBLogger.Error("failed to scan last reopen time", zap.Error(err))
+		return time.Time{}, err
+	}
+	return reopenedAt.Time, nil
+}
+
+// ReopenReception возвращает приёмке статус in_progress и пишет запись в журнал повторных открытий.
+func (r ReceptionRepository) ReopenReception(ctx context.Context, reopen models.ReceptionReopen) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("ReopenReception called",
+		zap.String("request_id", requestID),
+		zap.String("reception_id", reopen.ReceptionId),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+
+		query, args, err := sq.Update("receptions").
+			Set("status", models.STATUS_ACTIVE).
+			Set("auto_closed", false).
+			Set("close_reason", nil).
+			Where(sq.Eq{"id": reopen.ReceptionId}).
+			Where(sq.Eq{"status": models.STATUS_CLOSED}).
+			Suffix("RETURNING pvz_id").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		var pvzId string
+		if err = tx.QueryRowContext(ctx, query, args...).Scan(&pvzId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("reception is not closed")
+			}
+			if isUniqueViolation(err, activeReceptionPerPvzIndex) {
+				return errors.New("active reception already exists")
+			}
+			logger.DBLogger.Error("failed to reopen reception", zap.Error(err))
+			return err
+		}
+
+		query, args, err = sq.Insert("reception_reopens").
+			Columns("id", "reception_id", "reopened_by", "reopened_at", "reason").
+			Values(reopen.Id, reopen.ReceptionId, reopen.ReopenedBy, reopen.ReopenedAt, reopen.Reason).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert reception reopen", zap.Error(err))
+			return err
+		}
+
+		event, err := outbox.NewEvent(models.EVENT_RECEPTION_REOPENED, pvzId, models.ReceptionReopenedPayload{
+			ReceptionId: reopen.ReceptionId,
+			PvzId:       pvzId,
+			ReopenedBy:  reopen.ReopenedBy,
+			Reason:      reopen.Reason,
+			ReopenedAt:  reopen.ReopenedAt,
+		}, reopen.ReopenedAt)
+		if err != nil {
+			return err
+		}
+		if err = outbox.Append(ctx, tx, event); err != nil {
+			return err
+		}
+
+		logger.DBLogger.Info("Reception successfully reopened",
+			zap.String("request_id", requestID),
+			zap.String("reception_id", reopen.ReceptionId),
+		)
+		return nil
+	})
+}
+
+func (r ReceptionRepository) AddProductToReception(ctx context.Context, pvzId string, product *models.Product) error {
 	requestID := middleware.GetRequestID(ctx)
 	logger.DBLogger.Info("AddProductToReception called", zap.String("request_id", requestID))
-	queryBuilder := sq.Insert("products").
-		Columns("id", "date_time", "type", "reception_id").
-		Values(product.Id, product.DateTime, product.Type, product.ReceptionId).
-		PlaceholderFormat(sq.Dollar)
 
-	query, args, err := queryBuilder.ToSql()
+	var occupancy int
+	var capacity sql.NullInt64
+	err := r.WithinTransaction(ctx, func(ctx context.Context) error {
+		return r.addProductToReception(ctx, pvzId, product, &occupancy, &capacity)
+	})
+	if err != nil {
+		return err
+	}
+
+	product.OverCapacity = capacity.Valid && int64(occupancy) > capacity.Int64
+	logger.DBLogger.Info("Product successfully added",
+		zap.String("request_id", requestID),
+		zap.String("product_id", product.Id))
+
+	return nil
+}
+
+func (r ReceptionRepository) addProductToReception(ctx context.Context, pvzId string, product *models.Product, occupancy *int, capacity *sql.NullInt64) error {
+	tx := dbtx.Conn(ctx, r.db)
+
+	var err error
+	*occupancy, *capacity, err = pvzcapacity.Reserve(ctx, tx, pvzId, 1)
+	if err != nil {
+		return err
+	}
+
+	product.SeqNo, err = r.reserveSeqNumbers(ctx, product.ReceptionId, 1)
+	if err != nil {
+		return err
+	}
+
+	var externalId interface{}
+	if product.ExternalId != "" {
+		externalId = product.ExternalId
+	}
+	columns := []string{"id", "date_time", "type", "reception_id", "seq_no", "external_id"}
+	values := []interface{}{product.Id, product.DateTime, product.Type, product.ReceptionId, product.SeqNo, externalId}
+	if product.CellId != "" {
+		columns = append(columns, "cell_id")
+		values = append(values, product.CellId)
+	}
+	query, args, err := sq.Insert("products").
+		Columns(columns...).
+		Values(values...).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
 	if err != nil {
 		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
 		return err
 	}
-	_, err = r.db.ExecContext(ctx, query, args...)
+	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
+		if isUniqueViolation(err, productExternalIdPerReceptionIndex) {
+			return errors.New("product already scanned")
+		}
 		logger.DBLogger.Error("failed to insert product", zap.Error(err))
 		return err
 	}
-	logger.DBLogger.Info("Product successfully added",
+
+	event, err := newProductAddedEvent(pvzId, product)
+	if err != nil {
+		return err
+	}
+	return outbox.Append(ctx, tx, event)
+}
+
+func newProductAddedEvent(pvzId string, product *models.Product) (models.DomainEvent, error) {
+	return outbox.NewEvent(models.EVENT_PRODUCT_ADDED, pvzId, models.ProductAddedPayload{
+		ProductId:   product.Id,
+		ReceptionId: product.ReceptionId,
+		PvzId:       pvzId,
+		Type:        product.Type,
+		SeqNo:       product.SeqNo,
+		ExternalId:  product.ExternalId,
+		DateTime:    product.DateTime,
+	}, product.DateTime)
+}
+
+// reserveSeqNumbers выдаёт count следующих порядковых номеров товаров приёмки и возвращает первый из них.
+// Строка приёмки к этому моменту уже заблокирована вызывающим, так что номера не пересекаются.
+func (r ReceptionRepository) reserveSeqNumbers(ctx context.Context, receptionId string, count int) (int64, error) {
+	query, args, err := sq.Update("receptions").
+		Set("last_seq_no", sq.Expr("last_seq_no + ?", count)).
+		Where(sq.Eq{"id": receptionId}).
+		Suffix("RETURNING last_seq_no").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return 0, err
+	}
+
+	var lastSeqNo int64
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&lastSeqNo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("reception not found")
+		}
+		logger.DBLogger.Error("failed to reserve product sequence numbers", zap.Error(err))
+		return 0, err
+	}
+	return lastSeqNo - int64(count) + 1, nil
+}
+
+// AddProductsToReception принимает пачку товаров одним многострочным INSERT.
+// Вместимость проверяется для всей пачки сразу: либо помещаются все товары, либо ни один.
+func (r ReceptionRepository) AddProductsToReception(ctx context.Context, pvzId string, products []models.Product) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("AddProductsToReception called",
 		zap.String("request_id", requestID),
-		zap.String("product_id", product.Id))
+		zap.String("pvz_id", pvzId),
+		zap.Int("count", len(products)),
+	)
+	if len(products) == 0 {
+		return nil
+	}
+
+	var occupancy int
+	var capacity sql.NullInt64
+	err := r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		count := len(products)
+
+		var err error
+		occupancy, capacity, err = pvzcapacity.Reserve(ctx, tx, pvzId, count)
+		if err != nil {
+			return err
+		}
+
+		firstSeqNo, err := r.reserveSeqNumbers(ctx, products[0].ReceptionId, count)
+		if err != nil {
+			return err
+		}
+
+		insertBuilder := sq.Insert("products").
+			Columns("id", "date_time", "type", "reception_id", "seq_no", "external_id").
+			PlaceholderFormat(sq.Dollar)
+		for i := range products {
+			products[i].SeqNo = firstSeqNo + int64(i)
+			var externalId interface{}
+			if products[i].ExternalId != "" {
+				externalId = products[i].ExternalId
+			}
+			insertBuilder = insertBuilder.Values(products[i].Id, products[i].DateTime, products[i].Type,
+				products[i].ReceptionId, products[i].SeqNo, externalId)
+		}
+		query, args, err := insertBuilder.ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			if isUniqueViolation(err, productExternalIdPerReceptionIndex) {
+				return errors.New("product already scanned")
+			}
+			logger.DBLogger.Error("failed to insert products", zap.Error(err))
+			return err
+		}
+
+		events := make([]models.DomainEvent, 0, count)
+		for i := range products {
+			event, err := newProductAddedEvent(pvzId, &products[i])
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return outbox.Append(ctx, tx, events...)
+	})
+	if err != nil {
+		return err
+	}
+
+	if capacity.Valid {
+		before := occupancy - len(products)
+		for i := range products {
+			products[i].OverCapacity = int64(before+i+1) > capacity.Int64
+		}
+	}
+	logger.DBLogger.Info("Products successfully added",
+		zap.String("request_id", requestID),
+		zap.Int("count", len(products)))
 
 	return nil
 }
 
-func (r ReceptionRepository) GetLastProductInReception(ctx context.Context, receptionId string) (*models.Product, error) {
+// GetLastProductsInReception возвращает count последних по порядковому номеру товаров приёмки, начиная с самого свежего.
+func (r ReceptionRepository) GetLastProductsInReception(ctx context.Context, receptionId string, count int) ([]models.Product, error) {
 	requestID := middleware.GetRequestID(ctx)
-	logger.DBLogger.Info("GetLastProductInReception called",
+	logger.DBLogger.Info("GetLastProductsInReception called",
 		zap.String("request_id", requestID),
 		zap.String("reception_id", receptionId),
+		zap.Int("count", count),
 	)
 
 	queryBuilder := sq.
-		Select("id", "date_time", "type", "reception_id").
+		Select("id", "date_time", "type", "reception_id", "seq_no", "external_id").
 		From("products").
 		Where(sq.Eq{"reception_id": receptionId}).
-		OrderBy("date_time DESC").
-		Limit(1).
+		OrderBy("seq_no DESC").
+		Limit(uint64(count)).
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := queryBuilder.ToSql()
@@ -155,20 +542,64 @@ func (r ReceptionRepository) GetLastProductInReception(ctx context.Context, rece
 		return nil, err
 	}
 
-	var product models.Product
-	err = r.db.QueryRowContext(ctx, query, args...).Scan(
-		&product.Id,
-		&product.DateTime,
-		&product.Type,
-		&product.ReceptionId,
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query products", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var products []models.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan product", zap.Error(err))
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	if len(products) == 0 {
+		logger.DBLogger.Info("no product found for reception",
+			zap.String("request_id", requestID),
+			zap.String("reception_id", receptionId),
+		)
+		return nil, errors.New("no products in reception")
+	}
+
+	return products, nil
+}
+
+func (r ReceptionRepository) GetProductById(ctx context.Context, productId string) (*models.Product, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductById called",
+		zap.String("request_id", requestID),
+		zap.String("product_id", productId),
 	)
+
+	query, args, err := sq.
+		Select("id", "date_time", "type", "reception_id", "seq_no", "external_id").
+		From("products").
+		Where(sq.Eq{"id": productId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	product, err := scanProduct(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			logger.DBLogger.Info("no product found for reception",
-				zap.String("request_id", requestID),
-				zap.String("reception_id", receptionId),
-			)
-			return nil, errors.New("no products in reception")
+			return nil, errors.New("product not found")
 		}
 		logger.DBLogger.Error("failed to scan product", zap.Error(err))
 		return nil, err
@@ -177,41 +608,204 @@ func (r ReceptionRepository) GetLastProductInReception(ctx context.Context, rece
 	return &product, nil
 }
 
-func (r ReceptionRepository) DeleteProductById(ctx context.Context, productId string) error {
+// GetProductsByExternalId ищет товары по штрихкоду или номеру заказа во всех приёмках, сначала самые свежие.
+func (r ReceptionRepository) GetProductsByExternalId(ctx context.Context, externalId string) ([]models.ProductLookup, error) {
 	requestID := middleware.GetRequestID(ctx)
-	logger.DBLogger.Info("DeleteProductByID called",
+	logger.DBLogger.Info("GetProductsByExternalId called",
 		zap.String("request_id", requestID),
-		zap.String("product_id", productId),
+		zap.String("external_id", externalId),
 	)
 
-	queryBuilder := sq.
-		Delete("products").
-		Where(sq.Eq{"id": productId}).
-		PlaceholderFormat(sq.Dollar)
+	query, args, err := sq.
+		Select("pr.id", "pr.date_time", "pr.type", "pr.reception_id", "pr.seq_no", "pr.external_id", "r.pvz_id", "r.status").
+		From("products pr").
+		Join("receptions r ON r.id = pr.reception_id").
+		Where(sq.Eq{"pr.external_id": externalId}).
+		OrderBy("pr.date_time DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
 
-	query, args, err := queryBuilder.ToSql()
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query products", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	lookups := []models.ProductLookup{}
+	for rows.Next() {
+		var lookup models.ProductLookup
+		var productExternalId sql.NullString
+		err := rows.Scan(
+			&lookup.Product.Id,
+			&lookup.Product.DateTime,
+			&lookup.Product.Type,
+			&lookup.Product.ReceptionId,
+			&lookup.Product.SeqNo,
+			&productExternalId,
+			&lookup.PvzId,
+			&lookup.ReceptionStatus,
+		)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan product", zap.Error(err))
+			return nil, err
+		}
+		lookup.Product.ExternalId = productExternalId.String
+		lookups = append(lookups, lookup)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return lookups, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row rowScanner) (models.Product, error) {
+	var product models.Product
+	var externalId sql.NullString
+	err := row.Scan(
+		&product.Id,
+		&product.DateTime,
+		&product.Type,
+		&product.ReceptionId,
+		&product.SeqNo,
+		&externalId,
+	)
+	product.ExternalId = externalId.String
+	return product, err
+}
+
+// DeleteProducts удаляет товары одной приёмки, уменьшает заполненность ПВЗ и пишет журнал удалений.
+// Если хотя бы одного товара уже нет, транзакция откатывается целиком.
+func (r ReceptionRepository) DeleteProducts(ctx context.Context, deletions []models.ProductDeletion) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("DeleteProducts called",
+		zap.String("request_id", requestID),
+		zap.Int("count", len(deletions)),
+	)
+	if len(deletions) == 0 {
+		return nil
+	}
+
+	err := r.WithinTransaction(ctx, func(ctx context.Context) error {
+		return r.deleteProducts(ctx, deletions)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.DBLogger.Info("Products deleted successfully",
+		zap.String("request_id", requestID),
+		zap.Int("count", len(deletions)),
+	)
+
+	return nil
+}
+
+func (r ReceptionRepository) deleteProducts(ctx context.Context, deletions []models.ProductDeletion) error {
+	tx := dbtx.Conn(ctx, r.db)
+
+	productIds := make([]string, 0, len(deletions))
+	for _, deletion := range deletions {
+		productIds = append(productIds, deletion.ProductId)
+	}
+
+	query, args, err := sq.
+		Delete("products").
+		Where(sq.Eq{"id": productIds}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
 	if err != nil {
 		logger.DBLogger.Error("failed to build delete SQL", zap.Error(err))
 		return err
 	}
 
-	result, err := r.db.ExecContext(ctx, query, args...)
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
+		// У товара из переоткрытой приёмки уже может быть история выдачи, а в активную приёмку
+		// товар мог приехать перемещением с другого ПВЗ — такие товары удалять нельзя.
+		if isForeignKeyViolation(err, productStatusEventsProductFk) || isForeignKeyViolation(err, transferItemsProductFk) {
+			return errors.New("product has already left the reception")
+		}
+		// На товар составлен акт о повреждении: акт должен остаться вместе с товаром.
+		if isForeignKeyViolation(err, damageReportsProductFk) {
+			return errors.New("product has damage reports")
+		}
 		logger.DBLogger.Error("failed to execute delete", zap.Error(err))
 		return err
 	}
-
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected != int64(len(deletions)) {
 		return errors.New("product not found or already deleted")
 	}
 
-	logger.DBLogger.Info("Product deleted successfully",
-		zap.String("request_id", requestID),
-		zap.String("product_id", productId),
-	)
+	query, args, err = sq.Update("pvzs").
+		Set("occupancy", sq.Expr("GREATEST(occupancy - ?, 0)", len(deletions))).
+		Where(sq.Expr("id = (SELECT pvz_id FROM receptions WHERE id = ?)", deletions[0].ReceptionId)).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+	var pvzId string
+	if err = tx.QueryRowContext(ctx, query, args...).Scan(&pvzId); err != nil {
+		logger.DBLogger.Error("failed to update pvz occupancy", zap.Error(err))
+		return err
+	}
 
-	return nil
+	insertBuilder := sq.Insert("product_deletions").
+		Columns("id", "product_id", "reception_id", "seq_no", "type", "external_id", "kind", "reason", "deleted_by", "deleted_at").
+		PlaceholderFormat(sq.Dollar)
+	for _, deletion := range deletions {
+		var externalId interface{}
+		if deletion.ExternalId != "" {
+			externalId = deletion.ExternalId
+		}
+		insertBuilder = insertBuilder.Values(deletion.Id, deletion.ProductId, deletion.ReceptionId, deletion.SeqNo,
+			deletion.Type, externalId, deletion.Kind, deletion.Reason, deletion.DeletedBy, deletion.DeletedAt)
+	}
+	query, args, err = insertBuilder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to insert product deletions", zap.Error(err))
+		return err
+	}
+
+	events := make([]models.DomainEvent, 0, len(deletions))
+	for _, deletion := range deletions {
+		event, err := outbox.NewEvent(models.EVENT_PRODUCT_DELETED, pvzId, models.ProductDeletedPayload{
+			ProductId:   deletion.ProductId,
+			ReceptionId: deletion.ReceptionId,
+			PvzId:       pvzId,
+			Type:        deletion.Type,
+			SeqNo:       deletion.SeqNo,
+			Kind:        deletion.Kind,
+			Reason:      deletion.Reason,
+			DeletedAt:   deletion.DeletedAt,
+		}, deletion.DeletedAt)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	return outbox.Append(ctx, tx, events...)
 }
 
 func (r ReceptionRepository) CloseReception(ctx context.Context, reception *models.Reception) error {
@@ -225,6 +819,7 @@ func (r ReceptionRepository) CloseReception(ctx context.Context, reception *mode
 		Update("receptions").
 		Set("status", models.STATUS_CLOSED).
 		Where(sq.Eq{"id": reception.Id}).
+		Where(sq.Eq{"status": models.STATUS_ACTIVE}).
 		PlaceholderFormat(sq.Dollar)
 
 	query, args, err := queryBuilder.ToSql()
@@ -233,9 +828,18 @@ func (r ReceptionRepository) CloseReception(ctx context.Context, reception *mode
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, query, args...)
+	err = r.WithinTransaction(ctx, func(ctx context.Context) error {
+		result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+		if err != nil {
+			logger.DBLogger.Error("failed to update reception status", zap.Error(err))
+			return err
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return errors.New("no active reception")
+		}
+		return r.appendReceptionClosedEvent(ctx, reception, false, "")
+	})
 	if err != nil {
-		logger.DBLogger.Error("failed to update reception status", zap.Error(err))
 		return err
 	}
 
@@ -248,3 +852,139 @@ func (r ReceptionRepository) CloseReception(ctx context.Context, reception *mode
 
 	return nil
 }
+
+// receptionLastActivity — время последней активности приёмки r: создание, добавление или удаление товара,
+// переоткрытие и приём перемещения. Товар, приехавший перемещением, сохраняет своё время сканирования,
+// поэтому приём перемещения учитывается по transfers.accepted_at.
+const receptionLastActivity = "GREATEST(r.date_time, " +
+	"(SELECT MAX(p.date_time) FROM products p WHERE p.reception_id = r.id), " +
+	"(SELECT MAX(d.deleted_at) FROM product_deletions d WHERE d.reception_id = r.id), " +
+	"(SELECT MAX(ro.reopened_at) FROM reception_reopens ro WHERE ro.reception_id = r.id), " +
+	"(SELECT MAX(t.accepted_at) FROM transfers t WHERE t.reception_id = r.id))"
+
+// GetStaleReceptions возвращает активные приёмки, в которых не было активности начиная с before.
+func (r ReceptionRepository) GetStaleReceptions(ctx context.Context, before time.Time) ([]models.Reception, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetStaleReceptions called", zap.String("request_id", requestID), zap.Time("before", before))
+
+	query, args, err := sq.Select("r.id", "r.date_time", "r.pvz_id", "r.status").
+		From("receptions r").
+		Where(sq.Eq{"r.status": models.STATUS_ACTIVE}).
+		Where(sq.Expr(receptionLastActivity+" < ?", before)).
+		OrderBy("r.date_time").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query stale receptions", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	receptions := make([]models.Reception, 0)
+	for rows.Next() {
+		var reception models.Reception
+		if err = rows.Scan(&reception.Id, &reception.DateTime, &reception.PvzId, &reception.Status); err != nil {
+			logger.DBLogger.Error("failed to scan reception", zap.Error(err))
+			return nil, err
+		}
+		receptions = append(receptions, reception)
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("failed to iterate stale receptions", zap.Error(err))
+		return nil, err
+	}
+	return receptions, nil
+}
+
+// GetReceptionLastActivity возвращает время последней активности приёмки (см. receptionLastActivity).
+func (r ReceptionRepository) GetReceptionLastActivity(ctx context.Context, receptionId string) (time.Time, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetReceptionLastActivity called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select(receptionLastActivity).
+		From("receptions r").
+		Where(sq.Eq{"r.id": receptionId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return time.Time{}, err
+	}
+
+	var lastActivity time.Time
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&lastActivity)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, errors.New("reception not found")
+		}
+		logger.DBLogger.Error("failed to scan last activity", zap.Error(err))
+		return time.Time{}, err
+	}
+	return lastActivity, nil
+}
+
+// AutoCloseReception закрывает приёмку от имени фонового обработчика, помечая её
+// как закрытую автоматически и сохраняя причину.
+func (r ReceptionRepository) AutoCloseReception(ctx context.Context, reception *models.Reception, reason string) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("AutoCloseReception called",
+		zap.String("request_id", requestID),
+		zap.String("reception_id", reception.Id),
+	)
+
+	query, args, err := sq.Update("receptions").
+		Set("status", models.STATUS_CLOSED).
+		Set("auto_closed", true).
+		Set("close_reason", reason).
+		Where(sq.Eq{"id": reception.Id}).
+		Where(sq.Eq{"status": models.STATUS_ACTIVE}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build update SQL", zap.Error(err))
+		return err
+	}
+
+	err = r.WithinTransaction(ctx, func(ctx context.Context) error {
+		result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+		if err != nil {
+			logger.DBLogger.Error("failed to auto-close reception", zap.Error(err))
+			return err
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return errors.New("no active reception")
+		}
+		return r.appendReceptionClosedEvent(ctx, reception, true, reason)
+	})
+	if err != nil {
+		return err
+	}
+
+	reception.Status = models.STATUS_CLOSED
+	reception.AutoClosed = true
+	reception.CloseReason = reason
+	return nil
+}
+
+func (r ReceptionRepository) appendReceptionClosedEvent(ctx context.Context, reception *models.Reception, autoClosed bool, reason string) error {
+	closedAt := time.Now()
+	event, err := outbox.NewEvent(models.EVENT_RECEPTION_CLOSED, reception.PvzId, models.ReceptionClosedPayload{
+		ReceptionId: reception.Id,
+		PvzId:       reception.PvzId,
+		AutoClosed:  autoClosed,
+		CloseReason: reason,
+		ClosedAt:    closedAt,
+	}, closedAt)
+	if err != nil {
+		return err
+	}
+	return outbox.Append(ctx, dbtx.Conn(ctx, r.db), event)
+}
@@ -6,8 +6,10 @@ import (
 	"avito_spring_staj_2025/internal/service/middleware"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -34,9 +36,12 @@ func TestPvzRepository_CreateReception(t *testing.T) {
 				Status:   "ACTIVE",
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT INTO receptions \(id,date_time,pvz_id,status\) VALUES \(\$1,\$2,\$3,\$4\)`).
-					WithArgs("r1", sqlmock.AnyArg(), "pvz1", "ACTIVE").
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO receptions \(id,date_time,pvz_id,status,outside_working_hours\) VALUES \(\$1,\$2,\$3,\$4,\$5\)`).
+					WithArgs("r1", sqlmock.AnyArg(), "pvz1", "ACTIVE", false).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_RECEPTION_OPENED)
+				mock.ExpectCommit()
 			},
 			expectedErr: "",
 		},
@@ -49,12 +54,31 @@ func TestPvzRepository_CreateReception(t *testing.T) {
 				Status:   "ACTIVE",
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT INTO receptions \(id,date_time,pvz_id,status\) VALUES \(\$1,\$2,\$3,\$4\)`).
-					WithArgs("r2", sqlmock.AnyArg(), "pvz2", "ACTIVE").
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO receptions \(id,date_time,pvz_id,status,outside_working_hours\) VALUES \(\$1,\$2,\$3,\$4,\$5\)`).
+					WithArgs("r2", sqlmock.AnyArg(), "pvz2", "ACTIVE", false).
 					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
 			},
 			expectedErr: "insert failed",
 		},
+		{
+			name: "Active Reception Already Exists",
+			data: models.Reception{
+				Id:       "r3",
+				DateTime: time.Now(),
+				PvzId:    "pvz3",
+				Status:   "ACTIVE",
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO receptions`).
+					WithArgs("r3", sqlmock.AnyArg(), "pvz3", "ACTIVE", false).
+					WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: activeReceptionPerPvzIndex})
+				mock.ExpectRollback()
+			},
+			expectedErr: "active reception already exists",
+		},
 	}
 
 	for _, tt := range tests {
@@ -254,10 +278,11 @@ func TestPvzRepository_AddProductToReception(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name        string
-		product     models.Product
-		mock        func(sqlmock.Sqlmock)
-		expectedErr string
+		name                 string
+		product              models.Product
+		mock                 func(sqlmock.Sqlmock)
+		expectedErr          string
+		expectedOverCapacity bool
 	}{
 		{
 			name: "Success",
@@ -268,12 +293,81 @@ func TestPvzRepository_AddProductToReception(t *testing.T) {
 				ReceptionId: "rec1",
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT INTO products \(id,date_time,type,reception_id\) VALUES \(\$1,\$2,\$3,\$4\)`).
-					WithArgs("prod1", sqlmock.AnyArg(), "type1", "rec1").
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1 WHERE id = \$2 AND \(capacity IS NULL OR occupancy \+ \$3 <= capacity OR capacity_policy = \$4\) RETURNING occupancy, capacity`).
+					WithArgs(1, "pvz1", 1, models.CAPACITY_POLICY_WARN).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(5, 10))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no = last_seq_no \+ \$1 WHERE id = \$2 RETURNING last_seq_no`).
+					WithArgs(1, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(5)))
+				mock.ExpectExec(`INSERT INTO products \(id,date_time,type,reception_id,seq_no,external_id\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\)`).
+					WithArgs("prod1", sqlmock.AnyArg(), "type1", "rec1", int64(5), nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_PRODUCT_ADDED)
+				mock.ExpectCommit()
 			},
 			expectedErr: "",
 		},
+		{
+			name: "Success With Cell",
+			product: models.Product{
+				Id:          "prod5",
+				DateTime:    time.Now(),
+				Type:        "type1",
+				ReceptionId: "rec1",
+				CellId:      "cell1",
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(1, nil))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no`).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(3)))
+				mock.ExpectExec(`INSERT INTO products \(id,date_time,type,reception_id,seq_no,external_id,cell_id\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\)`).
+					WithArgs("prod5", sqlmock.AnyArg(), "type1", "rec1", int64(3), nil, "cell1").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_PRODUCT_ADDED)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Over Capacity With Warn Policy",
+			product: models.Product{
+				Id:          "prod3",
+				DateTime:    time.Now(),
+				Type:        "type1",
+				ReceptionId: "rec1",
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(11, 10))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no = last_seq_no \+ \$1 WHERE id = \$2 RETURNING last_seq_no`).
+					WithArgs(1, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(5)))
+				mock.ExpectExec(`INSERT INTO products`).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_PRODUCT_ADDED)
+				mock.ExpectCommit()
+			},
+			expectedOverCapacity: true,
+		},
+		{
+			name: "Capacity Exceeded",
+			product: models.Product{
+				Id:          "prod4",
+				DateTime:    time.Now(),
+				Type:        "type1",
+				ReceptionId: "rec1",
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: "pvz capacity exceeded",
+		},
 		{
 			name: "Database Error",
 			product: models.Product{
@@ -283,12 +377,41 @@ func TestPvzRepository_AddProductToReception(t *testing.T) {
 				ReceptionId: "rec2",
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`INSERT INTO products \(id,date_time,type,reception_id\) VALUES \(\$1,\$2,\$3,\$4\)`).
-					WithArgs("prod2", sqlmock.AnyArg(), "type2", "rec2").
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(1, nil))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no = last_seq_no \+ \$1 WHERE id = \$2 RETURNING last_seq_no`).
+					WithArgs(1, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(5)))
+				mock.ExpectExec(`INSERT INTO products \(id,date_time,type,reception_id,seq_no,external_id\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\)`).
+					WithArgs("prod2", sqlmock.AnyArg(), "type2", "rec2", int64(5), nil).
 					WillReturnError(errors.New("database error"))
+				mock.ExpectRollback()
 			},
 			expectedErr: "database error",
 		},
+		{
+			name: "Duplicate Barcode In Reception",
+			product: models.Product{
+				Id:          "prod3",
+				DateTime:    time.Now(),
+				Type:        "type1",
+				ReceptionId: "rec1",
+				ExternalId:  "BC-1",
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(1, nil))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no`).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(2)))
+				mock.ExpectExec(`INSERT INTO products`).
+					WithArgs("prod3", sqlmock.AnyArg(), "type1", "rec1", int64(2), "BC-1").
+					WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: productExternalIdPerReceptionIndex})
+				mock.ExpectRollback()
+			},
+			expectedErr: "product already scanned",
+		},
 	}
 
 	for _, tt := range tests {
@@ -305,13 +428,14 @@ func TestPvzRepository_AddProductToReception(t *testing.T) {
 			repo := NewReceptionRepository(db)
 			tt.mock(mock)
 
-			err = repo.AddProductToReception(ctx, tt.product)
+			err = repo.AddProductToReception(ctx, "pvz1", &tt.product)
 
 			if tt.expectedErr != "" {
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedErr)
 			} else {
 				require.NoError(t, err)
+				assert.Equal(t, tt.expectedOverCapacity, tt.product.OverCapacity)
 			}
 
 			assert.NoError(t, mock.ExpectationsWereMet())
@@ -319,45 +443,160 @@ func TestPvzRepository_AddProductToReception(t *testing.T) {
 	}
 }
 
-func TestPvzRepository_GetLastProductInReception(t *testing.T) {
+func TestPvzRepository_AddProductsToReception(t *testing.T) {
 	logger.DBLogger = zap.NewNop()
 	ctx := context.Background()
+	now := time.Now()
+
+	newProducts := func() []models.Product {
+		return []models.Product{
+			{Id: "prod1", DateTime: now, Type: models.CLOTHES_TYPE, ReceptionId: "rec1", ExternalId: "barcode-1"},
+			{Id: "prod2", DateTime: now.Add(time.Microsecond), Type: models.BOOTS_TYPE, ReceptionId: "rec1"},
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		mock                 func(sqlmock.Sqlmock)
+		expectedErr          string
+		expectedOverCapacity []bool
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1 WHERE id = \$2 AND \(capacity IS NULL OR occupancy \+ \$3 <= capacity OR capacity_policy = \$4\) RETURNING occupancy, capacity`).
+					WithArgs(2, "pvz1", 2, models.CAPACITY_POLICY_WARN).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(7, nil))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no = last_seq_no \+ \$1 WHERE id = \$2 RETURNING last_seq_no`).
+					WithArgs(2, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(2)))
+				mock.ExpectExec(`INSERT INTO products \(id,date_time,type,reception_id,seq_no,external_id\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\),\(\$7,\$8,\$9,\$10,\$11,\$12\)`).
+					WithArgs("prod1", sqlmock.AnyArg(), models.CLOTHES_TYPE, "rec1", int64(1), "barcode-1",
+						"prod2", sqlmock.AnyArg(), models.BOOTS_TYPE, "rec1", int64(2), nil).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_PRODUCT_ADDED, models.EVENT_PRODUCT_ADDED)
+				mock.ExpectCommit()
+			},
+			expectedOverCapacity: []bool{false, false},
+		},
+		{
+			name: "Partially Over Capacity With Warn Policy",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(11, 10))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no = last_seq_no \+ \$1 WHERE id = \$2 RETURNING last_seq_no`).
+					WithArgs(2, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(2)))
+				mock.ExpectExec(`INSERT INTO products`).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_PRODUCT_ADDED, models.EVENT_PRODUCT_ADDED)
+				mock.ExpectCommit()
+			},
+			expectedOverCapacity: []bool{false, true},
+		},
+		{
+			name: "Capacity Exceeded",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: "pvz capacity exceeded",
+		},
+		{
+			name: "Insert Error Rolls Back",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+					WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).AddRow(2, nil))
+				mock.ExpectQuery(`UPDATE receptions SET last_seq_no = last_seq_no \+ \$1 WHERE id = \$2 RETURNING last_seq_no`).
+					WithArgs(2, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(2)))
+				mock.ExpectExec(`INSERT INTO products`).
+					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
+			},
+			expectedErr: "insert failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			repo := NewReceptionRepository(db)
+			tt.mock(mock)
+
+			products := newProducts()
+			err = repo.AddProductsToReception(ctx, "pvz1", products)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				for i, product := range products {
+					assert.Equal(t, tt.expectedOverCapacity[i], product.OverCapacity)
+					assert.Equal(t, int64(i+1), product.SeqNo)
+				}
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzRepository_GetLastProductsInReception(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+	query := `^SELECT id, date_time, type, reception_id, seq_no, external_id FROM products WHERE reception_id = \$1 ORDER BY seq_no DESC LIMIT 2$`
 
 	tests := []struct {
 		name        string
 		receptionId string
 		mock        func(sqlmock.Sqlmock)
-		expected    *models.Product
+		expected    []models.Product
 		expectedErr string
 	}{
 		{
 			name:        "Success",
 			receptionId: "rec1",
 			mock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id"}).
-					AddRow("prod1", time.Now(), "type1", "rec1")
-				mock.ExpectQuery(`SELECT id, date_time, type, reception_id FROM products WHERE reception_id = \$1 ORDER BY date_time DESC LIMIT 1`).
+				rows := sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "seq_no", "external_id"}).
+					AddRow("prod3", time.Time{}, "type1", "rec1", int64(3), "BC-3").
+					AddRow("prod2", time.Time{}, "type1", "rec1", int64(2), nil)
+				mock.ExpectQuery(query).
 					WithArgs("rec1").
 					WillReturnRows(rows)
 			},
-			expected: &models.Product{
-				Id:          "prod1",
-				Type:        "type1",
-				ReceptionId: "rec1",
+			expected: []models.Product{
+				{Id: "prod3", Type: "type1", ReceptionId: "rec1", SeqNo: 3, ExternalId: "BC-3"},
+				{Id: "prod2", Type: "type1", ReceptionId: "rec1", SeqNo: 2},
 			},
-			expectedErr: "",
 		},
 		{
 			name:        "No Products",
 			receptionId: "rec2",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, date_time, type, reception_id FROM products WHERE reception_id = \$1 ORDER BY date_time DESC LIMIT 1`).
+				mock.ExpectQuery(query).
 					WithArgs("rec2").
-					WillReturnError(sql.ErrNoRows)
+					WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "seq_no", "external_id"}))
 			},
-			expected:    nil,
 			expectedErr: "no products in reception",
 		},
+		{
+			name:        "Query Error",
+			receptionId: "rec3",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("rec3").
+					WillReturnError(errors.New("db error"))
+			},
+			expectedErr: "db error",
+		},
 	}
 
 	for _, tt := range tests {
@@ -374,17 +613,15 @@ func TestPvzRepository_GetLastProductInReception(t *testing.T) {
 			repo := NewReceptionRepository(db)
 			tt.mock(mock)
 
-			product, err := repo.GetLastProductInReception(ctx, tt.receptionId)
+			products, err := repo.GetLastProductsInReception(ctx, tt.receptionId, 2)
 
 			if tt.expectedErr != "" {
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedErr)
-				assert.Nil(t, product)
+				assert.Nil(t, products)
 			} else {
 				require.NoError(t, err)
-				assert.Equal(t, tt.expected.Id, product.Id)
-				assert.Equal(t, tt.expected.Type, product.Type)
-				assert.Equal(t, tt.expected.ReceptionId, product.ReceptionId)
+				assert.Equal(t, tt.expected, products)
 			}
 
 			assert.NoError(t, mock.ExpectationsWereMet())
@@ -392,37 +629,122 @@ func TestPvzRepository_GetLastProductInReception(t *testing.T) {
 	}
 }
 
-func TestPvzRepository_DeleteProductById(t *testing.T) {
+func TestPvzRepository_GetProductById(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	query := `^SELECT id, date_time, type, reception_id, seq_no, external_id FROM products WHERE id = \$1$`
+
+	mock.ExpectQuery(query).
+		WithArgs("prod1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "type", "reception_id", "seq_no", "external_id"}).
+			AddRow("prod1", time.Time{}, "обувь", "rec1", int64(7), nil))
+	product, err := repo.GetProductById(context.Background(), "prod1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Product{Id: "prod1", Type: "обувь", ReceptionId: "rec1", SeqNo: 7}, product)
+
+	mock.ExpectQuery(query).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetProductById(context.Background(), "missing")
+	assert.EqualError(t, err, "product not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_DeleteProducts(t *testing.T) {
 	logger.DBLogger = zap.NewNop()
 	ctx := context.Background()
+	deletedAt := time.Now()
+	deletions := []models.ProductDeletion{
+		{Id: "del1", ProductId: "prod2", ReceptionId: "rec1", SeqNo: 2, Type: "обувь", Kind: models.PRODUCT_DELETION_UNDO, DeletedBy: "user-1", DeletedAt: deletedAt},
+		{Id: "del2", ProductId: "prod1", ReceptionId: "rec1", SeqNo: 1, Type: "одежда", ExternalId: "BC-1", Kind: models.PRODUCT_DELETION_UNDO, DeletedBy: "user-1", DeletedAt: deletedAt},
+	}
 
 	tests := []struct {
-		name      string
-		productId string
-		mock      func(sqlmock.Sqlmock)
-		expectErr bool
-		errMsg    string
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
 	}{
 		{
-			name:      "Success",
-			productId: "prod1",
+			name: "Success",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`DELETE FROM products WHERE id = \$1`).
-					WithArgs("prod1").
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM products WHERE id IN \(\$1,\$2\)$`).
+					WithArgs("prod2", "prod1").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectQuery(`^UPDATE pvzs SET occupancy = GREATEST\(occupancy - \$1, 0\) WHERE id = \(SELECT pvz_id FROM receptions WHERE id = \$2\) RETURNING id$`).
+					WithArgs(2, "rec1").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("pvz1"))
+				mock.ExpectExec(`^INSERT INTO product_deletions \(id,product_id,reception_id,seq_no,type,external_id,kind,reason,deleted_by,deleted_at\) VALUES`).
+					WithArgs("del1", "prod2", "rec1", int64(2), "обувь", nil, models.PRODUCT_DELETION_UNDO, "", "user-1", deletedAt,
+						"del2", "prod1", "rec1", int64(1), "одежда", "BC-1", models.PRODUCT_DELETION_UNDO, "", "user-1", deletedAt).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_PRODUCT_DELETED, models.EVENT_PRODUCT_DELETED)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Already Deleted",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM products WHERE id IN \(\$1,\$2\)$`).
+					WithArgs("prod2", "prod1").
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectRollback()
 			},
-			expectErr: false,
+			errMsg: "product not found or already deleted",
 		},
 		{
-			name:      "Not Found",
-			productId: "prod2",
+			name: "Product Has Status History",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`DELETE FROM products WHERE id = \$1`).
-					WithArgs("prod2").
-					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM products`).
+					WillReturnError(&pq.Error{Code: foreignKeyViolationCode, Constraint: productStatusEventsProductFk})
+				mock.ExpectRollback()
 			},
-			expectErr: true,
-			errMsg:    "product not found or already deleted",
+			errMsg: "product has already left the reception",
+		},
+		{
+			name: "Product Arrived By Transfer",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM products`).
+					WillReturnError(&pq.Error{Code: foreignKeyViolationCode, Constraint: transferItemsProductFk})
+				mock.ExpectRollback()
+			},
+			errMsg: "product has already left the reception",
+		},
+		{
+			name: "Product Has Damage Report",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM products`).
+					WillReturnError(&pq.Error{Code: foreignKeyViolationCode, Constraint: damageReportsProductFk})
+				mock.ExpectRollback()
+			},
+			errMsg: "product has damage reports",
+		},
+		{
+			name: "Audit Insert Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM products`).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectQuery(`^UPDATE pvzs`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("pvz1"))
+				mock.ExpectExec(`^INSERT INTO product_deletions`).
+					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
+			},
+			errMsg: "insert failed",
 		},
 	}
 
@@ -440,11 +762,10 @@ func TestPvzRepository_DeleteProductById(t *testing.T) {
 			repo := NewReceptionRepository(db)
 			tt.mock(mock)
 
-			err = repo.DeleteProductById(ctx, tt.productId)
+			err = repo.DeleteProducts(ctx, deletions)
 
-			if tt.expectErr {
-				require.Error(t, err)
-				assert.Contains(t, err.Error(), tt.errMsg)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
 			} else {
 				require.NoError(t, err)
 			}
@@ -470,16 +791,36 @@ func TestPvzRepository_CloseReception(t *testing.T) {
 			name: "Success",
 			reception: &models.Reception{
 				Id:     "rec1",
+				PvzId:  "pvz1",
 				Status: models.STATUS_ACTIVE,
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`UPDATE receptions SET status = \$1 WHERE id = \$2`).
-					WithArgs(models.STATUS_CLOSED, "rec1").
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE receptions SET status = \$1 WHERE id = \$2 AND status = \$3`).
+					WithArgs(models.STATUS_CLOSED, "rec1", models.STATUS_ACTIVE).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_RECEPTION_CLOSED)
+				mock.ExpectCommit()
 			},
 			expectErr:  false,
 			expectStat: models.STATUS_CLOSED,
 		},
+		{
+			name: "Already Closed",
+			reception: &models.Reception{
+				Id:     "rec1",
+				Status: models.STATUS_ACTIVE,
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE receptions SET status = \$1 WHERE id = \$2 AND status = \$3`).
+					WithArgs(models.STATUS_CLOSED, "rec1", models.STATUS_ACTIVE).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectErr: true,
+			errMsg:    "no active reception",
+		},
 		{
 			name: "Update Error",
 			reception: &models.Reception{
@@ -487,9 +828,11 @@ func TestPvzRepository_CloseReception(t *testing.T) {
 				Status: models.STATUS_ACTIVE,
 			},
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(`UPDATE receptions SET status = \$1 WHERE id = \$2`).
-					WithArgs(models.STATUS_CLOSED, "rec2").
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE receptions SET status = \$1 WHERE id = \$2 AND status = \$3`).
+					WithArgs(models.STATUS_CLOSED, "rec2", models.STATUS_ACTIVE).
 					WillReturnError(errors.New("update failed"))
+				mock.ExpectRollback()
 			},
 			expectErr: true,
 			errMsg:    "update failed",
@@ -524,3 +867,488 @@ func TestPvzRepository_CloseReception(t *testing.T) {
 		})
 	}
 }
+
+func TestPvzRepository_WithinTransaction(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+
+	t.Run("Commit With Row Lock", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := NewReceptionRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = \$1 AND status = \$2 FOR UPDATE`).
+			WithArgs("pvz1", models.STATUS_ACTIVE).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
+				AddRow("rec1", time.Now(), "pvz1", models.STATUS_ACTIVE))
+		mock.ExpectExec(`UPDATE receptions SET status = \$1 WHERE id = \$2 AND status = \$3`).
+			WithArgs(models.STATUS_CLOSED, "rec1", models.STATUS_ACTIVE).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOutboxEvents(mock, "pvz1", models.EVENT_RECEPTION_CLOSED)
+		mock.ExpectCommit()
+
+		err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+			reception, err := repo.LockCurrentReception(ctx, "pvz1")
+			if err != nil {
+				return err
+			}
+			return repo.CloseReception(ctx, reception)
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Nested Calls Share Transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := NewReceptionRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM products WHERE id IN \(\$1\)`).
+			WithArgs("prod1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE pvzs SET occupancy = GREATEST\(occupancy - \$1, 0\)`).
+			WithArgs(1, "rec1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("pvz1"))
+		mock.ExpectExec(`INSERT INTO product_deletions`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOutboxEvents(mock, "pvz1", models.EVENT_PRODUCT_DELETED)
+		mock.ExpectCommit()
+
+		err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+			return repo.DeleteProducts(ctx, []models.ProductDeletion{
+				{Id: "del1", ProductId: "prod1", ReceptionId: "rec1", Kind: models.PRODUCT_DELETION_UNDO, DeletedAt: time.Now()},
+			})
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rollback On Error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := NewReceptionRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = \$1 AND status = \$2 FOR UPDATE`).
+			WithArgs("pvz1", models.STATUS_ACTIVE).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := repo.LockCurrentReception(ctx, "pvz1")
+			return err
+		})
+
+		assert.EqualError(t, err, "no active reception")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPvzRepository_LockReceptionById(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	now := time.Now()
+
+	mock.ExpectQuery(`^SELECT id, date_time, pvz_id, status FROM receptions WHERE id = \$1 FOR UPDATE$`).
+		WithArgs("rec1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
+			AddRow("rec1", now, "pvz1", models.STATUS_CLOSED))
+	reception, err := repo.LockReceptionById(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Reception{Id: "rec1", DateTime: now, PvzId: "pvz1", Status: models.STATUS_CLOSED}, reception)
+
+	mock.ExpectQuery(`^SELECT id, date_time, pvz_id, status FROM receptions WHERE id = \$1 FOR UPDATE$`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.LockReceptionById(context.Background(), "missing")
+	assert.EqualError(t, err, "reception not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_GetLastReceptionId(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+
+	mock.ExpectQuery(`^SELECT id FROM receptions WHERE pvz_id = \$1 ORDER BY date_time DESC LIMIT 1$`).
+		WithArgs("pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("rec2"))
+	id, err := repo.GetLastReceptionId(context.Background(), "pvz1")
+	require.NoError(t, err)
+	assert.Equal(t, "rec2", id)
+
+	mock.ExpectQuery(`^SELECT id FROM receptions WHERE pvz_id = \$1 ORDER BY date_time DESC LIMIT 1$`).
+		WithArgs("pvz2").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetLastReceptionId(context.Background(), "pvz2")
+	assert.EqualError(t, err, "reception not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_GetLastReopenedAt(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+	repo := NewReceptionRepository(db)
+	query := `^SELECT MAX\(reopened_at\) FROM reception_reopens WHERE reception_id = \$1$`
+	reopenedAt := time.Now()
+
+	mock.ExpectQuery(query).WithArgs("rec1").WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(reopenedAt))
+	res, err := repo.GetLastReopenedAt(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, reopenedAt, res)
+
+	mock.ExpectQuery(query).WithArgs("rec2").WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	res, err = repo.GetLastReopenedAt(context.Background(), "rec2")
+	require.NoError(t, err)
+	assert.True(t, res.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_ReopenReception(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	reopen := models.ReceptionReopen{
+		Id:          "reopen1",
+		ReceptionId: "rec1",
+		ReopenedBy:  "moderator-1",
+		ReopenedAt:  time.Now(),
+		Reason:      "забыли коробку",
+	}
+	updateQuery := `^UPDATE receptions SET status = \$1, auto_closed = \$2, close_reason = \$3 WHERE id = \$4 AND status = \$5 RETURNING pvz_id$`
+	insertQuery := `^INSERT INTO reception_reopens \(id,reception_id,reopened_by,reopened_at,reason\) VALUES \(\$1,\$2,\$3,\$4,\$5\)$`
+
+	tests := []struct {
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateQuery).
+					WithArgs(models.STATUS_ACTIVE, false, nil, "rec1", models.STATUS_CLOSED).
+					WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}).AddRow("pvz1"))
+				mock.ExpectExec(insertQuery).
+					WithArgs("reopen1", "rec1", "moderator-1", reopen.ReopenedAt, "забыли коробку").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_RECEPTION_REOPENED)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Not Closed",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateQuery).
+					WithArgs(models.STATUS_ACTIVE, false, nil, "rec1", models.STATUS_CLOSED).
+					WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}))
+				mock.ExpectRollback()
+			},
+			errMsg: "reception is not closed",
+		},
+		{
+			name: "Concurrent Active Reception",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateQuery).
+					WithArgs(models.STATUS_ACTIVE, false, nil, "rec1", models.STATUS_CLOSED).
+					WillReturnError(&pq.Error{Code: uniqueViolationCode, Constraint: activeReceptionPerPvzIndex})
+				mock.ExpectRollback()
+			},
+			errMsg: "active reception already exists",
+		},
+		{
+			name: "Insert Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(updateQuery).
+					WithArgs(models.STATUS_ACTIVE, false, nil, "rec1", models.STATUS_CLOSED).
+					WillReturnRows(sqlmock.NewRows([]string{"pvz_id"}).AddRow("pvz1"))
+				mock.ExpectExec(insertQuery).
+					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
+			},
+			errMsg: "insert failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				err := db.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			repo := NewReceptionRepository(db)
+			tt.mock(mock)
+
+			err = repo.ReopenReception(context.Background(), reopen)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPvzRepository_GetProductsByExternalId(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	query := `^SELECT pr.id, pr.date_time, pr.type, pr.reception_id, pr.seq_no, pr.external_id, r.pvz_id, r.status ` +
+		`FROM products pr JOIN receptions r ON r.id = pr.reception_id WHERE pr.external_id = \$1 ORDER BY pr.date_time DESC$`
+	columns := []string{"id", "date_time", "type", "reception_id", "seq_no", "external_id", "pvz_id", "status"}
+
+	mock.ExpectQuery(query).
+		WithArgs("BC-1").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("prod2", time.Time{}, "обувь", "rec2", int64(1), "BC-1", "pvz1", models.STATUS_ACTIVE).
+			AddRow("prod1", time.Time{}, "обувь", "rec1", int64(4), "BC-1", "pvz1", models.STATUS_CLOSED))
+	lookups, err := repo.GetProductsByExternalId(context.Background(), "BC-1")
+	require.NoError(t, err)
+	assert.Equal(t, []models.ProductLookup{
+		{
+			Product:         models.Product{Id: "prod2", Type: "обувь", ReceptionId: "rec2", SeqNo: 1, ExternalId: "BC-1"},
+			PvzId:           "pvz1",
+			ReceptionStatus: models.STATUS_ACTIVE,
+		},
+		{
+			Product:         models.Product{Id: "prod1", Type: "обувь", ReceptionId: "rec1", SeqNo: 4, ExternalId: "BC-1"},
+			PvzId:           "pvz1",
+			ReceptionStatus: models.STATUS_CLOSED,
+		},
+	}, lookups)
+
+	mock.ExpectQuery(query).
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows(columns))
+	lookups, err = repo.GetProductsByExternalId(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.Empty(t, lookups)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// lastActivityPattern — активность приёмки: создание, товары, удаления, переоткрытия и принятые перемещения.
+const lastActivityPattern = `GREATEST\(r.date_time, ` +
+	`\(SELECT MAX\(p.date_time\) FROM products p WHERE p.reception_id = r.id\), ` +
+	`\(SELECT MAX\(d.deleted_at\) FROM product_deletions d WHERE d.reception_id = r.id\), ` +
+	`\(SELECT MAX\(ro.reopened_at\) FROM reception_reopens ro WHERE ro.reception_id = r.id\), ` +
+	`\(SELECT MAX\(t.accepted_at\) FROM transfers t WHERE t.reception_id = r.id\)\)`
+
+func TestPvzRepository_GetStaleReceptions(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	before := time.Now().Add(-12 * time.Hour)
+	query := `^SELECT r.id, r.date_time, r.pvz_id, r.status FROM receptions r WHERE r.status = \$1 ` +
+		`AND ` + lastActivityPattern + ` < \$2 ` +
+		`ORDER BY r.date_time$`
+
+	t.Run("Success", func(t *testing.T) {
+		created := before.Add(-time.Hour)
+		mock.ExpectQuery(query).
+			WithArgs(models.STATUS_ACTIVE, before).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
+				AddRow("rec1", created, "pvz1", models.STATUS_ACTIVE).
+				AddRow("rec2", created, "pvz2", models.STATUS_ACTIVE))
+
+		receptions, err := repo.GetStaleReceptions(context.Background(), before)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Reception{
+			{Id: "rec1", DateTime: created, PvzId: "pvz1", Status: models.STATUS_ACTIVE},
+			{Id: "rec2", DateTime: created, PvzId: "pvz2", Status: models.STATUS_ACTIVE},
+		}, receptions)
+	})
+
+	t.Run("Nothing Stale", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(models.STATUS_ACTIVE, before).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}))
+
+		receptions, err := repo.GetStaleReceptions(context.Background(), before)
+		require.NoError(t, err)
+		assert.Empty(t, receptions)
+	})
+
+	t.Run("Query Error", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(models.STATUS_ACTIVE, before).
+			WillReturnError(errors.New("db error"))
+
+		_, err := repo.GetStaleReceptions(context.Background(), before)
+		assert.EqualError(t, err, "db error")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_GetReceptionLastActivity(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	query := `^SELECT ` + lastActivityPattern + ` FROM receptions r WHERE r.id = \$1$`
+
+	lastActivity := time.Now()
+	mock.ExpectQuery(query).
+		WithArgs("rec1").
+		WillReturnRows(sqlmock.NewRows([]string{"greatest"}).AddRow(lastActivity))
+	res, err := repo.GetReceptionLastActivity(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, lastActivity, res)
+
+	mock.ExpectQuery(query).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetReceptionLastActivity(context.Background(), "missing")
+	assert.EqualError(t, err, "reception not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_AutoCloseReception(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	query := `^UPDATE receptions SET status = \$1, auto_closed = \$2, close_reason = \$3 WHERE id = \$4 AND status = \$5$`
+	reason := "no activity for more than 12h0m0s"
+
+	tests := []struct {
+		name   string
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).
+					WithArgs(models.STATUS_CLOSED, true, reason, "rec1", models.STATUS_ACTIVE).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectOutboxEvents(mock, "pvz1", models.EVENT_RECEPTION_CLOSED)
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Already Closed",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).
+					WithArgs(models.STATUS_CLOSED, true, reason, "rec1", models.STATUS_ACTIVE).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			errMsg: "no active reception",
+		},
+		{
+			name: "Update Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).
+					WillReturnError(errors.New("update failed"))
+				mock.ExpectRollback()
+			},
+			errMsg: "update failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				err := db.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			repo := NewReceptionRepository(db)
+			tt.mock(mock)
+
+			reception := &models.Reception{Id: "rec1", PvzId: "pvz1", Status: models.STATUS_ACTIVE}
+			err = repo.AutoCloseReception(context.Background(), reception, reason)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				assert.Equal(t, models.STATUS_ACTIVE, reception.Status)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.Reception{
+					Id:          "rec1",
+					PvzId:       "pvz1",
+					Status:      models.STATUS_CLOSED,
+					AutoClosed:  true,
+					CloseReason: reason,
+				}, *reception)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// expectOutboxEvents ожидает блокировку событий ПВЗ и вставку в outbox событий указанных типов.
+func expectOutboxEvents(mock sqlmock.Sqlmock, pvzId string, eventTypes ...string) {
+	mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1, hashtext\(id\)\) FROM unnest\(\$2::text\[\]\) AS id$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	args := make([]driver.Value, 0, 5*len(eventTypes))
+	for _, eventType := range eventTypes {
+		args = append(args, sqlmock.AnyArg(), eventType, pvzId, sqlmock.AnyArg(), sqlmock.AnyArg())
+	}
+	mock.ExpectExec(`^INSERT INTO outbox_events \(id,event_type,pvz_id,payload,occurred_at\) VALUES`).
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, int64(len(eventTypes))))
+}
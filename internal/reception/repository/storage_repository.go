@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+	"time"
+)
+
+// SetStorageDeadlines назначает срок хранения товарам закрытой приёмки: отсчёт идёт
+// от момента закрытия, длительность берётся из типа товара. При повторном закрытии после
+// переоткрытия срок получают только товары, добавленные после него, — у остальных он сохраняется.
+func (r ReceptionRepository) SetStorageDeadlines(ctx context.Context, receptionId string, closedAt time.Time) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("SetStorageDeadlines called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Update("products p").
+		Set("storage_deadline", sq.Expr("?::timestamp + pt.storage_days * INTERVAL '1 day'", closedAt)).
+		From("product_types pt").
+		Where("pt.code = p.type").
+		Where(sq.Eq{"p.reception_id": receptionId, "p.status": models.PRODUCT_STATUS_RECEIVED}).
+		Where(sq.Eq{"p.storage_deadline": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err = dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to set storage deadlines", zap.Error(err))
+		return err
+	}
+	return nil
+}
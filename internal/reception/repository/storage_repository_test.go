@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestPvzRepository_SetStorageDeadlines(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+	repo := NewReceptionRepository(db)
+	closedAt := time.Now()
+
+	mock.ExpectExec(`^UPDATE products p SET storage_deadline = \$1::timestamp \+ pt.storage_days \* INTERVAL '1 day' `+
+		`FROM product_types pt WHERE pt.code = p.type AND p.reception_id = \$2 AND p.status = \$3 AND p.storage_deadline IS NULL$`).
+		WithArgs(closedAt, "rec1", models.PRODUCT_STATUS_RECEIVED).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	require.NoError(t, repo.SetStorageDeadlines(context.Background(), "rec1", closedAt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
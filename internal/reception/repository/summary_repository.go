@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"encoding/json"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+	"time"
+)
+
+// CountProductsByType возвращает количество товаров приёмки по каждому типу.
+func (r ReceptionRepository) CountProductsByType(ctx context.Context, receptionId string) (map[string]int, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CountProductsByType called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("type", "COUNT(*)").
+		From("products").
+		Where(sq.Eq{"reception_id": receptionId}).
+		GroupBy("type").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to count products", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var productType string
+		var count int
+		if err = rows.Scan(&productType, &count); err != nil {
+			logger.DBLogger.Error("failed to scan product count", zap.Error(err))
+			return nil, err
+		}
+		counts[productType] = count
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountProductDeletions возвращает число удалений товаров (отмен и точечных удалений) из приёмки.
+func (r ReceptionRepository) CountProductDeletions(ctx context.Context, receptionId string) (int, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CountProductDeletions called", zap.String("request_id", requestID), zap.String("reception_id", receptionId))
+
+	query, args, err := sq.Select("COUNT(*)").
+		From("product_deletions").
+		Where(sq.Eq{"reception_id": receptionId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return 0, err
+	}
+
+	var count int
+	if err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		logger.DBLogger.Error("failed to count product deletions", zap.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
+
+// SaveReceptionSummary сохраняет итоги приёмки, перезаписывая итоги предыдущего закрытия. Время работы
+// переоткрытой приёмки складывается из всех её открытых интервалов, поэтому OpenDuration прибавляется
+// к сохранённому и в summary возвращается итоговое значение.
+func (r ReceptionRepository) SaveReceptionSummary(ctx context.Context, summary *models.ReceptionSummary) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("SaveReceptionSummary called",
+		zap.String("request_id", requestID),
+		zap.String("reception_id", summary.ReceptionId),
+	)
+
+	productsByType, err := json.Marshal(summary.ProductsByType)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := sq.Insert("reception_summaries").
+		Columns("reception_id", "closed_at", "total_products", "products_by_type", "open_duration_seconds", "deletions_count").
+		Values(summary.ReceptionId, summary.ClosedAt, summary.TotalProducts, productsByType,
+			int64(summary.OpenDuration/time.Second), summary.DeletionsCount).
+		Suffix("ON CONFLICT (reception_id) DO UPDATE SET closed_at = EXCLUDED.closed_at, " +
+			"total_products = EXCLUDED.total_products, products_by_type = EXCLUDED.products_by_type, " +
+			"open_duration_seconds = reception_summaries.open_duration_seconds + EXCLUDED.open_duration_seconds, " +
+			"deletions_count = EXCLUDED.deletions_count " +
+			"RETURNING open_duration_seconds").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	var openDurationSeconds int64
+	if err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&openDurationSeconds); err != nil {
+		logger.DBLogger.Error("failed to save reception summary", zap.Error(err))
+		return err
+	}
+	summary.OpenDuration = time.Duration(openDurationSeconds) * time.Second
+	return nil
+}
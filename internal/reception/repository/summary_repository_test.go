@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestPvzRepository_CountProductsByType(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+	query := `^SELECT type, COUNT\(\*\) FROM products WHERE reception_id = \$1 GROUP BY type$`
+
+	mock.ExpectQuery(query).WithArgs("rec1").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).
+			AddRow(models.CLOTHES_TYPE, 3).
+			AddRow(models.BOOTS_TYPE, 1))
+	counts, err := repo.CountProductsByType(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{models.CLOTHES_TYPE: 3, models.BOOTS_TYPE: 1}, counts)
+
+	mock.ExpectQuery(query).WithArgs("rec2").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "count"}))
+	counts, err = repo.CountProductsByType(context.Background(), "rec2")
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+
+	mock.ExpectQuery(query).WithArgs("rec3").
+		WillReturnError(errors.New("db error"))
+	_, err = repo.CountProductsByType(context.Background(), "rec3")
+	assert.EqualError(t, err, "db error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_CountProductDeletions(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+
+	mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM product_deletions WHERE reception_id = \$1$`).
+		WithArgs("rec1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	count, err := repo.CountProductDeletions(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPvzRepository_SaveReceptionSummary(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	repo := NewReceptionRepository(db)
+
+	summary := &models.ReceptionSummary{
+		ReceptionId:    "rec1",
+		ClosedAt:       time.Now(),
+		TotalProducts:  3,
+		ProductsByType: map[string]int{models.CLOTHES_TYPE: 3},
+		OpenDuration:   90*time.Minute + 500*time.Millisecond,
+		DeletionsCount: 1,
+	}
+
+	// Приёмку уже закрывали через 30 минут работы: итог складывается с новым интервалом.
+	mock.ExpectQuery(`^INSERT INTO reception_summaries \(reception_id,closed_at,total_products,products_by_type,open_duration_seconds,deletions_count\) `+
+		`VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\) ON CONFLICT \(reception_id\) DO UPDATE SET closed_at = EXCLUDED.closed_at, `+
+		`.*open_duration_seconds = reception_summaries.open_duration_seconds \+ EXCLUDED.open_duration_seconds, `+
+		`deletions_count = EXCLUDED.deletions_count RETURNING open_duration_seconds$`).
+		WithArgs("rec1", summary.ClosedAt, 3, []byte(`{"одежда":3}`), int64(5400), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"open_duration_seconds"}).AddRow(int64(7200)))
+
+	require.NoError(t, repo.SaveReceptionSummary(context.Background(), summary))
+	assert.Equal(t, 2*time.Hour, summary.OpenDuration)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,14 +3,47 @@ package usecase
 import (
 	"avito_spring_staj_2025/domain/models"
 	"context"
+	"time"
 )
 
 type ReceptionRepository interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 	CreateReception(ctx context.Context, data models.Reception) error
 	GetPvzById(ctx context.Context, pvzId string) (*models.Pvz, error)
 	GetCurrentReception(ctx context.Context, pvzId string) (*models.Reception, error)
-	AddProductToReception(ctx context.Context, product models.Product) error
-	GetLastProductInReception(ctx context.Context, receptionId string) (*models.Product, error)
-	DeleteProductById(ctx context.Context, productId string) error
+	LockCurrentReception(ctx context.Context, pvzId string) (*models.Reception, error)
+	LockReceptionById(ctx context.Context, receptionId string) (*models.Reception, error)
+	GetLastReceptionId(ctx context.Context, pvzId string) (string, error)
+	ReopenReception(ctx context.Context, reopen models.ReceptionReopen) error
+	GetLastReopenedAt(ctx context.Context, receptionId string) (time.Time, error)
+	AddProductToReception(ctx context.Context, pvzId string, product *models.Product) error
+	AddProductsToReception(ctx context.Context, pvzId string, products []models.Product) error
+	GetLastProductsInReception(ctx context.Context, receptionId string, count int) ([]models.Product, error)
+	GetProductsByExternalId(ctx context.Context, externalId string) ([]models.ProductLookup, error)
+	GetProductById(ctx context.Context, productId string) (*models.Product, error)
+	DeleteProducts(ctx context.Context, deletions []models.ProductDeletion) error
 	CloseReception(ctx context.Context, reception *models.Reception) error
+	GetStaleReceptions(ctx context.Context, before time.Time) ([]models.Reception, error)
+	GetReceptionLastActivity(ctx context.Context, receptionId string) (time.Time, error)
+	AutoCloseReception(ctx context.Context, reception *models.Reception, reason string) error
+	ReplaceManifest(ctx context.Context, receptionId string, items []models.ManifestItem) error
+	GetManifest(ctx context.Context, receptionId string) ([]models.ManifestItem, error)
+	GetProductsInReception(ctx context.Context, receptionId string) ([]models.Product, error)
+	GetExternalIdsReceivedEarlier(ctx context.Context, receptionId string) (map[string]struct{}, error)
+	SaveDiscrepancyReport(ctx context.Context, report *models.DiscrepancyReport) error
+	GetDiscrepancyReport(ctx context.Context, receptionId string) (*models.DiscrepancyReport, error)
+	CountProductsByType(ctx context.Context, receptionId string) (map[string]int, error)
+	CountProductDeletions(ctx context.Context, receptionId string) (int, error)
+	SaveReceptionSummary(ctx context.Context, summary *models.ReceptionSummary) error
+	SetStorageDeadlines(ctx context.Context, receptionId string, closedAt time.Time) error
+	LockStorageCell(ctx context.Context, pvzId, code string) (*models.StorageCell, error)
+}
+
+type ProductTypeCatalog interface {
+	IsActiveProductType(ctx context.Context, code string) (bool, error)
+	ProductTypeSize(ctx context.Context, code string) (string, error)
+}
+
+type ScheduleProvider interface {
+	GetScheduleAt(ctx context.Context, pvzId string, at time.Time) (models.PvzSchedule, error)
 }
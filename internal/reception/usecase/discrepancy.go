@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"time"
+)
+
+// buildDiscrepancyReport сверяет манифест с принятыми товарами по внешнему идентификатору.
+// Товар без внешнего идентификатора или не из манифеста — unexpected, товар из манифеста, который
+// раньше уже принимали в другой приёмке (receivedEarlier), — duplicated, совпадение идентификатора
+// при другом типе — type_mismatch, позиция манифеста без единого товара — missing.
+func buildDiscrepancyReport(receptionId string, manifest []models.ManifestItem, products []models.Product,
+	receivedEarlier map[string]struct{}, createdAt time.Time) *models.DiscrepancyReport {
+	expected := make(map[string]models.ManifestItem, len(manifest))
+	for _, item := range manifest {
+		expected[item.ExternalId] = item
+	}
+
+	report := &models.DiscrepancyReport{
+		ReceptionId:   receptionId,
+		CreatedAt:     createdAt,
+		ExpectedCount: len(manifest),
+		ReceivedCount: len(products),
+		Items:         make([]models.DiscrepancyItem, 0),
+	}
+
+	received := make(map[string]bool, len(products))
+	for _, product := range products {
+		item, ok := expected[product.ExternalId]
+		if product.ExternalId == "" || !ok {
+			report.Items = append(report.Items, models.DiscrepancyItem{
+				Kind:       models.DISCREPANCY_UNEXPECTED,
+				ExternalId: product.ExternalId,
+				ActualType: product.Type,
+				ProductId:  product.Id,
+			})
+			continue
+		}
+
+		kind := ""
+		_, duplicated := receivedEarlier[product.ExternalId]
+		switch {
+		case duplicated:
+			kind = models.DISCREPANCY_DUPLICATED
+		case item.Type != product.Type:
+			kind = models.DISCREPANCY_TYPE_MISMATCH
+		}
+		received[product.ExternalId] = true
+		if kind == "" {
+			report.MatchedCount++
+			continue
+		}
+		report.Items = append(report.Items, models.DiscrepancyItem{
+			Kind:         kind,
+			ExternalId:   product.ExternalId,
+			ExpectedType: item.Type,
+			ActualType:   product.Type,
+			ProductId:    product.Id,
+		})
+	}
+
+	for _, item := range manifest {
+		if !received[item.ExternalId] {
+			report.Items = append(report.Items, models.DiscrepancyItem{
+				Kind:         models.DISCREPANCY_MISSING,
+				ExternalId:   item.ExternalId,
+				ExpectedType: item.Type,
+			})
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBuildDiscrepancyReport(t *testing.T) {
+	createdAt := time.Now()
+	manifest := []models.ManifestItem{
+		{ExternalId: "BC-1", Type: models.CLOTHES_TYPE},
+		{ExternalId: "BC-2", Type: models.BOOTS_TYPE},
+		{ExternalId: "BC-3", Type: models.ELECTRONIC_TYPE},
+		{ExternalId: "BC-4", Type: models.CLOTHES_TYPE},
+	}
+
+	tests := []struct {
+		name            string
+		products        []models.Product
+		receivedEarlier map[string]struct{}
+		expectedMatch   int
+		expectedItems   []models.DiscrepancyItem
+	}{
+		{
+			name: "everything matches",
+			products: []models.Product{
+				{Id: "p1", ExternalId: "BC-1", Type: models.CLOTHES_TYPE},
+				{Id: "p2", ExternalId: "BC-2", Type: models.BOOTS_TYPE},
+				{Id: "p3", ExternalId: "BC-3", Type: models.ELECTRONIC_TYPE},
+				{Id: "p4", ExternalId: "BC-4", Type: models.CLOTHES_TYPE},
+			},
+			expectedMatch: 4,
+			expectedItems: []models.DiscrepancyItem{},
+		},
+		{
+			name:          "nothing received",
+			products:      []models.Product{},
+			expectedMatch: 0,
+			expectedItems: []models.DiscrepancyItem{
+				{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-1", ExpectedType: models.CLOTHES_TYPE},
+				{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-2", ExpectedType: models.BOOTS_TYPE},
+				{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-3", ExpectedType: models.ELECTRONIC_TYPE},
+				{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-4", ExpectedType: models.CLOTHES_TYPE},
+			},
+		},
+		{
+			name: "all kinds of discrepancies",
+			products: []models.Product{
+				{Id: "p1", ExternalId: "BC-1", Type: models.CLOTHES_TYPE},
+				{Id: "p3", ExternalId: "BC-2", Type: models.CLOTHES_TYPE},
+				{Id: "p4", ExternalId: "BC-9", Type: models.BOOTS_TYPE},
+				{Id: "p5", Type: models.ELECTRONIC_TYPE},
+				{Id: "p6", ExternalId: "BC-4", Type: models.CLOTHES_TYPE},
+			},
+			receivedEarlier: map[string]struct{}{"BC-4": {}, "BC-9": {}},
+			expectedMatch:   1,
+			expectedItems: []models.DiscrepancyItem{
+				{Kind: models.DISCREPANCY_TYPE_MISMATCH, ExternalId: "BC-2", ExpectedType: models.BOOTS_TYPE, ActualType: models.CLOTHES_TYPE, ProductId: "p3"},
+				{Kind: models.DISCREPANCY_UNEXPECTED, ExternalId: "BC-9", ActualType: models.BOOTS_TYPE, ProductId: "p4"},
+				{Kind: models.DISCREPANCY_UNEXPECTED, ActualType: models.ELECTRONIC_TYPE, ProductId: "p5"},
+				{Kind: models.DISCREPANCY_DUPLICATED, ExternalId: "BC-4", ExpectedType: models.CLOTHES_TYPE, ActualType: models.CLOTHES_TYPE, ProductId: "p6"},
+				{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-3", ExpectedType: models.ELECTRONIC_TYPE},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := buildDiscrepancyReport("rec1", manifest, tt.products, tt.receivedEarlier, createdAt)
+			assert.Equal(t, &models.DiscrepancyReport{
+				ReceptionId:   "rec1",
+				CreatedAt:     createdAt,
+				ExpectedCount: len(manifest),
+				ReceivedCount: len(tt.products),
+				MatchedCount:  tt.expectedMatch,
+				Items:         tt.expectedItems,
+			}, report)
+		})
+	}
+}
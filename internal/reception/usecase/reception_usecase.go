@@ -6,17 +6,23 @@ import (
 	"avito_spring_staj_2025/internal/service/middleware"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
 type ReceptionUsecase struct {
 	pvzRepository ReceptionRepository
+	productTypes  ProductTypeCatalog
+	schedules     ScheduleProvider
 }
 
-func NewReceptionUsecase(receptionRepository ReceptionRepository) ReceptionUsecase {
+func NewReceptionUsecase(receptionRepository ReceptionRepository, productTypes ProductTypeCatalog, schedules ScheduleProvider) ReceptionUsecase {
 	return ReceptionUsecase{
 		pvzRepository: receptionRepository,
+		productTypes:  productTypes,
+		schedules:     schedules,
 	}
 }
 
@@ -45,6 +51,23 @@ func (pu ReceptionUsecase) CreateReception(ctx context.Context, data requests.Cr
 		Status:   "in_progress",
 	}
 
+	schedule, err := pu.schedules.GetScheduleAt(ctx, data.PvzId, reception.DateTime)
+	if err != nil {
+		return models.Reception{}, err
+	}
+	if schedule.Policy != models.WORKING_HOURS_POLICY_NONE {
+		isOpen, err := schedule.IsOpenAt(reception.DateTime)
+		if err != nil {
+			return models.Reception{}, err
+		}
+		if !isOpen {
+			if schedule.Policy == models.WORKING_HOURS_POLICY_REJECT {
+				return models.Reception{}, errors.New("pvz is closed at this time")
+			}
+			reception.OutsideWorkingHours = true
+		}
+	}
+
 	err = pu.pvzRepository.CreateReception(ctx, reception)
 	if err != nil {
 		return models.Reception{}, err
@@ -57,8 +80,8 @@ func (pu ReceptionUsecase) AddProductToReception(ctx context.Context, data reque
 		return models.Product{}, errors.New("this role is not allowed")
 	}
 
-	if data.Type != models.CLOTHES_TYPE && data.Type != models.BOOTS_TYPE && data.Type != models.ELECTRONIC_TYPE {
-		return models.Product{}, errors.New("this type is not allowed")
+	if err := pu.checkProductType(ctx, data.Type); err != nil {
+		return models.Product{}, err
 	}
 
 	_, err := pu.pvzRepository.GetPvzById(ctx, data.PvzId)
@@ -66,50 +89,227 @@ func (pu ReceptionUsecase) AddProductToReception(ctx context.Context, data reque
 		return models.Product{}, err
 	}
 
-	reception, err := pu.pvzRepository.GetCurrentReception(ctx, data.PvzId)
+	cellCode := strings.TrimSpace(data.CellCode)
+	var size string
+	if cellCode != "" {
+		if size, err = pu.productTypes.ProductTypeSize(ctx, data.Type); err != nil {
+			return models.Product{}, err
+		}
+	}
+
+	var product models.Product
+	err = pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err := pu.pvzRepository.LockCurrentReception(ctx, data.PvzId)
+		if err != nil {
+			return err
+		}
+		product = models.Product{
+			Id:          uuid.New().String(),
+			Type:        data.Type,
+			ReceptionId: reception.Id,
+			ExternalId:  strings.TrimSpace(data.ExternalId),
+			DateTime:    time.Now(),
+		}
+		// Ячейку можно указать сразу при сканировании, иначе её назначают позже отдельным запросом
+		if cellCode != "" {
+			cell, err := pu.pvzRepository.LockStorageCell(ctx, data.PvzId, cellCode)
+			if err != nil {
+				return err
+			}
+			if err := cell.CanHold(size); err != nil {
+				return err
+			}
+			product.CellId = cell.Id
+			product.CellCode = cell.Code
+		}
+		return pu.pvzRepository.AddProductToReception(ctx, data.PvzId, &product)
+	})
 	if err != nil {
 		return models.Product{}, err
 	}
-	product := models.Product{
-		Id:          uuid.New().String(),
-		Type:        data.Type,
-		ReceptionId: reception.Id,
-		DateTime:    time.Now(),
+
+	return product, nil
+}
+
+// checkProductType пропускает только активные типы из справочника.
+func (pu ReceptionUsecase) checkProductType(ctx context.Context, productType string) error {
+	active, err := pu.productTypes.IsActiveProductType(ctx, productType)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return errors.New("this type is not allowed")
 	}
+	return nil
+}
 
-	err = pu.pvzRepository.AddProductToReception(ctx, product)
+func (pu ReceptionUsecase) GetProductsByBarcode(ctx context.Context, barcode string) ([]models.ProductLookup, error) {
+	barcode = strings.TrimSpace(barcode)
+	if barcode == "" {
+		return nil, errors.New("barcode is required")
+	}
+	return pu.pvzRepository.GetProductsByExternalId(ctx, barcode)
+}
+
+func (pu ReceptionUsecase) AddProductsToReception(ctx context.Context, data requests.AddProductsBatchRequest) ([]models.Product, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return nil, errors.New("this role is not allowed")
+	}
+
+	if len(data.Products) == 0 {
+		return nil, errors.New("empty product batch")
+	}
+	if len(data.Products) > models.MAX_PRODUCT_BATCH_SIZE {
+		return nil, errors.New("too many products in batch")
+	}
+	externalIds := make(map[string]struct{}, len(data.Products))
+	for _, item := range data.Products {
+		if err := pu.checkProductType(ctx, item.Type); err != nil {
+			return nil, err
+		}
+		// Сравниваем уже обрезанные значения: именно они попадут в уникальный индекс.
+		externalId := strings.TrimSpace(item.ExternalId)
+		if externalId == "" {
+			continue
+		}
+		if _, ok := externalIds[externalId]; ok {
+			return nil, errors.New("duplicate external id in batch")
+		}
+		externalIds[externalId] = struct{}{}
+	}
+
+	_, err := pu.pvzRepository.GetPvzById(ctx, data.PvzId)
 	if err != nil {
-		return models.Product{}, err
+		return nil, err
 	}
 
-	return product, nil
+	var products []models.Product
+	err = pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err := pu.pvzRepository.LockCurrentReception(ctx, data.PvzId)
+		if err != nil {
+			return err
+		}
+
+		// Сдвигаем время на микросекунду, чтобы порядок сканирования был виден и по date_time,
+		// а не только по порядковому номеру.
+		now := time.Now()
+		products = make([]models.Product, 0, len(data.Products))
+		for i, item := range data.Products {
+			products = append(products, models.Product{
+				Id:          uuid.New().String(),
+				Type:        item.Type,
+				ReceptionId: reception.Id,
+				ExternalId:  strings.TrimSpace(item.ExternalId),
+				DateTime:    now.Add(time.Duration(i) * time.Microsecond),
+			})
+		}
+		return pu.pvzRepository.AddProductsToReception(ctx, data.PvzId, products)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
 }
 
-func (pu ReceptionUsecase) DeleteLastProduct(ctx context.Context, pvzId string) error {
+// DeleteLastProducts отменяет count последних сканирований активной приёмки.
+// Удаляется либо ровно count товаров, либо ни одного.
+func (pu ReceptionUsecase) DeleteLastProducts(ctx context.Context, pvzId string, count int) ([]models.Product, error) {
 	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
-		return errors.New("this role is not allowed")
+		return nil, errors.New("this role is not allowed")
+	}
+	if count < 1 || count > models.MAX_PRODUCT_BATCH_SIZE {
+		return nil, errors.New("invalid count")
 	}
 	_, err := pu.pvzRepository.GetPvzById(ctx, pvzId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	reception, err := pu.pvzRepository.GetCurrentReception(ctx, pvzId)
+	var products []models.Product
+	err = pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err := pu.pvzRepository.LockCurrentReception(ctx, pvzId)
+		if err != nil {
+			return err
+		}
+
+		products, err = pu.pvzRepository.GetLastProductsInReception(ctx, reception.Id, count)
+		if err != nil {
+			return err
+		}
+		if len(products) < count {
+			return errors.New("not enough products in reception")
+		}
+
+		return pu.pvzRepository.DeleteProducts(ctx, newProductDeletions(ctx, products, models.PRODUCT_DELETION_UNDO, ""))
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	product, err := pu.pvzRepository.GetLastProductInReception(ctx, reception.Id)
+	return products, nil
+}
+
+// RemoveProduct удаляет из активной приёмки конкретный товар, причина удаления обязательна.
+func (pu ReceptionUsecase) RemoveProduct(ctx context.Context, pvzId string, data requests.RemoveProductRequest) (models.Product, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.Product{}, errors.New("this role is not allowed")
+	}
+	if data.ProductId == "" {
+		return models.Product{}, errors.New("product id is required")
+	}
+	reason := strings.TrimSpace(data.Reason)
+	if reason == "" {
+		return models.Product{}, errors.New("remove reason is required")
+	}
+	_, err := pu.pvzRepository.GetPvzById(ctx, pvzId)
 	if err != nil {
-		return err
+		return models.Product{}, err
 	}
 
-	err = pu.pvzRepository.DeleteProductById(ctx, product.Id)
+	var product *models.Product
+	err = pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err := pu.pvzRepository.LockCurrentReception(ctx, pvzId)
+		if err != nil {
+			return err
+		}
+
+		product, err = pu.pvzRepository.GetProductById(ctx, data.ProductId)
+		if err != nil {
+			return err
+		}
+		if product.ReceptionId != reception.Id {
+			return errors.New("product not found")
+		}
+
+		return pu.pvzRepository.DeleteProducts(ctx, newProductDeletions(ctx, []models.Product{*product}, models.PRODUCT_DELETION_REMOVE, reason))
+	})
 	if err != nil {
-		return err
+		return models.Product{}, err
 	}
 
-	return nil
+	return *product, nil
+}
+
+func newProductDeletions(ctx context.Context, products []models.Product, kind, reason string) []models.ProductDeletion {
+	deletedBy := middleware.GetUserId(ctx)
+	deletedAt := time.Now()
+	deletions := make([]models.ProductDeletion, 0, len(products))
+	for _, product := range products {
+		deletions = append(deletions, models.ProductDeletion{
+			Id:          uuid.New().String(),
+			ProductId:   product.Id,
+			ReceptionId: product.ReceptionId,
+			SeqNo:       product.SeqNo,
+			Type:        product.Type,
+			ExternalId:  product.ExternalId,
+			Kind:        kind,
+			Reason:      reason,
+			DeletedBy:   deletedBy,
+			DeletedAt:   deletedAt,
+		})
+	}
+	return deletions
 }
 
 func (pu ReceptionUsecase) CloseReception(ctx context.Context, pvzId string) (models.Reception, error) {
@@ -121,15 +321,269 @@ func (pu ReceptionUsecase) CloseReception(ctx context.Context, pvzId string) (mo
 		return models.Reception{}, err
 	}
 
-	reception, err := pu.pvzRepository.GetCurrentReception(ctx, pvzId)
+	var reception *models.Reception
+	err = pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err = pu.pvzRepository.LockCurrentReception(ctx, pvzId)
+		if err != nil {
+			return err
+		}
+		if err = pu.pvzRepository.CloseReception(ctx, reception); err != nil {
+			return err
+		}
+		return pu.finalizeClosedReception(ctx, reception)
+	})
 	if err != nil {
 		return models.Reception{}, err
 	}
 
-	err = pu.pvzRepository.CloseReception(ctx, reception)
+	return *reception, nil
+}
+
+// CloseStaleReceptions закрывает активные приёмки, в которых не было активности дольше inactiveFor.
+// Вызывается фоновым обработчиком, поэтому роль не проверяется. Перед закрытием приёмка
+// блокируется и простой проверяется повторно: товар мог быть добавлен после выборки.
+// Ошибка по одной приёмке не мешает закрыть остальные.
+func (pu ReceptionUsecase) CloseStaleReceptions(ctx context.Context, inactiveFor time.Duration) ([]models.Reception, error) {
+	before := time.Now().Add(-inactiveFor)
+	stale, err := pu.pvzRepository.GetStaleReceptions(ctx, before)
 	if err != nil {
-		return models.Reception{}, err
+		return nil, err
 	}
 
-	return *reception, nil
+	reason := fmt.Sprintf("no activity for more than %s", inactiveFor)
+	closed := make([]models.Reception, 0, len(stale))
+	var errs []error
+	for _, candidate := range stale {
+		var reception *models.Reception
+		err = pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+			locked, err := pu.pvzRepository.LockReceptionById(ctx, candidate.Id)
+			if err != nil {
+				return err
+			}
+			if locked.Status != models.STATUS_ACTIVE {
+				return nil
+			}
+			lastActivity, err := pu.pvzRepository.GetReceptionLastActivity(ctx, locked.Id)
+			if err != nil {
+				return err
+			}
+			if !lastActivity.Before(before) {
+				return nil
+			}
+			if err = pu.pvzRepository.AutoCloseReception(ctx, locked, reason); err != nil {
+				return err
+			}
+			if err = pu.finalizeClosedReception(ctx, locked); err != nil {
+				return err
+			}
+			reception = locked
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reception %s: %w", candidate.Id, err))
+			continue
+		}
+		if reception != nil {
+			closed = append(closed, *reception)
+		}
+	}
+
+	return closed, errors.Join(errs...)
+}
+
+// ReopenReception снова открывает последнюю закрытую приёмку ПВЗ. Открыть можно только
+// самую свежую приёмку и только если по ПВЗ нет другой активной: это же гарантирует
+// частичный уникальный индекс в базе на случай гонки.
+func (pu ReceptionUsecase) ReopenReception(ctx context.Context, receptionId string, data requests.ReopenReceptionRequest) (models.Reception, models.ReceptionReopen, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.Reception{}, models.ReceptionReopen{}, errors.New("this role is not allowed")
+	}
+	reason := strings.TrimSpace(data.Reason)
+	if reason == "" {
+		return models.Reception{}, models.ReceptionReopen{}, errors.New("reopen reason is required")
+	}
+
+	var reception *models.Reception
+	reopen := models.ReceptionReopen{
+		Id:          uuid.New().String(),
+		ReceptionId: receptionId,
+		ReopenedBy:  middleware.GetUserId(ctx),
+		ReopenedAt:  time.Now(),
+		Reason:      reason,
+	}
+	err := pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		reception, err = pu.pvzRepository.LockReceptionById(ctx, receptionId)
+		if err != nil {
+			return err
+		}
+		if reception.Status != models.STATUS_CLOSED {
+			return errors.New("reception is not closed")
+		}
+
+		lastReceptionId, err := pu.pvzRepository.GetLastReceptionId(ctx, reception.PvzId)
+		if err != nil {
+			return err
+		}
+		if lastReceptionId != reception.Id {
+			return errors.New("only the last reception can be reopened")
+		}
+
+		_, err = pu.pvzRepository.GetCurrentReception(ctx, reception.PvzId)
+		if err == nil {
+			return errors.New("active reception already exists")
+		}
+		if err.Error() != "no active reception" {
+			return err
+		}
+
+		return pu.pvzRepository.ReopenReception(ctx, reopen)
+	})
+	if err != nil {
+		return models.Reception{}, models.ReceptionReopen{}, err
+	}
+
+	reception.Status = models.STATUS_ACTIVE
+	return *reception, reopen, nil
+}
+
+// SetReceptionManifest прикладывает к активной приёмке манифест ожидаемой поставки,
+// заменяя ранее приложенный.
+func (pu ReceptionUsecase) SetReceptionManifest(ctx context.Context, receptionId string, data requests.SetManifestRequest) ([]models.ManifestItem, error) {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return nil, errors.New("this role is not allowed")
+	}
+
+	if len(data.Items) == 0 {
+		return nil, errors.New("empty manifest")
+	}
+	if len(data.Items) > models.MAX_MANIFEST_SIZE {
+		return nil, errors.New("too many items in manifest")
+	}
+	items := make([]models.ManifestItem, 0, len(data.Items))
+	externalIds := make(map[string]struct{}, len(data.Items))
+	for _, item := range data.Items {
+		externalId := strings.TrimSpace(item.ExternalId)
+		if externalId == "" {
+			return nil, errors.New("external id is required")
+		}
+		if _, ok := externalIds[externalId]; ok {
+			return nil, errors.New("duplicate external id in manifest")
+		}
+		externalIds[externalId] = struct{}{}
+		if err := pu.checkProductType(ctx, item.Type); err != nil {
+			return nil, err
+		}
+		items = append(items, models.ManifestItem{
+			Id:          uuid.New().String(),
+			ReceptionId: receptionId,
+			ExternalId:  externalId,
+			Type:        item.Type,
+		})
+	}
+
+	err := pu.pvzRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		reception, err := pu.pvzRepository.LockReceptionById(ctx, receptionId)
+		if err != nil {
+			return err
+		}
+		if reception.Status != models.STATUS_ACTIVE {
+			return errors.New("reception is not in progress")
+		}
+		return pu.pvzRepository.ReplaceManifest(ctx, receptionId, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (pu ReceptionUsecase) GetDiscrepancyReport(ctx context.Context, receptionId string) (models.DiscrepancyReport, error) {
+	report, err := pu.pvzRepository.GetDiscrepancyReport(ctx, receptionId)
+	if err != nil {
+		return models.DiscrepancyReport{}, err
+	}
+	return *report, nil
+}
+
+// finalizeClosedReception считает итоги только что закрытой приёмки, назначает товарам срок хранения
+// и сверяет приёмку с манифестом. Вызывается в транзакции закрытия, поэтому всё сохраняется вместе со сменой статуса.
+func (pu ReceptionUsecase) finalizeClosedReception(ctx context.Context, reception *models.Reception) error {
+	if err := pu.attachSummary(ctx, reception); err != nil {
+		return err
+	}
+	if err := pu.pvzRepository.SetStorageDeadlines(ctx, reception.Id, reception.Summary.ClosedAt); err != nil {
+		return err
+	}
+	return pu.attachDiscrepancyReport(ctx, reception)
+}
+
+func (pu ReceptionUsecase) attachSummary(ctx context.Context, reception *models.Reception) error {
+	productsByType, err := pu.pvzRepository.CountProductsByType(ctx, reception.Id)
+	if err != nil {
+		return err
+	}
+	deletions, err := pu.pvzRepository.CountProductDeletions(ctx, reception.Id)
+	if err != nil {
+		return err
+	}
+	reopenedAt, err := pu.pvzRepository.GetLastReopenedAt(ctx, reception.Id)
+	if err != nil {
+		return err
+	}
+
+	summary := &models.ReceptionSummary{
+		ReceptionId:    reception.Id,
+		ClosedAt:       time.Now(),
+		ProductsByType: productsByType,
+		DeletionsCount: deletions,
+	}
+	for _, count := range productsByType {
+		summary.TotalProducts += count
+	}
+	// Считается только последний открытый интервал: предыдущие уже учтены в итогах прошлых закрытий,
+	// и SaveReceptionSummary прибавляет к ним новый.
+	openedAt := reception.DateTime
+	if reopenedAt.After(openedAt) {
+		openedAt = reopenedAt
+	}
+	if summary.ClosedAt.After(openedAt) {
+		summary.OpenDuration = summary.ClosedAt.Sub(openedAt)
+	}
+
+	if err = pu.pvzRepository.SaveReceptionSummary(ctx, summary); err != nil {
+		return err
+	}
+	reception.Summary = summary
+	return nil
+}
+
+// attachDiscrepancyReport строит и сохраняет отчёт о расхождениях; приёмки без манифеста отчёта не получают.
+func (pu ReceptionUsecase) attachDiscrepancyReport(ctx context.Context, reception *models.Reception) error {
+	manifest, err := pu.pvzRepository.GetManifest(ctx, reception.Id)
+	if err != nil {
+		return err
+	}
+	if len(manifest) == 0 {
+		return nil
+	}
+
+	products, err := pu.pvzRepository.GetProductsInReception(ctx, reception.Id)
+	if err != nil {
+		return err
+	}
+
+	receivedEarlier, err := pu.pvzRepository.GetExternalIdsReceivedEarlier(ctx, reception.Id)
+	if err != nil {
+		return err
+	}
+
+	report := buildDiscrepancyReport(reception.Id, manifest, products, receivedEarlier, time.Now())
+	if err = pu.pvzRepository.SaveDiscrepancyReport(ctx, report); err != nil {
+		return err
+	}
+	reception.DiscrepancyReport = report
+	return nil
 }
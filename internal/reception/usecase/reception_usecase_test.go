@@ -5,10 +5,12 @@ import (
 	"avito_spring_staj_2025/domain/requests"
 	"avito_spring_staj_2025/internal/service/middleware"
 	"avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
 	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
 )
@@ -18,7 +20,7 @@ func TestPvzUsecase_CreateReception(t *testing.T) {
 		name        string
 		ctx         func() context.Context
 		data        requests.CreateReceptionRequest
-		mockSetup   func(repository *repositoryMocks.MockReceptionRepository)
+		mockSetup   func(repository *repositoryMocks.MockReceptionRepository, schedules *usecaseMocks.ScheduleUsecaseMock)
 		expectedRes models.Reception
 		expectedErr error
 	}{
@@ -28,11 +30,13 @@ func TestPvzUsecase_CreateReception(t *testing.T) {
 				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
 			},
 			data: requests.CreateReceptionRequest{PvzId: "pvz123"},
-			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository, s *usecaseMocks.ScheduleUsecaseMock) {
 				m.On("GetCurrentReception", mock.Anything, "pvz123").
 					Return(nil, errors.New("no active reception"))
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, nil)
+				s.On("GetScheduleAt", mock.Anything, "pvz123", mock.AnythingOfType("time.Time")).
+					Return(models.PvzSchedule{Policy: models.WORKING_HOURS_POLICY_NONE}, nil)
 				m.On("CreateReception", mock.Anything, mock.MatchedBy(func(r models.Reception) bool {
 					return r.PvzId == "pvz123" && r.Status == "in_progress"
 				})).Return(nil)
@@ -43,13 +47,64 @@ func TestPvzUsecase_CreateReception(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "closed pvz with reject policy",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.CreateReceptionRequest{PvzId: "pvz123"},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository, s *usecaseMocks.ScheduleUsecaseMock) {
+				m.On("GetCurrentReception", mock.Anything, "pvz123").
+					Return(nil, errors.New("no active reception"))
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				s.On("GetScheduleAt", mock.Anything, "pvz123", mock.AnythingOfType("time.Time")).
+					Return(models.PvzSchedule{
+						TimeZone: "UTC",
+						Policy:   models.WORKING_HOURS_POLICY_REJECT,
+						Exceptions: []models.ScheduleException{
+							{Date: time.Now().UTC(), IsClosed: true},
+						},
+					}, nil)
+			},
+			expectedErr: errors.New("pvz is closed at this time"),
+		},
+		{
+			name: "closed pvz with flag policy",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.CreateReceptionRequest{PvzId: "pvz123"},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository, s *usecaseMocks.ScheduleUsecaseMock) {
+				m.On("GetCurrentReception", mock.Anything, "pvz123").
+					Return(nil, errors.New("no active reception"))
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				s.On("GetScheduleAt", mock.Anything, "pvz123", mock.AnythingOfType("time.Time")).
+					Return(models.PvzSchedule{
+						TimeZone: "UTC",
+						Policy:   models.WORKING_HOURS_POLICY_FLAG,
+						Exceptions: []models.ScheduleException{
+							{Date: time.Now().UTC(), IsClosed: true},
+						},
+					}, nil)
+				m.On("CreateReception", mock.Anything, mock.MatchedBy(func(r models.Reception) bool {
+					return r.OutsideWorkingHours
+				})).Return(nil)
+			},
+			expectedRes: models.Reception{
+				PvzId:  "pvz123",
+				Status: "in_progress",
+			},
+			expectedErr: nil,
+		},
 		{
 			name: "invalid role",
 			ctx: func() context.Context {
 				return context.WithValue(context.Background(), middleware.ContextKeyRole, "wrong_role")
 			},
 			data:        requests.CreateReceptionRequest{PvzId: "pvz123"},
-			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository, _ *usecaseMocks.ScheduleUsecaseMock) {},
 			expectedErr: errors.New("this role is not allowed"),
 		},
 		{
@@ -58,19 +113,38 @@ func TestPvzUsecase_CreateReception(t *testing.T) {
 				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
 			},
 			data: requests.CreateReceptionRequest{PvzId: "pvz123"},
-			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository, s *usecaseMocks.ScheduleUsecaseMock) {
 				m.On("GetCurrentReception", mock.Anything, "pvz123").
 					Return(&models.Reception{}, nil)
 			},
 			expectedErr: errors.New("active reception already exists"),
 		},
+		{
+			name: "concurrent reception created first",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.CreateReceptionRequest{PvzId: "pvz123"},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository, s *usecaseMocks.ScheduleUsecaseMock) {
+				m.On("GetCurrentReception", mock.Anything, "pvz123").
+					Return(nil, errors.New("no active reception"))
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				s.On("GetScheduleAt", mock.Anything, "pvz123", mock.AnythingOfType("time.Time")).
+					Return(models.PvzSchedule{Policy: models.WORKING_HOURS_POLICY_NONE}, nil)
+				m.On("CreateReception", mock.Anything, mock.Anything).
+					Return(errors.New("active reception already exists"))
+			},
+			expectedErr: errors.New("active reception already exists"),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(repositoryMocks.MockReceptionRepository)
-			uc := NewReceptionUsecase(mockRepo)
-			tt.mockSetup(mockRepo)
+			mockSchedules := new(usecaseMocks.ScheduleUsecaseMock)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), mockSchedules)
+			tt.mockSetup(mockRepo, mockSchedules)
 
 			res, err := uc.CreateReception(tt.ctx(), tt.data)
 
@@ -84,6 +158,7 @@ func TestPvzUsecase_CreateReception(t *testing.T) {
 
 			assert.Equal(t, tt.expectedErr, err)
 			mockRepo.AssertExpectations(t)
+			mockSchedules.AssertExpectations(t)
 		})
 	}
 }
@@ -109,9 +184,9 @@ func TestPvzUsecase_AddProductToReception(t *testing.T) {
 			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
 					Return(&models.Reception{Id: "reception123"}, nil)
-				m.On("AddProductToReception", mock.Anything, mock.Anything).
+				m.On("AddProductToReception", mock.Anything, "pvz123", mock.Anything).
 					Return(nil)
 			},
 			expectedRes: models.Product{
@@ -120,6 +195,122 @@ func TestPvzUsecase_AddProductToReception(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "success with barcode",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.AddProductRequest{
+				PvzId:      "pvz123",
+				Type:       models.CLOTHES_TYPE,
+				ExternalId: " BC-1 ",
+			},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("AddProductToReception", mock.Anything, "pvz123", mock.MatchedBy(func(p *models.Product) bool {
+					return p.ExternalId == "BC-1"
+				})).Return(nil)
+			},
+			expectedRes: models.Product{
+				ReceptionId: "reception123",
+				Type:        models.CLOTHES_TYPE,
+				ExternalId:  "BC-1",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "success with cell",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.AddProductRequest{
+				PvzId:    "pvz123",
+				Type:     models.CLOTHES_TYPE,
+				CellCode: " A-1 ",
+			},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("LockStorageCell", mock.Anything, "pvz123", "A-1").
+					Return(&models.StorageCell{Id: "cell1", Code: "A-1", Size: models.CELL_SIZE_L, Capacity: 3, Occupied: 2}, nil)
+				m.On("AddProductToReception", mock.Anything, "pvz123", mock.MatchedBy(func(p *models.Product) bool {
+					return p.CellId == "cell1"
+				})).Return(nil)
+			},
+			expectedRes: models.Product{
+				ReceptionId: "reception123",
+				Type:        models.CLOTHES_TYPE,
+				CellId:      "cell1",
+				CellCode:    "A-1",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "cell too small",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.AddProductRequest{
+				PvzId:    "pvz123",
+				Type:     models.CLOTHES_TYPE,
+				CellCode: "S-1",
+			},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("LockStorageCell", mock.Anything, "pvz123", "S-1").
+					Return(&models.StorageCell{Id: "cell2", Code: "S-1", Size: models.CELL_SIZE_S, Capacity: 1}, nil)
+			},
+			expectedErr: errors.New("cell is too small"),
+		},
+		{
+			name: "cell full",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.AddProductRequest{
+				PvzId:    "pvz123",
+				Type:     models.CLOTHES_TYPE,
+				CellCode: "A-1",
+			},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("LockStorageCell", mock.Anything, "pvz123", "A-1").
+					Return(&models.StorageCell{Id: "cell1", Code: "A-1", Size: models.CELL_SIZE_M, Capacity: 1, Occupied: 1}, nil)
+			},
+			expectedErr: errors.New("cell is full"),
+		},
+		{
+			name: "duplicate barcode scan",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.AddProductRequest{
+				PvzId:      "pvz123",
+				Type:       models.CLOTHES_TYPE,
+				ExternalId: "BC-1",
+			},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("AddProductToReception", mock.Anything, "pvz123", mock.Anything).
+					Return(errors.New("product already scanned"))
+			},
+			expectedRes: models.Product{},
+			expectedErr: errors.New("product already scanned"),
+		},
 		{
 			name: "invalid product type",
 			ctx: func() context.Context {
@@ -132,6 +323,18 @@ func TestPvzUsecase_AddProductToReception(t *testing.T) {
 			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
 			expectedErr: errors.New("this type is not allowed"),
 		},
+		{
+			name: "inactive product type",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			data: requests.AddProductRequest{
+				PvzId: "pvz123",
+				Type:  "шины",
+			},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("this type is not allowed"),
+		},
 		{
 			name: "invalid role",
 			ctx: func() context.Context {
@@ -146,10 +349,13 @@ func TestPvzUsecase_AddProductToReception(t *testing.T) {
 		},
 	}
 
+	catalog := usecaseMocks.DefaultProductTypeCatalog()
+	catalog["шины"] = false
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(repositoryMocks.MockReceptionRepository)
-			uc := NewReceptionUsecase(mockRepo)
+			uc := NewReceptionUsecase(mockRepo, catalog, new(usecaseMocks.ScheduleUsecaseMock))
 			tt.mockSetup(mockRepo)
 
 			res, err := uc.AddProductToReception(tt.ctx(), tt.data)
@@ -167,150 +373,178 @@ func TestPvzUsecase_AddProductToReception(t *testing.T) {
 	}
 }
 
-func TestPvzUsecase_DeleteLastProduct(t *testing.T) {
+func TestPvzUsecase_AddProductsToReception(t *testing.T) {
+	employeeCtx := func() context.Context {
+		return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+	}
+
 	tests := []struct {
 		name        string
 		ctx         func() context.Context
-		pvzId       string
+		data        requests.AddProductsBatchRequest
 		mockSetup   func(*repositoryMocks.MockReceptionRepository)
 		expectedErr error
 	}{
 		{
 			name: "success",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			ctx:  employeeCtx,
+			data: requests.AddProductsBatchRequest{
+				PvzId: "pvz123",
+				Products: []requests.BatchProductItem{
+					{Type: models.CLOTHES_TYPE, ExternalId: "barcode-1"},
+					{Type: models.BOOTS_TYPE},
+					{Type: models.ELECTRONIC_TYPE, ExternalId: "barcode-2"},
+				},
 			},
-			pvzId: "pvz123",
 			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
 					Return(&models.Reception{Id: "reception123"}, nil)
-				m.On("GetLastProductInReception", mock.Anything, "reception123").
-					Return(&models.Product{Id: "product123"}, nil)
-				m.On("DeleteProductById", mock.Anything, "product123").
-					Return(nil)
+				m.On("AddProductsToReception", mock.Anything, "pvz123", mock.MatchedBy(func(products []models.Product) bool {
+					return len(products) == 3 &&
+						products[0].ExternalId == "barcode-1" && products[2].Type == models.ELECTRONIC_TYPE &&
+						products[0].DateTime.Before(products[1].DateTime) && products[1].DateTime.Before(products[2].DateTime)
+				})).Return(nil)
 			},
-			expectedErr: nil,
 		},
 		{
-			name: "invalid role",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "wrong_role")
+			name:        "empty batch",
+			ctx:         employeeCtx,
+			data:        requests.AddProductsBatchRequest{PvzId: "pvz123"},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("empty product batch"),
+		},
+		{
+			name: "invalid item rejects whole batch",
+			ctx:  employeeCtx,
+			data: requests.AddProductsBatchRequest{
+				PvzId: "pvz123",
+				Products: []requests.BatchProductItem{
+					{Type: models.CLOTHES_TYPE},
+					{Type: "мебель"},
+				},
 			},
-			pvzId:       "pvz123",
 			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
-			expectedErr: errors.New("this role is not allowed"),
+			expectedErr: errors.New("this type is not allowed"),
 		},
 		{
-			name: "pvz not found",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			name: "duplicate external id",
+			ctx:  employeeCtx,
+			data: requests.AddProductsBatchRequest{
+				PvzId: "pvz123",
+				Products: []requests.BatchProductItem{
+					{Type: models.CLOTHES_TYPE, ExternalId: "barcode-1"},
+					{Type: models.CLOTHES_TYPE, ExternalId: "barcode-1"},
+				},
 			},
-			pvzId: "pvz123",
-			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
-				m.On("GetPvzById", mock.Anything, "pvz123").
-					Return(&models.Pvz{}, errors.New("pvz not found"))
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("duplicate external id in batch"),
+		},
+		{
+			name: "duplicate external id after trimming",
+			ctx:  employeeCtx,
+			data: requests.AddProductsBatchRequest{
+				PvzId: "pvz123",
+				Products: []requests.BatchProductItem{
+					{Type: models.CLOTHES_TYPE, ExternalId: "barcode-1"},
+					{Type: models.CLOTHES_TYPE, ExternalId: " barcode-1 "},
+				},
 			},
-			expectedErr: errors.New("pvz not found"),
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("duplicate external id in batch"),
 		},
 		{
 			name: "no active reception",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			ctx:  employeeCtx,
+			data: requests.AddProductsBatchRequest{
+				PvzId:    "pvz123",
+				Products: []requests.BatchProductItem{{Type: models.CLOTHES_TYPE}},
 			},
-			pvzId: "pvz123",
 			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
 					Return(nil, errors.New("no active reception"))
 			},
 			expectedErr: errors.New("no active reception"),
 		},
 		{
-			name: "no products in reception",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
-			},
-			pvzId: "pvz123",
-			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
-				m.On("GetPvzById", mock.Anything, "pvz123").
-					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
-					Return(&models.Reception{Id: "reception123"}, nil)
-				m.On("GetLastProductInReception", mock.Anything, "reception123").
-					Return(&models.Product{}, errors.New("no products"))
-			},
-			expectedErr: errors.New("no products"),
-		},
-		{
-			name: "delete product error",
+			name: "invalid role",
 			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
 			},
-			pvzId: "pvz123",
-			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
-				m.On("GetPvzById", mock.Anything, "pvz123").
-					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
-					Return(&models.Reception{Id: "reception123"}, nil)
-				m.On("GetLastProductInReception", mock.Anything, "reception123").
-					Return(&models.Product{Id: "product123"}, nil)
-				m.On("DeleteProductById", mock.Anything, "product123").
-					Return(errors.New("delete error"))
+			data: requests.AddProductsBatchRequest{
+				PvzId:    "pvz123",
+				Products: []requests.BatchProductItem{{Type: models.CLOTHES_TYPE}},
 			},
-			expectedErr: errors.New("delete error"),
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(repositoryMocks.MockReceptionRepository)
-			uc := NewReceptionUsecase(mockRepo)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
 			tt.mockSetup(mockRepo)
 
-			err := uc.DeleteLastProduct(tt.ctx(), tt.pvzId)
+			res, err := uc.AddProductsToReception(tt.ctx(), tt.data)
+
 			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				require.Len(t, res, len(tt.data.Products))
+				for i, product := range res {
+					assert.NotEmpty(t, product.Id)
+					assert.Equal(t, "reception123", product.ReceptionId)
+					assert.Equal(t, tt.data.Products[i].Type, product.Type)
+				}
+			}
 			mockRepo.AssertExpectations(t)
 		})
 	}
 }
 
-func TestPvzUsecase_CloseReception(t *testing.T) {
+func TestPvzUsecase_DeleteLastProducts(t *testing.T) {
+	employeeCtx := func() context.Context {
+		ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+		return context.WithValue(ctx, middleware.ContextKeyUserId, "user-1")
+	}
+	lastTwo := []models.Product{
+		{Id: "product3", ReceptionId: "reception123", SeqNo: 3, Type: models.BOOTS_TYPE},
+		{Id: "product2", ReceptionId: "reception123", SeqNo: 2, Type: models.CLOTHES_TYPE, ExternalId: "BC-2"},
+	}
+
 	tests := []struct {
 		name        string
 		ctx         func() context.Context
 		pvzId       string
+		count       int
 		mockSetup   func(*repositoryMocks.MockReceptionRepository)
-		expectedRes models.Reception
+		expectedRes []models.Product
 		expectedErr error
 	}{
 		{
-			name: "success",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
-			},
+			name:  "success",
+			ctx:   employeeCtx,
 			pvzId: "pvz123",
+			count: 2,
 			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
-				reception := &models.Reception{
-					Id:     "reception123",
-					PvzId:  "pvz123",
-					Status: "closed",
-				}
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
-					Return(reception, nil)
-				m.On("CloseReception", mock.Anything, reception).
-					Return(nil)
-			},
-			expectedRes: models.Reception{
-				Id:     "reception123",
-				PvzId:  "pvz123",
-				Status: "closed",
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("GetLastProductsInReception", mock.Anything, "reception123", 2).
+					Return(lastTwo, nil)
+				m.On("DeleteProducts", mock.Anything, mock.MatchedBy(func(d []models.ProductDeletion) bool {
+					return len(d) == 2 &&
+						d[0].ProductId == "product3" && d[0].SeqNo == 3 &&
+						d[1].ProductId == "product2" && d[1].ExternalId == "BC-2" &&
+						d[0].Kind == models.PRODUCT_DELETION_UNDO && d[0].Reason == "" &&
+						d[0].DeletedBy == "user-1" && !d[0].DeletedAt.IsZero()
+				})).Return(nil)
 			},
-			expectedErr: nil,
+			expectedRes: lastTwo,
 		},
 		{
 			name: "invalid role",
@@ -318,56 +552,448 @@ func TestPvzUsecase_CloseReception(t *testing.T) {
 				return context.WithValue(context.Background(), middleware.ContextKeyRole, "wrong_role")
 			},
 			pvzId:       "pvz123",
+			count:       1,
 			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
-			expectedRes: models.Reception{},
 			expectedErr: errors.New("this role is not allowed"),
 		},
 		{
-			name: "pvz not found",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
-			},
+			name:        "zero count",
+			ctx:         employeeCtx,
+			pvzId:       "pvz123",
+			count:       0,
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("invalid count"),
+		},
+		{
+			name:        "count too large",
+			ctx:         employeeCtx,
+			pvzId:       "pvz123",
+			count:       models.MAX_PRODUCT_BATCH_SIZE + 1,
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("invalid count"),
+		},
+		{
+			name:  "pvz not found",
+			ctx:   employeeCtx,
 			pvzId: "pvz123",
+			count: 1,
 			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, errors.New("pvz not found"))
 			},
-			expectedRes: models.Reception{},
 			expectedErr: errors.New("pvz not found"),
 		},
 		{
-			name: "no active reception",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
-			},
+			name:  "no active reception",
+			ctx:   employeeCtx,
 			pvzId: "pvz123",
+			count: 1,
 			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
 					Return(nil, errors.New("no active reception"))
 			},
-			expectedRes: models.Reception{},
 			expectedErr: errors.New("no active reception"),
 		},
 		{
-			name: "close reception error",
-			ctx: func() context.Context {
-				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
-			},
+			name:  "no products in reception",
+			ctx:   employeeCtx,
 			pvzId: "pvz123",
+			count: 1,
 			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
-				reception := &models.Reception{
-					Id:     "reception123",
-					PvzId:  "pvz123",
-					Status: "in_progress",
-				}
 				m.On("GetPvzById", mock.Anything, "pvz123").
 					Return(&models.Pvz{}, nil)
-				m.On("GetCurrentReception", mock.Anything, "pvz123").
-					Return(reception, nil)
-				m.On("CloseReception", mock.Anything, mock.Anything).
-					Return(errors.New("close error"))
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("GetLastProductsInReception", mock.Anything, "reception123", 1).
+					Return(nil, errors.New("no products in reception"))
+			},
+			expectedErr: errors.New("no products in reception"),
+		},
+		{
+			name:  "not enough products",
+			ctx:   employeeCtx,
+			pvzId: "pvz123",
+			count: 3,
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("GetLastProductsInReception", mock.Anything, "reception123", 3).
+					Return(lastTwo, nil)
+			},
+			expectedErr: errors.New("not enough products in reception"),
+		},
+		{
+			name:  "delete products error",
+			ctx:   employeeCtx,
+			pvzId: "pvz123",
+			count: 2,
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("GetLastProductsInReception", mock.Anything, "reception123", 2).
+					Return(lastTwo, nil)
+				m.On("DeleteProducts", mock.Anything, mock.Anything).
+					Return(errors.New("delete error"))
+			},
+			expectedErr: errors.New("delete error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockReceptionRepository)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+			tt.mockSetup(mockRepo)
+
+			res, err := uc.DeleteLastProducts(tt.ctx(), tt.pvzId, tt.count)
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				assert.Equal(t, tt.expectedRes, res)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzUsecase_RemoveProduct(t *testing.T) {
+	employeeCtx := func() context.Context {
+		ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+		return context.WithValue(ctx, middleware.ContextKeyUserId, "user-1")
+	}
+	product := func(receptionId string) *models.Product {
+		return &models.Product{Id: "product2", ReceptionId: receptionId, SeqNo: 2, Type: models.CLOTHES_TYPE}
+	}
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.RemoveProductRequest
+		mockSetup   func(*repositoryMocks.MockReceptionRepository)
+		expectedRes models.Product
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  employeeCtx,
+			data: requests.RemoveProductRequest{ProductId: "product2", Reason: " повреждена упаковка "},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("GetProductById", mock.Anything, "product2").
+					Return(product("reception123"), nil)
+				m.On("DeleteProducts", mock.Anything, mock.MatchedBy(func(d []models.ProductDeletion) bool {
+					return len(d) == 1 && d[0].ProductId == "product2" && d[0].SeqNo == 2 &&
+						d[0].Kind == models.PRODUCT_DELETION_REMOVE && d[0].Reason == "повреждена упаковка" &&
+						d[0].DeletedBy == "user-1"
+				})).Return(nil)
+			},
+			expectedRes: *product("reception123"),
+		},
+		{
+			name: "invalid role",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+			},
+			data:        requests.RemoveProductRequest{ProductId: "product2", Reason: "r"},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "missing product id",
+			ctx:         employeeCtx,
+			data:        requests.RemoveProductRequest{Reason: "r"},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("product id is required"),
+		},
+		{
+			name:        "missing reason",
+			ctx:         employeeCtx,
+			data:        requests.RemoveProductRequest{ProductId: "product2", Reason: "  "},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("remove reason is required"),
+		},
+		{
+			name: "product not found",
+			ctx:  employeeCtx,
+			data: requests.RemoveProductRequest{ProductId: "product2", Reason: "r"},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("GetProductById", mock.Anything, "product2").
+					Return(nil, errors.New("product not found"))
+			},
+			expectedErr: errors.New("product not found"),
+		},
+		{
+			name: "product from another reception",
+			ctx:  employeeCtx,
+			data: requests.RemoveProductRequest{ProductId: "product2", Reason: "r"},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "reception123"}, nil)
+				m.On("GetProductById", mock.Anything, "product2").
+					Return(product("old-reception"), nil)
+			},
+			expectedErr: errors.New("product not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockReceptionRepository)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+			tt.mockSetup(mockRepo)
+
+			res, err := uc.RemoveProduct(tt.ctx(), "pvz123", tt.data)
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedRes, res)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzUsecase_CloseReception(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		pvzId       string
+		mockSetup   func(*repositoryMocks.MockReceptionRepository)
+		expectedRes models.Reception
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			pvzId: "pvz123",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				reception := &models.Reception{
+					Id:     "reception123",
+					PvzId:  "pvz123",
+					Status: "closed",
+				}
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(reception, nil)
+				m.On("CloseReception", mock.Anything, reception).
+					Return(nil)
+				m.On("CountProductsByType", mock.Anything, "reception123").
+					Return(map[string]int{models.CLOTHES_TYPE: 1}, nil)
+				m.On("CountProductDeletions", mock.Anything, "reception123").
+					Return(2, nil)
+				m.On("GetLastReopenedAt", mock.Anything, "reception123").
+					Return(time.Time{}, nil)
+				m.On("SaveReceptionSummary", mock.Anything, mock.Anything).
+					Return(nil)
+				m.On("SetStorageDeadlines", mock.Anything, "reception123", mock.AnythingOfType("time.Time")).
+					Return(nil)
+				m.On("GetManifest", mock.Anything, "reception123").
+					Return([]models.ManifestItem{}, nil)
+			},
+			expectedRes: models.Reception{
+				Id:     "reception123",
+				PvzId:  "pvz123",
+				Status: "closed",
+				Summary: &models.ReceptionSummary{
+					ReceptionId:    "reception123",
+					TotalProducts:  1,
+					ProductsByType: map[string]int{models.CLOTHES_TYPE: 1},
+					DeletionsCount: 2,
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "success with manifest",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			pvzId: "pvz123",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				reception := &models.Reception{
+					Id:     "reception123",
+					PvzId:  "pvz123",
+					Status: "closed",
+				}
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(reception, nil)
+				m.On("CloseReception", mock.Anything, reception).
+					Return(nil)
+				m.On("CountProductsByType", mock.Anything, "reception123").
+					Return(map[string]int{models.CLOTHES_TYPE: 1}, nil)
+				m.On("CountProductDeletions", mock.Anything, "reception123").
+					Return(2, nil)
+				m.On("GetLastReopenedAt", mock.Anything, "reception123").
+					Return(time.Time{}, nil)
+				m.On("SaveReceptionSummary", mock.Anything, mock.Anything).
+					Return(nil)
+				m.On("SetStorageDeadlines", mock.Anything, "reception123", mock.AnythingOfType("time.Time")).
+					Return(nil)
+				m.On("GetManifest", mock.Anything, "reception123").
+					Return([]models.ManifestItem{
+						{ExternalId: "BC-1", Type: models.CLOTHES_TYPE},
+						{ExternalId: "BC-2", Type: models.BOOTS_TYPE},
+					}, nil)
+				m.On("GetProductsInReception", mock.Anything, "reception123").
+					Return([]models.Product{
+						{Id: "p1", ExternalId: "BC-1", Type: models.CLOTHES_TYPE},
+					}, nil)
+				m.On("GetExternalIdsReceivedEarlier", mock.Anything, "reception123").
+					Return(map[string]struct{}{}, nil)
+				m.On("SaveDiscrepancyReport", mock.Anything, mock.MatchedBy(func(report *models.DiscrepancyReport) bool {
+					return report.ReceptionId == "reception123" && report.MatchedCount == 1 && len(report.Items) == 1
+				})).Return(nil)
+			},
+			expectedRes: models.Reception{
+				Id:     "reception123",
+				PvzId:  "pvz123",
+				Status: "closed",
+				Summary: &models.ReceptionSummary{
+					ReceptionId:    "reception123",
+					TotalProducts:  1,
+					ProductsByType: map[string]int{models.CLOTHES_TYPE: 1},
+					DeletionsCount: 2,
+				},
+				DiscrepancyReport: &models.DiscrepancyReport{
+					ReceptionId:   "reception123",
+					ExpectedCount: 2,
+					ReceivedCount: 1,
+					MatchedCount:  1,
+					Items: []models.DiscrepancyItem{
+						{Kind: models.DISCREPANCY_MISSING, ExternalId: "BC-2", ExpectedType: models.BOOTS_TYPE},
+					},
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "summary error",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			pvzId: "pvz123",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				reception := &models.Reception{Id: "reception123", PvzId: "pvz123"}
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(reception, nil)
+				m.On("CloseReception", mock.Anything, reception).
+					Return(nil)
+				m.On("CountProductsByType", mock.Anything, "reception123").
+					Return(nil, errors.New("db error"))
+			},
+			expectedRes: models.Reception{},
+			expectedErr: errors.New("db error"),
+		},
+		{
+			name: "save report error",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			pvzId: "pvz123",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				reception := &models.Reception{Id: "reception123", PvzId: "pvz123"}
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(reception, nil)
+				m.On("CloseReception", mock.Anything, reception).
+					Return(nil)
+				m.On("CountProductsByType", mock.Anything, "reception123").
+					Return(map[string]int{models.CLOTHES_TYPE: 1}, nil)
+				m.On("CountProductDeletions", mock.Anything, "reception123").
+					Return(2, nil)
+				m.On("GetLastReopenedAt", mock.Anything, "reception123").
+					Return(time.Time{}, nil)
+				m.On("SaveReceptionSummary", mock.Anything, mock.Anything).
+					Return(nil)
+				m.On("SetStorageDeadlines", mock.Anything, "reception123", mock.AnythingOfType("time.Time")).
+					Return(nil)
+				m.On("GetManifest", mock.Anything, "reception123").
+					Return([]models.ManifestItem{{ExternalId: "BC-1", Type: models.CLOTHES_TYPE}}, nil)
+				m.On("GetProductsInReception", mock.Anything, "reception123").
+					Return([]models.Product{}, nil)
+				m.On("GetExternalIdsReceivedEarlier", mock.Anything, "reception123").
+					Return(map[string]struct{}{}, nil)
+				m.On("SaveDiscrepancyReport", mock.Anything, mock.Anything).
+					Return(errors.New("db error"))
+			},
+			expectedRes: models.Reception{},
+			expectedErr: errors.New("db error"),
+		},
+		{
+			name: "invalid role",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "wrong_role")
+			},
+			pvzId:       "pvz123",
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedRes: models.Reception{},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name: "pvz not found",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			pvzId: "pvz123",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, errors.New("pvz not found"))
+			},
+			expectedRes: models.Reception{},
+			expectedErr: errors.New("pvz not found"),
+		},
+		{
+			name: "no active reception",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			pvzId: "pvz123",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(nil, errors.New("no active reception"))
+			},
+			expectedRes: models.Reception{},
+			expectedErr: errors.New("no active reception"),
+		},
+		{
+			name: "close reception error",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			pvzId: "pvz123",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				reception := &models.Reception{
+					Id:     "reception123",
+					PvzId:  "pvz123",
+					Status: "in_progress",
+				}
+				m.On("GetPvzById", mock.Anything, "pvz123").
+					Return(&models.Pvz{}, nil)
+				m.On("LockCurrentReception", mock.Anything, "pvz123").
+					Return(reception, nil)
+				m.On("CloseReception", mock.Anything, mock.Anything).
+					Return(errors.New("close error"))
 			},
 			expectedRes: models.Reception{},
 			expectedErr: errors.New("close error"),
@@ -377,13 +1003,508 @@ func TestPvzUsecase_CloseReception(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(repositoryMocks.MockReceptionRepository)
-			uc := NewReceptionUsecase(mockRepo)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
 			tt.mockSetup(mockRepo)
 
 			res, err := uc.CloseReception(tt.ctx(), tt.pvzId)
+			if res.Summary != nil {
+				assert.False(t, res.Summary.ClosedAt.IsZero())
+				assert.Positive(t, res.Summary.OpenDuration)
+				res.Summary.ClosedAt = time.Time{}
+				res.Summary.OpenDuration = 0
+			}
+			if res.DiscrepancyReport != nil {
+				assert.False(t, res.DiscrepancyReport.CreatedAt.IsZero())
+				res.DiscrepancyReport.CreatedAt = time.Time{}
+			}
 			assert.Equal(t, tt.expectedRes, res)
 			assert.Equal(t, tt.expectedErr, err)
 			mockRepo.AssertExpectations(t)
 		})
 	}
 }
+
+// Переоткрытая приёмка считает время работы от переоткрытия: прошлый интервал уже учтён в итогах первого закрытия.
+func TestPvzUsecase_CloseReception_AfterReopenMeasuresFromReopen(t *testing.T) {
+	mockRepo := new(repositoryMocks.MockReceptionRepository)
+	uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+	reception := &models.Reception{
+		Id:       "reception123",
+		PvzId:    "pvz123",
+		DateTime: time.Now().Add(-48 * time.Hour),
+		Status:   models.STATUS_ACTIVE,
+	}
+	reopenedAt := time.Now().Add(-10 * time.Minute)
+
+	mockRepo.On("GetPvzById", mock.Anything, "pvz123").Return(&models.Pvz{}, nil)
+	mockRepo.On("LockCurrentReception", mock.Anything, "pvz123").Return(reception, nil)
+	mockRepo.On("CloseReception", mock.Anything, reception).Return(nil)
+	mockRepo.On("CountProductsByType", mock.Anything, "reception123").Return(map[string]int{}, nil)
+	mockRepo.On("CountProductDeletions", mock.Anything, "reception123").Return(0, nil)
+	mockRepo.On("GetLastReopenedAt", mock.Anything, "reception123").Return(reopenedAt, nil)
+	mockRepo.On("SaveReceptionSummary", mock.Anything, mock.MatchedBy(func(summary *models.ReceptionSummary) bool {
+		return summary.OpenDuration >= 10*time.Minute && summary.OpenDuration < 11*time.Minute
+	})).Return(nil)
+	mockRepo.On("SetStorageDeadlines", mock.Anything, "reception123", mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("GetManifest", mock.Anything, "reception123").Return([]models.ManifestItem{}, nil)
+
+	ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+	_, err := uc.CloseReception(ctx, "pvz123")
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPvzUsecase_ReopenReception(t *testing.T) {
+	moderatorCtx := func() context.Context {
+		ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+		return context.WithValue(ctx, middleware.ContextKeyUserId, "moderator-1")
+	}
+	closed := func() *models.Reception {
+		return &models.Reception{Id: "rec1", PvzId: "pvz123", Status: models.STATUS_CLOSED}
+	}
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		reason      string
+		mockSetup   func(*repositoryMocks.MockReceptionRepository)
+		expectedRes models.Reception
+		expectedErr error
+	}{
+		{
+			name:   "success",
+			ctx:    moderatorCtx,
+			reason: "  забыли отсканировать коробку ",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").Return(closed(), nil)
+				m.On("GetLastReceptionId", mock.Anything, "pvz123").Return("rec1", nil)
+				m.On("GetCurrentReception", mock.Anything, "pvz123").
+					Return(nil, errors.New("no active reception"))
+				m.On("ReopenReception", mock.Anything, mock.MatchedBy(func(r models.ReceptionReopen) bool {
+					return r.ReceptionId == "rec1" && r.ReopenedBy == "moderator-1" &&
+						r.Reason == "забыли отсканировать коробку" && !r.ReopenedAt.IsZero()
+				})).Return(nil)
+			},
+			expectedRes: models.Reception{Id: "rec1", PvzId: "pvz123", Status: models.STATUS_ACTIVE},
+		},
+		{
+			name: "invalid role",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			reason:      "reason",
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "empty reason",
+			ctx:         moderatorCtx,
+			reason:      "   ",
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("reopen reason is required"),
+		},
+		{
+			name:   "reception not found",
+			ctx:    moderatorCtx,
+			reason: "reason",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(nil, errors.New("reception not found"))
+			},
+			expectedErr: errors.New("reception not found"),
+		},
+		{
+			name:   "reception is not closed",
+			ctx:    moderatorCtx,
+			reason: "reason",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(&models.Reception{Id: "rec1", PvzId: "pvz123", Status: models.STATUS_ACTIVE}, nil)
+			},
+			expectedErr: errors.New("reception is not closed"),
+		},
+		{
+			name:   "not the last reception",
+			ctx:    moderatorCtx,
+			reason: "reason",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").Return(closed(), nil)
+				m.On("GetLastReceptionId", mock.Anything, "pvz123").Return("rec2", nil)
+			},
+			expectedErr: errors.New("only the last reception can be reopened"),
+		},
+		{
+			name:   "another reception in progress",
+			ctx:    moderatorCtx,
+			reason: "reason",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").Return(closed(), nil)
+				m.On("GetLastReceptionId", mock.Anything, "pvz123").Return("rec1", nil)
+				m.On("GetCurrentReception", mock.Anything, "pvz123").
+					Return(&models.Reception{Id: "rec2"}, nil)
+			},
+			expectedErr: errors.New("active reception already exists"),
+		},
+		{
+			name:   "reopen error",
+			ctx:    moderatorCtx,
+			reason: "reason",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").Return(closed(), nil)
+				m.On("GetLastReceptionId", mock.Anything, "pvz123").Return("rec1", nil)
+				m.On("GetCurrentReception", mock.Anything, "pvz123").
+					Return(nil, errors.New("no active reception"))
+				m.On("ReopenReception", mock.Anything, mock.Anything).Return(errors.New("db error"))
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockReceptionRepository)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+			tt.mockSetup(mockRepo)
+
+			res, reopen, err := uc.ReopenReception(tt.ctx(), "rec1", requests.ReopenReceptionRequest{Reason: tt.reason})
+			assert.Equal(t, tt.expectedRes, res)
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				assert.Equal(t, "moderator-1", reopen.ReopenedBy)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzUsecase_GetProductsByBarcode(t *testing.T) {
+	lookups := []models.ProductLookup{
+		{Product: models.Product{Id: "p1", ExternalId: "BC-1"}, PvzId: "pvz1", ReceptionStatus: models.STATUS_ACTIVE},
+	}
+
+	tests := []struct {
+		name        string
+		barcode     string
+		mockSetup   func(*repositoryMocks.MockReceptionRepository)
+		expectedRes []models.ProductLookup
+		expectedErr error
+	}{
+		{
+			name:    "success",
+			barcode: " BC-1 ",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetProductsByExternalId", mock.Anything, "BC-1").Return(lookups, nil)
+			},
+			expectedRes: lookups,
+		},
+		{
+			name:        "empty barcode",
+			barcode:     "  ",
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("barcode is required"),
+		},
+		{
+			name:    "repository error",
+			barcode: "BC-1",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetProductsByExternalId", mock.Anything, "BC-1").Return(nil, errors.New("db error"))
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockReceptionRepository)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+			tt.mockSetup(mockRepo)
+
+			res, err := uc.GetProductsByBarcode(context.Background(), tt.barcode)
+			assert.Equal(t, tt.expectedRes, res)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzUsecase_CloseStaleReceptions(t *testing.T) {
+	inactiveFor := 12 * time.Hour
+	reason := "no activity for more than 12h0m0s"
+	longAgo := time.Now().Add(-48 * time.Hour)
+
+	tests := []struct {
+		name        string
+		mockSetup   func(*repositoryMocks.MockReceptionRepository)
+		expectedIds []string
+		expectedErr string
+	}{
+		{
+			name: "closes stale receptions",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetStaleReceptions", mock.Anything, mock.Anything).
+					Return([]models.Reception{{Id: "rec1"}, {Id: "rec2"}}, nil)
+				for _, id := range []string{"rec1", "rec2"} {
+					m.On("LockReceptionById", mock.Anything, id).
+						Return(&models.Reception{Id: id, Status: models.STATUS_ACTIVE}, nil)
+					m.On("GetReceptionLastActivity", mock.Anything, id).Return(longAgo, nil)
+					m.On("AutoCloseReception", mock.Anything, &models.Reception{Id: id, Status: models.STATUS_ACTIVE}, reason).
+						Return(nil)
+					m.On("CountProductsByType", mock.Anything, id).Return(map[string]int{}, nil)
+					m.On("CountProductDeletions", mock.Anything, id).Return(0, nil)
+					m.On("GetLastReopenedAt", mock.Anything, id).Return(time.Time{}, nil)
+					m.On("SaveReceptionSummary", mock.Anything, mock.Anything).Return(nil)
+					m.On("SetStorageDeadlines", mock.Anything, id, mock.AnythingOfType("time.Time")).Return(nil)
+					m.On("GetManifest", mock.Anything, id).Return([]models.ManifestItem{}, nil)
+				}
+			},
+			expectedIds: []string{"rec1", "rec2"},
+		},
+		{
+			name: "skips reception closed or touched meanwhile",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetStaleReceptions", mock.Anything, mock.Anything).
+					Return([]models.Reception{{Id: "rec1"}, {Id: "rec2"}}, nil)
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(&models.Reception{Id: "rec1", Status: models.STATUS_CLOSED}, nil)
+				m.On("LockReceptionById", mock.Anything, "rec2").
+					Return(&models.Reception{Id: "rec2", Status: models.STATUS_ACTIVE}, nil)
+				m.On("GetReceptionLastActivity", mock.Anything, "rec2").Return(time.Now(), nil)
+			},
+			expectedIds: []string{},
+		},
+		{
+			name: "error on one reception does not stop the rest",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetStaleReceptions", mock.Anything, mock.Anything).
+					Return([]models.Reception{{Id: "rec1"}, {Id: "rec2"}}, nil)
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(nil, errors.New("db error"))
+				m.On("LockReceptionById", mock.Anything, "rec2").
+					Return(&models.Reception{Id: "rec2", Status: models.STATUS_ACTIVE}, nil)
+				m.On("GetReceptionLastActivity", mock.Anything, "rec2").Return(longAgo, nil)
+				m.On("AutoCloseReception", mock.Anything, mock.Anything, reason).Return(nil)
+				m.On("CountProductsByType", mock.Anything, "rec2").Return(map[string]int{}, nil)
+				m.On("CountProductDeletions", mock.Anything, "rec2").Return(0, nil)
+				m.On("GetLastReopenedAt", mock.Anything, "rec2").Return(time.Time{}, nil)
+				m.On("SaveReceptionSummary", mock.Anything, mock.Anything).Return(nil)
+				m.On("SetStorageDeadlines", mock.Anything, "rec2", mock.AnythingOfType("time.Time")).Return(nil)
+				m.On("GetManifest", mock.Anything, "rec2").Return([]models.ManifestItem{}, nil)
+			},
+			expectedIds: []string{"rec2"},
+			expectedErr: "reception rec1: db error",
+		},
+		{
+			name: "lookup error",
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("GetStaleReceptions", mock.Anything, mock.Anything).
+					Return(nil, errors.New("db error"))
+			},
+			expectedErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockReceptionRepository)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+			tt.mockSetup(mockRepo)
+
+			started := time.Now()
+			closed, err := uc.CloseStaleReceptions(context.Background(), inactiveFor)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tt.expectedIds != nil {
+				ids := make([]string, 0, len(closed))
+				for _, reception := range closed {
+					ids = append(ids, reception.Id)
+				}
+				assert.Equal(t, tt.expectedIds, ids)
+			}
+
+			before := mockRepo.Calls[0].Arguments.Get(1).(time.Time)
+			assert.WithinDuration(t, started.Add(-inactiveFor), before, time.Second)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// Переоткрытая старая приёмка не должна закрыться на ближайшем тике: её активность отсчитывается от переоткрытия.
+func TestPvzUsecase_CloseStaleReceptions_AfterReopen(t *testing.T) {
+	mockRepo := new(repositoryMocks.MockReceptionRepository)
+	uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+	createdAt := time.Now().Add(-48 * time.Hour)
+
+	var reopenedAt time.Time
+	mockRepo.On("LockReceptionById", mock.Anything, "rec1").
+		Return(&models.Reception{Id: "rec1", PvzId: "pvz123", DateTime: createdAt, Status: models.STATUS_CLOSED}, nil).Once()
+	mockRepo.On("GetLastReceptionId", mock.Anything, "pvz123").Return("rec1", nil)
+	mockRepo.On("GetCurrentReception", mock.Anything, "pvz123").Return(nil, errors.New("no active reception"))
+	mockRepo.On("ReopenReception", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		reopenedAt = args.Get(1).(models.ReceptionReopen).ReopenedAt
+	}).Return(nil)
+
+	ctx := context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+	_, _, err := uc.ReopenReception(ctx, "rec1", requests.ReopenReceptionRequest{Reason: "забыли коробку"})
+	require.NoError(t, err)
+
+	active := &models.Reception{Id: "rec1", PvzId: "pvz123", DateTime: createdAt, Status: models.STATUS_ACTIVE}
+	mockRepo.On("GetStaleReceptions", mock.Anything, mock.Anything).Return([]models.Reception{*active}, nil)
+	mockRepo.On("LockReceptionById", mock.Anything, "rec1").Return(active, nil).Once()
+	mockRepo.On("GetReceptionLastActivity", mock.Anything, "rec1").Return(reopenedAt, nil)
+
+	closed, err := uc.CloseStaleReceptions(context.Background(), 12*time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, closed)
+	mockRepo.AssertNotCalled(t, "AutoCloseReception", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPvzUsecase_SetReceptionManifest(t *testing.T) {
+	employeeCtx := func() context.Context {
+		return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+	}
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.SetManifestRequest
+		mockSetup   func(*repositoryMocks.MockReceptionRepository)
+		expectedIds []string
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  employeeCtx,
+			data: requests.SetManifestRequest{Items: []requests.ManifestItemRequest{
+				{Type: models.CLOTHES_TYPE, ExternalId: " BC-1 "},
+				{Type: models.BOOTS_TYPE, ExternalId: "BC-2"},
+			}},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(&models.Reception{Id: "rec1", Status: models.STATUS_ACTIVE}, nil)
+				m.On("ReplaceManifest", mock.Anything, "rec1", mock.MatchedBy(func(items []models.ManifestItem) bool {
+					return len(items) == 2 && items[0].ExternalId == "BC-1" && items[0].ReceptionId == "rec1" && items[0].Id != ""
+				})).Return(nil)
+			},
+			expectedIds: []string{"BC-1", "BC-2"},
+		},
+		{
+			name: "moderator allowed",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+			},
+			data: requests.SetManifestRequest{Items: []requests.ManifestItemRequest{{Type: models.CLOTHES_TYPE, ExternalId: "BC-1"}}},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(&models.Reception{Id: "rec1", Status: models.STATUS_ACTIVE}, nil)
+				m.On("ReplaceManifest", mock.Anything, "rec1", mock.Anything).Return(nil)
+			},
+			expectedIds: []string{"BC-1"},
+		},
+		{
+			name: "invalid role",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "client")
+			},
+			data:        requests.SetManifestRequest{Items: []requests.ManifestItemRequest{{Type: models.CLOTHES_TYPE, ExternalId: "BC-1"}}},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "empty manifest",
+			ctx:         employeeCtx,
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("empty manifest"),
+		},
+		{
+			name:        "too many items",
+			ctx:         employeeCtx,
+			data:        requests.SetManifestRequest{Items: make([]requests.ManifestItemRequest, models.MAX_MANIFEST_SIZE+1)},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("too many items in manifest"),
+		},
+		{
+			name:        "missing external id",
+			ctx:         employeeCtx,
+			data:        requests.SetManifestRequest{Items: []requests.ManifestItemRequest{{Type: models.CLOTHES_TYPE, ExternalId: " "}}},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("external id is required"),
+		},
+		{
+			name: "duplicate external id",
+			ctx:  employeeCtx,
+			data: requests.SetManifestRequest{Items: []requests.ManifestItemRequest{
+				{Type: models.CLOTHES_TYPE, ExternalId: "BC-1"},
+				{Type: models.BOOTS_TYPE, ExternalId: "BC-1 "},
+			}},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("duplicate external id in manifest"),
+		},
+		{
+			name:        "unknown type",
+			ctx:         employeeCtx,
+			data:        requests.SetManifestRequest{Items: []requests.ManifestItemRequest{{Type: "мебель", ExternalId: "BC-1"}}},
+			mockSetup:   func(_ *repositoryMocks.MockReceptionRepository) {},
+			expectedErr: errors.New("this type is not allowed"),
+		},
+		{
+			name: "reception closed",
+			ctx:  employeeCtx,
+			data: requests.SetManifestRequest{Items: []requests.ManifestItemRequest{{Type: models.CLOTHES_TYPE, ExternalId: "BC-1"}}},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(&models.Reception{Id: "rec1", Status: models.STATUS_CLOSED}, nil)
+			},
+			expectedErr: errors.New("reception is not in progress"),
+		},
+		{
+			name: "reception not found",
+			ctx:  employeeCtx,
+			data: requests.SetManifestRequest{Items: []requests.ManifestItemRequest{{Type: models.CLOTHES_TYPE, ExternalId: "BC-1"}}},
+			mockSetup: func(m *repositoryMocks.MockReceptionRepository) {
+				m.On("LockReceptionById", mock.Anything, "rec1").
+					Return(nil, errors.New("reception not found"))
+			},
+			expectedErr: errors.New("reception not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockReceptionRepository)
+			uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+			tt.mockSetup(mockRepo)
+
+			items, err := uc.SetReceptionManifest(tt.ctx(), "rec1", tt.data)
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				ids := make([]string, 0, len(items))
+				for _, item := range items {
+					ids = append(ids, item.ExternalId)
+				}
+				assert.Equal(t, tt.expectedIds, ids)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzUsecase_GetDiscrepancyReport(t *testing.T) {
+	mockRepo := new(repositoryMocks.MockReceptionRepository)
+	uc := NewReceptionUsecase(mockRepo, usecaseMocks.DefaultProductTypeCatalog(), new(usecaseMocks.ScheduleUsecaseMock))
+
+	report := &models.DiscrepancyReport{ReceptionId: "rec1", ExpectedCount: 1, MatchedCount: 1}
+	mockRepo.On("GetDiscrepancyReport", mock.Anything, "rec1").Return(report, nil)
+	mockRepo.On("GetDiscrepancyReport", mock.Anything, "rec2").Return(nil, errors.New("discrepancy report not found"))
+
+	res, err := uc.GetDiscrepancyReport(context.Background(), "rec1")
+	require.NoError(t, err)
+	assert.Equal(t, *report, res)
+
+	_, err = uc.GetDiscrepancyReport(context.Background(), "rec2")
+	assert.EqualError(t, err, "discrepancy report not found")
+}
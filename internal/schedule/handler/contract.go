@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"context"
+)
+
+type ScheduleUsecase interface {
+	GetSchedule(ctx context.Context, pvzId string) (models.PvzSchedule, error)
+	SetWorkingHours(ctx context.Context, pvzId string, data requests.SetWorkingHoursRequest) (models.PvzSchedule, error)
+	AddScheduleException(ctx context.Context, pvzId string, data requests.AddScheduleExceptionRequest) (models.ScheduleException, error)
+	DeleteScheduleException(ctx context.Context, pvzId, exceptionId string) error
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type ScheduleHandler struct {
+	usecase ScheduleUsecase
+}
+
+func NewScheduleHandler(usecase ScheduleUsecase) *ScheduleHandler {
+	return &ScheduleHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *ScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	schedule, err := h.usecase.GetSchedule(ctx, pvzId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toScheduleResponse(schedule)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ScheduleHandler) SetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.SetWorkingHoursRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data.TimeZone = sanitizer.Sanitize(data.TimeZone)
+	data.Policy = sanitizer.Sanitize(data.Policy)
+
+	schedule, err := h.usecase.SetWorkingHours(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(toScheduleResponse(schedule)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ScheduleHandler) AddScheduleException(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.AddScheduleExceptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data = requests.AddScheduleExceptionRequest{
+		Date:      sanitizer.Sanitize(data.Date),
+		IsClosed:  data.IsClosed,
+		OpenTime:  sanitizer.Sanitize(data.OpenTime),
+		CloseTime: sanitizer.Sanitize(data.CloseTime),
+		Reason:    sanitizer.Sanitize(data.Reason),
+	}
+
+	exception, err := h.usecase.AddScheduleException(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(toExceptionResponse(exception)); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+}
+
+func (h *ScheduleHandler) DeleteScheduleException(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	vars := mux.Vars(r)
+	pvzId := sanitizer.Sanitize(vars["pvzId"])
+	exceptionId := sanitizer.Sanitize(vars["exceptionId"])
+
+	if err := h.usecase.DeleteScheduleException(ctx, pvzId, exceptionId); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func toScheduleResponse(schedule models.PvzSchedule) responses.PvzScheduleResponse {
+	hours := make([]responses.WorkingHoursResponse, 0, len(schedule.WorkingHours))
+	for _, h := range schedule.WorkingHours {
+		hours = append(hours, responses.WorkingHoursResponse{
+			Weekday:   h.Weekday,
+			OpenTime:  formatClock(h.OpenTime),
+			CloseTime: formatClock(h.CloseTime),
+		})
+	}
+	exceptions := make([]responses.ScheduleExceptionResponse, 0, len(schedule.Exceptions))
+	for _, e := range schedule.Exceptions {
+		exceptions = append(exceptions, toExceptionResponse(e))
+	}
+	return responses.PvzScheduleResponse{
+		PvzId:        schedule.PvzId,
+		TimeZone:     schedule.TimeZone,
+		Policy:       schedule.Policy,
+		WorkingHours: hours,
+		Exceptions:   exceptions,
+	}
+}
+
+func toExceptionResponse(exception models.ScheduleException) responses.ScheduleExceptionResponse {
+	return responses.ScheduleExceptionResponse{
+		Id:        exception.Id,
+		Date:      exception.Date.Format(models.DATE_LAYOUT),
+		IsClosed:  exception.IsClosed,
+		OpenTime:  formatClock(exception.OpenTime),
+		CloseTime: formatClock(exception.CloseTime),
+		Reason:    exception.Reason,
+	}
+}
+
+// formatClock приводит время из базы ("09:00:00") к формату API ("09:00").
+func formatClock(value string) string {
+	minutes, err := models.ParseClock(value)
+	if err != nil {
+		return value
+	}
+	return time.Date(0, 1, 1, 0, minutes, 0, 0, time.UTC).Format("15:04")
+}
+
+func (h *ScheduleHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "pvz not found", "invalid time zone", "invalid working hours policy",
+		"invalid working hours", "overnight working hours are not supported", "invalid exception date":
+		w.WriteHeader(http.StatusBadRequest)
+	case "schedule exception not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "schedule exception already exists":
+		w.WriteHeader(http.StatusConflict)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
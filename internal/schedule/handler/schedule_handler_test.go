@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleHandler_GetSchedule(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	tests := []struct {
+		name           string
+		mockBehavior   func(*usecaseMocks.ScheduleUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			mockBehavior: func(usecase *usecaseMocks.ScheduleUsecaseMock) {
+				usecase.On("GetSchedule", mock.Anything, "pvz1").Return(models.PvzSchedule{
+					PvzId:    "pvz1",
+					TimeZone: "Europe/Moscow",
+					Policy:   models.WORKING_HOURS_POLICY_FLAG,
+					WorkingHours: []models.WorkingHours{
+						{Weekday: 1, OpenTime: "09:00:00", CloseTime: "21:00:00"},
+					},
+					Exceptions: []models.ScheduleException{
+						{Id: "ex1", Date: time.Date(2025, 5, 9, 0, 0, 0, 0, time.UTC), IsClosed: true, Reason: "holiday"},
+					},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"pvzId":"pvz1","timeZone":"Europe/Moscow","policy":"flag",
+				"workingHours":[{"weekday":1,"openTime":"09:00","closeTime":"21:00"}],
+				"exceptions":[{"id":"ex1","date":"2025-05-09","isClosed":true,"reason":"holiday"}]}`,
+		},
+		{
+			name: "pvz not found",
+			mockBehavior: func(usecase *usecaseMocks.ScheduleUsecaseMock) {
+				usecase.On("GetSchedule", mock.Anything, "pvz1").Return(models.PvzSchedule{}, errors.New("pvz not found"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"pvz not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ScheduleUsecaseMock)
+			handler := NewScheduleHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/pvz/pvz1/schedule", nil)
+			req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz1"})
+			w := httptest.NewRecorder()
+
+			handler.GetSchedule(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScheduleHandler_SetWorkingHours(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	tests := []struct {
+		name           string
+		inputBody      string
+		mockBehavior   func(*usecaseMocks.ScheduleUsecaseMock)
+		expectedStatus int
+	}{
+		{
+			name:      "success",
+			inputBody: `{"timeZone":"Europe/Moscow","policy":"reject","workingHours":[{"weekday":1,"openTime":"09:00","closeTime":"21:00"}]}`,
+			mockBehavior: func(usecase *usecaseMocks.ScheduleUsecaseMock) {
+				usecase.On("SetWorkingHours", mock.Anything, "pvz1", requests.SetWorkingHoursRequest{
+					TimeZone:     "Europe/Moscow",
+					Policy:       "reject",
+					WorkingHours: []requests.WorkingHoursItem{{Weekday: 1, OpenTime: "09:00", CloseTime: "21:00"}},
+				}).Return(models.PvzSchedule{PvzId: "pvz1"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "forbidden",
+			inputBody: `{"policy":"reject"}`,
+			mockBehavior: func(usecase *usecaseMocks.ScheduleUsecaseMock) {
+				usecase.On("SetWorkingHours", mock.Anything, "pvz1", mock.Anything).
+					Return(models.PvzSchedule{}, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "bad json",
+			inputBody:      `{"policy":`,
+			mockBehavior:   func(_ *usecaseMocks.ScheduleUsecaseMock) {},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ScheduleUsecaseMock)
+			handler := NewScheduleHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/pvz/pvz1/schedule", strings.NewReader(tt.inputBody))
+			req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz1"})
+			w := httptest.NewRecorder()
+
+			handler.SetWorkingHours(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScheduleHandler_DeleteScheduleException(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+
+	tests := []struct {
+		name           string
+		usecaseErr     error
+		expectedStatus int
+	}{
+		{name: "success", expectedStatus: http.StatusOK},
+		{name: "not found", usecaseErr: errors.New("schedule exception not found"), expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.ScheduleUsecaseMock)
+			handler := NewScheduleHandler(mockUsecase)
+			mockUsecase.On("DeleteScheduleException", mock.Anything, "pvz1", "ex1").Return(tt.usecaseErr)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/pvz/pvz1/schedule/exceptions/ex1", nil)
+			req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz1", "exceptionId": "ex1"})
+			w := httptest.NewRecorder()
+
+			handler.DeleteScheduleException(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"time"
+)
+
+const uniqueViolationCode = "23505"
+
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) ScheduleRepository {
+	return ScheduleRepository{
+		db: db,
+	}
+}
+
+func (r ScheduleRepository) GetPvzSchedule(ctx context.Context, pvzId string) (*models.PvzSchedule, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetPvzSchedule called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	schedule, err := r.getWeeklySchedule(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	schedule.Exceptions, err = r.getScheduleExceptions(ctx, pvzId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// GetPvzScheduleAt возвращает расписание ПВЗ с исключениями только на локальную дату момента at
+// и предыдущий день: чтобы проверить часы работы, вся история исключений не нужна.
+func (r ScheduleRepository) GetPvzScheduleAt(ctx context.Context, pvzId string, at time.Time) (*models.PvzSchedule, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetPvzScheduleAt called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+		zap.Time("at", at),
+	)
+
+	schedule, err := r.getWeeklySchedule(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, errors.New("invalid time zone")
+	}
+	local := at.In(loc)
+	schedule.Exceptions, err = r.getScheduleExceptions(ctx, pvzId, []string{
+		local.AddDate(0, 0, -1).Format(models.DATE_LAYOUT),
+		local.Format(models.DATE_LAYOUT),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// getWeeklySchedule читает настройки расписания ПВЗ и недельные часы работы без исключений.
+func (r ScheduleRepository) getWeeklySchedule(ctx context.Context, pvzId string) (*models.PvzSchedule, error) {
+	requestID := middleware.GetRequestID(ctx)
+
+	query, args, err := sq.Select("id", "time_zone", "working_hours_policy").
+		From("pvzs").
+		Where(sq.Eq{"id": pvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var schedule models.PvzSchedule
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&schedule.PvzId, &schedule.TimeZone, &schedule.Policy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.DBLogger.Info("pvz not found",
+				zap.String("request_id", requestID),
+				zap.String("pvz_id", pvzId),
+			)
+			return nil, errors.New("pvz not found")
+		}
+		logger.DBLogger.Error("failed to scan pvz schedule", zap.Error(err))
+		return nil, err
+	}
+
+	schedule.WorkingHours, err = r.getWorkingHours(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func (r ScheduleRepository) getWorkingHours(ctx context.Context, pvzId string) ([]models.WorkingHours, error) {
+	query, args, err := sq.Select("weekday", "open_time", "close_time").
+		From("pvz_working_hours").
+		Where(sq.Eq{"pvz_id": pvzId}).
+		OrderBy("weekday").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query working hours", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var hours []models.WorkingHours
+	for rows.Next() {
+		var h models.WorkingHours
+		if err := rows.Scan(&h.Weekday, &h.OpenTime, &h.CloseTime); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+
+	return hours, rows.Err()
+}
+
+// getScheduleExceptions возвращает исключения ПВЗ на даты dates (в формате DATE_LAYOUT), а без них — все.
+func (r ScheduleRepository) getScheduleExceptions(ctx context.Context, pvzId string, dates []string) ([]models.ScheduleException, error) {
+	queryBuilder := sq.Select("id", "pvz_id", "date", "is_closed", "open_time", "close_time", "reason").
+		From("pvz_schedule_exceptions").
+		Where(sq.Eq{"pvz_id": pvzId})
+	if dates != nil {
+		queryBuilder = queryBuilder.Where(sq.Eq{"date": dates})
+	}
+	query, args, err := queryBuilder.
+		OrderBy("date").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query schedule exceptions", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var exceptions []models.ScheduleException
+	for rows.Next() {
+		var e models.ScheduleException
+		var openTime, closeTime sql.NullString
+		if err := rows.Scan(&e.Id, &e.PvzId, &e.Date, &e.IsClosed, &openTime, &closeTime, &e.Reason); err != nil {
+			return nil, err
+		}
+		e.OpenTime = openTime.String
+		e.CloseTime = closeTime.String
+		exceptions = append(exceptions, e)
+	}
+
+	return exceptions, rows.Err()
+}
+
+func (r ScheduleRepository) SetWorkingHours(ctx context.Context, schedule models.PvzSchedule) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("SetWorkingHours called", zap.String("request_id", requestID), zap.String("pvz_id", schedule.PvzId))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.DBLogger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query, args, err := sq.Update("pvzs").
+		Set("time_zone", schedule.TimeZone).
+		Set("working_hours_policy", schedule.Policy).
+		Where(sq.Eq{"id": schedule.PvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to update pvz schedule settings", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("pvz not found")
+	}
+
+	query, args, err = sq.Delete("pvz_working_hours").
+		Where(sq.Eq{"pvz_id": schedule.PvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to delete working hours", zap.Error(err))
+		return err
+	}
+
+	if len(schedule.WorkingHours) > 0 {
+		insertBuilder := sq.Insert("pvz_working_hours").
+			Columns("pvz_id", "weekday", "open_time", "close_time").
+			PlaceholderFormat(sq.Dollar)
+		for _, hours := range schedule.WorkingHours {
+			insertBuilder = insertBuilder.Values(schedule.PvzId, hours.Weekday, hours.OpenTime, hours.CloseTime)
+		}
+		query, args, err = insertBuilder.ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.DBLogger.Error("failed to insert working hours", zap.Error(err))
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.DBLogger.Error("failed to commit transaction", zap.Error(err))
+		return err
+	}
+
+	logger.DBLogger.Info("Working hours successfully updated",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", schedule.PvzId),
+	)
+
+	return nil
+}
+
+func (r ScheduleRepository) CreateScheduleException(ctx context.Context, exception models.ScheduleException) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CreateScheduleException called", zap.String("request_id", requestID), zap.String("pvz_id", exception.PvzId))
+
+	var openTime, closeTime interface{}
+	if !exception.IsClosed {
+		openTime, closeTime = exception.OpenTime, exception.CloseTime
+	}
+
+	query, args, err := sq.Insert("pvz_schedule_exceptions").
+		Columns("id", "pvz_id", "date", "is_closed", "open_time", "close_time", "reason").
+		Values(exception.Id, exception.PvzId, exception.Date, exception.IsClosed, openTime, closeTime, exception.Reason).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode {
+			return errors.New("schedule exception already exists")
+		}
+		logger.DBLogger.Error("failed to insert schedule exception", zap.Error(err))
+		return err
+	}
+
+	logger.DBLogger.Info("Schedule exception successfully created",
+		zap.String("request_id", requestID),
+		zap.String("exception_id", exception.Id),
+	)
+
+	return nil
+}
+
+func (r ScheduleRepository) DeleteScheduleException(ctx context.Context, pvzId, exceptionId string) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("DeleteScheduleException called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+		zap.String("exception_id", exceptionId),
+	)
+
+	query, args, err := sq.Delete("pvz_schedule_exceptions").
+		Where(sq.Eq{"id": exceptionId, "pvz_id": pvzId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build delete SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to execute delete", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return errors.New("schedule exception not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestScheduleRepository_GetPvzSchedule(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+
+	tests := []struct {
+		name        string
+		mock        func(sqlmock.Sqlmock)
+		expectedErr string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, time_zone, working_hours_policy FROM pvzs WHERE id = \$1`).
+					WithArgs("pvz1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "time_zone", "working_hours_policy"}).
+						AddRow("pvz1", "Europe/Moscow", "reject"))
+				mock.ExpectQuery(`SELECT weekday, open_time, close_time FROM pvz_working_hours WHERE pvz_id = \$1 ORDER BY weekday`).
+					WithArgs("pvz1").
+					WillReturnRows(sqlmock.NewRows([]string{"weekday", "open_time", "close_time"}).
+						AddRow(1, "09:00:00", "21:00:00"))
+				mock.ExpectQuery(`SELECT id, pvz_id, date, is_closed, open_time, close_time, reason FROM pvz_schedule_exceptions WHERE pvz_id = \$1 ORDER BY date`).
+					WithArgs("pvz1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "pvz_id", "date", "is_closed", "open_time", "close_time", "reason"}).
+						AddRow("ex1", "pvz1", time.Date(2025, 5, 9, 0, 0, 0, 0, time.UTC), true, nil, nil, "holiday"))
+			},
+		},
+		{
+			name: "Not Found",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, time_zone, working_hours_policy FROM pvzs WHERE id = \$1`).
+					WithArgs("pvz1").
+					WillReturnError(sql.ErrNoRows)
+			},
+			expectedErr: "pvz not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				err := db.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			repo := NewScheduleRepository(db)
+			tt.mock(mock)
+
+			schedule, err := repo.GetPvzSchedule(ctx, "pvz1")
+
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedErr, err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "reject", schedule.Policy)
+				assert.Len(t, schedule.WorkingHours, 1)
+				require.Len(t, schedule.Exceptions, 1)
+				assert.True(t, schedule.Exceptions[0].IsClosed)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestScheduleRepository_GetPvzScheduleAt(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	mock.ExpectQuery(`SELECT id, time_zone, working_hours_policy FROM pvzs WHERE id = \$1`).
+		WithArgs("pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "time_zone", "working_hours_policy"}).
+			AddRow("pvz1", "Europe/Moscow", "reject"))
+	mock.ExpectQuery(`SELECT weekday, open_time, close_time FROM pvz_working_hours WHERE pvz_id = \$1 ORDER BY weekday`).
+		WithArgs("pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"weekday", "open_time", "close_time"}))
+	// 22:30 UTC 9 мая — уже 10 мая по Москве, поэтому нужны исключения на 9 и 10 мая.
+	mock.ExpectQuery(`SELECT id, pvz_id, date, is_closed, open_time, close_time, reason FROM pvz_schedule_exceptions `+
+		`WHERE pvz_id = \$1 AND date IN \(\$2,\$3\) ORDER BY date`).
+		WithArgs("pvz1", "2025-05-09", "2025-05-10").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pvz_id", "date", "is_closed", "open_time", "close_time", "reason"}).
+			AddRow("ex1", "pvz1", time.Date(2025, 5, 10, 0, 0, 0, 0, time.UTC), true, nil, nil, "holiday"))
+
+	schedule, err := NewScheduleRepository(db).GetPvzScheduleAt(context.Background(), "pvz1",
+		time.Date(2025, 5, 9, 22, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, schedule.Exceptions, 1)
+	assert.Equal(t, "ex1", schedule.Exceptions[0].Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScheduleRepository_SetWorkingHours(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+
+	schedule := models.PvzSchedule{
+		PvzId:    "pvz1",
+		TimeZone: "Europe/Moscow",
+		Policy:   "flag",
+		WorkingHours: []models.WorkingHours{
+			{Weekday: 1, OpenTime: "09:00", CloseTime: "21:00"},
+			{Weekday: 2, OpenTime: "10:00", CloseTime: "20:00"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		mock        func(sqlmock.Sqlmock)
+		expectedErr string
+	}{
+		{
+			name: "Success",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE pvzs SET time_zone = \$1, working_hours_policy = \$2 WHERE id = \$3`).
+					WithArgs("Europe/Moscow", "flag", "pvz1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`DELETE FROM pvz_working_hours WHERE pvz_id = \$1`).
+					WithArgs("pvz1").
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(`INSERT INTO pvz_working_hours \(pvz_id,weekday,open_time,close_time\) VALUES \(\$1,\$2,\$3,\$4\),\(\$5,\$6,\$7,\$8\)`).
+					WithArgs("pvz1", 1, "09:00", "21:00", "pvz1", 2, "10:00", "20:00").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Pvz Not Found",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE pvzs`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedErr: "pvz not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				err := db.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			repo := NewScheduleRepository(db)
+			tt.mock(mock)
+
+			err = repo.SetWorkingHours(ctx, schedule)
+
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestScheduleRepository_CreateScheduleException(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "test-request-id")
+
+	exception := models.ScheduleException{
+		Id:       "ex1",
+		PvzId:    "pvz1",
+		Date:     time.Date(2025, 5, 9, 0, 0, 0, 0, time.UTC),
+		IsClosed: true,
+		Reason:   "holiday",
+	}
+
+	tests := []struct {
+		name        string
+		execErr     error
+		expectedErr string
+	}{
+		{name: "Success"},
+		{name: "Duplicate", execErr: &pq.Error{Code: "23505"}, expectedErr: "schedule exception already exists"},
+		{name: "Insert Error", execErr: errors.New("insert failed"), expectedErr: "insert failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				err := db.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			expectation := mock.ExpectExec(`INSERT INTO pvz_schedule_exceptions \(id,pvz_id,date,is_closed,open_time,close_time,reason\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\)`).
+				WithArgs("ex1", "pvz1", exception.Date, true, nil, nil, "holiday")
+			if tt.execErr != nil {
+				expectation.WillReturnError(tt.execErr)
+			} else {
+				expectation.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			err = NewScheduleRepository(db).CreateScheduleException(ctx, exception)
+
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"time"
+)
+
+type ScheduleRepository interface {
+	GetPvzSchedule(ctx context.Context, pvzId string) (*models.PvzSchedule, error)
+	GetPvzScheduleAt(ctx context.Context, pvzId string, at time.Time) (*models.PvzSchedule, error)
+	SetWorkingHours(ctx context.Context, schedule models.PvzSchedule) error
+	CreateScheduleException(ctx context.Context, exception models.ScheduleException) error
+	DeleteScheduleException(ctx context.Context, pvzId, exceptionId string) error
+}
@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+type ScheduleUsecase struct {
+	scheduleRepository ScheduleRepository
+}
+
+func NewScheduleUsecase(scheduleRepository ScheduleRepository) ScheduleUsecase {
+	return ScheduleUsecase{
+		scheduleRepository: scheduleRepository,
+	}
+}
+
+func (su ScheduleUsecase) GetSchedule(ctx context.Context, pvzId string) (models.PvzSchedule, error) {
+	schedule, err := su.scheduleRepository.GetPvzSchedule(ctx, pvzId)
+	if err != nil {
+		return models.PvzSchedule{}, err
+	}
+	return *schedule, nil
+}
+
+// GetScheduleAt возвращает расписание ПВЗ, достаточное для IsOpenAt(at): исключения только на нужные даты.
+func (su ScheduleUsecase) GetScheduleAt(ctx context.Context, pvzId string, at time.Time) (models.PvzSchedule, error) {
+	schedule, err := su.scheduleRepository.GetPvzScheduleAt(ctx, pvzId, at)
+	if err != nil {
+		return models.PvzSchedule{}, err
+	}
+	return *schedule, nil
+}
+
+func (su ScheduleUsecase) SetWorkingHours(ctx context.Context, pvzId string, data requests.SetWorkingHoursRequest) (models.PvzSchedule, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.PvzSchedule{}, errors.New("this role is not allowed")
+	}
+
+	if data.TimeZone == "" {
+		data.TimeZone = models.DEFAULT_TIME_ZONE
+	}
+	if _, err := time.LoadLocation(data.TimeZone); err != nil {
+		return models.PvzSchedule{}, errors.New("invalid time zone")
+	}
+	if data.Policy == "" {
+		data.Policy = models.WORKING_HOURS_POLICY_NONE
+	}
+	if data.Policy != models.WORKING_HOURS_POLICY_NONE &&
+		data.Policy != models.WORKING_HOURS_POLICY_REJECT &&
+		data.Policy != models.WORKING_HOURS_POLICY_FLAG {
+		return models.PvzSchedule{}, errors.New("invalid working hours policy")
+	}
+
+	seenDays := make(map[int]bool, len(data.WorkingHours))
+	hours := make([]models.WorkingHours, 0, len(data.WorkingHours))
+	for _, item := range data.WorkingHours {
+		if item.Weekday < 0 || item.Weekday > 6 || seenDays[item.Weekday] {
+			return models.PvzSchedule{}, errors.New("invalid working hours")
+		}
+		if err := validateHours(item.OpenTime, item.CloseTime); err != nil {
+			return models.PvzSchedule{}, err
+		}
+		seenDays[item.Weekday] = true
+		hours = append(hours, models.WorkingHours{
+			Weekday:   item.Weekday,
+			OpenTime:  item.OpenTime,
+			CloseTime: item.CloseTime,
+		})
+	}
+
+	schedule := models.PvzSchedule{
+		PvzId:        pvzId,
+		TimeZone:     data.TimeZone,
+		Policy:       data.Policy,
+		WorkingHours: hours,
+	}
+	if err := su.scheduleRepository.SetWorkingHours(ctx, schedule); err != nil {
+		return models.PvzSchedule{}, err
+	}
+
+	return su.GetSchedule(ctx, pvzId)
+}
+
+func (su ScheduleUsecase) AddScheduleException(ctx context.Context, pvzId string, data requests.AddScheduleExceptionRequest) (models.ScheduleException, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.ScheduleException{}, errors.New("this role is not allowed")
+	}
+
+	date, err := time.Parse(models.DATE_LAYOUT, data.Date)
+	if err != nil {
+		return models.ScheduleException{}, errors.New("invalid exception date")
+	}
+	if !data.IsClosed {
+		if err := validateHours(data.OpenTime, data.CloseTime); err != nil {
+			return models.ScheduleException{}, err
+		}
+	}
+
+	if _, err := su.scheduleRepository.GetPvzSchedule(ctx, pvzId); err != nil {
+		return models.ScheduleException{}, err
+	}
+
+	exception := models.ScheduleException{
+		Id:       uuid.New().String(),
+		PvzId:    pvzId,
+		Date:     date,
+		IsClosed: data.IsClosed,
+		Reason:   data.Reason,
+	}
+	if !data.IsClosed {
+		exception.OpenTime = data.OpenTime
+		exception.CloseTime = data.CloseTime
+	}
+
+	if err := su.scheduleRepository.CreateScheduleException(ctx, exception); err != nil {
+		return models.ScheduleException{}, err
+	}
+	return exception, nil
+}
+
+func (su ScheduleUsecase) DeleteScheduleException(ctx context.Context, pvzId, exceptionId string) error {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return errors.New("this role is not allowed")
+	}
+	return su.scheduleRepository.DeleteScheduleException(ctx, pvzId, exceptionId)
+}
+
+func validateHours(openTime, closeTime string) error {
+	open, err := models.ParseClock(openTime)
+	if err != nil {
+		return err
+	}
+	closing, err := models.ParseClock(closeTime)
+	if err != nil {
+		return err
+	}
+	// Смены через полночь не поддерживаются: IsOpenAt сравнивает время в пределах одних суток.
+	// Работу до конца суток задают закрытием в 24:00, продолжение — часами следующего дня с 00:00.
+	if open > closing {
+		return errors.New("overnight working hours are not supported")
+	}
+	if open == closing {
+		return errors.New("invalid working hours")
+	}
+	return nil
+}
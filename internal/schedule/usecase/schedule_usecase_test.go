@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestScheduleUsecase_SetWorkingHours(t *testing.T) {
+	moderatorCtx := func() context.Context {
+		return context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+	}
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.SetWorkingHoursRequest
+		mockSetup   func(*repositoryMocks.MockScheduleRepository)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  moderatorCtx,
+			data: requests.SetWorkingHoursRequest{
+				TimeZone: "Europe/Moscow",
+				Policy:   models.WORKING_HOURS_POLICY_REJECT,
+				WorkingHours: []requests.WorkingHoursItem{
+					{Weekday: 1, OpenTime: "09:00", CloseTime: "21:00"},
+				},
+			},
+			mockSetup: func(m *repositoryMocks.MockScheduleRepository) {
+				m.On("SetWorkingHours", mock.Anything, mock.MatchedBy(func(s models.PvzSchedule) bool {
+					return s.PvzId == "pvz1" && s.Policy == models.WORKING_HOURS_POLICY_REJECT && len(s.WorkingHours) == 1
+				})).Return(nil)
+				m.On("GetPvzSchedule", mock.Anything, "pvz1").
+					Return(&models.PvzSchedule{PvzId: "pvz1"}, nil)
+			},
+		},
+		{
+			name: "defaults applied",
+			ctx:  moderatorCtx,
+			data: requests.SetWorkingHoursRequest{},
+			mockSetup: func(m *repositoryMocks.MockScheduleRepository) {
+				m.On("SetWorkingHours", mock.Anything, mock.MatchedBy(func(s models.PvzSchedule) bool {
+					return s.TimeZone == models.DEFAULT_TIME_ZONE && s.Policy == models.WORKING_HOURS_POLICY_NONE
+				})).Return(nil)
+				m.On("GetPvzSchedule", mock.Anything, "pvz1").
+					Return(&models.PvzSchedule{PvzId: "pvz1"}, nil)
+			},
+		},
+		{
+			name: "invalid role",
+			ctx: func() context.Context {
+				return context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+			},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+		{
+			name:        "invalid time zone",
+			ctx:         moderatorCtx,
+			data:        requests.SetWorkingHoursRequest{TimeZone: "Mars/Olympus"},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("invalid time zone"),
+		},
+		{
+			name:        "invalid policy",
+			ctx:         moderatorCtx,
+			data:        requests.SetWorkingHoursRequest{Policy: "ignore"},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("invalid working hours policy"),
+		},
+		{
+			name: "open until midnight",
+			ctx:  moderatorCtx,
+			data: requests.SetWorkingHoursRequest{
+				WorkingHours: []requests.WorkingHoursItem{{Weekday: 5, OpenTime: "10:00", CloseTime: "24:00"}},
+			},
+			mockSetup: func(m *repositoryMocks.MockScheduleRepository) {
+				m.On("SetWorkingHours", mock.Anything, mock.MatchedBy(func(s models.PvzSchedule) bool {
+					return len(s.WorkingHours) == 1 && s.WorkingHours[0].CloseTime == "24:00"
+				})).Return(nil)
+				m.On("GetPvzSchedule", mock.Anything, "pvz1").
+					Return(&models.PvzSchedule{PvzId: "pvz1"}, nil)
+			},
+		},
+		{
+			name: "opening at midnight end",
+			ctx:  moderatorCtx,
+			data: requests.SetWorkingHoursRequest{
+				WorkingHours: []requests.WorkingHoursItem{{Weekday: 5, OpenTime: "24:00", CloseTime: "24:00"}},
+			},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("invalid working hours"),
+		},
+		{
+			name: "overnight hours",
+			ctx:  moderatorCtx,
+			data: requests.SetWorkingHoursRequest{
+				WorkingHours: []requests.WorkingHoursItem{{Weekday: 1, OpenTime: "21:00", CloseTime: "09:00"}},
+			},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("overnight working hours are not supported"),
+		},
+		{
+			name: "empty hours",
+			ctx:  moderatorCtx,
+			data: requests.SetWorkingHoursRequest{
+				WorkingHours: []requests.WorkingHoursItem{{Weekday: 1, OpenTime: "09:00", CloseTime: "09:00"}},
+			},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("invalid working hours"),
+		},
+		{
+			name: "duplicate weekday",
+			ctx:  moderatorCtx,
+			data: requests.SetWorkingHoursRequest{
+				WorkingHours: []requests.WorkingHoursItem{
+					{Weekday: 1, OpenTime: "09:00", CloseTime: "21:00"},
+					{Weekday: 1, OpenTime: "10:00", CloseTime: "20:00"},
+				},
+			},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("invalid working hours"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockScheduleRepository)
+			uc := NewScheduleUsecase(mockRepo)
+			tt.mockSetup(mockRepo)
+
+			_, err := uc.SetWorkingHours(tt.ctx(), "pvz1", tt.data)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScheduleUsecase_AddScheduleException(t *testing.T) {
+	moderatorCtx := context.WithValue(context.Background(), middleware.ContextKeyRole, "moderator")
+
+	tests := []struct {
+		name        string
+		data        requests.AddScheduleExceptionRequest
+		mockSetup   func(*repositoryMocks.MockScheduleRepository)
+		expectedErr error
+	}{
+		{
+			name: "holiday",
+			data: requests.AddScheduleExceptionRequest{Date: "2025-05-09", IsClosed: true, OpenTime: "10:00", Reason: "День Победы"},
+			mockSetup: func(m *repositoryMocks.MockScheduleRepository) {
+				m.On("GetPvzSchedule", mock.Anything, "pvz1").Return(&models.PvzSchedule{}, nil)
+				m.On("CreateScheduleException", mock.Anything, mock.MatchedBy(func(e models.ScheduleException) bool {
+					return e.IsClosed && e.OpenTime == "" && e.Date.Equal(time.Date(2025, 5, 9, 0, 0, 0, 0, time.UTC))
+				})).Return(nil)
+			},
+		},
+		{
+			name: "shortened day",
+			data: requests.AddScheduleExceptionRequest{Date: "2025-12-31", OpenTime: "10:00", CloseTime: "15:00"},
+			mockSetup: func(m *repositoryMocks.MockScheduleRepository) {
+				m.On("GetPvzSchedule", mock.Anything, "pvz1").Return(&models.PvzSchedule{}, nil)
+				m.On("CreateScheduleException", mock.Anything, mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:        "invalid date",
+			data:        requests.AddScheduleExceptionRequest{Date: "31.12.2025", IsClosed: true},
+			mockSetup:   func(_ *repositoryMocks.MockScheduleRepository) {},
+			expectedErr: errors.New("invalid exception date"),
+		},
+		{
+			name: "pvz not found",
+			data: requests.AddScheduleExceptionRequest{Date: "2025-05-09", IsClosed: true},
+			mockSetup: func(m *repositoryMocks.MockScheduleRepository) {
+				m.On("GetPvzSchedule", mock.Anything, "pvz1").Return(nil, errors.New("pvz not found"))
+			},
+			expectedErr: errors.New("pvz not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockScheduleRepository)
+			uc := NewScheduleUsecase(mockRepo)
+			tt.mockSetup(mockRepo)
+
+			res, err := uc.AddScheduleException(moderatorCtx, "pvz1", tt.data)
+
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				assert.NotEmpty(t, res.Id)
+				assert.Equal(t, "pvz1", res.PvzId)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPvzSchedule_IsOpenAt(t *testing.T) {
+	schedule := models.PvzSchedule{
+		TimeZone: "Europe/Moscow",
+		Policy:   models.WORKING_HOURS_POLICY_REJECT,
+		WorkingHours: []models.WorkingHours{
+			{Weekday: int(time.Monday), OpenTime: "09:00:00", CloseTime: "21:00:00"},
+			{Weekday: int(time.Friday), OpenTime: "10:00:00", CloseTime: "24:00:00"},
+		},
+		Exceptions: []models.ScheduleException{
+			{Date: time.Date(2025, 5, 12, 0, 0, 0, 0, time.UTC), IsClosed: true},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		// 2025-04-14 — понедельник, Москва UTC+3
+		{name: "open in local time", at: time.Date(2025, 4, 14, 7, 0, 0, 0, time.UTC), expected: true},
+		{name: "before opening in local time", at: time.Date(2025, 4, 14, 5, 59, 0, 0, time.UTC), expected: false},
+		{name: "at closing time", at: time.Date(2025, 4, 14, 18, 0, 0, 0, time.UTC), expected: false},
+		{name: "day without hours", at: time.Date(2025, 4, 15, 7, 0, 0, 0, time.UTC), expected: false},
+		{name: "open until midnight", at: time.Date(2025, 4, 18, 20, 59, 0, 0, time.UTC), expected: true},
+		{name: "closed after midnight", at: time.Date(2025, 4, 18, 21, 0, 0, 0, time.UTC), expected: false},
+		{name: "holiday exception", at: time.Date(2025, 5, 12, 7, 0, 0, 0, time.UTC), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isOpen, err := schedule.IsOpenAt(tt.at)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, isOpen)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package blobstore
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStore_PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "damage/report1/photo1.jpg", strings.NewReader("photo")))
+
+	reader, err := store.Get(ctx, "damage/report1/photo1.jpg")
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "photo", string(content))
+
+	require.NoError(t, store.Delete(ctx, "damage/report1/photo1.jpg"))
+	_, err = store.Get(ctx, "damage/report1/photo1.jpg")
+	assert.EqualError(t, err, "blob not found")
+	assert.NoError(t, store.Delete(ctx, "damage/report1/photo1.jpg"))
+}
+
+func TestLocalBlobStore_PutLeavesNoTempFiles(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalBlobStore(root)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(context.Background(), "a/b.png", strings.NewReader("x")))
+
+	entries, err := os.ReadDir(filepath.Join(root, "a"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "b.png", entries[0].Name())
+}
+
+func TestLocalBlobStore_RejectsKeysOutsideRoot(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../secret", "a/../../secret", "/etc/passwd"} {
+		assert.EqualError(t, store.Put(context.Background(), key, strings.NewReader("x")), "invalid blob key", key)
+		_, err := store.Get(context.Background(), key)
+		assert.EqualError(t, err, "invalid blob key", key)
+	}
+}
@@ -0,0 +1,54 @@
+package dbtx
+
+import (
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"go.uber.org/zap"
+)
+
+// Querier — общее подмножество *sql.DB и *sql.Tx, чтобы методы репозиториев
+// одинаково работали как внутри транзакции, так и вне её.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey общий для всех репозиториев: транзакция, открытая одним из них, видна остальным.
+type txKey struct{}
+
+// WithinTransaction выполняет fn в одной транзакции, передавая её через контекст.
+// Если транзакция уже открыта выше по стеку, fn выполняется в ней же.
+func WithinTransaction(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.DBLogger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.DBLogger.Error("failed to commit transaction", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Conn возвращает транзакцию из контекста, а если её нет — сам db.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,64 @@
+package dbtx
+
+import (
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestWithinTransaction(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+
+	t.Run("Nested Calls Share One Transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`^UPDATE pvzs`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`^INSERT INTO outbox_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = WithinTransaction(ctx, db, func(ctx context.Context) error {
+			if _, err := Conn(ctx, db).ExecContext(ctx, "UPDATE pvzs SET occupancy = occupancy + 1"); err != nil {
+				return err
+			}
+			// Вложенный вызов, например из другого репозитория, не открывает вторую транзакцию.
+			return WithinTransaction(ctx, db, func(ctx context.Context) error {
+				_, err := Conn(ctx, db).ExecContext(ctx, "INSERT INTO outbox_events DEFAULT VALUES")
+				return err
+			})
+		})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error Rolls Back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err = WithinTransaction(ctx, db, func(ctx context.Context) error {
+			return errors.New("pvz is full")
+		})
+		assert.EqualError(t, err, "pvz is full")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Conn Outside Transaction Uses Db", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		assert.Same(t, db, Conn(ctx, db))
+	})
+}
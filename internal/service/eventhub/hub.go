@@ -0,0 +1,95 @@
+package eventhub
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"sync"
+)
+
+// DefaultSubscriberBuffer — сколько событий может накопиться у подписчика, прежде чем его отключат как отстающего.
+const DefaultSubscriberBuffer = 256
+
+// Hub раздаёт события outbox подписчикам внутри процесса. Его наполняет outbox.Tail своего экземпляра,
+// поэтому подписчики получают только зафиксированные события и не опрашивают базу сами.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	buffer      int
+}
+
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscription]struct{}),
+		buffer:      buffer,
+	}
+}
+
+// Subscription получает события, для которых filter вернул true. filter вызывается из Publish,
+// поэтому должен быть быстрым и не блокироваться.
+type Subscription struct {
+	hub    *Hub
+	filter func(models.DomainEvent) bool
+	events chan models.DomainEvent
+	lagged bool
+}
+
+func (h *Hub) Subscribe(filter func(models.DomainEvent) bool) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan models.DomainEvent, h.buffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Publish никогда не блокирует чтение outbox: подписчик с заполненным буфером отключается, его канал закрывается,
+// и клиент переподключается, догоняя пропущенное по Last-Event-ID.
+func (h *Hub) Publish(_ context.Context, event models.DomainEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.lagged = true
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+	return nil
+}
+
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Events закрывается, когда подписка отменена или подписчик отстал.
+func (s *Subscription) Events() <-chan models.DomainEvent {
+	return s.events
+}
+
+// Lagged сообщает, что подписка была отключена из-за переполнения буфера. Читать после закрытия Events.
+func (s *Subscription) Lagged() bool {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	return s.lagged
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subscribers[s]; ok {
+		delete(s.hub.subscribers, s)
+		close(s.events)
+	}
+}
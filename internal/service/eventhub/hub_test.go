@@ -0,0 +1,93 @@
+package eventhub
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func event(seq int64, pvzId string) models.DomainEvent {
+	return models.DomainEvent{Seq: seq, Type: models.EVENT_PRODUCT_ADDED, PvzId: pvzId}
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub(DefaultSubscriberBuffer)
+	all := hub.Subscribe(nil)
+	pvz1 := hub.Subscribe(func(e models.DomainEvent) bool { return e.PvzId == "pvz1" })
+	defer all.Close()
+	defer pvz1.Close()
+
+	require.NoError(t, hub.Publish(context.Background(), event(1, "pvz1")))
+	require.NoError(t, hub.Publish(context.Background(), event(2, "pvz2")))
+
+	assert.Equal(t, int64(1), (<-all.Events()).Seq)
+	assert.Equal(t, int64(2), (<-all.Events()).Seq)
+	assert.Equal(t, int64(1), (<-pvz1.Events()).Seq)
+	assert.Empty(t, pvz1.Events())
+}
+
+func TestHub_LaggingSubscriberIsDropped(t *testing.T) {
+	hub := NewHub(2)
+	slow := hub.Subscribe(nil)
+	fast := hub.Subscribe(nil)
+	defer fast.Close()
+
+	for seq := int64(1); seq <= 3; seq++ {
+		require.NoError(t, hub.Publish(context.Background(), event(seq, "pvz1")))
+		if seq < 3 {
+			<-fast.Events()
+		}
+	}
+
+	var received []int64
+	for e := range slow.Events() {
+		received = append(received, e.Seq)
+	}
+	assert.Equal(t, []int64{1, 2}, received)
+	assert.True(t, slow.Lagged())
+	assert.False(t, fast.Lagged())
+	assert.Equal(t, int64(3), (<-fast.Events()).Seq)
+	assert.Equal(t, 1, hub.Subscribers())
+
+	// Повторное закрытие отключённой подписки безопасно.
+	slow.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(DefaultSubscriberBuffer)
+	sub := hub.Subscribe(nil)
+	sub.Close()
+	sub.Close()
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.False(t, sub.Lagged())
+	assert.Equal(t, 0, hub.Subscribers())
+	require.NoError(t, hub.Publish(context.Background(), event(1, "pvz1")))
+}
+
+func TestHub_ConcurrentSubscribers(t *testing.T) {
+	hub := NewHub(DefaultSubscriberBuffer)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		sub := hub.Subscribe(nil)
+		go func() {
+			defer wg.Done()
+			defer sub.Close()
+			for e := range sub.Events() {
+				if e.Seq == 100 {
+					return
+				}
+			}
+		}()
+	}
+	for seq := int64(1); seq <= 100; seq++ {
+		require.NoError(t, hub.Publish(context.Background(), event(seq, "pvz1")))
+	}
+	wg.Wait()
+	assert.Equal(t, 0, hub.Subscribers())
+}
@@ -0,0 +1,164 @@
+package idempotency
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Store хранит ключи идемпотентности в Postgres, чтобы повтор запроса узнавался любым экземпляром сервиса.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Acquire занимает ключ под новый запрос. Просроченная запись считается свободной и перезаписывается, как и
+// незавершённая запись с истёкшей арендой: запрос, занявший ключ, уже не выполняется.
+// Возвращает false, если ключ уже занят действующей записью.
+func (s *Store) Acquire(ctx context.Context, record models.IdempotencyRecord) (bool, error) {
+	query, args, err := sq.Insert("idempotency_keys").
+		Columns("scope", "key", "request_hash", "status", "created_at", "expires_at", "locked_until").
+		Values(record.Scope, record.Key, record.RequestHash, models.IDEMPOTENCY_STATUS_IN_PROGRESS, record.CreatedAt, record.ExpiresAt,
+			record.LockedUntil).
+		Suffix("ON CONFLICT (scope, key) DO UPDATE SET "+
+			"request_hash = EXCLUDED.request_hash, status = EXCLUDED.status, response_status = NULL, "+
+			"response_content_type = '', response_body = NULL, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at, "+
+			"locked_until = EXCLUDED.locked_until "+
+			"WHERE idempotency_keys.expires_at <= ? OR (idempotency_keys.status = ? AND idempotency_keys.locked_until <= ?)",
+			record.CreatedAt, models.IDEMPOTENCY_STATUS_IN_PROGRESS, record.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to acquire idempotency key", zap.Error(err))
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	query, args, err := sq.Select("scope", "key", "request_hash", "status", "response_status",
+		"response_content_type", "response_body", "created_at", "expires_at").
+		From("idempotency_keys").
+		Where(sq.Eq{"scope": scope, "key": key}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var record models.IdempotencyRecord
+	var responseStatus sql.NullInt64
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&record.Scope, &record.Key, &record.RequestHash, &record.Status,
+		&responseStatus, &record.ResponseContentType, &record.ResponseBody, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("idempotency key not found")
+		}
+		logger.DBLogger.Error("failed to scan idempotency key", zap.Error(err))
+		return nil, err
+	}
+	record.ResponseStatus = int(responseStatus.Int64)
+	return &record, nil
+}
+
+// Extend продлевает аренду ключа до record.LockedUntil, пока запрос, занявший его, ещё выполняется.
+func (s *Store) Extend(ctx context.Context, record models.IdempotencyRecord) error {
+	query, args, err := sq.Update("idempotency_keys").
+		Set("locked_until", record.LockedUntil).
+		Where(sq.Eq{"scope": record.Scope, "key": record.Key, "created_at": record.CreatedAt,
+			"status": models.IDEMPOTENCY_STATUS_IN_PROGRESS}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to extend idempotency key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ. Запись ищется по created_at: если аренда истекла и ключ
+// успел занять повтор, ответ опоздавшего запроса его не перезапишет.
+func (s *Store) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	query, args, err := sq.Update("idempotency_keys").
+		Set("status", models.IDEMPOTENCY_STATUS_COMPLETED).
+		Set("response_status", record.ResponseStatus).
+		Set("response_content_type", record.ResponseContentType).
+		Set("response_body", record.ResponseBody).
+		Where(sq.Eq{"scope": record.Scope, "key": record.Key, "request_hash": record.RequestHash, "created_at": record.CreatedAt}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to complete idempotency key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Release освобождает ключ незавершённого запроса, чтобы повтор выполнился заново.
+func (s *Store) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	query, args, err := sq.Delete("idempotency_keys").
+		Where(sq.Eq{"scope": record.Scope, "key": record.Key, "created_at": record.CreatedAt,
+			"status": models.IDEMPOTENCY_STATUS_IN_PROGRESS}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to release idempotency key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired удаляет просроченные ключи и возвращает их количество.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query, args, err := sq.Delete("idempotency_keys").
+		Where(sq.LtOrEq{"expires_at": now}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return 0, err
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to delete expired idempotency keys", zap.Error(err))
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,193 @@
+package idempotency
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	now := time.Date(2025, 5, 5, 10, 0, 0, 0, time.UTC)
+	record := models.IdempotencyRecord{
+		Scope:       "user:user1",
+		Key:         "key1",
+		RequestHash: "hash1",
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+		LockedUntil: now.Add(30 * time.Second),
+	}
+
+	t.Run("acquire new key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).
+			WithArgs("user:user1", "key1", "hash1", models.IDEMPOTENCY_STATUS_IN_PROGRESS, now, now.Add(time.Hour), now.Add(30*time.Second),
+				now, models.IDEMPOTENCY_STATUS_IN_PROGRESS, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		acquired, err := NewStore(db).Acquire(context.Background(), record)
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("acquire taken key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(regexp.QuoteMeta("ON CONFLICT (scope, key) DO UPDATE")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		acquired, err := NewStore(db).Acquire(context.Background(), record)
+		assert.NoError(t, err)
+		assert.False(t, acquired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("acquire takes over key with expired lease", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(regexp.QuoteMeta("WHERE idempotency_keys.expires_at <= $8 OR " +
+			"(idempotency_keys.status = $9 AND idempotency_keys.locked_until <= $10)")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		acquired, err := NewStore(db).Acquire(context.Background(), record)
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("get completed key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"scope", "key", "request_hash", "status", "response_status",
+			"response_content_type", "response_body", "created_at", "expires_at"}).
+			AddRow("user:user1", "key1", "hash1", models.IDEMPOTENCY_STATUS_COMPLETED, 201,
+				"application/json", []byte(`{"id":"1"}`), now, now.Add(time.Hour))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM idempotency_keys WHERE")).
+			WithArgs("key1", "user:user1").
+			WillReturnRows(rows)
+
+		stored, err := NewStore(db).Get(context.Background(), "user:user1", "key1")
+		require.NoError(t, err)
+		assert.Equal(t, models.IDEMPOTENCY_STATUS_COMPLETED, stored.Status)
+		assert.Equal(t, 201, stored.ResponseStatus)
+		assert.Equal(t, `{"id":"1"}`, string(stored.ResponseBody))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("get in progress key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{"scope", "key", "request_hash", "status", "response_status",
+			"response_content_type", "response_body", "created_at", "expires_at"}).
+			AddRow("user:user1", "key1", "hash1", models.IDEMPOTENCY_STATUS_IN_PROGRESS, nil, "", nil, now, now.Add(time.Hour))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM idempotency_keys WHERE")).WillReturnRows(rows)
+
+		stored, err := NewStore(db).Get(context.Background(), "user:user1", "key1")
+		require.NoError(t, err)
+		assert.Equal(t, models.IDEMPOTENCY_STATUS_IN_PROGRESS, stored.Status)
+		assert.Zero(t, stored.ResponseStatus)
+	})
+
+	t.Run("get missing key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("FROM idempotency_keys WHERE")).
+			WillReturnRows(sqlmock.NewRows([]string{"scope"}))
+
+		_, err = NewStore(db).Get(context.Background(), "user:user1", "key1")
+		assert.EqualError(t, err, "idempotency key not found")
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		completed := record
+		completed.ResponseStatus = 201
+		completed.ResponseContentType = "application/json"
+		completed.ResponseBody = []byte(`{}`)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE idempotency_keys SET status = $1, response_status = $2")).
+			WithArgs(models.IDEMPOTENCY_STATUS_COMPLETED, 201, "application/json", []byte(`{}`), now, "key1", "hash1", "user:user1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, NewStore(db).Complete(context.Background(), completed))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("extend", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		extended := record
+		extended.LockedUntil = now.Add(time.Minute)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE idempotency_keys SET locked_until = $1")).
+			WithArgs(now.Add(time.Minute), now, "key1", "user:user1", models.IDEMPOTENCY_STATUS_IN_PROGRESS).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, NewStore(db).Extend(context.Background(), extended))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("release", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys")).
+			WithArgs(now, "key1", "user:user1", models.IDEMPOTENCY_STATUS_IN_PROGRESS).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, NewStore(db).Release(context.Background(), record))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete expired", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE expires_at <= $1")).
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		deleted, err := NewStore(db).DeleteExpired(context.Background(), now)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete expired db error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys")).WillReturnError(errors.New("db error"))
+
+		_, err = NewStore(db).DeleteExpired(context.Background(), now)
+		assert.EqualError(t, err, "db error")
+	})
+}
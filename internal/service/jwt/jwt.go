@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"time"
 )
 
@@ -26,12 +27,21 @@ type JwtCsrfClaims struct {
 }
 
 func (tk JwtToken) Create(role string, tokenExpTime int64) (string, error) {
+	return tk.CreateWithSubject("", role, tokenExpTime)
+}
+
+// CreateWithSubject выпускает токен, в поле sub которого записан id пользователя:
+// по нему ручки фиксируют, кто выполнил действие. Каждый токен получает свой jti, чтобы различать
+// и токены /dummyLogin, у которых пользователя нет.
+func (tk JwtToken) CreateWithSubject(subject, role string, tokenExpTime int64) (string, error) {
 	if role == "" {
 		return "", errors.New("role is empty")
 	}
 	data := JwtCsrfClaims{
 		Role: role,
 		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Subject:   subject,
 			ExpiresAt: tokenExpTime,
 			IssuedAt:  time.Now().Unix(),
 		},
@@ -78,6 +78,38 @@ func TestJwtToken_CreateAndValidate(t *testing.T) {
 	}
 }
 
+func TestJwtToken_CreateWithSubject(t *testing.T) {
+	service, err := NewJwtToken("test-secret")
+	require.NoError(t, err)
+
+	token, err := service.CreateWithSubject("user-1", "moderator", time.Now().Add(1*time.Hour).Unix())
+	require.NoError(t, err)
+
+	claims, err := service.Validate(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "moderator", claims.Role)
+	assert.NotEmpty(t, claims.Id)
+}
+
+func TestJwtToken_CreateIssuesUniqueTokenIds(t *testing.T) {
+	service, err := NewJwtToken("test-secret")
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(1 * time.Hour).Unix()
+
+	first, err := service.Create("employee", expiresAt)
+	require.NoError(t, err)
+	second, err := service.Create("employee", expiresAt)
+	require.NoError(t, err)
+
+	firstClaims, err := service.Validate(first)
+	require.NoError(t, err)
+	secondClaims, err := service.Validate(second)
+	require.NoError(t, err)
+	assert.NotEmpty(t, firstClaims.Id)
+	assert.NotEqual(t, firstClaims.Id, secondClaims.Id)
+}
+
 func TestJwtToken_ValidateInvalidTokens(t *testing.T) {
 	secret := "test-secret"
 	service, err := NewJwtToken(secret)
@@ -8,6 +8,7 @@ import (
 var (
 	AccessLogger *zap.Logger
 	DBLogger     *zap.Logger
+	JobLogger    *zap.Logger
 )
 
 func InitLoggers() error {
@@ -36,6 +37,18 @@ func InitLoggers() error {
 		return err
 	}
 
+	jobConfig := zap.NewProductionConfig()
+	jobConfig.OutputPaths = []string{
+		"jobs.log",
+	}
+	jobConfig.EncoderConfig.TimeKey = "timestamp"
+	jobConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	JobLogger, err = jobConfig.Build()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -48,5 +61,9 @@ func SyncLoggers() error {
 	if err != nil {
 		return err
 	}
+	err = JobLogger.Sync()
+	if err != nil {
+		return err
+	}
 	return nil
 }
@@ -46,6 +46,20 @@ var (
 		},
 		[]string{"url"},
 	)
+
+	AmountOfAutoClosedReceptions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "auto_closed_receptions_total",
+			Help: "Total number of receptions closed automatically after inactivity",
+		},
+	)
+
+	AmountOfReturnedToSenderProducts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "returned_to_sender_products_total",
+			Help: "Total number of products sent back to the sender after the storage period expired",
+		},
+	)
 )
 
 func InitMetrics() {
@@ -54,4 +68,6 @@ func InitMetrics() {
 	prometheus.MustRegister(AmountOfCreatedPvz)
 	prometheus.MustRegister(AmountOfCreatedReceptions)
 	prometheus.MustRegister(AmountOfAddedProducts)
+	prometheus.MustRegister(AmountOfAutoClosedReceptions)
+	prometheus.MustRegister(AmountOfReturnedToSenderProducts)
 }
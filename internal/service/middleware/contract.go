@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"avito_spring_staj_2025/domain/models"
 	jwt_package "avito_spring_staj_2025/internal/service/jwt"
+	"context"
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -10,3 +12,11 @@ type JwtTokenService interface {
 	Validate(tokenString string) (*jwt_package.JwtCsrfClaims, error)
 	ParseSecretGetter(token *jwt.Token) (interface{}, error)
 }
+
+type IdempotencyStore interface {
+	Acquire(ctx context.Context, record models.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error)
+	Extend(ctx context.Context, record models.IdempotencyRecord) error
+	Complete(ctx context.Context, record models.IdempotencyRecord) error
+	Release(ctx context.Context, record models.IdempotencyRecord) error
+}
@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	DefaultIdempotencyKeyTTL  = 24 * time.Hour
+	maxIdempotentRequestBytes = 10 << 20
+)
+
+// Сколько повтор ждёт завершения исходного запроса, прежде чем получить 409.
+var (
+	idempotencyWaitTimeout  = 5 * time.Second
+	idempotencyPollInterval = 100 * time.Millisecond
+)
+
+// Аренда ключа выполняющимся запросом. Запрос продлевает её каждую треть срока, поэтому ключ освобождается
+// не позже чем через idempotencyLease после падения экземпляра, а не через весь TTL.
+var idempotencyLease = 30 * time.Second
+
+// IdempotencyMiddleware делает POST-запросы с заголовком Idempotency-Key идемпотентными: первый ответ
+// сохраняется, повтор с тем же ключом получает его без повторного выполнения. Должен стоять после RoleMiddleware,
+// так как ключи разных пользователей не пересекаются. Ответы 5xx не сохраняются — такой запрос можно повторить.
+// Если по токену нельзя понять, чей это ключ, заголовок игнорируется.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			scope := idempotencyScope(r.Context())
+			if r.Method != http.MethodPost || key == "" || scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestID := GetRequestID(r.Context())
+			if len(key) > models.MAX_IDEMPOTENCY_KEY_LENGTH {
+				writeIdempotencyError(w, http.StatusBadRequest, "invalid idempotency key", requestID)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBytes+1))
+			if err != nil || len(body) > maxIdempotentRequestBytes {
+				writeIdempotencyError(w, http.StatusRequestEntityTooLarge, "request is too large", requestID)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			record := models.IdempotencyRecord{
+				Scope:       scope,
+				Key:         key,
+				RequestHash: requestHash(r, body),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(ttl),
+				LockedUntil: now.Add(idempotencyLease),
+			}
+
+			stored, err := waitForIdempotencyKey(r.Context(), store, record)
+			if err != nil {
+				logger.AccessLogger.Error("Idempotency check failed",
+					zap.String("request_id", requestID),
+					zap.Error(err),
+				)
+				switch err.Error() {
+				case "idempotency key is used for another request":
+					writeIdempotencyError(w, http.StatusUnprocessableEntity, err.Error(), requestID)
+				case "request with this idempotency key is in progress":
+					writeIdempotencyError(w, http.StatusConflict, err.Error(), requestID)
+				default:
+					writeIdempotencyError(w, http.StatusInternalServerError, err.Error(), requestID)
+				}
+				return
+			}
+			if stored != nil {
+				if stored.ResponseContentType != "" {
+					w.Header().Set("Content-Type", stored.ResponseContentType)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.ResponseStatus)
+				_, _ = w.Write(stored.ResponseBody)
+				return
+			}
+
+			stopLease := keepIdempotencyLease(context.WithoutCancel(r.Context()), store, record, requestID)
+			recorder := &responseRecorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				stopLease()
+				// Запрос не дошёл до сохранения ответа (в том числе из-за паники) — освобождаем ключ.
+				if !completed {
+					if err := store.Release(context.WithoutCancel(r.Context()), record); err != nil {
+						logger.AccessLogger.Error("Failed to release idempotency key",
+							zap.String("request_id", requestID),
+							zap.Error(err),
+						)
+					}
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			stopLease()
+			record.ResponseStatus = recorder.status
+			record.ResponseContentType = recorder.Header().Get("Content-Type")
+			record.ResponseBody = recorder.body.Bytes()
+			if err := store.Complete(context.WithoutCancel(r.Context()), record); err != nil {
+				logger.AccessLogger.Error("Failed to save idempotent response",
+					zap.String("request_id", requestID),
+					zap.Error(err),
+				)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// waitForIdempotencyKey занимает ключ под текущий запрос (возвращает nil) или возвращает сохранённый ответ.
+// Если запрос с этим ключом ещё выполняется, ждёт его завершения не дольше idempotencyWaitTimeout.
+func waitForIdempotencyKey(ctx context.Context, store IdempotencyStore, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	deadline := time.Now().Add(idempotencyWaitTimeout)
+	for {
+		acquired, err := store.Acquire(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return nil, nil
+		}
+
+		stored, err := store.Get(ctx, record.Scope, record.Key)
+		if err != nil {
+			// Запись успели удалить между Acquire и Get — пробуем занять ключ ещё раз.
+			if err.Error() == "idempotency key not found" {
+				continue
+			}
+			return nil, err
+		}
+		if stored.RequestHash != record.RequestHash {
+			return nil, errors.New("idempotency key is used for another request")
+		}
+		if stored.Status == models.IDEMPOTENCY_STATUS_COMPLETED {
+			return stored, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("request with this idempotency key is in progress")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+// keepIdempotencyLease продлевает аренду занятого ключа, пока запрос выполняется. Возвращённая функция
+// останавливает продление и дожидается его; вызывать её можно несколько раз.
+func keepIdempotencyLease(ctx context.Context, store IdempotencyStore, record models.IdempotencyRecord, requestID string) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(idempotencyLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				record.LockedUntil = time.Now().Add(idempotencyLease)
+				if err := store.Extend(ctx, record); err != nil {
+					logger.AccessLogger.Error("Failed to extend idempotency key lease",
+						zap.String("request_id", requestID),
+						zap.Error(err),
+					)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}
+}
+
+// idempotencyScope — владелец ключа: пользователь, а у токенов /dummyLogin, где пользователя нет, — сам токен по jti.
+// Пустая строка значит, что владельца не определить (токен выпущен без jti).
+func idempotencyScope(ctx context.Context) string {
+	if userId := GetUserId(ctx); userId != "" {
+		return "user:" + userId
+	}
+	if tokenId := GetTokenId(ctx); tokenId != "" {
+		return "token:" + tokenId
+	}
+	return ""
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder пропускает ответ клиенту и одновременно запоминает его для сохранения.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, message, requestID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"errors": message}); err != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	idempotencyMocks "avito_spring_staj_2025/internal/tests/mocks/idempotency_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	idempotencyWaitTimeout = 50 * time.Millisecond
+	idempotencyPollInterval = 10 * time.Millisecond
+
+	newRequest := func(method, body, key string) *http.Request {
+		req := httptest.NewRequest(method, "/api/receptions", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		ctx := context.WithValue(req.Context(), ContextKeyRole, "employee")
+		ctx = context.WithValue(ctx, ContextKeyUserId, "user1")
+		ctx = context.WithValue(ctx, ContextKeyTokenId, "jti1")
+		return req.WithContext(ctx)
+	}
+	hashOf := func(body string) string {
+		return requestHash(httptest.NewRequest(http.MethodPost, "/api/receptions", nil), []byte(body))
+	}
+	isKey := func(record models.IdempotencyRecord) bool {
+		return record.Scope == "user:user1" && record.Key == "key1" && record.RequestHash == hashOf(`{"pvzId":"1"}`)
+	}
+
+	tests := []struct {
+		name            string
+		method          string
+		key             string
+		handlerStatus   int
+		mockBehavior    func(store *idempotencyMocks.MockIdempotencyStore)
+		expectedStatus  int
+		expectedBody    string
+		expectedReplay  bool
+		expectedHandled bool
+	}{
+		{
+			name:            "request without key is passed through",
+			method:          http.MethodPost,
+			handlerStatus:   http.StatusCreated,
+			mockBehavior:    func(store *idempotencyMocks.MockIdempotencyStore) {},
+			expectedStatus:  http.StatusCreated,
+			expectedBody:    `{"id":"new"}`,
+			expectedHandled: true,
+		},
+		{
+			name:            "non post request is passed through",
+			method:          http.MethodGet,
+			key:             "key1",
+			handlerStatus:   http.StatusOK,
+			mockBehavior:    func(store *idempotencyMocks.MockIdempotencyStore) {},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    `{"id":"new"}`,
+			expectedHandled: true,
+		},
+		{
+			name:           "too long key",
+			method:         http.MethodPost,
+			key:            strings.Repeat("k", models.MAX_IDEMPOTENCY_KEY_LENGTH+1),
+			mockBehavior:   func(store *idempotencyMocks.MockIdempotencyStore) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid idempotency key"}`,
+		},
+		{
+			name:          "first request stores response",
+			method:        http.MethodPost,
+			key:           "key1",
+			handlerStatus: http.StatusCreated,
+			mockBehavior: func(store *idempotencyMocks.MockIdempotencyStore) {
+				store.On("Acquire", mock.Anything, mock.MatchedBy(isKey)).Return(true, nil)
+				store.On("Complete", mock.Anything, mock.MatchedBy(func(record models.IdempotencyRecord) bool {
+					return isKey(record) && record.ResponseStatus == http.StatusCreated &&
+						record.ResponseContentType == "application/json" && string(record.ResponseBody) == `{"id":"new"}`
+				})).Return(nil)
+			},
+			expectedStatus:  http.StatusCreated,
+			expectedBody:    `{"id":"new"}`,
+			expectedHandled: true,
+		},
+		{
+			name:          "server error releases key",
+			method:        http.MethodPost,
+			key:           "key1",
+			handlerStatus: http.StatusInternalServerError,
+			mockBehavior: func(store *idempotencyMocks.MockIdempotencyStore) {
+				store.On("Acquire", mock.Anything, mock.MatchedBy(isKey)).Return(true, nil)
+				store.On("Release", mock.Anything, mock.MatchedBy(isKey)).Return(nil)
+			},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedBody:    `{"id":"new"}`,
+			expectedHandled: true,
+		},
+		{
+			name:   "retry replays stored response",
+			method: http.MethodPost,
+			key:    "key1",
+			mockBehavior: func(store *idempotencyMocks.MockIdempotencyStore) {
+				store.On("Acquire", mock.Anything, mock.Anything).Return(false, nil)
+				store.On("Get", mock.Anything, "user:user1", "key1").Return(&models.IdempotencyRecord{
+					RequestHash:         hashOf(`{"pvzId":"1"}`),
+					Status:              models.IDEMPOTENCY_STATUS_COMPLETED,
+					ResponseStatus:      http.StatusCreated,
+					ResponseContentType: "application/json",
+					ResponseBody:        []byte(`{"id":"stored"}`),
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":"stored"}`,
+			expectedReplay: true,
+		},
+		{
+			name:   "key reused for another request",
+			method: http.MethodPost,
+			key:    "key1",
+			mockBehavior: func(store *idempotencyMocks.MockIdempotencyStore) {
+				store.On("Acquire", mock.Anything, mock.Anything).Return(false, nil)
+				store.On("Get", mock.Anything, "user:user1", "key1").Return(&models.IdempotencyRecord{
+					RequestHash: hashOf(`{"pvzId":"2"}`),
+					Status:      models.IDEMPOTENCY_STATUS_COMPLETED,
+				}, nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"errors":"idempotency key is used for another request"}`,
+		},
+		{
+			name:   "concurrent request still in progress",
+			method: http.MethodPost,
+			key:    "key1",
+			mockBehavior: func(store *idempotencyMocks.MockIdempotencyStore) {
+				store.On("Acquire", mock.Anything, mock.Anything).Return(false, nil)
+				store.On("Get", mock.Anything, "user:user1", "key1").Return(&models.IdempotencyRecord{
+					RequestHash: hashOf(`{"pvzId":"1"}`),
+					Status:      models.IDEMPOTENCY_STATUS_IN_PROGRESS,
+				}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"request with this idempotency key is in progress"}`,
+		},
+		{
+			name:   "waits for concurrent request to finish",
+			method: http.MethodPost,
+			key:    "key1",
+			mockBehavior: func(store *idempotencyMocks.MockIdempotencyStore) {
+				store.On("Acquire", mock.Anything, mock.Anything).Return(false, nil)
+				store.On("Get", mock.Anything, "user:user1", "key1").Return(&models.IdempotencyRecord{
+					RequestHash: hashOf(`{"pvzId":"1"}`),
+					Status:      models.IDEMPOTENCY_STATUS_IN_PROGRESS,
+				}, nil).Once()
+				store.On("Get", mock.Anything, "user:user1", "key1").Return(&models.IdempotencyRecord{
+					RequestHash:         hashOf(`{"pvzId":"1"}`),
+					Status:              models.IDEMPOTENCY_STATUS_COMPLETED,
+					ResponseStatus:      http.StatusCreated,
+					ResponseContentType: "application/json",
+					ResponseBody:        []byte(`{"id":"stored"}`),
+				}, nil).Once()
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":"stored"}`,
+			expectedReplay: true,
+		},
+		{
+			name:   "store error",
+			method: http.MethodPost,
+			key:    "key1",
+			mockBehavior: func(store *idempotencyMocks.MockIdempotencyStore) {
+				store.On("Acquire", mock.Anything, mock.Anything).Return(false, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"errors":"db error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(idempotencyMocks.MockIdempotencyStore)
+			tt.mockBehavior(store)
+
+			handled := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = true
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, `{"pvzId":"1"}`, string(body))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.handlerStatus)
+				_, _ = w.Write([]byte(`{"id":"new"}`))
+			})
+
+			rr := httptest.NewRecorder()
+			IdempotencyMiddleware(store, time.Hour)(next).ServeHTTP(rr, newRequest(tt.method, `{"pvzId":"1"}`, tt.key))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			assert.Equal(t, tt.expectedHandled, handled)
+			if tt.expectedReplay {
+				assert.Equal(t, "true", rr.Header().Get(IdempotentReplayedHeader))
+			} else {
+				assert.Empty(t, rr.Header().Get(IdempotentReplayedHeader))
+			}
+			store.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ContextKeyRole, "employee")
+
+	assert.Equal(t, "user:user1", idempotencyScope(context.WithValue(context.WithValue(ctx, ContextKeyTokenId, "jti1"), ContextKeyUserId, "user1")))
+	assert.Equal(t, "token:jti1", idempotencyScope(context.WithValue(context.WithValue(ctx, ContextKeyTokenId, "jti1"), ContextKeyUserId, "")))
+	assert.Equal(t, "token:jti2", idempotencyScope(context.WithValue(ctx, ContextKeyTokenId, "jti2")))
+	assert.Empty(t, idempotencyScope(ctx))
+}
+
+func TestIdempotencyMiddleware_TokenWithoutOwnerIsPassedThrough(t *testing.T) {
+	store := new(idempotencyMocks.MockIdempotencyStore)
+	handled := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled = true
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/receptions", strings.NewReader(`{"pvzId":"1"}`))
+	req.Header.Set(IdempotencyKeyHeader, "key1")
+	req = req.WithContext(context.WithValue(req.Context(), ContextKeyRole, "employee"))
+	rr := httptest.NewRecorder()
+	IdempotencyMiddleware(store, time.Hour)(next).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.True(t, handled)
+	store.AssertExpectations(t)
+}
+
+func TestIdempotencyMiddleware_ExtendsLeaseWhileRequestRuns(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	previousLease := idempotencyLease
+	idempotencyLease = 30 * time.Millisecond
+	defer func() { idempotencyLease = previousLease }()
+
+	isKey := func(record models.IdempotencyRecord) bool {
+		return record.Scope == "token:jti1" && record.Key == "key1"
+	}
+	store := new(idempotencyMocks.MockIdempotencyStore)
+	store.On("Acquire", mock.Anything, mock.MatchedBy(func(record models.IdempotencyRecord) bool {
+		return isKey(record) && record.LockedUntil.Equal(record.CreatedAt.Add(30*time.Millisecond))
+	})).Return(true, nil)
+	store.On("Extend", mock.Anything, mock.MatchedBy(func(record models.IdempotencyRecord) bool {
+		return isKey(record) && record.LockedUntil.After(record.CreatedAt.Add(30*time.Millisecond))
+	})).Return(nil)
+	store.On("Complete", mock.Anything, mock.MatchedBy(isKey)).Return(nil)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(60 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/receptions", strings.NewReader(`{"pvzId":"1"}`))
+	req.Header.Set(IdempotencyKeyHeader, "key1")
+	ctx := context.WithValue(req.Context(), ContextKeyRole, "employee")
+	ctx = context.WithValue(ctx, ContextKeyTokenId, "jti1")
+	rr := httptest.NewRecorder()
+	IdempotencyMiddleware(store, time.Hour)(next).ServeHTTP(rr, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	store.AssertExpectations(t)
+
+	// После сохранения ответа аренда больше не продлевается.
+	extended := len(store.Calls)
+	time.Sleep(30 * time.Millisecond)
+	assert.Len(t, store.Calls, extended)
+}
@@ -77,8 +77,8 @@ func RateLimitMiddleware(next http.Handler) http.Handler {
 func EnableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Set-Cookie, X-CSRFToken, x-csrftoken, X-CSRF-Token")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Set-Cookie, X-CSRFToken, x-csrftoken, X-CSRF-Token, Idempotency-Key, Last-Event-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -90,9 +90,27 @@ func EnableCORS(next http.Handler) http.Handler {
 }
 
 const (
-	ContextKeyRole contextKey = "role"
+	ContextKeyRole    contextKey = "role"
+	ContextKeyUserId  contextKey = "user_id"
+	ContextKeyTokenId contextKey = "token_id"
 )
 
+// GetUserId возвращает id пользователя из токена; для токенов /dummyLogin он пустой.
+func GetUserId(ctx context.Context) string {
+	if userId, ok := ctx.Value(ContextKeyUserId).(string); ok {
+		return userId
+	}
+	return ""
+}
+
+// GetTokenId возвращает jti токена; у токенов, выпущенных без него, он пустой.
+func GetTokenId(ctx context.Context) string {
+	if tokenId, ok := ctx.Value(ContextKeyTokenId).(string); ok {
+		return tokenId
+	}
+	return ""
+}
+
 func RoleMiddleware(jwtService JwtTokenService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -110,6 +128,8 @@ func RoleMiddleware(jwtService JwtTokenService) func(http.Handler) http.Handler
 			}
 
 			ctx := context.WithValue(r.Context(), ContextKeyRole, claims.Role)
+			ctx = context.WithValue(ctx, ContextKeyUserId, claims.Subject)
+			ctx = context.WithValue(ctx, ContextKeyTokenId, claims.Id)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -100,28 +100,42 @@ func TestEnableCORS(t *testing.T) {
 
 			assert.Equal(t, tt.expectedStatus, rr.Code)
 			assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
-			assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", rr.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", rr.Header().Get("Access-Control-Allow-Methods"))
 		})
 	}
 }
 
 func TestRoleMiddleware(t *testing.T) {
 	tests := []struct {
-		name           string
-		setupMock      func(m *jwtMocks.MockJwtService)
-		authHeader     string
-		expectedStatus int
-		expectedRole   string
+		name            string
+		setupMock       func(m *jwtMocks.MockJwtService)
+		authHeader      string
+		expectedStatus  int
+		expectedRole    string
+		expectedUserId  string
+		expectedTokenId string
 	}{
 		{
 			name: "success with valid token",
 			setupMock: func(m *jwtMocks.MockJwtService) {
 				m.On("Validate", "valid.token").Return(
-					&jwt_service.JwtCsrfClaims{Role: "admin"}, nil)
+					&jwt_service.JwtCsrfClaims{Role: "admin", StandardClaims: jwt.StandardClaims{Id: "jti-1"}}, nil)
+			},
+			authHeader:      "Bearer valid.token",
+			expectedStatus:  http.StatusOK,
+			expectedRole:    "admin",
+			expectedTokenId: "jti-1",
+		},
+		{
+			name: "token with subject",
+			setupMock: func(m *jwtMocks.MockJwtService) {
+				m.On("Validate", "user.token").Return(
+					&jwt_service.JwtCsrfClaims{Role: "moderator", StandardClaims: jwt.StandardClaims{Subject: "user-1"}}, nil)
 			},
-			authHeader:     "Bearer valid.token",
+			authHeader:     "Bearer user.token",
 			expectedStatus: http.StatusOK,
-			expectedRole:   "admin",
+			expectedRole:   "moderator",
+			expectedUserId: "user-1",
 		},
 		{
 			name:           "missing authorization header",
@@ -164,6 +178,8 @@ func TestRoleMiddleware(t *testing.T) {
 			handler := RoleMiddleware(mockJwt)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				role := r.Context().Value(ContextKeyRole)
 				assert.Equal(t, tt.expectedRole, role)
+				assert.Equal(t, tt.expectedUserId, GetUserId(r.Context()))
+				assert.Equal(t, tt.expectedTokenId, GetTokenId(r.Context()))
 				w.WriteHeader(http.StatusOK)
 			}))
 
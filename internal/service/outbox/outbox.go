@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"encoding/json"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"slices"
+	"time"
+)
+
+// pvzEventsLockSpace — первый ключ двухключевого advisory lock; двухключевые блокировки не пересекаются
+// с одноключевыми блокировками фоновых задач.
+const pvzEventsLockSpace = 48
+
+// Execer — транзакция репозитория, в которой пишутся и изменения, и события о них.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func NewEvent(eventType, pvzId string, payload interface{}, occurredAt time.Time) (models.DomainEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return models.DomainEvent{}, err
+	}
+	return models.DomainEvent{
+		Id:         uuid.NewString(),
+		Type:       eventType,
+		PvzId:      pvzId,
+		Payload:    data,
+		OccurredAt: occurredAt,
+	}, nil
+}
+
+// Append пишет события в outbox. Вызывать нужно внутри транзакции, меняющей данные, — тогда событие
+// публикуется тогда и только тогда, когда изменение зафиксировано.
+// До вставки берётся блокировка каждого затронутого ПВЗ до конца транзакции: seq выдаётся при вставке,
+// и без блокировки транзакция с меньшим seq могла бы зафиксироваться позже и нарушить порядок событий ПВЗ.
+// Если транзакция пишет события разных ПВЗ несколькими вызовами Append, она заранее блокирует их все через LockPvzs.
+func Append(ctx context.Context, tx Execer, events ...models.DomainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	pvzIds := make([]string, 0, len(events))
+	for _, event := range events {
+		pvzIds = append(pvzIds, event.PvzId)
+	}
+	if err := LockPvzs(ctx, tx, pvzIds...); err != nil {
+		return err
+	}
+
+	insertBuilder := sq.Insert("outbox_events").
+		Columns("id", "event_type", "pvz_id", "payload", "occurred_at").
+		PlaceholderFormat(sq.Dollar)
+	for _, event := range events {
+		insertBuilder = insertBuilder.Values(event.Id, event.Type, event.PvzId, []byte(event.Payload), event.OccurredAt)
+	}
+	query, args, err := insertBuilder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to insert outbox events", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// LockPvzs берёт блокировки событий ПВЗ до конца транзакции по возрастанию id. Единый порядок захвата
+// исключает взаимоблокировки, но только в пределах одного вызова: транзакция, которая пишет события
+// нескольких ПВЗ разными вызовами Append, должна до первого из них заблокировать все эти ПВЗ сразу —
+// иначе порядок задали бы сами вызовы Append. Повторный захват уже взятой блокировки не ждёт.
+func LockPvzs(ctx context.Context, tx Execer, pvzIds ...string) error {
+	if len(pvzIds) == 0 {
+		return nil
+	}
+	pvzIds = slices.Clone(pvzIds)
+	slices.Sort(pvzIds)
+	pvzIds = slices.Compact(pvzIds)
+
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext(id)) FROM unnest($2::text[]) AS id",
+		pvzEventsLockSpace, pq.Array(pvzIds))
+	if err != nil {
+		logger.DBLogger.Error("failed to lock pvz events", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewEvent(t *testing.T) {
+	occurredAt := time.Date(2025, 5, 6, 10, 0, 0, 0, time.UTC)
+	event, err := NewEvent(models.EVENT_RECEPTION_OPENED, "pvz1", models.ReceptionOpenedPayload{
+		ReceptionId: "rec1",
+		PvzId:       "pvz1",
+		DateTime:    occurredAt,
+	}, occurredAt)
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, event.Id)
+	assert.Equal(t, models.EVENT_RECEPTION_OPENED, event.Type)
+	assert.Equal(t, "pvz1", event.PvzId)
+	assert.Equal(t, occurredAt, event.OccurredAt)
+	assert.JSONEq(t, `{"receptionId":"rec1","pvzId":"pvz1","dateTime":"2025-05-06T10:00:00Z"}`, string(event.Payload))
+}
+
+func TestAppend(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+	now := time.Now()
+	events := []models.DomainEvent{
+		{Id: "e1", Type: models.EVENT_PRODUCT_ADDED, PvzId: "pvz2", Payload: []byte(`{}`), OccurredAt: now},
+		{Id: "e2", Type: models.EVENT_PRODUCT_ADDED, PvzId: "pvz1", Payload: []byte(`{}`), OccurredAt: now},
+		{Id: "e3", Type: models.EVENT_RECEPTION_CLOSED, PvzId: "pvz2", Payload: []byte(`{}`), OccurredAt: now},
+	}
+
+	tests := []struct {
+		name        string
+		events      []models.DomainEvent
+		mock        func(sqlmock.Sqlmock)
+		expectedErr string
+	}{
+		{
+			name:   "Locks Each Pvz Once In Sorted Order",
+			events: events,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`^SELECT pg_advisory_xact_lock\(\$1, hashtext\(id\)\) FROM unnest\(\$2::text\[\]\) AS id$`).
+					WithArgs(pvzEventsLockSpace, `{"pvz1","pvz2"}`).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(`^INSERT INTO outbox_events \(id,event_type,pvz_id,payload,occurred_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\),\(\$6,\$7,\$8,\$9,\$10\),\(\$11,\$12,\$13,\$14,\$15\)$`).
+					WithArgs("e1", models.EVENT_PRODUCT_ADDED, "pvz2", []byte(`{}`), now,
+						"e2", models.EVENT_PRODUCT_ADDED, "pvz1", []byte(`{}`), now,
+						"e3", models.EVENT_RECEPTION_CLOSED, "pvz2", []byte(`{}`), now).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+		},
+		{
+			name:   "No Events",
+			events: nil,
+			mock:   func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name:   "Insert Error",
+			events: events[:1],
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`^SELECT pg_advisory_xact_lock`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`^INSERT INTO outbox_events`).
+					WillReturnError(errors.New("insert failed"))
+			},
+			expectedErr: "insert failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			tt.mock(mock)
+
+			err = Append(ctx, db, tt.events...)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Транзакция, пишущая события двух ПВЗ разными вызовами Append, блокирует оба заранее: порядок захвата
+// не зависит от того, событие какого ПВЗ записано первым.
+func TestLockPvzs(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	ctx := context.Background()
+	lockQuery := `^SELECT pg_advisory_xact_lock\(\$1, hashtext\(id\)\) FROM unnest\(\$2::text\[\]\) AS id$`
+
+	for _, pvzIds := range [][]string{{"pvz2", "pvz1"}, {"pvz1", "pvz2", "pvz1"}} {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		mock.ExpectExec(lockQuery).
+			WithArgs(pvzEventsLockSpace, `{"pvz1","pvz2"}`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		passed := slices.Clone(pvzIds)
+		require.NoError(t, LockPvzs(ctx, db, passed...))
+		assert.Equal(t, pvzIds, passed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		_ = db.Close()
+	}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, LockPvzs(ctx, db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LogPublisher пишет события в лог — для локального запуска, когда брокера нет.
+type LogPublisher struct {
+	logger *zap.Logger
+}
+
+func NewLogPublisher(logger *zap.Logger) *LogPublisher {
+	return &LogPublisher{
+		logger: logger,
+	}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, event models.DomainEvent) error {
+	p.logger.Info("domain event",
+		zap.String("event_id", event.Id),
+		zap.Int64("seq", event.Seq),
+		zap.String("event_type", event.Type),
+		zap.String("pvz_id", event.PvzId),
+		zap.ByteString("payload", event.Payload),
+		zap.Time("occurred_at", event.OccurredAt),
+	)
+	return nil
+}
+
+// FilePublisher дописывает события в файл по одному JSON на строку.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{
+		file: file,
+	}, nil
+}
+
+func (p *FilePublisher) Publish(_ context.Context, event models.DomainEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err = p.file.Write(line); err != nil {
+		return err
+	}
+	// Событие отмечается опубликованным сразу после Publish, поэтому запись должна дойти до диска.
+	return p.file.Sync()
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// MultiPublisher отдаёт событие каждому publisher по очереди. Если один из них вернул ошибку, relay повторит
+// событие целиком, и предыдущие получат его ещё раз — это укладывается в доставку «хотя бы один раз».
+type MultiPublisher []Publisher
+
+func NewMultiPublisher(publishers ...Publisher) MultiPublisher {
+	return publishers
+}
+
+func (p MultiPublisher) Publish(ctx context.Context, event models.DomainEvent) error {
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	outboxMocks "avito_spring_staj_2025/internal/tests/mocks/outbox_mocks"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	occurredAt := time.Date(2025, 5, 6, 10, 0, 0, 0, time.UTC)
+
+	publisher, err := NewFilePublisher(path)
+	require.NoError(t, err)
+	for _, id := range []string{"e1", "e2"} {
+		require.NoError(t, publisher.Publish(context.Background(), models.DomainEvent{
+			Id:         id,
+			Seq:        1,
+			Type:       models.EVENT_PVZ_CREATED,
+			PvzId:      "pvz1",
+			Payload:    []byte(`{"pvzId":"pvz1"}`),
+			OccurredAt: occurredAt,
+		}))
+	}
+	require.NoError(t, publisher.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id":"e1","seq":1,"type":"PvzCreated","pvzId":"pvz1","payload":{"pvzId":"pvz1"},"occurredAt":"2025-05-06T10:00:00Z"}`, lines[0])
+
+	var event models.DomainEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "e2", event.Id)
+}
+
+func TestLogPublisher(t *testing.T) {
+	err := NewLogPublisher(zap.NewNop()).Publish(context.Background(), models.DomainEvent{Id: "e1"})
+	assert.NoError(t, err)
+}
+
+func TestMultiPublisher(t *testing.T) {
+	event := models.DomainEvent{Id: "event1", Type: models.EVENT_RECEPTION_CLOSED, PvzId: "pvz1"}
+
+	t.Run("Publishes To All", func(t *testing.T) {
+		first, second := new(outboxMocks.MockPublisher), new(outboxMocks.MockPublisher)
+		first.On("Publish", context.Background(), event).Return(nil)
+		second.On("Publish", context.Background(), event).Return(nil)
+
+		require.NoError(t, NewMultiPublisher(first, second).Publish(context.Background(), event))
+		first.AssertExpectations(t)
+		second.AssertExpectations(t)
+	})
+
+	t.Run("Stops On Error", func(t *testing.T) {
+		first, second := new(outboxMocks.MockPublisher), new(outboxMocks.MockPublisher)
+		first.On("Publish", context.Background(), event).Return(errors.New("broker is down"))
+
+		err := NewMultiPublisher(first, second).Publish(context.Background(), event)
+		assert.EqualError(t, err, "broker is down")
+		second.AssertNotCalled(t, "Publish", context.Background(), event)
+	})
+}
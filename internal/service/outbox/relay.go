@@ -0,0 +1,140 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+	"time"
+)
+
+const DefaultRelayBatchSize = 100
+
+// Publisher доставляет событие во внешнюю систему. Доставка «хотя бы один раз»: после сбоя между
+// публикацией и отметкой в outbox событие будет опубликовано повторно, подписчики отсеивают дубли по id.
+type Publisher interface {
+	Publish(ctx context.Context, event models.DomainEvent) error
+}
+
+// Relay переносит неопубликованные события из outbox в Publisher строго по seq.
+// Запускать его нужно в одном экземпляре (фоновая задача под advisory lock), иначе порядок не гарантируется.
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	batchSize int
+}
+
+func NewRelay(db *sql.DB, publisher Publisher, batchSize int) *Relay {
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		batchSize: batchSize,
+	}
+}
+
+// RelayOnce публикует очередную пачку событий и возвращает число опубликованных.
+// Если событие ПВЗ опубликовать не удалось, следующие события этого ПВЗ в пачке пропускаются до следующего
+// запуска, чтобы не нарушить порядок; события других ПВЗ публикуются как обычно.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.pendingEvents(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	blockedPvzs := make(map[string]bool)
+	var publishErr error
+	for _, event := range events {
+		if blockedPvzs[event.PvzId] {
+			continue
+		}
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			logger.JobLogger.Error("failed to publish event",
+				zap.String("event_id", event.Id),
+				zap.String("event_type", event.Type),
+				zap.String("pvz_id", event.PvzId),
+				zap.Error(err),
+			)
+			blockedPvzs[event.PvzId] = true
+			publishErr = errors.Join(publishErr, err)
+			continue
+		}
+		if err := r.markPublished(ctx, event.Id); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, publishErr
+}
+
+// Relay публикует события пачками, пока они есть, и возвращает общее число опубликованных.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, err := r.RelayOnce(ctx)
+		total += published
+		if err != nil || published < r.batchSize {
+			return total, err
+		}
+	}
+}
+
+func (r *Relay) pendingEvents(ctx context.Context) ([]models.DomainEvent, error) {
+	query, args, err := sq.Select("id", "seq", "event_type", "pvz_id", "payload", "occurred_at").
+		From("outbox_events").
+		Where(sq.Eq{"published_at": nil}).
+		OrderBy("seq").
+		Limit(uint64(r.batchSize)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query outbox events", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	events := make([]models.DomainEvent, 0)
+	for rows.Next() {
+		var event models.DomainEvent
+		var payload []byte
+		if err = rows.Scan(&event.Id, &event.Seq, &event.Type, &event.PvzId, &payload, &event.OccurredAt); err != nil {
+			logger.DBLogger.Error("failed to scan outbox event", zap.Error(err))
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("failed to iterate outbox events", zap.Error(err))
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *Relay) markPublished(ctx context.Context, eventId string) error {
+	query, args, err := sq.Update("outbox_events").
+		Set("published_at", time.Now()).
+		Where(sq.Eq{"id": eventId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to mark outbox event as published", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	outboxMocks "avito_spring_staj_2025/internal/tests/mocks/outbox_mocks"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestRelay_RelayOnce(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	logger.JobLogger = zap.NewNop()
+	ctx := context.Background()
+	now := time.Now()
+	selectQuery := `^SELECT id, seq, event_type, pvz_id, payload, occurred_at FROM outbox_events WHERE published_at IS NULL ORDER BY seq LIMIT 10$`
+	markQuery := `^UPDATE outbox_events SET published_at = \$1 WHERE id = \$2$`
+	pendingRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "seq", "event_type", "pvz_id", "payload", "occurred_at"}).
+			AddRow("e1", int64(1), models.EVENT_RECEPTION_OPENED, "pvz1", []byte(`{"receptionId":"rec1"}`), now).
+			AddRow("e2", int64(2), models.EVENT_RECEPTION_OPENED, "pvz2", []byte(`{"receptionId":"rec2"}`), now).
+			AddRow("e3", int64(3), models.EVENT_PRODUCT_ADDED, "pvz1", []byte(`{"productId":"prod1"}`), now)
+	}
+	isEvent := func(id string) interface{} {
+		return mock.MatchedBy(func(event models.DomainEvent) bool { return event.Id == id })
+	}
+
+	tests := []struct {
+		name              string
+		mock              func(sqlmock.Sqlmock, *outboxMocks.MockPublisher)
+		expectedPublished int
+		expectedErr       string
+	}{
+		{
+			name: "Publishes In Order And Marks Each Event",
+			mock: func(db sqlmock.Sqlmock, publisher *outboxMocks.MockPublisher) {
+				db.ExpectQuery(selectQuery).WillReturnRows(pendingRows())
+				publisher.On("Publish", mock.Anything, models.DomainEvent{
+					Id:         "e1",
+					Seq:        1,
+					Type:       models.EVENT_RECEPTION_OPENED,
+					PvzId:      "pvz1",
+					Payload:    []byte(`{"receptionId":"rec1"}`),
+					OccurredAt: now,
+				}).Return(nil).Once()
+				db.ExpectExec(markQuery).WithArgs(sqlmock.AnyArg(), "e1").WillReturnResult(sqlmock.NewResult(0, 1))
+				publisher.On("Publish", mock.Anything, isEvent("e2")).Return(nil).Once()
+				db.ExpectExec(markQuery).WithArgs(sqlmock.AnyArg(), "e2").WillReturnResult(sqlmock.NewResult(0, 1))
+				publisher.On("Publish", mock.Anything, isEvent("e3")).Return(nil).Once()
+				db.ExpectExec(markQuery).WithArgs(sqlmock.AnyArg(), "e3").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedPublished: 3,
+		},
+		{
+			name: "Failed Event Holds Back Later Events Of The Same Pvz",
+			mock: func(db sqlmock.Sqlmock, publisher *outboxMocks.MockPublisher) {
+				db.ExpectQuery(selectQuery).WillReturnRows(pendingRows())
+				publisher.On("Publish", mock.Anything, isEvent("e1")).Return(errors.New("broker unavailable")).Once()
+				publisher.On("Publish", mock.Anything, isEvent("e2")).Return(nil).Once()
+				db.ExpectExec(markQuery).WithArgs(sqlmock.AnyArg(), "e2").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedPublished: 1,
+			expectedErr:       "broker unavailable",
+		},
+		{
+			name: "Mark Error Stops Relay",
+			mock: func(db sqlmock.Sqlmock, publisher *outboxMocks.MockPublisher) {
+				db.ExpectQuery(selectQuery).WillReturnRows(pendingRows())
+				publisher.On("Publish", mock.Anything, isEvent("e1")).Return(nil).Once()
+				db.ExpectExec(markQuery).WithArgs(sqlmock.AnyArg(), "e1").WillReturnError(errors.New("db error"))
+			},
+			expectedErr: "db error",
+		},
+		{
+			name: "Query Error",
+			mock: func(db sqlmock.Sqlmock, publisher *outboxMocks.MockPublisher) {
+				db.ExpectQuery(selectQuery).WillReturnError(errors.New("db error"))
+			},
+			expectedErr: "db error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			publisher := new(outboxMocks.MockPublisher)
+			tt.mock(dbMock, publisher)
+
+			published, err := NewRelay(db, publisher, 10).RelayOnce(ctx)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedPublished, published)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+			publisher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRelay_Relay(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	logger.JobLogger = zap.NewNop()
+
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	publisher := new(outboxMocks.MockPublisher)
+	columns := []string{"id", "seq", "event_type", "pvz_id", "payload", "occurred_at"}
+
+	dbMock.ExpectQuery(`FROM outbox_events`).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("e1", int64(1), models.EVENT_PVZ_CREATED, "pvz1", []byte(`{}`), time.Now()))
+	dbMock.ExpectExec(`UPDATE outbox_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectQuery(`FROM outbox_events`).WillReturnRows(sqlmock.NewRows(columns))
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+
+	published, err := NewRelay(db, publisher, 1).Relay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+	publisher.AssertExpectations(t)
+}
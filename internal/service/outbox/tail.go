@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Сколько Tail ждёт пропущенный seq: его транзакция могла зафиксироваться позже следующих событий
+// (события разных ПВЗ не упорядочены между собой) или откатиться, и тогда номер не появится никогда.
+// maxTailGaps ограничивает число ожидаемых номеров, если последовательность резко скакнула.
+var (
+	tailGapTimeout = 10 * time.Second
+	maxTailGaps    = 1000
+)
+
+// Tail читает зафиксированные события outbox и отдаёт их Publisher внутри своего процесса, например
+// SSE-хабу. В отличие от Relay он не отмечает события и не берёт блокировок, поэтому работает на каждом
+// экземпляре сервиса: идёт по seq от момента запуска и сам помнит, до какого события дочитал.
+// Порядок в пределах ПВЗ сохраняется, потому что транзакция с событиями ПВЗ держит его advisory lock до фиксации.
+type Tail struct {
+	db        *sql.DB
+	publisher Publisher
+	batchSize int
+
+	started bool
+	lastSeq int64
+	gaps    map[int64]time.Time
+}
+
+func NewTail(db *sql.DB, publisher Publisher, batchSize int) *Tail {
+	return &Tail{
+		db:        db,
+		publisher: publisher,
+		batchSize: batchSize,
+		gaps:      make(map[int64]time.Time),
+	}
+}
+
+// PollOnce отдаёт Publisher очередную пачку новых событий и возвращает число прочитанных. Первый вызов
+// только запоминает текущий конец outbox: история нужна подписчикам лишь при переподключении, и её они читают сами.
+// Tail не рассчитан на одновременные вызовы.
+func (t *Tail) PollOnce(ctx context.Context) (int, error) {
+	if !t.started {
+		if err := t.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM outbox_events").Scan(&t.lastSeq); err != nil {
+			logger.DBLogger.Error("failed to read last outbox seq", zap.Error(err))
+			return 0, err
+		}
+		t.started = true
+		return 0, nil
+	}
+
+	now := time.Now()
+	for seq, waitUntil := range t.gaps {
+		if now.After(waitUntil) {
+			delete(t.gaps, seq)
+		}
+	}
+
+	events, err := t.newEvents(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if event.Seq > t.lastSeq {
+			for seq := t.lastSeq + 1; seq < event.Seq && len(t.gaps) < maxTailGaps; seq++ {
+				t.gaps[seq] = now.Add(tailGapTimeout)
+			}
+			t.lastSeq = event.Seq
+		} else {
+			delete(t.gaps, event.Seq)
+		}
+
+		// Публикация во внутренний Publisher не повторяется: отставший подписчик догонит пропущенное по seq.
+		if err := t.publisher.Publish(ctx, event); err != nil {
+			logger.JobLogger.Error("failed to publish event locally",
+				zap.String("event_id", event.Id),
+				zap.String("event_type", event.Type),
+				zap.String("pvz_id", event.PvzId),
+				zap.Error(err),
+			)
+		}
+	}
+	return len(events), nil
+}
+
+// Poll читает события пачками, пока они есть, и возвращает общее число прочитанных.
+func (t *Tail) Poll(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		read, err := t.PollOnce(ctx)
+		total += read
+		if err != nil || read < t.batchSize {
+			return total, err
+		}
+	}
+}
+
+func (t *Tail) newEvents(ctx context.Context) ([]models.DomainEvent, error) {
+	var where sq.Sqlizer = sq.Gt{"seq": t.lastSeq}
+	if len(t.gaps) > 0 {
+		gapSeqs := make([]int64, 0, len(t.gaps))
+		for seq := range t.gaps {
+			gapSeqs = append(gapSeqs, seq)
+		}
+		where = sq.Or{where, sq.Eq{"seq": gapSeqs}}
+	}
+
+	query, args, err := sq.Select("id", "seq", "event_type", "pvz_id", "payload", "occurred_at").
+		From("outbox_events").
+		Where(where).
+		OrderBy("seq").
+		Limit(uint64(t.batchSize)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query outbox events", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	events := make([]models.DomainEvent, 0)
+	for rows.Next() {
+		var event models.DomainEvent
+		var payload []byte
+		if err = rows.Scan(&event.Id, &event.Seq, &event.Type, &event.PvzId, &payload, &event.OccurredAt); err != nil {
+			logger.DBLogger.Error("failed to scan outbox event", zap.Error(err))
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		logger.DBLogger.Error("failed to iterate outbox events", zap.Error(err))
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,215 @@
+package outbox
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/eventhub"
+	"avito_spring_staj_2025/internal/service/logger"
+	outboxMocks "avito_spring_staj_2025/internal/tests/mocks/outbox_mocks"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+var outboxEventColumns = []string{"id", "seq", "event_type", "pvz_id", "payload", "occurred_at"}
+
+const (
+	tailStartQuery = `^SELECT COALESCE\(MAX\(seq\), 0\) FROM outbox_events$`
+	tailQuery      = `^SELECT id, seq, event_type, pvz_id, payload, occurred_at FROM outbox_events WHERE seq > \$1 ORDER BY seq LIMIT 10$`
+)
+
+func expectTailStart(dbMock sqlmock.Sqlmock, lastSeq int64) {
+	dbMock.ExpectQuery(tailStartQuery).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(lastSeq))
+}
+
+func TestTail_PollOnce(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	logger.JobLogger = zap.NewNop()
+	ctx := context.Background()
+	now := time.Now()
+	isEvent := func(id string) interface{} {
+		return mock.MatchedBy(func(event models.DomainEvent) bool { return event.Id == id })
+	}
+
+	t.Run("Starts From The End Of Outbox", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		publisher := new(outboxMocks.MockPublisher)
+
+		expectTailStart(dbMock, 5)
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows(outboxEventColumns).
+				AddRow("e6", int64(6), models.EVENT_RECEPTION_OPENED, "pvz1", []byte(`{}`), now).
+				AddRow("e7", int64(7), models.EVENT_PRODUCT_ADDED, "pvz1", []byte(`{}`), now))
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(outboxEventColumns))
+		publisher.On("Publish", mock.Anything, isEvent("e6")).Return(nil).Once()
+		publisher.On("Publish", mock.Anything, isEvent("e7")).Return(nil).Once()
+
+		tail := NewTail(db, publisher, 10)
+		read, err := tail.PollOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, read)
+		read, err = tail.PollOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, read)
+		read, err = tail.PollOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, read)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+		publisher.AssertExpectations(t)
+	})
+
+	t.Run("Picks Up Event Committed After A Later One", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		publisher := new(outboxMocks.MockPublisher)
+
+		expectTailStart(dbMock, 0)
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(0)).
+			WillReturnRows(sqlmock.NewRows(outboxEventColumns).
+				AddRow("e2", int64(2), models.EVENT_RECEPTION_OPENED, "pvz2", []byte(`{}`), now))
+		dbMock.ExpectQuery(`^SELECT id, seq, event_type, pvz_id, payload, occurred_at FROM outbox_events `+
+			`WHERE \(seq > \$1 OR seq IN \(\$2\)\) ORDER BY seq LIMIT 10$`).
+			WithArgs(int64(2), int64(1)).
+			WillReturnRows(sqlmock.NewRows(outboxEventColumns).
+				AddRow("e1", int64(1), models.EVENT_RECEPTION_OPENED, "pvz1", []byte(`{}`), now))
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows(outboxEventColumns))
+		publisher.On("Publish", mock.Anything, isEvent("e2")).Return(nil).Once()
+		publisher.On("Publish", mock.Anything, isEvent("e1")).Return(nil).Once()
+
+		tail := NewTail(db, publisher, 10)
+		for i := 0; i < 4; i++ {
+			_, err = tail.PollOnce(ctx)
+			require.NoError(t, err)
+		}
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+		publisher.AssertExpectations(t)
+	})
+
+	t.Run("Stops Waiting For A Missing Seq", func(t *testing.T) {
+		previousTimeout := tailGapTimeout
+		tailGapTimeout = 0
+		defer func() { tailGapTimeout = previousTimeout }()
+
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		publisher := new(outboxMocks.MockPublisher)
+
+		expectTailStart(dbMock, 0)
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(0)).
+			WillReturnRows(sqlmock.NewRows(outboxEventColumns).
+				AddRow("e2", int64(2), models.EVENT_RECEPTION_OPENED, "pvz1", []byte(`{}`), now))
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows(outboxEventColumns))
+		publisher.On("Publish", mock.Anything, isEvent("e2")).Return(nil).Once()
+
+		tail := NewTail(db, publisher, 10)
+		for i := 0; i < 3; i++ {
+			_, err = tail.PollOnce(ctx)
+			require.NoError(t, err)
+		}
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("Publish Error Does Not Stop Tail", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		publisher := new(outboxMocks.MockPublisher)
+
+		expectTailStart(dbMock, 0)
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(0)).
+			WillReturnRows(sqlmock.NewRows(outboxEventColumns).
+				AddRow("e1", int64(1), models.EVENT_RECEPTION_OPENED, "pvz1", []byte(`{}`), now).
+				AddRow("e2", int64(2), models.EVENT_PRODUCT_ADDED, "pvz1", []byte(`{}`), now))
+		publisher.On("Publish", mock.Anything, isEvent("e1")).Return(errors.New("hub closed")).Once()
+		publisher.On("Publish", mock.Anything, isEvent("e2")).Return(nil).Once()
+
+		tail := NewTail(db, publisher, 10)
+		_, err = tail.PollOnce(ctx)
+		require.NoError(t, err)
+		read, err := tail.PollOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, read)
+		publisher.AssertExpectations(t)
+	})
+
+	t.Run("Query Error", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		dbMock.ExpectQuery(tailStartQuery).WillReturnError(errors.New("db error"))
+
+		_, err = NewTail(db, new(outboxMocks.MockPublisher), 10).PollOnce(ctx)
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+// Каждый экземпляр сервиса читает outbox своим Tail, а Relay работает только на одном из них:
+// живые события должны получить подписчики обоих экземпляров, а внешний Publisher — один раз.
+func TestTail_EveryInstanceHubReceivesEvent(t *testing.T) {
+	logger.DBLogger = zap.NewNop()
+	logger.JobLogger = zap.NewNop()
+	ctx := context.Background()
+	now := time.Now()
+	newEventRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(outboxEventColumns).
+			AddRow("e1", int64(1), models.EVENT_PRODUCT_ADDED, "pvz1", []byte(`{"productId":"prod1"}`), now)
+	}
+
+	subscriptions := make([]*eventhub.Subscription, 0, 2)
+	tails := make([]*Tail, 0, 2)
+	for i := 0; i < 2; i++ {
+		db, dbMock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		expectTailStart(dbMock, 0)
+		dbMock.ExpectQuery(tailQuery).WithArgs(int64(0)).WillReturnRows(newEventRows())
+
+		hub := eventhub.NewHub(eventhub.DefaultSubscriberBuffer)
+		subscription := hub.Subscribe(nil)
+		defer subscription.Close()
+		subscriptions = append(subscriptions, subscription)
+		tails = append(tails, NewTail(db, hub, 10))
+	}
+
+	relayDb, relayMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer relayDb.Close()
+	relayMock.ExpectQuery(`FROM outbox_events WHERE published_at IS NULL`).WillReturnRows(newEventRows())
+	relayMock.ExpectExec(`^UPDATE outbox_events SET published_at`).WillReturnResult(sqlmock.NewResult(0, 1))
+	external := new(outboxMocks.MockPublisher)
+	external.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+
+	for _, tail := range tails {
+		_, err = tail.PollOnce(ctx)
+		require.NoError(t, err)
+	}
+	published, err := NewRelay(relayDb, external, 10).RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	for _, tail := range tails {
+		read, err := tail.PollOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, read)
+	}
+
+	for _, subscription := range subscriptions {
+		select {
+		case event := <-subscription.Events():
+			assert.Equal(t, "e1", event.Id)
+		case <-time.After(time.Second):
+			t.Fatal("subscriber did not receive the event")
+		}
+	}
+	external.AssertExpectations(t)
+	assert.NoError(t, relayMock.ExpectationsWereMet())
+}
@@ -0,0 +1,50 @@
+package pvzcapacity
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// Reserve занимает на ПВЗ count мест под товары и возвращает заполненность и вместимость после этого.
+// Инкремент условный и идёт под блокировкой строки ПВЗ, поэтому параллельные транзакции не превысят
+// вместимость. С политикой warn места выдаются и сверх неё — это видно по возвращённым значениям.
+// Вызывать нужно в транзакции, которая кладёт товары на ПВЗ, чтобы при ошибке откатилось всё вместе.
+func Reserve(ctx context.Context, tx dbtx.Querier, pvzId string, count int) (int, sql.NullInt64, error) {
+	var occupancy int
+	var capacity sql.NullInt64
+
+	query, args, err := sq.Update("pvzs").
+		Set("occupancy", sq.Expr("occupancy + ?", count)).
+		Where(sq.Eq{"id": pvzId}).
+		Where(sq.Or{
+			sq.Eq{"capacity": nil},
+			sq.Expr("occupancy + ? <= capacity", count),
+			sq.Eq{"capacity_policy": models.CAPACITY_POLICY_WARN},
+		}).
+		Suffix("RETURNING occupancy, capacity").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return 0, capacity, err
+	}
+
+	if err = tx.QueryRowContext(ctx, query, args...).Scan(&occupancy, &capacity); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.DBLogger.Info("pvz capacity exceeded",
+				zap.String("request_id", middleware.GetRequestID(ctx)),
+				zap.String("pvz_id", pvzId))
+			return 0, capacity, errors.New("pvz capacity exceeded")
+		}
+		logger.DBLogger.Error("failed to update pvz occupancy", zap.Error(err))
+		return 0, capacity, err
+	}
+	return occupancy, capacity, nil
+}
@@ -2,22 +2,33 @@ package router
 
 import (
 	auth "avito_spring_staj_2025/internal/auth/handler"
+	damage "avito_spring_staj_2025/internal/damage/handler"
+	eventStream "avito_spring_staj_2025/internal/eventstream/handler"
+	export "avito_spring_staj_2025/internal/export/handler"
+	inventory "avito_spring_staj_2025/internal/inventory/handler"
+	product "avito_spring_staj_2025/internal/product/handler"
+	productType "avito_spring_staj_2025/internal/producttype/handler"
 	pvz "avito_spring_staj_2025/internal/pvz/handler"
 	reception "avito_spring_staj_2025/internal/reception/handler"
+	schedule "avito_spring_staj_2025/internal/schedule/handler"
 	"avito_spring_staj_2025/internal/service/jwt"
 	"avito_spring_staj_2025/internal/service/metrics"
 	"avito_spring_staj_2025/internal/service/middleware"
+	storageCell "avito_spring_staj_2025/internal/storagecell/handler"
+	webhook "avito_spring_staj_2025/internal/webhook/handler"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
+	"time"
 )
 
-func SetUpRoutes(authHandler *auth.AuthHandler, pvzHandler *pvz.PvzHandler, receptionHandler *reception.ReceptionHandler, jwtService jwt.JwtToken) *mux.Router {
+func SetUpRoutes(authHandler *auth.AuthHandler, pvzHandler *pvz.PvzHandler, receptionHandler *reception.ReceptionHandler, scheduleHandler *schedule.ScheduleHandler, exportHandler *export.ExportHandler, productTypeHandler *productType.ProductTypeHandler, productHandler *product.ProductHandler, storageCellHandler *storageCell.StorageCellHandler, inventoryHandler *inventory.InventoryHandler, damageHandler *damage.DamageHandler, webhookHandler *webhook.WebhookHandler, eventStreamHandler *eventStream.EventStreamHandler, idempotencyStore middleware.IdempotencyStore, idempotencyKeyTTL time.Duration, jwtService jwt.JwtToken) *mux.Router {
 	router := mux.NewRouter()
 	api := "/api"
 
 	withLogging := middleware.WithLoggingAndMetrics
 	withAuth := middleware.RoleMiddleware(jwtService)
+	withIdempotency := middleware.IdempotencyMiddleware(idempotencyStore, idempotencyKeyTTL)
 
 	withCreatedPvzMetric := middleware.WithCustomMetric(metrics.AmountOfCreatedPvz)
 	withCreatedReceptionMetric := middleware.WithCustomMetric(metrics.AmountOfCreatedReceptions)
@@ -27,14 +38,67 @@ func SetUpRoutes(authHandler *auth.AuthHandler, pvzHandler *pvz.PvzHandler, rece
 	router.Handle(api+"/register", middleware.ChainMiddlewares(http.HandlerFunc(authHandler.Register), withLogging)).Methods("POST")
 	router.Handle(api+"/login", middleware.ChainMiddlewares(http.HandlerFunc(authHandler.Login), withLogging)).Methods("POST")
 
-	router.Handle(api+"/pvz", middleware.ChainMiddlewares(http.HandlerFunc(pvzHandler.CreatePvz), withLogging, withAuth, withCreatedPvzMetric)).Methods("POST")
-	router.Handle(api+"/receptions", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.CreateReception), withLogging, withAuth, withCreatedReceptionMetric)).Methods("POST")
-	router.Handle(api+"/products", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.AddProductToReception), withLogging, withAuth, withAddedProductMetric)).Methods("POST")
-	router.Handle(api+"/pvz/{pvzId}/delete_last_product", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.DeleteLastProduct), withLogging, withAuth)).Methods("POST")
-	router.Handle(api+"/pvz/{pvzId}/close_last_reception", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.CloseLastReception), withLogging, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz", middleware.ChainMiddlewares(http.HandlerFunc(pvzHandler.CreatePvz), withLogging, withIdempotency, withAuth, withCreatedPvzMetric)).Methods("POST")
+	router.Handle(api+"/pvz/import", middleware.ChainMiddlewares(http.HandlerFunc(pvzHandler.ImportPvzs), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/receptions", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.CreateReception), withLogging, withIdempotency, withAuth, withCreatedReceptionMetric)).Methods("POST")
+	router.Handle(api+"/products", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.AddProductToReception), withLogging, withIdempotency, withAuth, withAddedProductMetric)).Methods("POST")
+	router.Handle(api+"/products", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.GetProductsByBarcode), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/products/batch", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.AddProductsToReception), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/delete_last_product", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.DeleteLastProduct), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/remove_product", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.RemoveProduct), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/issue_product", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.IssueProduct), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/refuse_product", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.RefuseProduct), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/return_product", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.ReturnProduct), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/pickup_product", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.PickupProduct), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/orders/{externalId}/customer", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.SetOrderCustomer), withLogging, withAuth)).Methods("PUT")
+	router.Handle(api+"/pickup_codes", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.GetPickupCodes), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pickup_codes/{productId}/regenerate", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.RegeneratePickupCode), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/overdue_products", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.GetOverdueProducts), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/transfers", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.CreateTransfer), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/transfers/incoming", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.GetIncomingTransfers), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/transfers/{transferId}/accept", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.AcceptTransfer), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/cells", middleware.ChainMiddlewares(http.HandlerFunc(storageCellHandler.GetStorageCells), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/cells", middleware.ChainMiddlewares(http.HandlerFunc(storageCellHandler.SetStorageCells), withLogging, withAuth)).Methods("PUT")
+	router.Handle(api+"/pvz/{pvzId}/cells/suggestion", middleware.ChainMiddlewares(http.HandlerFunc(storageCellHandler.SuggestCell), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/cells/assign", middleware.ChainMiddlewares(http.HandlerFunc(storageCellHandler.AssignCell), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/cells/lookup", middleware.ChainMiddlewares(http.HandlerFunc(storageCellHandler.LookupProduct), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/inventory", middleware.ChainMiddlewares(http.HandlerFunc(inventoryHandler.StartInventory), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/inventory/{sessionId}", middleware.ChainMiddlewares(http.HandlerFunc(inventoryHandler.GetInventorySession), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/inventory/{sessionId}/scans", middleware.ChainMiddlewares(http.HandlerFunc(inventoryHandler.ScanInventoryItem), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/inventory/{sessionId}/complete", middleware.ChainMiddlewares(http.HandlerFunc(inventoryHandler.CompleteInventory), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/inventory/{sessionId}/approve", middleware.ChainMiddlewares(http.HandlerFunc(inventoryHandler.ApproveInventory), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/damage-reports", middleware.ChainMiddlewares(http.HandlerFunc(damageHandler.CreateDamageReport), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/damage-reports", middleware.ChainMiddlewares(http.HandlerFunc(damageHandler.GetDamageReports), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/damage-reports/{reportId}", middleware.ChainMiddlewares(http.HandlerFunc(damageHandler.GetDamageReport), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/damage-reports/{reportId}/photos", middleware.ChainMiddlewares(http.HandlerFunc(damageHandler.UploadDamagePhoto), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/damage-reports/{reportId}/photos/{photoId}", middleware.ChainMiddlewares(http.HandlerFunc(damageHandler.GetDamagePhoto), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/webhooks", middleware.ChainMiddlewares(http.HandlerFunc(webhookHandler.CreateWebhook), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/webhooks", middleware.ChainMiddlewares(http.HandlerFunc(webhookHandler.GetWebhooks), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/webhooks/{webhookId}", middleware.ChainMiddlewares(http.HandlerFunc(webhookHandler.DeleteWebhook), withLogging, withAuth)).Methods("DELETE")
+	router.Handle(api+"/webhooks/{webhookId}/deliveries", middleware.ChainMiddlewares(http.HandlerFunc(webhookHandler.GetWebhookDeliveries), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", middleware.ChainMiddlewares(http.HandlerFunc(webhookHandler.RedeliverWebhookDelivery), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/products/{productId}/history", middleware.ChainMiddlewares(http.HandlerFunc(productHandler.GetProductHistory), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/close_last_reception", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.CloseLastReception), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/receptions/{receptionId}/reopen", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.ReopenReception), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/receptions/{receptionId}/manifest", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.SetReceptionManifest), withLogging, withAuth)).Methods("PUT")
+	router.Handle(api+"/receptions/{receptionId}/discrepancy_report", middleware.ChainMiddlewares(http.HandlerFunc(receptionHandler.GetDiscrepancyReport), withLogging, withAuth)).Methods("GET")
 	router.Handle(api+"/pvz", middleware.ChainMiddlewares(http.HandlerFunc(pvzHandler.GetPvzsInformation), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/schedule", middleware.ChainMiddlewares(http.HandlerFunc(scheduleHandler.GetSchedule), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/schedule", middleware.ChainMiddlewares(http.HandlerFunc(scheduleHandler.SetWorkingHours), withLogging, withAuth)).Methods("PUT")
+	router.Handle(api+"/pvz/{pvzId}/schedule/exceptions", middleware.ChainMiddlewares(http.HandlerFunc(scheduleHandler.AddScheduleException), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/pvz/{pvzId}/schedule/exceptions/{exceptionId}", middleware.ChainMiddlewares(http.HandlerFunc(scheduleHandler.DeleteScheduleException), withLogging, withAuth)).Methods("DELETE")
+	router.Handle(api+"/product_types", middleware.ChainMiddlewares(http.HandlerFunc(productTypeHandler.GetProductTypes), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/product_types", middleware.ChainMiddlewares(http.HandlerFunc(productTypeHandler.CreateProductType), withLogging, withIdempotency, withAuth)).Methods("POST")
+	router.Handle(api+"/product_types/{code}", middleware.ChainMiddlewares(http.HandlerFunc(productTypeHandler.UpdateProductType), withLogging, withAuth)).Methods("PATCH")
+	router.Handle(api+"/product_types/{code}", middleware.ChainMiddlewares(http.HandlerFunc(productTypeHandler.DeleteProductType), withLogging, withAuth)).Methods("DELETE")
+	router.Handle(api+"/export/{entity}", middleware.ChainMiddlewares(http.HandlerFunc(exportHandler.Export), withLogging, withAuth)).Methods("GET")
 	router.Handle(api+"/metrics", promhttp.Handler())
 
 	router.HandleFunc(api+"/pvz/grpc", pvzHandler.GetPvzListFromGrpc).Methods("GET")
+	// SSE-потоки живут дольше таймаута запроса из WithLoggingAndMetrics, поэтому открытие и закрытие логирует сам обработчик.
+	router.Handle(api+"/pvz/events", middleware.ChainMiddlewares(http.HandlerFunc(eventStreamHandler.StreamCityEvents), withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/events", middleware.ChainMiddlewares(http.HandlerFunc(eventStreamHandler.StreamPvzEvents), withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}", middleware.ChainMiddlewares(http.HandlerFunc(pvzHandler.GetPvz), withLogging, withAuth)).Methods("GET")
+	router.Handle(api+"/pvz/{pvzId}/capacity", middleware.ChainMiddlewares(http.HandlerFunc(pvzHandler.SetPvzCapacity), withLogging, withAuth)).Methods("PUT")
 	return router
 }
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Job — периодическая фоновая задача. LockKey — ключ advisory lock в Postgres:
+// при нескольких запущенных экземплярах сервиса задачу за один тик выполняет только один.
+// Local-задача работает с состоянием своего процесса, поэтому выполняется на каждом экземпляре без блокировки.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	LockKey  int64
+	Local    bool
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	db   *sql.DB
+	jobs []Job
+}
+
+func NewScheduler(db *sql.DB) *Scheduler {
+	return &Scheduler{
+		db: db,
+	}
+}
+
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start запускает каждую задачу в своей горутине; задачи работают, пока не отменён ctx.
+// Первый запуск происходит сразу, иначе редкую задачу откладывал бы на полный интервал каждый перезапуск сервиса.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	s.runLogged(ctx, job)
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runLogged(ctx, job)
+		}
+	}
+}
+
+func (s *Scheduler) runLogged(ctx context.Context, job Job) {
+	if _, err := s.RunOnce(ctx, job); err != nil {
+		logger.JobLogger.Error("job failed", zap.String("job", job.Name), zap.Error(err))
+	}
+}
+
+// RunOnce выполняет задачу, если удалось взять её advisory lock (Local-задачу — всегда). Возвращает false,
+// если задачу в этот момент выполняет другой экземпляр.
+func (s *Scheduler) RunOnce(ctx context.Context, job Job) (bool, error) {
+	if job.Local {
+		return true, job.Run(ctx)
+	}
+
+	// Сессионная блокировка принадлежит соединению, поэтому захват и освобождение
+	// выполняются на одном выделенном соединении из пула.
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var locked bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", job.LockKey).Scan(&locked); err != nil {
+		return false, err
+	}
+	if !locked {
+		// Короткие задачи тикают часто, и на каждом экземпляре без блокировки это обычная ситуация.
+		logger.JobLogger.Debug("job is running on another instance", zap.String("job", job.Name))
+		return false, nil
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", job.LockKey); err != nil {
+			logger.JobLogger.Error("failed to release job lock", zap.String("job", job.Name), zap.Error(err))
+		}
+	}()
+
+	return true, job.Run(ctx)
+}
@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_RunOnce(t *testing.T) {
+	logger.JobLogger = zap.NewNop()
+	lockQuery := `^SELECT pg_try_advisory_lock\(\$1\)$`
+	unlockQuery := `^SELECT pg_advisory_unlock\(\$1\)$`
+
+	tests := []struct {
+		name        string
+		mock        func(sqlmock.Sqlmock)
+		jobErr      error
+		expectedRan bool
+		expectedErr string
+		expectCalls int32
+	}{
+		{
+			name: "Lock Acquired",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockQuery).WithArgs(int64(42)).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+				mock.ExpectExec(unlockQuery).WithArgs(int64(42)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedRan: true,
+			expectCalls: 1,
+		},
+		{
+			name: "Locked By Another Instance",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockQuery).WithArgs(int64(42)).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+			},
+			expectedRan: false,
+			expectCalls: 0,
+		},
+		{
+			name: "Job Error Releases Lock",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockQuery).WithArgs(int64(42)).
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+				mock.ExpectExec(unlockQuery).WithArgs(int64(42)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			jobErr:      errors.New("job failed"),
+			expectedRan: true,
+			expectedErr: "job failed",
+			expectCalls: 1,
+		},
+		{
+			name: "Lock Query Error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lockQuery).WithArgs(int64(42)).
+					WillReturnError(errors.New("db error"))
+			},
+			expectedRan: false,
+			expectedErr: "db error",
+			expectCalls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func() {
+				err := db.Close()
+				if err != nil {
+					return
+				}
+			}()
+			tt.mock(mock)
+
+			var calls int32
+			job := Job{
+				Name:    "test",
+				LockKey: 42,
+				Run: func(ctx context.Context) error {
+					atomic.AddInt32(&calls, 1)
+					return tt.jobErr
+				},
+			}
+
+			ran, err := NewScheduler(db).RunOnce(context.Background(), job)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedRan, ran)
+			assert.Equal(t, tt.expectCalls, atomic.LoadInt32(&calls))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestScheduler_RunOnceLocalJobSkipsLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	calls := 0
+	ran, err := NewScheduler(db).RunOnce(context.Background(), Job{
+		Name:  "local",
+		Local: true,
+		Run: func(ctx context.Context) error {
+			calls++
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScheduler_Start(t *testing.T) {
+	logger.JobLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		err := db.Close()
+		if err != nil {
+			return
+		}
+	}()
+	mock.MatchExpectationsInOrder(false)
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`pg_try_advisory_lock`).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectExec(`pg_advisory_unlock`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{}, 2)
+	s := NewScheduler(db)
+	s.Add(Job{
+		Name:     "tick",
+		Interval: 10 * time.Millisecond,
+		LockKey:  1,
+		Run: func(ctx context.Context) error {
+			done <- struct{}{}
+			return nil
+		},
+	})
+	s.Start(ctx)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("job was not run by scheduler")
+		}
+	}
+	cancel()
+}
+
+func TestScheduler_StartRunsJobImmediately(t *testing.T) {
+	logger.JobLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{}, 1)
+	s := NewScheduler(db)
+	s.Add(Job{
+		Name:     "daily",
+		Interval: 24 * time.Hour,
+		Local:    true,
+		Run: func(ctx context.Context) error {
+			done <- struct{}{}
+			return nil
+		},
+	})
+	s.Start(ctx)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job was not run on start")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,100 @@
+package webhookclient
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DeliveryHeader  = "X-Webhook-Id"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+
+	DefaultTimeout = 10 * time.Second
+
+	// maxResponseBytes — сколько тела ответа вычитываем, чтобы соединение можно было переиспользовать.
+	maxResponseBytes = 64 << 10
+)
+
+// Client отправляет доставки вебхуков. Тело подписывается HMAC-SHA256 от строки "<timestamp>.<body>",
+// поэтому получатель может проверить и подлинность, и свежесть запроса.
+type Client struct {
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: timeout,
+			// Редиректы не выполняем: подпись выдана конкретному адресу подписки.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Send возвращает код ответа получателя (0, если ответа не было) и ошибку для любого ответа кроме 2xx.
+func (c *Client) Send(ctx context.Context, url, secret string, delivery models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(c.now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pvz-webhooks/1.0")
+	req.Header.Set(DeliveryHeader, delivery.Id)
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, delivery.Payload))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign возвращает значение заголовка подписи: "sha256=" и hex от HMAC-SHA256.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись на стороне получателя; tolerance ограничивает возраст запроса.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("webhook timestamp is outside the tolerance")
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package webhookclient
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testDelivery() models.WebhookDelivery {
+	return models.WebhookDelivery{
+		Id:        "delivery1",
+		EventType: models.EVENT_RECEPTION_CLOSED,
+		Payload:   []byte(`{"type":"ReceptionClosed"}`),
+	}
+}
+
+func TestClient_Send(t *testing.T) {
+	now := time.Date(2025, 5, 7, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Signed Delivery", func(t *testing.T) {
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client := NewClient(time.Second)
+		client.now = func() time.Time { return now }
+
+		status, err := client.Send(context.Background(), server.URL, "super-secret-value", testDelivery())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, status)
+
+		require.NotNil(t, received)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, "delivery1", received.Header.Get(DeliveryHeader))
+		assert.Equal(t, models.EVENT_RECEPTION_CLOSED, received.Header.Get(EventHeader))
+		assert.Equal(t, "1746612000", received.Header.Get(TimestampHeader))
+		assert.Equal(t, `{"type":"ReceptionClosed"}`, string(body))
+		assert.NoError(t, Verify("super-secret-value", received.Header.Get(TimestampHeader),
+			received.Header.Get(SignatureHeader), body, 5*time.Minute, now))
+	})
+
+	t.Run("Receiver Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		status, err := NewClient(time.Second).Send(context.Background(), server.URL, "super-secret-value", testDelivery())
+		assert.EqualError(t, err, "receiver responded with status 503")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	})
+
+	t.Run("Redirect Is Not Followed", func(t *testing.T) {
+		followed := false
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			followed = true
+		}))
+		defer target.Close()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+		}))
+		defer server.Close()
+
+		status, err := NewClient(time.Second).Send(context.Background(), server.URL, "super-secret-value", testDelivery())
+		assert.EqualError(t, err, "receiver responded with status 307")
+		assert.Equal(t, http.StatusTemporaryRedirect, status)
+		assert.False(t, followed)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		status, err := NewClient(50*time.Millisecond).Send(context.Background(), server.URL, "super-secret-value", testDelivery())
+		assert.Error(t, err)
+		assert.Equal(t, 0, status)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2025, 5, 7, 10, 0, 0, 0, time.UTC)
+	body := []byte(`{"a":1}`)
+	timestamp := "1746612000"
+	signature := Sign("super-secret-value", timestamp, body)
+
+	assert.NoError(t, Verify("super-secret-value", timestamp, signature, body, time.Minute, now))
+	assert.EqualError(t, Verify("another-secret-value", timestamp, signature, body, time.Minute, now), "invalid webhook signature")
+	assert.EqualError(t, Verify("super-secret-value", timestamp, signature, []byte(`{"a":2}`), time.Minute, now), "invalid webhook signature")
+	assert.EqualError(t, Verify("super-secret-value", timestamp, signature, body, time.Minute, now.Add(time.Hour)),
+		"webhook timestamp is outside the tolerance")
+	assert.EqualError(t, Verify("super-secret-value", "abc", signature, body, time.Minute, now), "invalid webhook timestamp")
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"context"
+)
+
+type StorageCellUsecase interface {
+	SetStorageCells(ctx context.Context, pvzId string, data requests.SetStorageCellsRequest) ([]models.StorageCell, error)
+	GetStorageCells(ctx context.Context, pvzId string) ([]models.StorageCell, error)
+	SuggestCell(ctx context.Context, pvzId, productId string) (models.StorageCell, error)
+	AssignCell(ctx context.Context, pvzId string, data requests.AssignCellRequest) (models.ProductLocation, error)
+	LookupProduct(ctx context.Context, pvzId, productId, pickupCode string) (models.ProductLocation, error)
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type StorageCellHandler struct {
+	usecase StorageCellUsecase
+}
+
+func NewStorageCellHandler(usecase StorageCellUsecase) *StorageCellHandler {
+	return &StorageCellHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *StorageCellHandler) SetStorageCells(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.SetStorageCellsRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	for i := range data.Cells {
+		data.Cells[i].Code = sanitizer.Sanitize(data.Cells[i].Code)
+		data.Cells[i].Size = sanitizer.Sanitize(data.Cells[i].Size)
+	}
+
+	cells, err := h.usecase.SetStorageCells(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toStorageCellsResponse(pvzId, cells), requestID)
+}
+
+func (h *StorageCellHandler) GetStorageCells(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	cells, err := h.usecase.GetStorageCells(ctx, pvzId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toStorageCellsResponse(pvzId, cells), requestID)
+}
+
+func (h *StorageCellHandler) SuggestCell(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+	productId := sanitizer.Sanitize(r.URL.Query().Get("productId"))
+
+	cell, err := h.usecase.SuggestCell(ctx, pvzId, productId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toStorageCellResponse(cell), requestID)
+}
+
+func (h *StorageCellHandler) AssignCell(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+
+	var data requests.AssignCellRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	data = requests.AssignCellRequest{
+		ProductId: sanitizer.Sanitize(data.ProductId),
+		CellCode:  sanitizer.Sanitize(data.CellCode),
+	}
+
+	location, err := h.usecase.AssignCell(ctx, pvzId, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toProductLocationResponse(location), requestID)
+}
+
+func (h *StorageCellHandler) LookupProduct(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	pvzId := sanitizer.Sanitize(mux.Vars(r)["pvzId"])
+	productId := sanitizer.Sanitize(r.URL.Query().Get("productId"))
+	pickupCode := sanitizer.Sanitize(r.URL.Query().Get("pickupCode"))
+
+	location, err := h.usecase.LookupProduct(ctx, pvzId, productId, pickupCode)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, toProductLocationResponse(location), requestID)
+}
+
+func (h *StorageCellHandler) writeJSON(w http.ResponseWriter, status int, response interface{}, requestID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.AccessLogger.Error("Failed to encode response",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+}
+
+func toStorageCellResponse(cell models.StorageCell) responses.StorageCellResponse {
+	return responses.StorageCellResponse{
+		Code:     cell.Code,
+		Size:     cell.Size,
+		Capacity: cell.Capacity,
+		Occupied: cell.Occupied,
+	}
+}
+
+func toStorageCellsResponse(pvzId string, cells []models.StorageCell) responses.GetStorageCellsResponse {
+	response := responses.GetStorageCellsResponse{
+		PvzId: pvzId,
+		Cells: make([]responses.StorageCellResponse, 0, len(cells)),
+	}
+	for _, cell := range cells {
+		response.Cells = append(response.Cells, toStorageCellResponse(cell))
+	}
+	return response
+}
+
+func toProductLocationResponse(location models.ProductLocation) responses.ProductLocationResponse {
+	response := responses.ProductLocationResponse{
+		ProductId:    location.ProductId,
+		Type:         location.Type,
+		Status:       location.Status,
+		PvzId:        location.PvzId,
+		SizeCategory: location.SizeCategory,
+	}
+	if location.Cell != nil {
+		cell := toStorageCellResponse(*location.Cell)
+		response.Cell = &cell
+	}
+	return response
+}
+
+func (h *StorageCellHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "too many cells", "invalid cell code", "duplicate cell code", "invalid cell size",
+		"invalid cell capacity", "product id is required", "product id or pickup code is required":
+		w.WriteHeader(http.StatusBadRequest)
+	case "pvz not found", "product not found", "cell not found", "pickup code not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "cell is occupied", "cell is too small", "cell is full", "no free cell", "product is not at this pvz":
+		w.WriteHeader(http.StatusConflict)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStorageCellHandler(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	capacity := 2
+	vars := map[string]string{"pvzId": "pvz1"}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		call           func(h *StorageCellHandler) http.HandlerFunc
+		mockBehavior   func(usecase *usecaseMocks.StorageCellUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "set layout",
+			method: http.MethodPut,
+			path:   "/api/pvz/pvz1/cells",
+			body:   `{"cells":[{"code":"A-1","size":"m","capacity":2}]}`,
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.SetStorageCells },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("SetStorageCells", mock.Anything, "pvz1", requests.SetStorageCellsRequest{
+					Cells: []requests.StorageCellRequest{{Code: "A-1", Size: "m", Capacity: &capacity}},
+				}).Return([]models.StorageCell{{Id: "cell1", Code: "A-1", Size: "m", Capacity: 2}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"pvzId":"pvz1","cells":[{"code":"A-1","size":"m","capacity":2,"occupied":0}]}`,
+		},
+		{
+			name:   "set layout removes occupied cell",
+			method: http.MethodPut,
+			path:   "/api/pvz/pvz1/cells",
+			body:   `{"cells":[]}`,
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.SetStorageCells },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("SetStorageCells", mock.Anything, "pvz1", mock.Anything).
+					Return(nil, errors.New("cell is occupied"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"cell is occupied"}`,
+		},
+		{
+			name:   "list cells",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz1/cells",
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.GetStorageCells },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("GetStorageCells", mock.Anything, "pvz1").
+					Return([]models.StorageCell{{Code: "A-1", Size: "s", Capacity: 1, Occupied: 1}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"pvzId":"pvz1","cells":[{"code":"A-1","size":"s","capacity":1,"occupied":1}]}`,
+		},
+		{
+			name:   "suggestion",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz1/cells/suggestion?productId=prod1",
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.SuggestCell },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("SuggestCell", mock.Anything, "pvz1", "prod1").
+					Return(models.StorageCell{Code: "B-2", Size: "l", Capacity: 3, Occupied: 1}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"code":"B-2","size":"l","capacity":3,"occupied":1}`,
+		},
+		{
+			name:   "suggestion without free cells",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz1/cells/suggestion?productId=prod1",
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.SuggestCell },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("SuggestCell", mock.Anything, "pvz1", "prod1").
+					Return(models.StorageCell{}, errors.New("no free cell"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"no free cell"}`,
+		},
+		{
+			name:   "assign",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz1/cells/assign",
+			body:   `{"productId":"prod1","cellCode":"A-1"}`,
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.AssignCell },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("AssignCell", mock.Anything, "pvz1", requests.AssignCellRequest{ProductId: "prod1", CellCode: "A-1"}).
+					Return(models.ProductLocation{
+						ProductId:    "prod1",
+						Type:         "обувь",
+						Status:       models.PRODUCT_STATUS_RECEIVED,
+						PvzId:        "pvz1",
+						SizeCategory: "m",
+						Cell:         &models.StorageCell{Code: "A-1", Size: "m", Capacity: 2, Occupied: 1},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"productId":"prod1","type":"обувь","status":"received","pvzId":"pvz1","sizeCategory":"m",
+				"cell":{"code":"A-1","size":"m","capacity":2,"occupied":1}}`,
+		},
+		{
+			name:   "assign to unknown cell",
+			method: http.MethodPost,
+			path:   "/api/pvz/pvz1/cells/assign",
+			body:   `{"productId":"prod1","cellCode":"Z-9"}`,
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.AssignCell },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("AssignCell", mock.Anything, "pvz1", mock.Anything).
+					Return(models.ProductLocation{}, errors.New("cell not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"cell not found"}`,
+		},
+		{
+			name:   "lookup by pickup code without cell",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz1/cells/lookup?pickupCode=123456",
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.LookupProduct },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("LookupProduct", mock.Anything, "pvz1", "", "123456").
+					Return(models.ProductLocation{
+						ProductId:    "prod1",
+						Type:         "обувь",
+						Status:       models.PRODUCT_STATUS_RECEIVED,
+						PvzId:        "pvz1",
+						SizeCategory: "s",
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"productId":"prod1","type":"обувь","status":"received","pvzId":"pvz1","sizeCategory":"s"}`,
+		},
+		{
+			name:   "lookup forbidden",
+			method: http.MethodGet,
+			path:   "/api/pvz/pvz1/cells/lookup?productId=prod1",
+			call:   func(h *StorageCellHandler) http.HandlerFunc { return h.LookupProduct },
+			mockBehavior: func(usecase *usecaseMocks.StorageCellUsecaseMock) {
+				usecase.On("LookupProduct", mock.Anything, "pvz1", "prod1", "").
+					Return(models.ProductLocation{}, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"errors":"this role is not allowed"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.StorageCellUsecaseMock)
+			handler := NewStorageCellHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, vars)
+			w := httptest.NewRecorder()
+			tt.call(handler)(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
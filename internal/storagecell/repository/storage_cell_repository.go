@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/dbtx"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	foreignKeyViolationCode = "23503"
+
+	productCellFk    = "products_cell_id_fkey"
+	storageCellPvzFk = "storage_cells_pvz_id_fkey"
+)
+
+type StorageCellRepository struct {
+	db *sql.DB
+}
+
+func NewStorageCellRepository(db *sql.DB) StorageCellRepository {
+	return StorageCellRepository{
+		db: db,
+	}
+}
+
+// WithinTransaction выполняет fn в одной транзакции; транзакция, уже открытая выше по стеку
+// любым репозиторием, переиспользуется.
+func (r StorageCellRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return dbtx.WithinTransaction(ctx, r.db, fn)
+}
+
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolationCode && pqErr.Constraint == constraint
+}
+
+// ReplaceStorageCells приводит раскладку ячеек ПВЗ к переданной: новые ячейки создаются,
+// существующие с тем же кодом обновляются, остальные удаляются. Ячейку с товарами удалить нельзя.
+func (r StorageCellRepository) ReplaceStorageCells(ctx context.Context, pvzId string, cells []models.StorageCell) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("ReplaceStorageCells called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+		zap.Int("cells", len(cells)),
+	)
+
+	return r.WithinTransaction(ctx, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+
+		codes := make([]string, 0, len(cells))
+		for _, cell := range cells {
+			codes = append(codes, cell.Code)
+		}
+
+		query, args, err := sq.Delete("storage_cells").
+			Where(sq.Eq{"pvz_id": pvzId}).
+			Where(sq.NotEq{"code": codes}).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			if isForeignKeyViolation(err, productCellFk) {
+				return errors.New("cell is occupied")
+			}
+			logger.DBLogger.Error("failed to delete storage cells", zap.Error(err))
+			return err
+		}
+
+		if len(cells) == 0 {
+			return nil
+		}
+
+		insertBuilder := sq.Insert("storage_cells").
+			Columns("id", "pvz_id", "code", "size", "capacity").
+			Suffix("ON CONFLICT ON CONSTRAINT storage_cells_pvz_code DO UPDATE SET size = EXCLUDED.size, capacity = EXCLUDED.capacity").
+			PlaceholderFormat(sq.Dollar)
+		for _, cell := range cells {
+			insertBuilder = insertBuilder.Values(cell.Id, pvzId, cell.Code, cell.Size, cell.Capacity)
+		}
+		query, args, err = insertBuilder.ToSql()
+		if err != nil {
+			logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			if isForeignKeyViolation(err, storageCellPvzFk) {
+				return errors.New("pvz not found")
+			}
+			logger.DBLogger.Error("failed to save storage cells", zap.Error(err))
+			return err
+		}
+
+		logger.DBLogger.Info("Storage cells successfully replaced",
+			zap.String("request_id", requestID),
+			zap.String("pvz_id", pvzId),
+		)
+		return nil
+	})
+}
+
+// cellWithOccupancy выбирает ячейки вместе с числом лежащих в них товаров.
+func cellWithOccupancy() sq.SelectBuilder {
+	return sq.Select("c.id", "c.pvz_id", "c.code", "c.size", "c.capacity", "COUNT(p.id)").
+		From("storage_cells c").
+		LeftJoin("products p ON p.cell_id = c.id").
+		GroupBy("c.id")
+}
+
+func scanStorageCell(row rowScanner) (models.StorageCell, error) {
+	var cell models.StorageCell
+	err := row.Scan(&cell.Id, &cell.PvzId, &cell.Code, &cell.Size, &cell.Capacity, &cell.Occupied)
+	return cell, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r StorageCellRepository) GetStorageCells(ctx context.Context, pvzId string) ([]models.StorageCell, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetStorageCells called", zap.String("request_id", requestID), zap.String("pvz_id", pvzId))
+
+	query, args, err := cellWithOccupancy().
+		Where(sq.Eq{"c.pvz_id": pvzId}).
+		OrderBy("c.code").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query storage cells", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	cells := []models.StorageCell{}
+	for rows.Next() {
+		cell, err := scanStorageCell(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan storage cell", zap.Error(err))
+			return nil, err
+		}
+		cells = append(cells, cell)
+	}
+	if err := rows.Err(); err != nil {
+		logger.DBLogger.Error("rows iteration error", zap.Error(err))
+		return nil, err
+	}
+
+	return cells, nil
+}
+
+// FindFreeCell подбирает самую маленькую незаполненную ячейку, в которую помещается товар размера size.
+func (r StorageCellRepository) FindFreeCell(ctx context.Context, pvzId, size string) (*models.StorageCell, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("FindFreeCell called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+		zap.String("size", size),
+	)
+
+	query, args, err := cellWithOccupancy().
+		Where(sq.Eq{"c.pvz_id": pvzId}).
+		// Размеры сравниваются по порядку s < m < l
+		Where(sq.Expr("array_position(ARRAY['s','m','l'], c.size) >= array_position(ARRAY['s','m','l'], ?)", size)).
+		Having("COUNT(p.id) < c.capacity").
+		OrderBy("array_position(ARRAY['s','m','l'], c.size)", "c.code").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	cell, err := scanStorageCell(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("no free cell")
+		}
+		logger.DBLogger.Error("failed to find free cell", zap.Error(err))
+		return nil, err
+	}
+	return &cell, nil
+}
+
+// LockStorageCell блокирует ячейку ПВЗ по коду до конца транзакции и считает, сколько товаров в ней лежит.
+// Занятость читается отдельным запросом уже после блокировки, чтобы видеть товары, положенные параллельно.
+func (r StorageCellRepository) LockStorageCell(ctx context.Context, pvzId, code string) (*models.StorageCell, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockStorageCell called",
+		zap.String("request_id", requestID),
+		zap.String("pvz_id", pvzId),
+		zap.String("code", code),
+	)
+
+	query, args, err := sq.Select("id", "pvz_id", "code", "size", "capacity").
+		From("storage_cells").
+		Where(sq.Eq{"pvz_id": pvzId, "code": code}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var cell models.StorageCell
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&cell.Id, &cell.PvzId, &cell.Code, &cell.Size, &cell.Capacity)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("cell not found")
+		}
+		logger.DBLogger.Error("failed to lock storage cell", zap.Error(err))
+		return nil, err
+	}
+
+	query, args, err = sq.Select("COUNT(*)").
+		From("products").
+		Where(sq.Eq{"cell_id": cell.Id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	if err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&cell.Occupied); err != nil {
+		logger.DBLogger.Error("failed to count products in cell", zap.Error(err))
+		return nil, err
+	}
+
+	return &cell, nil
+}
+
+func productLocationQuery() sq.SelectBuilder {
+	return sq.Select("p.id", "p.type", "p.status", "r.pvz_id", "pt.size_category",
+		"c.id", "c.pvz_id", "c.code", "c.size", "c.capacity").
+		From("products p").
+		Join("receptions r ON r.id = p.reception_id").
+		Join("product_types pt ON pt.code = p.type").
+		LeftJoin("storage_cells c ON c.id = p.cell_id")
+}
+
+// GetProductLocation возвращает ПВЗ и ячейку товара.
+func (r StorageCellRepository) GetProductLocation(ctx context.Context, productId string) (*models.ProductLocation, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductLocation called", zap.String("request_id", requestID), zap.String("product_id", productId))
+
+	return r.getProductLocation(ctx, productLocationById(productId))
+}
+
+// LockProductLocation то же, что GetProductLocation, но блокирует строку товара до конца транзакции.
+func (r StorageCellRepository) LockProductLocation(ctx context.Context, productId string) (*models.ProductLocation, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("LockProductLocation called", zap.String("request_id", requestID), zap.String("product_id", productId))
+
+	return r.getProductLocation(ctx, productLocationById(productId).Suffix("FOR UPDATE OF p"))
+}
+
+func productLocationById(productId string) sq.SelectBuilder {
+	return productLocationQuery().Where(sq.Eq{"p.id": productId})
+}
+
+func (r StorageCellRepository) getProductLocation(ctx context.Context, builder sq.SelectBuilder) (*models.ProductLocation, error) {
+	query, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	var location models.ProductLocation
+	var cellId, cellPvzId, cellCode, cellSize sql.NullString
+	var cellCapacity sql.NullInt64
+	err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(
+		&location.ProductId, &location.Type, &location.Status, &location.PvzId, &location.SizeCategory,
+		&cellId, &cellPvzId, &cellCode, &cellSize, &cellCapacity,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		logger.DBLogger.Error("failed to scan product location", zap.Error(err))
+		return nil, err
+	}
+	if cellId.Valid {
+		location.Cell = &models.StorageCell{
+			Id:       cellId.String,
+			PvzId:    cellPvzId.String,
+			Code:     cellCode.String,
+			Size:     cellSize.String,
+			Capacity: int(cellCapacity.Int64),
+		}
+	}
+	return &location, nil
+}
+
+// GetProductIdByPickupCode находит товар по ещё не использованному коду выдачи.
+func (r StorageCellRepository) GetProductIdByPickupCode(ctx context.Context, code string) (string, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetProductIdByPickupCode called", zap.String("request_id", requestID))
+
+	query, args, err := sq.Select("product_id").
+		From("pickup_codes").
+		Where(sq.Eq{"code": code, "used_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return "", err
+	}
+
+	var productId string
+	if err = dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&productId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errors.New("pickup code not found")
+		}
+		logger.DBLogger.Error("failed to find pickup code", zap.Error(err))
+		return "", err
+	}
+	return productId, nil
+}
+
+func (r StorageCellRepository) SetProductCell(ctx context.Context, productId, cellId string) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("SetProductCell called",
+		zap.String("request_id", requestID),
+		zap.String("product_id", productId),
+		zap.String("cell_id", cellId),
+	)
+
+	query, args, err := sq.Update("products").
+		Set("cell_id", cellId).
+		Where(sq.Eq{"id": productId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to set product cell", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("product not found")
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func newMockRepository(t *testing.T) (StorageCellRepository, sqlmock.Sqlmock) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return NewStorageCellRepository(db), mock
+}
+
+var cellColumns = []string{"id", "pvz_id", "code", "size", "capacity", "count"}
+
+func TestStorageCellRepository_ReplaceStorageCells(t *testing.T) {
+	cells := []models.StorageCell{
+		{Id: "cell1", Code: "A-1", Size: models.CELL_SIZE_S, Capacity: 1},
+		{Id: "cell2", Code: "B-1", Size: models.CELL_SIZE_L, Capacity: 3},
+	}
+	deleteQuery := `^DELETE FROM storage_cells WHERE pvz_id = \$1 AND code NOT IN \(\$2,\$3\)$`
+	insertQuery := `^INSERT INTO storage_cells \(id,pvz_id,code,size,capacity\) VALUES \(\$1,\$2,\$3,\$4,\$5\),\(\$6,\$7,\$8,\$9,\$10\) ` +
+		`ON CONFLICT ON CONSTRAINT storage_cells_pvz_code DO UPDATE SET size = EXCLUDED.size, capacity = EXCLUDED.capacity$`
+
+	tests := []struct {
+		name   string
+		cells  []models.StorageCell
+		mock   func(sqlmock.Sqlmock)
+		errMsg string
+	}{
+		{
+			name:  "Success",
+			cells: cells,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(deleteQuery).
+					WithArgs("pvz1", "A-1", "B-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(insertQuery).
+					WithArgs("cell1", "pvz1", "A-1", models.CELL_SIZE_S, 1, "cell2", "pvz1", "B-1", models.CELL_SIZE_L, 3).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:  "Clear Layout",
+			cells: nil,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`^DELETE FROM storage_cells WHERE pvz_id = \$1 AND \(1=1\)$`).
+					WithArgs("pvz1").
+					WillReturnResult(sqlmock.NewResult(0, 4))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:  "Removed Cell Is Occupied",
+			cells: cells,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(deleteQuery).
+					WillReturnError(&pq.Error{Code: foreignKeyViolationCode, Constraint: productCellFk})
+				mock.ExpectRollback()
+			},
+			errMsg: "cell is occupied",
+		},
+		{
+			name:  "Pvz Not Found",
+			cells: cells,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(deleteQuery).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insertQuery).
+					WillReturnError(&pq.Error{Code: foreignKeyViolationCode, Constraint: storageCellPvzFk})
+				mock.ExpectRollback()
+			},
+			errMsg: "pvz not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newMockRepository(t)
+			tt.mock(mock)
+
+			err := repo.ReplaceStorageCells(context.Background(), "pvz1", tt.cells)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStorageCellRepository_GetStorageCells(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`^SELECT c.id, c.pvz_id, c.code, c.size, c.capacity, COUNT\(p.id\) FROM storage_cells c ` +
+		`LEFT JOIN products p ON p.cell_id = c.id WHERE c.pvz_id = \$1 GROUP BY c.id ORDER BY c.code$`).
+		WithArgs("pvz1").
+		WillReturnRows(sqlmock.NewRows(cellColumns).
+			AddRow("cell1", "pvz1", "A-1", "s", 1, 1).
+			AddRow("cell2", "pvz1", "B-1", "l", 3, 0))
+
+	cells, err := repo.GetStorageCells(context.Background(), "pvz1")
+	require.NoError(t, err)
+	assert.Equal(t, []models.StorageCell{
+		{Id: "cell1", PvzId: "pvz1", Code: "A-1", Size: "s", Capacity: 1, Occupied: 1},
+		{Id: "cell2", PvzId: "pvz1", Code: "B-1", Size: "l", Capacity: 3, Occupied: 0},
+	}, cells)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageCellRepository_FindFreeCell(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT c.id, c.pvz_id, c.code, c.size, c.capacity, COUNT\(p.id\) FROM storage_cells c ` +
+		`LEFT JOIN products p ON p.cell_id = c.id WHERE c.pvz_id = \$1 ` +
+		`AND array_position\(ARRAY\['s','m','l'\], c.size\) >= array_position\(ARRAY\['s','m','l'\], \$2\) ` +
+		`GROUP BY c.id HAVING COUNT\(p.id\) < c.capacity ` +
+		`ORDER BY array_position\(ARRAY\['s','m','l'\], c.size\), c.code LIMIT 1$`
+
+	mock.ExpectQuery(query).
+		WithArgs("pvz1", "m").
+		WillReturnRows(sqlmock.NewRows(cellColumns).AddRow("cell3", "pvz1", "C-2", "m", 2, 1))
+	cell, err := repo.FindFreeCell(context.Background(), "pvz1", "m")
+	require.NoError(t, err)
+	assert.Equal(t, &models.StorageCell{Id: "cell3", PvzId: "pvz1", Code: "C-2", Size: "m", Capacity: 2, Occupied: 1}, cell)
+
+	mock.ExpectQuery(query).
+		WithArgs("pvz1", "l").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.FindFreeCell(context.Background(), "pvz1", "l")
+	assert.EqualError(t, err, "no free cell")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageCellRepository_LockStorageCell(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(`^SELECT id, pvz_id, code, size, capacity FROM storage_cells WHERE code = \$1 AND pvz_id = \$2 FOR UPDATE$`).
+		WithArgs("A-1", "pvz1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "pvz_id", "code", "size", "capacity"}).
+			AddRow("cell1", "pvz1", "A-1", "m", 2))
+	mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM products WHERE cell_id = \$1$`).
+		WithArgs("cell1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	cell, err := repo.LockStorageCell(context.Background(), "pvz1", "A-1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.StorageCell{Id: "cell1", PvzId: "pvz1", Code: "A-1", Size: "m", Capacity: 2, Occupied: 2}, cell)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageCellRepository_GetProductLocation(t *testing.T) {
+	query := `^SELECT p.id, p.type, p.status, r.pvz_id, pt.size_category, c.id, c.pvz_id, c.code, c.size, c.capacity ` +
+		`FROM products p JOIN receptions r ON r.id = p.reception_id JOIN product_types pt ON pt.code = p.type ` +
+		`LEFT JOIN storage_cells c ON c.id = p.cell_id WHERE p.id = \$1`
+	columns := []string{"id", "type", "status", "pvz_id", "size_category", "cell_id", "cell_pvz_id", "code", "size", "capacity"}
+
+	t.Run("With Cell", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query + `$`).
+			WithArgs("prod1").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("prod1", "обувь", models.PRODUCT_STATUS_RECEIVED, "pvz1", "m", "cell1", "pvz1", "A-1", "l", 2))
+
+		location, err := repo.GetProductLocation(context.Background(), "prod1")
+		require.NoError(t, err)
+		assert.Equal(t, &models.ProductLocation{
+			ProductId:    "prod1",
+			Type:         "обувь",
+			Status:       models.PRODUCT_STATUS_RECEIVED,
+			PvzId:        "pvz1",
+			SizeCategory: "m",
+			Cell:         &models.StorageCell{Id: "cell1", PvzId: "pvz1", Code: "A-1", Size: "l", Capacity: 2},
+		}, location)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Locked Without Cell", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query + ` FOR UPDATE OF p$`).
+			WithArgs("prod1").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("prod1", "обувь", models.PRODUCT_STATUS_RECEIVED, "pvz1", "s", nil, nil, nil, nil, nil))
+
+		location, err := repo.LockProductLocation(context.Background(), "prod1")
+		require.NoError(t, err)
+		assert.Nil(t, location.Cell)
+		assert.Equal(t, "s", location.SizeCategory)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetProductLocation(context.Background(), "prod1")
+		assert.EqualError(t, err, "product not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStorageCellRepository_GetProductIdByPickupCode(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^SELECT product_id FROM pickup_codes WHERE code = \$1 AND used_at IS NULL$`
+
+	mock.ExpectQuery(query).
+		WithArgs("123456").
+		WillReturnRows(sqlmock.NewRows([]string{"product_id"}).AddRow("prod1"))
+	productId, err := repo.GetProductIdByPickupCode(context.Background(), "123456")
+	require.NoError(t, err)
+	assert.Equal(t, "prod1", productId)
+
+	mock.ExpectQuery(query).
+		WithArgs("000000").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetProductIdByPickupCode(context.Background(), "000000")
+	assert.EqualError(t, err, "pickup code not found")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageCellRepository_SetProductCell(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	query := `^UPDATE products SET cell_id = \$1 WHERE id = \$2$`
+
+	mock.ExpectExec(query).
+		WithArgs("cell1", "prod1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.SetProductCell(context.Background(), "prod1", "cell1"))
+
+	mock.ExpectExec(query).
+		WithArgs("cell1", "prod2").
+		WillReturnError(errors.New("db error"))
+	assert.EqualError(t, repo.SetProductCell(context.Background(), "prod2", "cell1"), "db error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,18 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+)
+
+type StorageCellRepository interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	ReplaceStorageCells(ctx context.Context, pvzId string, cells []models.StorageCell) error
+	GetStorageCells(ctx context.Context, pvzId string) ([]models.StorageCell, error)
+	FindFreeCell(ctx context.Context, pvzId, size string) (*models.StorageCell, error)
+	LockStorageCell(ctx context.Context, pvzId, code string) (*models.StorageCell, error)
+	GetProductLocation(ctx context.Context, productId string) (*models.ProductLocation, error)
+	LockProductLocation(ctx context.Context, productId string) (*models.ProductLocation, error)
+	GetProductIdByPickupCode(ctx context.Context, code string) (string, error)
+	SetProductCell(ctx context.Context, productId, cellId string) error
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+)
+
+type StorageCellUsecase struct {
+	storageCellRepository StorageCellRepository
+}
+
+func NewStorageCellUsecase(storageCellRepository StorageCellRepository) StorageCellUsecase {
+	return StorageCellUsecase{
+		storageCellRepository: storageCellRepository,
+	}
+}
+
+// SetStorageCells заменяет раскладку ячеек ПВЗ целиком. Ячейки сопоставляются по коду,
+// поэтому товары в сохранившихся ячейках остаются на месте.
+func (su StorageCellUsecase) SetStorageCells(ctx context.Context, pvzId string, data requests.SetStorageCellsRequest) ([]models.StorageCell, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return nil, errors.New("this role is not allowed")
+	}
+	if len(data.Cells) > models.MAX_STORAGE_CELLS {
+		return nil, errors.New("too many cells")
+	}
+
+	seen := make(map[string]bool, len(data.Cells))
+	cells := make([]models.StorageCell, 0, len(data.Cells))
+	for _, item := range data.Cells {
+		code := strings.TrimSpace(item.Code)
+		if code == "" || len([]rune(code)) > models.MAX_CELL_CODE_LENGTH {
+			return nil, errors.New("invalid cell code")
+		}
+		if seen[code] {
+			return nil, errors.New("duplicate cell code")
+		}
+		seen[code] = true
+		if !models.IsValidCellSize(item.Size) {
+			return nil, errors.New("invalid cell size")
+		}
+		capacity := 1
+		if item.Capacity != nil {
+			capacity = *item.Capacity
+		}
+		if capacity < 1 {
+			return nil, errors.New("invalid cell capacity")
+		}
+		cells = append(cells, models.StorageCell{
+			Id:       uuid.New().String(),
+			PvzId:    pvzId,
+			Code:     code,
+			Size:     item.Size,
+			Capacity: capacity,
+		})
+	}
+
+	if err := su.storageCellRepository.ReplaceStorageCells(ctx, pvzId, cells); err != nil {
+		return nil, err
+	}
+	return su.storageCellRepository.GetStorageCells(ctx, pvzId)
+}
+
+func (su StorageCellUsecase) GetStorageCells(ctx context.Context, pvzId string) ([]models.StorageCell, error) {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return nil, errors.New("this role is not allowed")
+	}
+	return su.storageCellRepository.GetStorageCells(ctx, pvzId)
+}
+
+// SuggestCell подбирает свободную ячейку под размер товара, ничего не резервируя.
+func (su StorageCellUsecase) SuggestCell(ctx context.Context, pvzId, productId string) (models.StorageCell, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.StorageCell{}, errors.New("this role is not allowed")
+	}
+	if productId == "" {
+		return models.StorageCell{}, errors.New("product id is required")
+	}
+
+	location, err := su.storageCellRepository.GetProductLocation(ctx, productId)
+	if err != nil {
+		return models.StorageCell{}, err
+	}
+	if err := checkProductAtPvz(location, pvzId); err != nil {
+		return models.StorageCell{}, err
+	}
+
+	cell, err := su.storageCellRepository.FindFreeCell(ctx, pvzId, location.SizeCategory)
+	if err != nil {
+		return models.StorageCell{}, err
+	}
+	return *cell, nil
+}
+
+// AssignCell кладёт уже принятый товар в ячейку. Без кода ячейки товар кладётся в подобранную свободную.
+func (su StorageCellUsecase) AssignCell(ctx context.Context, pvzId string, data requests.AssignCellRequest) (models.ProductLocation, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "employee" {
+		return models.ProductLocation{}, errors.New("this role is not allowed")
+	}
+	if data.ProductId == "" {
+		return models.ProductLocation{}, errors.New("product id is required")
+	}
+	cellCode := strings.TrimSpace(data.CellCode)
+
+	var location *models.ProductLocation
+	err := su.storageCellRepository.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		location, err = su.storageCellRepository.LockProductLocation(ctx, data.ProductId)
+		if err != nil {
+			return err
+		}
+		if err := checkProductAtPvz(location, pvzId); err != nil {
+			return err
+		}
+
+		if cellCode == "" {
+			free, err := su.storageCellRepository.FindFreeCell(ctx, pvzId, location.SizeCategory)
+			if err != nil {
+				return err
+			}
+			cellCode = free.Code
+		}
+		if location.Cell != nil && location.Cell.Code == cellCode {
+			return nil
+		}
+
+		cell, err := su.storageCellRepository.LockStorageCell(ctx, pvzId, cellCode)
+		if err != nil {
+			return err
+		}
+		if err := cell.CanHold(location.SizeCategory); err != nil {
+			return err
+		}
+		if err := su.storageCellRepository.SetProductCell(ctx, location.ProductId, cell.Id); err != nil {
+			return err
+		}
+		cell.Occupied++
+		location.Cell = cell
+		return nil
+	})
+	if err != nil {
+		return models.ProductLocation{}, err
+	}
+
+	return *location, nil
+}
+
+// LookupProduct показывает, в какой ячейке лежит товар. Товар ищется по id или по коду выдачи клиента.
+func (su StorageCellUsecase) LookupProduct(ctx context.Context, pvzId, productId, pickupCode string) (models.ProductLocation, error) {
+	role := ctx.Value(middleware.ContextKeyRole).(string)
+	if role != "employee" && role != "moderator" {
+		return models.ProductLocation{}, errors.New("this role is not allowed")
+	}
+
+	pickupCode = strings.TrimSpace(pickupCode)
+	if (productId == "") == (pickupCode == "") {
+		return models.ProductLocation{}, errors.New("product id or pickup code is required")
+	}
+	if pickupCode != "" {
+		var err error
+		productId, err = su.storageCellRepository.GetProductIdByPickupCode(ctx, pickupCode)
+		if err != nil {
+			return models.ProductLocation{}, err
+		}
+	}
+
+	location, err := su.storageCellRepository.GetProductLocation(ctx, productId)
+	if err != nil {
+		return models.ProductLocation{}, err
+	}
+	if err := checkProductAtPvz(location, pvzId); err != nil {
+		return models.ProductLocation{}, err
+	}
+	return *location, nil
+}
+
+// checkProductAtPvz пропускает только товары, которые физически лежат на этом ПВЗ.
+func checkProductAtPvz(location *models.ProductLocation, pvzId string) error {
+	if location.PvzId != pvzId || !models.IsProductAtPvz(location.Status) {
+		return errors.New("product is not at this pvz")
+	}
+	return nil
+}
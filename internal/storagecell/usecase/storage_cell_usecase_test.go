@@ -0,0 +1,330 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	repositoryMocks "avito_spring_staj_2025/internal/tests/mocks/repository_mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func roleCtx(role string) func() context.Context {
+	return func() context.Context {
+		return context.WithValue(context.Background(), middleware.ContextKeyRole, role)
+	}
+}
+
+func TestStorageCellUsecase_SetStorageCells(t *testing.T) {
+	capacity := 3
+	zero := 0
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		data        requests.SetStorageCellsRequest
+		mockSetup   func(*repositoryMocks.MockStorageCellRepository)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  roleCtx("moderator"),
+			data: requests.SetStorageCellsRequest{Cells: []requests.StorageCellRequest{
+				{Code: " A-1 ", Size: models.CELL_SIZE_S},
+				{Code: "B-1", Size: models.CELL_SIZE_L, Capacity: &capacity},
+			}},
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("ReplaceStorageCells", mock.Anything, "pvz1", mock.MatchedBy(func(cells []models.StorageCell) bool {
+					return len(cells) == 2 &&
+						cells[0].Code == "A-1" && cells[0].Capacity == 1 && cells[0].Id != "" &&
+						cells[1].Code == "B-1" && cells[1].Capacity == 3
+				})).Return(nil)
+				m.On("GetStorageCells", mock.Anything, "pvz1").Return([]models.StorageCell{}, nil)
+			},
+		},
+		{
+			name: "duplicate code",
+			ctx:  roleCtx("moderator"),
+			data: requests.SetStorageCellsRequest{Cells: []requests.StorageCellRequest{
+				{Code: "A-1", Size: models.CELL_SIZE_S},
+				{Code: "A-1 ", Size: models.CELL_SIZE_M},
+			}},
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("duplicate cell code"),
+		},
+		{
+			name:        "empty code",
+			ctx:         roleCtx("moderator"),
+			data:        requests.SetStorageCellsRequest{Cells: []requests.StorageCellRequest{{Code: " ", Size: models.CELL_SIZE_S}}},
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("invalid cell code"),
+		},
+		{
+			name:        "invalid size",
+			ctx:         roleCtx("moderator"),
+			data:        requests.SetStorageCellsRequest{Cells: []requests.StorageCellRequest{{Code: "A-1", Size: "xl"}}},
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("invalid cell size"),
+		},
+		{
+			name:        "invalid capacity",
+			ctx:         roleCtx("moderator"),
+			data:        requests.SetStorageCellsRequest{Cells: []requests.StorageCellRequest{{Code: "A-1", Size: "s", Capacity: &zero}}},
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("invalid cell capacity"),
+		},
+		{
+			name: "occupied cell removed",
+			ctx:  roleCtx("moderator"),
+			data: requests.SetStorageCellsRequest{},
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("ReplaceStorageCells", mock.Anything, "pvz1", []models.StorageCell{}).
+					Return(errors.New("cell is occupied"))
+			},
+			expectedErr: errors.New("cell is occupied"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         roleCtx("employee"),
+			data:        requests.SetStorageCellsRequest{},
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockStorageCellRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewStorageCellUsecase(mockRepo)
+
+			_, err := uc.SetStorageCells(tt.ctx(), "pvz1", tt.data)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStorageCellUsecase_SuggestCell(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		mockSetup   func(*repositoryMocks.MockStorageCellRepository)
+		expectedRes models.StorageCell
+		expectedErr error
+	}{
+		{
+			name: "success",
+			ctx:  roleCtx("employee"),
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("GetProductLocation", mock.Anything, "prod1").Return(&models.ProductLocation{
+					ProductId: "prod1", PvzId: "pvz1", Status: models.PRODUCT_STATUS_RECEIVED, SizeCategory: models.CELL_SIZE_M,
+				}, nil)
+				m.On("FindFreeCell", mock.Anything, "pvz1", models.CELL_SIZE_M).
+					Return(&models.StorageCell{Code: "B-2", Size: models.CELL_SIZE_M, Capacity: 2}, nil)
+			},
+			expectedRes: models.StorageCell{Code: "B-2", Size: models.CELL_SIZE_M, Capacity: 2},
+		},
+		{
+			name: "product at another pvz",
+			ctx:  roleCtx("employee"),
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("GetProductLocation", mock.Anything, "prod1").Return(&models.ProductLocation{
+					ProductId: "prod1", PvzId: "pvz2", Status: models.PRODUCT_STATUS_RECEIVED,
+				}, nil)
+			},
+			expectedErr: errors.New("product is not at this pvz"),
+		},
+		{
+			name: "product already issued",
+			ctx:  roleCtx("employee"),
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("GetProductLocation", mock.Anything, "prod1").Return(&models.ProductLocation{
+					ProductId: "prod1", PvzId: "pvz1", Status: models.PRODUCT_STATUS_ISSUED,
+				}, nil)
+			},
+			expectedErr: errors.New("product is not at this pvz"),
+		},
+		{
+			name:        "invalid role",
+			ctx:         roleCtx("moderator"),
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("this role is not allowed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockStorageCellRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewStorageCellUsecase(mockRepo)
+
+			res, err := uc.SuggestCell(tt.ctx(), "pvz1", "prod1")
+			assert.Equal(t, tt.expectedRes, res)
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStorageCellUsecase_AssignCell(t *testing.T) {
+	received := func() *models.ProductLocation {
+		return &models.ProductLocation{
+			ProductId: "prod1", PvzId: "pvz1", Status: models.PRODUCT_STATUS_RECEIVED, SizeCategory: models.CELL_SIZE_M,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		data         requests.AssignCellRequest
+		mockSetup    func(*repositoryMocks.MockStorageCellRepository)
+		expectedCell *models.StorageCell
+		expectedErr  error
+	}{
+		{
+			name: "explicit cell",
+			data: requests.AssignCellRequest{ProductId: "prod1", CellCode: " B-1 "},
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("LockProductLocation", mock.Anything, "prod1").Return(received(), nil)
+				m.On("LockStorageCell", mock.Anything, "pvz1", "B-1").
+					Return(&models.StorageCell{Id: "cell1", Code: "B-1", Size: models.CELL_SIZE_L, Capacity: 2, Occupied: 1}, nil)
+				m.On("SetProductCell", mock.Anything, "prod1", "cell1").Return(nil)
+			},
+			expectedCell: &models.StorageCell{Id: "cell1", Code: "B-1", Size: models.CELL_SIZE_L, Capacity: 2, Occupied: 2},
+		},
+		{
+			name: "suggested cell",
+			data: requests.AssignCellRequest{ProductId: "prod1"},
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("LockProductLocation", mock.Anything, "prod1").Return(received(), nil)
+				m.On("FindFreeCell", mock.Anything, "pvz1", models.CELL_SIZE_M).
+					Return(&models.StorageCell{Id: "cell2", Code: "C-1"}, nil)
+				m.On("LockStorageCell", mock.Anything, "pvz1", "C-1").
+					Return(&models.StorageCell{Id: "cell2", Code: "C-1", Size: models.CELL_SIZE_M, Capacity: 1}, nil)
+				m.On("SetProductCell", mock.Anything, "prod1", "cell2").Return(nil)
+			},
+			expectedCell: &models.StorageCell{Id: "cell2", Code: "C-1", Size: models.CELL_SIZE_M, Capacity: 1, Occupied: 1},
+		},
+		{
+			name: "already in this cell",
+			data: requests.AssignCellRequest{ProductId: "prod1", CellCode: "B-1"},
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				location := received()
+				location.Cell = &models.StorageCell{Id: "cell1", Code: "B-1"}
+				m.On("LockProductLocation", mock.Anything, "prod1").Return(location, nil)
+			},
+			expectedCell: &models.StorageCell{Id: "cell1", Code: "B-1"},
+		},
+		{
+			name: "cell too small",
+			data: requests.AssignCellRequest{ProductId: "prod1", CellCode: "S-1"},
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("LockProductLocation", mock.Anything, "prod1").Return(received(), nil)
+				m.On("LockStorageCell", mock.Anything, "pvz1", "S-1").
+					Return(&models.StorageCell{Id: "cell3", Code: "S-1", Size: models.CELL_SIZE_S, Capacity: 5}, nil)
+			},
+			expectedErr: errors.New("cell is too small"),
+		},
+		{
+			name: "no free cell",
+			data: requests.AssignCellRequest{ProductId: "prod1"},
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("LockProductLocation", mock.Anything, "prod1").Return(received(), nil)
+				m.On("FindFreeCell", mock.Anything, "pvz1", models.CELL_SIZE_M).Return(nil, errors.New("no free cell"))
+			},
+			expectedErr: errors.New("no free cell"),
+		},
+		{
+			name:        "product id required",
+			data:        requests.AssignCellRequest{},
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("product id is required"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockStorageCellRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewStorageCellUsecase(mockRepo)
+
+			res, err := uc.AssignCell(roleCtx("employee")(), "pvz1", tt.data)
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				assert.Equal(t, tt.expectedCell, res.Cell)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStorageCellUsecase_LookupProduct(t *testing.T) {
+	location := &models.ProductLocation{
+		ProductId: "prod1",
+		PvzId:     "pvz1",
+		Status:    models.PRODUCT_STATUS_RECEIVED,
+		Cell:      &models.StorageCell{Code: "A-1"},
+	}
+
+	tests := []struct {
+		name        string
+		productId   string
+		pickupCode  string
+		mockSetup   func(*repositoryMocks.MockStorageCellRepository)
+		expectedErr error
+	}{
+		{
+			name:      "by product id",
+			productId: "prod1",
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("GetProductLocation", mock.Anything, "prod1").Return(location, nil)
+			},
+		},
+		{
+			name:       "by pickup code",
+			pickupCode: " 123456 ",
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("GetProductIdByPickupCode", mock.Anything, "123456").Return("prod1", nil)
+				m.On("GetProductLocation", mock.Anything, "prod1").Return(location, nil)
+			},
+		},
+		{
+			name:       "unknown pickup code",
+			pickupCode: "000000",
+			mockSetup: func(m *repositoryMocks.MockStorageCellRepository) {
+				m.On("GetProductIdByPickupCode", mock.Anything, "000000").Return("", errors.New("pickup code not found"))
+			},
+			expectedErr: errors.New("pickup code not found"),
+		},
+		{
+			name:        "both identifiers",
+			productId:   "prod1",
+			pickupCode:  "123456",
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("product id or pickup code is required"),
+		},
+		{
+			name:        "no identifiers",
+			mockSetup:   func(_ *repositoryMocks.MockStorageCellRepository) {},
+			expectedErr: errors.New("product id or pickup code is required"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositoryMocks.MockStorageCellRepository)
+			tt.mockSetup(mockRepo)
+			uc := NewStorageCellUsecase(mockRepo)
+
+			res, err := uc.LookupProduct(roleCtx("employee")(), "pvz1", tt.productId, tt.pickupCode)
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				assert.Equal(t, *location, res)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
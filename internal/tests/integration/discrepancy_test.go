@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	productTypeRepository "avito_spring_staj_2025/internal/producttype/repository"
+	productTypeUsecase "avito_spring_staj_2025/internal/producttype/usecase"
+	receptionController "avito_spring_staj_2025/internal/reception/handler"
+	receptionRepository "avito_spring_staj_2025/internal/reception/repository"
+	receptionUsecase "avito_spring_staj_2025/internal/reception/usecase"
+	scheduleRepository "avito_spring_staj_2025/internal/schedule/repository"
+	scheduleUsecase "avito_spring_staj_2025/internal/schedule/usecase"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Повторно отсканировать штрихкод в той же приёмке не даёт уникальный индекс, поэтому duplicated
+// в отчёте — это товар из манифеста, который ПВЗ уже принимал в одной из прошлых приёмок.
+// Запуск: TEST_DATABASE_URL=postgres://... go test ./internal/tests/integration/ -run Discrepancy
+func TestDiscrepancyReportDuplicatedAcrossReceptions(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	logger.AccessLogger = zap.NewNop()
+	logger.DBLogger = zap.NewNop()
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+	require.NoError(t, goose.Up(db, filepath.Join("..", "..", "..", "cmd", "migration", "migrations")))
+
+	pvzId := uuid.New().String()
+	_, err = db.Exec(`INSERT INTO pvzs (id, registration_date, city) VALUES ($1, NOW(), 'Москва')`, pvzId)
+	require.NoError(t, err)
+
+	handler := receptionController.NewReceptionHandler(
+		receptionUsecase.NewReceptionUsecase(
+			receptionRepository.NewReceptionRepository(db),
+			productTypeUsecase.NewProductTypeUsecase(productTypeRepository.NewProductTypeRepository(db)),
+			scheduleUsecase.NewScheduleUsecase(scheduleRepository.NewScheduleRepository(db)),
+		),
+	)
+	employeeCtx := context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+	barcode := "BC-" + uuid.New().String()
+
+	createReception := func() string {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/receptions", mockJSONBody(t, requests.CreateReceptionRequest{
+			PvzId: pvzId,
+		})).WithContext(employeeCtx)
+		handler.CreateReception(rr, req)
+		require.Equal(t, http.StatusCreated, rr.Code)
+		var response responses.CreateReceptionResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		return response.Id
+	}
+	addProduct := func(externalId string) int {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/products", mockJSONBody(t, requests.AddProductRequest{
+			Type:       "одежда",
+			PvzId:      pvzId,
+			ExternalId: externalId,
+		})).WithContext(employeeCtx)
+		handler.AddProductToReception(rr, req)
+		return rr.Code
+	}
+	closeReception := func() {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/pvz/"+pvzId+"/close_last_reception", nil).WithContext(employeeCtx)
+		req = mux.SetURLVars(req, map[string]string{"pvzId": pvzId})
+		handler.CloseLastReception(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	createReception()
+	assert.Equal(t, http.StatusCreated, addProduct(barcode))
+	assert.Equal(t, http.StatusConflict, addProduct(barcode))
+	closeReception()
+
+	receptionId := createReception()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/receptions/"+receptionId+"/manifest", mockJSONBody(t, requests.SetManifestRequest{
+		Items: []requests.ManifestItemRequest{{Type: "одежда", ExternalId: barcode}},
+	})).WithContext(employeeCtx)
+	req = mux.SetURLVars(req, map[string]string{"receptionId": receptionId})
+	handler.SetReceptionManifest(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, http.StatusCreated, addProduct(barcode))
+	closeReception()
+
+	var kind, externalId string
+	require.NoError(t, db.QueryRow(
+		`SELECT kind, external_id FROM reception_discrepancy_items WHERE reception_id = $1`, receptionId,
+	).Scan(&kind, &externalId))
+	assert.Equal(t, models.DISCREPANCY_DUPLICATED, kind)
+	assert.Equal(t, barcode, externalId)
+}
@@ -12,7 +12,10 @@ import (
 	receptionController "avito_spring_staj_2025/internal/reception/handler"
 	receptionRepository "avito_spring_staj_2025/internal/reception/repository"
 	receptionUsecase "avito_spring_staj_2025/internal/reception/usecase"
+	scheduleRepository "avito_spring_staj_2025/internal/schedule/repository"
+	scheduleUsecase "avito_spring_staj_2025/internal/schedule/usecase"
 	"avito_spring_staj_2025/internal/service/middleware"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -41,8 +44,9 @@ func TestFullPVZWorkflow(t *testing.T) {
 	pvzRepo := pvzRepository.NewPvzRepository(db)
 	receptionRepo := receptionRepository.NewReceptionRepository(db)
 
-	pvzUsecase := pvzUsecase.NewPvzUsecase(pvzRepo)
-	receptionUsecase := receptionUsecase.NewReceptionUsecase(receptionRepo)
+	pvzUsecase := pvzUsecase.NewPvzUsecase(pvzRepo, usecaseMocks.DefaultProductTypeCatalog())
+	receptionUsecase := receptionUsecase.NewReceptionUsecase(receptionRepo, usecaseMocks.DefaultProductTypeCatalog(),
+		scheduleUsecase.NewScheduleUsecase(scheduleRepository.NewScheduleRepository(db)))
 
 	pvzHandler := pvzController.NewPvzHandler(pvzUsecase)
 	receptionHandler := receptionController.NewReceptionHandler(receptionUsecase)
@@ -51,9 +55,12 @@ func TestFullPVZWorkflow(t *testing.T) {
 
 	t.Run("Create PVZ - moderator", func(t *testing.T) {
 
-		mock.ExpectExec(`^INSERT INTO pvzs \(id,registration_date,city\) VALUES \(\$1,\$2,\$3\)$`).
-			WithArgs("pvz-1", sqlmock.AnyArg(), "Москва").
+		mock.ExpectBegin()
+		mock.ExpectExec(`^INSERT INTO pvzs \(id,registration_date,city,address\) VALUES \(\$1,\$2,\$3,\$4\)$`).
+			WithArgs("pvz-1", sqlmock.AnyArg(), "Москва", "").
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectOutboxEvent(mock, models.EVENT_PVZ_CREATED)
+		mock.ExpectCommit()
 
 		req := httptest.NewRequest("POST", "/pvz", mockJSONBody(t, requests.CreatePvzRequest{
 			Id:               "pvz-1",
@@ -81,9 +88,23 @@ func TestFullPVZWorkflow(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city"}).
 				AddRow("pvz-1", time.Now(), "Москва"))
 
+		mock.ExpectQuery("SELECT id, time_zone, working_hours_policy FROM pvzs").
+			WithArgs("pvz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "time_zone", "working_hours_policy"}).
+				AddRow("pvz-1", models.DEFAULT_TIME_ZONE, models.WORKING_HOURS_POLICY_NONE))
+		mock.ExpectQuery("SELECT weekday, open_time, close_time FROM pvz_working_hours").
+			WithArgs("pvz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"weekday", "open_time", "close_time"}))
+		mock.ExpectQuery(`SELECT .* FROM pvz_schedule_exceptions WHERE pvz_id = \$1 AND date IN \(\$2,\$3\)`).
+			WithArgs("pvz-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "pvz_id", "date", "is_closed", "open_time", "close_time", "reason"}))
+
+		mock.ExpectBegin()
 		mock.ExpectExec("INSERT INTO receptions").
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "pvz-1", "in_progress").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "pvz-1", "in_progress", false).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectOutboxEvent(mock, models.EVENT_RECEPTION_OPENED)
+		mock.ExpectCommit()
 
 		req := httptest.NewRequest("POST", "/receptions", nil).WithContext(ctx)
 		req.Body = mockJSONBody(t, requests.CreateReceptionRequest{
@@ -103,14 +124,25 @@ func TestFullPVZWorkflow(t *testing.T) {
 				WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city"}).
 					AddRow("pvz-1", time.Now(), "Москва"))
 
-			mock.ExpectQuery(`^SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = \$1 AND status = \$2$`).
+			mock.ExpectBegin()
+			mock.ExpectQuery(`^SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = \$1 AND status = \$2 FOR UPDATE$`).
 				WithArgs("pvz-1", models.STATUS_ACTIVE).
 				WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
 					AddRow("rec-1", time.Now(), "pvz-1", "in_progress"))
+			mock.ExpectQuery(`^UPDATE pvzs SET occupancy = occupancy \+ \$1`).
+				WithArgs(1, "pvz-1", 1, models.CAPACITY_POLICY_WARN).
+				WillReturnRows(sqlmock.NewRows([]string{"occupancy", "capacity"}).
+					AddRow(i+1, nil))
+
+			mock.ExpectQuery(`^UPDATE receptions SET last_seq_no = last_seq_no \+ \$1 WHERE id = \$2 RETURNING last_seq_no$`).
+				WithArgs(1, "rec-1").
+				WillReturnRows(sqlmock.NewRows([]string{"last_seq_no"}).AddRow(int64(i + 1)))
 
-			mock.ExpectExec(`^INSERT INTO products \(id,date_time,type,reception_id\) VALUES \(\$1,\$2,\$3,\$4\)$`).
-				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "одежда", "rec-1").
+			mock.ExpectExec(`^INSERT INTO products \(id,date_time,type,reception_id,seq_no,external_id\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\)$`).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "одежда", "rec-1", int64(i+1), nil).
 				WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+			expectOutboxEvent(mock, models.EVENT_PRODUCT_ADDED)
+			mock.ExpectCommit()
 		}
 
 		for i := 0; i < 50; i++ {
@@ -134,14 +166,35 @@ func TestFullPVZWorkflow(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"id", "registration_date", "city"}).
 				AddRow("pvz-1", time.Now(), "Москва"))
 
-		mock.ExpectQuery("SELECT.*FROM receptions").
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT.*FROM receptions .* FOR UPDATE").
 			WithArgs("pvz-1", models.STATUS_ACTIVE).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).
 				AddRow("rec-1", time.Now(), "pvz-1", "in_progress"))
 
 		mock.ExpectExec("UPDATE receptions").
-			WithArgs(models.STATUS_CLOSED, "rec-1").
+			WithArgs(models.STATUS_CLOSED, "rec-1", models.STATUS_ACTIVE).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOutboxEvent(mock, models.EVENT_RECEPTION_CLOSED)
+		mock.ExpectQuery("SELECT type, COUNT.* FROM products").
+			WithArgs("rec-1").
+			WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).AddRow("одежда", 50))
+		mock.ExpectQuery("SELECT COUNT.* FROM product_deletions").
+			WithArgs("rec-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("SELECT MAX.*reopened_at.* FROM reception_reopens").
+			WithArgs("rec-1").
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+		mock.ExpectQuery("INSERT INTO reception_summaries").
+			WithArgs("rec-1", sqlmock.AnyArg(), 50, []byte(`{"одежда":50}`), sqlmock.AnyArg(), 0).
+			WillReturnRows(sqlmock.NewRows([]string{"open_duration_seconds"}).AddRow(int64(0)))
+		mock.ExpectExec("UPDATE products p SET storage_deadline = .* FROM product_types pt").
+			WithArgs(sqlmock.AnyArg(), "rec-1", models.PRODUCT_STATUS_RECEIVED).
+			WillReturnResult(sqlmock.NewResult(0, 50))
+		mock.ExpectQuery("SELECT .* FROM reception_manifest_items").
+			WithArgs("rec-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "reception_id", "external_id", "type"}))
+		mock.ExpectCommit()
 
 		req := httptest.NewRequest("PUT", "/pvz/pvz-1/close_last_reception", nil).WithContext(ctx)
 		req = mux.SetURLVars(req, map[string]string{"pvzId": "pvz-1"})
@@ -150,11 +203,29 @@ func TestFullPVZWorkflow(t *testing.T) {
 		receptionHandler.CloseLastReception(rr, req)
 
 		assert.Equal(t, http.StatusOK, rr.Code)
+		var response struct {
+			Summary struct {
+				TotalProducts  int            `json:"totalProducts"`
+				ProductsByType map[string]int `json:"productsByType"`
+			} `json:"summary"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, 50, response.Summary.TotalProducts)
+		assert.Equal(t, map[string]int{"одежда": 50}, response.Summary.ProductsByType)
 	})
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// expectOutboxEvent ожидает запись события ПВЗ pvz-1 в outbox в транзакции изменения.
+func expectOutboxEvent(mock sqlmock.Sqlmock, eventType string) {
+	mock.ExpectExec(`^SELECT pg_advisory_xact_lock`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`^INSERT INTO outbox_events`).
+		WithArgs(sqlmock.AnyArg(), eventType, "pvz-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
 func mockJSONBody(t *testing.T, data interface{}) *mockBody {
 	body, err := json.Marshal(data)
 	require.NoError(t, err)
@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"avito_spring_staj_2025/domain/requests"
+	productTypeRepository "avito_spring_staj_2025/internal/producttype/repository"
+	productTypeUsecase "avito_spring_staj_2025/internal/producttype/usecase"
+	receptionController "avito_spring_staj_2025/internal/reception/handler"
+	receptionRepository "avito_spring_staj_2025/internal/reception/repository"
+	receptionUsecase "avito_spring_staj_2025/internal/reception/usecase"
+	scheduleRepository "avito_spring_staj_2025/internal/schedule/repository"
+	scheduleUsecase "avito_spring_staj_2025/internal/schedule/usecase"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Тест гоняет ручки приёмки параллельно на настоящем Postgres: гонки ловятся только
+// ограничениями и блокировками базы, sqlmock их не воспроизводит.
+// Запуск: TEST_DATABASE_URL=postgres://... go test ./internal/tests/integration/ -run Concurrency
+func TestReceptionLifecycleConcurrency(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	logger.AccessLogger = zap.NewNop()
+	logger.DBLogger = zap.NewNop()
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer func() {
+		_ = db.Close()
+	}()
+	require.NoError(t, goose.Up(db, filepath.Join("..", "..", "..", "cmd", "migration", "migrations")))
+
+	pvzId := uuid.New().String()
+	_, err = db.Exec(`INSERT INTO pvzs (id, registration_date, city) VALUES ($1, NOW(), 'Москва')`, pvzId)
+	require.NoError(t, err)
+
+	handler := receptionController.NewReceptionHandler(
+		receptionUsecase.NewReceptionUsecase(
+			receptionRepository.NewReceptionRepository(db),
+			productTypeUsecase.NewProductTypeUsecase(productTypeRepository.NewProductTypeRepository(db)),
+			scheduleUsecase.NewScheduleUsecase(scheduleRepository.NewScheduleRepository(db)),
+		),
+	)
+	employeeCtx := context.WithValue(context.Background(), middleware.ContextKeyRole, "employee")
+
+	const workers = 20
+
+	createStatuses := hammer(workers, func(int) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/receptions", mockJSONBody(t, requests.CreateReceptionRequest{
+			PvzId: pvzId,
+		})).WithContext(employeeCtx)
+		rr := httptest.NewRecorder()
+		handler.CreateReception(rr, req)
+		return rr.Code
+	})
+	assert.Equal(t, 1, countStatus(createStatuses, http.StatusCreated))
+	assert.Equal(t, workers-1, countStatus(createStatuses, http.StatusBadRequest))
+
+	// Половина горутин добавляет товары, одна закрывает приёмку посреди потока.
+	closeWorker := workers / 2
+	lifecycleStatuses := hammer(workers, func(i int) int {
+		rr := httptest.NewRecorder()
+		if i == closeWorker {
+			req := httptest.NewRequest(http.MethodPost, "/api/pvz/"+pvzId+"/close_last_reception", nil).WithContext(employeeCtx)
+			req = mux.SetURLVars(req, map[string]string{"pvzId": pvzId})
+			handler.CloseLastReception(rr, req)
+			return rr.Code
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/products", mockJSONBody(t, requests.AddProductRequest{
+			Type:  "одежда",
+			PvzId: pvzId,
+		})).WithContext(employeeCtx)
+		handler.AddProductToReception(rr, req)
+		return rr.Code
+	})
+	assert.Equal(t, http.StatusOK, lifecycleStatuses[closeWorker])
+
+	added := countStatus(lifecycleStatuses, http.StatusCreated)
+	var products, occupancy, active int
+	require.NoError(t, db.QueryRow(
+		`SELECT COUNT(*) FROM products p JOIN receptions r ON r.id = p.reception_id WHERE r.pvz_id = $1`, pvzId,
+	).Scan(&products))
+	require.NoError(t, db.QueryRow(`SELECT occupancy FROM pvzs WHERE id = $1`, pvzId).Scan(&occupancy))
+	require.NoError(t, db.QueryRow(
+		`SELECT COUNT(*) FROM receptions WHERE pvz_id = $1 AND status = 'in_progress'`, pvzId,
+	).Scan(&active))
+
+	assert.Equal(t, added, products)
+	assert.Equal(t, added, occupancy)
+	assert.Equal(t, 0, active)
+}
+
+// hammer запускает n вызовов fn одновременно и возвращает их HTTP-статусы по номеру вызова.
+func hammer(n int, fn func(i int) int) []int {
+	statuses := make([]int, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			statuses[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+func countStatus(statuses []int, status int) int {
+	count := 0
+	for _, s := range statuses {
+		if s == status {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,39 @@
+package idempotencyMocks
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdempotencyStore struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyStore) Acquire(ctx context.Context, record models.IdempotencyRecord) (bool, error) {
+	args := m.Called(ctx, record)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyStore) Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	args := m.Called(ctx, scope, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockIdempotencyStore) Extend(ctx context.Context, record models.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyStore) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyStore) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
@@ -15,6 +15,11 @@ func (m *MockJwtService) Create(role string, exp int64) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockJwtService) CreateWithSubject(subject, role string, exp int64) (string, error) {
+	args := m.Called(subject, role, exp)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockJwtService) Validate(tokenString string) (*jwtService.JwtCsrfClaims, error) {
 	args := m.Called(tokenString)
 	return args.Get(0).(*jwtService.JwtCsrfClaims), args.Error(1)
@@ -0,0 +1,16 @@
+package outboxMocks
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, event models.DomainEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
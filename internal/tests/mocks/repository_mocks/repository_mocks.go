@@ -8,6 +8,14 @@ import (
 	"time"
 )
 
+// inlineTransaction не записывает вызов WithinTransaction, а сразу выполняет fn:
+// транзакционность проверяется тестами репозиториев.
+type inlineTransaction struct{}
+
+func (inlineTransaction) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 type MockAuthRepository struct {
 	mock.Mock
 }
@@ -31,6 +39,19 @@ func (m *MockPvzRepository) CreatePvz(ctx context.Context, data *requests.Create
 	return args.Error(0)
 }
 
+func (m *MockPvzRepository) CreatePvzs(ctx context.Context, pvzs []requests.CreatePvzRequest, allOrNothing bool) ([]string, error) {
+	args := m.Called(ctx, pvzs, allOrNothing)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockPvzRepository) GetExistingPvzIds(ctx context.Context, ids []string) ([]string, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *MockPvzRepository) GetPvzReceptions(ctx context.Context, pvzId string) ([]models.Reception, error) {
 	args := m.Called(ctx, pvzId)
 	return args.Get(0).([]models.Reception), args.Error(1)
@@ -41,8 +62,8 @@ func (m *MockPvzRepository) GetReceptionProducts(ctx context.Context, receptionI
 	return args.Get(0).([]models.Product), args.Error(1)
 }
 
-func (m *MockPvzRepository) GetPvzsFilteredByReceptionDate(ctx context.Context, from, to time.Time, limit, offset int) ([]models.Pvz, error) {
-	args := m.Called(ctx, from, to, limit, offset)
+func (m *MockPvzRepository) GetPvzsFiltered(ctx context.Context, filter models.PvzFilter, limit, offset int) ([]models.Pvz, error) {
+	args := m.Called(ctx, filter, limit, offset)
 	return args.Get(0).([]models.Pvz), args.Error(1)
 }
 
@@ -51,8 +72,22 @@ func (m *MockPvzRepository) GetAllPvzs(ctx context.Context) ([]models.Pvz, error
 	return args.Get(0).([]models.Pvz), args.Error(1)
 }
 
+func (m *MockPvzRepository) GetPvzById(ctx context.Context, pvzId string) (*models.Pvz, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Pvz), args.Error(1)
+}
+
+func (m *MockPvzRepository) SetPvzCapacity(ctx context.Context, pvzId string, capacity *int, policy string) error {
+	args := m.Called(ctx, pvzId, capacity, policy)
+	return args.Error(0)
+}
+
 type MockReceptionRepository struct {
 	mock.Mock
+	inlineTransaction
 }
 
 func (m *MockReceptionRepository) CreateReception(ctx context.Context, data models.Reception) error {
@@ -73,18 +108,73 @@ func (m *MockReceptionRepository) GetCurrentReception(ctx context.Context, pvzId
 	return args.Get(0).(*models.Reception), args.Error(1)
 }
 
-func (m *MockReceptionRepository) AddProductToReception(ctx context.Context, product models.Product) error {
-	args := m.Called(ctx, product)
-	return args.Error(0)
+func (m *MockReceptionRepository) LockCurrentReception(ctx context.Context, pvzId string) (*models.Reception, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Reception), args.Error(1)
 }
 
-func (m *MockReceptionRepository) GetLastProductInReception(ctx context.Context, receptionId string) (*models.Product, error) {
+func (m *MockReceptionRepository) LockReceptionById(ctx context.Context, receptionId string) (*models.Reception, error) {
 	args := m.Called(ctx, receptionId)
-	return args.Get(0).(*models.Product), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Reception), args.Error(1)
+}
+
+func (m *MockReceptionRepository) GetLastReceptionId(ctx context.Context, pvzId string) (string, error) {
+	args := m.Called(ctx, pvzId)
+	return args.String(0), args.Error(1)
 }
 
-func (m *MockReceptionRepository) DeleteProductById(ctx context.Context, productId string) error {
+func (m *MockReceptionRepository) GetLastReopenedAt(ctx context.Context, receptionId string) (time.Time, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockReceptionRepository) ReopenReception(ctx context.Context, reopen models.ReceptionReopen) error {
+	args := m.Called(ctx, reopen)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) AddProductToReception(ctx context.Context, pvzId string, product *models.Product) error {
+	args := m.Called(ctx, pvzId, product)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) AddProductsToReception(ctx context.Context, pvzId string, products []models.Product) error {
+	args := m.Called(ctx, pvzId, products)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) GetLastProductsInReception(ctx context.Context, receptionId string, count int) ([]models.Product, error) {
+	args := m.Called(ctx, receptionId, count)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Product), args.Error(1)
+}
+
+func (m *MockReceptionRepository) GetProductsByExternalId(ctx context.Context, externalId string) ([]models.ProductLookup, error) {
+	args := m.Called(ctx, externalId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ProductLookup), args.Error(1)
+}
+
+func (m *MockReceptionRepository) GetProductById(ctx context.Context, productId string) (*models.Product, error) {
 	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Product), args.Error(1)
+}
+
+func (m *MockReceptionRepository) DeleteProducts(ctx context.Context, deletions []models.ProductDeletion) error {
+	args := m.Called(ctx, deletions)
 	return args.Error(0)
 }
 
@@ -92,3 +182,628 @@ func (m *MockReceptionRepository) CloseReception(ctx context.Context, reception
 	args := m.Called(ctx, reception)
 	return args.Error(0)
 }
+
+func (m *MockReceptionRepository) GetStaleReceptions(ctx context.Context, before time.Time) ([]models.Reception, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Reception), args.Error(1)
+}
+
+func (m *MockReceptionRepository) GetReceptionLastActivity(ctx context.Context, receptionId string) (time.Time, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockReceptionRepository) AutoCloseReception(ctx context.Context, reception *models.Reception, reason string) error {
+	args := m.Called(ctx, reception, reason)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) ReplaceManifest(ctx context.Context, receptionId string, items []models.ManifestItem) error {
+	args := m.Called(ctx, receptionId, items)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) GetManifest(ctx context.Context, receptionId string) ([]models.ManifestItem, error) {
+	args := m.Called(ctx, receptionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ManifestItem), args.Error(1)
+}
+
+func (m *MockReceptionRepository) GetProductsInReception(ctx context.Context, receptionId string) ([]models.Product, error) {
+	args := m.Called(ctx, receptionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Product), args.Error(1)
+}
+
+func (m *MockReceptionRepository) GetExternalIdsReceivedEarlier(ctx context.Context, receptionId string) (map[string]struct{}, error) {
+	args := m.Called(ctx, receptionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]struct{}), args.Error(1)
+}
+
+func (m *MockReceptionRepository) SaveDiscrepancyReport(ctx context.Context, report *models.DiscrepancyReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) GetDiscrepancyReport(ctx context.Context, receptionId string) (*models.DiscrepancyReport, error) {
+	args := m.Called(ctx, receptionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DiscrepancyReport), args.Error(1)
+}
+
+func (m *MockReceptionRepository) CountProductsByType(ctx context.Context, receptionId string) (map[string]int, error) {
+	args := m.Called(ctx, receptionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockReceptionRepository) CountProductDeletions(ctx context.Context, receptionId string) (int, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockReceptionRepository) SaveReceptionSummary(ctx context.Context, summary *models.ReceptionSummary) error {
+	args := m.Called(ctx, summary)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) SetStorageDeadlines(ctx context.Context, receptionId string, closedAt time.Time) error {
+	args := m.Called(ctx, receptionId, closedAt)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepository) LockStorageCell(ctx context.Context, pvzId, code string) (*models.StorageCell, error) {
+	args := m.Called(ctx, pvzId, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StorageCell), args.Error(1)
+}
+
+type MockScheduleRepository struct {
+	mock.Mock
+}
+
+func (m *MockScheduleRepository) GetPvzSchedule(ctx context.Context, pvzId string) (*models.PvzSchedule, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PvzSchedule), args.Error(1)
+}
+
+func (m *MockScheduleRepository) GetPvzScheduleAt(ctx context.Context, pvzId string, at time.Time) (*models.PvzSchedule, error) {
+	args := m.Called(ctx, pvzId, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PvzSchedule), args.Error(1)
+}
+
+func (m *MockScheduleRepository) SetWorkingHours(ctx context.Context, schedule models.PvzSchedule) error {
+	args := m.Called(ctx, schedule)
+	return args.Error(0)
+}
+
+func (m *MockScheduleRepository) CreateScheduleException(ctx context.Context, exception models.ScheduleException) error {
+	args := m.Called(ctx, exception)
+	return args.Error(0)
+}
+
+func (m *MockScheduleRepository) DeleteScheduleException(ctx context.Context, pvzId, exceptionId string) error {
+	args := m.Called(ctx, pvzId, exceptionId)
+	return args.Error(0)
+}
+
+type MockExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockExportRepository) stream(args mock.Arguments, fn func(values []string) error) error {
+	if rows, ok := args.Get(0).([][]string); ok {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockExportRepository) StreamPvzs(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error {
+	return m.stream(m.Called(ctx, filter), fn)
+}
+
+func (m *MockExportRepository) StreamReceptions(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error {
+	return m.stream(m.Called(ctx, filter), fn)
+}
+
+func (m *MockExportRepository) StreamProducts(ctx context.Context, filter models.ExportFilter, fn func(values []string) error) error {
+	return m.stream(m.Called(ctx, filter), fn)
+}
+
+type MockProductTypeRepository struct {
+	mock.Mock
+}
+
+func (m *MockProductTypeRepository) GetProductTypes(ctx context.Context) ([]models.ProductType, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ProductType), args.Error(1)
+}
+
+func (m *MockProductTypeRepository) GetProductType(ctx context.Context, code string) (*models.ProductType, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductType), args.Error(1)
+}
+
+func (m *MockProductTypeRepository) CreateProductType(ctx context.Context, productType models.ProductType) error {
+	args := m.Called(ctx, productType)
+	return args.Error(0)
+}
+
+func (m *MockProductTypeRepository) UpdateProductType(ctx context.Context, productType models.ProductType) error {
+	args := m.Called(ctx, productType)
+	return args.Error(0)
+}
+
+func (m *MockProductTypeRepository) DeleteProductType(ctx context.Context, code string) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+type MockProductRepository struct {
+	mock.Mock
+	inlineTransaction
+}
+
+func (m *MockProductRepository) GetPvzById(ctx context.Context, pvzId string) (*models.Pvz, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Pvz), args.Error(1)
+}
+
+func (m *MockProductRepository) GetProductState(ctx context.Context, productId string) (*models.ProductState, error) {
+	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductState), args.Error(1)
+}
+
+func (m *MockProductRepository) LockProductState(ctx context.Context, productId string) (*models.ProductState, error) {
+	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductState), args.Error(1)
+}
+
+func (m *MockProductRepository) ChangeProductStatus(ctx context.Context, event models.ProductStatusEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) ChangePvzOccupancy(ctx context.Context, pvzId string, delta int) error {
+	args := m.Called(ctx, pvzId, delta)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) ReservePvzCapacity(ctx context.Context, pvzId string, count int) error {
+	args := m.Called(ctx, pvzId, count)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) GetProductStatusEvents(ctx context.Context, productId string) ([]models.ProductStatusEvent, error) {
+	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ProductStatusEvent), args.Error(1)
+}
+
+func (m *MockProductRepository) GetUserRole(ctx context.Context, userId string) (string, error) {
+	args := m.Called(ctx, userId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockProductRepository) SetOrderOwner(ctx context.Context, owner models.OrderOwner) error {
+	args := m.Called(ctx, owner)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) GetCustomerPickupCodes(ctx context.Context, customerId string) ([]models.PickupCode, error) {
+	args := m.Called(ctx, customerId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PickupCode), args.Error(1)
+}
+
+func (m *MockProductRepository) SavePickupCode(ctx context.Context, code models.PickupCode) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) LockPickupCode(ctx context.Context, productId string) (*models.PickupCode, error) {
+	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PickupCode), args.Error(1)
+}
+
+func (m *MockProductRepository) RegisterFailedPickupAttempt(ctx context.Context, productId string) error {
+	args := m.Called(ctx, productId)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) MarkPickupCodeUsed(ctx context.Context, productId string, usedAt time.Time) error {
+	args := m.Called(ctx, productId, usedAt)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) GetOverdueProductIds(ctx context.Context, now time.Time) ([]string, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockProductRepository) GetPvzOverdueProducts(ctx context.Context, pvzId string, now time.Time) ([]models.ProductState, error) {
+	args := m.Called(ctx, pvzId, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ProductState), args.Error(1)
+}
+
+func (m *MockProductRepository) CreateTransfer(ctx context.Context, transfer models.Transfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) LockTransfer(ctx context.Context, transferId string) (*models.Transfer, error) {
+	args := m.Called(ctx, transferId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Transfer), args.Error(1)
+}
+
+func (m *MockProductRepository) GetIncomingTransfers(ctx context.Context, pvzId string) ([]models.Transfer, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Transfer), args.Error(1)
+}
+
+func (m *MockProductRepository) AcceptTransfer(ctx context.Context, transfer models.Transfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) LockActiveReception(ctx context.Context, pvzId string) (*models.Reception, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Reception), args.Error(1)
+}
+
+func (m *MockProductRepository) MoveProductToReception(ctx context.Context, pvzId, productId, receptionId, transferId string) error {
+	args := m.Called(ctx, pvzId, productId, receptionId, transferId)
+	return args.Error(0)
+}
+
+type MockStorageCellRepository struct {
+	mock.Mock
+	inlineTransaction
+}
+
+func (m *MockStorageCellRepository) ReplaceStorageCells(ctx context.Context, pvzId string, cells []models.StorageCell) error {
+	args := m.Called(ctx, pvzId, cells)
+	return args.Error(0)
+}
+
+func (m *MockStorageCellRepository) GetStorageCells(ctx context.Context, pvzId string) ([]models.StorageCell, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StorageCell), args.Error(1)
+}
+
+func (m *MockStorageCellRepository) FindFreeCell(ctx context.Context, pvzId, size string) (*models.StorageCell, error) {
+	args := m.Called(ctx, pvzId, size)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StorageCell), args.Error(1)
+}
+
+func (m *MockStorageCellRepository) LockStorageCell(ctx context.Context, pvzId, code string) (*models.StorageCell, error) {
+	args := m.Called(ctx, pvzId, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StorageCell), args.Error(1)
+}
+
+func (m *MockStorageCellRepository) GetProductLocation(ctx context.Context, productId string) (*models.ProductLocation, error) {
+	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductLocation), args.Error(1)
+}
+
+func (m *MockStorageCellRepository) LockProductLocation(ctx context.Context, productId string) (*models.ProductLocation, error) {
+	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ProductLocation), args.Error(1)
+}
+
+func (m *MockStorageCellRepository) GetProductIdByPickupCode(ctx context.Context, code string) (string, error) {
+	args := m.Called(ctx, code)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockStorageCellRepository) SetProductCell(ctx context.Context, productId, cellId string) error {
+	args := m.Called(ctx, productId, cellId)
+	return args.Error(0)
+}
+
+type MockInventoryRepository struct {
+	mock.Mock
+	inlineTransaction
+}
+
+func (m *MockInventoryRepository) CreateInventorySession(ctx context.Context, session models.InventorySession) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockInventoryRepository) GetInventorySession(ctx context.Context, sessionId string) (*models.InventorySession, error) {
+	args := m.Called(ctx, sessionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InventorySession), args.Error(1)
+}
+
+func (m *MockInventoryRepository) LockInventorySession(ctx context.Context, sessionId string) (*models.InventorySession, error) {
+	args := m.Called(ctx, sessionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InventorySession), args.Error(1)
+}
+
+func (m *MockInventoryRepository) FindPvzProductsByCode(ctx context.Context, sessionId, pvzId, code string) ([]models.InventoryProductMatch, error) {
+	args := m.Called(ctx, sessionId, pvzId, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.InventoryProductMatch), args.Error(1)
+}
+
+func (m *MockInventoryRepository) AddInventoryScan(ctx context.Context, scan models.InventoryScan) error {
+	args := m.Called(ctx, scan)
+	return args.Error(0)
+}
+
+func (m *MockInventoryRepository) GetInventoryScans(ctx context.Context, sessionId string) ([]models.InventoryScan, error) {
+	args := m.Called(ctx, sessionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.InventoryScan), args.Error(1)
+}
+
+func (m *MockInventoryRepository) GetPvzStoredProducts(ctx context.Context, pvzId string) ([]models.InventoryProduct, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.InventoryProduct), args.Error(1)
+}
+
+func (m *MockInventoryRepository) CompleteInventorySession(ctx context.Context, session *models.InventorySession) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockInventoryRepository) ApproveInventorySession(ctx context.Context, session *models.InventorySession) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+type MockDamageRepository struct {
+	mock.Mock
+	inlineTransaction
+}
+
+func (m *MockDamageRepository) GetProductPvz(ctx context.Context, productId string) (string, error) {
+	args := m.Called(ctx, productId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDamageRepository) GetReceptionPvz(ctx context.Context, receptionId string) (string, error) {
+	args := m.Called(ctx, receptionId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDamageRepository) IsProductOwnedBy(ctx context.Context, productId, customerId string) (bool, error) {
+	args := m.Called(ctx, productId, customerId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDamageRepository) CreateDamageReport(ctx context.Context, report models.DamageReport) error {
+	args := m.Called(ctx, report)
+	return args.Error(0)
+}
+
+func (m *MockDamageRepository) GetDamageReport(ctx context.Context, reportId string) (*models.DamageReport, error) {
+	args := m.Called(ctx, reportId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DamageReport), args.Error(1)
+}
+
+func (m *MockDamageRepository) LockDamageReport(ctx context.Context, reportId string) (*models.DamageReport, error) {
+	args := m.Called(ctx, reportId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DamageReport), args.Error(1)
+}
+
+func (m *MockDamageRepository) GetDamageReports(ctx context.Context, productId, receptionId string) ([]models.DamageReport, error) {
+	args := m.Called(ctx, productId, receptionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DamageReport), args.Error(1)
+}
+
+func (m *MockDamageRepository) CountDamagePhotos(ctx context.Context, reportId string) (int, error) {
+	args := m.Called(ctx, reportId)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDamageRepository) AddDamagePhoto(ctx context.Context, photo models.DamagePhoto) error {
+	args := m.Called(ctx, photo)
+	return args.Error(0)
+}
+
+func (m *MockDamageRepository) GetDamagePhoto(ctx context.Context, reportId, photoId string) (*models.DamagePhoto, error) {
+	args := m.Called(ctx, reportId, photoId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DamagePhoto), args.Error(1)
+}
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) CreateWebhook(ctx context.Context, webhook models.WebhookSubscription) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) GetWebhook(ctx context.Context, webhookId string) (*models.WebhookSubscription, error) {
+	args := m.Called(ctx, webhookId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetMatchingWebhooks(ctx context.Context, eventType, pvzId string) ([]models.WebhookSubscription, error) {
+	args := m.Called(ctx, eventType, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, webhookId string) error {
+	args := m.Called(ctx, webhookId)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	args := m.Called(ctx, deliveries)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DueWebhookDelivery, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DueWebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetDeliveries(ctx context.Context, webhookId, status string, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookId, status, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetDelivery(ctx context.Context, webhookId, deliveryId string) (*models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookId, deliveryId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+type MockEventStreamRepository struct {
+	mock.Mock
+}
+
+func (m *MockEventStreamRepository) PvzExists(ctx context.Context, pvzId string) (bool, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockEventStreamRepository) GetCityPvzIds(ctx context.Context, city string) ([]string, error) {
+	args := m.Called(ctx, city)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockEventStreamRepository) GetEventsAfter(ctx context.Context, filter models.EventStreamFilter, afterSeq int64, limit int) ([]models.DomainEvent, error) {
+	args := m.Called(ctx, filter, afterSeq, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DomainEvent), args.Error(1)
+}
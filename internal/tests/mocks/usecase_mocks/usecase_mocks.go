@@ -3,8 +3,11 @@ package usecaseMocks
 import (
 	"avito_spring_staj_2025/domain/models"
 	"avito_spring_staj_2025/domain/requests"
+	eventStreamUsecase "avito_spring_staj_2025/internal/eventstream/usecase"
+	exportUsecase "avito_spring_staj_2025/internal/export/usecase"
 	"context"
 	"github.com/stretchr/testify/mock"
+	"io"
 	"time"
 )
 
@@ -17,8 +20,13 @@ func (m *MockPvzUsecase) CreatePvz(ctx context.Context, req *requests.CreatePvzR
 	return args.Error(0)
 }
 
-func (m *MockPvzUsecase) GetPvzsInformation(ctx context.Context, fromDate, endDate time.Time, limit, page int) ([]models.Pvz, error) {
-	args := m.Called(ctx, fromDate, endDate, limit, page)
+func (m *MockPvzUsecase) ImportPvzs(ctx context.Context, data requests.ImportPvzsRequest) (models.PvzImportReport, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(models.PvzImportReport), args.Error(1)
+}
+
+func (m *MockPvzUsecase) GetPvzsInformation(ctx context.Context, filter models.PvzFilter, limit, page int) ([]models.Pvz, error) {
+	args := m.Called(ctx, filter, limit, page)
 	return args.Get(0).([]models.Pvz), args.Error(1)
 }
 
@@ -27,6 +35,16 @@ func (m *MockPvzUsecase) GetAllPvzs(ctx context.Context) ([]models.Pvz, error) {
 	return args.Get(0).([]models.Pvz), args.Error(1)
 }
 
+func (m *MockPvzUsecase) GetPvz(ctx context.Context, pvzId string) (models.Pvz, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).(models.Pvz), args.Error(1)
+}
+
+func (m *MockPvzUsecase) SetPvzCapacity(ctx context.Context, pvzId string, data requests.SetPvzCapacityRequest) (models.Pvz, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.Pvz), args.Error(1)
+}
+
 type AuthUsecaseMock struct {
 	mock.Mock
 }
@@ -60,12 +78,381 @@ func (m *ReceptionUsecaseMock) AddProductToReception(ctx context.Context, req re
 	return args.Get(0).(models.Product), args.Error(1)
 }
 
-func (m *ReceptionUsecaseMock) DeleteLastProduct(ctx context.Context, pvzID string) error {
-	args := m.Called(ctx, pvzID)
-	return args.Error(0)
+func (m *ReceptionUsecaseMock) AddProductsToReception(ctx context.Context, req requests.AddProductsBatchRequest) ([]models.Product, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]models.Product), args.Error(1)
+}
+
+func (m *ReceptionUsecaseMock) GetProductsByBarcode(ctx context.Context, barcode string) ([]models.ProductLookup, error) {
+	args := m.Called(ctx, barcode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ProductLookup), args.Error(1)
+}
+
+func (m *ReceptionUsecaseMock) DeleteLastProducts(ctx context.Context, pvzID string, count int) ([]models.Product, error) {
+	args := m.Called(ctx, pvzID, count)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Product), args.Error(1)
+}
+
+func (m *ReceptionUsecaseMock) RemoveProduct(ctx context.Context, pvzID string, req requests.RemoveProductRequest) (models.Product, error) {
+	args := m.Called(ctx, pvzID, req)
+	return args.Get(0).(models.Product), args.Error(1)
+}
+
+func (m *ReceptionUsecaseMock) ReopenReception(ctx context.Context, receptionId string, req requests.ReopenReceptionRequest) (models.Reception, models.ReceptionReopen, error) {
+	args := m.Called(ctx, receptionId, req)
+	return args.Get(0).(models.Reception), args.Get(1).(models.ReceptionReopen), args.Error(2)
 }
 
 func (m *ReceptionUsecaseMock) CloseReception(ctx context.Context, pvzID string) (models.Reception, error) {
 	args := m.Called(ctx, pvzID)
 	return args.Get(0).(models.Reception), args.Error(1)
 }
+
+func (m *ReceptionUsecaseMock) SetReceptionManifest(ctx context.Context, receptionId string, data requests.SetManifestRequest) ([]models.ManifestItem, error) {
+	args := m.Called(ctx, receptionId, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ManifestItem), args.Error(1)
+}
+
+func (m *ReceptionUsecaseMock) GetDiscrepancyReport(ctx context.Context, receptionId string) (models.DiscrepancyReport, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).(models.DiscrepancyReport), args.Error(1)
+}
+
+type ScheduleUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *ScheduleUsecaseMock) GetSchedule(ctx context.Context, pvzId string) (models.PvzSchedule, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).(models.PvzSchedule), args.Error(1)
+}
+
+func (m *ScheduleUsecaseMock) GetScheduleAt(ctx context.Context, pvzId string, at time.Time) (models.PvzSchedule, error) {
+	args := m.Called(ctx, pvzId, at)
+	return args.Get(0).(models.PvzSchedule), args.Error(1)
+}
+
+func (m *ScheduleUsecaseMock) SetWorkingHours(ctx context.Context, pvzId string, data requests.SetWorkingHoursRequest) (models.PvzSchedule, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.PvzSchedule), args.Error(1)
+}
+
+func (m *ScheduleUsecaseMock) AddScheduleException(ctx context.Context, pvzId string, data requests.AddScheduleExceptionRequest) (models.ScheduleException, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.ScheduleException), args.Error(1)
+}
+
+func (m *ScheduleUsecaseMock) DeleteScheduleException(ctx context.Context, pvzId, exceptionId string) error {
+	args := m.Called(ctx, pvzId, exceptionId)
+	return args.Error(0)
+}
+
+type ExportUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *ExportUsecaseMock) Export(ctx context.Context, entity string, filter models.ExportFilter, writer exportUsecase.RowWriter) error {
+	args := m.Called(ctx, entity, filter)
+	if rows, ok := args.Get(0).([][]string); ok {
+		for _, row := range rows {
+			if err := writer.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+type ProductTypeUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *ProductTypeUsecaseMock) GetProductTypes(ctx context.Context) ([]models.ProductType, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ProductType), args.Error(1)
+}
+
+func (m *ProductTypeUsecaseMock) CreateProductType(ctx context.Context, req requests.CreateProductTypeRequest) (models.ProductType, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(models.ProductType), args.Error(1)
+}
+
+func (m *ProductTypeUsecaseMock) UpdateProductType(ctx context.Context, code string, req requests.UpdateProductTypeRequest) (models.ProductType, error) {
+	args := m.Called(ctx, code, req)
+	return args.Get(0).(models.ProductType), args.Error(1)
+}
+
+func (m *ProductTypeUsecaseMock) DeleteProductType(ctx context.Context, code string) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+type MockProductUsecase struct {
+	mock.Mock
+}
+
+func (m *MockProductUsecase) IssueProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.ProductStatusEvent), args.Error(1)
+}
+
+func (m *MockProductUsecase) RefuseProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.ProductStatusEvent), args.Error(1)
+}
+
+func (m *MockProductUsecase) ReturnProduct(ctx context.Context, pvzId string, data requests.ChangeProductStatusRequest) (models.ProductStatusEvent, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.ProductStatusEvent), args.Error(1)
+}
+
+func (m *MockProductUsecase) GetProductHistory(ctx context.Context, productId string) (*models.ProductState, []models.ProductStatusEvent, error) {
+	args := m.Called(ctx, productId)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.ProductState), args.Get(1).([]models.ProductStatusEvent), args.Error(2)
+}
+
+func (m *MockProductUsecase) SetOrderCustomer(ctx context.Context, externalId string, data requests.SetOrderCustomerRequest) (models.OrderOwner, error) {
+	args := m.Called(ctx, externalId, data)
+	return args.Get(0).(models.OrderOwner), args.Error(1)
+}
+
+func (m *MockProductUsecase) GetPickupCodes(ctx context.Context) ([]models.PickupCode, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PickupCode), args.Error(1)
+}
+
+func (m *MockProductUsecase) RegeneratePickupCode(ctx context.Context, productId string) (models.PickupCode, error) {
+	args := m.Called(ctx, productId)
+	return args.Get(0).(models.PickupCode), args.Error(1)
+}
+
+func (m *MockProductUsecase) PickupProduct(ctx context.Context, pvzId string, data requests.PickupProductRequest) (models.ProductStatusEvent, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.ProductStatusEvent), args.Error(1)
+}
+
+func (m *MockProductUsecase) GetOverdueProducts(ctx context.Context, pvzId string) ([]models.ProductState, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ProductState), args.Error(1)
+}
+
+func (m *MockProductUsecase) CreateTransfer(ctx context.Context, sourcePvzId string, data requests.CreateTransferRequest) (models.Transfer, error) {
+	args := m.Called(ctx, sourcePvzId, data)
+	return args.Get(0).(models.Transfer), args.Error(1)
+}
+
+func (m *MockProductUsecase) AcceptTransfer(ctx context.Context, pvzId, transferId string) (models.Transfer, error) {
+	args := m.Called(ctx, pvzId, transferId)
+	return args.Get(0).(models.Transfer), args.Error(1)
+}
+
+func (m *MockProductUsecase) GetIncomingTransfers(ctx context.Context, pvzId string) ([]models.Transfer, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Transfer), args.Error(1)
+}
+
+// StaticProductTypeCatalog — справочник типов для тестов: код -> активен ли тип.
+type StaticProductTypeCatalog map[string]bool
+
+// DefaultProductTypeCatalog содержит три типа, которые заводит миграция справочника.
+func DefaultProductTypeCatalog() StaticProductTypeCatalog {
+	return StaticProductTypeCatalog{
+		models.ELECTRONIC_TYPE: true,
+		models.CLOTHES_TYPE:    true,
+		models.BOOTS_TYPE:      true,
+	}
+}
+
+func (c StaticProductTypeCatalog) IsActiveProductType(_ context.Context, code string) (bool, error) {
+	return c[code], nil
+}
+
+func (c StaticProductTypeCatalog) ProductTypeSize(_ context.Context, _ string) (string, error) {
+	return models.DEFAULT_SIZE_CATEGORY, nil
+}
+
+func (c StaticProductTypeCatalog) ProductTypeExists(_ context.Context, code string) (bool, error) {
+	_, ok := c[code]
+	return ok, nil
+}
+
+type StorageCellUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *StorageCellUsecaseMock) SetStorageCells(ctx context.Context, pvzId string, data requests.SetStorageCellsRequest) ([]models.StorageCell, error) {
+	args := m.Called(ctx, pvzId, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StorageCell), args.Error(1)
+}
+
+func (m *StorageCellUsecaseMock) GetStorageCells(ctx context.Context, pvzId string) ([]models.StorageCell, error) {
+	args := m.Called(ctx, pvzId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StorageCell), args.Error(1)
+}
+
+func (m *StorageCellUsecaseMock) SuggestCell(ctx context.Context, pvzId, productId string) (models.StorageCell, error) {
+	args := m.Called(ctx, pvzId, productId)
+	return args.Get(0).(models.StorageCell), args.Error(1)
+}
+
+func (m *StorageCellUsecaseMock) AssignCell(ctx context.Context, pvzId string, data requests.AssignCellRequest) (models.ProductLocation, error) {
+	args := m.Called(ctx, pvzId, data)
+	return args.Get(0).(models.ProductLocation), args.Error(1)
+}
+
+func (m *StorageCellUsecaseMock) LookupProduct(ctx context.Context, pvzId, productId, pickupCode string) (models.ProductLocation, error) {
+	args := m.Called(ctx, pvzId, productId, pickupCode)
+	return args.Get(0).(models.ProductLocation), args.Error(1)
+}
+
+type InventoryUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *InventoryUsecaseMock) StartInventory(ctx context.Context, pvzId string) (models.InventorySession, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).(models.InventorySession), args.Error(1)
+}
+
+func (m *InventoryUsecaseMock) ScanInventoryItem(ctx context.Context, sessionId string, data requests.InventoryScanRequest) (models.InventoryScan, error) {
+	args := m.Called(ctx, sessionId, data)
+	return args.Get(0).(models.InventoryScan), args.Error(1)
+}
+
+func (m *InventoryUsecaseMock) CompleteInventory(ctx context.Context, sessionId string) (models.InventorySession, error) {
+	args := m.Called(ctx, sessionId)
+	return args.Get(0).(models.InventorySession), args.Error(1)
+}
+
+func (m *InventoryUsecaseMock) GetInventorySession(ctx context.Context, sessionId string) (models.InventorySession, error) {
+	args := m.Called(ctx, sessionId)
+	return args.Get(0).(models.InventorySession), args.Error(1)
+}
+
+func (m *InventoryUsecaseMock) ApproveInventory(ctx context.Context, sessionId string, data requests.ApproveInventoryRequest) (models.InventorySession, error) {
+	args := m.Called(ctx, sessionId, data)
+	return args.Get(0).(models.InventorySession), args.Error(1)
+}
+
+type DamageUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *DamageUsecaseMock) CreateDamageReport(ctx context.Context, data requests.CreateDamageReportRequest) (models.DamageReport, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(models.DamageReport), args.Error(1)
+}
+
+func (m *DamageUsecaseMock) GetDamageReport(ctx context.Context, reportId string) (models.DamageReport, error) {
+	args := m.Called(ctx, reportId)
+	return args.Get(0).(models.DamageReport), args.Error(1)
+}
+
+func (m *DamageUsecaseMock) GetDamageReports(ctx context.Context, productId, receptionId string) ([]models.DamageReport, error) {
+	args := m.Called(ctx, productId, receptionId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DamageReport), args.Error(1)
+}
+
+func (m *DamageUsecaseMock) UploadDamagePhoto(ctx context.Context, reportId string, content []byte) (models.DamagePhoto, error) {
+	args := m.Called(ctx, reportId, content)
+	return args.Get(0).(models.DamagePhoto), args.Error(1)
+}
+
+func (m *DamageUsecaseMock) GetDamagePhoto(ctx context.Context, reportId, photoId string) (models.DamagePhoto, io.ReadCloser, error) {
+	args := m.Called(ctx, reportId, photoId)
+	if args.Get(1) == nil {
+		return args.Get(0).(models.DamagePhoto), nil, args.Error(2)
+	}
+	return args.Get(0).(models.DamagePhoto), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+type WebhookUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *WebhookUsecaseMock) CreateWebhook(ctx context.Context, data requests.CreateWebhookRequest) (models.WebhookSubscription, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(models.WebhookSubscription), args.Error(1)
+}
+
+func (m *WebhookUsecaseMock) GetWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WebhookSubscription), args.Error(1)
+}
+
+func (m *WebhookUsecaseMock) DeleteWebhook(ctx context.Context, webhookId string) error {
+	args := m.Called(ctx, webhookId)
+	return args.Error(0)
+}
+
+func (m *WebhookUsecaseMock) GetWebhookDeliveries(ctx context.Context, webhookId, status string, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookId, status, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *WebhookUsecaseMock) RedeliverWebhookDelivery(ctx context.Context, webhookId, deliveryId string) (models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookId, deliveryId)
+	return args.Get(0).(models.WebhookDelivery), args.Error(1)
+}
+
+type EventStreamUsecaseMock struct {
+	mock.Mock
+}
+
+func (m *EventStreamUsecaseMock) OpenPvzStream(ctx context.Context, pvzId string, lastEventId int64) (*eventStreamUsecase.EventStream, error) {
+	args := m.Called(ctx, pvzId, lastEventId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*eventStreamUsecase.EventStream), args.Error(1)
+}
+
+func (m *EventStreamUsecaseMock) OpenCityStream(ctx context.Context, city string, lastEventId int64) (*eventStreamUsecase.EventStream, error) {
+	args := m.Called(ctx, city, lastEventId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*eventStreamUsecase.EventStream), args.Error(1)
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"context"
+)
+
+type WebhookUsecase interface {
+	CreateWebhook(ctx context.Context, data requests.CreateWebhookRequest) (models.WebhookSubscription, error)
+	GetWebhooks(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, webhookId string) error
+	GetWebhookDeliveries(ctx context.Context, webhookId, status string, limit int) ([]models.WebhookDelivery, error)
+	RedeliverWebhookDelivery(ctx context.Context, webhookId, deliveryId string) (models.WebhookDelivery, error)
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/domain/responses"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/microcosm-cc/bluemonday"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+type WebhookHandler struct {
+	usecase WebhookUsecase
+}
+
+func NewWebhookHandler(usecase WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{
+		usecase: usecase,
+	}
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	var data requests.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+	// url и secret не санитизируются: экранирование испортило бы query-строку адреса и подпись.
+	// Оба значения никогда не выводятся в HTML, а url дополнительно валидируется в usecase.
+	for i := range data.EventTypes {
+		data.EventTypes[i] = sanitizer.Sanitize(data.EventTypes[i])
+	}
+	for i := range data.PvzIds {
+		data.PvzIds[i] = sanitizer.Sanitize(data.PvzIds[i])
+	}
+
+	webhook, err := h.usecase.CreateWebhook(ctx, data)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := toWebhookResponse(webhook)
+	response.Secret = webhook.Secret
+	h.writeJSON(w, http.StatusCreated, response, requestID)
+}
+
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	defer cancel()
+
+	webhooks, err := h.usecase.GetWebhooks(ctx)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetWebhooksResponse{
+		Webhooks: make([]responses.WebhookResponse, 0, len(webhooks)),
+	}
+	for _, webhook := range webhooks {
+		response.Webhooks = append(response.Webhooks, toWebhookResponse(webhook))
+	}
+	h.writeJSON(w, http.StatusOK, response, requestID)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	webhookId := sanitizer.Sanitize(mux.Vars(r)["webhookId"])
+
+	if err := h.usecase.DeleteWebhook(ctx, webhookId); err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	webhookId := sanitizer.Sanitize(mux.Vars(r)["webhookId"])
+	status := sanitizer.Sanitize(r.URL.Query().Get("status"))
+	limit, err := strconv.Atoi(sanitizer.Sanitize(r.URL.Query().Get("limit")))
+	if err != nil || limit < 1 {
+		limit = models.DEFAULT_WEBHOOK_DELIVERIES_LIMIT
+	}
+
+	deliveries, err := h.usecase.GetWebhookDeliveries(ctx, webhookId, status, limit)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	response := responses.GetWebhookDeliveriesResponse{
+		Deliveries: make([]responses.WebhookDeliveryResponse, 0, len(deliveries)),
+	}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, toWebhookDeliveryResponse(delivery))
+	}
+	h.writeJSON(w, http.StatusOK, response, requestID)
+}
+
+func (h *WebhookHandler) RedeliverWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ctx, cancel := middleware.WithTimeout(r.Context())
+	sanitizer := bluemonday.UGCPolicy()
+	defer cancel()
+
+	webhookId := sanitizer.Sanitize(mux.Vars(r)["webhookId"])
+	deliveryId := sanitizer.Sanitize(mux.Vars(r)["deliveryId"])
+
+	delivery, err := h.usecase.RedeliverWebhookDelivery(ctx, webhookId, deliveryId)
+	if err != nil {
+		h.handleError(w, err, requestID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusAccepted, toWebhookDeliveryResponse(delivery), requestID)
+}
+
+func (h *WebhookHandler) writeJSON(w http.ResponseWriter, status int, response interface{}, requestID string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.AccessLogger.Error("Failed to encode response",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+}
+
+// toWebhookResponse не заполняет секрет: он отдаётся только при создании подписки.
+func toWebhookResponse(webhook models.WebhookSubscription) responses.WebhookResponse {
+	return responses.WebhookResponse{
+		Id:         webhook.Id,
+		Url:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+		PvzIds:     webhook.PvzIds,
+		CreatedBy:  webhook.CreatedBy,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(delivery models.WebhookDelivery) responses.WebhookDeliveryResponse {
+	response := responses.WebhookDeliveryResponse{
+		Id:             delivery.Id,
+		EventId:        delivery.EventId,
+		EventType:      delivery.EventType,
+		PvzId:          delivery.PvzId,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+	if delivery.Status == models.WEBHOOK_DELIVERY_PENDING {
+		nextAttemptAt := delivery.NextAttemptAt
+		response.NextAttemptAt = &nextAttemptAt
+	}
+	return response
+}
+
+func (h *WebhookHandler) handleError(w http.ResponseWriter, err error, requestID string) {
+	logger.AccessLogger.Error("Handling error",
+		zap.String("request_id", requestID),
+		zap.Error(err),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	errorResponse := map[string]string{"errors": err.Error()}
+
+	switch err.Error() {
+	case "invalid webhook url", "event types are required", "invalid event type", "invalid pvz id",
+		"webhook secret is too short", "webhook secret is too long", "invalid delivery status":
+		w.WriteHeader(http.StatusBadRequest)
+	case "webhook not found", "webhook delivery not found":
+		w.WriteHeader(http.StatusNotFound)
+	case "webhook delivery is already pending":
+		w.WriteHeader(http.StatusConflict)
+	case "this role is not allowed":
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if jsonErr := json.NewEncoder(w).Encode(errorResponse); jsonErr != nil {
+		logger.AccessLogger.Error("Failed to encode error response",
+			zap.String("request_id", requestID),
+			zap.Error(jsonErr),
+		)
+		http.Error(w, jsonErr.Error(), http.StatusInternalServerError)
+	}
+}
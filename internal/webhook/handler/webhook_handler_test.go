@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/logger"
+	usecaseMocks "avito_spring_staj_2025/internal/tests/mocks/usecase_mocks"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	createdAt := time.Date(2025, 5, 7, 10, 0, 0, 0, time.UTC)
+	nextAttemptAt := createdAt.Add(time.Minute)
+	vars := map[string]string{"webhookId": "hook1", "deliveryId": "delivery1"}
+	webhook := models.WebhookSubscription{
+		Id: "hook1", Url: "https://partner.example/hooks?token=a&b=c", Secret: "0123456789abcdef",
+		EventTypes: []string{models.EVENT_RECEPTION_CLOSED}, PvzIds: []string{}, CreatedBy: "moderator1", CreatedAt: createdAt,
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		call           func(h *WebhookHandler) http.HandlerFunc
+		mockBehavior   func(usecase *usecaseMocks.WebhookUsecaseMock)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "create returns secret and keeps url intact",
+			method: http.MethodPost,
+			path:   "/api/webhooks",
+			body:   `{"url":"https://partner.example/hooks?token=a&b=c","eventTypes":["ReceptionClosed"]}`,
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.CreateWebhook },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("CreateWebhook", mock.Anything, requests.CreateWebhookRequest{
+					Url: "https://partner.example/hooks?token=a&b=c", EventTypes: []string{"ReceptionClosed"},
+				}).Return(webhook, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"id":"hook1","url":"https://partner.example/hooks?token=a&b=c","eventTypes":["ReceptionClosed"],
+				"pvzIds":[],"secret":"0123456789abcdef","createdBy":"moderator1","createdAt":"2025-05-07T10:00:00Z"}`,
+		},
+		{
+			name:   "create with invalid url",
+			method: http.MethodPost,
+			path:   "/api/webhooks",
+			body:   `{"url":"ftp://partner.example","eventTypes":["ReceptionClosed"]}`,
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.CreateWebhook },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("CreateWebhook", mock.Anything, mock.Anything).
+					Return(models.WebhookSubscription{}, errors.New("invalid webhook url"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":"invalid webhook url"}`,
+		},
+		{
+			name:   "list hides secret",
+			method: http.MethodGet,
+			path:   "/api/webhooks",
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.GetWebhooks },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("GetWebhooks", mock.Anything).Return([]models.WebhookSubscription{webhook}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"webhooks":[{"id":"hook1","url":"https://partner.example/hooks?token=a&b=c",
+				"eventTypes":["ReceptionClosed"],"pvzIds":[],"createdBy":"moderator1","createdAt":"2025-05-07T10:00:00Z"}]}`,
+		},
+		{
+			name:   "list by employee",
+			method: http.MethodGet,
+			path:   "/api/webhooks",
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.GetWebhooks },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("GetWebhooks", mock.Anything).Return(nil, errors.New("this role is not allowed"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"errors":"this role is not allowed"}`,
+		},
+		{
+			name:   "delete missing",
+			method: http.MethodDelete,
+			path:   "/api/webhooks/hook1",
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.DeleteWebhook },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("DeleteWebhook", mock.Anything, "hook1").Return(errors.New("webhook not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":"webhook not found"}`,
+		},
+		{
+			name:   "deliveries",
+			method: http.MethodGet,
+			path:   "/api/webhooks/hook1/deliveries?status=pending&limit=10",
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.GetWebhookDeliveries },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("GetWebhookDeliveries", mock.Anything, "hook1", "pending", 10).Return([]models.WebhookDelivery{{
+					Id: "delivery1", SubscriptionId: "hook1", EventId: "event1", EventType: models.EVENT_RECEPTION_CLOSED,
+					PvzId: "pvz1", Status: models.WEBHOOK_DELIVERY_PENDING, Attempts: 2, NextAttemptAt: nextAttemptAt,
+					LastAttemptAt: &createdAt, LastStatusCode: 503, LastError: "receiver responded with status 503",
+					CreatedAt: createdAt,
+				}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"deliveries":[{"id":"delivery1","eventId":"event1","eventType":"ReceptionClosed","pvzId":"pvz1",
+				"status":"pending","attempts":2,"nextAttemptAt":"2025-05-07T10:01:00Z","lastAttemptAt":"2025-05-07T10:00:00Z",
+				"lastStatusCode":503,"lastError":"receiver responded with status 503","createdAt":"2025-05-07T10:00:00Z"}]}`,
+		},
+		{
+			name:   "deliveries with default limit",
+			method: http.MethodGet,
+			path:   "/api/webhooks/hook1/deliveries?limit=abc",
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.GetWebhookDeliveries },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("GetWebhookDeliveries", mock.Anything, "hook1", "", models.DEFAULT_WEBHOOK_DELIVERIES_LIMIT).
+					Return([]models.WebhookDelivery{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"deliveries":[]}`,
+		},
+		{
+			name:   "redeliver",
+			method: http.MethodPost,
+			path:   "/api/webhooks/hook1/deliveries/delivery1/redeliver",
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.RedeliverWebhookDelivery },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("RedeliverWebhookDelivery", mock.Anything, "hook1", "delivery1").Return(models.WebhookDelivery{
+					Id: "delivery1", SubscriptionId: "hook1", EventId: "event1", EventType: models.EVENT_RECEPTION_CLOSED,
+					PvzId: "pvz1", Status: models.WEBHOOK_DELIVERY_PENDING, NextAttemptAt: nextAttemptAt,
+					LastAttemptAt: &createdAt, LastStatusCode: 500, LastError: "receiver responded with status 500",
+					CreatedAt: createdAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody: `{"id":"delivery1","eventId":"event1","eventType":"ReceptionClosed","pvzId":"pvz1","status":"pending",
+				"attempts":0,"nextAttemptAt":"2025-05-07T10:01:00Z","lastAttemptAt":"2025-05-07T10:00:00Z","lastStatusCode":500,
+				"lastError":"receiver responded with status 500","createdAt":"2025-05-07T10:00:00Z"}`,
+		},
+		{
+			name:   "redeliver pending",
+			method: http.MethodPost,
+			path:   "/api/webhooks/hook1/deliveries/delivery1/redeliver",
+			call:   func(h *WebhookHandler) http.HandlerFunc { return h.RedeliverWebhookDelivery },
+			mockBehavior: func(usecase *usecaseMocks.WebhookUsecaseMock) {
+				usecase.On("RedeliverWebhookDelivery", mock.Anything, "hook1", "delivery1").
+					Return(models.WebhookDelivery{}, errors.New("webhook delivery is already pending"))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"errors":"webhook delivery is already pending"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(usecaseMocks.WebhookUsecaseMock)
+			handler := NewWebhookHandler(mockUsecase)
+			tt.mockBehavior(mockUsecase)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, vars)
+			w := httptest.NewRecorder()
+			tt.call(handler)(w, req)
+
+			res := w.Result()
+			defer func() {
+				err := res.Body.Close()
+				if err != nil {
+					return
+				}
+			}()
+
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			assert.JSONEq(t, tt.expectedBody, string(body))
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWebhookHandler_DeleteWebhook(t *testing.T) {
+	logger.AccessLogger = zap.NewNop()
+	mockUsecase := new(usecaseMocks.WebhookUsecaseMock)
+	mockUsecase.On("DeleteWebhook", mock.Anything, "hook1").Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/webhooks/hook1", nil)
+	req = mux.SetURLVars(req, map[string]string{"webhookId": "hook1"})
+	w := httptest.NewRecorder()
+	NewWebhookHandler(mockUsecase).DeleteWebhook(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockUsecase.AssertExpectations(t)
+}
@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"database/sql"
+	"errors"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const webhookColumns = "id, url, secret, event_types, pvz_ids, created_by, created_at"
+
+var (
+	deliveryColumnNames = []string{"id", "subscription_id", "event_id", "event_type", "pvz_id", "payload", "status",
+		"attempts", "next_attempt_at", "last_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"}
+	deliveryColumns = strings.Join(deliveryColumnNames, ", ")
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return WebhookRepository{
+		db: db,
+	}
+}
+
+func (r WebhookRepository) CreateWebhook(ctx context.Context, webhook models.WebhookSubscription) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CreateWebhook called", zap.String("request_id", requestID), zap.String("webhook_id", webhook.Id))
+
+	query, args, err := sq.Insert("webhook_subscriptions").
+		Columns("id", "url", "secret", "event_types", "pvz_ids", "created_by", "created_at").
+		Values(webhook.Id, webhook.Url, webhook.Secret, pq.Array(webhook.EventTypes), pq.Array(webhook.PvzIds),
+			webhook.CreatedBy, webhook.CreatedAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to insert webhook", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r WebhookRepository) GetWebhook(ctx context.Context, webhookId string) (*models.WebhookSubscription, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetWebhook called", zap.String("request_id", requestID), zap.String("webhook_id", webhookId))
+
+	query, args, err := sq.Select(webhookColumns).
+		From("webhook_subscriptions").
+		Where(sq.Eq{"id": webhookId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("webhook not found")
+		}
+		logger.DBLogger.Error("failed to scan webhook", zap.Error(err))
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r WebhookRepository) GetWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetWebhooks called", zap.String("request_id", requestID))
+
+	return r.queryWebhooks(ctx, sq.Select(webhookColumns).
+		From("webhook_subscriptions").
+		OrderBy("created_at"))
+}
+
+// GetMatchingWebhooks возвращает подписки на тип события, у которых нет фильтра по ПВЗ или он включает pvzId.
+func (r WebhookRepository) GetMatchingWebhooks(ctx context.Context, eventType, pvzId string) ([]models.WebhookSubscription, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetMatchingWebhooks called",
+		zap.String("request_id", requestID),
+		zap.String("event_type", eventType),
+		zap.String("pvz_id", pvzId),
+	)
+
+	return r.queryWebhooks(ctx, sq.Select(webhookColumns).
+		From("webhook_subscriptions").
+		Where("? = ANY(event_types)", eventType).
+		Where("(cardinality(pvz_ids) = 0 OR ?::uuid = ANY(pvz_ids))", pvzId).
+		OrderBy("created_at"))
+}
+
+func (r WebhookRepository) queryWebhooks(ctx context.Context, builder sq.SelectBuilder) ([]models.WebhookSubscription, error) {
+	query, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query webhooks", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	webhooks := []models.WebhookSubscription{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan webhook", zap.Error(err))
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row rowScanner) (*models.WebhookSubscription, error) {
+	var webhook models.WebhookSubscription
+	var eventTypes, pvzIds pq.StringArray
+	err := row.Scan(&webhook.Id, &webhook.Url, &webhook.Secret, &eventTypes, &pvzIds, &webhook.CreatedBy, &webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	webhook.EventTypes = append([]string{}, eventTypes...)
+	webhook.PvzIds = append([]string{}, pvzIds...)
+	return &webhook, nil
+}
+
+// DeleteWebhook удаляет подписку вместе с её доставками.
+func (r WebhookRepository) DeleteWebhook(ctx context.Context, webhookId string) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("DeleteWebhook called", zap.String("request_id", requestID), zap.String("webhook_id", webhookId))
+
+	query, args, err := sq.Delete("webhook_subscriptions").
+		Where(sq.Eq{"id": webhookId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to delete webhook", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("webhook not found")
+	}
+	return nil
+}
+
+// CreateDeliveries пропускает уже существующие доставки: relay outbox может опубликовать событие повторно.
+func (r WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("CreateDeliveries called", zap.String("request_id", requestID), zap.Int("count", len(deliveries)))
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	builder := sq.Insert("webhook_deliveries").
+		Columns("id", "subscription_id", "event_id", "event_type", "pvz_id", "payload", "status", "attempts",
+			"next_attempt_at", "created_at").
+		Suffix("ON CONFLICT (subscription_id, event_id) DO NOTHING").
+		PlaceholderFormat(sq.Dollar)
+	for _, delivery := range deliveries {
+		builder = builder.Values(delivery.Id, delivery.SubscriptionId, delivery.EventId, delivery.EventType, delivery.PvzId,
+			delivery.Payload, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		logger.DBLogger.Error("failed to insert webhook deliveries", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetDueDeliveries возвращает ожидающие доставки, время попытки которых наступило, от самых старых.
+func (r WebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DueWebhookDelivery, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetDueDeliveries called", zap.String("request_id", requestID), zap.Int("limit", limit))
+
+	query, args, err := sq.Select("d."+strings.Join(deliveryColumnNames, ", d."), "s.url", "s.secret").
+		From("webhook_deliveries d").
+		Join("webhook_subscriptions s ON s.id = d.subscription_id").
+		Where(sq.Eq{"d.status": models.WEBHOOK_DELIVERY_PENDING}).
+		Where(sq.LtOrEq{"d.next_attempt_at": now}).
+		OrderBy("d.next_attempt_at").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query due webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	due := []models.DueWebhookDelivery{}
+	for rows.Next() {
+		var item models.DueWebhookDelivery
+		delivery, err := scanDelivery(rows, &item.Url, &item.Secret)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan webhook delivery", zap.Error(err))
+			return nil, err
+		}
+		item.Delivery = *delivery
+		due = append(due, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// GetDeliveries возвращает журнал доставок подписки от новых к старым. Пустой status — все статусы.
+func (r WebhookRepository) GetDeliveries(ctx context.Context, webhookId, status string, limit int) ([]models.WebhookDelivery, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetDeliveries called",
+		zap.String("request_id", requestID),
+		zap.String("webhook_id", webhookId),
+		zap.String("status", status),
+	)
+
+	builder := sq.Select(deliveryColumns).
+		From("webhook_deliveries").
+		Where(sq.Eq{"subscription_id": webhookId}).
+		OrderBy("created_at DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+	if status != "" {
+		builder = builder.Where(sq.Eq{"status": status})
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to query webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.DBLogger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			logger.DBLogger.Error("failed to scan webhook delivery", zap.Error(err))
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// GetDelivery ищет доставку только внутри указанной подписки.
+func (r WebhookRepository) GetDelivery(ctx context.Context, webhookId, deliveryId string) (*models.WebhookDelivery, error) {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("GetDelivery called", zap.String("request_id", requestID), zap.String("delivery_id", deliveryId))
+
+	query, args, err := sq.Select(deliveryColumns).
+		From("webhook_deliveries").
+		Where(sq.Eq{"id": deliveryId, "subscription_id": webhookId}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return nil, err
+	}
+
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("webhook delivery not found")
+		}
+		logger.DBLogger.Error("failed to scan webhook delivery", zap.Error(err))
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func scanDelivery(row rowScanner, extra ...interface{}) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var lastAttemptAt, deliveredAt sql.NullTime
+	var lastStatusCode sql.NullInt64
+	dest := []interface{}{&delivery.Id, &delivery.SubscriptionId, &delivery.EventId, &delivery.EventType, &delivery.PvzId,
+		&delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &lastAttemptAt,
+		&lastStatusCode, &delivery.LastError, &delivery.CreatedAt, &deliveredAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if lastAttemptAt.Valid {
+		delivery.LastAttemptAt = &lastAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	delivery.LastStatusCode = int(lastStatusCode.Int64)
+	return &delivery, nil
+}
+
+// UpdateDelivery сохраняет состояние доставки после попытки отправки или ручного перезапуска.
+func (r WebhookRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	requestID := middleware.GetRequestID(ctx)
+	logger.DBLogger.Info("UpdateDelivery called",
+		zap.String("request_id", requestID),
+		zap.String("delivery_id", delivery.Id),
+		zap.String("status", delivery.Status),
+	)
+
+	var lastStatusCode interface{}
+	if delivery.LastStatusCode != 0 {
+		lastStatusCode = delivery.LastStatusCode
+	}
+	query, args, err := sq.Update("webhook_deliveries").
+		Set("status", delivery.Status).
+		Set("attempts", delivery.Attempts).
+		Set("next_attempt_at", delivery.NextAttemptAt).
+		Set("last_attempt_at", delivery.LastAttemptAt).
+		Set("last_status_code", lastStatusCode).
+		Set("last_error", delivery.LastError).
+		Set("delivered_at", delivery.DeliveredAt).
+		Where(sq.Eq{"id": delivery.Id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		logger.DBLogger.Error("failed to build SQL", zap.Error(err))
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.DBLogger.Error("failed to update webhook delivery", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("webhook delivery not found")
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/internal/service/logger"
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func newMockRepository(t *testing.T) (WebhookRepository, sqlmock.Sqlmock) {
+	logger.DBLogger = zap.NewNop()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return NewWebhookRepository(db), mock
+}
+
+var webhookColumnList = []string{"id", "url", "secret", "event_types", "pvz_ids", "created_by", "created_at"}
+
+func TestWebhookRepository_CreateWebhook(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	createdAt := time.Now()
+	mock.ExpectExec(`^INSERT INTO webhook_subscriptions \(id,url,secret,event_types,pvz_ids,created_by,created_at\) `+
+		`VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\)$`).
+		WithArgs("hook1", "https://partner.example", "secret", `{"ReceptionClosed","ProductAdded"}`, `{}`, "user1", createdAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.CreateWebhook(context.Background(), models.WebhookSubscription{
+		Id: "hook1", Url: "https://partner.example", Secret: "secret",
+		EventTypes: []string{models.EVENT_RECEPTION_CLOSED, models.EVENT_PRODUCT_ADDED}, PvzIds: []string{},
+		CreatedBy: "user1", CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_GetWebhook(t *testing.T) {
+	createdAt := time.Date(2025, 5, 7, 10, 0, 0, 0, time.UTC)
+	query := `^SELECT id, url, secret, event_types, pvz_ids, created_by, created_at FROM webhook_subscriptions WHERE id = \$1$`
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).WithArgs("hook1").WillReturnRows(sqlmock.NewRows(webhookColumnList).
+			AddRow("hook1", "https://partner.example", "secret", `{ReceptionClosed}`, `{11111111-1111-1111-1111-111111111111}`,
+				"user1", createdAt))
+
+		webhook, err := repo.GetWebhook(context.Background(), "hook1")
+		require.NoError(t, err)
+		assert.Equal(t, &models.WebhookSubscription{
+			Id: "hook1", Url: "https://partner.example", Secret: "secret", EventTypes: []string{models.EVENT_RECEPTION_CLOSED},
+			PvzIds: []string{"11111111-1111-1111-1111-111111111111"}, CreatedBy: "user1", CreatedAt: createdAt,
+		}, webhook)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectQuery(query).WithArgs("hook1").WillReturnRows(sqlmock.NewRows(webhookColumnList))
+
+		_, err := repo.GetWebhook(context.Background(), "hook1")
+		assert.EqualError(t, err, "webhook not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookRepository_GetMatchingWebhooks(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT id, url, secret, event_types, pvz_ids, created_by, created_at FROM webhook_subscriptions `+
+		`WHERE \$1 = ANY\(event_types\) AND \(cardinality\(pvz_ids\) = 0 OR \$2::uuid = ANY\(pvz_ids\)\) ORDER BY created_at$`).
+		WithArgs(models.EVENT_RECEPTION_CLOSED, "pvz1").
+		WillReturnRows(sqlmock.NewRows(webhookColumnList).
+			AddRow("hook1", "https://partner.example", "secret", `{ReceptionClosed}`, `{}`, "user1", time.Now()))
+
+	webhooks, err := repo.GetMatchingWebhooks(context.Background(), models.EVENT_RECEPTION_CLOSED, "pvz1")
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, []string{}, webhooks[0].PvzIds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_DeleteWebhook(t *testing.T) {
+	query := `^DELETE FROM webhook_subscriptions WHERE id = \$1$`
+
+	t.Run("Success", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).WithArgs("hook1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.DeleteWebhook(context.Background(), "hook1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mock.ExpectExec(query).WithArgs("hook1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.EqualError(t, repo.DeleteWebhook(context.Background(), "hook1"), "webhook not found")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookRepository_CreateDeliveries(t *testing.T) {
+	t.Run("Skips Duplicates", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		now := time.Now()
+		payload := []byte(`{"id":"event1"}`)
+		mock.ExpectExec(`^INSERT INTO webhook_deliveries \(id,subscription_id,event_id,event_type,pvz_id,payload,status,attempts,`+
+			`next_attempt_at,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10\),`+
+			`\(\$11,\$12,\$13,\$14,\$15,\$16,\$17,\$18,\$19,\$20\) ON CONFLICT \(subscription_id, event_id\) DO NOTHING$`).
+			WithArgs("d1", "hook1", "event1", models.EVENT_RECEPTION_CLOSED, "pvz1", payload, models.WEBHOOK_DELIVERY_PENDING, 0, now, now,
+				"d2", "hook2", "event1", models.EVENT_RECEPTION_CLOSED, "pvz1", payload, models.WEBHOOK_DELIVERY_PENDING, 0, now, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		delivery := models.WebhookDelivery{
+			EventId: "event1", EventType: models.EVENT_RECEPTION_CLOSED, PvzId: "pvz1", Payload: payload,
+			Status: models.WEBHOOK_DELIVERY_PENDING, NextAttemptAt: now, CreatedAt: now,
+		}
+		first, second := delivery, delivery
+		first.Id, first.SubscriptionId = "d1", "hook1"
+		second.Id, second.SubscriptionId = "d2", "hook2"
+		require.NoError(t, repo.CreateDeliveries(context.Background(), []models.WebhookDelivery{first, second}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		require.NoError(t, repo.CreateDeliveries(context.Background(), nil))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookRepository_GetDueDeliveries(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Date(2025, 5, 7, 10, 0, 0, 0, time.UTC)
+	attemptedAt := now.Add(-time.Minute)
+	mock.ExpectQuery(`^SELECT d.id, d.subscription_id, d.event_id, d.event_type, d.pvz_id, d.payload, d.status, d.attempts, `+
+		`d.next_attempt_at, d.last_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at, s.url, s.secret `+
+		`FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id `+
+		`WHERE d.status = \$1 AND d.next_attempt_at <= \$2 ORDER BY d.next_attempt_at LIMIT 50$`).
+		WithArgs(models.WEBHOOK_DELIVERY_PENDING, now).
+		WillReturnRows(sqlmock.NewRows(append(append([]string{}, deliveryColumnNames...), "url", "secret")).
+			AddRow("d1", "hook1", "event1", models.EVENT_RECEPTION_CLOSED, "pvz1", []byte(`{"id":"event1"}`),
+				models.WEBHOOK_DELIVERY_PENDING, 1, now, attemptedAt, 503, "receiver responded with status 503", attemptedAt, nil,
+				"https://partner.example", "secret"))
+
+	due, err := repo.GetDueDeliveries(context.Background(), now, 50)
+	require.NoError(t, err)
+	assert.Equal(t, []models.DueWebhookDelivery{{
+		Delivery: models.WebhookDelivery{
+			Id: "d1", SubscriptionId: "hook1", EventId: "event1", EventType: models.EVENT_RECEPTION_CLOSED, PvzId: "pvz1",
+			Payload: []byte(`{"id":"event1"}`), Status: models.WEBHOOK_DELIVERY_PENDING, Attempts: 1, NextAttemptAt: now,
+			LastAttemptAt: &attemptedAt, LastStatusCode: 503, LastError: "receiver responded with status 503", CreatedAt: attemptedAt,
+		},
+		Url:    "https://partner.example",
+		Secret: "secret",
+	}}, due)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_GetDeliveries(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+	mock.ExpectQuery(`^SELECT id, subscription_id, event_id, event_type, pvz_id, payload, status, attempts, next_attempt_at, `+
+		`last_attempt_at, last_status_code, last_error, created_at, delivered_at FROM webhook_deliveries `+
+		`WHERE subscription_id = \$1 AND status = \$2 ORDER BY created_at DESC LIMIT 20$`).
+		WithArgs("hook1", models.WEBHOOK_DELIVERY_DEAD).
+		WillReturnRows(sqlmock.NewRows(deliveryColumnNames).
+			AddRow("d1", "hook1", "event1", models.EVENT_RECEPTION_CLOSED, "pvz1", []byte(`{}`),
+				models.WEBHOOK_DELIVERY_DEAD, 8, now, now, nil, "timeout", now, nil))
+
+	deliveries, err := repo.GetDeliveries(context.Background(), "hook1", models.WEBHOOK_DELIVERY_DEAD, 20)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 0, deliveries[0].LastStatusCode)
+	assert.Nil(t, deliveries[0].DeliveredAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_GetDelivery(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	mock.ExpectQuery(`^SELECT .+ FROM webhook_deliveries WHERE id = \$1 AND subscription_id = \$2$`).
+		WithArgs("d1", "hook1").
+		WillReturnRows(sqlmock.NewRows(deliveryColumnNames))
+
+	_, err := repo.GetDelivery(context.Background(), "hook1", "d1")
+	assert.EqualError(t, err, "webhook delivery not found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_UpdateDelivery(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+	mock.ExpectExec(`^UPDATE webhook_deliveries SET status = \$1, attempts = \$2, next_attempt_at = \$3, last_attempt_at = \$4, `+
+		`last_status_code = \$5, last_error = \$6, delivered_at = \$7 WHERE id = \$8$`).
+		WithArgs(models.WEBHOOK_DELIVERY_DELIVERED, 1, now, now, 200, "", now, "d1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.UpdateDelivery(context.Background(), models.WebhookDelivery{
+		Id: "d1", Status: models.WEBHOOK_DELIVERY_DELIVERED, Attempts: 1, NextAttemptAt: now,
+		LastAttemptAt: &now, LastStatusCode: 200, DeliveredAt: &now,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"context"
+	"time"
+)
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook models.WebhookSubscription) error
+	GetWebhook(ctx context.Context, webhookId string) (*models.WebhookSubscription, error)
+	GetWebhooks(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetMatchingWebhooks(ctx context.Context, eventType, pvzId string) ([]models.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, webhookId string) error
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.DueWebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookId, status string, limit int) ([]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookId, deliveryId string) (*models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
+
+// WebhookSender отправляет подписанный запрос получателю и возвращает код его ответа.
+type WebhookSender interface {
+	Send(ctx context.Context, url, secret string, delivery models.WebhookDelivery) (int, error)
+}
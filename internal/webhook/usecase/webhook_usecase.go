@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"avito_spring_staj_2025/domain/models"
+	"avito_spring_staj_2025/domain/requests"
+	"avito_spring_staj_2025/internal/service/middleware"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDeliveryBatchSize — сколько доставок отправляется за один запуск фоновой задачи.
+	DefaultDeliveryBatchSize = 50
+	// maxLastErrorLength ограничивает текст ошибки, который попадает в журнал доставок.
+	maxLastErrorLength = 500
+)
+
+type WebhookUsecase struct {
+	webhookRepository WebhookRepository
+	sender            WebhookSender
+}
+
+func NewWebhookUsecase(webhookRepository WebhookRepository, sender WebhookSender) WebhookUsecase {
+	return WebhookUsecase{
+		webhookRepository: webhookRepository,
+		sender:            sender,
+	}
+}
+
+// CreateWebhook создаёт подписку. Если секрет не передан, он генерируется; увидеть его можно только в ответе на создание.
+func (wu WebhookUsecase) CreateWebhook(ctx context.Context, data requests.CreateWebhookRequest) (models.WebhookSubscription, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.WebhookSubscription{}, errors.New("this role is not allowed")
+	}
+
+	webhookUrl := strings.TrimSpace(data.Url)
+	if err := validateWebhookUrl(webhookUrl); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+
+	if len(data.EventTypes) == 0 {
+		return models.WebhookSubscription{}, errors.New("event types are required")
+	}
+	eventTypes := make([]string, 0, len(data.EventTypes))
+	seenTypes := make(map[string]bool, len(data.EventTypes))
+	for _, eventType := range data.EventTypes {
+		if !models.WebhookEventTypes[eventType] {
+			return models.WebhookSubscription{}, errors.New("invalid event type")
+		}
+		if !seenTypes[eventType] {
+			seenTypes[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	pvzIds := make([]string, 0, len(data.PvzIds))
+	seenPvzs := make(map[string]bool, len(data.PvzIds))
+	for _, pvzId := range data.PvzIds {
+		if _, err := uuid.Parse(pvzId); err != nil {
+			return models.WebhookSubscription{}, errors.New("invalid pvz id")
+		}
+		if !seenPvzs[pvzId] {
+			seenPvzs[pvzId] = true
+			pvzIds = append(pvzIds, pvzId)
+		}
+	}
+
+	secret := data.Secret
+	if secret == "" {
+		generated, err := generateSecret()
+		if err != nil {
+			return models.WebhookSubscription{}, err
+		}
+		secret = generated
+	}
+	if len(secret) < models.MIN_WEBHOOK_SECRET_LENGTH {
+		return models.WebhookSubscription{}, errors.New("webhook secret is too short")
+	}
+	if len(secret) > models.MAX_WEBHOOK_SECRET_LENGTH {
+		return models.WebhookSubscription{}, errors.New("webhook secret is too long")
+	}
+
+	webhook := models.WebhookSubscription{
+		Id:         uuid.New().String(),
+		Url:        webhookUrl,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		PvzIds:     pvzIds,
+		CreatedBy:  middleware.GetUserId(ctx),
+		CreatedAt:  time.Now(),
+	}
+	if err := wu.webhookRepository.CreateWebhook(ctx, webhook); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	return webhook, nil
+}
+
+func validateWebhookUrl(webhookUrl string) error {
+	if webhookUrl == "" || len(webhookUrl) > models.MAX_WEBHOOK_URL_LENGTH {
+		return errors.New("invalid webhook url")
+	}
+	parsed, err := url.Parse(webhookUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.User != nil {
+		return errors.New("invalid webhook url")
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (wu WebhookUsecase) GetWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return nil, errors.New("this role is not allowed")
+	}
+	return wu.webhookRepository.GetWebhooks(ctx)
+}
+
+func (wu WebhookUsecase) DeleteWebhook(ctx context.Context, webhookId string) error {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return errors.New("this role is not allowed")
+	}
+	return wu.webhookRepository.DeleteWebhook(ctx, webhookId)
+}
+
+// GetWebhookDeliveries возвращает журнал доставок подписки. Пустой status — все статусы.
+func (wu WebhookUsecase) GetWebhookDeliveries(ctx context.Context, webhookId, status string, limit int) ([]models.WebhookDelivery, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return nil, errors.New("this role is not allowed")
+	}
+	if status != "" && !models.IsValidWebhookDeliveryStatus(status) {
+		return nil, errors.New("invalid delivery status")
+	}
+	if limit < 1 {
+		limit = models.DEFAULT_WEBHOOK_DELIVERIES_LIMIT
+	}
+	if limit > models.MAX_WEBHOOK_DELIVERIES_LIMIT {
+		limit = models.MAX_WEBHOOK_DELIVERIES_LIMIT
+	}
+
+	if _, err := wu.webhookRepository.GetWebhook(ctx, webhookId); err != nil {
+		return nil, err
+	}
+	return wu.webhookRepository.GetDeliveries(ctx, webhookId, status, limit)
+}
+
+// RedeliverWebhookDelivery ставит доставленную или «мёртвую» доставку в очередь заново со сброшенным счётчиком попыток.
+func (wu WebhookUsecase) RedeliverWebhookDelivery(ctx context.Context, webhookId, deliveryId string) (models.WebhookDelivery, error) {
+	if ctx.Value(middleware.ContextKeyRole).(string) != "moderator" {
+		return models.WebhookDelivery{}, errors.New("this role is not allowed")
+	}
+
+	delivery, err := wu.webhookRepository.GetDelivery(ctx, webhookId, deliveryId)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if delivery.Status == models.WEBHOOK_DELIVERY_PENDING {
+		return models.WebhookDelivery{}, errors.New("webhook delivery is already pending")
+	}
+
+	delivery.Status = models.WEBHOOK_DELIVERY_PENDING
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+	if err := wu.webhookRepository.UpdateDelivery(ctx, *delivery); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return *delivery, nil
+}
+
+// Publish раскладывает событие outbox по подходящим подпискам. Usecase подключается к relay как ещё один Publisher,
+// поэтому доставки появляются только для закоммиченных событий.
+func (wu WebhookUsecase) Publish(ctx context.Context, event models.DomainEvent) error {
+	if !models.WebhookEventTypes[event.Type] {
+		return nil
+	}
+
+	webhooks, err := wu.webhookRepository.GetMatchingWebhooks(ctx, event.Type, event.PvzId)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			Id:             uuid.New().String(),
+			SubscriptionId: webhook.Id,
+			EventId:        event.Id,
+			EventType:      event.Type,
+			PvzId:          event.PvzId,
+			Payload:        payload,
+			Status:         models.WEBHOOK_DELIVERY_PENDING,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+	return wu.webhookRepository.CreateDeliveries(ctx, deliveries)
+}
+
+// DeliverPending отправляет доставки, время которых наступило, и возвращает их новое состояние.
+// Неудачная попытка откладывает следующую по экспоненте, после MAX_WEBHOOK_ATTEMPTS доставка становится dead.
+func (wu WebhookUsecase) DeliverPending(ctx context.Context, batchSize int) ([]models.WebhookDelivery, error) {
+	due, err := wu.webhookRepository.GetDueDeliveries(ctx, time.Now(), batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	processed := make([]models.WebhookDelivery, 0, len(due))
+	var errs []error
+	for _, item := range due {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+
+		delivery := item.Delivery
+		statusCode, sendErr := wu.sender.Send(ctx, item.Url, item.Secret, delivery)
+		attemptedAt := time.Now()
+		delivery.Attempts++
+		delivery.LastAttemptAt = &attemptedAt
+		delivery.LastStatusCode = statusCode
+		switch {
+		case sendErr == nil:
+			delivery.Status = models.WEBHOOK_DELIVERY_DELIVERED
+			delivery.LastError = ""
+			delivery.DeliveredAt = &attemptedAt
+		case delivery.Attempts >= models.MAX_WEBHOOK_ATTEMPTS:
+			delivery.Status = models.WEBHOOK_DELIVERY_DEAD
+			delivery.LastError = truncateError(sendErr)
+		default:
+			delivery.LastError = truncateError(sendErr)
+			delivery.NextAttemptAt = attemptedAt.Add(RetryDelay(delivery.Attempts))
+		}
+
+		if err := wu.webhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		processed = append(processed, delivery)
+	}
+	return processed, errors.Join(errs...)
+}
+
+// RetryDelay — пауза после attempts неудачных попыток: 30s, 1m, 2m, 4m… но не больше WEBHOOK_RETRY_MAX_DELAY.
+func RetryDelay(attempts int) time.Duration {
+	delay := models.WEBHOOK_RETRY_BASE_DELAY
+	for i := 1; i < attempts && delay < models.WEBHOOK_RETRY_MAX_DELAY; i++ {
+		delay *= 2
+	}
+	if delay > models.WEBHOOK_RETRY_MAX_DELAY {
+		delay = models.WEBHOOK_RETRY_MAX_DELAY
+	}
+	return delay
+}
+
+func truncateError(err error) string {
+	message := []rune(err.Error())
+	if len(message) > maxLastErrorLength {
+		message = message[:maxLastErrorLength]
+	}
+	return string(message)
+}